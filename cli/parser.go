@@ -150,7 +150,10 @@ func (p *Parser) parseCommandArg(s string) (flag *Flag, value string, err error)
 		value = v
 	}
 
-	if strings.HasPrefix(prefix, "-") {
+	if s == "-" {
+		// 单独的连字符视为值，例如表示标准输入或标准输出
+		value = s
+	} else if strings.HasPrefix(prefix, "-") {
 		// 特殊处理 SSL 证书等以多个连字符开头的情况
 		if strings.HasPrefix(prefix, "---") {
 			// 对于以三个或更多连字符开头的参数，视为值而非 flag
@@ -98,7 +98,13 @@ func TestParser1(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Equal(t, "not support '--' in command line", err.Error())
 
-	_, _, err = parser.parseCommandArg("-")
+	// a single dash is a value, such as stdin or stdout
+	flag, v, err = parser.parseCommandArg("-")
+	assert.Nil(t, err)
+	assert.Nil(t, flag)
+	assert.Equal(t, "-", v)
+
+	_, _, err = parser.parseCommandArg("-=a")
 	assert.NotNil(t, err)
 	assert.Equal(t, "not support flag form -", err.Error())
 
@@ -18,8 +18,8 @@ func addPluginSourceBaseFlag(cmd *cli.Command) {
 	cmd.Flags().Add(&cli.Flag{
 		Name: "source-base",
 		Short: i18n.T(
-			"Override plugins tree base URL for this command only (e.g. https://example.com/plugins)",
-			"仅本次命令覆盖插件源根地址（例如 https://example.com/plugins）"),
+			"Override plugins tree base URL for this command only (e.g. https://example.com/plugins or file:///opt/aliyun-plugins)",
+			"仅本次命令覆盖插件源根地址（例如 https://example.com/plugins 或 file:///opt/aliyun-plugins）"),
 		AssignedMode: cli.AssignedOnce,
 		DefaultValue: "",
 	})
@@ -60,6 +60,8 @@ func NewPluginCommand() *cli.Command {
 	cmd.AddSubCommand(newInstallAllCommand())
 	cmd.AddSubCommand(newUninstallCommand())
 	cmd.AddSubCommand(newUpdateCommand())
+	cmd.AddSubCommand(newLockCommand())
+	cmd.AddSubCommand(newMirrorCommand())
 
 	return cmd
 }
@@ -262,8 +264,19 @@ func newInstallCommand() *cli.Command {
 	cmd := &cli.Command{
 		Name:  "install",
 		Short: i18n.T("Install a plugin (from remote index or package file/URL)", "安装插件（远程索引或指定包文件/URL）"),
-		Usage: "install [--source-base <url>] (--name <plugin_name> | --names <plugin1> [<plugin2> ...]) [--version <version>] [--enable-pre] | install --package <path-or-url>",
+		Usage: "install [--source-base <url>] (--name <plugin_name> | --names <plugin1> [<plugin2> ...]) [--version <version>] [--enable-pre] | install --package <path-or-url> | install --from-lock <lockfile>",
 		Run: func(ctx *cli.Context, args []string) error {
+			if lockPath, ok, err := parseFromLockArg(ctx); ok || err != nil {
+				if err != nil {
+					return err
+				}
+				mgr, err := newManagerWithOptionalSourceBase(ctx)
+				if err != nil {
+					return err
+				}
+				return mgr.InstallFromLock(ctx, lockPath)
+			}
+
 			names, pkgRef, version, enablePre, err := parseInstallArgs(ctx)
 			if err != nil {
 				return err
@@ -311,6 +324,13 @@ func newInstallCommand() *cli.Command {
 		DefaultValue: "",
 	})
 
+	cmd.Flags().Add(&cli.Flag{
+		Name:         "from-lock",
+		Short:        i18n.T("Install exactly the plugin versions recorded in a lockfile written by `aliyun plugin lock`", "安装由 `aliyun plugin lock` 生成的锁文件中记录的插件版本"),
+		AssignedMode: cli.AssignedOnce,
+		DefaultValue: "",
+	})
+
 	addPluginSourceBaseFlag(cmd)
 	return cmd
 }
@@ -425,6 +445,174 @@ func newUpdateCommand() *cli.Command {
 	return cmd
 }
 
+func newLockCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "lock",
+		Short: i18n.T("Write plugin versions and checksums to a lockfile", "将插件版本和校验和写入锁文件"),
+		Long: i18n.T(
+			"Resolve plugins against the index and write their names, versions, package URLs and SHA-256 checksums for all platforms "+
+				"to a lockfile. Without --names the installed plugins are locked at their installed versions; with --names the latest "+
+				"version of each plugin is locked. Use `aliyun plugin install --from-lock <lockfile>` to reproduce the installation.",
+			"根据插件索引解析插件，并将名称、版本、各平台安装包地址和 SHA-256 校验和写入锁文件。不指定 --names 时锁定已安装插件的当前版本；"+
+				"指定 --names 时锁定各插件的最新版本。使用 `aliyun plugin install --from-lock <lockfile>` 按锁文件安装。"),
+		Usage: "lock [--source-base <url>] [--file <lockfile>] [--names <plugin1> [<plugin2> ...]] [--enable-pre]",
+		Run: func(ctx *cli.Context, args []string) error {
+			mgr, err := newManagerWithOptionalSourceBase(ctx)
+			if err != nil {
+				return err
+			}
+
+			path := DefaultLockfileName
+			if v, ok := ctx.Flags().GetValue("file"); ok && strings.TrimSpace(v) != "" {
+				path = strings.TrimSpace(v)
+			}
+
+			var names []string
+			if f := ctx.Flags().Get("names"); f != nil && f.IsAssigned() {
+				for _, n := range f.GetValues() {
+					if n != "" {
+						names = append(names, n)
+					}
+				}
+			}
+
+			enablePre := false
+			if enablePreFlag := ctx.Flags().Get("enable-pre"); enablePreFlag != nil && enablePreFlag.IsAssigned() {
+				enablePre = true
+			}
+
+			lock, err := mgr.Lock(names, enablePre)
+			if err != nil {
+				return err
+			}
+			if err := WriteLockfile(path, lock); err != nil {
+				return fmt.Errorf("failed to write lockfile: %w", err)
+			}
+			for _, p := range lock.Plugins {
+				cli.Printf(ctx.Stdout(), "Locked %s %s\n", p.Name, p.Version)
+			}
+			cli.Printf(ctx.Stdout(), "Wrote %d plugin(s) to %s\n", len(lock.Plugins), path)
+			return nil
+		},
+	}
+
+	cmd.Flags().Add(&cli.Flag{
+		Name:         "file",
+		Short:        i18n.T("Lockfile path (default: "+DefaultLockfileName+")", "锁文件路径（默认 "+DefaultLockfileName+"）"),
+		AssignedMode: cli.AssignedOnce,
+		DefaultValue: "",
+	})
+
+	cmd.Flags().Add(&cli.Flag{
+		Name:         "names",
+		Short:        i18n.T("Lock the latest version of these plugins instead of the installed ones", "锁定指定插件的最新版本，而不是已安装的插件"),
+		AssignedMode: cli.AssignedRepeatable,
+	})
+
+	cmd.Flags().Add(&cli.Flag{
+		Name:         "enable-pre",
+		Short:        i18n.T("Allow locking pre-release versions", "允许锁定预发布版本"),
+		AssignedMode: cli.AssignedNone,
+	})
+
+	addPluginSourceBaseFlag(cmd)
+	return cmd
+}
+
+func newMirrorCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "mirror",
+		Short: i18n.T("Create an offline plugin mirror in a directory", "在目录中创建离线插件镜像"),
+		Long: i18n.T(
+			"Download the plugin index, the command index and the latest package of the selected plugins into a directory. "+
+				"Serve the directory with any static file server, or use it directly with --source-base file:///path/to/dir. "+
+				"Package checksums are verified while mirroring and again when installing from the mirror.",
+			"将插件索引、命令索引和所选插件的最新安装包下载到目录中。该目录可以通过任意静态文件服务器提供，"+
+				"也可以通过 --source-base file:///path/to/dir 直接使用。镜像和从镜像安装时都会校验安装包的校验和。"),
+		Usage: "mirror --dest <dir> [--source-base <url>] [--platforms <linux-amd64,...>] [--names <plugin1> [<plugin2> ...]] [--enable-pre]",
+		Run: func(ctx *cli.Context, args []string) error {
+			dest := ""
+			if v, ok := ctx.Flags().GetValue("dest"); ok {
+				dest = strings.TrimSpace(v)
+			}
+			if dest == "" {
+				return fmt.Errorf("--dest is required")
+			}
+
+			opts := MirrorOptions{Dest: dest}
+			if v, ok := ctx.Flags().GetValue("platforms"); ok {
+				for _, platform := range strings.Split(v, ",") {
+					if platform = strings.TrimSpace(platform); platform != "" {
+						opts.Platforms = append(opts.Platforms, platform)
+					}
+				}
+			}
+			if f := ctx.Flags().Get("names"); f != nil && f.IsAssigned() {
+				for _, n := range f.GetValues() {
+					if n != "" {
+						opts.Names = append(opts.Names, n)
+					}
+				}
+			}
+			if enablePreFlag := ctx.Flags().Get("enable-pre"); enablePreFlag != nil && enablePreFlag.IsAssigned() {
+				opts.EnablePre = true
+			}
+
+			mgr, err := newManagerWithOptionalSourceBase(ctx)
+			if err != nil {
+				return err
+			}
+			return mgr.Mirror(ctx, opts)
+		},
+	}
+
+	cmd.Flags().Add(&cli.Flag{
+		Name:         "dest",
+		Short:        i18n.T("Directory to write the mirror to", "镜像写入的目录"),
+		AssignedMode: cli.AssignedOnce,
+	})
+
+	cmd.Flags().Add(&cli.Flag{
+		Name:         "platforms",
+		Short:        i18n.T("Comma-separated platforms to mirror, e.g. linux-amd64,darwin-arm64 (default: all)", "要镜像的平台，用逗号分隔，例如 linux-amd64,darwin-arm64（默认全部）"),
+		AssignedMode: cli.AssignedOnce,
+	})
+
+	cmd.Flags().Add(&cli.Flag{
+		Name:         "names",
+		Short:        i18n.T("Plugin name(s) to mirror (default: all plugins in the index)", "要镜像的插件名称（默认索引中的全部插件）"),
+		AssignedMode: cli.AssignedRepeatable,
+	})
+
+	cmd.Flags().Add(&cli.Flag{
+		Name:         "enable-pre",
+		Short:        i18n.T("Allow mirroring pre-release versions", "允许镜像预发布版本"),
+		AssignedMode: cli.AssignedNone,
+	})
+
+	addPluginSourceBaseFlag(cmd)
+	return cmd
+}
+
+// parseFromLockArg returns the --from-lock path; it cannot be combined with other install sources.
+func parseFromLockArg(ctx *cli.Context) (string, bool, error) {
+	f := ctx.Flags().Get("from-lock")
+	if f == nil || !f.IsAssigned() {
+		return "", false, nil
+	}
+	v, _ := f.GetValue()
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return "", true, fmt.Errorf("--from-lock requires a lockfile path")
+	}
+	for _, name := range []string{"name", "names", "package", "version", "enable-pre"} {
+		if other := ctx.Flags().Get(name); other != nil && other.IsAssigned() {
+			return "", true, fmt.Errorf("--from-lock cannot be used together with --%s", name)
+		}
+	}
+	return v, true, nil
+}
+
 func parseInstallArgs(ctx *cli.Context) (names []string, pkgRef string, version string, enablePre bool, err error) {
 	nameFlag := ctx.Flags().Get("name")
 	namesFlag := ctx.Flags().Get("names")
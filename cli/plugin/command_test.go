@@ -27,6 +27,7 @@ func TestNewPluginCommand(t *testing.T) {
 	assert.NotNil(t, cmd.GetSubCommand("uninstall"), "Should have uninstall subcommand")
 	assert.NotNil(t, cmd.GetSubCommand("show"), "Should have show subcommand")
 	assert.NotNil(t, cmd.GetSubCommand("update"), "Should have update subcommand")
+	assert.NotNil(t, cmd.GetSubCommand("lock"), "Should have lock subcommand")
 }
 
 func TestNewPluginCommand_Run(t *testing.T) {
@@ -272,6 +273,10 @@ func TestNewInstallCommand(t *testing.T) {
 	assert.NotNil(t, packageFlag)
 	assert.False(t, packageFlag.Required)
 
+	fromLockFlag := flags.Get("from-lock")
+	assert.NotNil(t, fromLockFlag)
+	assert.False(t, fromLockFlag.Required)
+
 	sourceBaseFlag := flags.Get("source-base")
 	assert.NotNil(t, sourceBaseFlag)
 	assert.False(t, sourceBaseFlag.Required)
@@ -764,7 +769,7 @@ func TestNewManagerWithOptionalSourceBase(t *testing.T) {
 		mgr, err := newManagerWithOptionalSourceBase(ctx)
 		assert.Error(t, err)
 		assert.Nil(t, mgr)
-		assert.Contains(t, err.Error(), "source-base must start with http:// or https://")
+		assert.Contains(t, err.Error(), "source-base must start with http://, https:// or file://")
 	})
 
 	t.Run("whitespace only value returns error", func(t *testing.T) {
@@ -0,0 +1,166 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Shell completion for plugin commands is delegated to the plugin binary, which is run as
+//
+//	<binary> __complete <args the plugin would receive> <word being completed>
+//
+// e.g. `aliyun fc list --re<TAB>` runs `<binary> __complete fc list --re`. The plugin prints
+// one candidate per line, optionally followed by a tab and a description, and ends the output
+// with a directive line `:<number>`. This is the protocol of cobra's hidden __complete command,
+// so cobra based plugins support it without extra code. Output without the directive line is
+// treated as "completion not supported" so a plugin printing its usage is not mistaken for candidates.
+
+const (
+	completeCommand     = "__complete"
+	completionCacheFile = "completion_cache.json"
+	completionCacheTTL  = 10 * time.Minute
+	completionTimeout   = 2 * time.Second
+)
+
+type completionCacheEntry struct {
+	Candidates []string  `json:"candidates"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// completionCacheKey includes the plugin version so upgrading a plugin invalidates its entries.
+func completionCacheKey(lp *LocalPlugin, args []string, current string) string {
+	return lp.Name + "@" + lp.Version + "\x00" + strings.Join(append(append([]string{}, args...), current), "\x00")
+}
+
+func (m *Manager) readCompletionCache() map[string]completionCacheEntry {
+	cache := make(map[string]completionCacheEntry)
+	data, err := os.ReadFile(filepath.Join(m.rootDir, completionCacheFile))
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return make(map[string]completionCacheEntry)
+	}
+	return cache
+}
+
+func (m *Manager) writeCompletionCache(cache map[string]completionCacheEntry) error {
+	now := time.Now()
+	for key, entry := range cache {
+		if now.After(entry.ExpiresAt) {
+			delete(cache, key)
+		}
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.rootDir, 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(m.rootDir, completionCacheFile), data)
+}
+
+// parseCompletionOutput returns the candidates printed by a plugin, or false when the
+// output does not end with a directive line.
+func parseCompletionOutput(out []byte, current string) ([]string, bool) {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, false
+	}
+	directive := lines[len(lines)-1]
+	if !strings.HasPrefix(directive, ":") {
+		return nil, false
+	}
+	if _, err := strconv.Atoi(directive[1:]); err != nil {
+		return nil, false
+	}
+
+	candidates := make([]string, 0, len(lines)-1)
+	for _, line := range lines[:len(lines)-1] {
+		candidate, _, _ := strings.Cut(line, "\t")
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "" || !strings.HasPrefix(candidate, current) {
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, true
+}
+
+func runPluginComplete(binPath string, args []string, current string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+
+	cmdArgs := append(append([]string{completeCommand}, args...), current)
+	cmd := exec.CommandContext(ctx, binPath, cmdArgs...)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil
+	}
+	candidates, ok := parseCompletionOutput(stdout.Bytes(), current)
+	if !ok {
+		return nil
+	}
+	return candidates
+}
+
+// CompletePluginCommand returns the completion candidates of the installed plugin handling
+// command. args are the words after `aliyun` that precede current, starting with command.
+// handled is false when no plugin handles the command. Results, including failures and
+// plugins that do not support completion, are cached per plugin version for a few minutes
+// so that repeated <TAB> presses do not start the plugin every time.
+func CompletePluginCommand(command string, args []string, current string) (candidates []string, handled bool, err error) {
+	mgr, err := NewManager()
+	if err != nil {
+		return nil, false, err
+	}
+	_, lp, err := mgr.findLocalPlugin(command)
+	if err != nil {
+		var notFoundErr *ErrPluginNotFound
+		if errors.As(err, &notFoundErr) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return mgr.completePlugin(lp, args, current), true, nil
+}
+
+func (m *Manager) completePlugin(lp *LocalPlugin, args []string, current string) []string {
+	pluginArgs := append([]string{}, args...)
+	if len(pluginArgs) > 0 {
+		pluginArgs[0] = strings.ToLower(pluginArgs[0])
+	}
+
+	key := completionCacheKey(lp, pluginArgs, current)
+	cache := m.readCompletionCache()
+	if entry, ok := cache[key]; ok && time.Now().Before(entry.ExpiresAt) {
+		return entry.Candidates
+	}
+
+	binPath, err := resolvePluginBinaryPath(lp)
+	if err != nil {
+		return nil
+	}
+	candidates := runPluginComplete(binPath, pluginArgs, current)
+	cache[key] = completionCacheEntry{Candidates: candidates, ExpiresAt: time.Now().Add(completionCacheTTL)}
+	// The cache is an optimisation only; completion must never print errors into the shell.
+	_ = m.writeCompletionCache(cache)
+	return candidates
+}
@@ -0,0 +1,113 @@
+package plugin
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCompletionOutput(t *testing.T) {
+	candidates, ok := parseCompletionOutput([]byte("list\tList functions\nlogs\ndeploy\n:4\n"), "l")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"list", "logs"}, candidates)
+
+	candidates, ok = parseCompletionOutput([]byte(":0\n"), "")
+	assert.True(t, ok)
+	assert.Empty(t, candidates)
+
+	// 没有 directive 行的输出（例如插件打印了 usage）不作为候选
+	_, ok = parseCompletionOutput([]byte("Usage: fc [command]\n"), "")
+	assert.False(t, ok)
+	_, ok = parseCompletionOutput([]byte("list\n:abc\n"), "")
+	assert.False(t, ok)
+	_, ok = parseCompletionOutput(nil, "")
+	assert.False(t, ok)
+}
+
+func TestCompletePluginCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script test skipped on Windows")
+	}
+
+	testHome := t.TempDir()
+	cleanup := setTestHomeDir(t, testHome)
+	defer cleanup()
+
+	rootDir := filepath.Join(testHome, ".aliyun", "plugins")
+	pluginDir := filepath.Join(rootDir, "aliyun-cli-fc")
+	require.NoError(t, os.MkdirAll(pluginDir, 0755))
+	callLog := filepath.Join(testHome, "calls")
+	script := "#!/bin/sh\necho \"$@\" >> " + callLog + "\n" +
+		"if [ \"$1\" != \"__complete\" ]; then exit 1; fi\n" +
+		"printf 'list\\tList functions\\nlogs\\ndeploy\\n:4\\n'\n"
+	require.NoError(t, os.WriteFile(filepath.Join(pluginDir, "aliyun-cli-fc"), []byte(script), 0755))
+	manifestJSON := `{"plugins":{"aliyun-cli-fc":{"name":"aliyun-cli-fc","version":"1.0.0","path":"` + pluginDir + `","command":"fc"}}}`
+	require.NoError(t, os.WriteFile(filepath.Join(rootDir, "manifest.json"), []byte(manifestJSON), 0644))
+
+	calls := func() []string {
+		data, _ := os.ReadFile(callLog)
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+
+	candidates, handled, err := CompletePluginCommand("FC", []string{"FC"}, "l")
+	require.NoError(t, err)
+	assert.True(t, handled)
+	assert.Equal(t, []string{"list", "logs"}, candidates)
+	assert.Equal(t, []string{"__complete fc l"}, calls())
+
+	// 第二次补全命中缓存，不再启动插件
+	candidates, _, err = CompletePluginCommand("fc", []string{"fc"}, "l")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"list", "logs"}, candidates)
+	assert.Len(t, calls(), 1)
+
+	_, _, err = CompletePluginCommand("fc", []string{"fc", "list"}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "__complete fc list", strings.TrimSpace(calls()[1]))
+
+	// 缓存过期后重新调用插件
+	cachePath := filepath.Join(rootDir, completionCacheFile)
+	var cache map[string]completionCacheEntry
+	data, err := os.ReadFile(cachePath)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &cache))
+	assert.Len(t, cache, 2)
+	for key, entry := range cache {
+		entry.ExpiresAt = time.Now().Add(-time.Minute)
+		cache[key] = entry
+	}
+	data, _ = json.Marshal(cache)
+	require.NoError(t, os.WriteFile(cachePath, data, 0644))
+	_, _, err = CompletePluginCommand("fc", []string{"fc"}, "l")
+	require.NoError(t, err)
+	assert.Len(t, calls(), 3)
+
+	candidates, handled, err = CompletePluginCommand("ecs", []string{"ecs"}, "")
+	assert.NoError(t, err)
+	assert.False(t, handled)
+	assert.Nil(t, candidates)
+}
+
+func TestCompletePluginCommand_Unsupported(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script test skipped on Windows")
+	}
+
+	rootDir := t.TempDir()
+	pluginDir := filepath.Join(rootDir, "aliyun-cli-old")
+	require.NoError(t, os.MkdirAll(pluginDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(pluginDir, "aliyun-cli-old"), []byte("#!/bin/sh\necho 'Usage: old [command]'\n"), 0755))
+
+	mgr := &Manager{rootDir: rootDir}
+	lp := &LocalPlugin{Name: "aliyun-cli-old", Version: "1.0.0", Path: pluginDir}
+	assert.Empty(t, mgr.completePlugin(lp, []string{"old"}, ""))
+	// 不支持补全的插件也会被缓存，避免每次按 TAB 都启动插件
+	assert.Contains(t, mgr.readCompletionCache(), completionCacheKey(lp, []string{"old"}, ""))
+}
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/aliyun/aliyun-cli/v3/cli"
 )
@@ -49,6 +50,24 @@ func IsProfileRequiredForCommand(command string) bool {
 	return lp.IsProfileRequired()
 }
 
+// ExecutionResult describes a finished plugin process.
+type ExecutionResult struct {
+	Command  string
+	Args     []string
+	ExitCode int
+	Duration time.Duration
+	Err      error
+}
+
+var executionObserver func(ExecutionResult)
+
+// SetExecutionObserver registers fn to be called after every plugin process
+// finishes. It also runs right before the host exits with a plugin's non-zero
+// exit code, which is the only chance to observe failed runs (e.g. audit log).
+func SetExecutionObserver(fn func(ExecutionResult)) {
+	executionObserver = fn
+}
+
 // Returns (true, nil) if plugin was found and executed successfully.
 // Returns (true, error) if plugin execution failed.
 // Returns (false, nil) if plugin was not found (not an error).
@@ -94,7 +113,19 @@ func ExecutePlugin(command string, args []string, ctx *cli.Context) (bool, error
 		envs = mergeEnvs(envs, ctx.GetRuntimeEnvs())
 	}
 
-	if err := runPluginCommand(binPath, adjustedArgs, stdout, stderr, envs); err != nil {
+	start := time.Now()
+	done := func(exitCode int, runErr error) {
+		if executionObserver != nil {
+			executionObserver(ExecutionResult{
+				Command:  command,
+				Args:     adjustedArgs,
+				ExitCode: exitCode,
+				Duration: time.Since(start),
+				Err:      runErr,
+			})
+		}
+	}
+	if err := runPluginCommand(binPath, adjustedArgs, stdout, stderr, envs, done); err != nil {
 		return true, err
 	}
 
@@ -176,7 +207,12 @@ func resolvePluginBinaryPath(plugin *LocalPlugin) (string, error) {
 	return binPath, nil
 }
 
-func runPluginCommand(binPath string, args []string, stdout io.Writer, stderr io.Writer, envs []string) error {
+// runPluginCommand runs the plugin binary; done, when not nil, is told the exit
+// code (-1 if the process could not run) before the host exits on failure.
+func runPluginCommand(binPath string, args []string, stdout io.Writer, stderr io.Writer, envs []string, done func(exitCode int, err error)) error {
+	if done == nil {
+		done = func(int, error) {}
+	}
 	if binPath == "" {
 		return fmt.Errorf("binary path is empty")
 	}
@@ -189,10 +225,13 @@ func runPluginCommand(binPath string, args []string, stdout io.Writer, stderr io
 
 	if err := cmd.Run(); err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
+			done(exitError.ExitCode(), err)
 			os.Exit(exitError.ExitCode())
 		}
+		done(-1, err)
 		return fmt.Errorf("plugin execution failed: %w", err)
 	}
 
+	done(0, nil)
 	return nil
 }
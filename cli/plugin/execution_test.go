@@ -336,14 +336,14 @@ func TestAdjustPluginArgs(t *testing.T) {
 
 func TestRunPluginCommand(t *testing.T) {
 	t.Run("Empty binary path", func(t *testing.T) {
-		err := runPluginCommand("", []string{}, os.Stdout, os.Stderr, os.Environ())
+		err := runPluginCommand("", []string{}, os.Stdout, os.Stderr, os.Environ(), nil)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "binary path is empty")
 	})
 
 	t.Run("Non-existent binary", func(t *testing.T) {
 		nonExistentPath := "/non/existent/binary/path"
-		err := runPluginCommand(nonExistentPath, []string{}, os.Stdout, os.Stderr, os.Environ())
+		err := runPluginCommand(nonExistentPath, []string{}, os.Stdout, os.Stderr, os.Environ(), nil)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "plugin execution failed")
 	})
@@ -361,7 +361,7 @@ func TestRunPluginCommand(t *testing.T) {
 			t.Fatalf("Failed to create test script: %v", err)
 		}
 
-		err := runPluginCommand(scriptPath, []string{}, os.Stdout, os.Stderr, os.Environ())
+		err := runPluginCommand(scriptPath, []string{}, os.Stdout, os.Stderr, os.Environ(), nil)
 		assert.NoError(t, err)
 		if err != nil {
 			t.Errorf("runPluginCommand() with valid script unexpected error: %v", err)
@@ -381,7 +381,7 @@ func TestRunPluginCommand(t *testing.T) {
 			t.Fatalf("Failed to create test script: %v", err)
 		}
 
-		err := runPluginCommand(scriptPath, []string{"arg1", "arg2"}, os.Stdout, os.Stderr, os.Environ())
+		err := runPluginCommand(scriptPath, []string{"arg1", "arg2"}, os.Stdout, os.Stderr, os.Environ(), nil)
 		assert.NoError(t, err)
 		if err != nil {
 			t.Errorf("runPluginCommand() with valid script and args unexpected error: %v", err)
@@ -389,6 +389,40 @@ func TestRunPluginCommand(t *testing.T) {
 	})
 }
 
+func TestExecutePlugin_NotifiesObserver(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script test skipped on Windows")
+	}
+
+	testHome := t.TempDir()
+	cleanup := setTestHomeDir(t, testHome)
+	defer cleanup()
+
+	pluginDir := filepath.Join(testHome, ".aliyun", "plugins", "aliyun-cli-test")
+	os.MkdirAll(pluginDir, 0755)
+	binPath := filepath.Join(pluginDir, "aliyun-cli-test")
+	os.WriteFile(binPath, []byte("#!/bin/sh\nexit 0\n"), 0755)
+
+	manifestPath := filepath.Join(testHome, ".aliyun", "plugins", "manifest.json")
+	manifestJSON := `{"plugins":{"aliyun-cli-test":{"name":"aliyun-cli-test","version":"1.0.0","description":"Test plugin","path":"` + pluginDir + `","command":"test"}}}`
+	os.WriteFile(manifestPath, []byte(manifestJSON), 0644)
+
+	var results []ExecutionResult
+	SetExecutionObserver(func(r ExecutionResult) { results = append(results, r) })
+	defer SetExecutionObserver(nil)
+
+	ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+	ok, err := ExecutePlugin("test", []string{"test", "list-things"}, ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "test", results[0].Command)
+		assert.Equal(t, []string{"test", "list-things"}, results[0].Args)
+		assert.Equal(t, 0, results[0].ExitCode)
+		assert.NoError(t, results[0].Err)
+	}
+}
+
 func TestExecutePlugin(t *testing.T) {
 	t.Run("Plugin not found", func(t *testing.T) {
 		testHome := t.TempDir()
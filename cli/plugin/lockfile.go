@@ -0,0 +1,234 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+)
+
+const (
+	DefaultLockfileName = "aliyun-plugins.lock.json"
+	lockfileVersion     = 1
+)
+
+// Lockfile pins plugins to exact versions and package checksums so that
+// `aliyun plugin install --from-lock` reproduces the same installation (e.g. in CI).
+type Lockfile struct {
+	LockfileVersion int            `json:"lockfileVersion"`
+	Plugins         []LockedPlugin `json:"plugins"`
+}
+
+type LockedPlugin struct {
+	Name      string                  `json:"name"`
+	Version   string                  `json:"version"`
+	Platforms map[string]PlatformInfo `json:"platforms"` // platform (e.g. linux-amd64) -> package URL and SHA-256
+}
+
+func findPluginByExactName(index *Index, name string) *PluginInfo {
+	for i := range index.Plugins {
+		if index.Plugins[i].Name == name {
+			return &index.Plugins[i]
+		}
+	}
+	return nil
+}
+
+func lockPluginVersion(p *PluginInfo, version string) (LockedPlugin, error) {
+	verInfo, ok := p.Versions[version]
+	if !ok {
+		return LockedPlugin{}, fmt.Errorf("version %s of plugin %s is not offered by the plugin index", version, p.Name)
+	}
+	if len(verInfo.Platforms) == 0 {
+		return LockedPlugin{}, fmt.Errorf("plugin %s version %s has no packages in the plugin index", p.Name, version)
+	}
+	platforms := make(map[string]PlatformInfo, len(verInfo.Platforms))
+	for platform, info := range verInfo.Platforms {
+		if info.Checksum == "" {
+			return LockedPlugin{}, fmt.Errorf("plugin %s version %s has no checksum for %s in the plugin index", p.Name, version, platform)
+		}
+		platforms[platform] = info
+	}
+	return LockedPlugin{Name: p.Name, Version: version, Platforms: platforms}, nil
+}
+
+// Lock resolves plugins against the index. Without names, the installed plugins are locked
+// at their installed versions; with names, the latest version of each named plugin is locked.
+func (m *Manager) Lock(names []string, enablePre bool) (*Lockfile, error) {
+	index, err := m.GetIndex()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plugin index: %w", err)
+	}
+
+	lock := &Lockfile{LockfileVersion: lockfileVersion, Plugins: []LockedPlugin{}}
+	seen := make(map[string]bool)
+	add := func(p *PluginInfo, version string) error {
+		if seen[p.Name] {
+			return nil
+		}
+		locked, err := lockPluginVersion(p, version)
+		if err != nil {
+			return err
+		}
+		seen[p.Name] = true
+		lock.Plugins = append(lock.Plugins, locked)
+		return nil
+	}
+
+	if len(names) == 0 {
+		localManifest, err := m.GetLocalManifest()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get local manifest: %w", err)
+		}
+		if len(localManifest.Plugins) == 0 {
+			return nil, fmt.Errorf("no plugins installed, use --names to lock plugins from the index")
+		}
+		for name, lp := range localManifest.Plugins {
+			p := findPluginByExactName(index, name)
+			if p == nil {
+				return nil, fmt.Errorf("installed plugin %s is not in the plugin index and cannot be locked", name)
+			}
+			if err := add(p, lp.Version); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		for _, name := range names {
+			var p *PluginInfo
+			for i := range index.Plugins {
+				if matchPluginName(index.Plugins[i].Name, name) {
+					p = &index.Plugins[i]
+					break
+				}
+			}
+			if p == nil {
+				return nil, fmt.Errorf("plugin %s not found", name)
+			}
+			version, err := getLatestVersion(p, enablePre)
+			if err != nil {
+				return nil, err
+			}
+			if err := add(p, version); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sort.Slice(lock.Plugins, func(i, j int) bool {
+		return lock.Plugins[i].Name < lock.Plugins[j].Name
+	})
+	return lock, nil
+}
+
+func WriteLockfile(path string, lock *Lockfile) error {
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func ReadLockfile(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+	var lock Lockfile
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %w", path, err)
+	}
+	if lock.LockfileVersion != lockfileVersion {
+		return nil, fmt.Errorf("unsupported lockfile version %d in %s", lock.LockfileVersion, path)
+	}
+	for _, p := range lock.Plugins {
+		if p.Name == "" || p.Version == "" {
+			return nil, fmt.Errorf("invalid lockfile %s: every plugin needs a name and version", path)
+		}
+	}
+	return &lock, nil
+}
+
+// resolveLockedPlugin checks that the index still offers the locked version with the locked
+// checksum for the current platform, and returns the locked package to install.
+func (m *Manager) resolveLockedPlugin(ctx *cli.Context, index *Index, locked LockedPlugin) (*PlatformInfo, error) {
+	platform := GetCurrentPlatform()
+	lockedPlat, ok := locked.Platforms[platform]
+	if !ok {
+		return nil, fmt.Errorf("lockfile has no package of plugin %s %s for %s", locked.Name, locked.Version, platform)
+	}
+
+	p := findPluginByExactName(index, locked.Name)
+	if p == nil {
+		return nil, fmt.Errorf("plugin %s is no longer offered by the plugin index", locked.Name)
+	}
+	if _, ok := p.Versions[locked.Version]; !ok {
+		return nil, fmt.Errorf("plugin %s version %s is no longer offered by the plugin index", locked.Name, locked.Version)
+	}
+	indexPlat, err := m.validateVersionAndPlatform(ctx, p, locked.Version, locked.Name)
+	if err != nil {
+		return nil, err
+	}
+	if indexPlat.Checksum != lockedPlat.Checksum {
+		return nil, fmt.Errorf(
+			"plugin %s version %s for %s no longer matches the lockfile\n"+
+				"Locked: %s\n"+
+				"Index:  %s",
+			locked.Name, locked.Version, platform, lockedPlat.Checksum, indexPlat.Checksum,
+		)
+	}
+	return &lockedPlat, nil
+}
+
+// InstallFromLock installs exactly the plugin versions recorded in the lockfile. Every entry is
+// checked against the index before anything is installed; plugins already installed at the
+// locked version are skipped.
+func (m *Manager) InstallFromLock(ctx *cli.Context, path string) error {
+	lock, err := ReadLockfile(path)
+	if err != nil {
+		return err
+	}
+	if len(lock.Plugins) == 0 {
+		cli.Printf(ctx.Stdout(), "No plugins in lockfile %s.\n", path)
+		return nil
+	}
+
+	index, err := m.GetIndex()
+	if err != nil {
+		return fmt.Errorf("failed to get plugin index: %w", err)
+	}
+
+	resolved := make([]*PlatformInfo, len(lock.Plugins))
+	for i, locked := range lock.Plugins {
+		if resolved[i], err = m.resolveLockedPlugin(ctx, index, locked); err != nil {
+			return err
+		}
+	}
+
+	localManifest, err := m.GetLocalManifest()
+	if err != nil {
+		return fmt.Errorf("failed to get local manifest: %w", err)
+	}
+
+	var installed, skipped int
+	for i, locked := range lock.Plugins {
+		if lp, ok := localManifest.Plugins[locked.Name]; ok && lp.Version == locked.Version {
+			cli.Printf(ctx.Stdout(), "Skipping %s (already installed: %s)\n", locked.Name, locked.Version)
+			skipped++
+			continue
+		}
+		if err := m.installPlatformPackage(ctx, locked.Name, locked.Version, resolved[i], false); err != nil {
+			return fmt.Errorf("failed to install %s %s: %w", locked.Name, locked.Version, err)
+		}
+		installed++
+	}
+
+	if installed > 0 {
+		cli.Printf(ctx.Stdout(), "Installed: %d\n", installed)
+	}
+	if skipped > 0 {
+		cli.Printf(ctx.Stdout(), "Skipped: %d\n", skipped)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLockTestServer serves a plugin index with lock-a 1.0.0/1.1.0 and lock-b 2.0.0, a command index and the packages.
+func newLockTestServer(t *testing.T) (*httptest.Server, *Index) {
+	archives := map[string][]byte{
+		"/pkgs/lock-a/1.0.0/lock-a.tar.gz": createTestPluginArchive(t, "lock-a", "1.0.0", "a"),
+		"/pkgs/lock-a/1.1.0/lock-a.tar.gz": createTestPluginArchive(t, "lock-a", "1.1.0", "a"),
+		"/pkgs/lock-b/2.0.0/lock-b.tar.gz": createTestPluginArchive(t, "lock-b", "2.0.0", "b"),
+	}
+	index := &Index{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/plugin_pkg_index.json" {
+			_ = json.NewEncoder(w).Encode(index)
+			return
+		}
+		if r.URL.Path == "/plugin_search_index.json" {
+			_ = json.NewEncoder(w).Encode(CommandIndex{"a": "lock-a", "b": "lock-b", "c": "missing"})
+			return
+		}
+		data, ok := archives[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	version := func(name, v string) VersionInfo {
+		path := "/pkgs/" + name + "/" + v + "/" + name + ".tar.gz"
+		sum, err := calculateSHA256FromBytes(archives[path])
+		require.NoError(t, err)
+		return VersionInfo{Platforms: map[string]PlatformInfo{
+			GetCurrentPlatform(): {URL: server.URL + path, Checksum: sum},
+			"other-platform":     {URL: server.URL + "/other", Checksum: "abc"},
+		}}
+	}
+	index.Plugins = []PluginInfo{
+		{Name: "lock-a", Versions: map[string]VersionInfo{"1.0.0": version("lock-a", "1.0.0"), "1.1.0": version("lock-a", "1.1.0")}},
+		{Name: "lock-b", Versions: map[string]VersionInfo{"2.0.0": version("lock-b", "2.0.0")}},
+	}
+	return server, index
+}
+
+func newLockTestManager(t *testing.T, server *httptest.Server) *Manager {
+	return &Manager{
+		rootDir:                    t.TempDir(),
+		indexURL:                   server.URL + "/plugin_pkg_index.json",
+		commandIndexURL:            server.URL + "/plugin_search_index.json",
+		skipPluginIndexCacheForCLI: true,
+	}
+}
+
+func TestManager_Lock(t *testing.T) {
+	server, index := newLockTestServer(t)
+
+	t.Run("names lock latest versions for all platforms", func(t *testing.T) {
+		mgr := newLockTestManager(t, server)
+		lock, err := mgr.Lock([]string{"lock-b", "lock-a", "lock-a"}, false)
+		require.NoError(t, err)
+		require.Len(t, lock.Plugins, 2)
+		assert.Equal(t, lockfileVersion, lock.LockfileVersion)
+		assert.Equal(t, "lock-a", lock.Plugins[0].Name)
+		assert.Equal(t, "1.1.0", lock.Plugins[0].Version)
+		assert.Equal(t, index.Plugins[0].Versions["1.1.0"].Platforms, lock.Plugins[0].Platforms)
+		assert.Equal(t, "lock-b", lock.Plugins[1].Name)
+	})
+
+	t.Run("installed plugins are locked at installed versions", func(t *testing.T) {
+		mgr := newLockTestManager(t, server)
+		ctx := newTestContext()
+		require.NoError(t, mgr.Install(ctx, "lock-a", "1.0.0", false))
+
+		lock, err := mgr.Lock(nil, false)
+		require.NoError(t, err)
+		require.Len(t, lock.Plugins, 1)
+		assert.Equal(t, "1.0.0", lock.Plugins[0].Version)
+	})
+
+	t.Run("nothing installed", func(t *testing.T) {
+		mgr := newLockTestManager(t, server)
+		_, err := mgr.Lock(nil, false)
+		assert.ErrorContains(t, err, "no plugins installed")
+	})
+
+	t.Run("installed plugin missing from index", func(t *testing.T) {
+		mgr := newLockTestManager(t, server)
+		require.NoError(t, mgr.saveLocalManifest(&LocalManifest{Plugins: map[string]LocalPlugin{
+			"local-only": {Name: "local-only", Version: "0.1.0"},
+		}}))
+		_, err := mgr.Lock(nil, false)
+		assert.ErrorContains(t, err, "installed plugin local-only is not in the plugin index")
+	})
+
+	t.Run("unknown name", func(t *testing.T) {
+		mgr := newLockTestManager(t, server)
+		_, err := mgr.Lock([]string{"missing"}, false)
+		assert.ErrorContains(t, err, "plugin missing not found")
+	})
+}
+
+func TestReadWriteLockfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultLockfileName)
+	lock := &Lockfile{LockfileVersion: lockfileVersion, Plugins: []LockedPlugin{
+		{Name: "p", Version: "1.0.0", Platforms: map[string]PlatformInfo{"linux-amd64": {URL: "u", Checksum: "c"}}},
+	}}
+	require.NoError(t, WriteLockfile(path, lock))
+	got, err := ReadLockfile(path)
+	require.NoError(t, err)
+	assert.Equal(t, lock, got)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"lockfileVersion":2,"plugins":[]}`), 0644))
+	_, err = ReadLockfile(path)
+	assert.ErrorContains(t, err, "unsupported lockfile version 2")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"lockfileVersion":1,"plugins":[{"name":"p"}]}`), 0644))
+	_, err = ReadLockfile(path)
+	assert.ErrorContains(t, err, "needs a name and version")
+
+	_, err = ReadLockfile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorContains(t, err, "failed to read lockfile")
+}
+
+func TestManager_InstallFromLock(t *testing.T) {
+	server, index := newLockTestServer(t)
+	writeLock := func(t *testing.T, mgr *Manager, names ...string) string {
+		lock, err := mgr.Lock(names, false)
+		require.NoError(t, err)
+		path := filepath.Join(t.TempDir(), DefaultLockfileName)
+		require.NoError(t, WriteLockfile(path, lock))
+		return path
+	}
+
+	t.Run("installs locked versions and skips installed ones", func(t *testing.T) {
+		mgr := newLockTestManager(t, server)
+		lockPath := writeLock(t, mgr, "lock-a", "lock-b")
+
+		ctx := newTestContext()
+		require.NoError(t, mgr.InstallFromLock(ctx, lockPath))
+		manifest, err := mgr.GetLocalManifest()
+		require.NoError(t, err)
+		assert.Equal(t, "1.1.0", manifest.Plugins["lock-a"].Version)
+		assert.Equal(t, "2.0.0", manifest.Plugins["lock-b"].Version)
+		assert.Contains(t, ctx.Stdout().(*bytes.Buffer).String(), "Installed: 2")
+
+		ctx = newTestContext()
+		require.NoError(t, mgr.InstallFromLock(ctx, lockPath))
+		assert.Contains(t, ctx.Stdout().(*bytes.Buffer).String(), "Skipped: 2")
+	})
+
+	t.Run("replaces a different installed version", func(t *testing.T) {
+		mgr := newLockTestManager(t, server)
+		lockPath := writeLock(t, mgr, "lock-a")
+		require.NoError(t, mgr.Install(newTestContext(), "lock-a", "1.0.0", false))
+
+		require.NoError(t, mgr.InstallFromLock(newTestContext(), lockPath))
+		manifest, err := mgr.GetLocalManifest()
+		require.NoError(t, err)
+		assert.Equal(t, "1.1.0", manifest.Plugins["lock-a"].Version)
+	})
+
+	t.Run("fails before installing when the index changed", func(t *testing.T) {
+		mgr := newLockTestManager(t, server)
+		lockPath := writeLock(t, mgr, "lock-a", "lock-b")
+
+		platform := GetCurrentPlatform()
+		original := index.Plugins[1].Versions["2.0.0"]
+		changed := VersionInfo{Platforms: map[string]PlatformInfo{
+			platform: {URL: original.Platforms[platform].URL, Checksum: "0000"},
+		}}
+		index.Plugins[1].Versions["2.0.0"] = changed
+		defer func() { index.Plugins[1].Versions["2.0.0"] = original }()
+
+		err := mgr.InstallFromLock(newTestContext(), lockPath)
+		assert.ErrorContains(t, err, "plugin lock-b version 2.0.0 for "+platform+" no longer matches the lockfile")
+		manifest, err := mgr.GetLocalManifest()
+		require.NoError(t, err)
+		assert.Empty(t, manifest.Plugins)
+
+		delete(index.Plugins[1].Versions, "2.0.0")
+		err = mgr.InstallFromLock(newTestContext(), lockPath)
+		assert.ErrorContains(t, err, "plugin lock-b version 2.0.0 is no longer offered by the plugin index")
+	})
+
+	t.Run("missing platform in lockfile", func(t *testing.T) {
+		mgr := newLockTestManager(t, server)
+		path := filepath.Join(t.TempDir(), DefaultLockfileName)
+		require.NoError(t, WriteLockfile(path, &Lockfile{LockfileVersion: lockfileVersion, Plugins: []LockedPlugin{
+			{Name: "lock-a", Version: "1.0.0", Platforms: map[string]PlatformInfo{"other-platform": {URL: "u", Checksum: "abc"}}},
+		}}))
+		err := mgr.InstallFromLock(newTestContext(), path)
+		assert.ErrorContains(t, err, "lockfile has no package of plugin lock-a 1.0.0 for "+GetCurrentPlatform())
+	})
+
+	t.Run("download verified against locked checksum", func(t *testing.T) {
+		mgr := newLockTestManager(t, server)
+		path := filepath.Join(t.TempDir(), DefaultLockfileName)
+		platform := GetCurrentPlatform()
+		plat := index.Plugins[0].Versions["1.0.0"].Platforms[platform]
+		// 锁文件中的地址被替换为其他包时，下载后的校验和与锁定值不一致
+		plat.URL = index.Plugins[0].Versions["1.1.0"].Platforms[platform].URL
+		require.NoError(t, WriteLockfile(path, &Lockfile{LockfileVersion: lockfileVersion, Plugins: []LockedPlugin{
+			{Name: "lock-a", Version: "1.0.0", Platforms: map[string]PlatformInfo{platform: plat}},
+		}}))
+		err := mgr.InstallFromLock(newTestContext(), path)
+		assert.ErrorContains(t, err, "checksum verification failed")
+	})
+}
+
+func TestNewLockCommand_Run(t *testing.T) {
+	server, _ := newLockTestServer(t)
+	testHome := t.TempDir()
+	cleanup := setTestHomeDir(t, testHome)
+	defer cleanup()
+
+	cmd := newLockCommand()
+	stdout := new(bytes.Buffer)
+	ctx := cli.NewCommandContext(stdout, new(bytes.Buffer))
+	ctx.EnterCommand(cmd)
+
+	lockPath := filepath.Join(t.TempDir(), "plugins.lock.json")
+	ctx.Flags().Get("file").SetAssigned(true)
+	ctx.Flags().Get("file").SetValue(lockPath)
+	ctx.Flags().Get("names").SetAssigned(true)
+	ctx.Flags().Get("names").SetValues([]string{"lock-a"})
+	ctx.Flags().Get("source-base").SetAssigned(true)
+	ctx.Flags().Get("source-base").SetValue(server.URL)
+
+	require.NoError(t, cmd.Run(ctx, []string{}))
+	assert.Contains(t, stdout.String(), "Locked lock-a 1.1.0")
+	lock, err := ReadLockfile(lockPath)
+	require.NoError(t, err)
+	require.Len(t, lock.Plugins, 1)
+}
+
+func TestNewInstallCommand_Run_FromLockConflict(t *testing.T) {
+	cmd := newInstallCommand()
+	testHome := t.TempDir()
+	cleanup := setTestHomeDir(t, testHome)
+	defer cleanup()
+
+	ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+	ctx.EnterCommand(cmd)
+	ctx.Flags().Get("from-lock").SetAssigned(true)
+	ctx.Flags().Get("from-lock").SetValue("plugins.lock.json")
+	ctx.Flags().Get("name").SetAssigned(true)
+	ctx.Flags().Get("name").SetValue("lock-a")
+
+	err := cmd.Run(ctx, []string{})
+	assert.EqualError(t, err, "--from-lock cannot be used together with --name")
+}
@@ -52,6 +52,12 @@ type Manager struct {
 	commandIndexURL string // For testing: allows overriding resolved command index URL
 	// skipPluginIndexCacheForCLI is set when --source-base is used on this command only.
 	skipPluginIndexCacheForCLI bool
+	signaturePolicy            string   // from plugin-settings.json / env; empty = no verification
+	trustedKeys                []string // user trusted keys, in addition to builtinTrustedKeys
+	warnedNoTrustedKeys        bool     // the "verify without keys" warning is printed once per command
+	// settingsErr is set when plugin-settings.json cannot be parsed; signature checks then fail
+	// instead of running with a policy that may be weaker than the one in the file.
+	settingsErr error
 }
 
 func getHomePath() string {
@@ -94,12 +100,26 @@ func NewManager() (*Manager, error) {
 		return nil, err
 	}
 	sysDir := filepath.Join(getHomePath(), ".aliyun")
-	settings, err := pluginsettings.Load(sysDir)
-	if err != nil {
+	// A broken settings file must not stop installed plugins from running, but falling back to
+	// the defaults would silently drop a "require" signature policy, so remember the error.
+	settings, settingsErr := pluginsettings.LoadStrict(sysDir)
+	if settingsErr != nil {
 		settings = pluginsettings.Default()
 	}
 	base := pluginsettings.EffectiveSourceBase(settings)
-	return &Manager{rootDir: rootDir, sourceBase: base}, nil
+	return &Manager{
+		rootDir:         rootDir,
+		sourceBase:      base,
+		signaturePolicy: pluginsettings.EffectiveSignaturePolicy(settings),
+		trustedKeys:     settings.TrustedKeys,
+		settingsErr:     settingsErr,
+	}, nil
+}
+
+// SilenceSignatureWarning suppresses the "verify without trusted keys" warning, for index
+// loads that only serve help and suggestions.
+func (m *Manager) SilenceSignatureWarning() {
+	m.warnedNoTrustedKeys = true
 }
 
 func (m *Manager) ApplySourceBaseOverride(raw string) error {
@@ -107,9 +127,8 @@ func (m *Manager) ApplySourceBaseOverride(raw string) error {
 	if v == "" {
 		return fmt.Errorf("source-base must not be empty")
 	}
-	lower := strings.ToLower(v)
-	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
-		return fmt.Errorf("source-base must start with http:// or https://")
+	if !pluginsettings.IsSupportedSourceBase(v) {
+		return fmt.Errorf("source-base must start with http://, https:// or file://")
 	}
 	m.sourceBase = strings.TrimRight(v, "/")
 	m.skipPluginIndexCacheForCLI = true
@@ -121,7 +140,7 @@ func (m *Manager) resolvedPkgIndexURL() string {
 		return m.indexURL
 	}
 	if b := strings.TrimRight(strings.TrimSpace(m.sourceBase), "/"); b != "" {
-		return b + "/plugin_pkg_index.json"
+		return b + "/" + pkgIndexFileName
 	}
 	return IndexURL
 }
@@ -131,7 +150,7 @@ func (m *Manager) resolvedCommandIndexURL() string {
 		return m.commandIndexURL
 	}
 	if b := strings.TrimRight(strings.TrimSpace(m.sourceBase), "/"); b != "" {
-		return b + "/plugin_search_index.json"
+		return b + "/" + commandIndexFileName
 	}
 	return CommandIndexURL
 }
@@ -153,7 +172,7 @@ func (m *Manager) resolvePackageDownloadURL(origURL, pluginName, version string)
 	return fmt.Sprintf("%s/pkgs/%s/%s/%s", b, pluginName, version, baseName)
 }
 
-func (m *Manager) readCache(cacheFile string, ttl time.Duration, result interface{}) (hit bool, staleAvailable bool) {
+func (m *Manager) readCache(cacheFile string, ttl time.Duration, result interface{}, verify signatureCheck) (hit bool, staleAvailable bool) {
 	info, err := os.Stat(cacheFile)
 	if err != nil {
 		return false, false
@@ -162,6 +181,12 @@ func (m *Manager) readCache(cacheFile string, ttl time.Duration, result interfac
 	if err != nil {
 		return false, false
 	}
+	if verify != nil {
+		sig, _ := os.ReadFile(cacheFile + signatureSuffix)
+		if verify(data, sig) != nil {
+			return false, false
+		}
+	}
 	if err := json.Unmarshal(data, result); err != nil {
 		return false, false
 	}
@@ -171,12 +196,17 @@ func (m *Manager) readCache(cacheFile string, ttl time.Duration, result interfac
 	return false, true
 }
 
-func (m *Manager) writeCache(cacheFile string, data []byte) {
+func (m *Manager) writeCache(cacheFile string, data, sig []byte) {
 	_ = os.WriteFile(cacheFile, data, 0644)
+	if sig != nil {
+		_ = os.WriteFile(cacheFile+signatureSuffix, sig, 0644)
+	} else {
+		_ = os.Remove(cacheFile + signatureSuffix)
+	}
 }
 
 func httpGet(url string, timeout time.Duration) (*http.Response, error) {
-	client := &http.Client{Timeout: timeout}
+	client := &http.Client{Timeout: timeout, Transport: pluginTransport}
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
@@ -207,24 +237,39 @@ func (m *Manager) skipPluginIndexCache() bool {
 	return m.skipPluginIndexCacheForCLI
 }
 
-func (m *Manager) fetchWithCache(url, cacheFile string, result interface{}) error {
+// fetchVerified fetches url and, when verify is set, checks it against its detached signature.
+func (m *Manager) fetchVerified(url string, verify signatureCheck) (data, sig []byte, err error) {
+	data, err = m.fetchRemote(url)
+	if err != nil || verify == nil {
+		return data, nil, err
+	}
+	if sig, err = fetchSignature(url); err != nil {
+		return nil, nil, err
+	}
+	if err := verify(data, sig); err != nil {
+		return nil, nil, err
+	}
+	return data, sig, nil
+}
+
+func (m *Manager) fetchWithCache(url, cacheFile string, result interface{}, verify signatureCheck) error {
 	if noCacheEnabled() || m.skipPluginIndexCache() {
-		data, err := m.fetchRemote(url)
+		data, _, err := m.fetchVerified(url, verify)
 		if err != nil {
 			return err
 		}
 		return json.Unmarshal(data, result)
 	}
 
-	hit, staleAvailable := m.readCache(cacheFile, cacheTTL, result)
+	hit, staleAvailable := m.readCache(cacheFile, cacheTTL, result, verify)
 	if hit {
 		return nil
 	}
 
-	data, fetchErr := m.fetchRemote(url)
+	data, sig, fetchErr := m.fetchVerified(url, verify)
 	if fetchErr == nil {
 		if decErr := json.Unmarshal(data, result); decErr == nil {
-			m.writeCache(cacheFile, data)
+			m.writeCache(cacheFile, data, sig)
 			return nil
 		} else {
 			fetchErr = fmt.Errorf("failed to decode: %w", decErr)
@@ -241,8 +286,12 @@ func (m *Manager) fetchWithCache(url, cacheFile string, result interface{}) erro
 func (m *Manager) GetIndex() (*Index, error) {
 	indexURL := m.resolvedPkgIndexURL()
 	cacheFile := filepath.Join(m.rootDir, indexCacheFile)
+	verify, err := m.indexSignatureCheck("plugin index")
+	if err != nil {
+		return nil, err
+	}
 	var index Index
-	if err := m.fetchWithCache(indexURL, cacheFile, &index); err != nil {
+	if err := m.fetchWithCache(indexURL, cacheFile, &index, verify); err != nil {
 		return nil, fmt.Errorf("failed to fetch plugin index: %w", err)
 	}
 	return &index, nil
@@ -251,8 +300,12 @@ func (m *Manager) GetIndex() (*Index, error) {
 func (m *Manager) GetCommandIndex() (*CommandIndex, error) {
 	commandIndexURL := m.resolvedCommandIndexURL()
 	cacheFile := filepath.Join(m.rootDir, commandCacheFile)
+	verify, err := m.indexSignatureCheck("command index")
+	if err != nil {
+		return nil, err
+	}
 	var index CommandIndex
-	if err := m.fetchWithCache(commandIndexURL, cacheFile, &index); err != nil {
+	if err := m.fetchWithCache(commandIndexURL, cacheFile, &index, verify); err != nil {
 		return nil, fmt.Errorf("failed to fetch command index: %w", err)
 	}
 	return &index, nil
@@ -696,6 +749,12 @@ func (m *Manager) downloadAndVerifyPlugin(ctx *cli.Context, platInfo *PlatformIn
 			actualPluginName, version, platInfo.Checksum, actualChecksum,
 		)
 	}
+
+	subject := fmt.Sprintf("plugin %s version %s", actualPluginName, version)
+	if err := m.verifyPackageSignature(ctx, subject, actualChecksum, platInfo.URL); err != nil {
+		os.RemoveAll(tmpDir)
+		return "", err
+	}
 	return archivePath, nil
 }
 
@@ -918,6 +977,14 @@ func (m *Manager) installFromPackageFile(ctx *cli.Context, absPath, userFacing s
 
 	cli.Printf(ctx.Stdout(), "Installing plugin from %s...\n", userFacing)
 
+	checksum, err := calculateSHA256(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to calculate checksum: %w", err)
+	}
+	if err := m.verifyPackageSignature(ctx, "plugin package "+userFacing, checksum, userFacing); err != nil {
+		return err
+	}
+
 	tmpParent, err := os.MkdirTemp("", "aliyun-plugin-local-*")
 	if err != nil {
 		return err
@@ -1011,6 +1078,11 @@ func (m *Manager) installPlugin(ctx *cli.Context, targetPlugin *PluginInfo, vers
 		return err
 	}
 
+	return m.installPlatformPackage(ctx, actualPluginName, version, platInfo, warnIfAlreadyInstalled)
+}
+
+// installPlatformPackage downloads the package described by platInfo, verifies its checksum and installs it.
+func (m *Manager) installPlatformPackage(ctx *cli.Context, actualPluginName, version string, platInfo *PlatformInfo, warnIfAlreadyInstalled bool) error {
 	downloadURL := m.resolvePackageDownloadURL(platInfo.URL, actualPluginName, version)
 	platForDownload := *platInfo
 	platForDownload.URL = downloadURL
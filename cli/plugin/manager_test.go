@@ -60,6 +60,24 @@ func TestNewManager_LoadsSourceBaseFromFile(t *testing.T) {
 	assert.Equal(t, "https://x.example/plugins", mgr.sourceBase)
 }
 
+func TestNewManager_InvalidSettingsFailsSignatureChecks(t *testing.T) {
+	home := t.TempDir()
+	cleanup := setTestHomeDir(t, home)
+	defer cleanup()
+	confDir := filepath.Join(home, ".aliyun")
+	assert.NoError(t, os.MkdirAll(confDir, 0755))
+	data := []byte(`{"signature_policy":"require",`)
+	assert.NoError(t, os.WriteFile(filepath.Join(confDir, pluginsettings.ConfigFileName), data, 0600))
+	// 设置文件损坏时已安装的插件仍可使用
+	mgr, err := NewManager()
+	assert.NoError(t, err)
+	_, err = mgr.GetLocalManifest()
+	assert.NoError(t, err)
+	// 但不能退回默认值悄悄放宽 require 签名策略
+	_, err = mgr.signatureVerifier()
+	assert.ErrorContains(t, err, "cannot check plugin signatures: invalid plugin settings file")
+}
+
 func TestManager_ApplySourceBaseOverride(t *testing.T) {
 	m := &Manager{}
 	assert.ErrorContains(t, m.ApplySourceBaseOverride(""), "must not be empty")
@@ -0,0 +1,380 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+)
+
+const (
+	pkgIndexFileName     = "plugin_pkg_index.json"
+	commandIndexFileName = "plugin_search_index.json"
+)
+
+// pluginTransport is used for index and package downloads; besides http(s) it serves file:// URLs
+// so that a directory created by `aliyun plugin mirror` can be used directly as a source base.
+var pluginTransport = newPluginTransport()
+
+func newPluginTransport() http.RoundTripper {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.RegisterProtocol("file", fileTransport{})
+	return t
+}
+
+type fileTransport struct{}
+
+func (fileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	p, err := fileURLToPath(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	resp := &http.Response{
+		Proto:      "HTTP/1.0",
+		ProtoMajor: 1,
+		Header:     make(http.Header),
+		Request:    req,
+	}
+	f, err := os.Open(p)
+	if err == nil {
+		info, statErr := f.Stat()
+		if statErr == nil && !info.IsDir() {
+			resp.StatusCode = http.StatusOK
+			resp.ContentLength = info.Size()
+			resp.Body = f
+			resp.Status = "200 OK"
+			return resp, nil
+		}
+		f.Close()
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	resp.StatusCode = http.StatusNotFound
+	resp.Status = "404 Not Found"
+	resp.Body = io.NopCloser(strings.NewReader(""))
+	return resp, nil
+}
+
+func fileURLToPath(u *url.URL) (string, error) {
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("file URL with host %q is not supported", u.Host)
+	}
+	p := u.Path
+	// file:///C:/plugins -> C:/plugins
+	if runtime.GOOS == "windows" && len(p) >= 3 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+	return filepath.FromSlash(p), nil
+}
+
+func pathToFileURL(absPath string) string {
+	p := filepath.ToSlash(absPath)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
+
+type MirrorOptions struct {
+	Dest      string
+	Names     []string // empty = every plugin in the index
+	Platforms []string // empty = every platform offered
+	EnablePre bool
+}
+
+// writeFileAtomic writes data to a temporary file next to dest and renames it into place.
+func writeFileAtomic(dest string, data []byte) error {
+	tmp := dest + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// writeSignatureFile stores sig next to path, removing a stale signature when sig is nil.
+func writeSignatureFile(path string, sig []byte) error {
+	if sig == nil {
+		if err := os.Remove(path + signatureSuffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return writeFileAtomic(path+signatureSuffix, sig)
+}
+
+// mirrorPackagePath returns {dest}/pkgs/{name}/{version}/{baseName}. The segments come from
+// the remote index, so any that could leave dest is refused.
+func mirrorPackagePath(dest, name, version, baseName string) (string, error) {
+	for _, segment := range []string{name, version, baseName} {
+		if segment == "" || segment == "." || strings.Contains(segment, "..") || strings.ContainsAny(segment, `/\`) {
+			return "", fmt.Errorf("unsafe path segment %q", segment)
+		}
+	}
+	target := filepath.Join(dest, "pkgs", name, version, baseName)
+	rel, err := filepath.Rel(dest, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return "", fmt.Errorf("%s is outside %s", target, dest)
+	}
+	return target, nil
+}
+
+// mirrorPackage stores the package at dest, reusing an existing file whose checksum already matches.
+func mirrorPackage(downloadURL, checksum, dest string) (reused bool, err error) {
+	if actual, err := calculateSHA256(dest); err == nil && actual == checksum {
+		return true, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return false, err
+	}
+	tmp := dest + ".tmp"
+	defer os.Remove(tmp)
+	if err := downloadFile(downloadURL, tmp); err != nil {
+		return false, fmt.Errorf("download %s: %w", downloadURL, err)
+	}
+	actual, err := calculateSHA256(tmp)
+	if err != nil {
+		return false, fmt.Errorf("failed to calculate checksum: %w", err)
+	}
+	if actual != checksum {
+		return false, fmt.Errorf(
+			"checksum verification failed for %s\n"+
+				"Expected: %s\n"+
+				"Actual:   %s",
+			downloadURL, checksum, actual,
+		)
+	}
+	return false, os.Rename(tmp, dest)
+}
+
+func selectPlatforms(available map[string]PlatformInfo, wanted []string) map[string]PlatformInfo {
+	selected := make(map[string]PlatformInfo)
+	for platform, info := range available {
+		if len(wanted) == 0 {
+			selected[platform] = info
+			continue
+		}
+		for _, w := range wanted {
+			if w == platform {
+				selected[platform] = info
+				break
+			}
+		}
+	}
+	return selected
+}
+
+// Mirror downloads the index, the command index and the latest package of the selected plugins
+// into opts.Dest using the source base layout:
+//
+//	{Dest}/plugin_pkg_index.json
+//	{Dest}/plugin_search_index.json
+//	{Dest}/pkgs/{name}/{version}/{filename}
+//
+// Every package is verified against the index checksum; installing from the mirror verifies it again.
+func (m *Manager) Mirror(ctx *cli.Context, opts MirrorOptions) error {
+	dest, err := filepath.Abs(opts.Dest)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+
+	verify, err := m.indexSignatureCheck("plugin index")
+	if err != nil {
+		return err
+	}
+	indexData, indexSig, err := m.fetchVerified(m.resolvedPkgIndexURL(), verify)
+	if err != nil {
+		return fmt.Errorf("failed to fetch plugin index: %w", err)
+	}
+	if indexSig == nil && verify == nil {
+		// Keep the upstream signature so the mirror also works on hosts that verify signatures.
+		if indexSig, err = fetchSignature(m.resolvedPkgIndexURL()); err != nil {
+			return err
+		}
+	}
+	var index Index
+	if err := json.Unmarshal(indexData, &index); err != nil {
+		return fmt.Errorf("failed to decode plugin index: %w", err)
+	}
+
+	explicit := len(opts.Names) > 0
+	var selected []*PluginInfo
+	if explicit {
+		for _, name := range opts.Names {
+			var found *PluginInfo
+			for i := range index.Plugins {
+				if matchPluginName(index.Plugins[i].Name, name) {
+					found = &index.Plugins[i]
+					break
+				}
+			}
+			if found == nil {
+				return fmt.Errorf("plugin %s not found", name)
+			}
+			selected = append(selected, found)
+		}
+	} else {
+		for i := range index.Plugins {
+			selected = append(selected, &index.Plugins[i])
+		}
+	}
+
+	mirrored := &Index{Plugins: []PluginInfo{}}
+	mirroredNames := make(map[string]bool)
+	var downloaded, reused int
+	for _, p := range selected {
+		if mirroredNames[p.Name] {
+			continue
+		}
+		version, err := getLatestVersion(p, opts.EnablePre)
+		if err != nil {
+			if explicit {
+				return err
+			}
+			cli.Printf(ctx.Stdout(), "Skipping %s: %v\n", p.Name, err)
+			continue
+		}
+		verInfo := p.Versions[version]
+		platforms := selectPlatforms(verInfo.Platforms, opts.Platforms)
+		if len(platforms) == 0 {
+			if explicit {
+				return fmt.Errorf("plugin %s version %s has no package for %s", p.Name, version, strings.Join(opts.Platforms, ", "))
+			}
+			cli.Printf(ctx.Stdout(), "Skipping %s %s (no package for the selected platforms)\n", p.Name, version)
+			continue
+		}
+
+		platformNames := make([]string, 0, len(platforms))
+		for platform := range platforms {
+			platformNames = append(platformNames, platform)
+		}
+		sort.Strings(platformNames)
+
+		fileChecksums := make(map[string]string)
+		for _, platform := range platformNames {
+			info := platforms[platform]
+			u, err := url.Parse(info.URL)
+			if err != nil {
+				return fmt.Errorf("invalid package URL of plugin %s %s for %s: %w", p.Name, version, platform, err)
+			}
+			baseName := path.Base(u.Path)
+			if baseName == "" || baseName == "." || baseName == "/" {
+				return fmt.Errorf("invalid package URL of plugin %s %s for %s: %s", p.Name, version, platform, info.URL)
+			}
+			if sum, ok := fileChecksums[baseName]; ok {
+				if sum != info.Checksum {
+					return fmt.Errorf("packages of plugin %s %s share the file name %s but have different checksums", p.Name, version, baseName)
+				}
+				continue
+			}
+			fileChecksums[baseName] = info.Checksum
+
+			target, err := mirrorPackagePath(dest, p.Name, version, baseName)
+			if err != nil {
+				return fmt.Errorf("invalid package path of plugin %q %q for %s: %w", p.Name, version, platform, err)
+			}
+			cli.Printf(ctx.Stdout(), "Mirroring %s %s (%s)...\n", p.Name, version, platform)
+			downloadURL := m.resolvePackageDownloadURL(info.URL, p.Name, version)
+			wasReused, err := mirrorPackage(downloadURL, info.Checksum, target)
+			if err != nil {
+				return fmt.Errorf("failed to mirror plugin %s %s for %s: %w", p.Name, version, platform, err)
+			}
+			sig, err := fetchSignature(downloadURL)
+			if err != nil {
+				return err
+			}
+			subject := fmt.Sprintf("plugin %s version %s (%s)", p.Name, version, platform)
+			if err := m.checkPackageSignature(ctx, subject, info.Checksum, sig); err != nil {
+				return err
+			}
+			if err := writeSignatureFile(target, sig); err != nil {
+				return err
+			}
+			if wasReused {
+				reused++
+			} else {
+				downloaded++
+			}
+		}
+
+		mirroredPlugin := *p
+		mirroredPlugin.Versions = map[string]VersionInfo{
+			version: {Metadata: verInfo.Metadata, Platforms: platforms},
+		}
+		mirrored.Plugins = append(mirrored.Plugins, mirroredPlugin)
+		mirroredNames[p.Name] = true
+	}
+
+	commandVerify, err := m.indexSignatureCheck("command index")
+	if err != nil {
+		return err
+	}
+	commandData, commandSig, err := m.fetchVerified(m.resolvedCommandIndexURL(), commandVerify)
+	if err != nil {
+		return fmt.Errorf("failed to fetch command index: %w", err)
+	}
+	if commandSig == nil && commandVerify == nil {
+		if commandSig, err = fetchSignature(m.resolvedCommandIndexURL()); err != nil {
+			return err
+		}
+	}
+	var commandIndex CommandIndex
+	if err := json.Unmarshal(commandData, &commandIndex); err != nil {
+		return fmt.Errorf("failed to decode command index: %w", err)
+	}
+	// Like the package index, a signed command index is kept unfiltered.
+	if commandSig == nil {
+		mirroredCommands := make(CommandIndex)
+		for command, pluginName := range commandIndex {
+			if mirroredNames[pluginName] {
+				mirroredCommands[command] = pluginName
+			}
+		}
+		if commandData, err = json.MarshalIndent(mirroredCommands, "", "  "); err != nil {
+			return err
+		}
+	}
+	if err := writeFileAtomic(filepath.Join(dest, commandIndexFileName), commandData); err != nil {
+		return err
+	}
+	if err := writeSignatureFile(filepath.Join(dest, commandIndexFileName), commandSig); err != nil {
+		return err
+	}
+	// The package index is written last so an interrupted mirror never lists missing packages.
+	// A signed index is kept unfiltered since filtering would invalidate its signature; plugins
+	// that were not mirrored then fail to download from the mirror.
+	if indexSig == nil {
+		if indexData, err = json.MarshalIndent(mirrored, "", "  "); err != nil {
+			return err
+		}
+	} else {
+		cli.Printf(ctx.Stdout(), "The plugin index is signed, keeping it unfiltered.\n")
+	}
+	if err := writeFileAtomic(filepath.Join(dest, pkgIndexFileName), indexData); err != nil {
+		return err
+	}
+	if err := writeSignatureFile(filepath.Join(dest, pkgIndexFileName), indexSig); err != nil {
+		return err
+	}
+
+	cli.Printf(ctx.Stdout(), "Mirrored %d plugin(s) to %s (downloaded: %d, up to date: %d)\n", len(mirrored.Plugins), dest, downloaded, reused)
+	cli.Printf(ctx.Stdout(), "Serve the directory with any static file server, or use it directly:\n")
+	cli.Printf(ctx.Stdout(), "  aliyun plugin install --source-base %s --name <plugin_name>\n", pathToFileURL(dest))
+	cli.Printf(ctx.Stdout(), "  aliyun configure plugin-settings set --source-base %s\n", pathToFileURL(dest))
+	return nil
+}
@@ -0,0 +1,189 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileTransport(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "index.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"plugins":[]}`), 0644))
+
+	base := pathToFileURL(dir)
+	data, err := (&Manager{}).fetchRemote(base + "/index.json")
+	require.NoError(t, err)
+	assert.Equal(t, `{"plugins":[]}`, string(data))
+
+	_, err = (&Manager{}).fetchRemote(base + "/missing.json")
+	assert.EqualError(t, err, "status 404")
+
+	resp, err := httpGet(base, fetchTimeout)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	_, err = httpGet("file://remote-host/share/index.json", fetchTimeout)
+	assert.ErrorContains(t, err, `file URL with host "remote-host" is not supported`)
+}
+
+func TestPathToFileURL(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		assert.Equal(t, "file:///C:/plugins", pathToFileURL(`C:\plugins`))
+		u, _ := url.Parse("file:///C:/plugins")
+		p, err := fileURLToPath(u)
+		require.NoError(t, err)
+		assert.Equal(t, `C:\plugins`, p)
+		return
+	}
+	assert.Equal(t, "file:///opt/aliyun%20plugins", pathToFileURL("/opt/aliyun plugins"))
+	u, _ := url.Parse("file:///opt/aliyun%20plugins")
+	p, err := fileURLToPath(u)
+	require.NoError(t, err)
+	assert.Equal(t, "/opt/aliyun plugins", p)
+}
+
+func TestManager_Mirror(t *testing.T) {
+	server, _ := newLockTestServer(t)
+	platform := GetCurrentPlatform()
+
+	t.Run("mirror and install from file source base", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "mirror")
+		mgr := newLockTestManager(t, server)
+		ctx := newTestContext()
+		require.NoError(t, mgr.Mirror(ctx, MirrorOptions{Dest: dest, Platforms: []string{platform}}))
+		out := ctx.Stdout().(*bytes.Buffer).String()
+		assert.Contains(t, out, "Mirrored 2 plugin(s)")
+		assert.Contains(t, out, "--source-base "+pathToFileURL(dest))
+
+		assert.FileExists(t, filepath.Join(dest, "pkgs", "lock-a", "1.1.0", "lock-a.tar.gz"))
+		assert.NoFileExists(t, filepath.Join(dest, "pkgs", "lock-a", "1.0.0", "lock-a.tar.gz"))
+
+		var index Index
+		data, err := os.ReadFile(filepath.Join(dest, pkgIndexFileName))
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &index))
+		require.Len(t, index.Plugins, 2)
+		assert.Len(t, index.Plugins[0].Versions, 1)
+		assert.Len(t, index.Plugins[0].Versions["1.1.0"].Platforms, 1)
+
+		var commands CommandIndex
+		data, err = os.ReadFile(filepath.Join(dest, commandIndexFileName))
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &commands))
+		assert.Equal(t, CommandIndex{"a": "lock-a", "b": "lock-b"}, commands)
+
+		// 再次镜像时复用校验和一致的安装包
+		ctx = newTestContext()
+		require.NoError(t, mgr.Mirror(ctx, MirrorOptions{Dest: dest, Platforms: []string{platform}}))
+		assert.Contains(t, ctx.Stdout().(*bytes.Buffer).String(), "downloaded: 0, up to date: 2")
+
+		offline := &Manager{rootDir: t.TempDir()}
+		require.NoError(t, offline.ApplySourceBaseOverride(pathToFileURL(dest)))
+		require.NoError(t, offline.Install(newTestContext(), "lock-a", "", false))
+		manifest, err := offline.GetLocalManifest()
+		require.NoError(t, err)
+		assert.Equal(t, "1.1.0", manifest.Plugins["lock-a"].Version)
+
+		// 安装时再次校验镜像中的安装包
+		pkg := filepath.Join(dest, "pkgs", "lock-b", "2.0.0", "lock-b.tar.gz")
+		require.NoError(t, os.WriteFile(pkg, []byte("tampered"), 0644))
+		err = offline.Install(newTestContext(), "lock-b", "", false)
+		assert.ErrorContains(t, err, "checksum verification failed")
+	})
+
+	t.Run("selected names", func(t *testing.T) {
+		dest := t.TempDir()
+		mgr := newLockTestManager(t, server)
+		require.NoError(t, mgr.Mirror(newTestContext(), MirrorOptions{Dest: dest, Names: []string{"lock-b"}, Platforms: []string{platform}}))
+		var index Index
+		data, err := os.ReadFile(filepath.Join(dest, pkgIndexFileName))
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &index))
+		require.Len(t, index.Plugins, 1)
+		assert.Equal(t, "lock-b", index.Plugins[0].Name)
+
+		err = mgr.Mirror(newTestContext(), MirrorOptions{Dest: dest, Names: []string{"lock-b"}, Platforms: []string{"plan9-386"}})
+		assert.EqualError(t, err, "plugin lock-b version 2.0.0 has no package for plan9-386")
+		err = mgr.Mirror(newTestContext(), MirrorOptions{Dest: dest, Names: []string{"missing"}})
+		assert.EqualError(t, err, "plugin missing not found")
+	})
+
+	t.Run("failed download leaves no index", func(t *testing.T) {
+		dest := t.TempDir()
+		mgr := newLockTestManager(t, server)
+		// other-platform 的安装包在服务端不存在
+		err := mgr.Mirror(newTestContext(), MirrorOptions{Dest: dest, Names: []string{"lock-a"}})
+		assert.ErrorContains(t, err, "failed to mirror plugin lock-a 1.1.0 for other-platform")
+		assert.NoFileExists(t, filepath.Join(dest, pkgIndexFileName))
+	})
+
+	t.Run("unsafe name or version in index", func(t *testing.T) {
+		server, index := newLockTestServer(t)
+		valid := index.Plugins[1].Versions["2.0.0"]
+		for _, p := range []PluginInfo{
+			{Name: "../../evil", Versions: map[string]VersionInfo{"2.0.0": valid}},
+			{Name: "lock-b", Versions: map[string]VersionInfo{"..": valid}},
+			{Name: `lock-b\..`, Versions: map[string]VersionInfo{"2.0.0": valid}},
+		} {
+			index.Plugins = []PluginInfo{p}
+			root := t.TempDir()
+			dest := filepath.Join(root, "mirror")
+			mgr := newLockTestManager(t, server)
+			err := mgr.Mirror(newTestContext(), MirrorOptions{Dest: dest, Platforms: []string{platform}})
+			assert.ErrorContains(t, err, "invalid package path of plugin")
+			assert.ErrorContains(t, err, "unsafe path segment")
+			// 不会在镜像目录之外写入文件
+			entries, err := os.ReadDir(root)
+			require.NoError(t, err)
+			assert.Len(t, entries, 1)
+			assert.NoFileExists(t, filepath.Join(dest, pkgIndexFileName))
+		}
+	})
+}
+
+func TestMirrorPackagePath(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "mirror")
+	target, err := mirrorPackagePath(dest, "plugin-fc", "1.0.0-beta.1", "plugin-fc.tar.gz")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dest, "pkgs", "plugin-fc", "1.0.0-beta.1", "plugin-fc.tar.gz"), target)
+
+	for _, segments := range [][3]string{
+		{"", "1.0.0", "a.tar.gz"},
+		{".", "1.0.0", "a.tar.gz"},
+		{"..", "1.0.0", "a.tar.gz"},
+		{"a/b", "1.0.0", "a.tar.gz"},
+		{"a", `1.0.0\..\..`, "a.tar.gz"},
+		{"a", "1..0", "a.tar.gz"},
+		{"a", "1.0.0", ".."},
+	} {
+		_, err := mirrorPackagePath(dest, segments[0], segments[1], segments[2])
+		assert.ErrorContains(t, err, "unsafe path segment", "%q", segments)
+	}
+}
+
+func TestNewMirrorCommand_Run(t *testing.T) {
+	testHome := t.TempDir()
+	cleanup := setTestHomeDir(t, testHome)
+	defer cleanup()
+
+	cmd := newMirrorCommand()
+	ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+	ctx.EnterCommand(cmd)
+	err := cmd.Run(ctx, []string{})
+	assert.EqualError(t, err, "--dest is required")
+
+	assert.NotNil(t, cmd.Flags().Get("platforms"))
+	assert.NotNil(t, cmd.Flags().Get("names"))
+	assert.NotNil(t, cmd.Flags().Get("source-base"))
+}
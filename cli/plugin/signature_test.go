@@ -0,0 +1,332 @@
+package plugin
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/pluginsettings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSigner struct {
+	pub  ed25519.PublicKey
+	priv ed25519.PrivateKey
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return &testSigner{pub: pub, priv: priv}
+}
+
+func (s *testSigner) key() string {
+	return base64.StdEncoding.EncodeToString(s.pub)
+}
+
+func (s *testSigner) sign(data []byte) []byte {
+	digest := sha256.Sum256(data)
+	sig := ed25519.Sign(s.priv, digest[:])
+	return []byte("# signed for tests\n" + signatureKeyID(s.pub) + " " + base64.StdEncoding.EncodeToString(sig) + "\n")
+}
+
+func TestSignatureVerifier_verifyDigest(t *testing.T) {
+	signer := newTestSigner(t)
+	other := newTestSigner(t)
+	data := []byte("package")
+	digest := sha256.Sum256(data)
+
+	mgr := &Manager{signaturePolicy: pluginsettings.SignaturePolicyVerify, trustedKeys: []string{signer.key()}}
+	v, err := mgr.signatureVerifier()
+	require.NoError(t, err)
+
+	signed, err := v.verifyDigest("pkg", digest[:], signer.sign(data))
+	assert.NoError(t, err)
+	assert.True(t, signed)
+
+	// 同时由不受信任的密钥签名时忽略该行
+	both := append(other.sign(data), signer.sign(data)...)
+	signed, err = v.verifyDigest("pkg", digest[:], both)
+	assert.NoError(t, err)
+	assert.True(t, signed)
+
+	_, err = v.verifyDigest("pkg", digest[:], signer.sign([]byte("tampered")))
+	assert.ErrorContains(t, err, "signature verification failed for pkg")
+
+	signed, err = v.verifyDigest("pkg", digest[:], nil)
+	assert.NoError(t, err)
+	assert.False(t, signed)
+	signed, err = v.verifyDigest("pkg", digest[:], other.sign(data))
+	assert.NoError(t, err)
+	assert.False(t, signed)
+
+	mgr.signaturePolicy = pluginsettings.SignaturePolicyRequire
+	v, err = mgr.signatureVerifier()
+	require.NoError(t, err)
+	_, err = v.verifyDigest("pkg", digest[:], other.sign(data))
+	assert.ErrorContains(t, err, `pkg is not signed by a trusted key, refusing it because the plugin signature policy is "require"`)
+
+	mgr.signaturePolicy = pluginsettings.SignaturePolicyOff
+	v, err = mgr.signatureVerifier()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+	mgr.signaturePolicy = pluginsettings.SignaturePolicyVerify
+	mgr.trustedKeys = []string{"not-a-key"}
+	_, err = mgr.signatureVerifier()
+	assert.ErrorContains(t, err, "invalid trusted key 'not-a-key'")
+}
+
+func TestSignatureVerifier_WarnsWithoutTrustedKeys(t *testing.T) {
+	out := new(bytes.Buffer)
+	origin := signatureWarningOut
+	signatureWarningOut = out
+	t.Cleanup(func() { signatureWarningOut = origin })
+
+	mgr := &Manager{signaturePolicy: pluginsettings.SignaturePolicyVerify}
+	v, err := mgr.signatureVerifier()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+	assert.Contains(t, out.String(), `WARNING: the plugin signature policy is "verify" but no trusted key is configured`)
+
+	// 同一命令中只提示一次
+	out.Reset()
+	_, err = mgr.signatureVerifier()
+	require.NoError(t, err)
+	assert.Empty(t, out.String())
+
+	// 默认策略 off 不提示
+	_, err = (&Manager{}).signatureVerifier()
+	require.NoError(t, err)
+	assert.Empty(t, out.String())
+
+	// 仅用于帮助信息的索引加载不提示
+	quiet := &Manager{signaturePolicy: pluginsettings.SignaturePolicyVerify}
+	quiet.SilenceSignatureWarning()
+	v, err = quiet.signatureVerifier()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+	assert.Empty(t, out.String())
+}
+
+// newSignedSourceServer serves files from a map; paths ending in .sig that are absent return 404.
+func newSignedSourceServer(t *testing.T, files map[string][]byte) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestManager_GetIndex_Signature(t *testing.T) {
+	signer := newTestSigner(t)
+	indexData, _ := json.Marshal(Index{Plugins: []PluginInfo{{Name: "signed-plugin"}}})
+	files := map[string][]byte{
+		"/plugin_pkg_index.json":     indexData,
+		"/plugin_pkg_index.json.sig": signer.sign(indexData),
+	}
+	server := newSignedSourceServer(t, files)
+
+	newMgr := func(rootDir string) *Manager {
+		return &Manager{
+			rootDir:         rootDir,
+			indexURL:        server.URL + "/plugin_pkg_index.json",
+			signaturePolicy: pluginsettings.SignaturePolicyRequire,
+			trustedKeys:     []string{signer.key()},
+		}
+	}
+
+	rootDir := t.TempDir()
+	index, err := newMgr(rootDir).GetIndex()
+	require.NoError(t, err)
+	assert.Equal(t, "signed-plugin", index.Plugins[0].Name)
+	assert.FileExists(t, filepath.Join(rootDir, indexCacheFile+signatureSuffix))
+
+	// 缓存同样需要通过签名校验
+	require.NoError(t, os.WriteFile(filepath.Join(rootDir, indexCacheFile), []byte(`{"plugins":[{"name":"evil"}]}`), 0644))
+	index, err = newMgr(rootDir).GetIndex()
+	require.NoError(t, err)
+	assert.Equal(t, "signed-plugin", index.Plugins[0].Name)
+
+	files["/plugin_pkg_index.json"] = []byte(`{"plugins":[{"name":"evil"}]}`)
+	_, err = newMgr(t.TempDir()).GetIndex()
+	assert.ErrorContains(t, err, "signature verification failed for plugin index")
+
+	delete(files, "/plugin_pkg_index.json.sig")
+	_, err = newMgr(t.TempDir()).GetIndex()
+	assert.ErrorContains(t, err, "plugin index is not signed by a trusted key")
+
+	unverified := newMgr(t.TempDir())
+	unverified.signaturePolicy = pluginsettings.SignaturePolicyVerify
+	index, err = unverified.GetIndex()
+	require.NoError(t, err)
+	assert.Equal(t, "evil", index.Plugins[0].Name)
+}
+
+func TestManager_GetCommandIndex_Signature(t *testing.T) {
+	signer := newTestSigner(t)
+	indexData := []byte(`{"fc":"aliyun-cli-fc"}`)
+	files := map[string][]byte{
+		"/plugin_search_index.json":     indexData,
+		"/plugin_search_index.json.sig": signer.sign(indexData),
+	}
+	server := newSignedSourceServer(t, files)
+	newMgr := func() *Manager {
+		return &Manager{
+			rootDir:         t.TempDir(),
+			commandIndexURL: server.URL + "/plugin_search_index.json",
+			signaturePolicy: pluginsettings.SignaturePolicyRequire,
+			trustedKeys:     []string{signer.key()},
+		}
+	}
+
+	index, err := newMgr().GetCommandIndex()
+	require.NoError(t, err)
+	assert.Equal(t, "aliyun-cli-fc", (*index)["fc"])
+
+	// 命令索引被替换时，不能把命令引向其他插件
+	files["/plugin_search_index.json"] = []byte(`{"fc":"evil-plugin"}`)
+	_, err = newMgr().GetCommandIndex()
+	assert.ErrorContains(t, err, "signature verification failed for command index")
+
+	delete(files, "/plugin_search_index.json.sig")
+	_, err = newMgr().GetCommandIndex()
+	assert.ErrorContains(t, err, "command index is not signed by a trusted key")
+}
+
+func TestManager_installPlugin_Signature(t *testing.T) {
+	signer := newTestSigner(t)
+	archive := createTestPluginArchive(t, "sig-plugin", "1.0.0", "sig")
+	checksum, err := calculateSHA256FromBytes(archive)
+	require.NoError(t, err)
+	files := map[string][]byte{
+		"/pkgs/sig-plugin.tar.gz":     archive,
+		"/pkgs/sig-plugin.tar.gz.sig": signer.sign(archive),
+	}
+	server := newSignedSourceServer(t, files)
+	targetPlugin := &PluginInfo{
+		Name: "sig-plugin",
+		Versions: map[string]VersionInfo{"1.0.0": {Platforms: map[string]PlatformInfo{
+			GetCurrentPlatform(): {URL: server.URL + "/pkgs/sig-plugin.tar.gz", Checksum: checksum},
+		}}},
+	}
+	newMgr := func(policy string) *Manager {
+		return &Manager{rootDir: t.TempDir(), signaturePolicy: policy, trustedKeys: []string{signer.key()}}
+	}
+
+	ctx := newTestContext()
+	require.NoError(t, newMgr(pluginsettings.SignaturePolicyRequire).installPlugin(ctx, targetPlugin, "1.0.0", false, false))
+	assert.Contains(t, ctx.Stdout().(*bytes.Buffer).String(), "Signature of plugin sig-plugin version 1.0.0 verified.")
+
+	// 镜像同时替换安装包和校验和时，签名校验失败
+	files["/pkgs/sig-plugin.tar.gz.sig"] = signer.sign([]byte("other"))
+	err = newMgr(pluginsettings.SignaturePolicyVerify).installPlugin(newTestContext(), targetPlugin, "1.0.0", false, false)
+	assert.ErrorContains(t, err, "signature verification failed for plugin sig-plugin version 1.0.0")
+
+	delete(files, "/pkgs/sig-plugin.tar.gz.sig")
+	err = newMgr(pluginsettings.SignaturePolicyRequire).installPlugin(newTestContext(), targetPlugin, "1.0.0", false, false)
+	assert.ErrorContains(t, err, "plugin sig-plugin version 1.0.0 is not signed by a trusted key")
+
+	ctx = newTestContext()
+	require.NoError(t, newMgr(pluginsettings.SignaturePolicyVerify).installPlugin(ctx, targetPlugin, "1.0.0", false, false))
+	assert.Contains(t, ctx.Stderr().(*bytes.Buffer).String(), "Warning: plugin sig-plugin version 1.0.0 is not signed by a trusted key.")
+}
+
+func TestManager_InstallFromLocalFile_Signature(t *testing.T) {
+	signer := newTestSigner(t)
+	archive := createTestPluginArchive(t, "local-sig", "1.0.0", "sig")
+	pkg := filepath.Join(t.TempDir(), "local-sig.tar.gz")
+	require.NoError(t, os.WriteFile(pkg, archive, 0644))
+	mgr := &Manager{rootDir: t.TempDir(), signaturePolicy: pluginsettings.SignaturePolicyRequire, trustedKeys: []string{signer.key()}}
+
+	err := mgr.InstallFromLocalFile(newTestContext(), pkg)
+	assert.ErrorContains(t, err, "is not signed by a trusted key")
+
+	require.NoError(t, os.WriteFile(pkg+signatureSuffix, signer.sign(archive), 0644))
+	ctx := newTestContext()
+	require.NoError(t, mgr.InstallFromLocalFile(ctx, pkg))
+	assert.Contains(t, ctx.Stdout().(*bytes.Buffer).String(), "verified")
+
+	require.NoError(t, os.WriteFile(pkg, append(archive, 0), 0644))
+	err = mgr.InstallFromLocalFile(newTestContext(), pkg)
+	assert.ErrorContains(t, err, "signature verification failed")
+}
+
+func TestManager_Mirror_Signature(t *testing.T) {
+	signer := newTestSigner(t)
+	server, index := newLockTestServer(t)
+	platform := GetCurrentPlatform()
+
+	// 为上游的索引和安装包生成签名
+	indexData, err := json.Marshal(index)
+	require.NoError(t, err)
+	files := map[string][]byte{"/plugin_pkg_index.json.sig": signer.sign(indexData)}
+	for _, p := range index.Plugins {
+		for _, v := range p.Versions {
+			info := v.Platforms[platform]
+			resp, err := http.Get(info.URL)
+			require.NoError(t, err)
+			var buf bytes.Buffer
+			_, _ = buf.ReadFrom(resp.Body)
+			resp.Body.Close()
+			files[strings.TrimPrefix(info.URL, server.URL)+signatureSuffix] = signer.sign(buf.Bytes())
+		}
+	}
+	// require 策略下命令索引同样需要签名
+	resp, err := http.Get(server.URL + "/" + commandIndexFileName)
+	require.NoError(t, err)
+	var commandData bytes.Buffer
+	_, _ = commandData.ReadFrom(resp.Body)
+	resp.Body.Close()
+	files["/"+commandIndexFileName+signatureSuffix] = signer.sign(commandData.Bytes())
+	sigServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if data, ok := files[r.URL.Path]; ok {
+			_, _ = w.Write(data)
+			return
+		}
+		if r.URL.Path == "/plugin_pkg_index.json" {
+			_, _ = w.Write(indexData)
+			return
+		}
+		http.Redirect(w, r, server.URL+r.URL.Path, http.StatusFound)
+	}))
+	defer sigServer.Close()
+
+	dest := t.TempDir()
+	mgr := &Manager{
+		rootDir:                    t.TempDir(),
+		sourceBase:                 sigServer.URL,
+		skipPluginIndexCacheForCLI: true,
+		signaturePolicy:            pluginsettings.SignaturePolicyRequire,
+		trustedKeys:                []string{signer.key()},
+	}
+	ctx := newTestContext()
+	require.NoError(t, mgr.Mirror(ctx, MirrorOptions{Dest: dest, Names: []string{"lock-b"}, Platforms: []string{platform}}))
+	assert.Contains(t, ctx.Stdout().(*bytes.Buffer).String(), "The plugin index is signed, keeping it unfiltered.")
+	assert.FileExists(t, filepath.Join(dest, pkgIndexFileName+signatureSuffix))
+	assert.FileExists(t, filepath.Join(dest, commandIndexFileName+signatureSuffix))
+	assert.FileExists(t, filepath.Join(dest, "pkgs", "lock-b", "2.0.0", "lock-b.tar.gz"+signatureSuffix))
+
+	offline := &Manager{
+		rootDir:         t.TempDir(),
+		signaturePolicy: pluginsettings.SignaturePolicyRequire,
+		trustedKeys:     []string{signer.key()},
+	}
+	require.NoError(t, offline.ApplySourceBaseOverride(pathToFileURL(dest)))
+	require.NoError(t, offline.Install(newTestContext(), "lock-b", "", false))
+}
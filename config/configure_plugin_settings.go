@@ -56,7 +56,8 @@ func NewConfigurePluginSettingsCommand() *cli.Command {
 
 func loadPluginSettings() (configDir string, cfg *pluginsettings.PluginSettings, err error) {
 	configDir = GetConfigPath()
-	cfg, err = pluginsettings.Load(configDir)
+	// An unparsable file must be reported here; set and clear would otherwise overwrite it with defaults.
+	cfg, err = pluginsettings.LoadStrict(configDir)
 	if err != nil {
 		return "", nil, fmt.Errorf("load plugin-settings failed: %w", err)
 	}
@@ -84,29 +85,55 @@ func newPluginSettingsShowCommand() *cli.Command {
 func newPluginSettingsSetCommand() *cli.Command {
 	cmd := &cli.Command{
 		Name:  "set",
-		Usage: "set --source-base <url>",
-		Short: i18n.T("set plugins tree source base URL", "设置插件源根地址"),
+		Usage: "set [--source-base <url>] [--signature-policy off|verify|require] [--trusted-keys <key1,key2>]",
+		Short: i18n.T("set plugins tree source base URL and signature verification", "设置插件源根地址和签名校验"),
 		Run: func(ctx *cli.Context, args []string) error {
 			if len(args) > 0 {
 				return cli.NewInvalidCommandError(args[0], ctx)
 			}
-			flag := ctx.Flags().Get("source-base")
-			if flag == nil || !flag.IsAssigned() {
-				return fmt.Errorf("missing --source-base <url>")
-			}
-			v, _ := flag.GetValue()
-			v = strings.TrimSpace(v)
-			if v == "" {
-				return fmt.Errorf("source-base must not be empty (use 'configure plugin-settings clear' to reset)")
-			}
-			if !strings.HasPrefix(strings.ToLower(v), "http://") && !strings.HasPrefix(strings.ToLower(v), "https://") {
-				return fmt.Errorf("source-base must start with http:// or https://")
+			sourceBaseFlag := ctx.Flags().Get("source-base")
+			policyFlag := ctx.Flags().Get("signature-policy")
+			keysFlag := ctx.Flags().Get("trusted-keys")
+			if !sourceBaseFlag.IsAssigned() && !policyFlag.IsAssigned() && !keysFlag.IsAssigned() {
+				return fmt.Errorf("missing --source-base <url>, --signature-policy or --trusted-keys")
 			}
 			configDir, cfg, err := loadPluginSettings()
 			if err != nil {
 				return err
 			}
-			cfg.SourceBase = strings.TrimRight(v, "/")
+			if sourceBaseFlag.IsAssigned() {
+				v, _ := sourceBaseFlag.GetValue()
+				v = strings.TrimSpace(v)
+				if v == "" {
+					return fmt.Errorf("source-base must not be empty (use 'configure plugin-settings clear' to reset)")
+				}
+				if !pluginsettings.IsSupportedSourceBase(v) {
+					return fmt.Errorf("source-base must start with http://, https:// or file://")
+				}
+				cfg.SourceBase = strings.TrimRight(v, "/")
+			}
+			if policyFlag.IsAssigned() {
+				v, _ := policyFlag.GetValue()
+				v = strings.ToLower(strings.TrimSpace(v))
+				if !pluginsettings.IsValidSignaturePolicy(v) {
+					return fmt.Errorf("invalid signature-policy '%s', must be off, verify or require", v)
+				}
+				cfg.SignaturePolicy = v
+			}
+			if keysFlag.IsAssigned() {
+				v, _ := keysFlag.GetValue()
+				keys := []string{}
+				for _, key := range strings.Split(v, ",") {
+					if key = strings.TrimSpace(key); key == "" {
+						continue
+					}
+					if _, err := pluginsettings.ParseTrustedKey(key); err != nil {
+						return err
+					}
+					keys = append(keys, key)
+				}
+				cfg.TrustedKeys = keys
+			}
 			if err := pluginsettings.Save(configDir, cfg); err != nil {
 				return err
 			}
@@ -121,6 +148,22 @@ func newPluginSettingsSetCommand() *cli.Command {
 			"plugins tree base URL for set (e.g. https://example.com/plugins)",
 			"set 命令使用的插件源 URL（例如 https://example.com/plugins）"),
 	})
+	cmd.Flags().Add(&cli.Flag{
+		Category:     "plugin-settings",
+		Name:         "signature-policy",
+		AssignedMode: cli.AssignedOnce,
+		Short: i18n.T(
+			"plugin signature policy: off (default), verify (check signatures when present) or require (refuse unsigned indexes and packages)",
+			"插件签名策略：off（默认）、verify（存在签名时校验）或 require（拒绝未签名的索引和插件包）"),
+	})
+	cmd.Flags().Add(&cli.Flag{
+		Category:     "plugin-settings",
+		Name:         "trusted-keys",
+		AssignedMode: cli.AssignedOnce,
+		Short: i18n.T(
+			"comma-separated base64 ed25519 public keys trusted for plugin signatures in addition to the built-in keys; empty to clear",
+			"除内置公钥外信任的插件签名公钥（base64 编码的 ed25519 公钥），用逗号分隔；为空时清除"),
+	})
 	return cmd
 }
 
@@ -133,11 +176,11 @@ func newPluginSettingsClearCommand() *cli.Command {
 			if len(args) > 0 {
 				return cli.NewInvalidCommandError(args[0], ctx)
 			}
-			configDir, _, err := loadPluginSettings()
+			configDir, cfg, err := loadPluginSettings()
 			if err != nil {
 				return err
 			}
-			cfg := pluginsettings.Default()
+			cfg.SourceBase = ""
 			if err := pluginsettings.Save(configDir, cfg); err != nil {
 				return err
 			}
@@ -152,10 +195,13 @@ func doPluginSettingsShow(ctx *cli.Context, configDir string, cfg *pluginsetting
 	enc := json.NewEncoder(w)
 	enc.SetIndent("", "  ")
 	_ = enc.Encode(map[string]any{
-		"config_file":           pluginsettings.GetConfigFilePath(configDir),
-		"source_base":           strings.TrimSpace(cfg.SourceBase),
-		"source_base_effective": effective,
-		"env_override":          strings.TrimSpace(os.Getenv(pluginsettings.EnvSourceBase)),
+		"config_file":                pluginsettings.GetConfigFilePath(configDir),
+		"source_base":                strings.TrimSpace(cfg.SourceBase),
+		"source_base_effective":      effective,
+		"env_override":               strings.TrimSpace(os.Getenv(pluginsettings.EnvSourceBase)),
+		"signature_policy":           strings.TrimSpace(cfg.SignaturePolicy),
+		"signature_policy_effective": pluginsettings.EffectiveSignaturePolicy(cfg),
+		"trusted_keys":               cfg.TrustedKeys,
 	})
 	return nil
 }
@@ -218,3 +218,84 @@ func TestConfigurePluginSettings_Clear_InvalidArg(t *testing.T) {
 	assert.Contains(t, err.Error(), "oops")
 	assert.Contains(t, err.Error(), "not a valid command")
 }
+
+func TestConfigurePluginSettings_Set_SignaturePolicy(t *testing.T) {
+	ctx, w, aliyunDir := testPluginSettingsIsolatedHome(t)
+	t.Setenv(pluginsettings.EnvSignaturePolicy, "")
+	require.NoError(t, os.MkdirAll(aliyunDir, 0755))
+	require.NoError(t, pluginsettings.Save(aliyunDir, &pluginsettings.PluginSettings{
+		SourceBase: "https://old.example/plugins",
+	}))
+	root := NewConfigurePluginSettingsCommand()
+	ctx.EnterCommand(root)
+	set := root.GetSubCommand("set")
+	ctx.EnterCommand(set)
+	policy := ctx.Flags().Get("signature-policy")
+	policy.SetAssigned(true)
+	policy.SetValue("Require")
+	keys := ctx.Flags().Get("trusted-keys")
+	keys.SetAssigned(true)
+	keys.SetValue("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=, ")
+	require.NoError(t, set.Run(ctx, nil))
+
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(w.Bytes(), &m))
+	assert.Equal(t, "require", m["signature_policy_effective"])
+
+	cfg, err := pluginsettings.Load(aliyunDir)
+	require.NoError(t, err)
+	assert.Equal(t, "https://old.example/plugins", cfg.SourceBase)
+	assert.Equal(t, "require", cfg.SignaturePolicy)
+	assert.Equal(t, []string{"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}, cfg.TrustedKeys)
+
+	// clear 只清除 source-base
+	clear := root.GetSubCommand("clear")
+	ctx.EnterCommand(clear)
+	require.NoError(t, clear.Run(ctx, nil))
+	cfg, err = pluginsettings.Load(aliyunDir)
+	require.NoError(t, err)
+	assert.Equal(t, "", cfg.SourceBase)
+	assert.Equal(t, "require", cfg.SignaturePolicy)
+}
+
+func TestConfigurePluginSettings_Set_InvalidSignatureSettings(t *testing.T) {
+	ctx, _, _ := testPluginSettingsIsolatedHome(t)
+	root := NewConfigurePluginSettingsCommand()
+	ctx.EnterCommand(root)
+	set := root.GetSubCommand("set")
+	ctx.EnterCommand(set)
+	policy := ctx.Flags().Get("signature-policy")
+	policy.SetAssigned(true)
+	policy.SetValue("strict")
+	err := set.Run(ctx, nil)
+	assert.EqualError(t, err, "invalid signature-policy 'strict', must be off, verify or require")
+
+	policy.SetAssigned(false)
+	keys := ctx.Flags().Get("trusted-keys")
+	keys.SetAssigned(true)
+	keys.SetValue("bad")
+	err = set.Run(ctx, nil)
+	assert.ErrorContains(t, err, "invalid trusted key 'bad'")
+}
+
+func TestConfigurePluginSettings_InvalidFileIsReported(t *testing.T) {
+	ctx, _, aliyunDir := testPluginSettingsIsolatedHome(t)
+	require.NoError(t, os.MkdirAll(aliyunDir, 0755))
+	path := pluginsettings.GetConfigFilePath(aliyunDir)
+	data := []byte(`{"signature_policy":"require",`)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+
+	root := NewConfigurePluginSettingsCommand()
+	ctx.EnterCommand(root)
+	show := root.GetSubCommand("show")
+	ctx.EnterCommand(show)
+	assert.ErrorContains(t, show.Run(ctx, nil), "invalid plugin settings file")
+
+	// clear 不能用默认值覆盖无法解析的文件
+	clear := root.GetSubCommand("clear")
+	ctx.EnterCommand(clear)
+	assert.ErrorContains(t, clear.Run(ctx, nil), "invalid plugin settings file")
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+}
@@ -31,17 +31,31 @@ func NewConfigureSafetyPolicyCommand() *cli.Command {
 			"管理安全策略和人工确认规则"),
 		Usage: "safety-policy [command] [--config-path <configPath>]",
 		Long: i18n.T(
-			`Configure safety policy to deny or require confirmation for destructive operations.`,
-			`配置安全策略，用于拒绝或要求确认破坏性操作。`),
+			`Configure safety policy to deny or require confirmation for destructive operations.
+
+Policies are read from several layers and merged:
+  system   /etc/aliyun/safety-policy.json (%ProgramData%\aliyun on Windows)
+  project  .aliyun/safety-policy.json in the current directory or a parent
+  user     safety-policy.json in the config directory (edited by these commands)
+  env      ALIBABA_CLOUD_SAFETY_POLICY_ENABLED / ALIBABA_CLOUD_SAFETY_POLICY_RULES
+
+A system or project file with "locked": true is evaluated before all other
+rules and keeps the policy enabled. 'show' prints the merged result.`,
+			`配置安全策略，用于拒绝或要求确认破坏性操作。
+
+策略从以下各层读取并合并：
+  system   /etc/aliyun/safety-policy.json (Windows 下为 %ProgramData%\aliyun)
+  project  当前目录或上级目录中的 .aliyun/safety-policy.json
+  user     配置目录中的 safety-policy.json（由本组命令编辑）
+  env      ALIBABA_CLOUD_SAFETY_POLICY_ENABLED / ALIBABA_CLOUD_SAFETY_POLICY_RULES
+
+设置了 "locked": true 的系统或项目策略会先于其他规则生效，并始终保持启用。
+'show' 输出合并后的结果。`),
 		Run: func(ctx *cli.Context, args []string) error {
 			if len(args) > 0 {
 				return cli.NewInvalidCommandError(args[0], ctx)
 			}
-			configDir, policy, err := loadSafetyPolicy(ctx)
-			if err != nil {
-				return err
-			}
-			return doSafetyPolicyShow(ctx, configDir, policy)
+			return doSafetyPolicyShow(ctx, GetConfigDir(ctx))
 		},
 	}
 
@@ -72,6 +86,10 @@ func NewConfigureSafetyPolicyCommand() *cli.Command {
 	cmd.AddSubCommand(newConfigureSafetyPolicyAddCommand())
 	cmd.AddSubCommand(newConfigureSafetyPolicyRemoveCommand())
 	cmd.AddSubCommand(newConfigureSafetyPolicyListCommand())
+	cmd.AddSubCommand(newConfigureSafetyPolicyTestCommand())
+	cmd.AddSubCommand(newConfigureSafetyPolicyApproveCommand())
+	cmd.AddSubCommand(newConfigureSafetyPolicyApprovalsCommand())
+	cmd.AddSubCommand(newConfigureSafetyPolicyRevokeCommand())
 	return cmd
 }
 
@@ -88,16 +106,14 @@ func newConfigureSafetyPolicyShowCommand() *cli.Command {
 	return &cli.Command{
 		Name:  "show",
 		Usage: "show [--config-path <configPath>]",
-		Short: i18n.T("display current safety policy", "显示当前安全策略"),
+		Short: i18n.T(
+			"display the effective safety policy merged from system, project, user and env layers",
+			"显示由系统、项目、用户和环境变量各层合并后的生效安全策略"),
 		Run: func(ctx *cli.Context, args []string) error {
 			if len(args) > 0 {
 				return cli.NewInvalidCommandError(args[0], ctx)
 			}
-			configDir, policy, err := loadSafetyPolicy(ctx)
-			if err != nil {
-				return err
-			}
-			return doSafetyPolicyShow(ctx, configDir, policy)
+			return doSafetyPolicyShow(ctx, GetConfigDir(ctx))
 		},
 	}
 }
@@ -192,8 +208,11 @@ func newConfigureSafetyPolicyListCommand() *cli.Command {
 	}
 }
 
-func doSafetyPolicyShow(ctx *cli.Context, configDir string, policy *safety.Policy) error {
-	data, err := json.MarshalIndent(policy, "", "  ")
+// doSafetyPolicyShow prints the merged policy the CLI actually enforces; each
+// rule carries the layer it came from. Edits (add/remove/enable/disable) only
+// touch the user layer.
+func doSafetyPolicyShow(ctx *cli.Context, configDir string) error {
+	data, err := json.MarshalIndent(safety.LoadLayeredPolicy(configDir), "", "  ")
 	if err != nil {
 		return err
 	}
@@ -226,9 +245,10 @@ func doSafetyPolicyAdd(ctx *cli.Context, configDir string, policy *safety.Policy
 		return fmt.Errorf("action must be deny, confirm, or forbid")
 	}
 
-	// Check if rule already exists, update it
+	// Check if rule already exists, update it. Conditional rules are edited in
+	// the policy file and never rewritten from here.
 	for i := range policy.Rules {
-		if policy.Rules[i].Pattern == pattern {
+		if policy.Rules[i].Pattern == pattern && policy.Rules[i].When.IsEmpty() {
 			policy.Rules[i].Action = action
 			return safety.SavePolicy(configDir, policy)
 		}
@@ -268,6 +288,10 @@ func doSafetyPolicyList(ctx *cli.Context, configDir string, policy *safety.Polic
 	}
 	cli.Println(w, "Rules:")
 	for i, r := range policy.Rules {
+		if cond := r.When.String(); cond != "" {
+			cli.Printf(w, "  %d. %s -> %s (when %s)\n", i+1, r.Pattern, r.Action, cond)
+			continue
+		}
 		cli.Printf(w, "  %d. %s -> %s\n", i+1, r.Pattern, r.Action)
 	}
 	return nil
@@ -0,0 +1,177 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"os/user"
+	"time"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/i18n"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/safety"
+)
+
+const (
+	safetyApprovalTTLFlagName = "ttl"
+	defaultSafetyApprovalTTL  = 15 * time.Minute
+)
+
+// confirmSafetyApproval asks the human at the terminal to confirm a grant. An
+// approval must come from a person, so it is refused when stdin is not a
+// terminal (for example when an agent runs the command).
+var confirmSafetyApproval = func(ctx *cli.Context, prompt string) bool {
+	if !safety.IsInteractive() {
+		return false
+	}
+	return safety.PromptConfirm(ctx.Stderr(), prompt)
+}
+
+func newConfigureSafetyPolicyApproveCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "approve",
+		Usage: "approve --pattern <pattern> [--ttl <duration>] [--config-path <configPath>]",
+		Short: i18n.T(
+			"approve commands matching a pattern for a limited time, without --yes",
+			"在限定时间内批准匹配模式的命令，无需 --yes"),
+		Long: i18n.T(
+			`Write a signed, expiring grant that satisfies 'confirm' rules for commands
+matching --pattern (e.g. ecs:StopInstance or fc:function:*) until it expires.
+'deny' rules, and 'confirm' rules of a locked system or project policy, are
+never affected. The grant must be confirmed interactively, so an agent cannot
+approve in passing; this is not a hard boundary, as the signing key is readable
+by any process running as you. Use locked rules for what an agent must not bypass.
+
+  aliyun configure safety-policy approve --pattern 'ecs:StopInstance' --ttl 15m`,
+			`写入一条带签名、会过期的审批，在过期前满足匹配 --pattern 的命令
+（如 ecs:StopInstance 或 fc:function:*）的 'confirm' 规则；'deny' 规则以及锁定的
+系统或项目策略中的 'confirm' 规则不受影响。审批必须在交互终端中确认，使智能体无法
+顺带为自己审批；但签名密钥可被以当前用户身份运行的任何进程读取，这并非严格隔离。
+智能体绝不能绕过的操作请使用锁定规则。
+
+  aliyun configure safety-policy approve --pattern 'ecs:StopInstance' --ttl 15m`),
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			return doSafetyPolicyApprove(ctx, GetConfigDir(ctx))
+		},
+	}
+	cmd.Flags().Add(&cli.Flag{
+		Name:         safetyApprovalTTLFlagName,
+		AssignedMode: cli.AssignedOnce,
+		Short: i18n.T(
+			"how long the approval lasts, e.g. 15m or 2h (default 15m, at most 24h)",
+			"审批有效时长，如 15m 或 2h（默认 15m，最长 24h）"),
+	})
+	return cmd
+}
+
+func newConfigureSafetyPolicyApprovalsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "approvals",
+		Usage: "approvals [--config-path <configPath>]",
+		Short: i18n.T("list active safety approvals", "列出生效中的安全审批"),
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			return doSafetyPolicyApprovals(ctx, GetConfigDir(ctx))
+		},
+	}
+}
+
+func newConfigureSafetyPolicyRevokeCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "revoke",
+		Usage: "revoke [--pattern <pattern>] [--config-path <configPath>]",
+		Short: i18n.T(
+			"revoke safety approvals for a pattern, or all approvals",
+			"撤销指定模式的安全审批，或撤销全部审批"),
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			return doSafetyPolicyRevoke(ctx, GetConfigDir(ctx))
+		},
+	}
+}
+
+func doSafetyPolicyApprove(ctx *cli.Context, configDir string) error {
+	pattern, ok := ctx.Flags().Get("pattern").GetValue()
+	if !ok || pattern == "" {
+		return fmt.Errorf("--pattern is required for approve")
+	}
+	ttl := defaultSafetyApprovalTTL
+	if raw, ok := ctx.Flags().Get(safetyApprovalTTLFlagName).GetValue(); ok && raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid --ttl %q: %w", raw, err)
+		}
+		ttl = d
+	}
+	if ttl <= 0 || ttl > safety.MaxApprovalTTL {
+		return fmt.Errorf("--ttl must be between 1s and %s", safety.MaxApprovalTTL)
+	}
+
+	prompt := fmt.Sprintf(i18n.T(
+		"Approve commands matching '%s' without confirmation for %s?\nType 'yes' to approve, anything else to cancel: ",
+		"批准匹配 '%s' 的命令在 %s 内无需确认？\n输入 'yes' 批准，其他任意输入取消: ",
+	).GetMessage(), pattern, ttl)
+	if !confirmSafetyApproval(ctx, prompt) {
+		return fmt.Errorf(i18n.T(
+			"approval not granted: it must be confirmed by a person in an interactive terminal",
+			"未批准：审批必须由用户在交互终端中确认",
+		).GetMessage())
+	}
+
+	createdBy := ""
+	if u, err := user.Current(); err == nil {
+		createdBy = u.Username
+	}
+	g, err := safety.AddApproval(configDir, pattern, ttl, createdBy)
+	if err != nil {
+		return err
+	}
+	cli.Printf(ctx.Stdout(), "Approved %s until %s (id %s)\n", g.Pattern, g.ExpiresAt.Local().Format(time.RFC3339), g.ID)
+	return nil
+}
+
+func doSafetyPolicyApprovals(ctx *cli.Context, configDir string) error {
+	w := ctx.Stdout()
+	grants := safety.LoadApprovals(configDir)
+	if len(grants) == 0 {
+		cli.Println(w, i18n.T("No active approvals.", "没有生效中的审批。").GetMessage())
+		return nil
+	}
+	for _, g := range grants {
+		cli.Printf(w, "%s  %s  expires %s", g.ID, g.Pattern, g.ExpiresAt.Local().Format(time.RFC3339))
+		if g.CreatedBy != "" {
+			cli.Printf(w, "  by %s", g.CreatedBy)
+		}
+		cli.Println(w, "")
+	}
+	return nil
+}
+
+func doSafetyPolicyRevoke(ctx *cli.Context, configDir string) error {
+	pattern, _ := ctx.Flags().Get("pattern").GetValue()
+	n, err := safety.RevokeApprovals(configDir, pattern)
+	if err != nil {
+		return err
+	}
+	cli.Printf(ctx.Stdout(), "Revoked %d approval(s)\n", n)
+	return nil
+}
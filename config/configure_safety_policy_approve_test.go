@@ -0,0 +1,98 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/safety"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stubConfirmSafetyApproval(t *testing.T, answer bool) *string {
+	t.Helper()
+	var asked string
+	orig := confirmSafetyApproval
+	confirmSafetyApproval = func(ctx *cli.Context, prompt string) bool {
+		asked = prompt
+		return answer
+	}
+	t.Cleanup(func() { confirmSafetyApproval = orig })
+	return &asked
+}
+
+func setFlag(ctx *cli.Context, name, value string) {
+	f := ctx.Flags().Get(name)
+	f.SetAssigned(true)
+	f.SetValue(value)
+}
+
+func TestConfigureSafetyPolicy_Approve(t *testing.T) {
+	dir := t.TempDir()
+	asked := stubConfirmSafetyApproval(t, true)
+	ctx, w := testAiModeContext(t, dir)
+	sub := enterSafetyPolicySub(t, ctx, "approve")
+	setFlag(ctx, "pattern", "ecs:StopInstance")
+	setFlag(ctx, safetyApprovalTTLFlagName, "30m")
+
+	require.NoError(t, sub.Run(ctx, []string{}))
+	assert.Contains(t, *asked, "ecs:StopInstance")
+	assert.Contains(t, *asked, "30m0s")
+	assert.Contains(t, w.String(), "Approved ecs:StopInstance until")
+
+	grants := safety.LoadApprovals(dir)
+	require.Len(t, grants, 1)
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), grants[0].ExpiresAt, time.Minute)
+
+	ctx, w = testAiModeContext(t, dir)
+	require.NoError(t, enterSafetyPolicySub(t, ctx, "approvals").Run(ctx, []string{}))
+	assert.Contains(t, w.String(), "ecs:StopInstance")
+
+	ctx, w = testAiModeContext(t, dir)
+	require.NoError(t, enterSafetyPolicySub(t, ctx, "revoke").Run(ctx, []string{}))
+	assert.Contains(t, w.String(), "Revoked 1 approval(s)")
+	assert.Empty(t, safety.LoadApprovals(dir))
+}
+
+func TestConfigureSafetyPolicy_Approve_RequiresHumanConfirmation(t *testing.T) {
+	dir := t.TempDir()
+	stubConfirmSafetyApproval(t, false)
+	ctx, _ := testAiModeContext(t, dir)
+	sub := enterSafetyPolicySub(t, ctx, "approve")
+	setFlag(ctx, "pattern", "ecs:StopInstance")
+
+	assert.ErrorContains(t, sub.Run(ctx, []string{}), "approval not granted")
+	assert.Empty(t, safety.LoadApprovals(dir))
+}
+
+func TestConfigureSafetyPolicy_Approve_InvalidInput(t *testing.T) {
+	dir := t.TempDir()
+	stubConfirmSafetyApproval(t, true)
+
+	ctx, _ := testAiModeContext(t, dir)
+	sub := enterSafetyPolicySub(t, ctx, "approve")
+	assert.ErrorContains(t, sub.Run(ctx, []string{}), "--pattern is required")
+
+	for _, ttl := range []string{"soon", "-5m", "48h"} {
+		ctx, _ = testAiModeContext(t, dir)
+		sub = enterSafetyPolicySub(t, ctx, "approve")
+		setFlag(ctx, "pattern", "ecs:*")
+		setFlag(ctx, safetyApprovalTTLFlagName, ttl)
+		assert.Error(t, sub.Run(ctx, []string{}), ttl)
+	}
+}
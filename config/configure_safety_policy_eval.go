@@ -0,0 +1,244 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/i18n"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/safety"
+)
+
+const safetyPolicyTestFileFlagName = "file"
+
+func newConfigureSafetyPolicyTestCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "test",
+		Usage: "test [--file <cases-file>] [<product> <api> [--Param value ...]]",
+		Short: i18n.T(
+			"evaluate a command line against the safety policy without running it",
+			"在不执行命令的情况下，用安全策略评估命令行"),
+		Long: i18n.T(
+			`Evaluate a command line against the merged safety policy and print the
+matched rule and action. Nothing is sent to the cloud. Rules are evaluated as
+if the policy were enabled, so a policy can be tested before it is switched on.
+
+  aliyun configure safety-policy test ecs DeleteInstance --InstanceId i-1 --region cn-shanghai
+  aliyun configure safety-policy test fc function delete --name my-fn
+
+With --file, each non-empty line of the file is a test case of the form
+'<command line> => <allow|deny|confirm|forbid>' ('#' starts a comment). The
+command exits with an error if any case does not get the expected action,
+which makes it usable as a policy regression test in CI.`,
+			`用合并后的安全策略评估命令行，输出命中的规则与动作，不会发出任何请求。
+规则按策略已启用的方式评估，因此可以在启用策略前进行测试。
+
+  aliyun configure safety-policy test ecs DeleteInstance --InstanceId i-1 --region cn-shanghai
+  aliyun configure safety-policy test fc function delete --name my-fn
+
+使用 --file 时，文件中每个非空行是一个用例，格式为
+'<命令行> => <allow|deny|confirm|forbid>'（'#' 开头为注释）。任一用例结果
+与期望不符时命令以错误退出，可用于 CI 中的策略回归测试。`),
+		EnableUnknownFlag: true,
+		Run: func(ctx *cli.Context, args []string) error {
+			configDir := GetConfigDir(ctx)
+			lp := safety.LoadLayeredPolicy(configDir)
+			if path, ok := ctx.Flags().GetValue(safetyPolicyTestFileFlagName); ok && path != "" {
+				if len(args) > 0 {
+					return fmt.Errorf("--file cannot be combined with a command line")
+				}
+				return doSafetyPolicyTestFile(ctx, lp, path)
+			}
+			if len(args) == 0 {
+				return fmt.Errorf("a command line or --file is required for test")
+			}
+			// The command line was parsed by the same flag set the root
+			// command uses, so ctx already holds its region, profile and
+			// API parameters.
+			cmd, err := safetyCommandInfo(ctx, ctx, safety.TrimProgramName(args))
+			if err != nil {
+				return err
+			}
+			return doSafetyPolicyTest(ctx, lp, cmd)
+		},
+	}
+	cmd.Flags().Add(&cli.Flag{
+		Name:         safetyPolicyTestFileFlagName,
+		AssignedMode: cli.AssignedOnce,
+		Short: i18n.T(
+			"file of '<command line> => <action>' test cases",
+			"包含 '<命令行> => <动作>' 测试用例的文件"),
+	})
+	// --region is not persistent, so the test command registers it itself.
+	cmd.Flags().Add(NewRegionFlag())
+	addSafetyEvalFlags(cmd.Flags())
+	return cmd
+}
+
+// safetyEvalNoValueFlags are the openapi switches that never take a value;
+// registering them keeps `--force ecs ...` from swallowing the next token.
+var safetyEvalNoValueFlags = []struct {
+	name      string
+	shorthand rune
+}{
+	{"force", 0}, {"yes", 'y'}, {"quiet", 'q'}, {"dryrun", 0}, {"cli-dry-run-json", 0},
+	{"estimate-cost", 0}, {"secure", 0}, {"insecure", 0}, {"cli-ai-mode", 0}, {"no-cli-ai-mode", 0},
+}
+
+// safetyEvalValueFlags are the other flags the openapi command consumes
+// itself; like there, they do not count as API parameters for conditions.
+var safetyEvalValueFlags = []string{
+	"version", "body", "body-file", "accept", "roa", "log-level", "cli-query", "method", "user-agent",
+	"cli-log-format", "cli-log-time-format",
+}
+
+var safetyEvalRepeatableFlags = []string{"header", "output", "pager", "waiter"}
+
+// addSafetyEvalFlags registers the flags the openapi command consumes itself,
+// so a command line under test is split into flags and API parameters the same
+// way as when it runs. The flags are hidden: they only shape parsing here.
+func addSafetyEvalFlags(fs *cli.FlagSet) {
+	for _, f := range safetyEvalNoValueFlags {
+		fs.Add(&cli.Flag{Name: f.name, Shorthand: f.shorthand, AssignedMode: cli.AssignedNone, Hidden: true})
+	}
+	for _, name := range safetyEvalValueFlags {
+		fs.Add(&cli.Flag{Name: name, AssignedMode: cli.AssignedOnce, Hidden: true})
+	}
+	for _, name := range safetyEvalRepeatableFlags {
+		fs.Add(&cli.Flag{Name: name, AssignedMode: cli.AssignedRepeatable, Hidden: true})
+	}
+}
+
+// parseSafetyCommandLine parses one test-file command line in a fresh context
+// built like the root command's, returning that context and the positional args.
+func parseSafetyCommandLine(args []string) (*cli.Context, []string, error) {
+	evalCtx := cli.NewCommandContext(io.Discard, io.Discard)
+	root := &cli.Command{Name: "aliyun", EnableUnknownFlag: true}
+	AddFlags(root.Flags())
+	addSafetyEvalFlags(root.Flags())
+	evalCtx.EnterCommand(root)
+
+	parser := cli.NewParser(args, evalCtx)
+	parser.SetAllowUnknown(true)
+	positional, err := parser.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	return evalCtx, positional, nil
+}
+
+// safetyCommandInfo fills CommandInfo the way Commando.checkSafetyPolicy does:
+// API parameters from the unknown flags, region from --region or the profile,
+// the profile name and --endpoint. parsed holds the flags of the command line
+// under test; ctx is the test command's own context (config path, --profile).
+func safetyCommandInfo(ctx, parsed *cli.Context, positional []string) (safety.CommandInfo, error) {
+	cmd, err := safety.CommandInfoFromArgs(positional)
+	if err != nil {
+		return safety.CommandInfo{}, err
+	}
+	profileName := ProfileFlag(parsed.Flags()).GetStringOrDefault(getProfileName(ctx))
+	profile, _ := LoadProfile(getConfigurePath(ctx), profileName)
+	if profile.Name == "" {
+		profile.Name = profileName
+	}
+	cmd.Params = safety.ParamsFromFlags(parsed.UnknownFlags())
+	cmd.Region = RegionFlag(parsed.Flags()).GetStringOrDefault(profile.RegionId)
+	cmd.Profile = profile.Name
+	cmd.Endpoint = EndpointFlag(parsed.Flags()).GetStringOrDefault("")
+	return cmd, nil
+}
+
+func doSafetyPolicyTest(ctx *cli.Context, lp *safety.LayeredPolicy, cmd safety.CommandInfo) error {
+	result, origin := lp.Evaluate(cmd)
+
+	w := ctx.Stdout()
+	if !lp.Enabled {
+		cli.Printf(w, "Safety policy: %s (rules evaluated as if enabled)\n", enabledStatus(false))
+	}
+	cli.Printf(w, "Command: %s\n", cmd.Pattern())
+	if cmd.Region != "" {
+		cli.Printf(w, "Region:  %s\n", cmd.Region)
+	}
+	if cmd.Profile != "" {
+		cli.Printf(w, "Profile: %s\n", cmd.Profile)
+	}
+	if cmd.Endpoint != "" {
+		cli.Printf(w, "Endpoint: %s\n", cmd.Endpoint)
+	}
+	cli.Printf(w, "Action:  %s\n", result.Action)
+	cli.Printf(w, "Rule:    %s\n", describeMatchedRule(result, origin))
+	return nil
+}
+
+func doSafetyPolicyTestFile(ctx *cli.Context, lp *safety.LayeredPolicy, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	cases, err := safety.ParseTestCases(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	w := ctx.Stdout()
+	failed := 0
+	for _, tc := range cases {
+		var cmd safety.CommandInfo
+		evalCtx, positional, err := parseSafetyCommandLine(tc.Args)
+		if err == nil {
+			cmd, err = safetyCommandInfo(ctx, evalCtx, positional)
+		}
+		if err != nil {
+			failed++
+			cli.Printf(w, "FAIL %s:%d: %s: %s\n", path, tc.Line, tc.Command, err)
+			continue
+		}
+		result, origin := lp.Evaluate(cmd)
+		if result.Action != safety.NormalizeAction(tc.Expected) {
+			failed++
+			cli.Printf(w, "FAIL %s:%d: %s: expected %s, got %s (rule: %s)\n",
+				path, tc.Line, cmd.Pattern(), tc.Expected, result.Action, describeMatchedRule(result, origin))
+			continue
+		}
+		cli.Printf(w, "ok   %s:%d: %s => %s\n", path, tc.Line, cmd.Pattern(), result.Action)
+	}
+	cli.Printf(w, "%d passed, %d failed\n", len(cases)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d safety policy test cases failed", failed, len(cases))
+	}
+	return nil
+}
+
+func describeMatchedRule(result safety.CheckResult, origin *safety.OriginRule) string {
+	if result.Rule == nil {
+		return "(no rule matched)"
+	}
+	s := result.Rule.Pattern + " -> " + string(result.Rule.Action)
+	if result.Condition != "" {
+		s += " when " + result.Condition
+	}
+	if origin != nil {
+		s += " [" + origin.Origin
+		if origin.Locked {
+			s += ", locked"
+		}
+		s += "]"
+	}
+	return s
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/safety"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func saveTestSafetyPolicy(t *testing.T, dir string) {
+	t.Helper()
+	require.NoError(t, safety.SavePolicy(dir, &safety.Policy{
+		Enabled: true,
+		Rules: []safety.Rule{
+			{Pattern: "ecs:DeleteInstance", Action: safety.ActionDeny, When: &safety.Condition{
+				Regions: []string{"cn-shanghai"},
+			}},
+			{Pattern: "ecs:DeleteInstance", Action: safety.ActionConfirm, When: &safety.Condition{
+				Params: []safety.ParamCondition{{Name: "Force", Value: "true"}},
+			}},
+			{Pattern: "fc:function:delete", Action: safety.ActionForbid},
+			{Pattern: "cs:DELETE/clusters/*", Action: safety.ActionDeny},
+		},
+	}))
+}
+
+func TestSafetyCommandInfo_ParsesLikeRootCommand(t *testing.T) {
+	dir := t.TempDir()
+	ctx, _ := testAiModeContext(t, dir)
+
+	parsed, positional, err := parseSafetyCommandLine([]string{
+		"ecs", "DeleteInstance", "--force", "--InstanceId", "i-1", "--region", "cn-shanghai", "--body", "{}",
+	})
+	require.NoError(t, err)
+	cmd, err := safetyCommandInfo(ctx, parsed, positional)
+	require.NoError(t, err)
+	assert.Equal(t, "ecs:DeleteInstance", cmd.Pattern())
+	assert.Equal(t, "cn-shanghai", cmd.Region)
+	// --force is a CLI switch and --body a CLI flag, neither is an API parameter.
+	assert.Equal(t, map[string][]string{"InstanceId": {"i-1"}}, cmd.Params)
+}
+
+func TestConfigureSafetyPolicy_Test_SingleCommand(t *testing.T) {
+	dir := t.TempDir()
+	saveTestSafetyPolicy(t, dir)
+	ctx, w := testAiModeContext(t, dir)
+	sub := enterSafetyPolicySub(t, ctx, "test")
+	RegionFlag(ctx.Flags()).SetAssigned(true)
+	RegionFlag(ctx.Flags()).SetValue("cn-shanghai")
+
+	require.NoError(t, sub.Run(ctx, []string{"aliyun", "ecs", "DeleteInstance"}))
+	out := w.String()
+	assert.Contains(t, out, "Command: ecs:DeleteInstance")
+	assert.Contains(t, out, "Region:  cn-shanghai")
+	assert.Contains(t, out, "Action:  deny")
+	assert.Contains(t, out, "ecs:DeleteInstance -> deny when region=cn-shanghai [user:")
+}
+
+func TestConfigureSafetyPolicy_Test_RequiresCommandOrFile(t *testing.T) {
+	dir := t.TempDir()
+	ctx, _ := testAiModeContext(t, dir)
+	sub := enterSafetyPolicySub(t, ctx, "test")
+	assert.Error(t, sub.Run(ctx, []string{}))
+	assert.Error(t, sub.Run(ctx, []string{"ecs"}))
+}
+
+func TestConfigureSafetyPolicy_Test_File(t *testing.T) {
+	dir := t.TempDir()
+	saveTestSafetyPolicy(t, dir)
+	cases := filepath.Join(dir, "cases.txt")
+	require.NoError(t, os.WriteFile(cases, []byte(`# regression cases
+aliyun ecs DeleteInstance --InstanceId i-1 --region cn-shanghai => deny
+ecs DeleteInstance --InstanceId i-1 --Force true --region cn-beijing => confirm
+ecs DeleteInstance --InstanceId i-1 --region cn-beijing => allow
+fc function delete --name my-fn => forbid
+cs DELETE /clusters/c-1 => deny
+`), 0600))
+
+	ctx, w := testAiModeContext(t, dir)
+	sub := enterSafetyPolicySub(t, ctx, "test")
+	f := ctx.Flags().Get(safetyPolicyTestFileFlagName)
+	f.SetAssigned(true)
+	f.SetValue(cases)
+	require.NoError(t, sub.Run(ctx, []string{}))
+	assert.Contains(t, w.String(), "5 passed, 0 failed")
+
+	require.NoError(t, os.WriteFile(cases, []byte("ecs DeleteInstance --region cn-shanghai => allow\n"), 0600))
+	w.Reset()
+	err := sub.Run(ctx, []string{})
+	assert.ErrorContains(t, err, "1 of 1 safety policy test cases failed")
+	assert.Contains(t, w.String(), "expected allow, got deny")
+}
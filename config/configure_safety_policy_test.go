@@ -16,6 +16,8 @@ package config
 
 import (
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -222,3 +224,76 @@ func TestConfigureSafetyPolicy_List_NoRulesMessage(t *testing.T) {
 		strings.Contains(w.String(), "No rules") || strings.Contains(w.String(), "未配置"),
 		"output: %s", w.String())
 }
+
+func TestConfigureSafetyPolicy_List_ShowsConditions(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, safety.SavePolicy(dir, &safety.Policy{
+		Enabled: true,
+		Rules: []safety.Rule{
+			{Pattern: "*", Action: safety.ActionDeny, When: &safety.Condition{
+				Regions:  []string{"cn-shanghai"},
+				Profiles: []string{"prod"},
+			}},
+		},
+	}))
+	ctx, w := testAiModeContext(t, dir)
+	list := enterSafetyPolicySub(t, ctx, "list")
+	require.NoError(t, list.Run(ctx, []string{}))
+	assert.Contains(t, w.String(), "* -> deny (when region=cn-shanghai, profile=prod)")
+}
+
+func TestConfigureSafetyPolicy_Add_KeepsConditionalRule(t *testing.T) {
+	dir := t.TempDir()
+	cond := &safety.Condition{Profiles: []string{"prod"}}
+	require.NoError(t, safety.SavePolicy(dir, &safety.Policy{
+		Enabled: true,
+		Rules:   []safety.Rule{{Pattern: "ecs:Delete*", Action: safety.ActionDeny, When: cond}},
+	}))
+	ctx, _ := testAiModeContext(t, dir)
+	add := enterSafetyPolicySub(t, ctx, "add")
+	ctx.Flags().Get("pattern").SetAssigned(true)
+	ctx.Flags().Get("pattern").SetValue("ecs:Delete*")
+	ctx.Flags().Get("action").SetAssigned(true)
+	ctx.Flags().Get("action").SetValue("confirm")
+	require.NoError(t, add.Run(ctx, []string{}))
+
+	p, err := safety.LoadPolicy(dir)
+	require.NoError(t, err)
+	require.Len(t, p.Rules, 2)
+	assert.Equal(t, safety.ActionDeny, p.Rules[0].Action)
+	assert.NotNil(t, p.Rules[0].When)
+	assert.Equal(t, safety.ActionConfirm, p.Rules[1].Action)
+	assert.Nil(t, p.Rules[1].When)
+}
+
+func TestConfigureSafetyPolicy_Show_MergesProjectLayer(t *testing.T) {
+	dir := t.TempDir()
+	repo := t.TempDir()
+	projectDir := filepath.Join(repo, safety.ProjectPolicyDir)
+	require.NoError(t, safety.SavePolicy(projectDir, &safety.Policy{
+		Enabled: true,
+		Locked:  true,
+		Rules:   []safety.Rule{{Pattern: "*:Delete*", Action: safety.ActionDeny}},
+	}))
+	require.NoError(t, safety.SavePolicy(dir, &safety.Policy{
+		Rules: []safety.Rule{{Pattern: "ecs:StopInstance", Action: safety.ActionConfirm}},
+	}))
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(repo))
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	ctx, w := testAiModeContext(t, dir)
+	sub := enterSafetyPolicySub(t, ctx, "show")
+	require.NoError(t, sub.Run(ctx, []string{}))
+
+	var lp safety.LayeredPolicy
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(w.String())), &lp))
+	assert.True(t, lp.Enabled)
+	require.Len(t, lp.Rules, 2)
+	assert.Equal(t, "*:Delete*", lp.Rules[0].Pattern)
+	assert.True(t, lp.Rules[0].Locked)
+	assert.Contains(t, lp.Rules[0].Origin, safety.LayerProject+":")
+	assert.Equal(t, "ecs:StopInstance", lp.Rules[1].Pattern)
+	assert.Contains(t, lp.Rules[1].Origin, safety.LayerUser+":")
+}
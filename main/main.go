@@ -45,6 +45,7 @@ import (
 	go_migrate "github.com/aliyun/aliyun-cli/v3/go-migrate"
 	"github.com/aliyun/aliyun-cli/v3/i18n"
 	"github.com/aliyun/aliyun-cli/v3/mcpproxy"
+	"github.com/aliyun/aliyun-cli/v3/mcpserver"
 	"github.com/aliyun/aliyun-cli/v3/mock"
 	"github.com/aliyun/aliyun-cli/v3/openapi"
 	"github.com/aliyun/aliyun-cli/v3/oss/lib"
@@ -128,6 +129,8 @@ func newRootCommand(profile config.Profile, stdout io.Writer) *cli.Command {
 	rootCmd.AddSubCommand(cli.NewAutoCompleteCommand())
 	// mcp proxy command
 	rootCmd.AddSubCommand(mcpproxy.NewMCPProxyCommand())
+	// mcp server command
+	rootCmd.AddSubCommand(mcpserver.NewMCPServerCommand())
 	// go v1 to v2 migrate command
 	rootCmd.AddSubCommand(go_migrate.NewGoMigrateCommand())
 	// new oss command
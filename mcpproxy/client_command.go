@@ -0,0 +1,241 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpproxy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/i18n"
+)
+
+// aliyun mcp-proxy client add/list/remove：管理可以连接代理的客户端
+
+func newMCPProxyClientCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "client",
+		Short: i18n.T("Manage clients allowed to connect to the MCP proxy", "管理允许连接 MCP 代理的客户端"),
+		Usage: "aliyun mcp-proxy client [add|list|remove] [--name NAME]",
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			return doListInboundClients(ctx)
+		},
+	}
+	cmd.AddSubCommand(newMCPProxyClientAddCommand())
+	cmd.AddSubCommand(newMCPProxyClientListCommand())
+	cmd.AddSubCommand(newMCPProxyClientRemoveCommand())
+	return cmd
+}
+
+func newClientNameFlag() *cli.Flag {
+	return &cli.Flag{
+		Name:         "name",
+		AssignedMode: cli.AssignedOnce,
+		Short:        i18n.T("client name", "客户端名称"),
+	}
+}
+
+func newMCPProxyClientAddCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "add",
+		Usage: "aliyun mcp-proxy client add --name NAME [--allowed-servers SERVERS] [--cert]",
+		Short: i18n.T(
+			"register a client and print its bearer token",
+			"登记客户端并打印其 bearer token"),
+		Long: i18n.T(
+			`Register a client for a proxy started with --auth-token or --mtls and print
+its bearer token. --allowed-servers limits the client to some MCP servers
+(names, IDs or path prefixes, as in 'aliyun mcp-proxy --allowed-servers').
+--cert also issues a client certificate signed by the local CA for --mtls.
+A running proxy picks up the change without restarting.
+
+  aliyun mcp-proxy client add --name agent-a --allowed-servers ecs,rds`,
+			`为使用 --auth-token 或 --mtls 启动的代理登记客户端，并打印其 bearer token。
+--allowed-servers 限制该客户端可访问的 MCP 服务器（服务器名称、ID 或路径前缀，
+格式同 'aliyun mcp-proxy --allowed-servers'）。--cert 同时签发由本地 CA
+签名的客户端证书，用于 --mtls。运行中的代理无需重启即可生效。
+
+  aliyun mcp-proxy client add --name agent-a --allowed-servers ecs,rds`),
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			return doAddInboundClient(ctx)
+		},
+	}
+	cmd.Flags().Add(newClientNameFlag())
+	cmd.Flags().Add(&cli.Flag{
+		Name:         "allowed-servers",
+		AssignedMode: cli.AssignedOnce,
+		Short: i18n.T(
+			"comma-separated MCP server names, IDs or path prefixes the client may access; all servers if omitted",
+			"客户端可访问的 MCP 服务器名称、ID 或路径前缀，用逗号分隔；不指定时可访问所有服务器"),
+	})
+	cmd.Flags().Add(&cli.Flag{
+		Name:         "cert",
+		AssignedMode: cli.AssignedNone,
+		Short: i18n.T(
+			"also issue a client certificate from the local CA for --mtls",
+			"同时由本地 CA 签发客户端证书，用于 --mtls"),
+	})
+	return cmd
+}
+
+func newMCPProxyClientListCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "list",
+		Usage: "aliyun mcp-proxy client list",
+		Short: i18n.T("list registered clients", "列出已登记的客户端"),
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			return doListInboundClients(ctx)
+		},
+	}
+}
+
+func newMCPProxyClientRemoveCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "remove",
+		Usage: "aliyun mcp-proxy client remove --name NAME",
+		Short: i18n.T(
+			"remove a client, revoking its token and certificate",
+			"删除客户端，使其 token 和证书失效"),
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			return doRemoveInboundClient(ctx)
+		},
+	}
+	cmd.Flags().Add(newClientNameFlag())
+	return cmd
+}
+
+func doAddInboundClient(ctx *cli.Context) error {
+	name, _ := ctx.Flags().Get("name").GetValue()
+	if name == "" {
+		return fmt.Errorf("--name is required")
+	}
+	allowed := splitCommaList(ctx.Flags().Get("allowed-servers").GetStringOrDefault(""))
+	client, err := addInboundClient(name, allowed)
+	if err != nil {
+		return err
+	}
+
+	w := ctx.Stdout()
+	cli.Printf(w, "Added client '%s'\n", client.Name)
+	cli.Printf(w, "Token: %s\n", client.Token)
+	if len(allowed) > 0 {
+		cli.Printf(w, "Allowed servers: %s\n", strings.Join(allowed, ", "))
+	}
+	if ctx.Flags().Get("cert").IsAssigned() {
+		dir := getMCPTLSDir()
+		ca, err := loadOrCreateLocalCA(dir)
+		if err != nil {
+			return err
+		}
+		certPath, keyPath, err := ca.issueClientCertificate(dir, client.Name)
+		if err != nil {
+			return err
+		}
+		cli.Printf(w, "Certificate: %s\nKey: %s\nCA: %s\n", certPath, keyPath, filepath.Join(dir, localCACertFile))
+	}
+	return nil
+}
+
+// maskInboundToken 只显示 token 的前后几位
+func maskInboundToken(token string) string {
+	if len(token) <= len(inboundTokenPrefix)+8 {
+		return "***"
+	}
+	return token[:len(inboundTokenPrefix)+4] + "..." + token[len(token)-4:]
+}
+
+func doListInboundClients(ctx *cli.Context) error {
+	clients, err := loadInboundClients()
+	if err != nil {
+		return err
+	}
+	w := ctx.Stdout()
+	if len(clients) == 0 {
+		cli.Println(w, i18n.T("No clients registered.", "没有登记任何客户端。").GetMessage())
+		return nil
+	}
+	for _, c := range clients {
+		servers := "all servers"
+		if len(c.AllowedServers) > 0 {
+			servers = strings.Join(c.AllowedServers, ",")
+		}
+		cli.Printf(w, "%s  %s  %s", c.Name, maskInboundToken(c.Token), servers)
+		if c.CreatedAt > 0 {
+			cli.Printf(w, "  created %s", time.Unix(c.CreatedAt, 0).Local().Format(time.RFC3339))
+		}
+		cli.Println(w, "")
+	}
+	return nil
+}
+
+func doRemoveInboundClient(ctx *cli.Context) error {
+	name, _ := ctx.Flags().Get("name").GetValue()
+	if name == "" {
+		return fmt.Errorf("--name is required")
+	}
+	if err := removeInboundClient(name); err != nil {
+		return err
+	}
+	cli.Printf(ctx.Stdout(), "Removed client '%s'\n", name)
+	return nil
+}
+
+func printInboundAuthInfo(ctx *cli.Context, proxy *MCPProxy) {
+	w := ctx.Stdout()
+	if proxy.inboundAuth == nil {
+		cli.Println(w, "Inbound Auth: disabled")
+		return
+	}
+	var modes []string
+	if proxy.inboundAuth.config.RequireToken {
+		modes = append(modes, "bearer token")
+	}
+	if proxy.inboundAuth.config.MTLS {
+		modes = append(modes, "mTLS")
+	}
+	cli.Printf(w, "Inbound Auth: %s\n", strings.Join(modes, " + "))
+	if proxy.inboundAuth.config.MTLS {
+		cli.Printf(w, "  CA: %s\n", filepath.Join(proxy.inboundAuth.config.TLSDir, localCACertFile))
+	}
+	clients := proxy.inboundAuth.currentClients()
+	if proxy.inboundAuth.config.RequireToken {
+		if c := findInboundClient(clients, DefaultInboundClientName); c != nil {
+			cli.Printf(w, "  Default token: %s (send as 'Authorization: Bearer <token>')\n", c.Token)
+		}
+	}
+	var names []string
+	for _, c := range clients {
+		names = append(names, c.Name)
+	}
+	if len(names) > 0 {
+		cli.Printf(w, "  Clients: %s\n", strings.Join(names, ", "))
+	} else {
+		cli.Println(w, "  No clients registered, add one with 'aliyun mcp-proxy client add --name NAME --cert'")
+	}
+}
@@ -17,6 +17,7 @@ package mcpproxy
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/url"
 	"os"
 	"os/signal"
@@ -25,6 +26,7 @@ import (
 	"syscall"
 
 	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/config"
 	"github.com/aliyun/aliyun-cli/v3/i18n"
 )
 
@@ -40,12 +42,16 @@ func NewMCPProxyCommand() *cli.Command {
 				"代理自动处理 OAuth 认证，"+
 				"允许 MCP 客户端无需管理凭证即可连接。",
 		),
-		Usage:  "aliyun mcp-proxy [--port PORT] [--host HOST] [--region-type REGION_TYPE] [--upstream-url URL] [--oauth-app-name NAME]",
+		Usage:  "aliyun mcp-proxy [--port PORT] [--host HOST] [--region-type REGION_TYPE] [--upstream-url URL] [--oauth-app-name NAME] [--transport http|stdio] [--server NAME] [--traffic-log FILE] [--auth-token] [--mtls]",
 		Sample: "aliyun mcp-proxy --region-type CN --port 8088",
 		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
 			return runMCPProxy(ctx)
 		},
 	}
+	cmd.AddSubCommand(newMCPProxyClientCommand())
 
 	cmd.Flags().Add(&cli.Flag{
 		Name:         "port",
@@ -123,6 +129,91 @@ func NewMCPProxyCommand() *cli.Command {
 		),
 	})
 
+	cmd.Flags().Add(&cli.Flag{
+		Name: "allowed-tools",
+		Short: i18n.T(
+			"Comma-separated list of allowed tool name patterns; * is a wildcard and 'server:tool' limits a pattern to one server (e.g., 'Describe*,ecs:List*'). If not specified, all tools are allowed.",
+			"允许调用的工具名模式列表，用逗号分隔；* 为通配符，'server:tool' 表示仅对指定服务器生效（如 'Describe*,ecs:List*'）。如果不指定，则允许调用所有工具。",
+		),
+	})
+
+	cmd.Flags().Add(&cli.Flag{
+		Name: "blocked-tools",
+		Short: i18n.T(
+			"Comma-separated list of blocked tool name patterns, in the same form as --allowed-tools. Blocked tools are hidden from tools/list and their calls are rejected. Blacklist takes precedence over whitelist.",
+			"禁止调用的工具名模式列表，格式同 --allowed-tools。被禁止的工具不会出现在 tools/list 中，调用会被拒绝。黑名单优先级高于白名单。",
+		),
+	})
+
+	cmd.Flags().Add(&cli.Flag{
+		Name:         "transport",
+		DefaultValue: TransportHTTP,
+		Short: i18n.T(
+			"Transport for MCP clients: http (listen on --host/--port) or stdio (JSON-RPC over stdin/stdout, for clients that launch the proxy as a subprocess). In stdio mode OAuth uses manual code input from the terminal and no callback port is opened",
+			"面向 MCP 客户端的传输方式：http（监听 --host/--port）或 stdio（通过标准输入输出传递 JSON-RPC，适用于以子进程方式启动代理的客户端）。stdio 模式下 OAuth 通过终端手动输入授权码，不会监听回调端口",
+		),
+	})
+
+	cmd.Flags().Add(&cli.Flag{
+		Name: "server",
+		Short: i18n.T(
+			"Name or ID of the MCP server to forward to in stdio mode. May be omitted when only one server is allowed",
+			"stdio 模式下转发的 MCP 服务器名称或 ID。仅允许一个服务器时可省略",
+		),
+	})
+
+	cmd.Flags().Add(&cli.Flag{
+		Name: "traffic-log",
+		Short: i18n.T(
+			"Write a JSON-lines log of every JSON-RPC message passing through the proxy (direction, server, method, tool, id, latency, error code) to this file. Tool arguments are redacted and truncated; the file is rotated by size",
+			"将经过代理的每条 JSON-RPC 消息（方向、服务器、方法、工具、ID、耗时、错误码）以 JSON 行格式写入该文件。工具参数会脱敏并截断，文件按大小轮转",
+		),
+	})
+
+	cmd.Flags().Add(&cli.Flag{
+		Name: "traffic-log-redact",
+		Short: i18n.T(
+			"Comma-separated tool argument name patterns (* is a wildcard) whose values are masked in the traffic log, in addition to built-in secret-like names such as *Password* and *Token*. Use '*' to mask all argument values",
+			"流量日志中需要脱敏的工具参数名模式列表，用逗号分隔（* 为通配符），内置的 *Password*、*Token* 等敏感字段始终脱敏。使用 '*' 脱敏所有参数值",
+		),
+	})
+
+	cmd.Flags().Add(&cli.Flag{
+		Name:         "traffic-log-max-value-size",
+		DefaultValue: strconv.Itoa(defaultTrafficLogMaxValueSize),
+		Short: i18n.T(
+			"Maximum bytes of each tool argument value recorded in the traffic log; longer values are truncated",
+			"流量日志中每个工具参数值记录的最大字节数，超出部分截断",
+		),
+	})
+
+	cmd.Flags().Add(&cli.Flag{
+		Name:         "traffic-log-max-size",
+		DefaultValue: strconv.Itoa(defaultTrafficLogMaxSizeMB),
+		Short: i18n.T(
+			"Rotate the traffic log once it grows beyond this size in MB, keeping 5 rotated files",
+			"流量日志超过该大小（MB）后轮转，保留 5 个轮转文件",
+		),
+	})
+
+	cmd.Flags().Add(&cli.Flag{
+		Name:         "auth-token",
+		AssignedMode: cli.AssignedNone,
+		Short: i18n.T(
+			"Require clients to send 'Authorization: Bearer <token>' with a token registered by 'aliyun mcp-proxy client add'. If no client is registered, a 'default' client is created and its token is printed at startup",
+			"要求客户端携带 'Authorization: Bearer <token>'，token 通过 'aliyun mcp-proxy client add' 登记。没有登记任何客户端时自动创建 'default' 客户端并在启动时打印其 token",
+		),
+	})
+
+	cmd.Flags().Add(&cli.Flag{
+		Name:         "mtls",
+		AssignedMode: cli.AssignedNone,
+		Short: i18n.T(
+			"Serve HTTPS and require client certificates issued by the local CA ('aliyun mcp-proxy client add --cert'). The CA is created on first use; OAuth re-authorization then uses manual code input",
+			"以 HTTPS 监听，并要求客户端提供本地 CA 签发的证书（'aliyun mcp-proxy client add --cert'）。本地 CA 在首次使用时创建；此模式下 OAuth 重新授权使用手动输入授权码",
+		),
+	})
+
 	return cmd
 }
 
@@ -159,29 +250,25 @@ func runMCPProxy(ctx *cli.Context) error {
 	scope := ctx.Flags().Get("scope").GetStringOrDefault("/acs/mcp-server")
 	upstreamURL := ctx.Flags().Get("upstream-url").GetStringOrDefault("")
 	oauthAppName := ctx.Flags().Get("oauth-app-name").GetStringOrDefault("")
-	allowedServersStr := ctx.Flags().Get("allowed-servers").GetStringOrDefault("")
-	blockedServersStr := ctx.Flags().Get("blocked-servers").GetStringOrDefault("")
-
-	var allowedServers []string
-	if allowedServersStr != "" {
-		parts := strings.Split(allowedServersStr, ",")
-		for _, part := range parts {
-			trimmed := strings.TrimSpace(part)
-			if trimmed != "" {
-				allowedServers = append(allowedServers, trimmed)
-			}
-		}
+	allowedServers := splitCommaList(ctx.Flags().Get("allowed-servers").GetStringOrDefault(""))
+	blockedServers := splitCommaList(ctx.Flags().Get("blocked-servers").GetStringOrDefault(""))
+	allowedTools := splitCommaList(ctx.Flags().Get("allowed-tools").GetStringOrDefault(""))
+	blockedTools := splitCommaList(ctx.Flags().Get("blocked-tools").GetStringOrDefault(""))
+	transport := ctx.Flags().Get("transport").GetStringOrDefault(TransportHTTP)
+	if transport != TransportHTTP && transport != TransportStdio {
+		return fmt.Errorf("invalid transport: %s, must be http or stdio", transport)
 	}
-
-	var blockedServers []string
-	if blockedServersStr != "" {
-		parts := strings.Split(blockedServersStr, ",")
-		for _, part := range parts {
-			trimmed := strings.TrimSpace(part)
-			if trimmed != "" {
-				blockedServers = append(blockedServers, trimmed)
-			}
-		}
+	trafficLog, err := parseTrafficLogFlags(ctx)
+	if err != nil {
+		return err
+	}
+	inboundAuth := InboundAuthConfig{
+		RequireToken: ctx.Flags().Get("auth-token").IsAssigned(),
+		MTLS:         ctx.Flags().Get("mtls").IsAssigned(),
+		TLSDir:       getMCPTLSDir(),
+	}
+	if transport == TransportStdio && inboundAuth.Enabled() {
+		return fmt.Errorf("--auth-token and --mtls only apply to the http transport")
 	}
 
 	proxyConfig := ProxyConfig{
@@ -193,7 +280,13 @@ func runMCPProxy(ctx *cli.Context) error {
 		UpstreamBaseURL: upstreamURL,
 		OAuthAppName:    oauthAppName,
 		AllowedServers:  allowedServers,
-		BlockedServers: blockedServers,
+		BlockedServers:  blockedServers,
+		Transport:       transport,
+		AllowedTools:    allowedTools,
+		BlockedTools:    blockedTools,
+		SafetyConfigDir: config.GetConfigDir(ctx),
+		TrafficLog:      trafficLog,
+		InboundAuth:     inboundAuth,
 	}
 
 	mcpProfile, err := getOrCreateMCPProfile(ctx, proxyConfig)
@@ -201,9 +294,45 @@ func runMCPProxy(ctx *cli.Context) error {
 		return err
 	}
 	proxyConfig.McpProfile = mcpProfile
+	if profile, err := config.LoadProfileWithContext(ctx); err == nil {
+		proxyConfig.ProfileName = profile.Name
+	}
+	if transport == TransportStdio {
+		return startMCPStdioProxy(ctx, proxyConfig, ctx.Flags().Get("server").GetStringOrDefault(""))
+	}
 	return startMCPProxy(ctx, proxyConfig)
 }
 
+func parseTrafficLogFlags(ctx *cli.Context) (TrafficLogConfig, error) {
+	trafficLog := TrafficLogConfig{
+		Path:   ctx.Flags().Get("traffic-log").GetStringOrDefault(""),
+		Redact: splitCommaList(ctx.Flags().Get("traffic-log-redact").GetStringOrDefault("")),
+	}
+	valueSize := ctx.Flags().Get("traffic-log-max-value-size").GetStringOrDefault(strconv.Itoa(defaultTrafficLogMaxValueSize))
+	n, err := strconv.Atoi(valueSize)
+	if err != nil || n <= 0 {
+		return trafficLog, fmt.Errorf("invalid traffic log max value size: %s", valueSize)
+	}
+	trafficLog.MaxValueSize = n
+	maxSize := ctx.Flags().Get("traffic-log-max-size").GetStringOrDefault(strconv.Itoa(defaultTrafficLogMaxSizeMB))
+	n, err = strconv.Atoi(maxSize)
+	if err != nil || n <= 0 {
+		return trafficLog, fmt.Errorf("invalid traffic log max size: %s", maxSize)
+	}
+	trafficLog.MaxSizeMB = n
+	return trafficLog, nil
+}
+
+func splitCommaList(s string) []string {
+	var items []string
+	for _, part := range strings.Split(s, ",") {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			items = append(items, trimmed)
+		}
+	}
+	return items
+}
+
 func startMCPProxy(ctx *cli.Context, config ProxyConfig) error {
 	servers, err := ListMCPServers(ctx, config.RegionType)
 	if err != nil {
@@ -217,6 +346,10 @@ func startMCPProxy(ctx *cli.Context, config ProxyConfig) error {
 	config.CallbackManager = NewOAuthCallbackManager()
 	config.ExistMcpServers = servers
 
+	if err := setupInboundAuth(ctx, &config); err != nil {
+		return err
+	}
+
 	proxy := NewMCPProxy(config)
 	go proxy.TokenRefresher.Start()
 
@@ -262,9 +395,91 @@ func startMCPProxy(ctx *cli.Context, config ProxyConfig) error {
 	}
 }
 
+// startMCPStdioProxy 以 stdio 方式运行代理：stdout 只输出 JSON-RPC 消息，提示信息写到 stderr
+func startMCPStdioProxy(ctx *cli.Context, config ProxyConfig, serverName string) error {
+	servers, err := ListMCPServers(ctx, config.RegionType)
+	if err != nil {
+		return fmt.Errorf("failed to list MCP servers: %w", err)
+	}
+
+	if len(servers) == 0 {
+		return fmt.Errorf("no MCP servers found")
+	}
+
+	config.CallbackManager = NewOAuthCallbackManager()
+	config.ExistMcpServers = servers
+
+	proxy := NewMCPProxy(config)
+	server, err := proxy.selectStdioServer(serverName)
+	if err != nil {
+		return err
+	}
+	go proxy.TokenRefresher.Start()
+
+	cli.Printf(ctx.Stderr(), "MCP Proxy stdio transport started\nRegion: %s\nServer: %s\n", proxy.RegionType, server.Name)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	doneChan := make(chan error, 1)
+	go func() {
+		doneChan <- proxy.ServeStdio(os.Stdin, ctx.Stdout(), server)
+	}()
+
+	var result error
+	select {
+	case sig := <-sigChan:
+		cli.Printf(ctx.Stderr(), "Received signal: %v, shutting down...\n", sig)
+	case result = <-doneChan:
+	case result = <-proxy.TokenRefresher.fatalErrCh:
+		cli.Printf(ctx.Stderr(), "Fatal error: %v\n", result)
+	}
+	proxy.TokenRefresher.Stop()
+	if err := proxy.Stop(); err != nil {
+		cli.Printf(ctx.Stderr(), "Warning: %v\n", err)
+	}
+	return result
+}
+
+// setupInboundAuth 准备入站认证：开启 token 认证时确保至少有一个客户端，开启 mTLS 时加载本地 CA 并签发服务端证书
+func setupInboundAuth(ctx *cli.Context, config *ProxyConfig) error {
+	if config.InboundAuth.RequireToken {
+		if _, err := ensureDefaultInboundClient(); err != nil {
+			return fmt.Errorf("failed to set up inbound clients: %w", err)
+		}
+	}
+	if config.InboundAuth.MTLS {
+		tlsConfig, err := newMTLSConfig(config.InboundAuth.TLSDir, config.Host)
+		if err != nil {
+			return fmt.Errorf("failed to set up mTLS: %w", err)
+		}
+		config.TLSConfig = tlsConfig
+	}
+	if !config.InboundAuth.Enabled() && !isLoopbackHost(config.Host) {
+		cli.Printf(ctx.Stderr(), "Warning: the proxy listens on %s without inbound authentication, anyone who can reach it can use your MCP identity. Consider --auth-token or --mtls\n", config.Host)
+	}
+	return nil
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 func printProxyInfo(ctx *cli.Context, proxy *MCPProxy) {
-	cli.Printf(ctx.Stdout(), "\nMCP Proxy Server Started\nListen: %s:%d\nRegion: %s\n",
-		proxy.Host, proxy.Port, proxy.RegionType)
+	scheme := "http"
+	if proxy.tlsConfig != nil {
+		scheme = "https"
+	}
+	cli.Printf(ctx.Stdout(), "\nMCP Proxy Server Started\nListen: %s:%d\nRegion: %s\nMetrics: %s://%s:%d/metrics\n",
+		proxy.Host, proxy.Port, proxy.RegionType, scheme, proxy.Host, proxy.Port)
+	if proxy.traffic != nil {
+		cli.Printf(ctx.Stdout(), "Traffic Log: %s\n", proxy.traffic.config.Path)
+	}
+	printInboundAuthInfo(ctx, proxy)
 
 	hasAccessControl := len(proxy.BlockedServers) > 0 || len(proxy.AllowedServers) > 0
 	if hasAccessControl {
@@ -288,6 +503,16 @@ func printProxyInfo(ctx *cli.Context, proxy *MCPProxy) {
 		cli.Println(ctx.Stdout(), "\nAccess Control: All servers are allowed")
 	}
 
+	if len(proxy.BlockedTools) > 0 || len(proxy.AllowedTools) > 0 {
+		cli.Println(ctx.Stdout(), "\nTool Access Control:")
+		if len(proxy.BlockedTools) > 0 {
+			cli.Printf(ctx.Stdout(), "  Blocked tools: %s\n", strings.Join(proxy.BlockedTools, ", "))
+		}
+		if len(proxy.AllowedTools) > 0 {
+			cli.Printf(ctx.Stdout(), "  Allowed tools: %s\n", strings.Join(proxy.AllowedTools, ", "))
+		}
+	}
+
 	cli.Println(ctx.Stdout(), "\nAvailable Servers:")
 	for _, server := range proxy.ExistMcpServers {
 		isBlocked := proxy.isServerBlocked(server)
@@ -306,12 +531,12 @@ func printProxyInfo(ctx *cli.Context, proxy *MCPProxy) {
 		cli.Printf(ctx.Stdout(), "  - %s%s\n", server.Name, status)
 		if server.Urls.MCP != "" {
 			if upstreamURL, err := url.Parse(server.Urls.MCP); err == nil {
-				cli.Printf(ctx.Stdout(), "    MCP: http://%s:%d%s\n", proxy.Host, proxy.Port, upstreamURL.Path)
+				cli.Printf(ctx.Stdout(), "    MCP: %s://%s:%d%s\n", scheme, proxy.Host, proxy.Port, upstreamURL.Path)
 			}
 		}
 		if server.Urls.SSE != "" {
 			if upstreamURL, err := url.Parse(server.Urls.SSE); err == nil {
-				cli.Printf(ctx.Stdout(), "    SSE: http://%s:%d%s\n", proxy.Host, proxy.Port, upstreamURL.Path)
+				cli.Printf(ctx.Stdout(), "    SSE: %s://%s:%d%s\n", scheme, proxy.Host, proxy.Port, upstreamURL.Path)
 			}
 		}
 	}
@@ -51,6 +51,19 @@ func TestNewMCPProxyCommand(t *testing.T) {
 	scopeFlag := flags.Get("scope")
 	assert.NotNil(t, scopeFlag)
 	assert.Equal(t, "/acs/mcp-server", scopeFlag.DefaultValue)
+
+	transportFlag := flags.Get("transport")
+	assert.NotNil(t, transportFlag)
+	assert.Equal(t, "http", transportFlag.DefaultValue)
+	assert.NotNil(t, flags.Get("server"))
+
+	assert.NotNil(t, flags.Get("traffic-log"))
+	assert.NotNil(t, flags.Get("traffic-log-redact"))
+	assert.Equal(t, "256", flags.Get("traffic-log-max-value-size").DefaultValue)
+	assert.Equal(t, "10", flags.Get("traffic-log-max-size").DefaultValue)
+
+	assert.NotNil(t, flags.Get("auth-token"))
+	assert.NotNil(t, flags.Get("mtls"))
 }
 
 func TestGetContentFromApiResponse(t *testing.T) {
@@ -0,0 +1,275 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpproxy
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 入站认证：代理以自身的 OAuth 身份访问上游，未开启认证时本机（或 --host 0.0.0.0 时网络上）
+// 任何人都能借用该身份。开启后每个客户端（agent）在 MCP profile 中登记为一个 InboundClient，
+// 通过 bearer token 和/或本地 CA 签发的客户端证书认证，并可限制其能访问的服务器。
+
+const (
+	DefaultInboundClientName = "default"
+	inboundTokenPrefix       = "mcpp_"
+)
+
+var inboundClientNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+type InboundClient struct {
+	Name           string   `json:"name"`
+	Token          string   `json:"token,omitempty"`
+	AllowedServers []string `json:"allowed_servers,omitempty"` // 服务器名称、ID 或路径前缀，为空时不限制
+	CreatedAt      int64    `json:"created_at,omitempty"`
+}
+
+type InboundAuthConfig struct {
+	RequireToken bool   // 要求 Authorization: Bearer <token>
+	MTLS         bool   // 使用 HTTPS 并要求本地 CA 签发的客户端证书，证书 CN 为客户端名称
+	TLSDir       string // 本地 CA 和证书所在目录
+}
+
+func (c InboundAuthConfig) Enabled() bool {
+	return c.RequireToken || c.MTLS
+}
+
+func validateInboundClientName(name string) error {
+	if !inboundClientNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid client name '%s': use letters, digits, '.', '_' or '-' (at most 64 characters)", name)
+	}
+	return nil
+}
+
+func generateInboundToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return inboundTokenPrefix + hex.EncodeToString(b), nil
+}
+
+func findInboundClient(clients []InboundClient, name string) *InboundClient {
+	for i := range clients {
+		if clients[i].Name == name {
+			return &clients[i]
+		}
+	}
+	return nil
+}
+
+// loadInboundClients 从 MCP profile 文件读取客户端列表，文件不存在时返回空
+func loadInboundClients() ([]InboundClient, error) {
+	data, err := os.ReadFile(getMCPConfigPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	profile, err := NewMcpProfileFromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return profile.InboundClients, nil
+}
+
+// updateInboundClients 修改 MCP profile 文件中的客户端列表，其他字段保持不变。
+// 客户端列表以文件为准，运行中的代理会重新加载，保存 OAuth token 时也不会覆盖。
+func updateInboundClients(update func([]InboundClient) ([]InboundClient, error)) error {
+	profile := NewMcpProfile(DefaultMcpProfileName)
+	data, err := os.ReadFile(getMCPConfigPath())
+	if err == nil {
+		if profile, err = NewMcpProfileFromBytes(data); err != nil {
+			return fmt.Errorf("failed to parse mcp profile: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	clients, err := update(profile.InboundClients)
+	if err != nil {
+		return err
+	}
+	profile.InboundClients = clients
+	return writeMcpProfile(profile)
+}
+
+// addInboundClient 登记一个新客户端并生成 token
+func addInboundClient(name string, allowedServers []string) (*InboundClient, error) {
+	if err := validateInboundClientName(name); err != nil {
+		return nil, err
+	}
+	token, err := generateInboundToken()
+	if err != nil {
+		return nil, err
+	}
+	client := InboundClient{Name: name, Token: token, AllowedServers: allowedServers, CreatedAt: time.Now().Unix()}
+	err = updateInboundClients(func(clients []InboundClient) ([]InboundClient, error) {
+		if findInboundClient(clients, name) != nil {
+			return nil, fmt.Errorf("client '%s' already exists, remove it first to issue a new token", name)
+		}
+		return append(clients, client), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+func removeInboundClient(name string) error {
+	return updateInboundClients(func(clients []InboundClient) ([]InboundClient, error) {
+		kept := clients[:0]
+		for _, c := range clients {
+			if c.Name != name {
+				kept = append(kept, c)
+			}
+		}
+		if len(kept) == len(clients) {
+			return nil, fmt.Errorf("client '%s' not found", name)
+		}
+		return kept, nil
+	})
+}
+
+// ensureDefaultInboundClient 在开启 token 认证但还没有任何客户端时生成默认客户端
+func ensureDefaultInboundClient() (*InboundClient, error) {
+	clients, err := loadInboundClients()
+	if err != nil {
+		return nil, err
+	}
+	if c := findInboundClient(clients, DefaultInboundClientName); c != nil {
+		return c, nil
+	}
+	if len(clients) > 0 {
+		return nil, nil
+	}
+	return addInboundClient(DefaultInboundClientName, nil)
+}
+
+// inboundAuth 校验客户端请求，客户端列表按文件修改时间重新加载，增删客户端无需重启代理
+type inboundAuth struct {
+	config InboundAuthConfig
+
+	mu      sync.Mutex
+	path    string
+	modTime time.Time
+	clients []InboundClient
+	loaded  bool
+}
+
+func newInboundAuth(config InboundAuthConfig) *inboundAuth {
+	if !config.Enabled() {
+		return nil
+	}
+	return &inboundAuth{config: config, path: getMCPConfigPath()}
+}
+
+func (a *inboundAuth) currentClients() []InboundClient {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	info, err := os.Stat(a.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			a.clients = nil
+			a.modTime = time.Time{}
+		}
+		return a.clients
+	}
+	if a.loaded && info.ModTime().Equal(a.modTime) {
+		return a.clients
+	}
+	clients, err := loadInboundClients()
+	if err != nil {
+		log.Printf("MCP Proxy failed to load inbound clients: %v", err)
+		return a.clients
+	}
+	a.clients = clients
+	a.modTime = info.ModTime()
+	a.loaded = true
+	return a.clients
+}
+
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// authenticate 返回请求对应的客户端；同时开启 token 和 mTLS 时两者必须属于同一客户端
+func (a *inboundAuth) authenticate(r *http.Request) (*InboundClient, error) {
+	clients := a.currentClients()
+	var byCert, byToken *InboundClient
+
+	if a.config.MTLS {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			return nil, errors.New("client certificate required")
+		}
+		name := r.TLS.PeerCertificates[0].Subject.CommonName
+		if byCert = findInboundClient(clients, name); byCert == nil {
+			return nil, fmt.Errorf("client certificate '%s' is not registered", name)
+		}
+	}
+
+	if a.config.RequireToken {
+		token := bearerToken(r)
+		if token == "" {
+			return nil, errors.New("bearer token required")
+		}
+		for i := range clients {
+			if clients[i].Token != "" && subtle.ConstantTimeCompare([]byte(clients[i].Token), []byte(token)) == 1 {
+				byToken = &clients[i]
+				break
+			}
+		}
+		if byToken == nil {
+			return nil, errors.New("invalid bearer token")
+		}
+		if byCert != nil && byCert.Name != byToken.Name {
+			return nil, errors.New("bearer token does not belong to the client certificate")
+		}
+		return byToken, nil
+	}
+	return byCert, nil
+}
+
+// authorizeInbound 校验入站请求，失败时写入 401 并返回 false；未开启认证时返回 nil, true
+func (p *MCPProxy) authorizeInbound(w http.ResponseWriter, r *http.Request) (*InboundClient, bool) {
+	if p.inboundAuth == nil {
+		return nil, true
+	}
+	client, err := p.inboundAuth.authenticate(r)
+	if err != nil {
+		log.Printf("MCP Proxy rejected unauthenticated request %s: %v", r.URL.Path, err)
+		if p.inboundAuth.config.RequireToken {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="aliyun-mcp-proxy"`)
+		}
+		http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+		return nil, false
+	}
+	return client, true
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpproxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInboundClients(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	clients, err := loadInboundClients()
+	require.NoError(t, err)
+	assert.Empty(t, clients)
+
+	_, err = addInboundClient("bad name", nil)
+	assert.ErrorContains(t, err, "invalid client name")
+
+	a, err := addInboundClient("agent-a", []string{"ecs-server"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(a.Token, inboundTokenPrefix))
+	_, err = addInboundClient("agent-a", nil)
+	assert.ErrorContains(t, err, "already exists")
+
+	// 已有客户端时不再生成默认客户端
+	def, err := ensureDefaultInboundClient()
+	require.NoError(t, err)
+	assert.Nil(t, def)
+
+	// 保存 OAuth 信息时保留文件中的客户端
+	profile := NewMcpProfile(DefaultMcpProfileName)
+	profile.MCPOAuthAppId = "app-id"
+	require.NoError(t, saveMcpProfile(profile))
+	clients, err = loadInboundClients()
+	require.NoError(t, err)
+	require.Len(t, clients, 1)
+	assert.Equal(t, a.Token, clients[0].Token)
+	assert.Equal(t, []string{"ecs-server"}, clients[0].AllowedServers)
+	assert.Nil(t, profile.InboundClients)
+
+	require.NoError(t, removeInboundClient("agent-a"))
+	assert.ErrorContains(t, removeInboundClient("agent-a"), "not found")
+	data, err := os.ReadFile(getMCPConfigPath())
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"mcp_oauth_app_id": "app-id"`)
+
+	def, err = ensureDefaultInboundClient()
+	require.NoError(t, err)
+	require.NotNil(t, def)
+	assert.Equal(t, DefaultInboundClientName, def.Name)
+	again, err := ensureDefaultInboundClient()
+	require.NoError(t, err)
+	assert.Equal(t, def.Token, again.Token)
+}
+
+func TestInboundAuth_authenticate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	assert.Nil(t, newInboundAuth(InboundAuthConfig{}))
+
+	a, err := addInboundClient("agent-a", nil)
+	require.NoError(t, err)
+	b, err := addInboundClient("agent-b", nil)
+	require.NoError(t, err)
+
+	auth := newInboundAuth(InboundAuthConfig{RequireToken: true})
+	req := httptest.NewRequest(http.MethodPost, "/mcp/ecs", nil)
+	_, err = auth.authenticate(req)
+	assert.EqualError(t, err, "bearer token required")
+
+	req.Header.Set("Authorization", "Bearer wrong")
+	_, err = auth.authenticate(req)
+	assert.EqualError(t, err, "invalid bearer token")
+
+	req.Header.Set("Authorization", "bearer "+b.Token)
+	client, err := auth.authenticate(req)
+	require.NoError(t, err)
+	assert.Equal(t, "agent-b", client.Name)
+
+	// 删除客户端后无需重启即失效
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, removeInboundClient("agent-b"))
+	_, err = auth.authenticate(req)
+	assert.EqualError(t, err, "invalid bearer token")
+
+	withCert := func(r *http.Request, cn string) *http.Request {
+		r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: cn}}}}
+		return r
+	}
+	mtls := newInboundAuth(InboundAuthConfig{MTLS: true})
+	_, err = mtls.authenticate(httptest.NewRequest(http.MethodPost, "/mcp/ecs", nil))
+	assert.EqualError(t, err, "client certificate required")
+	_, err = mtls.authenticate(withCert(httptest.NewRequest(http.MethodPost, "/mcp/ecs", nil), "agent-b"))
+	assert.EqualError(t, err, "client certificate 'agent-b' is not registered")
+	client, err = mtls.authenticate(withCert(httptest.NewRequest(http.MethodPost, "/mcp/ecs", nil), "agent-a"))
+	require.NoError(t, err)
+	assert.Equal(t, "agent-a", client.Name)
+
+	c, err := addInboundClient("agent-c", nil)
+	require.NoError(t, err)
+	both := newInboundAuth(InboundAuthConfig{RequireToken: true, MTLS: true})
+	req = withCert(httptest.NewRequest(http.MethodPost, "/mcp/ecs", nil), "agent-a")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	_, err = both.authenticate(req)
+	assert.EqualError(t, err, "bearer token does not belong to the client certificate")
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	client, err = both.authenticate(req)
+	require.NoError(t, err)
+	assert.Equal(t, "agent-a", client.Name)
+}
+
+func TestMCPProxy_ServeMCPProxyRequest_InboundAuth(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":{}}`)
+	}))
+	defer upstream.Close()
+
+	all, err := addInboundClient("all", nil)
+	require.NoError(t, err)
+	rds, err := addInboundClient("rds-only", []string{"rds-server"})
+	require.NoError(t, err)
+
+	profile := NewMcpProfile("p")
+	profile.MCPOAuthAccessToken = "token"
+	profile.MCPOAuthAccessTokenExpire = time.Now().Unix() + 3600
+	proxy := NewMCPProxy(ProxyConfig{
+		McpProfile:      profile,
+		ExistMcpServers: []MCPServerInfo{guardTestServer},
+		UpstreamBaseURL: upstream.URL,
+		InboundAuth:     InboundAuthConfig{RequireToken: true},
+	})
+
+	send := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		proxy.ServeMCPProxyRequest(w, req)
+		return w
+	}
+
+	w := send("/mcp/ecs", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+
+	w = send("/mcp/ecs", all.Token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"result":{}`)
+
+	w = send("/mcp/ecs", rds.Token)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "not allowed for this client")
+
+	w = httptest.NewRecorder()
+	proxy.handleMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
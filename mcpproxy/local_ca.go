@@ -0,0 +1,236 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpproxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aliyun/aliyun-cli/v3/config"
+)
+
+// 本地 CA：mTLS 模式下代理用它签发自己的服务端证书，并只接受由它签发的客户端证书。
+// CA 私钥只保存在本机配置目录中（权限 0600），客户端证书通过 aliyun mcp-proxy client add --cert 签发。
+
+const (
+	localCACertFile = "ca.crt"
+	localCAKeyFile  = "ca.key"
+
+	localCAValidity          = 10 * 365 * 24 * time.Hour
+	localCertificateValidity = 365 * 24 * time.Hour
+)
+
+func getMCPTLSDir() string {
+	return filepath.Join(config.GetConfigPath(), "mcpproxy_tls")
+}
+
+type localCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newSerialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func writePEM(path, blockType string, der []byte, mode os.FileMode) error {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func readPEM(path, blockType string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%s does not contain a %s PEM block", path, blockType)
+	}
+	return block.Bytes, nil
+}
+
+// loadOrCreateLocalCA 加载 dir 中的本地 CA，不存在时生成新的 CA
+func loadOrCreateLocalCA(dir string) (*localCA, error) {
+	certPath := filepath.Join(dir, localCACertFile)
+	keyPath := filepath.Join(dir, localCAKeyFile)
+
+	certDER, certErr := readPEM(certPath, "CERTIFICATE")
+	keyDER, keyErr := readPEM(keyPath, "EC PRIVATE KEY")
+	if certErr == nil && keyErr == nil {
+		cert, err := x509.ParseCertificate(certDER)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", certPath, err)
+		}
+		key, err := x509.ParseECPrivateKey(keyDER)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", keyPath, err)
+		}
+		return &localCA{cert: cert, key: key}, nil
+	}
+	if !errors.Is(certErr, os.ErrNotExist) || !errors.Is(keyErr, os.ErrNotExist) {
+		// 只存在其中一个文件或文件损坏时不自动覆盖，避免已签发的证书失效
+		return nil, fmt.Errorf("local CA in %s is incomplete or invalid: certificate: %v, key: %v", dir, certErr, keyErr)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "Aliyun CLI MCP Proxy Local CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(localCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create local CA: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := writePEM(keyPath, "EC PRIVATE KEY", keyBytes, 0600); err != nil {
+		return nil, err
+	}
+	if err := writePEM(certPath, "CERTIFICATE", der, 0644); err != nil {
+		return nil, err
+	}
+	return &localCA{cert: cert, key: key}, nil
+}
+
+// issue 签发叶子证书，返回证书 DER 和私钥
+func (ca *localCA) issue(template *x509.Certificate) ([]byte, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	template.SerialNumber = serial
+	template.NotBefore = now.Add(-time.Hour)
+	template.NotAfter = now.Add(localCertificateValidity)
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return der, key, nil
+}
+
+// serverCertificate 为代理签发服务端证书，包含 localhost、回环地址和监听地址
+func (ca *localCA) serverCertificate(host string) (tls.Certificate, error) {
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "aliyun-mcp-proxy"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if !ip.IsLoopback() && !ip.IsUnspecified() {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		}
+	} else if host != "" && host != "localhost" {
+		template.DNSNames = append(template.DNSNames, host)
+	}
+	der, key, err := ca.issue(template)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to issue server certificate: %w", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der, ca.cert.Raw}, PrivateKey: key}, nil
+}
+
+// issueClientCertificate 为客户端签发证书，CN 为客户端名称，写入 dir/clients/<name>.crt 和 <name>.key
+func (ca *localCA) issueClientCertificate(dir, name string) (certPath, keyPath string, err error) {
+	der, key, err := ca.issue(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to issue client certificate: %w", err)
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	clientsDir := filepath.Join(dir, "clients")
+	if err := os.MkdirAll(clientsDir, 0700); err != nil {
+		return "", "", fmt.Errorf("failed to create directory %s: %w", clientsDir, err)
+	}
+	certPath = filepath.Join(clientsDir, name+".crt")
+	keyPath = filepath.Join(clientsDir, name+".key")
+	if err := writePEM(keyPath, "EC PRIVATE KEY", keyBytes, 0600); err != nil {
+		return "", "", err
+	}
+	if err := writePEM(certPath, "CERTIFICATE", der, 0644); err != nil {
+		return "", "", err
+	}
+	return certPath, keyPath, nil
+}
+
+// newMTLSConfig 返回要求并校验本地 CA 签发的客户端证书的 TLS 配置
+func newMTLSConfig(dir, host string) (*tls.Config, error) {
+	ca, err := loadOrCreateLocalCA(dir)
+	if err != nil {
+		return nil, err
+	}
+	serverCert, err := ca.serverCertificate(host)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}, nil
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpproxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOrCreateLocalCA(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tls")
+	ca, err := loadOrCreateLocalCA(dir)
+	require.NoError(t, err)
+	assert.True(t, ca.cert.IsCA)
+
+	info, err := os.Stat(filepath.Join(dir, localCAKeyFile))
+	require.NoError(t, err)
+	if os.PathSeparator == '/' {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+
+	again, err := loadOrCreateLocalCA(dir)
+	require.NoError(t, err)
+	assert.Equal(t, ca.cert.Raw, again.cert.Raw)
+
+	// 缺少私钥时不覆盖已有的 CA
+	require.NoError(t, os.Remove(filepath.Join(dir, localCAKeyFile)))
+	_, err = loadOrCreateLocalCA(dir)
+	assert.ErrorContains(t, err, "incomplete or invalid")
+}
+
+func TestLocalCA_serverCertificate(t *testing.T) {
+	ca, err := loadOrCreateLocalCA(t.TempDir())
+	require.NoError(t, err)
+
+	cert, err := ca.serverCertificate("10.0.0.8")
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"localhost"}, leaf.DNSNames)
+	assert.Len(t, leaf.IPAddresses, 3)
+	assert.NoError(t, leaf.VerifyHostname("127.0.0.1"))
+	assert.NoError(t, leaf.VerifyHostname("10.0.0.8"))
+
+	cert, err = ca.serverCertificate("proxy.internal")
+	require.NoError(t, err)
+	leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"localhost", "proxy.internal"}, leaf.DNSNames)
+}
+
+func TestNewMTLSConfig_Handshake(t *testing.T) {
+	dir := t.TempDir()
+	serverConfig, err := newMTLSConfig(dir, "127.0.0.1")
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	server.TLS = serverConfig
+	server.StartTLS()
+	defer server.Close()
+
+	ca, err := loadOrCreateLocalCA(dir)
+	require.NoError(t, err)
+	certPath, keyPath, err := ca.issueClientCertificate(dir, "agent-a")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "clients", "agent-a.crt"), certPath)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	clientCert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	require.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      pool,
+		Certificates: []tls.Certificate{clientCert},
+	}}}
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "agent-a", string(body))
+
+	// 没有客户端证书时握手失败
+	noCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	_, err = noCert.Get(server.URL)
+	assert.Error(t, err)
+}
@@ -37,6 +37,9 @@ type McpProfile struct {
 	MCPOAuthAccessTokenExpire    int64  `json:"mcp_oauth_access_token_expire,omitempty"`
 	MCPOAuthRefreshTokenValidity int    `json:"mcp_oauth_refresh_token_validity,omitempty"`
 	MCPOAuthRefreshTokenExpire   int64  `json:"mcp_oauth_refresh_token_expire,omitempty"`
+
+	// InboundClients 为允许连接代理的客户端，由 aliyun mcp-proxy client 命令维护
+	InboundClients []InboundClient `json:"inbound_clients,omitempty"`
 }
 
 func getMCPConfigPath() string {
@@ -57,6 +60,20 @@ func NewMcpProfileFromBytes(bytes []byte) (profile *McpProfile, err error) {
 }
 
 func saveMcpProfile(profile *McpProfile) error {
+	// 入站客户端列表以文件为准，保存 OAuth 信息时保留文件中的客户端，避免覆盖代理运行期间增删的客户端
+	toSave := *profile
+	if clients, err := loadInboundClients(); err == nil {
+		toSave.InboundClients = clients
+	}
+	if err := writeMcpProfile(&toSave); err != nil {
+		return err
+	}
+
+	log.Printf("saveMcpProfile: Successfully saved MCP profile")
+	return nil
+}
+
+func writeMcpProfile(profile *McpProfile) error {
 	mcpConfigPath := getMCPConfigPath()
 	dir := filepath.Dir(mcpConfigPath)
 
@@ -88,8 +105,6 @@ func saveMcpProfile(profile *McpProfile) error {
 		_ = os.Remove(tempFile)
 		return fmt.Errorf("failed to rename temp file to %q: %w", mcpConfigPath, err)
 	}
-
-	log.Printf("saveMcpProfile: Successfully saved MCP profile")
 	return nil
 }
 
@@ -168,6 +183,12 @@ func getOrCreateMCPProfile(ctx *cli.Context, opts ProxyConfig) (*McpProfile, err
 		desiredAppName = MCPOAuthAppName
 	}
 
+	// stdio 模式下 stdout 只能输出 JSON-RPC 消息
+	out := ctx.Stdout()
+	if opts.Transport == TransportStdio {
+		out = ctx.Stderr()
+	}
+
 	existingMcpProfile := loadExistingMCPProfile(ctx, profile, opts, desiredAppName)
 	if existingMcpProfile != nil {
 		// mcpprofile might change, save it again to ensure the latest state is saved
@@ -187,14 +208,14 @@ func getOrCreateMCPProfile(ctx *cli.Context, opts ProxyConfig) (*McpProfile, err
 			// if user provide app name, but not found, return error
 			return nil, fmt.Errorf("OAuth application '%s' not found", opts.OAuthAppName)
 		}
-		cli.Printf(ctx.Stdout(), "Creating new default MCP profile '%s'...\n", DefaultMcpProfileName)
+		cli.Printf(out, "Creating new default MCP profile '%s'...\n", DefaultMcpProfileName)
 		app, err = createDefaultMCPOauthApplication(ctx, profile, opts.RegionType, opts.Host, opts.Port, opts.Scope)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create default OAuth application: %w", err)
 		}
-		cli.Printf(ctx.Stdout(), "Created new default OAuth application '%s' (AppId: %s)\n", app.AppName, app.ApplicationId)
+		cli.Printf(out, "Created new default OAuth application '%s' (AppId: %s)\n", app.AppName, app.ApplicationId)
 	} else {
-		cli.Printf(ctx.Stdout(), "Using existing OAuth application '%s' (AppId: %s)\n", app.AppName, app.ApplicationId)
+		cli.Printf(out, "Using existing OAuth application '%s' (AppId: %s)\n", app.AppName, app.ApplicationId)
 	}
 
 	if err := validateOAuthApplication(app, opts.Scope, opts.Host, opts.Port); err != nil {
@@ -202,7 +223,7 @@ func getOrCreateMCPProfile(ctx *cli.Context, opts ProxyConfig) (*McpProfile, err
 	}
 	validatedApp := app
 
-	cli.Printf(ctx.Stdout(), "Setting up MCPOAuth profile '%s'...\n", DefaultMcpProfileName)
+	cli.Printf(out, "Setting up MCPOAuth profile '%s'...\n", DefaultMcpProfileName)
 	mcpProfile := NewMcpProfile(DefaultMcpProfileName)
 	mcpProfile.MCPOAuthSiteType = string(opts.RegionType)
 	mcpProfile.MCPOAuthAppId = validatedApp.ApplicationId
@@ -214,7 +235,13 @@ func getOrCreateMCPProfile(ctx *cli.Context, opts ProxyConfig) (*McpProfile, err
 
 	// noBrowser=true 表示禁用自动打开浏览器，autoOpenBrowser=false
 	// noBrowser=false 表示启用自动打开浏览器，autoOpenBrowser=true
-	tokenResult, err := startMCPOAuthFlow(ctx, mcpProfile.MCPOAuthAppId, opts.RegionType, opts.Host, opts.Port, opts.AutoOpenBrowser, opts.Scope)
+	var tokenResult *OAuthTokenResult
+	if opts.Transport == TransportStdio {
+		// stdio 模式不监听回调端口，授权码从终端手动输入
+		tokenResult, err = startMCPOAuthFlowManual(ctx, mcpProfile.MCPOAuthAppId, opts.RegionType, opts.Host, opts.Port, opts.Scope, ttyAuthCodeInput)
+	} else {
+		tokenResult, err = startMCPOAuthFlow(ctx, mcpProfile.MCPOAuthAppId, opts.RegionType, opts.Host, opts.Port, opts.AutoOpenBrowser, opts.Scope)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("OAuth login failed: %w", err)
 	}
@@ -238,7 +265,7 @@ func getOrCreateMCPProfile(ctx *cli.Context, opts ProxyConfig) (*McpProfile, err
 		return nil, fmt.Errorf("failed to save mcp profile: %w", err)
 	}
 
-	cli.Printf(ctx.Stdout(), "MCP Profile '%s' configured for oauth app '%s' successfully!\n", mcpProfile.Name, mcpProfile.MCPOAuthAppName)
+	cli.Printf(out, "MCP Profile '%s' configured for oauth app '%s' successfully!\n", mcpProfile.Name, mcpProfile.MCPOAuthAppName)
 
 	return mcpProfile, nil
 }
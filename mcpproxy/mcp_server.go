@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -112,8 +113,21 @@ type ProxyConfig struct {
 	OAuthAppName    string   // 用户自定义的 OAuth 应用名称，如果为空则使用默认的 OAuth 应用
 	AllowedServers  []string // 允许访问的服务器列表（服务器名称、ID 或路径前缀），如果为空则允许所有服务器
 	BlockedServers  []string // 禁止访问的服务器列表（服务器名称、ID 或路径前缀），黑名单优先级高于白名单
+	Transport       string   // 传输方式：http（默认）或 stdio
+	AllowedTools    []string // 允许调用的工具名模式（支持 * 通配，"server:tool" 限定服务器），如果为空则允许所有工具
+	BlockedTools    []string // 禁止调用的工具名模式，黑名单优先级高于白名单
+	SafetyConfigDir string   // 加载安全策略的配置目录，为空时不检查安全策略
+	ProfileName     string   // 当前 CLI profile 名称，用于安全策略的 profile 条件
+	TrafficLog      TrafficLogConfig
+	InboundAuth     InboundAuthConfig
+	TLSConfig       *tls.Config // 非空时以 HTTPS 监听（mTLS 模式）
 }
 
+const (
+	TransportHTTP  = "http"
+	TransportStdio = "stdio"
+)
+
 type MCPProxy struct {
 	Host            string
 	Port            int
@@ -127,6 +141,16 @@ type MCPProxy struct {
 	AllowedServers  []string            // 允许访问的服务器列表（服务器名称、ID 或路径前缀），如果为空则允许所有服务器
 	BlockedServers  []string            // 禁止访问的服务器列表（服务器名称、ID 或路径前缀），黑名单优先级高于白名单
 	serverPaths     map[string][]string // 服务器名称/ID -> 路径列表的映射，启动时构建，避免重复解析
+	AllowedTools    []string            // 允许调用的工具名模式，如果为空则允许所有工具
+	BlockedTools    []string            // 禁止调用的工具名模式，黑名单优先级高于白名单
+	safetyConfigDir string
+	profileName     string
+	metrics         *proxyMetrics  // 按上游服务器区分的延迟、状态码和 SSE 时长，由 /metrics 输出
+	traffic         *trafficLogger // JSON-RPC 流量日志，未开启时为 nil
+	inboundAuth     *inboundAuth   // 入站认证，未开启时为 nil
+	tlsConfig       *tls.Config
+	sseMu           sync.Mutex
+	sseSessions     map[string]*sseSession // 旧版 SSE 传输的消息地址 -> 事件流
 }
 
 const (
@@ -150,10 +174,11 @@ type TokenRefresher struct {
 	regionType      RegionType
 	scope           string // OAuth scope
 	callbackManager *OAuthCallbackManager
-	mu              sync.RWMutex // 保护刷新操作的读写锁
-	refreshing      bool         // 标记是否正在刷新，防止重复刷新
-	reauthorizing   bool         // 标记是否正在重新授权，防止重复重新授权
-	autoOpenBrowser bool         // 是否自动打开浏览器（false 表示手动输入 code 模式）
+	mu              sync.RWMutex  // 保护刷新操作的读写锁
+	refreshing      bool          // 标记是否正在刷新，防止重复刷新
+	reauthorizing   bool          // 标记是否正在重新授权，防止重复重新授权
+	autoOpenBrowser bool          // 是否自动打开浏览器（false 表示手动输入 code 模式）
+	codeInput       authCodeInput // 手动输入授权码的来源，为空时使用标准输入
 	stopCh          chan struct{}
 	tokenCh         chan TokenInfo // 用于传递 token 的 channel
 	ticker          *time.Ticker
@@ -187,6 +212,18 @@ func NewMCPProxy(config ProxyConfig) *MCPProxy {
 		}
 	}
 
+	// stdio 模式下没有回调端口，标准输入也被 JSON-RPC 占用，重新授权时从终端手动输入授权码
+	autoOpenBrowser := config.AutoOpenBrowser
+	var codeInput authCodeInput
+	if config.Transport == TransportStdio {
+		autoOpenBrowser = false
+		codeInput = ttyAuthCodeInput
+	}
+	// mTLS 模式下浏览器无法访问 HTTPS 上的回调地址，重新授权时手动输入授权码
+	if config.InboundAuth.MTLS {
+		autoOpenBrowser = false
+	}
+
 	return &MCPProxy{
 		Host:            config.Host,
 		Port:            config.Port,
@@ -199,7 +236,8 @@ func NewMCPProxy(config ProxyConfig) *MCPProxy {
 			host:            config.Host,
 			port:            config.Port,
 			scope:           config.Scope,
-			autoOpenBrowser: config.AutoOpenBrowser,
+			autoOpenBrowser: autoOpenBrowser,
+			codeInput:       codeInput,
 			stopCh:          make(chan struct{}),
 			tokenCh:         make(chan TokenInfo, 1), // 带缓冲的 channel，存储最新的 token
 			fatalErrCh:      make(chan error, 1),
@@ -211,6 +249,14 @@ func NewMCPProxy(config ProxyConfig) *MCPProxy {
 		AllowedServers:  config.AllowedServers,
 		BlockedServers:  config.BlockedServers,
 		serverPaths:     serverPaths,
+		AllowedTools:    config.AllowedTools,
+		BlockedTools:    config.BlockedTools,
+		safetyConfigDir: config.SafetyConfigDir,
+		profileName:     config.ProfileName,
+		metrics:         newProxyMetrics(),
+		traffic:         newTrafficLogger(config.TrafficLog),
+		inboundAuth:     newInboundAuth(config.InboundAuth),
+		tlsConfig:       config.TLSConfig,
 	}
 }
 
@@ -218,16 +264,24 @@ func (p *MCPProxy) Start() error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/callback", p.handleOAuthCallback)
 	mux.HandleFunc("/health", p.handleHealth)
+	mux.HandleFunc("/metrics", p.handleMetrics)
 	mux.HandleFunc("/", p.ServeMCPProxyRequest)
 
 	p.Server = &http.Server{
-		Addr:    fmt.Sprintf("%s:%d", p.Host, p.Port),
-		Handler: mux,
+		Addr:      fmt.Sprintf("%s:%d", p.Host, p.Port),
+		Handler:   mux,
+		TLSConfig: p.tlsConfig,
 	}
 
 	log.Printf("MCP Proxy starting on %s:%d\n", p.Host, p.Port)
 
-	if err := p.Server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	var err error
+	if p.tlsConfig != nil {
+		err = p.Server.ListenAndServeTLS("", "")
+	} else {
+		err = p.Server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("proxy server failed: %w", err)
 	}
 
@@ -375,6 +429,22 @@ func (p *MCPProxy) ServeMCPProxyRequest(w http.ResponseWriter, r *http.Request)
 	default:
 	}
 
+	client, ok := p.authorizeInbound(w, r)
+	if !ok {
+		atomic.AddInt64(&p.stats.ErrorRequests, 1)
+		return
+	}
+	if client != nil && len(client.AllowedServers) > 0 && !p.pathInServerList(client.AllowedServers, path) {
+		log.Printf("MCP Proxy access denied: path %s is not allowed for client %s", path, client.Name)
+		atomic.AddInt64(&p.stats.ErrorRequests, 1)
+		http.Error(w, "Access denied: This MCP server is not allowed for this client", http.StatusForbidden)
+		return
+	}
+	clientName := ""
+	if client != nil {
+		clientName = client.Name
+	}
+
 	// 访问控制检查：优先级 黑名单 > 白名单 > 默认允许
 	if len(p.BlockedServers) > 0 {
 		if p.isPathBlocked(path) {
@@ -414,12 +484,66 @@ func (p *MCPProxy) ServeMCPProxyRequest(w http.ResponseWriter, r *http.Request)
 			return
 		}
 		_ = r.Body.Close()
-		log.Println("MCP Proxy upstream request body content", string(bodyBytes))
+		log.Printf("MCP Proxy upstream request body %d bytes", len(bodyBytes))
 		r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
 	} else {
 		log.Println("MCP Proxy upstream request body <nil>")
 	}
 
+	// 工具级访问控制和安全策略检查，被拒绝的调用不再转发到上游
+	server := p.serverForPath(path)
+	// 旧版 SSE 传输：GET 建立的事件流承载后续所有响应，POST 到消息地址的请求属于对应的事件流
+	var session, stream *sseSession
+	if r.Method == http.MethodGet {
+		stream = newSSESession(server)
+	} else if session = p.sseSessionFor(r.URL); session != nil {
+		server = session.server
+	}
+	tx := p.traffic.begin(TransportHTTP, server, clientName, bodyBytes)
+	if tx != nil {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		w = recorder
+		defer func() { tx.finish(recorder.status) }()
+	}
+	var listIDs map[string]bool
+	if r.Method == http.MethodPost && len(bodyBytes) > 0 {
+		var reply []byte
+		reply, listIDs = p.guardRequest(server, bodyBytes)
+		if reply != nil {
+			atomic.AddInt64(&p.stats.ErrorRequests, 1)
+			if len(reply) == 0 {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			tx.respond(reply)
+			if session != nil {
+				if !session.send(reply) {
+					http.Error(w, "SSE stream closed", http.StatusGone)
+					return
+				}
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write(reply)
+			return
+		}
+		if session != nil {
+			session.expectList(listIDs)
+			listIDs = nil
+		}
+	}
+	rewrite := func(data []byte) []byte {
+		if stream != nil {
+			data = stream.filter(p, data)
+		} else {
+			data = p.filterToolsList(server, data, listIDs)
+		}
+		tx.respond(data)
+		return data
+	}
+
 	sendRequest := func(token string) (*http.Response, error) {
 		upstreamReq, err := p.buildUpstreamRequest(r, token)
 		if err != nil {
@@ -433,10 +557,13 @@ func (p *MCPProxy) ServeMCPProxyRequest(w http.ResponseWriter, r *http.Request)
 		}
 
 		client := &http.Client{Timeout: 0}
+		start := time.Now()
 		resp, err := client.Do(upstreamReq)
 		if err != nil {
+			p.metrics.observeUpstream(serverLabel(server), 0, time.Since(start))
 			return nil, fmt.Errorf("failed to send request: %w", err)
 		}
+		p.metrics.observeUpstream(serverLabel(server), resp.StatusCode, time.Since(start))
 		return resp, nil
 	}
 
@@ -455,22 +582,7 @@ func (p *MCPProxy) ServeMCPProxyRequest(w http.ResponseWriter, r *http.Request)
 	// 如果响应状态码为 401，先尝试刷新 token，然后重试请求
 	if resp.StatusCode == http.StatusUnauthorized {
 		log.Println("MCP Proxy gets mcp server response status code 401, attempting to refresh token")
-		var refreshErr error
-		p.TokenRefresher.mu.RLock()
-		MCPOAuthRefreshTokenExpire := p.TokenRefresher.profile.MCPOAuthRefreshTokenExpire
-		currentTime := util.GetCurrentUnixTime()
-		p.TokenRefresher.mu.RUnlock()
-		if MCPOAuthRefreshTokenExpire > currentTime {
-			// refresh token 未过期，尝试刷新 access token
-			log.Println("Received 401, attempting to refresh access token using refresh token")
-			refreshErr = p.TokenRefresher.refreshAccessToken()
-		} else {
-			// refresh token 已过期，需要重新授权
-			log.Println("Received 401, refresh token expired, reauthorizing")
-			refreshErr = p.TokenRefresher.reauthorizeWithProxy()
-		}
-
-		if refreshErr != nil {
+		if refreshErr := p.TokenRefresher.recoverFromUnauthorized(); refreshErr != nil {
 			log.Printf("Failed to handle 401: %v", refreshErr)
 			atomic.AddInt64(&p.stats.ErrorRequests, 1)
 			http.Error(w, fmt.Sprintf("Authentication failed: %v", refreshErr), http.StatusUnauthorized)
@@ -517,7 +629,9 @@ func (p *MCPProxy) ServeMCPProxyRequest(w http.ResponseWriter, r *http.Request)
 	log.Println("MCP Proxy gets mcp server response content type", resp.Header.Get("Content-Type"))
 	contentType := resp.Header.Get("Content-Type")
 	if strings.Contains(strings.ToLower(contentType), "text/event-stream") {
-		p.handleSSE(w, resp)
+		streamStart := time.Now()
+		p.handleSSE(w, resp, rewrite, stream)
+		p.metrics.observeSSE(serverLabel(server), time.Since(streamStart))
 		if resp.StatusCode < 400 {
 			atomic.AddInt64(&p.stats.SuccessRequests, 1)
 		} else {
@@ -526,7 +640,7 @@ func (p *MCPProxy) ServeMCPProxyRequest(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	p.handleHTTP(w, resp)
+	p.handleHTTP(w, resp, rewrite)
 	if resp.StatusCode < 400 {
 		atomic.AddInt64(&p.stats.SuccessRequests, 1)
 	} else {
@@ -572,25 +686,7 @@ func (p *MCPProxy) isPathBlocked(requestPath string) bool {
 	if len(p.BlockedServers) == 0 {
 		return false
 	}
-
-	for _, blocked := range p.BlockedServers {
-		if strings.HasPrefix(blocked, "/") {
-			if strings.HasPrefix(requestPath, blocked) {
-				return true
-			}
-			continue
-		}
-
-		if paths, exists := p.serverPaths[blocked]; exists {
-			for _, path := range paths {
-				if strings.HasPrefix(requestPath, path) {
-					return true
-				}
-			}
-		}
-	}
-
-	return false
+	return p.pathInServerList(p.BlockedServers, requestPath)
 }
 
 func (p *MCPProxy) isServerAllowed(server MCPServerInfo) bool {
@@ -630,16 +726,20 @@ func (p *MCPProxy) isPathAllowed(requestPath string) bool {
 	if len(p.AllowedServers) == 0 {
 		return true
 	}
+	return p.pathInServerList(p.AllowedServers, requestPath)
+}
 
-	for _, allowed := range p.AllowedServers {
-		if strings.HasPrefix(allowed, "/") {
-			if strings.HasPrefix(requestPath, allowed) {
+// pathInServerList 判断请求路径是否属于列表中的服务器（服务器名称、ID 或路径前缀）
+func (p *MCPProxy) pathInServerList(servers []string, requestPath string) bool {
+	for _, server := range servers {
+		if strings.HasPrefix(server, "/") {
+			if strings.HasPrefix(requestPath, server) {
 				return true
 			}
 			continue
 		}
 
-		if paths, exists := p.serverPaths[allowed]; exists {
+		if paths, exists := p.serverPaths[server]; exists {
 			for _, path := range paths {
 				if strings.HasPrefix(requestPath, path) {
 					return true
@@ -735,7 +835,9 @@ func (p *MCPProxy) buildUpstreamRequest(r *http.Request, accessToken string) (*h
 	return upstreamReq, nil
 }
 
-func (p *MCPProxy) handleSSE(w http.ResponseWriter, resp *http.Response) {
+// handleSSE 转发 SSE 流，rewrite 用于改写单行 data 中的 JSON-RPC 消息。
+// stream 非空时为旧版 SSE 传输的事件流：登记 endpoint 事件给出的消息地址，并在事件边界处写出代理生成的响应。
+func (p *MCPProxy) handleSSE(w http.ResponseWriter, resp *http.Response, rewrite func([]byte) []byte, stream *sseSession) {
 	log.Println("MCP Proxy handle SSE response from upstream request url", resp.Request.URL.String())
 
 	w.Header().Set("Content-Type", "text/event-stream")
@@ -761,31 +863,91 @@ func (p *MCPProxy) handleSSE(w http.ResponseWriter, resp *http.Response) {
 
 	w.WriteHeader(resp.StatusCode)
 
-	reader := bufio.NewReader(resp.Body)
+	var inject chan []byte
+	if stream != nil {
+		inject = stream.inject
+		defer close(stream.done)
+	}
+	sessionKey := ""
+	defer func() {
+		if sessionKey != "" {
+			p.unregisterSSESession(sessionKey, stream)
+		}
+	}()
+
+	// 上游的读取放在单独的 goroutine 中，以便在等待上游时写出代理生成的响应
+	lines := make(chan []byte)
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		defer close(lines)
+		reader := bufio.NewReader(resp.Body)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+			select {
+			case lines <- line:
+			case <-stopped:
+				return
+			}
+		}
+	}()
+
+	event := ""
+	atBoundary := true
 	for {
-		// 检查是否正在关闭
+		// 只在事件之间插入响应，避免拆开上游的事件
+		var pending chan []byte
+		if atBoundary {
+			pending = inject
+		}
+		var line []byte
 		select {
 		case <-p.stopCh:
 			log.Println("MCP Proxy handle SSE connection closed due to server shutdown")
 			return
-		default:
-		}
+		case reply := <-pending:
+			line = append(append([]byte("event: message\ndata: "), reply...), '\n', '\n')
+		case next, ok := <-lines:
+			if !ok {
+				return
+			}
+			line = next
+			field := bytes.TrimRight(line, "\r\n")
+			switch {
+			case len(field) == 0:
+				event, atBoundary = "", true
+			case bytes.HasPrefix(field, []byte("event:")):
+				event, atBoundary = string(bytes.TrimSpace(field[len("event:"):])), false
+			default:
+				atBoundary = false
+			}
 
-		line, err := reader.ReadBytes('\n')
-		if err != nil {
-			break
+			if bytes.HasPrefix(line, []byte("data:")) {
+				data := bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+				if stream != nil && event == "endpoint" {
+					if sessionKey == "" {
+						sessionKey = p.registerSSESession(string(data), stream)
+					}
+				} else if rewrite != nil {
+					if rewritten := rewrite(data); !bytes.Equal(rewritten, data) {
+						line = append(append([]byte("data: "), rewritten...), '\n')
+					}
+				}
+			}
 		}
 
-		if _, err = w.Write(line); err != nil {
-			break
+		if _, err := w.Write(line); err != nil {
+			return
 		}
-		log.Println("MCP Proxy handle SSE response line", string(line))
 
 		flusher.Flush()
 	}
 }
 
-func (p *MCPProxy) handleHTTP(w http.ResponseWriter, resp *http.Response) {
+func (p *MCPProxy) handleHTTP(w http.ResponseWriter, resp *http.Response, rewrite func([]byte) []byte) {
 	log.Println("MCP Proxy handle HTTP response from upstream request url", resp.Request.URL.String())
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -803,7 +965,14 @@ func (p *MCPProxy) handleHTTP(w http.ResponseWriter, resp *http.Response) {
 	default:
 	}
 
+	if rewrite != nil && strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "json") {
+		bodyBytes = rewrite(bodyBytes)
+	}
+
 	for k, v := range resp.Header {
+		if strings.ToLower(k) == "content-length" {
+			continue
+		}
 		w.Header()[k] = v
 	}
 
@@ -1032,6 +1201,21 @@ func (r *TokenRefresher) refreshAccessToken() error {
 	return nil
 }
 
+// recoverFromUnauthorized 在上游返回 401 后刷新 access token；refresh token 已过期时重新授权
+func (r *TokenRefresher) recoverFromUnauthorized() error {
+	r.mu.RLock()
+	refreshTokenExpire := r.profile.MCPOAuthRefreshTokenExpire
+	r.mu.RUnlock()
+	if refreshTokenExpire > util.GetCurrentUnixTime() {
+		// refresh token 未过期，尝试刷新 access token
+		log.Println("Received 401, attempting to refresh access token using refresh token")
+		return r.refreshAccessToken()
+	}
+	// refresh token 已过期，需要重新授权
+	log.Println("Received 401, refresh token expired, reauthorizing")
+	return r.reauthorizeWithProxy()
+}
+
 func (r *TokenRefresher) waitForRefresh(currentExpiresAt int64) error {
 	deadline := time.Now().Add(WaitForRefreshTimeout)
 	for time.Now().Before(deadline) {
@@ -1178,7 +1362,7 @@ func (r *TokenRefresher) reauthorizeWithProxy() error {
 		oauthScope = "/acs/mcp-server"
 	}
 	stderr := getStderrWriter(nil)
-	tokenResult, err := executeOAuthFlow(nil, clientId, r.regionType, r.callbackManager, r.host, r.port, r.autoOpenBrowser, oauthScope, func(authURL string) {
+	tokenResult, err := executeOAuthFlow(nil, clientId, r.regionType, r.callbackManager, r.host, r.port, r.autoOpenBrowser, oauthScope, r.codeInput, func(authURL string) {
 		cli.Printf(stderr, "OAuth Re-authorization Required. Please visit: %s\n", authURL)
 	})
 	if err != nil {
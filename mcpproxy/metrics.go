@@ -0,0 +1,233 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpproxy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aliyun/aliyun-cli/v3/util"
+)
+
+// /metrics 以 OpenMetrics 文本格式输出代理的运行指标，便于在共享开发机上抓取长期运行的代理。
+
+const (
+	metricsNamespace       = "aliyun_mcp_proxy"
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+	unknownServerLabel     = "unknown"
+)
+
+var (
+	upstreamLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+	sseDurationBuckets     = []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600}
+)
+
+type histogram struct {
+	bounds []float64
+	counts []uint64 // 每个桶（不累计）的计数，最后一个为 +Inf
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.counts[i]++
+	h.count++
+	h.sum += v
+}
+
+type responseKey struct {
+	server string
+	code   string
+}
+
+// proxyMetrics 记录按上游服务器区分的指标；RuntimeStats 中的计数器在输出时直接读取
+type proxyMetrics struct {
+	mu              sync.Mutex
+	upstreamLatency map[string]*histogram
+	sseDuration     map[string]*histogram
+	responses       map[responseKey]uint64
+}
+
+func newProxyMetrics() *proxyMetrics {
+	return &proxyMetrics{
+		upstreamLatency: make(map[string]*histogram),
+		sseDuration:     make(map[string]*histogram),
+		responses:       make(map[responseKey]uint64),
+	}
+}
+
+// observeUpstream 记录一次上游请求的耗时（到收到响应头为止）和状态码；statusCode 为 0 表示请求失败
+func (m *proxyMetrics) observeUpstream(server string, statusCode int, d time.Duration) {
+	code := "error"
+	if statusCode > 0 {
+		code = strconv.Itoa(statusCode)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.upstreamLatency[server]
+	if !ok {
+		h = newHistogram(upstreamLatencyBuckets)
+		m.upstreamLatency[server] = h
+	}
+	h.observe(d.Seconds())
+	m.responses[responseKey{server: server, code: code}]++
+}
+
+func (m *proxyMetrics) observeSSE(server string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.sseDuration[server]
+	if !ok {
+		h = newHistogram(sseDurationBuckets)
+		m.sseDuration[server] = h
+	}
+	h.observe(d.Seconds())
+}
+
+// serverLabel 返回用于指标标签的服务器名称
+func serverLabel(server *MCPServerInfo) string {
+	if server == nil {
+		return unknownServerLabel
+	}
+	return server.Name
+}
+
+func (p *MCPProxy) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if _, ok := p.authorizeInbound(w, r); !ok {
+		return
+	}
+	w.Header().Set("Content-Type", openMetricsContentType)
+	p.writeMetrics(w)
+}
+
+func (p *MCPProxy) writeMetrics(out io.Writer) {
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	counter := func(name, help string, value int64) {
+		fmt.Fprintf(w, "# TYPE %s_%s counter\n# HELP %s_%s %s\n%s_%s_total %d\n",
+			metricsNamespace, name, metricsNamespace, name, help, metricsNamespace, name, value)
+	}
+	gauge := func(name, help string, value float64) {
+		fmt.Fprintf(w, "# TYPE %s_%s gauge\n# HELP %s_%s %s\n%s_%s %s\n",
+			metricsNamespace, name, metricsNamespace, name, help, metricsNamespace, name, formatMetricValue(value))
+	}
+
+	stats := p.stats
+	gauge("start_time_seconds", "Unix time the proxy started.", float64(stats.StartTime.Unix()))
+	counter("requests", "MCP requests received from clients.", atomic.LoadInt64(&stats.TotalRequests))
+	counter("requests_succeeded", "MCP requests answered with a status below 400.", atomic.LoadInt64(&stats.SuccessRequests))
+	counter("requests_failed", "MCP requests that failed or were rejected.", atomic.LoadInt64(&stats.ErrorRequests))
+	gauge("active_requests", "MCP requests currently in flight.", float64(atomic.LoadInt64(&stats.ActiveRequests)))
+	counter("token_refreshes", "Successful OAuth token refreshes and re-authorizations.", atomic.LoadInt64(&stats.TokenRefreshes))
+	counter("token_refresh_errors", "Failed OAuth token refreshes and re-authorizations.", atomic.LoadInt64(&stats.TokenRefreshErrors))
+	gauge("last_token_refresh_timestamp_seconds", "Unix time of the last successful token refresh, 0 if none.",
+		float64(atomic.LoadInt64(&stats.LastTokenRefresh)))
+
+	if r := p.TokenRefresher; r != nil && r.profile != nil {
+		r.mu.RLock()
+		accessExpire := r.profile.MCPOAuthAccessTokenExpire
+		refreshExpire := r.profile.MCPOAuthRefreshTokenExpire
+		r.mu.RUnlock()
+		now := util.GetCurrentUnixTime()
+		gauge("access_token_expires_in_seconds", "Seconds until the OAuth access token expires, negative once expired.",
+			float64(accessExpire-now))
+		gauge("refresh_token_expires_in_seconds", "Seconds until the OAuth refresh token expires, negative once expired.",
+			float64(refreshExpire-now))
+	}
+
+	p.metrics.mu.Lock()
+	defer p.metrics.mu.Unlock()
+
+	name := metricsNamespace + "_upstream_responses"
+	fmt.Fprintf(w, "# TYPE %s counter\n# HELP %s Responses from upstream MCP servers by status code (\"error\" when no response was received).\n", name, name)
+	keys := make([]responseKey, 0, len(p.metrics.responses))
+	for k := range p.metrics.responses {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].server != keys[j].server {
+			return keys[i].server < keys[j].server
+		}
+		return keys[i].code < keys[j].code
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s_total{server=\"%s\",code=\"%s\"} %d\n", name, escapeLabelValue(k.server), k.code, p.metrics.responses[k])
+	}
+
+	writeHistograms(w, metricsNamespace+"_upstream_request_duration_seconds",
+		"Time from sending a request to an upstream MCP server until its response headers arrive.", p.metrics.upstreamLatency)
+	writeHistograms(w, metricsNamespace+"_sse_stream_duration_seconds",
+		"How long SSE response streams from upstream MCP servers stayed open.", p.metrics.sseDuration)
+
+	fmt.Fprint(w, "# EOF\n")
+}
+
+func writeHistograms(w io.Writer, name, help string, byServer map[string]*histogram) {
+	fmt.Fprintf(w, "# TYPE %s histogram\n# HELP %s %s\n", name, name, help)
+	servers := make([]string, 0, len(byServer))
+	for server := range byServer {
+		servers = append(servers, server)
+	}
+	sort.Strings(servers)
+	for _, server := range servers {
+		h := byServer[server]
+		label := escapeLabelValue(server)
+		var cumulative uint64
+		for i, bound := range h.bounds {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "%s_bucket{server=\"%s\",le=\"%s\"} %d\n", name, label, formatBucketBound(bound), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{server=\"%s\",le=\"+Inf\"} %d\n", name, label, h.count)
+		fmt.Fprintf(w, "%s_count{server=\"%s\"} %d\n", name, label, h.count)
+		fmt.Fprintf(w, "%s_sum{server=\"%s\"} %s\n", name, label, formatMetricValue(h.sum))
+	}
+}
+
+func formatMetricValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// formatBucketBound 按 OpenMetrics 的规范形式输出桶边界，整数也带小数点，如 "1.0"
+func formatBucketBound(v float64) string {
+	s := formatMetricValue(v)
+	if !strings.ContainsAny(s, ".I") {
+		s += ".0"
+	}
+	return s
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(s string) string {
+	return labelValueEscaper.Replace(s)
+}
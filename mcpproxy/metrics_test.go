@@ -0,0 +1,114 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpproxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogram_observe(t *testing.T) {
+	h := newHistogram([]float64{0.1, 1})
+	h.observe(0.05)
+	h.observe(0.1)
+	h.observe(0.5)
+	h.observe(3)
+	assert.Equal(t, []uint64{2, 1, 1}, h.counts)
+	assert.Equal(t, uint64(4), h.count)
+	assert.InDelta(t, 3.65, h.sum, 1e-9)
+}
+
+func TestFormatMetricValues(t *testing.T) {
+	assert.Equal(t, "0.005", formatBucketBound(0.005))
+	assert.Equal(t, "1.0", formatBucketBound(1))
+	assert.Equal(t, "1760000000", formatMetricValue(1760000000))
+	assert.Equal(t, `a\"b\\c\n`, escapeLabelValue("a\"b\\c\n"))
+}
+
+func TestMCPProxy_handleMetrics(t *testing.T) {
+	profile := NewMcpProfile("p")
+	profile.MCPOAuthAccessTokenExpire = time.Now().Unix() + 600
+	profile.MCPOAuthRefreshTokenExpire = time.Now().Unix() + 86400
+	proxy := NewMCPProxy(ProxyConfig{McpProfile: profile})
+	proxy.stats.TotalRequests = 3
+	proxy.stats.ErrorRequests = 1
+	proxy.metrics.observeUpstream("ecs", 200, 20*time.Millisecond)
+	proxy.metrics.observeUpstream("ecs", 401, 2*time.Second)
+	proxy.metrics.observeUpstream("rds", 0, time.Millisecond)
+	proxy.metrics.observeSSE("ecs", 42*time.Second)
+
+	w := httptest.NewRecorder()
+	proxy.handleMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	assert.Equal(t, openMetricsContentType, w.Header().Get("Content-Type"))
+	assert.True(t, strings.HasSuffix(body, "# EOF\n"))
+	assert.Contains(t, body, "# TYPE aliyun_mcp_proxy_requests counter\n")
+	assert.Contains(t, body, "aliyun_mcp_proxy_requests_total 3\n")
+	assert.Contains(t, body, "aliyun_mcp_proxy_requests_failed_total 1\n")
+	assert.Contains(t, body, "aliyun_mcp_proxy_active_requests 0\n")
+	assert.Regexp(t, `aliyun_mcp_proxy_access_token_expires_in_seconds (59\d|600)\n`, body)
+	assert.Contains(t, body, `aliyun_mcp_proxy_upstream_responses_total{server="ecs",code="200"} 1`)
+	assert.Contains(t, body, `aliyun_mcp_proxy_upstream_responses_total{server="ecs",code="401"} 1`)
+	assert.Contains(t, body, `aliyun_mcp_proxy_upstream_responses_total{server="rds",code="error"} 1`)
+	assert.Contains(t, body, `aliyun_mcp_proxy_upstream_request_duration_seconds_bucket{server="ecs",le="0.025"} 1`)
+	assert.Contains(t, body, `aliyun_mcp_proxy_upstream_request_duration_seconds_bucket{server="ecs",le="2.5"} 2`)
+	assert.Contains(t, body, `aliyun_mcp_proxy_upstream_request_duration_seconds_bucket{server="ecs",le="+Inf"} 2`)
+	assert.Contains(t, body, `aliyun_mcp_proxy_upstream_request_duration_seconds_count{server="ecs"} 2`)
+	assert.Contains(t, body, `aliyun_mcp_proxy_sse_stream_duration_seconds_bucket{server="ecs",le="30.0"} 0`)
+	assert.Contains(t, body, `aliyun_mcp_proxy_sse_stream_duration_seconds_bucket{server="ecs",le="60.0"} 1`)
+	assert.Contains(t, body, `aliyun_mcp_proxy_sse_stream_duration_seconds_sum{server="ecs"} 42`)
+}
+
+func TestMCPProxy_ServeMCPProxyRequest_RecordsMetrics(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/sse/") {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {}\n\n")
+			return
+		}
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer upstream.Close()
+
+	profile := NewMcpProfile("p")
+	profile.MCPOAuthAccessToken = "token"
+	profile.MCPOAuthAccessTokenExpire = time.Now().Unix() + 3600
+	proxy := NewMCPProxy(ProxyConfig{
+		McpProfile: profile,
+		ExistMcpServers: []MCPServerInfo{
+			{Id: "id1", Name: "ecs", Urls: MCPInfoUrls{MCP: "https://example.com/mcp/ecs", SSE: "https://example.com/sse/ecs"}},
+		},
+		UpstreamBaseURL: upstream.URL,
+	})
+
+	proxy.ServeMCPProxyRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/sse/ecs", nil))
+	proxy.ServeMCPProxyRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/mcp/other", strings.NewReader("{}")))
+
+	var buf strings.Builder
+	proxy.writeMetrics(&buf)
+	body := buf.String()
+	assert.Contains(t, body, `aliyun_mcp_proxy_upstream_responses_total{server="ecs",code="200"} 1`)
+	assert.Contains(t, body, `aliyun_mcp_proxy_upstream_responses_total{server="unknown",code="418"} 1`)
+	assert.Contains(t, body, `aliyun_mcp_proxy_sse_stream_duration_seconds_count{server="ecs"} 1`)
+	assert.Contains(t, body, "aliyun_mcp_proxy_requests_total 2\n")
+	assert.Contains(t, body, "aliyun_mcp_proxy_requests_failed_total 1\n")
+}
@@ -579,7 +579,7 @@ func buildOAuthURL(clientId string, region RegionType, host string, port int, co
 }
 
 func executeOAuthFlow(ctx *cli.Context, clientId string, regionType RegionType, manager *OAuthCallbackManager,
-	host string, port int, autoOpenBrowser bool, scope string, logAuthURL func(string)) (*OAuthTokenResult, error) {
+	host string, port int, autoOpenBrowser bool, scope string, codeInput authCodeInput, logAuthURL func(string)) (*OAuthTokenResult, error) {
 	stderr := getStderrWriter(ctx)
 	codeVerifier, err := generateCodeVerifier()
 	if err != nil {
@@ -609,13 +609,9 @@ func executeOAuthFlow(ctx *cli.Context, clientId string, regionType RegionType,
 			// 错误信息输出到 stderr，确保用户能看到
 			cli.Printf(stderr, "Failed to open browser automatically: %v\n", err)
 			cli.Printf(stderr, "Falling back to manual code input mode...\n")
-			if !isInteractiveInput() {
-				return nil, fmt.Errorf("manual authorization required but standard input is not interactive")
-			}
-			reader := bufio.NewReader(os.Stdin)
-			code, err = promptAuthorizationCode(stderr, reader)
+			code, err = readAuthorizationCode(stderr, codeInput)
 			if err != nil {
-				return nil, fmt.Errorf("failed to read authorization code: %w", err)
+				return nil, err
 			}
 		} else {
 			manager.StartWaiting()
@@ -626,13 +622,9 @@ func executeOAuthFlow(ctx *cli.Context, clientId string, regionType RegionType,
 			}
 		}
 	} else {
-		if !isInteractiveInput() {
-			return nil, fmt.Errorf("manual authorization required but standard input is not interactive")
-		}
-		reader := bufio.NewReader(os.Stdin)
-		code, err = promptAuthorizationCode(stderr, reader)
+		code, err = readAuthorizationCode(stderr, codeInput)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read authorization code: %w", err)
+			return nil, err
 		}
 	}
 
@@ -645,9 +637,9 @@ func executeOAuthFlow(ctx *cli.Context, clientId string, regionType RegionType,
 }
 
 func startMCPOAuthFlowWithManager(ctx *cli.Context, clientId string, region RegionType,
-	manager *OAuthCallbackManager, host string, port int, autoOpenBrowser bool, scope string) (*OAuthTokenResult, error) {
+	manager *OAuthCallbackManager, host string, port int, autoOpenBrowser bool, scope string, codeInput authCodeInput) (*OAuthTokenResult, error) {
 	stderr := getStderrWriter(ctx)
-	tokenResult, err := executeOAuthFlow(ctx, clientId, region, manager, host, port, autoOpenBrowser, scope, func(authURL string) {
+	tokenResult, err := executeOAuthFlow(ctx, clientId, region, manager, host, port, autoOpenBrowser, scope, codeInput, func(authURL string) {
 		cli.Printf(stderr, "Opening browser for OAuth login...\nURL: %s\n\n", authURL)
 	})
 	if err != nil {
@@ -677,7 +669,12 @@ func startMCPOAuthFlow(ctx *cli.Context, clientId string, region RegionType, hos
 
 	defer server.Close()
 
-	return startMCPOAuthFlowWithManager(ctx, clientId, region, manager, host, port, autoOpenBrowser, scope)
+	return startMCPOAuthFlowWithManager(ctx, clientId, region, manager, host, port, autoOpenBrowser, scope, nil)
+}
+
+// startMCPOAuthFlowManual 不监听回调端口，只通过手动输入授权码完成登录（用于 stdio 传输模式）
+func startMCPOAuthFlowManual(ctx *cli.Context, clientId string, region RegionType, host string, port int, scope string, codeInput authCodeInput) (*OAuthTokenResult, error) {
+	return startMCPOAuthFlowWithManager(ctx, clientId, region, NewOAuthCallbackManager(), host, port, false, scope, codeInput)
 }
 
 func isStderrRedirected() bool {
@@ -720,6 +717,49 @@ func getStderrWriter(ctx *cli.Context) io.Writer {
 	return stderrWriter
 }
 
+// authCodeInput 返回手动输入授权码所用的 reader 以及释放它的函数
+type authCodeInput func() (*bufio.Reader, func(), error)
+
+func stdinAuthCodeInput() (*bufio.Reader, func(), error) {
+	if !isInteractiveInput() {
+		return nil, nil, fmt.Errorf("manual authorization required but standard input is not interactive")
+	}
+	return bufio.NewReader(os.Stdin), func() {}, nil
+}
+
+// ttyAuthCodeInput 从控制终端读取授权码。stdio 传输模式下标准输入承载 JSON-RPC 消息，不能用于交互。
+func ttyAuthCodeInput() (*bufio.Reader, func(), error) {
+	tty, err := os.Open(ttyDevice())
+	if err != nil {
+		return nil, nil, fmt.Errorf("manual authorization required but no terminal is available: %w. "+
+			"Run 'aliyun mcp-proxy' once in a terminal to sign in, then restart the stdio proxy", err)
+	}
+	return bufio.NewReader(tty), func() { tty.Close() }, nil
+}
+
+func ttyDevice() string {
+	if runtime.GOOS == "windows" {
+		return "CONIN$"
+	}
+	return "/dev/tty"
+}
+
+func readAuthorizationCode(stderr io.Writer, codeInput authCodeInput) (string, error) {
+	if codeInput == nil {
+		codeInput = stdinAuthCodeInput
+	}
+	reader, release, err := codeInput()
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	code, err := promptAuthorizationCode(stderr, reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to read authorization code: %w", err)
+	}
+	return code, nil
+}
+
 func isInteractiveInput() bool {
 	info, err := os.Stdin.Stat()
 	if err != nil {
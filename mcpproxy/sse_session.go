@@ -0,0 +1,124 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpproxy
+
+import (
+	"encoding/json"
+	"log"
+	"net/url"
+	"sync"
+)
+
+// 旧版 SSE 传输：客户端 GET SSE 地址建立事件流，上游先发送 endpoint 事件告知消息地址，
+// 客户端随后把 JSON-RPC 请求 POST 到该地址，POST 只返回 202，所有响应都经由事件流返回。
+// 因此 tools/list 的过滤和被拒绝调用的错误响应都作用在事件流上，消息地址也通过事件流找到所属服务器。
+
+type sseSession struct {
+	server  *MCPServerInfo
+	mu      sync.Mutex
+	listIDs map[string]bool // 经由该事件流返回、需要过滤结果的 tools/list 请求 ID
+	inject  chan []byte     // 代理生成的响应，由事件流在事件边界处写出
+	done    chan struct{}   // 事件流结束时关闭
+}
+
+func newSSESession(server *MCPServerInfo) *sseSession {
+	return &sseSession{
+		server: server,
+		inject: make(chan []byte),
+		done:   make(chan struct{}),
+	}
+}
+
+// sseSessionKey 以路径和查询参数标识消息地址，会话 ID 通常在查询参数中
+func sseSessionKey(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Path
+	}
+	return u.Path + "?" + u.RawQuery
+}
+
+// registerSSESession 记录 endpoint 事件给出的消息地址，返回用于注销的键
+func (p *MCPProxy) registerSSESession(endpoint string, s *sseSession) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Path == "" {
+		log.Printf("MCP Proxy ignored SSE endpoint event %q", endpoint)
+		return ""
+	}
+	key := sseSessionKey(u)
+	p.sseMu.Lock()
+	defer p.sseMu.Unlock()
+	if p.sseSessions == nil {
+		p.sseSessions = make(map[string]*sseSession)
+	}
+	p.sseSessions[key] = s
+	return key
+}
+
+func (p *MCPProxy) unregisterSSESession(key string, s *sseSession) {
+	p.sseMu.Lock()
+	defer p.sseMu.Unlock()
+	if p.sseSessions[key] == s {
+		delete(p.sseSessions, key)
+	}
+}
+
+// sseSessionFor 返回消息地址所属的事件流，不是旧版 SSE 的消息地址时返回 nil
+func (p *MCPProxy) sseSessionFor(u *url.URL) *sseSession {
+	p.sseMu.Lock()
+	defer p.sseMu.Unlock()
+	return p.sseSessions[sseSessionKey(u)]
+}
+
+// expectList 记录需要在事件流上过滤结果的 tools/list 请求
+func (s *sseSession) expectList(ids map[string]bool) {
+	if len(ids) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listIDs == nil {
+		s.listIDs = make(map[string]bool)
+	}
+	for id := range ids {
+		s.listIDs[id] = true
+	}
+}
+
+// filter 过滤事件流中的 tools/list 响应，已返回的请求 ID 随即移除
+func (s *sseSession) filter(p *MCPProxy, data []byte) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.listIDs) == 0 {
+		return data
+	}
+	out := p.filterToolsList(s.server, data, s.listIDs)
+	var msg struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(data, &msg) == nil && len(msg.ID) > 0 {
+		delete(s.listIDs, string(msg.ID))
+	}
+	return out
+}
+
+// send 把代理生成的响应写入事件流，事件流已结束时返回 false
+func (s *sseSession) send(reply []byte) bool {
+	select {
+	case s.inject <- reply:
+		return true
+	case <-s.done:
+		return false
+	}
+}
@@ -0,0 +1,394 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpproxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// stdio 传输：按行从 stdin 读取 JSON-RPC 消息，通过 streamable HTTP 转发到选定的上游 MCP Server，
+// 并把响应和通知逐行写到 stdout。stdout 只输出协议消息，日志全部写到 stderr。
+
+const (
+	headerMCPSessionID       = "Mcp-Session-Id"
+	headerMCPProtocolVersion = "MCP-Protocol-Version"
+
+	jsonRPCParseError    = -32700
+	jsonRPCInternalError = -32603
+)
+
+type jsonRPCEnvelope struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+}
+
+// stdioExchange 是一条客户端消息及其响应处理所需的状态
+type stdioExchange struct {
+	msg     jsonRPCEnvelope
+	listIDs map[string]bool  // 需要过滤结果的 tools/list 请求 ID
+	traffic *trafficExchange // 流量日志，未开启时为 nil
+}
+
+type stdioSession struct {
+	proxy  *MCPProxy
+	server *MCPServerInfo
+	path   string // 上游 MCP Server 的路径（含查询参数）
+
+	outMu sync.Mutex
+	out   io.Writer
+
+	mu              sync.RWMutex
+	sessionID       string
+	protocolVersion string
+}
+
+// selectStdioServer 选出 stdio 模式下转发的上游服务器：name 为空时要求访问控制过滤后只剩一个服务器
+func (p *MCPProxy) selectStdioServer(name string) (*MCPServerInfo, error) {
+	var candidates []MCPServerInfo
+	for _, server := range p.ExistMcpServers {
+		if p.isServerBlocked(server) || !p.isServerAllowed(server) {
+			continue
+		}
+		if name != "" && server.Name != name && server.Id != name {
+			continue
+		}
+		candidates = append(candidates, server)
+	}
+
+	switch {
+	case len(candidates) == 1:
+		if candidates[0].Urls.MCP == "" {
+			return nil, fmt.Errorf("MCP server '%s' has no streamable HTTP endpoint, which the stdio transport requires", candidates[0].Name)
+		}
+		return &candidates[0], nil
+	case name != "":
+		return nil, fmt.Errorf("MCP server '%s' not found or not allowed", name)
+	case len(candidates) == 0:
+		return nil, fmt.Errorf("no allowed MCP servers found")
+	default:
+		names := make([]string, 0, len(candidates))
+		for _, server := range candidates {
+			names = append(names, server.Name)
+		}
+		return nil, fmt.Errorf("the stdio transport forwards to a single MCP server, use --server to select one of: %s",
+			strings.Join(names, ", "))
+	}
+}
+
+// ServeStdio 转发 in 中的 JSON-RPC 消息到 server，直到 in 结束或代理停止
+func (p *MCPProxy) ServeStdio(in io.Reader, out io.Writer, server *MCPServerInfo) error {
+	upstreamURL, err := url.Parse(server.Urls.MCP)
+	if err != nil {
+		return fmt.Errorf("invalid MCP url of server '%s': %w", server.Name, err)
+	}
+	s := &stdioSession{proxy: p, server: server, path: upstreamURL.RequestURI(), out: out}
+	log.Printf("MCP Proxy stdio transport forwarding to server %s (%s)", server.Name, s.path)
+
+	var wg sync.WaitGroup
+	reader := bufio.NewReader(in)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			var msg jsonRPCEnvelope
+			if err := json.Unmarshal(line, &msg); err != nil && line[0] != '[' {
+				s.writeError(nil, jsonRPCParseError, fmt.Sprintf("Parse error: %v", err))
+			} else if msg.Method == "initialize" {
+				// 后续请求依赖 initialize 返回的会话 ID，必须先完成
+				s.forward(line, msg)
+			} else {
+				wg.Add(1)
+				go func(body []byte, msg jsonRPCEnvelope) {
+					defer wg.Done()
+					s.forward(body, msg)
+				}(line, msg)
+			}
+		}
+
+		if readErr != nil {
+			wg.Wait()
+			s.close()
+			if readErr == io.EOF {
+				log.Println("MCP Proxy stdio transport input closed")
+				return nil
+			}
+			return fmt.Errorf("failed to read stdin: %w", readErr)
+		}
+
+		select {
+		case <-p.stopCh:
+			wg.Wait()
+			s.close()
+			return nil
+		default:
+		}
+	}
+}
+
+func (s *stdioSession) forward(body []byte, msg jsonRPCEnvelope) {
+	p := s.proxy
+	atomic.AddInt64(&p.stats.TotalRequests, 1)
+	atomic.AddInt64(&p.stats.ActiveRequests, 1)
+	defer atomic.AddInt64(&p.stats.ActiveRequests, -1)
+
+	log.Printf("MCP Proxy stdio transport received message method=%s id=%s", msg.Method, string(msg.ID))
+
+	ex := &stdioExchange{msg: msg, traffic: p.traffic.begin(TransportStdio, s.server, "", body)}
+	statusCode := 0
+	defer func() { ex.traffic.finish(statusCode) }()
+
+	reply, listIDs := p.guardRequest(s.server, body)
+	if reply != nil {
+		atomic.AddInt64(&p.stats.ErrorRequests, 1)
+		if len(reply) > 0 {
+			s.writeMessage(reply, ex)
+		}
+		return
+	}
+	ex.listIDs = listIDs
+
+	resp, err := s.send(http.MethodPost, body)
+	if err != nil {
+		log.Printf("MCP Proxy stdio transport request failed: %v", err)
+		atomic.AddInt64(&p.stats.ErrorRequests, 1)
+		s.writeError(ex, jsonRPCInternalError, err.Error())
+		return
+	}
+	defer resp.Body.Close()
+	statusCode = resp.StatusCode
+
+	log.Println("MCP Proxy stdio transport gets mcp server response status code", resp.StatusCode)
+
+	if sessionID := resp.Header.Get(headerMCPSessionID); sessionID != "" {
+		s.mu.Lock()
+		s.sessionID = sessionID
+		s.mu.Unlock()
+	}
+
+	if resp.StatusCode >= 400 {
+		atomic.AddInt64(&p.stats.ErrorRequests, 1)
+		s.writeUpstreamError(resp, ex)
+		return
+	}
+	atomic.AddInt64(&p.stats.SuccessRequests, 1)
+
+	// 202 Accepted：通知或响应，无返回内容
+	if resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusNoContent {
+		return
+	}
+
+	if strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "text/event-stream") {
+		streamStart := time.Now()
+		s.relaySSE(resp.Body, ex)
+		p.metrics.observeSSE(serverLabel(s.server), time.Since(streamStart))
+		return
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		s.writeError(ex, jsonRPCInternalError, fmt.Sprintf("failed to read upstream response: %v", err))
+		return
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		s.writeMessage(data, ex)
+	}
+}
+
+// send 发送请求到上游，遇到 401 时刷新 token 或重新授权后重试一次
+func (s *stdioSession) send(method string, body []byte) (*http.Response, error) {
+	p := s.proxy
+	token, err := p.getMCPAccessToken()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.doSend(method, body, token)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	log.Println("MCP Proxy stdio transport gets mcp server response status code 401, attempting to refresh token")
+	if err := p.TokenRefresher.recoverFromUnauthorized(); err != nil {
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+	token, err = p.getMCPAccessToken()
+	if err != nil {
+		return nil, err
+	}
+	return s.doSend(method, body, token)
+}
+
+func (s *stdioSession) doSend(method string, body []byte, token string) (*http.Response, error) {
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	r, err := http.NewRequest(method, s.path, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	r.Header.Set("Accept", "application/json, text/event-stream")
+	s.mu.RLock()
+	if s.sessionID != "" {
+		r.Header.Set(headerMCPSessionID, s.sessionID)
+	}
+	if s.protocolVersion != "" {
+		r.Header.Set(headerMCPProtocolVersion, s.protocolVersion)
+	}
+	s.mu.RUnlock()
+
+	upstreamReq, err := s.proxy.buildUpstreamRequest(r, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build upstream request: %w", err)
+	}
+	client := &http.Client{Timeout: 0}
+	start := time.Now()
+	resp, err := client.Do(upstreamReq)
+	if err != nil {
+		s.proxy.metrics.observeUpstream(serverLabel(s.server), 0, time.Since(start))
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	s.proxy.metrics.observeUpstream(serverLabel(s.server), resp.StatusCode, time.Since(start))
+	return resp, nil
+}
+
+// relaySSE 把 SSE 流中每个事件的 data 作为一条消息写到 stdout
+func (s *stdioSession) relaySSE(body io.Reader, ex *stdioExchange) {
+	reader := bufio.NewReader(body)
+	var data []string
+	flush := func() {
+		if len(data) > 0 {
+			s.writeMessage([]byte(strings.Join(data, "\n")), ex)
+			data = data[:0]
+		}
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		trimmed := strings.TrimRight(line, "\r\n")
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(trimmed, "data:"), " "))
+		}
+		if err != nil {
+			flush()
+			return
+		}
+
+		select {
+		case <-s.proxy.stopCh:
+			return
+		default:
+		}
+	}
+}
+
+func (s *stdioSession) writeUpstreamError(resp *http.Response, ex *stdioExchange) {
+	data, _ := io.ReadAll(resp.Body)
+	// 上游已经返回 JSON-RPC 错误时原样转发
+	var rpc struct {
+		JSONRPC string `json:"jsonrpc"`
+	}
+	if json.Unmarshal(data, &rpc) == nil && rpc.JSONRPC != "" {
+		s.writeMessage(data, ex)
+		return
+	}
+	if len(ex.msg.ID) == 0 {
+		log.Printf("MCP Proxy stdio transport notification rejected by upstream with status %d: %s", resp.StatusCode, string(data))
+		return
+	}
+	message := fmt.Sprintf("upstream MCP server returned status %d", resp.StatusCode)
+	if text := strings.TrimSpace(string(data)); text != "" {
+		message += ": " + text
+	}
+	s.writeError(ex, jsonRPCInternalError, message)
+}
+
+// writeError 返回代理生成的错误响应，ex 为 nil 时（无法解析的消息）ID 为 null
+func (s *stdioSession) writeError(ex *stdioExchange, code int, message string) {
+	var id json.RawMessage
+	if ex != nil {
+		id = ex.msg.ID
+	}
+	data, _ := json.Marshal(jsonRPCErrorResponse(id, &jsonRPCError{Code: code, Message: message}))
+	s.writeMessage(data, ex)
+}
+
+// writeMessage 以单行形式写出一条消息，stdio 传输要求消息内不能包含换行
+func (s *stdioSession) writeMessage(data []byte, ex *stdioExchange) {
+	if ex != nil {
+		data = s.proxy.filterToolsList(s.server, data, ex.listIDs)
+		ex.traffic.respond(data)
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		log.Printf("MCP Proxy stdio transport dropped invalid JSON from upstream: %v", err)
+		return
+	}
+	if ex != nil && ex.msg.Method == "initialize" {
+		s.rememberProtocolVersion(buf.Bytes())
+	}
+	buf.WriteByte('\n')
+
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	if _, err := s.out.Write(buf.Bytes()); err != nil {
+		log.Printf("MCP Proxy stdio transport failed to write stdout: %v", err)
+	}
+}
+
+func (s *stdioSession) rememberProtocolVersion(data []byte) {
+	var resp struct {
+		Result struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		} `json:"result"`
+	}
+	if json.Unmarshal(data, &resp) == nil && resp.Result.ProtocolVersion != "" {
+		s.mu.Lock()
+		s.protocolVersion = resp.Result.ProtocolVersion
+		s.mu.Unlock()
+	}
+}
+
+// close 结束上游会话，失败不影响退出
+func (s *stdioSession) close() {
+	s.mu.RLock()
+	sessionID := s.sessionID
+	s.mu.RUnlock()
+	if sessionID == "" {
+		return
+	}
+	resp, err := s.send(http.MethodDelete, nil)
+	if err != nil {
+		log.Printf("MCP Proxy stdio transport failed to close session: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStdioTestProxy(upstreamURL string, servers []MCPServerInfo) *MCPProxy {
+	profile := NewMcpProfile("test-profile")
+	profile.MCPOAuthAccessToken = "access-token"
+	profile.MCPOAuthAccessTokenExpire = time.Now().Unix() + 3600
+	profile.MCPOAuthRefreshToken = "refresh-token"
+	profile.MCPOAuthRefreshTokenExpire = time.Now().Unix() + 86400
+	return NewMCPProxy(ProxyConfig{
+		Host:            "127.0.0.1",
+		Port:            8088,
+		RegionType:      RegionCN,
+		McpProfile:      profile,
+		ExistMcpServers: servers,
+		CallbackManager: NewOAuthCallbackManager(),
+		AutoOpenBrowser: true,
+		UpstreamBaseURL: upstreamURL,
+		Transport:       TransportStdio,
+	})
+}
+
+func readStdioOutput(t *testing.T, out string) []map[string]any {
+	var msgs []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &m), line)
+		msgs = append(msgs, m)
+	}
+	return msgs
+}
+
+func TestNewMCPProxy_StdioTransportUsesManualCodeInput(t *testing.T) {
+	proxy := newStdioTestProxy("", nil)
+	assert.False(t, proxy.TokenRefresher.autoOpenBrowser)
+	assert.NotNil(t, proxy.TokenRefresher.codeInput)
+
+	httpProxy := NewMCPProxy(ProxyConfig{McpProfile: NewMcpProfile("p"), AutoOpenBrowser: true})
+	assert.True(t, httpProxy.TokenRefresher.autoOpenBrowser)
+	assert.Nil(t, httpProxy.TokenRefresher.codeInput)
+}
+
+func TestMCPProxy_selectStdioServer(t *testing.T) {
+	servers := []MCPServerInfo{
+		{Id: "id1", Name: "ecs", Urls: MCPInfoUrls{MCP: "https://example.com/mcp/ecs"}},
+		{Id: "id2", Name: "rds", Urls: MCPInfoUrls{MCP: "https://example.com/mcp/rds"}},
+		{Id: "id3", Name: "sse-only", Urls: MCPInfoUrls{SSE: "https://example.com/sse/x"}},
+	}
+
+	proxy := newStdioTestProxy("", servers)
+	_, err := proxy.selectStdioServer("")
+	assert.ErrorContains(t, err, "--server")
+
+	server, err := proxy.selectStdioServer("id2")
+	require.NoError(t, err)
+	assert.Equal(t, "rds", server.Name)
+
+	_, err = proxy.selectStdioServer("sse-only")
+	assert.ErrorContains(t, err, "streamable HTTP")
+
+	_, err = proxy.selectStdioServer("missing")
+	assert.ErrorContains(t, err, "not found or not allowed")
+
+	proxy.AllowedServers = []string{"ecs"}
+	server, err = proxy.selectStdioServer("")
+	require.NoError(t, err)
+	assert.Equal(t, "ecs", server.Name)
+
+	_, err = proxy.selectStdioServer("rds")
+	assert.Error(t, err)
+}
+
+func TestMCPProxy_ServeStdio(t *testing.T) {
+	var mu sync.Mutex
+	var requests []*http.Request
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, r)
+		mu.Unlock()
+
+		assert.Equal(t, "/mcp/ecs", r.URL.Path)
+		assert.Equal(t, "Bearer access-token", r.Header.Get("Authorization"))
+		if r.Method == http.MethodDelete {
+			assert.Equal(t, "session-1", r.Header.Get(headerMCPSessionID))
+			return
+		}
+
+		var msg map[string]any
+		require.NoError(t, json.Unmarshal(body, &msg))
+		switch msg["method"] {
+		case "initialize":
+			assert.Empty(t, r.Header.Get(headerMCPSessionID))
+			w.Header().Set(headerMCPSessionID, "session-1")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, "{\n  \"jsonrpc\": \"2.0\",\n  \"id\": 1,\n  \"result\": {\"protocolVersion\": \"2025-06-18\"}\n}")
+		case "notifications/initialized":
+			assert.Equal(t, "session-1", r.Header.Get(headerMCPSessionID))
+			w.WriteHeader(http.StatusAccepted)
+		case "tools/list":
+			assert.Equal(t, "session-1", r.Header.Get(headerMCPSessionID))
+			assert.Equal(t, "2025-06-18", r.Header.Get(headerMCPProtocolVersion))
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+			fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":2,\"result\":{\"tools\":[]}}\n\n")
+		default:
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}
+	}))
+	defer upstream.Close()
+
+	proxy := newStdioTestProxy(upstream.URL, []MCPServerInfo{
+		{Id: "id1", Name: "ecs", Urls: MCPInfoUrls{MCP: "https://example.com/mcp/ecs"}},
+	})
+	server, err := proxy.selectStdioServer("")
+	require.NoError(t, err)
+
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"unknown"}`,
+		`not json`,
+	}, "\n") + "\n"
+	var out bytes.Buffer
+	require.NoError(t, proxy.ServeStdio(strings.NewReader(in), &out, server))
+
+	msgs := readStdioOutput(t, out.String())
+	byID := map[string]map[string]any{}
+	var notifications, parseErrors int
+	for _, m := range msgs {
+		switch {
+		case m["id"] == nil && m["method"] != nil:
+			notifications++
+		case m["id"] == nil:
+			parseErrors++
+			assert.Equal(t, float64(jsonRPCParseError), m["error"].(map[string]any)["code"])
+		default:
+			byID[fmt.Sprint(m["id"])] = m
+		}
+	}
+	assert.Equal(t, 1, notifications)
+	assert.Equal(t, 1, parseErrors)
+	assert.Equal(t, "2025-06-18", byID["1"]["result"].(map[string]any)["protocolVersion"])
+	assert.NotNil(t, byID["2"]["result"])
+	assert.Contains(t, byID["3"]["error"].(map[string]any)["message"], "status 500")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, http.MethodDelete, requests[len(requests)-1].Method)
+}
+
+func TestMCPProxy_ServeStdio_RefreshesTokenOn401(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalHome := getHomeEnv()
+	setHomeEnv(tmpDir)
+	defer restoreHomeEnv(originalHome)
+
+	oauth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"new-token","refresh_token":"new-refresh","expires_in":3600}`)
+	}))
+	defer oauth.Close()
+	originalEndpoint := EndpointMap[RegionCN]
+	endpoint := originalEndpoint
+	endpoint.OAuth = oauth.URL
+	EndpointMap[RegionCN] = endpoint
+	defer func() { EndpointMap[RegionCN] = originalEndpoint }()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer new-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":{}}`)
+	}))
+	defer upstream.Close()
+
+	proxy := newStdioTestProxy(upstream.URL, []MCPServerInfo{
+		{Id: "id1", Name: "ecs", Urls: MCPInfoUrls{MCP: "https://example.com/mcp/ecs"}},
+	})
+	server, err := proxy.selectStdioServer("ecs")
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, proxy.ServeStdio(strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`), &out, server))
+
+	msgs := readStdioOutput(t, out.String())
+	require.Len(t, msgs, 1)
+	assert.NotNil(t, msgs[0]["result"])
+	assert.Equal(t, "new-token", proxy.TokenRefresher.profile.MCPOAuthAccessToken)
+}
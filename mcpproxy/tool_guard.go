@@ -0,0 +1,427 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/aliyun/aliyun-cli/v3/i18n"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/safety"
+)
+
+// 工具级访问控制：解析 JSON-RPC 消息，过滤 tools/list 结果并拦截 tools/call。
+// 优先级与服务器访问控制一致：黑名单 > 白名单 > 默认允许；通过后再按安全策略（safety.Policy）检查，
+// 工具按 "<product>:<tool>" 参与规则匹配，如 "ecs:DeleteInstance"，调用参数参与 when 条件。
+
+const (
+	jsonRPCInvalidRequest  = -32600
+	jsonRPCInvalidParams   = -32602
+	jsonRPCToolBlocked     = -32001
+	jsonRPCConfirmRequired = -32002
+)
+
+type jsonRPCRequest struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+type toolCallParams struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+}
+
+// matchToolPattern 匹配工具名，支持 * 通配；"server:tool" 形式只匹配指定服务器（名称或 ID）上的工具
+func matchToolPattern(pattern string, server *MCPServerInfo, tool string) bool {
+	if i := strings.Index(pattern, ":"); i >= 0 {
+		serverPattern := pattern[:i]
+		if server == nil || !(safety.MatchPattern(serverPattern, server.Name) || safety.MatchPattern(serverPattern, server.Id)) {
+			return false
+		}
+		pattern = pattern[i+1:]
+	}
+	return safety.MatchPattern(pattern, tool)
+}
+
+// isToolBlocked 在找不到请求所属的服务器时，"server:tool" 形式的黑名单规则也按工具名匹配，宁可多拦截
+func (p *MCPProxy) isToolBlocked(server *MCPServerInfo, tool string) bool {
+	for _, pattern := range p.BlockedTools {
+		if server == nil {
+			if i := strings.Index(pattern, ":"); i >= 0 {
+				pattern = pattern[i+1:]
+			}
+		}
+		if matchToolPattern(pattern, server, tool) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *MCPProxy) isToolAllowed(server *MCPServerInfo, tool string) bool {
+	// 如果白名单为空，允许所有工具
+	if len(p.AllowedTools) == 0 {
+		return true
+	}
+	for _, pattern := range p.AllowedTools {
+		if matchToolPattern(pattern, server, tool) {
+			return true
+		}
+	}
+	return false
+}
+
+// serverForPath 根据请求路径找到对应的上游服务器，找不到时返回 nil
+func (p *MCPProxy) serverForPath(requestPath string) *MCPServerInfo {
+	for i := range p.ExistMcpServers {
+		server := &p.ExistMcpServers[i]
+		for _, raw := range []string{server.Urls.MCP, server.Urls.SSE} {
+			if raw == "" {
+				continue
+			}
+			if u, err := url.Parse(raw); err == nil && u.Path != "" && strings.HasPrefix(requestPath, u.Path) {
+				return server
+			}
+		}
+	}
+	// 旧版 SSE 的消息地址通常与 SSE 地址同级（如 /ecs/sse 与 /ecs/messages），事件流登记的地址优先
+	for i := range p.ExistMcpServers {
+		server := &p.ExistMcpServers[i]
+		if server.Urls.SSE == "" {
+			continue
+		}
+		if u, err := url.Parse(server.Urls.SSE); err == nil {
+			if dir := path.Dir(u.Path); dir != "/" && dir != "." && strings.HasPrefix(requestPath, dir+"/") {
+				return server
+			}
+		}
+	}
+	return nil
+}
+
+// loadSafetyPolicy 每次检查时重新加载，使策略修改和新的审批无需重启代理即可生效；
+// 系统或锁定策略无法加载时拒绝所有工具调用并隐藏所有工具（与 Commando 一致）
+func (p *MCPProxy) loadSafetyPolicy() *safety.Policy {
+	if p.safetyConfigDir == "" {
+		return nil
+	}
+	policy, err := safety.LoadEffectivePolicy(p.safetyConfigDir)
+	if err != nil {
+		log.Printf("MCP Proxy blocks all tool calls: %v", err)
+		return safety.DenyAllPolicy()
+	}
+	return policy
+}
+
+func (p *MCPProxy) toolCommand(server *MCPServerInfo, call toolCallParams) safety.CommandInfo {
+	product := "mcp"
+	if server != nil {
+		product = server.Product
+		if product == "" {
+			product = server.Name
+		}
+	}
+	params := make(map[string][]string, len(call.Arguments))
+	for k, v := range call.Arguments {
+		params[k] = toolArgumentValues(v)
+	}
+	region := ""
+	if values := params["RegionId"]; len(values) > 0 {
+		region = values[0]
+	}
+	return safety.CommandInfo{
+		Product:     product,
+		ApiOrMethod: call.Name,
+		Params:      params,
+		Region:      region,
+		Profile:     p.profileName,
+	}
+}
+
+func toolArgumentValues(v any) []string {
+	switch t := v.(type) {
+	case nil:
+		return []string{""}
+	case string:
+		return []string{t}
+	case []any:
+		values := make([]string, 0, len(t))
+		for _, item := range t {
+			values = append(values, toolArgumentValues(item)...)
+		}
+		return values
+	case map[string]any:
+		data, _ := json.Marshal(t)
+		return []string{string(data)}
+	default:
+		return []string{fmt.Sprint(t)}
+	}
+}
+
+func describeSafetyRule(result safety.CheckResult) string {
+	if result.Rule == nil {
+		return ""
+	}
+	if result.Condition != "" {
+		return result.Rule.Pattern + " when " + result.Condition
+	}
+	return result.Rule.Pattern
+}
+
+// checkToolCall 返回拒绝该调用的 JSON-RPC 错误；允许转发时返回 nil
+func (p *MCPProxy) checkToolCall(server *MCPServerInfo, call toolCallParams, policy *safety.Policy) *jsonRPCError {
+	if p.isToolBlocked(server, call.Name) {
+		return &jsonRPCError{
+			Code: jsonRPCToolBlocked,
+			Message: fmt.Sprintf(i18n.T(
+				"MCP tool %s is blocked by the proxy",
+				"MCP 工具 %s 已被代理禁止",
+			).GetMessage(), call.Name),
+		}
+	}
+	if !p.isToolAllowed(server, call.Name) {
+		return &jsonRPCError{
+			Code: jsonRPCToolBlocked,
+			Message: fmt.Sprintf(i18n.T(
+				"MCP tool %s is not in the proxy's allowed tools",
+				"MCP 工具 %s 不在代理允许的工具列表中",
+			).GetMessage(), call.Name),
+		}
+	}
+	if policy == nil {
+		return nil
+	}
+
+	cmd := p.toolCommand(server, call)
+	result := policy.Check(cmd)
+	data := map[string]any{"tool": call.Name, "pattern": cmd.Pattern(), "action": result.Action, "rule": describeSafetyRule(result)}
+	switch result.Action {
+	case safety.ActionDeny:
+		return &jsonRPCError{
+			Code: jsonRPCToolBlocked,
+			Message: fmt.Sprintf(i18n.T(
+				"operation blocked by safety policy: MCP tool %s (rule: %s)",
+				"操作被安全策略拒绝: MCP 工具 %s (规则: %s)",
+			).GetMessage(), cmd.Pattern(), describeSafetyRule(result)),
+			Data: data,
+		}
+	case safety.ActionConfirm:
+		if g := policy.ApprovalFor(cmd); g != nil {
+			log.Printf("MCP Proxy tool call %s satisfied by safety approval %s (%s)", cmd.Pattern(), g.ID, g.Pattern)
+			return nil
+		}
+		return &jsonRPCError{
+			Code: jsonRPCConfirmRequired,
+			Message: fmt.Sprintf(i18n.T(
+				"Safety policy requires human approval for MCP tool %s (rule: %s). "+
+					"If you are an agent, ask the user whether this operation is allowed; they can approve it in a terminal with "+
+					"'aliyun configure safety-policy approve --pattern %s --ttl <duration>', after which the call can be retried.",
+				"安全策略要求人工批准 MCP 工具 %s (规则: %s)。"+
+					"若调用方为智能体，请先向用户说明并征得同意；用户可在终端中执行 "+
+					"'aliyun configure safety-policy approve --pattern %s --ttl <时长>' 进行审批，之后可重试该调用。",
+			).GetMessage(), cmd.Pattern(), describeSafetyRule(result), cmd.Pattern()),
+			Data: data,
+		}
+	}
+	return nil
+}
+
+// toolVisible 决定 tools/list 中是否保留该工具：被代理禁止或被安全策略无条件拒绝的工具不再暴露给客户端
+func (p *MCPProxy) toolVisible(server *MCPServerInfo, tool string, policy *safety.Policy) bool {
+	if p.isToolBlocked(server, tool) || !p.isToolAllowed(server, tool) {
+		return false
+	}
+	if policy == nil {
+		return true
+	}
+	return policy.Check(p.toolCommand(server, toolCallParams{Name: tool})).Action != safety.ActionDeny
+}
+
+func jsonRPCErrorResponse(id json.RawMessage, rpcErr *jsonRPCError) map[string]any {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return map[string]any{"jsonrpc": "2.0", "id": id, "error": rpcErr}
+}
+
+// parseJSONRPCRequests 解析单条或批量消息；批量消息中无法解析的项记录在 invalid 中，由调用方拒绝
+func parseJSONRPCRequests(body []byte) (reqs []jsonRPCRequest, batch bool, invalid map[int]bool, err error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var items []json.RawMessage
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, true, nil, err
+		}
+		reqs = make([]jsonRPCRequest, len(items))
+		for i, item := range items {
+			if json.Unmarshal(item, &reqs[i]) != nil {
+				if invalid == nil {
+					invalid = make(map[int]bool)
+				}
+				invalid[i] = true
+				reqs[i] = jsonRPCRequest{}
+			}
+		}
+		return reqs, true, invalid, nil
+	}
+	var req jsonRPCRequest
+	err = json.Unmarshal(trimmed, &req)
+	return []jsonRPCRequest{req}, false, nil, err
+}
+
+// guardRequest 检查客户端发来的消息。reply 非空时不转发，直接把它作为响应返回；
+// listIDs 是需要过滤结果的 tools/list 请求 ID。
+// 无法解析的消息和参数一律拒绝：上游的解析方式可能不同，转发它们会绕过工具检查。
+func (p *MCPProxy) guardRequest(server *MCPServerInfo, body []byte) (reply []byte, listIDs map[string]bool) {
+	reqs, batch, invalid, err := parseJSONRPCRequests(body)
+	if err != nil {
+		log.Printf("MCP Proxy rejected a message that is not valid JSON-RPC: %v", err)
+		reply, _ = json.Marshal(jsonRPCErrorResponse(nil, &jsonRPCError{Code: jsonRPCParseError, Message: fmt.Sprintf("Parse error: %v", err)}))
+		return reply, nil
+	}
+
+	var policy *safety.Policy
+	policyLoaded := false
+	rejected := make(map[int]*jsonRPCError)
+	for i := range invalid {
+		rejected[i] = &jsonRPCError{Code: jsonRPCInvalidRequest, Message: "Invalid request: not a JSON-RPC request object"}
+	}
+	for i, req := range reqs {
+		if invalid[i] {
+			continue
+		}
+		switch req.Method {
+		case "tools/list":
+			if listIDs == nil {
+				listIDs = make(map[string]bool)
+			}
+			listIDs[string(req.ID)] = true
+		case "tools/call":
+			var call toolCallParams
+			if err := json.Unmarshal(req.Params, &call); err != nil || call.Name == "" {
+				log.Printf("MCP Proxy rejected tool call with invalid params: %s", req.Params)
+				rejected[i] = &jsonRPCError{Code: jsonRPCInvalidParams, Message: "Invalid params: tools/call needs a tool name and object arguments"}
+				continue
+			}
+			if !policyLoaded {
+				policy, policyLoaded = p.loadSafetyPolicy(), true
+			}
+			if rpcErr := p.checkToolCall(server, call, policy); rpcErr != nil {
+				log.Printf("MCP Proxy rejected tool call %s: %s", call.Name, rpcErr.Message)
+				rejected[i] = rpcErr
+			}
+		}
+	}
+	if len(rejected) == 0 {
+		return nil, listIDs
+	}
+
+	if !batch {
+		reply, _ = json.Marshal(jsonRPCErrorResponse(reqs[0].ID, rejected[0]))
+		return reply, nil
+	}
+	// 批量消息中任一调用被拒绝时整批不转发，其余请求返回说明原因的错误
+	var responses []map[string]any
+	for i, req := range reqs {
+		if len(req.ID) == 0 && !invalid[i] {
+			continue
+		}
+		rpcErr := rejected[i]
+		if rpcErr == nil {
+			rpcErr = &jsonRPCError{Code: jsonRPCToolBlocked, Message: "batch not forwarded: it contains a tool call rejected by the proxy"}
+		}
+		responses = append(responses, jsonRPCErrorResponse(req.ID, rpcErr))
+	}
+	if len(responses) == 0 {
+		// 只有通知，没有需要响应的请求
+		return []byte{}, nil
+	}
+	reply, _ = json.Marshal(responses)
+	return reply, nil
+}
+
+// filterToolsList 从 ID 属于 listIDs 的 tools/list 响应中去掉不可见的工具；其他消息原样返回
+func (p *MCPProxy) filterToolsList(server *MCPServerInfo, data []byte, listIDs map[string]bool) []byte {
+	if len(listIDs) == 0 {
+		return data
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(trimmed, &batch); err != nil {
+			return data
+		}
+		for i := range batch {
+			batch[i] = p.filterToolsList(server, batch[i], listIDs)
+		}
+		out, err := json.Marshal(batch)
+		if err != nil {
+			return data
+		}
+		return out
+	}
+
+	var msg map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &msg); err != nil || !listIDs[string(msg["id"])] || msg["result"] == nil {
+		return data
+	}
+	var result map[string]json.RawMessage
+	if err := json.Unmarshal(msg["result"], &result); err != nil {
+		return data
+	}
+	var tools []map[string]json.RawMessage
+	if err := json.Unmarshal(result["tools"], &tools); err != nil {
+		return data
+	}
+
+	policy := p.loadSafetyPolicy()
+	kept := make([]map[string]json.RawMessage, 0, len(tools))
+	for _, tool := range tools {
+		var name string
+		_ = json.Unmarshal(tool["name"], &name)
+		if p.toolVisible(server, name, policy) {
+			kept = append(kept, tool)
+		}
+	}
+	if len(kept) == len(tools) {
+		return data
+	}
+	log.Printf("MCP Proxy filtered %d of %d tools from tools/list", len(tools)-len(kept), len(tools))
+
+	var err error
+	if result["tools"], err = json.Marshal(kept); err != nil {
+		return data
+	}
+	if msg["result"], err = json.Marshal(result); err != nil {
+		return data
+	}
+	out, err := json.Marshal(msg)
+	if err != nil {
+		return data
+	}
+	return out
+}
@@ -0,0 +1,330 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpproxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/safety"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var guardTestServer = MCPServerInfo{
+	Id:      "ecs-id",
+	Name:    "ecs-server",
+	Product: "ecs",
+	Urls:    MCPInfoUrls{MCP: "https://example.com/mcp/ecs"},
+}
+
+func writeGuardTestPolicy(t *testing.T, rules string) string {
+	dir := t.TempDir()
+	policy := fmt.Sprintf(`{"enabled": true, "rules": %s}`, rules)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, safety.SafetyPolicyFileName), []byte(policy), 0600))
+	return dir
+}
+
+func decodeRPCError(t *testing.T, reply []byte) jsonRPCError {
+	var resp struct {
+		Error jsonRPCError `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(reply, &resp), string(reply))
+	return resp.Error
+}
+
+func TestMatchToolPattern(t *testing.T) {
+	server := &guardTestServer
+	assert.True(t, matchToolPattern("Describe*", server, "DescribeInstances"))
+	assert.True(t, matchToolPattern("describe*", server, "DescribeInstances"))
+	assert.False(t, matchToolPattern("Delete*", server, "DescribeInstances"))
+	assert.True(t, matchToolPattern("ecs-server:Delete*", server, "DeleteInstance"))
+	assert.True(t, matchToolPattern("ecs-id:Delete*", server, "DeleteInstance"))
+	assert.False(t, matchToolPattern("rds:Delete*", server, "DeleteInstance"))
+	assert.False(t, matchToolPattern("ecs-server:Delete*", nil, "DeleteInstance"))
+}
+
+func TestMCPProxy_guardRequest_ToolLists(t *testing.T) {
+	proxy := NewMCPProxy(ProxyConfig{
+		McpProfile:      NewMcpProfile("p"),
+		ExistMcpServers: []MCPServerInfo{guardTestServer},
+		AllowedTools:    []string{"Describe*", "Delete*"},
+		BlockedTools:    []string{"ecs-server:Delete*"},
+	})
+	server := &guardTestServer
+
+	reply, _ := proxy.guardRequest(server, []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"DescribeInstances"}}`))
+	assert.Nil(t, reply)
+
+	reply, _ = proxy.guardRequest(server, []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"DeleteInstance"}}`))
+	rpcErr := decodeRPCError(t, reply)
+	assert.Equal(t, jsonRPCToolBlocked, rpcErr.Code)
+	assert.Contains(t, rpcErr.Message, "blocked by the proxy")
+
+	reply, _ = proxy.guardRequest(server, []byte(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"StopInstance"}}`))
+	assert.Contains(t, decodeRPCError(t, reply).Message, "not in the proxy's allowed tools")
+
+	reply, listIDs := proxy.guardRequest(server, []byte(`{"jsonrpc":"2.0","id":4,"method":"tools/list"}`))
+	assert.Nil(t, reply)
+	assert.True(t, listIDs["4"])
+
+	// 无法解析的消息和参数不转发
+	reply, _ = proxy.guardRequest(server, []byte(`not json`))
+	assert.Equal(t, jsonRPCParseError, decodeRPCError(t, reply).Code)
+	reply, _ = proxy.guardRequest(server, []byte(`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"DeleteInstance","arguments":[1]}}`))
+	assert.Equal(t, jsonRPCInvalidParams, decodeRPCError(t, reply).Code)
+	reply, _ = proxy.guardRequest(server, []byte(`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":"DeleteInstance"}`))
+	assert.Equal(t, jsonRPCInvalidParams, decodeRPCError(t, reply).Code)
+}
+
+func TestMCPProxy_guardRequest_UnknownServer(t *testing.T) {
+	proxy := NewMCPProxy(ProxyConfig{
+		McpProfile:   NewMcpProfile("p"),
+		BlockedTools: []string{"ecs-server:Delete*"},
+		AllowedTools: []string{"ecs-server:*"},
+	})
+	// 找不到所属服务器时，限定服务器的黑名单规则按工具名匹配，白名单规则不匹配
+	reply, _ := proxy.guardRequest(nil, []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"DeleteInstance"}}`))
+	assert.Contains(t, decodeRPCError(t, reply).Message, "blocked by the proxy")
+	reply, _ = proxy.guardRequest(nil, []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"DescribeInstances"}}`))
+	assert.Contains(t, decodeRPCError(t, reply).Message, "not in the proxy's allowed tools")
+}
+
+func TestMCPProxy_guardRequest_Batch(t *testing.T) {
+	proxy := NewMCPProxy(ProxyConfig{McpProfile: NewMcpProfile("p"), BlockedTools: []string{"Delete*"}})
+
+	reply, _ := proxy.guardRequest(&guardTestServer, []byte(`[
+		{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"DescribeInstances"}},
+		{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"DeleteInstance"}},
+		{"jsonrpc":"2.0","method":"notifications/progress"}
+	]`))
+	var responses []struct {
+		ID    int          `json:"id"`
+		Error jsonRPCError `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(reply, &responses))
+	require.Len(t, responses, 2)
+	assert.Contains(t, responses[0].Error.Message, "batch not forwarded")
+	assert.Contains(t, responses[1].Error.Message, "DeleteInstance")
+
+	// 批量消息中有无法解析的项时整批拒绝
+	reply, _ = proxy.guardRequest(&guardTestServer, []byte(`[{"jsonrpc":"2.0","method":"tools/call","params":{"name":"DeleteInstance"}},5]`))
+	var invalid []struct {
+		ID    *int         `json:"id"`
+		Error jsonRPCError `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(reply, &invalid))
+	require.Len(t, invalid, 1)
+	assert.Nil(t, invalid[0].ID)
+	assert.Equal(t, jsonRPCInvalidRequest, invalid[0].Error.Code)
+
+	reply, _ = proxy.guardRequest(&guardTestServer, []byte(`[{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"DescribeInstances"}},`))
+	assert.Equal(t, jsonRPCParseError, decodeRPCError(t, reply).Code)
+}
+
+func TestMCPProxy_guardRequest_SafetyPolicy(t *testing.T) {
+	dir := writeGuardTestPolicy(t, `[
+		{"pattern": "ecs:DeleteInstance", "action": "deny", "when": {"params": [{"name": "Force", "value": "true"}]}},
+		{"pattern": "ecs:Delete*", "action": "deny"},
+		{"pattern": "ecs:Stop*", "action": "confirm"}
+	]`)
+	proxy := NewMCPProxy(ProxyConfig{
+		McpProfile:      NewMcpProfile("p"),
+		ExistMcpServers: []MCPServerInfo{guardTestServer},
+		SafetyConfigDir: dir,
+	})
+	server := &guardTestServer
+
+	reply, _ := proxy.guardRequest(server, []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"DeleteInstance","arguments":{"Force":true}}}`))
+	rpcErr := decodeRPCError(t, reply)
+	assert.Equal(t, jsonRPCToolBlocked, rpcErr.Code)
+	assert.Contains(t, rpcErr.Message, "ecs:DeleteInstance")
+	assert.Contains(t, rpcErr.Message, "when")
+
+	reply, _ = proxy.guardRequest(server, []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"StopInstance"}}`))
+	rpcErr = decodeRPCError(t, reply)
+	assert.Equal(t, jsonRPCConfirmRequired, rpcErr.Code)
+	assert.Contains(t, rpcErr.Message, "safety-policy approve --pattern ecs:StopInstance")
+
+	_, err := safety.AddApproval(dir, "ecs:StopInstance", 10*time.Minute, "tester")
+	require.NoError(t, err)
+	reply, _ = proxy.guardRequest(server, []byte(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"StopInstance"}}`))
+	assert.Nil(t, reply)
+
+	reply, _ = proxy.guardRequest(server, []byte(`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"DescribeInstances"}}`))
+	assert.Nil(t, reply)
+}
+
+func TestMCPProxy_filterToolsList(t *testing.T) {
+	dir := writeGuardTestPolicy(t, `[{"pattern": "ecs:Delete*", "action": "deny"}, {"pattern": "ecs:Stop*", "action": "confirm"}]`)
+	proxy := NewMCPProxy(ProxyConfig{
+		McpProfile:      NewMcpProfile("p"),
+		BlockedTools:    []string{"Reboot*"},
+		SafetyConfigDir: dir,
+	})
+	server := &guardTestServer
+	resp := []byte(`{"jsonrpc":"2.0","id":7,"result":{"tools":[
+		{"name":"DescribeInstances","description":"d"},
+		{"name":"DeleteInstance"},
+		{"name":"RebootInstance"},
+		{"name":"StopInstance"}
+	],"nextCursor":"c"}}`)
+
+	// 非 tools/list 响应保持不变
+	assert.Equal(t, resp, proxy.filterToolsList(server, resp, map[string]bool{"8": true}))
+
+	var msg struct {
+		Result struct {
+			Tools []struct {
+				Name        string `json:"name"`
+				Description string `json:"description"`
+			} `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		} `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(proxy.filterToolsList(server, resp, map[string]bool{"7": true}), &msg))
+	require.Len(t, msg.Result.Tools, 2)
+	assert.Equal(t, "DescribeInstances", msg.Result.Tools[0].Name)
+	assert.Equal(t, "d", msg.Result.Tools[0].Description)
+	assert.Equal(t, "StopInstance", msg.Result.Tools[1].Name)
+	assert.Equal(t, "c", msg.Result.NextCursor)
+}
+
+func TestMCPProxy_ServeMCPProxyRequest_ToolGuard(t *testing.T) {
+	var upstreamCalls int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&upstreamCalls, 1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{\"tools\":[{\"name\":\"DescribeInstances\"},{\"name\":\"DeleteInstance\"}]}}\n\n")
+	}))
+	defer upstream.Close()
+
+	profile := NewMcpProfile("p")
+	profile.MCPOAuthAccessToken = "token"
+	profile.MCPOAuthAccessTokenExpire = time.Now().Unix() + 3600
+	proxy := NewMCPProxy(ProxyConfig{
+		McpProfile:      profile,
+		ExistMcpServers: []MCPServerInfo{guardTestServer},
+		UpstreamBaseURL: upstream.URL,
+		BlockedTools:    []string{"Delete*"},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/mcp/ecs", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	w := httptest.NewRecorder()
+	proxy.ServeMCPProxyRequest(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "DescribeInstances")
+	assert.NotContains(t, w.Body.String(), "DeleteInstance")
+
+	req = httptest.NewRequest(http.MethodPost, "/mcp/ecs", strings.NewReader(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"DeleteInstance"}}`))
+	w = httptest.NewRecorder()
+	proxy.ServeMCPProxyRequest(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, jsonRPCToolBlocked, decodeRPCError(t, w.Body.Bytes()).Code)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&upstreamCalls))
+}
+
+func TestMCPProxy_ServeMCPProxyRequest_LegacySSE(t *testing.T) {
+	// 旧版 SSE 上游：POST 到消息地址只返回 202，响应经由 GET 建立的事件流返回
+	events := make(chan string, 4)
+	closed := make(chan struct{})
+	var upstreamCalls int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/ecs/sse":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "event: endpoint\ndata: /ecs/messages?sessionId=abc\n\n")
+			w.(http.Flusher).Flush()
+			for {
+				select {
+				case e := <-events:
+					fmt.Fprintf(w, "event: message\ndata: %s\n\n", e)
+					w.(http.Flusher).Flush()
+				case <-closed:
+					return
+				}
+			}
+		case r.Method == http.MethodPost && r.URL.Path == "/ecs/messages":
+			atomic.AddInt64(&upstreamCalls, 1)
+			events <- `{"jsonrpc":"2.0","id":1,"result":{"tools":[{"name":"DescribeInstances"},{"name":"DeleteInstance"}]}}`
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	profile := NewMcpProfile("p")
+	profile.MCPOAuthAccessToken = "token"
+	profile.MCPOAuthAccessTokenExpire = time.Now().Unix() + 3600
+	proxy := NewMCPProxy(ProxyConfig{
+		McpProfile: profile,
+		ExistMcpServers: []MCPServerInfo{{
+			Id:      "ecs-id",
+			Name:    "ecs-server",
+			Product: "ecs",
+			Urls:    MCPInfoUrls{SSE: "https://example.com/ecs/sse"},
+		}},
+		UpstreamBaseURL: upstream.URL,
+		// 限定服务器的模式要求消息地址能找到所属服务器
+		BlockedTools: []string{"ecs-server:Delete*"},
+	})
+	front := httptest.NewServer(http.HandlerFunc(proxy.ServeMCPProxyRequest))
+	defer front.Close()
+	// 先结束上游事件流，代理转发的事件流随之结束
+	defer close(closed)
+
+	resp, err := http.Get(front.URL + "/ecs/sse")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	nextData := func() string {
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			if strings.HasPrefix(line, "data:") {
+				return strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			}
+		}
+	}
+	endpoint := nextData()
+	assert.Equal(t, "/ecs/messages?sessionId=abc", endpoint)
+
+	post := func(body string) *http.Response {
+		resp, err := http.Post(front.URL+endpoint, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+	assert.Equal(t, http.StatusAccepted, post(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`).StatusCode)
+	list := nextData()
+	assert.Contains(t, list, "DescribeInstances")
+	assert.NotContains(t, list, "DeleteInstance")
+
+	assert.Equal(t, http.StatusAccepted, post(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"DeleteInstance"}}`).StatusCode)
+	rejected := nextData()
+	assert.Equal(t, jsonRPCToolBlocked, decodeRPCError(t, []byte(rejected)).Code)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&upstreamCalls))
+}
@@ -0,0 +1,320 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/audit"
+)
+
+// 流量日志：可选地把经过代理的每条 JSON-RPC 消息记录为一行 JSON，写入按大小轮转的文件，
+// 用于事后审查 agent 通过代理做了什么。只记录方法、工具名、ID、耗时和错误码等摘要，
+// tools/call 的参数按名称脱敏并限制长度，不记录完整的消息体。
+
+const (
+	trafficToServer = "to_server" // 客户端发往上游 MCP Server 的消息
+	trafficToClient = "to_client" // 上游或代理返回给客户端的消息
+
+	defaultTrafficLogMaxSizeMB    = 10
+	defaultTrafficLogMaxBackups   = 5
+	defaultTrafficLogMaxValueSize = 256
+
+	// maxTrafficArgumentsSize 限制单条记录中参数的总长度，超出时只记录大小
+	maxTrafficArgumentsSize = 4096
+	maxTrafficErrorSize     = 512
+)
+
+type TrafficLogConfig struct {
+	Path         string   // 日志文件路径，为空时不记录流量日志
+	Redact       []string // 除内置的敏感字段（*Password*、*Token* 等）外，额外需要脱敏的参数名模式，支持 * 通配
+	MaxValueSize int      // 单个参数值记录的最大字节数，超出部分截断
+	MaxSizeMB    int      // 日志文件超过该大小后轮转为 <path>.1
+	MaxBackups   int      // 保留的轮转文件个数
+}
+
+type trafficRecord struct {
+	Time       string          `json:"time"`
+	Direction  string          `json:"direction"`
+	Transport  string          `json:"transport"`
+	Server     string          `json:"server"`
+	Client     string          `json:"client,omitempty"`
+	Method     string          `json:"method,omitempty"`
+	Tool       string          `json:"tool,omitempty"`
+	ID         json.RawMessage `json:"id,omitempty"`
+	Arguments  any             `json:"arguments,omitempty"`
+	LatencyMS  *int64          `json:"latency_ms,omitempty"`
+	StatusCode int             `json:"status_code,omitempty"`
+	ErrorCode  int             `json:"error_code,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+type trafficMessage struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Error  *jsonRPCError   `json:"error,omitempty"`
+}
+
+type trafficLogger struct {
+	mu          sync.Mutex
+	config      TrafficLogConfig
+	writeFailed bool // 写入失败只提示一次，避免刷屏
+}
+
+// newTrafficLogger 在配置了日志路径时创建流量日志，否则返回 nil（不记录）
+func newTrafficLogger(config TrafficLogConfig) *trafficLogger {
+	if config.Path == "" {
+		return nil
+	}
+	if config.MaxValueSize <= 0 {
+		config.MaxValueSize = defaultTrafficLogMaxValueSize
+	}
+	if config.MaxSizeMB <= 0 {
+		config.MaxSizeMB = defaultTrafficLogMaxSizeMB
+	}
+	if config.MaxBackups <= 0 {
+		config.MaxBackups = defaultTrafficLogMaxBackups
+	}
+	return &trafficLogger{config: config}
+}
+
+func (l *trafficLogger) write(rec *trafficRecord) {
+	rec.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := audit.AppendLine(l.config.Path, line, int64(l.config.MaxSizeMB)*1024*1024, l.config.MaxBackups); err != nil {
+		if !l.writeFailed {
+			log.Printf("MCP Proxy failed to write traffic log %s: %v", l.config.Path, err)
+			l.writeFailed = true
+		}
+		return
+	}
+	l.writeFailed = false
+}
+
+// redactArguments 脱敏工具参数：敏感字段替换为 ******，过长的字符串截断，整体过大时只记录大小
+func (l *trafficLogger) redactArguments(args map[string]any) any {
+	if len(args) == 0 {
+		return nil
+	}
+	redacted := l.redactValue(args)
+	if encoded, err := json.Marshal(redacted); err != nil || len(encoded) > maxTrafficArgumentsSize {
+		return fmt.Sprintf("<omitted, %d bytes>", len(encoded))
+	}
+	return redacted
+}
+
+func (l *trafficLogger) redactValue(v any) any {
+	switch value := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(value))
+		for k, child := range value {
+			if audit.IsSensitiveName(k, l.config.Redact) {
+				out[k] = audit.RedactedValue
+				continue
+			}
+			out[k] = l.redactValue(child)
+		}
+		return out
+	case []any:
+		out := make([]any, len(value))
+		for i, child := range value {
+			out[i] = l.redactValue(child)
+		}
+		return out
+	case string:
+		return truncateTrafficValue(value, l.config.MaxValueSize)
+	default:
+		return v
+	}
+}
+
+// truncateTrafficValue 截断到 max 字节以内，不拆分 UTF-8 字符
+func truncateTrafficValue(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	n := max
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + fmt.Sprintf("...(%d bytes)", len(s))
+}
+
+func parseTrafficMessages(data []byte) []trafficMessage {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var batch []trafficMessage
+		if json.Unmarshal(trimmed, &batch) != nil {
+			return nil
+		}
+		return batch
+	}
+	var msg trafficMessage
+	if json.Unmarshal(trimmed, &msg) != nil {
+		return nil
+	}
+	return []trafficMessage{msg}
+}
+
+type pendingCall struct {
+	method string
+	tool   string
+}
+
+// trafficExchange 记录一次转发（一个 HTTP 请求或一条 stdio 消息）中的消息，
+// 按 ID 把响应与请求关联起来计算耗时。logger 为 nil 时所有方法都不做任何事。
+type trafficExchange struct {
+	logger    *trafficLogger
+	transport string
+	server    string
+	client    string
+	start     time.Time
+
+	mu      sync.Mutex
+	pending map[string]pendingCall
+}
+
+// begin 记录客户端发来的消息，body 为空（如 SSE 的 GET 请求）时只创建用于记录响应的 exchange。
+// client 为开启入站认证时的客户端名称
+func (l *trafficLogger) begin(transport string, server *MCPServerInfo, client string, body []byte) *trafficExchange {
+	if l == nil {
+		return nil
+	}
+	ex := &trafficExchange{
+		logger:    l,
+		transport: transport,
+		server:    serverLabel(server),
+		client:    client,
+		start:     time.Now(),
+		pending:   make(map[string]pendingCall),
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return ex
+	}
+	for _, msg := range parseTrafficMessages(body) {
+		rec := ex.record(trafficToServer, msg)
+		if msg.Method == "tools/call" {
+			var call toolCallParams
+			if json.Unmarshal(msg.Params, &call) == nil {
+				rec.Tool = call.Name
+				rec.Arguments = l.redactArguments(call.Arguments)
+			}
+		}
+		if msg.Method != "" && len(msg.ID) > 0 {
+			ex.mu.Lock()
+			ex.pending[string(msg.ID)] = pendingCall{method: msg.Method, tool: rec.Tool}
+			ex.mu.Unlock()
+		}
+		l.write(rec)
+	}
+	return ex
+}
+
+func (ex *trafficExchange) record(direction string, msg trafficMessage) *trafficRecord {
+	rec := &trafficRecord{
+		Direction: direction,
+		Transport: ex.transport,
+		Server:    ex.server,
+		Client:    ex.client,
+		Method:    msg.Method,
+		ID:        msg.ID,
+	}
+	if msg.Error != nil {
+		rec.ErrorCode = msg.Error.Code
+		rec.Error = truncateTrafficValue(msg.Error.Message, maxTrafficErrorSize)
+	}
+	return rec
+}
+
+// respond 记录返回给客户端的消息，包括上游的响应、上游主动发出的通知以及代理直接生成的错误
+func (ex *trafficExchange) respond(data []byte) {
+	if ex == nil {
+		return
+	}
+	for _, msg := range parseTrafficMessages(data) {
+		rec := ex.record(trafficToClient, msg)
+		if msg.Method == "" && len(msg.ID) > 0 {
+			ex.mu.Lock()
+			call, ok := ex.pending[string(msg.ID)]
+			delete(ex.pending, string(msg.ID))
+			ex.mu.Unlock()
+			if ok {
+				latency := time.Since(ex.start).Milliseconds()
+				rec.Method = call.method
+				rec.Tool = call.tool
+				rec.LatencyMS = &latency
+			}
+		}
+		ex.logger.write(rec)
+	}
+}
+
+// finish 为没有收到 JSON-RPC 响应的请求补记一条失败记录。statusCode 为 202 时
+// 响应会从单独的 SSE 流返回（旧版 SSE 传输），不视为失败。
+func (ex *trafficExchange) finish(statusCode int) {
+	if ex == nil || statusCode == http.StatusAccepted {
+		return
+	}
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+	latency := time.Since(ex.start).Milliseconds()
+	for id, call := range ex.pending {
+		ex.logger.write(&trafficRecord{
+			Direction:  trafficToClient,
+			Transport:  ex.transport,
+			Server:     ex.server,
+			Client:     ex.client,
+			Method:     call.method,
+			Tool:       call.tool,
+			ID:         json.RawMessage(id),
+			LatencyMS:  &latency,
+			StatusCode: statusCode,
+			Error:      "no JSON-RPC response from upstream",
+		})
+	}
+	ex.pending = nil
+}
+
+// statusRecorder 记录写给客户端的 HTTP 状态码，供流量日志使用
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
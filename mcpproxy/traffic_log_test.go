@@ -0,0 +1,218 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readTrafficLog(t *testing.T, path string) []map[string]any {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var rec map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &rec), line)
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestNewTrafficLogger(t *testing.T) {
+	assert.Nil(t, newTrafficLogger(TrafficLogConfig{}))
+
+	l := newTrafficLogger(TrafficLogConfig{Path: "traffic.log"})
+	require.NotNil(t, l)
+	assert.Equal(t, defaultTrafficLogMaxValueSize, l.config.MaxValueSize)
+	assert.Equal(t, defaultTrafficLogMaxSizeMB, l.config.MaxSizeMB)
+	assert.Equal(t, defaultTrafficLogMaxBackups, l.config.MaxBackups)
+
+	// 未开启时所有记录操作都是空操作
+	var disabled *trafficLogger
+	ex := disabled.begin(TransportHTTP, nil, "", []byte(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	assert.Nil(t, ex)
+	ex.respond([]byte(`{"jsonrpc":"2.0","id":1,"result":{}}`))
+	ex.finish(http.StatusOK)
+}
+
+func TestTrafficLogger_redactArguments(t *testing.T) {
+	l := newTrafficLogger(TrafficLogConfig{Path: "traffic.log", Redact: []string{"Tag*"}, MaxValueSize: 8})
+	got := l.redactArguments(map[string]any{
+		"InstanceId":    "i-123",
+		"Password":      "p@ss",
+		"TagValue":      "project",
+		"Description":   "0123456789",
+		"Nested":        map[string]any{"SecurityToken": "t", "Names": []any{"a", "中文中文"}},
+		"Count":         float64(3),
+		"DryRun":        true,
+		"UserDataShort": "x",
+	}).(map[string]any)
+
+	assert.Equal(t, "i-123", got["InstanceId"])
+	assert.Equal(t, "******", got["Password"])
+	assert.Equal(t, "******", got["TagValue"])
+	assert.Equal(t, "01234567...(10 bytes)", got["Description"])
+	assert.Equal(t, float64(3), got["Count"])
+	assert.Equal(t, true, got["DryRun"])
+	assert.Equal(t, "x", got["UserDataShort"])
+	nested := got["Nested"].(map[string]any)
+	assert.Equal(t, "******", nested["SecurityToken"])
+	assert.Equal(t, []any{"a", "中文...(12 bytes)"}, nested["Names"])
+
+	assert.Nil(t, l.redactArguments(nil))
+
+	l = newTrafficLogger(TrafficLogConfig{Path: "traffic.log", MaxValueSize: maxTrafficArgumentsSize})
+	large := l.redactArguments(map[string]any{"Content": strings.Repeat("a", maxTrafficArgumentsSize)})
+	assert.Equal(t, fmt.Sprintf("<omitted, %d bytes>", maxTrafficArgumentsSize+14), large)
+
+	all := newTrafficLogger(TrafficLogConfig{Path: "traffic.log", Redact: []string{"*"}}).redactArguments(map[string]any{"InstanceId": "i-1"})
+	assert.Equal(t, map[string]any{"InstanceId": "******"}, all)
+}
+
+func TestTrafficExchange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "traffic.log")
+	l := newTrafficLogger(TrafficLogConfig{Path: path})
+
+	ex := l.begin(TransportStdio, &guardTestServer, "agent-a", []byte(`[
+		{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"RunCommand","arguments":{"InstanceId":"i-1","Password":"secret"}}},
+		{"jsonrpc":"2.0","id":"two","method":"tools/list"},
+		{"jsonrpc":"2.0","id":3,"method":"ping"},
+		{"jsonrpc":"2.0","method":"notifications/initialized"}
+	]`))
+	ex.respond([]byte(`{"jsonrpc":"2.0","method":"notifications/progress","params":{"progress":1}}`))
+	ex.respond([]byte(`[{"jsonrpc":"2.0","id":1,"error":{"code":-32001,"message":"blocked"}},{"jsonrpc":"2.0","id":"two","result":{"tools":[]}}]`))
+	ex.finish(http.StatusBadGateway)
+
+	records := readTrafficLog(t, path)
+	require.Len(t, records, 8)
+
+	assert.Equal(t, trafficToServer, records[0]["direction"])
+	assert.Equal(t, TransportStdio, records[0]["transport"])
+	assert.Equal(t, "ecs-server", records[0]["server"])
+	assert.Equal(t, "agent-a", records[0]["client"])
+	assert.Equal(t, "tools/call", records[0]["method"])
+	assert.Equal(t, "RunCommand", records[0]["tool"])
+	assert.Equal(t, float64(1), records[0]["id"])
+	assert.Equal(t, map[string]any{"InstanceId": "i-1", "Password": "******"}, records[0]["arguments"])
+	assert.NotContains(t, records[0], "latency_ms")
+	assert.Equal(t, "notifications/initialized", records[3]["method"])
+	assert.NotContains(t, records[3], "id")
+
+	// 上游主动发出的通知
+	assert.Equal(t, trafficToClient, records[4]["direction"])
+	assert.Equal(t, "notifications/progress", records[4]["method"])
+	assert.NotContains(t, records[4], "latency_ms")
+
+	assert.Equal(t, "tools/call", records[5]["method"])
+	assert.Equal(t, "RunCommand", records[5]["tool"])
+	assert.Equal(t, float64(jsonRPCToolBlocked), records[5]["error_code"])
+	assert.Equal(t, "blocked", records[5]["error"])
+	assert.Contains(t, records[5], "latency_ms")
+	assert.NotContains(t, records[5], "arguments")
+
+	assert.Equal(t, "tools/list", records[6]["method"])
+	assert.Equal(t, "two", records[6]["id"])
+	assert.NotContains(t, records[6], "error_code")
+
+	// 没有收到响应的请求
+	assert.Equal(t, "ping", records[7]["method"])
+	assert.Equal(t, float64(3), records[7]["id"])
+	assert.Equal(t, float64(http.StatusBadGateway), records[7]["status_code"])
+	assert.Contains(t, records[7]["error"], "no JSON-RPC response")
+
+	// 旧版 SSE 传输的 202 不视为失败
+	ex = l.begin(TransportHTTP, nil, "", []byte(`{"jsonrpc":"2.0","id":9,"method":"ping"}`))
+	ex.finish(http.StatusAccepted)
+	records = readTrafficLog(t, path)
+	require.Len(t, records, 9)
+	assert.Equal(t, unknownServerLabel, records[8]["server"])
+	assert.NotContains(t, records[8], "client")
+}
+
+func TestMCPProxy_ServeMCPProxyRequest_TrafficLog(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{\"content\":[]}}\n\n")
+	}))
+	defer upstream.Close()
+
+	path := filepath.Join(t.TempDir(), "traffic.log")
+	profile := NewMcpProfile("p")
+	profile.MCPOAuthAccessToken = "token"
+	profile.MCPOAuthAccessTokenExpire = time.Now().Unix() + 3600
+	proxy := NewMCPProxy(ProxyConfig{
+		McpProfile:      profile,
+		ExistMcpServers: []MCPServerInfo{guardTestServer},
+		UpstreamBaseURL: upstream.URL,
+		BlockedTools:    []string{"Delete*"},
+		TrafficLog:      TrafficLogConfig{Path: path},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/mcp/ecs", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"DescribeInstances","arguments":{"RegionId":"cn-hangzhou"}}}`))
+	w := httptest.NewRecorder()
+	proxy.ServeMCPProxyRequest(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"content":[]`)
+
+	req = httptest.NewRequest(http.MethodPost, "/mcp/ecs", strings.NewReader(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"DeleteInstance"}}`))
+	proxy.ServeMCPProxyRequest(httptest.NewRecorder(), req)
+
+	records := readTrafficLog(t, path)
+	require.Len(t, records, 4)
+	assert.Equal(t, TransportHTTP, records[0]["transport"])
+	assert.Equal(t, "DescribeInstances", records[0]["tool"])
+	assert.Equal(t, map[string]any{"RegionId": "cn-hangzhou"}, records[0]["arguments"])
+	assert.Equal(t, trafficToClient, records[1]["direction"])
+	assert.Equal(t, "DescribeInstances", records[1]["tool"])
+	assert.Contains(t, records[1], "latency_ms")
+	assert.NotContains(t, records[1], "error_code")
+	assert.Equal(t, "DeleteInstance", records[3]["tool"])
+	assert.Equal(t, float64(jsonRPCToolBlocked), records[3]["error_code"])
+}
+
+func TestMCPProxy_ServeStdio_TrafficLog(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, "boom")
+	}))
+	defer upstream.Close()
+
+	path := filepath.Join(t.TempDir(), "traffic.log")
+	proxy := newStdioTestProxy(upstream.URL, nil)
+	proxy.traffic = newTrafficLogger(TrafficLogConfig{Path: path})
+
+	var out strings.Builder
+	in := strings.NewReader(`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"DescribeInstances"}}` + "\n")
+	require.NoError(t, proxy.ServeStdio(in, &out, &guardTestServer))
+
+	records := readTrafficLog(t, path)
+	require.Len(t, records, 2)
+	assert.Equal(t, TransportStdio, records[1]["transport"])
+	assert.Equal(t, trafficToClient, records[1]["direction"])
+	assert.Equal(t, "DescribeInstances", records[1]["tool"])
+	assert.Equal(t, float64(jsonRPCInternalError), records[1]["error_code"])
+	assert.Contains(t, records[1]["error"], "status 500")
+}
@@ -0,0 +1,276 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpserver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aliyun/aliyun-cli/v3/meta"
+	"github.com/aliyun/aliyun-cli/v3/newmeta"
+)
+
+// 元数据目录：产品和 API 以 CLI 内置的 meta.Repository 为准（即 CLI 能调用的范围），
+// 名称、摘要和参数说明来自 newmeta，缺失时回退到 meta 中的信息。
+
+var (
+	getProducts  = newmeta.GetProducts
+	getAPIs      = newmeta.GetAPIs
+	getAPIDetail = newmeta.GetAPIDetail
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+type catalog struct {
+	repo     *meta.Repository
+	language string
+
+	mu           sync.Mutex
+	productNames map[string]string                 // 小写产品代码 -> 产品名称
+	apis         map[string]map[string]newmeta.API // 小写产品代码 -> API 名称 -> 标题和摘要
+}
+
+func newCatalog(repo *meta.Repository, language string) *catalog {
+	return &catalog{
+		repo:     repo,
+		language: language,
+		apis:     make(map[string]map[string]newmeta.API),
+	}
+}
+
+type productSummary struct {
+	Code    string `json:"code"`
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version"`
+	Style   string `json:"style"`
+	APIs    int    `json:"apis"`
+}
+
+type apiSummary struct {
+	Product    string `json:"product"`
+	API        string `json:"api"`
+	Title      string `json:"title,omitempty"`
+	Summary    string `json:"summary,omitempty"`
+	Deprecated bool   `json:"deprecated,omitempty"`
+}
+
+type parameterDescription struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Position    string `json:"position,omitempty"`
+	Required    bool   `json:"required"`
+	Description string `json:"description,omitempty"`
+}
+
+type apiDescription struct {
+	Product    string                 `json:"product"`
+	Version    string                 `json:"version"`
+	Style      string                 `json:"style"`
+	API        string                 `json:"api"`
+	Title      string                 `json:"title,omitempty"`
+	Summary    string                 `json:"summary,omitempty"`
+	Deprecated bool                   `json:"deprecated,omitempty"`
+	Method     string                 `json:"method,omitempty"`
+	Path       string                 `json:"path,omitempty"`
+	Parameters []parameterDescription `json:"parameters"`
+}
+
+func (c *catalog) productName(code string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.productNames == nil {
+		c.productNames = make(map[string]string)
+		if products, err := getProducts(c.language); err == nil {
+			for _, p := range products {
+				c.productNames[strings.ToLower(p.Code)] = strings.TrimSpace(p.Name)
+			}
+		}
+	}
+	return c.productNames[strings.ToLower(code)]
+}
+
+// apiSummaries 按产品缓存 API 的标题和摘要，元数据缺失时返回空
+func (c *catalog) apiSummaries(code string) map[string]newmeta.API {
+	key := strings.ToLower(code)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	apis, ok := c.apis[key]
+	if !ok {
+		apis, _ = getAPIs(c.language, code)
+		c.apis[key] = apis
+	}
+	return apis
+}
+
+func (c *catalog) product(code string) (meta.Product, error) {
+	product, ok := c.repo.GetProduct(code)
+	if !ok {
+		return product, fmt.Errorf("product '%s' not found, use list_products to find the product code", code)
+	}
+	return product, nil
+}
+
+// apiName 返回产品中与 name 匹配的 API 名称，大小写不敏感
+func (c *catalog) apiName(product meta.Product, name string) (string, error) {
+	for _, api := range product.ApiNames {
+		if api == name {
+			return api, nil
+		}
+	}
+	for _, api := range product.ApiNames {
+		if strings.EqualFold(api, name) {
+			return api, nil
+		}
+	}
+	return "", fmt.Errorf("API '%s' not found in product '%s', use search_apis to find the API name", name, product.Code)
+}
+
+func matchKeywords(text string, keywords []string) bool {
+	text = strings.ToLower(text)
+	for _, k := range keywords {
+		if !strings.Contains(text, k) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *catalog) listProducts(query string) []productSummary {
+	keywords := strings.Fields(strings.ToLower(query))
+	var out []productSummary
+	for _, p := range c.repo.Products {
+		name := c.productName(p.Code)
+		if !matchKeywords(p.Code+" "+name, keywords) {
+			continue
+		}
+		out = append(out, productSummary{
+			Code:    strings.ToLower(p.Code),
+			Name:    name,
+			Version: p.Version,
+			Style:   p.ApiStyle,
+			APIs:    len(p.ApiNames),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Code < out[j].Code })
+	return out
+}
+
+// searchAPIs 返回所有关键字都出现在产品代码、产品名称、API 名称或摘要中的 API；
+// API 名称本身包含全部关键字的结果排在前面。total 为截断前的匹配数。
+func (c *catalog) searchAPIs(query, productCode string, limit int) (results []apiSummary, total int, err error) {
+	products := c.repo.Products
+	if productCode != "" {
+		product, err := c.product(productCode)
+		if err != nil {
+			return nil, 0, err
+		}
+		products = []meta.Product{product}
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	keywords := strings.Fields(strings.ToLower(query))
+	var byName, byText []apiSummary
+	for _, p := range products {
+		code := strings.ToLower(p.Code)
+		productText := code + " " + c.productName(p.Code)
+		summaries := c.apiSummaries(p.Code)
+		for _, name := range p.ApiNames {
+			info := summaries[name]
+			s := apiSummary{Product: code, API: name, Title: info.Title, Summary: info.Summary, Deprecated: info.Deprecated}
+			switch {
+			case matchKeywords(name, keywords):
+				byName = append(byName, s)
+			case matchKeywords(productText+" "+name+" "+info.Title+" "+info.Summary, keywords):
+				byText = append(byText, s)
+			}
+		}
+	}
+	results = append(byName, byText...)
+	total = len(results)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, total, nil
+}
+
+func (c *catalog) describeAPI(productCode, name string) (*apiDescription, error) {
+	product, err := c.product(productCode)
+	if err != nil {
+		return nil, err
+	}
+	name, err = c.apiName(product, name)
+	if err != nil {
+		return nil, err
+	}
+
+	desc := &apiDescription{
+		Product:    strings.ToLower(product.Code),
+		Version:    product.Version,
+		Style:      product.ApiStyle,
+		API:        name,
+		Parameters: []parameterDescription{},
+	}
+	if info, ok := c.apiSummaries(product.Code)[name]; ok {
+		desc.Title = info.Title
+		desc.Summary = info.Summary
+		desc.Deprecated = info.Deprecated
+	}
+
+	if detail, err := getAPIDetail(c.language, product.Code, name); err == nil && detail != nil {
+		desc.Deprecated = desc.Deprecated || detail.Deprecated
+		desc.Method = detail.Method
+		desc.Path = detail.PathPattern
+		for _, p := range detail.Parameters {
+			desc.Parameters = append(desc.Parameters, parameterDescription{
+				Name:        p.Name,
+				Type:        p.Type,
+				Position:    p.Position,
+				Required:    p.Required,
+				Description: p.Description,
+			})
+		}
+		return desc, nil
+	}
+
+	api, ok := meta.HookGetApi(c.repo.GetApi)(product.Code, product.Version, name)
+	if !ok {
+		return nil, fmt.Errorf("no metadata for API '%s' of product '%s'", name, product.Code)
+	}
+	desc.Method = api.Method
+	desc.Path = api.PathPattern
+	for _, p := range api.Parameters {
+		if p.Hidden {
+			continue
+		}
+		desc.Parameters = append(desc.Parameters, parameterDescription{
+			Name:        p.Name,
+			Type:        p.Type,
+			Position:    p.Position,
+			Required:    p.Required,
+			Description: p.Description[c.language],
+		})
+	}
+	return desc, nil
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpserver
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aliyun/aliyun-cli/v3/meta"
+	"github.com/aliyun/aliyun-cli/v3/newmeta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockCatalog 使用模拟的元数据构造 catalog，并在测试结束后恢复 hook
+func mockCatalog(t *testing.T) *catalog {
+	repo, err := meta.MockLoadRepository([]meta.Product{
+		{Code: "Ecs", Version: "2014-05-26", ApiStyle: "rpc", ApiNames: []string{"DescribeInstances", "DeleteInstance", "DescribeRegions", "CreateSnapshot"}},
+		{Code: "CS", Version: "2015-12-15", ApiStyle: "restful", ApiNames: []string{"DescribeClustersV1"}},
+	})
+	require.NoError(t, err)
+
+	originProducts, originAPIs, originDetail, originGetApi := getProducts, getAPIs, getAPIDetail, meta.HookGetApi
+	t.Cleanup(func() {
+		getProducts, getAPIs, getAPIDetail, meta.HookGetApi = originProducts, originAPIs, originDetail, originGetApi
+	})
+	getProducts = func(language string) ([]newmeta.Product, error) {
+		return []newmeta.Product{
+			{Code: "ecs", Name: "Elastic Compute Service "},
+			{Code: "cs", Name: "Container Service for Kubernetes"},
+		}, nil
+	}
+	getAPIs = func(language, code string) (map[string]newmeta.API, error) {
+		if code != "Ecs" {
+			return nil, errors.New("not found")
+		}
+		return map[string]newmeta.API{
+			"DescribeInstances": {Title: "查询实例", Summary: "Queries the details of one or more instances."},
+			"CreateSnapshot":    {Summary: "Creates a snapshot for a disk of an instance."},
+			"DeleteInstance":    {Summary: "Releases a pay-as-you-go instance.", Deprecated: true},
+		}, nil
+	}
+	getAPIDetail = func(language, code, name string) (*newmeta.APIDetail, error) {
+		if name != "DescribeInstances" {
+			return nil, errors.New("not found")
+		}
+		return &newmeta.APIDetail{
+			Name:   "DescribeInstances",
+			Method: "POST|GET",
+			Parameters: []newmeta.RequestParameter{
+				{Name: "RegionId", Type: "string", Position: "Query", Required: true, Description: "The region ID."},
+				{Name: "PageSize", Type: "integer", Position: "Query"},
+			},
+		}, nil
+	}
+	meta.HookGetApi = func(fn func(string, string, string) (meta.Api, bool)) func(string, string, string) (meta.Api, bool) {
+		return func(productCode, version, apiName string) (meta.Api, bool) {
+			switch apiName {
+			case "DescribeClustersV1":
+				return meta.Api{
+					Name:        apiName,
+					Method:      "GET",
+					PathPattern: "/api/v1/clusters",
+					Parameters: []meta.Parameter{
+						{Name: "name", Position: "Query", Type: "String", Description: map[string]string{"en": "The cluster name."}},
+						{Name: "Hidden", Position: "Query", Hidden: true},
+					},
+				}, true
+			case "DescribeInstances":
+				return meta.Api{Name: apiName, Parameters: []meta.Parameter{{Name: "RegionId", Position: "Query", Required: true}}}, true
+			}
+			return meta.Api{}, false
+		}
+	}
+	return newCatalog(repo, "en")
+}
+
+func TestCatalog_listProducts(t *testing.T) {
+	c := mockCatalog(t)
+
+	products := c.listProducts("")
+	require.Len(t, products, 2)
+	assert.Equal(t, productSummary{Code: "cs", Name: "Container Service for Kubernetes", Version: "2015-12-15", Style: "restful", APIs: 1}, products[0])
+	assert.Equal(t, "ecs", products[1].Code)
+	assert.Equal(t, "Elastic Compute Service", products[1].Name)
+
+	products = c.listProducts("elastic COMPUTE")
+	require.Len(t, products, 1)
+	assert.Equal(t, "ecs", products[0].Code)
+
+	assert.Empty(t, c.listProducts("database"))
+}
+
+func TestCatalog_searchAPIs(t *testing.T) {
+	c := mockCatalog(t)
+
+	results, total, err := c.searchAPIs("instance", "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	// API 名称匹配的排在摘要匹配之前
+	assert.Equal(t, "DeleteInstance", results[0].API)
+	assert.True(t, results[0].Deprecated)
+	assert.Equal(t, "DescribeInstances", results[1].API)
+	assert.Equal(t, "查询实例", results[1].Title)
+	assert.Equal(t, "CreateSnapshot", results[2].API)
+
+	results, total, err = c.searchAPIs("describe", "", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Len(t, results, 1)
+
+	results, _, err = c.searchAPIs("", "cs", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []apiSummary{{Product: "cs", API: "DescribeClustersV1"}}, results)
+
+	_, _, err = c.searchAPIs("x", "unknown", 0)
+	assert.ErrorContains(t, err, "product 'unknown' not found")
+}
+
+func TestCatalog_describeAPI(t *testing.T) {
+	c := mockCatalog(t)
+
+	desc, err := c.describeAPI("ecs", "describeinstances")
+	require.NoError(t, err)
+	assert.Equal(t, "DescribeInstances", desc.API)
+	assert.Equal(t, "2014-05-26", desc.Version)
+	assert.Equal(t, "查询实例", desc.Title)
+	assert.Equal(t, "POST|GET", desc.Method)
+	assert.Equal(t, []parameterDescription{
+		{Name: "RegionId", Type: "string", Position: "Query", Required: true, Description: "The region ID."},
+		{Name: "PageSize", Type: "integer", Position: "Query"},
+	}, desc.Parameters)
+
+	// newmeta 中没有详情时回退到 meta
+	desc, err = c.describeAPI("cs", "DescribeClustersV1")
+	require.NoError(t, err)
+	assert.Equal(t, "/api/v1/clusters", desc.Path)
+	assert.Equal(t, []parameterDescription{{Name: "name", Type: "String", Position: "Query", Description: "The cluster name."}}, desc.Parameters)
+
+	_, err = c.describeAPI("ecs", "DescribeRegions")
+	assert.ErrorContains(t, err, "no metadata")
+	_, err = c.describeAPI("ecs", "Unknown")
+	assert.ErrorContains(t, err, "use search_apis")
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/config"
+	"github.com/aliyun/aliyun-cli/v3/i18n"
+	"github.com/aliyun/aliyun-cli/v3/meta"
+)
+
+// loadRepository 加载内置的 OpenAPI 元数据，测试中可替换
+var loadRepository = meta.LoadRepository
+
+func NewMCPServerCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "mcp-server",
+		Short: i18n.T("Start a local MCP server for Alibaba Cloud OpenAPI", "启动阿里云 OpenAPI 本地 MCP 服务"),
+		Long: i18n.T(
+			"Start a local MCP server that lets MCP clients search products and APIs from the metadata built into the CLI, "+
+				"describe API parameters, and call APIs with the active profile. Calls go through the same path as "+
+				"'aliyun <product> <api>', so the safety policy and audit log apply; operations that need confirmation "+
+				"must be pre-approved by the user in a terminal with 'aliyun configure safety-policy approve'.",
+			"启动本地 MCP 服务，MCP 客户端可以基于 CLI 内置的元数据搜索产品和 API、查看 API 参数，并使用当前 profile 调用 API。"+
+				"调用与 'aliyun <product> <api>' 走同一流程，安全策略和审计日志同样生效；需要确认的操作须由用户在终端中"+
+				"通过 'aliyun configure safety-policy approve' 预先审批。",
+		),
+		Usage:  "aliyun mcp-server [--transport stdio|http] [--host HOST] [--port PORT] [--profile PROFILE]",
+		Sample: "aliyun mcp-server --profile default",
+		Run: func(ctx *cli.Context, args []string) error {
+			return runMCPServer(ctx)
+		},
+	}
+
+	cmd.Flags().Add(&cli.Flag{
+		Name:         "transport",
+		DefaultValue: TransportStdio,
+		Short: i18n.T(
+			"Transport for MCP clients: stdio (JSON-RPC over stdin/stdout, for clients that launch the server as a subprocess) or http (listen on --host/--port at /mcp)",
+			"面向 MCP 客户端的传输方式：stdio（通过标准输入输出传递 JSON-RPC，适用于以子进程方式启动的客户端）或 http（在 --host/--port 的 /mcp 上监听）",
+		),
+	})
+
+	cmd.Flags().Add(&cli.Flag{
+		Name:         "host",
+		DefaultValue: "127.0.0.1",
+		Short: i18n.T(
+			"Listen host in http mode, must be a loopback address such as 127.0.0.1 or localhost",
+			"http 模式下的监听地址，必须是 127.0.0.1、localhost 等本机回环地址",
+		),
+	})
+
+	cmd.Flags().Add(&cli.Flag{
+		Name:         "port",
+		DefaultValue: "8089",
+		Short: i18n.T(
+			"Listen port in http mode",
+			"http 模式下的监听端口",
+		),
+	})
+
+	return cmd
+}
+
+// globalArgs 返回启动命令上指定的 profile、凭证等全局参数，透传给每次 API 调用；
+// region 由 invoke_api 的 region 选项或 profile 决定，不在此透传
+func globalArgs(ctx *cli.Context) []string {
+	fs := cli.NewFlagSet()
+	config.AddFlags(fs)
+	var args []string
+	for _, f := range fs.Flags() {
+		if f.Name == config.RegionFlagName || f.Name == config.RegionIdFlagName {
+			continue
+		}
+		assigned := ctx.Flags().Get(f.Name)
+		if !assigned.IsAssigned() {
+			continue
+		}
+		if value, _ := assigned.GetValue(); value != "" {
+			args = append(args, flagArg(f.Name, value)...)
+		} else {
+			args = append(args, "--"+f.Name)
+		}
+	}
+	return args
+}
+
+func newServer(ctx *cli.Context, host string) (*Server, error) {
+	profile, err := config.LoadProfileWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load profile failed: %w", err)
+	}
+	server := &Server{
+		catalog:    newCatalog(loadRepository(), i18n.GetLanguage()),
+		profile:    profile,
+		configDir:  config.GetConfigDir(ctx),
+		globalArgs: globalArgs(ctx),
+		host:       host,
+		endpoint:   config.EndpointFlag(ctx.Flags()).GetStringOrDefault(""),
+	}
+	if region, ok := config.RegionFlag(ctx.Flags()).GetValue(); ok && region != "" {
+		server.defaultRegion = region
+	}
+	return server, nil
+}
+
+func runMCPServer(ctx *cli.Context) error {
+	transport := ctx.Flags().Get("transport").GetStringOrDefault(TransportStdio)
+	host := ctx.Flags().Get("host").GetStringOrDefault("127.0.0.1")
+	portStr := ctx.Flags().Get("port").GetStringOrDefault("8089")
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return fmt.Errorf("invalid port: %s", portStr)
+	}
+	if transport != TransportStdio && transport != TransportHTTP {
+		return fmt.Errorf("invalid transport: %s, must be stdio or http", transport)
+	}
+	// HTTP 端点没有入站认证，任何能访问它的人都能以当前 profile 调用 API，因此只监听本机
+	if transport == TransportHTTP && !isLoopbackHost(host) {
+		return fmt.Errorf("invalid host: %s, the http transport has no authentication and only listens on a loopback address", host)
+	}
+
+	server, err := newServer(ctx, host)
+	if err != nil {
+		return err
+	}
+	if transport == TransportStdio {
+		// stdout 只输出 JSON-RPC 消息，提示信息写到 stderr
+		cli.Printf(ctx.Stderr(), "Aliyun MCP server started on stdio, profile: %s\n", server.profile.Name)
+		return server.ServeStdio(os.Stdin, ctx.Stdout())
+	}
+	return startHTTPServer(ctx, server, host, port)
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func startHTTPServer(ctx *cli.Context, server *Server, host string, port int) error {
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", server)
+	httpServer := &http.Server{
+		Addr:              net.JoinHostPort(host, strconv.Itoa(port)),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	serverErrChan := make(chan error, 1)
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErrChan <- err
+		}
+	}()
+	cli.Printf(ctx.Stdout(), "\nAliyun MCP Server Started\nEndpoint: http://%s/mcp\nProfile: %s\n", httpServer.Addr, server.profile.Name)
+
+	select {
+	case sig := <-sigChan:
+		cli.Printf(ctx.Stdout(), "\nReceived signal: %v, shutting down gracefully...\n", sig)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			cli.Printf(ctx.Stderr(), "Warning: %v\n", err)
+		}
+		cli.Println(ctx.Stdout(), "MCP Server stopped successfully")
+		return nil
+	case err := <-serverErrChan:
+		return err
+	}
+}
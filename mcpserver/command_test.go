@@ -0,0 +1,83 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpserver
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMCPServerCommand(t *testing.T) {
+	cmd := NewMCPServerCommand()
+	assert.Equal(t, "mcp-server", cmd.Name)
+	assert.NotEmpty(t, cmd.Short)
+	assert.NotEmpty(t, cmd.Long)
+	assert.NotNil(t, cmd.Run)
+
+	flags := cmd.Flags()
+	assert.Equal(t, TransportStdio, flags.Get("transport").DefaultValue)
+	assert.Equal(t, "127.0.0.1", flags.Get("host").DefaultValue)
+	assert.Equal(t, "8089", flags.Get("port").DefaultValue)
+}
+
+func TestGlobalArgs(t *testing.T) {
+	ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+	config.AddFlags(ctx.Flags())
+	assert.Empty(t, globalArgs(ctx))
+
+	config.ProfileFlag(ctx.Flags()).SetAssigned(true)
+	config.ProfileFlag(ctx.Flags()).SetValue("prod")
+	config.RegionFlag(ctx.Flags()).SetAssigned(true)
+	config.RegionFlag(ctx.Flags()).SetValue("cn-beijing")
+	skip := ctx.Flags().Get(config.SkipSecureVerifyName)
+	require.NotNil(t, skip)
+	skip.SetAssigned(true)
+	assert.Equal(t, []string{"--profile=prod", "--" + config.SkipSecureVerifyName}, globalArgs(ctx))
+}
+
+func TestRunMCPServer_InvalidFlags(t *testing.T) {
+	cmd := NewMCPServerCommand()
+	ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+	ctx.EnterCommand(cmd)
+
+	ctx.Flags().Get("transport").SetAssigned(true)
+	ctx.Flags().Get("transport").SetValue("sse")
+	assert.EqualError(t, runMCPServer(ctx), "invalid transport: sse, must be stdio or http")
+
+	ctx.Flags().Get("port").SetAssigned(true)
+	ctx.Flags().Get("port").SetValue("abc")
+	assert.EqualError(t, runMCPServer(ctx), "invalid port: abc")
+
+	// http 传输没有认证，拒绝监听非本机地址
+	ctx.Flags().Get("port").SetValue("8089")
+	ctx.Flags().Get("transport").SetValue(TransportHTTP)
+	ctx.Flags().Get("host").SetAssigned(true)
+	ctx.Flags().Get("host").SetValue("0.0.0.0")
+	assert.ErrorContains(t, runMCPServer(ctx), "invalid host: 0.0.0.0")
+}
+
+func TestIsLoopbackHost(t *testing.T) {
+	assert.True(t, isLoopbackHost("localhost"))
+	assert.True(t, isLoopbackHost("127.0.0.1"))
+	assert.True(t, isLoopbackHost("::1"))
+	assert.False(t, isLoopbackHost("0.0.0.0"))
+	assert.False(t, isLoopbackHost("192.168.1.10"))
+	assert.False(t, isLoopbackHost("example.com"))
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/config"
+	"github.com/aliyun/aliyun-cli/v3/i18n"
+	"github.com/aliyun/aliyun-cli/v3/meta"
+	"github.com/aliyun/aliyun-cli/v3/openapi"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/safety"
+)
+
+// invoke_api 把工具参数转换为 aliyun <product> <api> --Param=value ... 命令行，
+// 在进程内交给 Commando 执行，与终端中直接调用使用同一套 profile、端点解析、审计日志和安全策略。
+// 安全策略的确认规则在这里预先检查：MCP 客户端无法回答终端提示，未确认的调用直接返回说明。
+// 确认只能来自用户在终端中写入的签名审批，智能体自己能设置的工具参数不能代替确认。
+
+var parameterNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+type invokeArguments struct {
+	Product    string         `json:"product"`
+	API        string         `json:"api"`
+	Parameters map[string]any `json:"parameters,omitempty"`
+	Region     string         `json:"region,omitempty"`
+	Body       any            `json:"body,omitempty"`
+	DryRun     bool           `json:"dry_run,omitempty"`
+}
+
+// runCommand 按 main 中的方式构造根命令，在进程内执行 aliyun <args>，错误直接返回而不是退出进程
+var runCommand = func(profile config.Profile, args []string, stdout, stderr io.Writer) error {
+	root := &cli.Command{Name: "aliyun", EnableUnknownFlag: true}
+	config.AddFlags(root.Flags())
+	openapi.AddFlags(root.Flags())
+	openapi.NewCommando(stdout, profile).InitWithCommand(root)
+
+	ctx := cli.NewCommandContext(stdout, stderr)
+	ctx.EnterCommand(root)
+
+	parser := cli.NewParser(args, ctx)
+	parser.SetAllowUnknown(true)
+	positional, err := parser.ReadAll()
+	if err != nil {
+		return err
+	}
+	if err := ctx.CheckFlags(); err != nil {
+		return err
+	}
+	return root.Run(ctx, positional)
+}
+
+// parameterValue 把 JSON 参数值转换为命令行取值：数组和对象按 JSON 传递
+func parameterValue(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case []any, map[string]any:
+		data, _ := json.Marshal(t)
+		return string(data)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// flagArg 使用 --name=value 形式，避免以 - 开头的取值被解析成参数名
+func flagArg(name, value string) []string {
+	if value == "" {
+		return []string{"--" + name, ""}
+	}
+	return []string{"--" + name + "=" + value}
+}
+
+// buildInvokeArgs 校验工具参数并生成命令行，同时返回安全策略检查所用的命令信息
+func (s *Server) buildInvokeArgs(in invokeArguments) ([]string, safety.CommandInfo, error) {
+	var cmd safety.CommandInfo
+	if in.Product == "" || in.API == "" {
+		return nil, cmd, fmt.Errorf("both product and api are required")
+	}
+	product, err := s.catalog.product(in.Product)
+	if err != nil {
+		return nil, cmd, err
+	}
+	apiName, err := s.catalog.apiName(product, in.API)
+	if err != nil {
+		return nil, cmd, err
+	}
+	api, _ := meta.HookGetApi(s.catalog.repo.GetApi)(product.Code, product.Version, apiName)
+
+	cliFlags := cli.NewFlagSet()
+	config.AddFlags(cliFlags)
+	openapi.AddFlags(cliFlags)
+	names := make([]string, 0, len(in.Parameters))
+	for name := range in.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := []string{strings.ToLower(product.Code), apiName}
+	params := make(map[string][]string, len(names))
+	for _, name := range names {
+		if !parameterNamePattern.MatchString(name) {
+			return nil, cmd, fmt.Errorf("invalid parameter name '%s'", name)
+		}
+		// 与 CLI 自身参数（如 profile、endpoint）同名的只能是该 API 声明的参数，避免通过参数改变调用方式
+		if cliFlags.Get(name) != nil && api.FindParameter(name) == nil {
+			return nil, cmd, fmt.Errorf("parameter '%s' conflicts with a CLI flag, use the region option to select the region", name)
+		}
+		value := parameterValue(in.Parameters[name])
+		args = append(args, flagArg(name, value)...)
+		params[name] = []string{value}
+	}
+	region := in.Region
+	if region == "" && params[config.RegionIdFlagName] == nil {
+		region = s.defaultRegion
+	}
+	if region != "" {
+		args = append(args, flagArg(config.RegionFlagName, region)...)
+	}
+	if in.Body != nil {
+		args = append(args, flagArg("body", parameterValue(in.Body))...)
+	}
+	if in.DryRun {
+		args = append(args, "--"+openapi.CliDryRunJsonFlagName)
+	}
+
+	if region == "" {
+		if values := params[config.RegionIdFlagName]; len(values) > 0 {
+			region = values[0]
+		} else {
+			region = s.profile.RegionId
+		}
+	}
+	cmd = safety.CommandInfo{
+		Product:     product.Code,
+		ApiOrMethod: apiName,
+		Params:      params,
+		Region:      region,
+		Profile:     s.profile.Name,
+		Endpoint:    s.endpoint,
+	}
+	return args, cmd, nil
+}
+
+// checkSafetyPolicy 返回拒绝调用的原因；确认规则需要有效审批，dry_run 不发送请求因此无需确认
+func (s *Server) checkSafetyPolicy(cmd safety.CommandInfo, in invokeArguments) error {
+	policy, err := safety.LoadEffectivePolicy(s.configDir)
+	if err != nil {
+		// 与 Commando 一致，系统或锁定策略无法加载时拒绝调用
+		return err
+	}
+	result := policy.Check(cmd)
+	rule := ""
+	if result.Rule != nil {
+		rule = result.Rule.Pattern
+		if result.Condition != "" {
+			rule += " when " + result.Condition
+		}
+	}
+	switch result.Action {
+	case safety.ActionDeny:
+		return fmt.Errorf(i18n.T(
+			"operation blocked by safety policy: %s (rule: %s)",
+			"操作被安全策略拒绝: %s (规则: %s)",
+		).GetMessage(), cmd.Pattern(), rule)
+	case safety.ActionConfirm:
+		if in.DryRun || policy.ApprovalFor(cmd) != nil {
+			return nil
+		}
+		return fmt.Errorf(i18n.T(
+			"Safety policy requires confirmation for: %s (rule: %s)\n"+
+				"If you are an agent, ask the user whether this operation is allowed; they can approve it in a terminal with "+
+				"'aliyun configure safety-policy approve --pattern %s --ttl <duration>', after which invoke_api can be called again.",
+			"安全策略要求确认以下操作：%s (规则: %s)\n"+
+				"若调用方为智能体，请先向用户说明并征得同意；用户可在终端中执行 "+
+				"'aliyun configure safety-policy approve --pattern %s --ttl <时长>' 进行审批，之后可重新调用 invoke_api。",
+		).GetMessage(), cmd.Pattern(), rule, cmd.Pattern())
+	}
+	return nil
+}
+
+// invokeAPI 执行一次 API 调用，返回命令输出；isError 表示调用失败或被拒绝
+func (s *Server) invokeAPI(in invokeArguments) (text string, isError bool) {
+	args, cmd, err := s.buildInvokeArgs(in)
+	if err != nil {
+		return err.Error(), true
+	}
+	if err := s.checkSafetyPolicy(cmd, in); err != nil {
+		return err.Error(), true
+	}
+	// 确认规则由 Commando 按同一审批再次检查；dry_run 不发送请求，--yes 避免它在终端上提示
+	if in.DryRun {
+		args = append(args, "--"+openapi.YesFlagName)
+	}
+	args = append(args, s.globalArgs...)
+
+	s.invokeMu.Lock()
+	defer s.invokeMu.Unlock()
+	var stdout, stderr bytes.Buffer
+	err = runCommand(s.profile, args, &stdout, &stderr)
+	if err != nil {
+		message := err.Error()
+		if e, ok := err.(cli.ErrorWithTip); ok {
+			message += "\n" + e.GetTip(i18n.GetLanguage())
+		}
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			message += "\n" + detail
+		}
+		return message, true
+	}
+	return strings.TrimSpace(stdout.String()), false
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpserver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aliyun/aliyun-cli/v3/config"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/safety"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockRunCommand 替换 runCommand，记录每次调用的参数
+func mockRunCommand(t *testing.T, output string, err error) *[][]string {
+	origin := runCommand
+	t.Cleanup(func() { runCommand = origin })
+	var calls [][]string
+	runCommand = func(profile config.Profile, args []string, stdout, stderr io.Writer) error {
+		calls = append(calls, args)
+		fmt.Fprint(stdout, output)
+		if err != nil {
+			fmt.Fprint(stderr, "stderr detail\n")
+		}
+		return err
+	}
+	return &calls
+}
+
+func TestParameterValue(t *testing.T) {
+	assert.Equal(t, "", parameterValue(nil))
+	assert.Equal(t, "-1", parameterValue("-1"))
+	assert.Equal(t, "10", parameterValue(float64(10)))
+	assert.Equal(t, "1.5", parameterValue(1.5))
+	assert.Equal(t, "true", parameterValue(true))
+	assert.Equal(t, `["i-1","i-2"]`, parameterValue([]any{"i-1", "i-2"}))
+	assert.Equal(t, `{"k":"v"}`, parameterValue(map[string]any{"k": "v"}))
+
+	assert.Equal(t, []string{"--Name=-1"}, flagArg("Name", "-1"))
+	assert.Equal(t, []string{"--Name", ""}, flagArg("Name", ""))
+}
+
+func TestServer_buildInvokeArgs(t *testing.T) {
+	s := newTestServer(t)
+
+	args, cmd, err := s.buildInvokeArgs(invokeArguments{
+		Product:    "ECS",
+		API:        "describeinstances",
+		Parameters: map[string]any{"RegionId": "cn-beijing", "PageSize": float64(10), "InstanceIds": []any{"i-1"}, "Description": ""},
+		DryRun:     true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ecs", "DescribeInstances", "--Description", "", `--InstanceIds=["i-1"]`, "--PageSize=10", "--RegionId=cn-beijing", "--cli-dry-run-json"}, args)
+	assert.Equal(t, "ecs:DescribeInstances", cmd.Pattern())
+	assert.Equal(t, "cn-beijing", cmd.Region)
+	assert.Equal(t, "default", cmd.Profile)
+	assert.Equal(t, []string{"10"}, cmd.Params["PageSize"])
+
+	// 未指定地域时使用启动参数中的 --region，安全策略按 profile 的地域检查
+	s.defaultRegion = "cn-shanghai"
+	args, cmd, err = s.buildInvokeArgs(invokeArguments{Product: "cs", API: "DescribeClustersV1", Body: map[string]any{"name": "c"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cs", "DescribeClustersV1", "--region=cn-shanghai", `--body={"name":"c"}`}, args)
+	assert.Equal(t, "cn-shanghai", cmd.Region)
+
+	args, _, err = s.buildInvokeArgs(invokeArguments{Product: "ecs", API: "DescribeRegions", Region: "cn-hongkong"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ecs", "DescribeRegions", "--region=cn-hongkong"}, args)
+
+	_, _, err = s.buildInvokeArgs(invokeArguments{Product: "ecs", API: "DescribeRegions", Parameters: map[string]any{"profile": "admin"}})
+	assert.ErrorContains(t, err, "conflicts with a CLI flag")
+	_, _, err = s.buildInvokeArgs(invokeArguments{Product: "ecs", API: "DescribeRegions", Parameters: map[string]any{"--endpoint": "x"}})
+	assert.ErrorContains(t, err, "invalid parameter name")
+	_, _, err = s.buildInvokeArgs(invokeArguments{Product: "ecs"})
+	assert.ErrorContains(t, err, "required")
+	_, _, err = s.buildInvokeArgs(invokeArguments{Product: "ecs", API: "RunCommand"})
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestServer_invokeAPI(t *testing.T) {
+	s := newTestServer(t)
+	s.globalArgs = []string{"--profile=default"}
+	calls := mockRunCommand(t, "{\"Instances\":{}}\n", nil)
+
+	text, isError := s.invokeAPI(invokeArguments{Product: "ecs", API: "DescribeInstances"})
+	assert.False(t, isError)
+	assert.Equal(t, `{"Instances":{}}`, text)
+	require.Len(t, *calls, 1)
+	assert.Equal(t, []string{"ecs", "DescribeInstances", "--profile=default"}, (*calls)[0])
+
+	mockRunCommand(t, "", errors.New("InvalidParameter"))
+	text, isError = s.invokeAPI(invokeArguments{Product: "ecs", API: "DescribeInstances"})
+	assert.True(t, isError)
+	assert.Equal(t, "InvalidParameter\nstderr detail", text)
+}
+
+func TestServer_invokeAPI_SafetyPolicy(t *testing.T) {
+	s := newTestServer(t)
+	require.NoError(t, safety.SavePolicy(s.configDir, &safety.Policy{
+		Enabled: true,
+		Rules: []safety.Rule{
+			{Pattern: "ecs:Delete*", Action: safety.ActionDeny},
+			{Pattern: "ecs:Create*", Action: safety.ActionConfirm},
+		},
+	}))
+	calls := mockRunCommand(t, "ok", nil)
+
+	text, isError := s.invokeAPI(invokeArguments{Product: "ecs", API: "DeleteInstance"})
+	assert.True(t, isError)
+	assert.Contains(t, text, "blocked by safety policy")
+	assert.Contains(t, text, "ecs:Delete*")
+
+	text, isError = s.invokeAPI(invokeArguments{Product: "ecs", API: "CreateSnapshot"})
+	assert.True(t, isError)
+	assert.Contains(t, text, "requires confirmation")
+	assert.Contains(t, text, "--pattern ecs:CreateSnapshot")
+	assert.Empty(t, *calls)
+
+	_, isError = s.invokeAPI(invokeArguments{Product: "ecs", API: "CreateSnapshot", DryRun: true})
+	assert.False(t, isError)
+	require.Len(t, *calls, 1)
+	assert.Equal(t, []string{"ecs", "CreateSnapshot", "--cli-dry-run-json", "--yes"}, (*calls)[0])
+
+	// 智能体自行设置的 yes 参数不能代替确认
+	text, isError = toolText(t, decodeResponse(t, s.HandleMessage([]byte(
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"invoke_api","arguments":{"product":"ecs","api":"CreateSnapshot","yes":true}}}`))))
+	assert.True(t, isError)
+	assert.Contains(t, text, "requires confirmation")
+	assert.Len(t, *calls, 1)
+
+	// 只有用户写入的有效审批等同于确认，且不再附加 --yes
+	_, err := safety.AddApproval(s.configDir, "ecs:CreateSnapshot", time.Hour, "test")
+	require.NoError(t, err)
+	_, isError = s.invokeAPI(invokeArguments{Product: "ecs", API: "CreateSnapshot"})
+	assert.False(t, isError)
+	require.Len(t, *calls, 2)
+	assert.Equal(t, []string{"ecs", "CreateSnapshot"}, (*calls)[1])
+}
+
+func TestServer_checkSafetyPolicy_Endpoint(t *testing.T) {
+	s := newTestServer(t)
+	s.endpoint = "ecs-vpc.cn-hangzhou.aliyuncs.com"
+	require.NoError(t, safety.SavePolicy(s.configDir, &safety.Policy{
+		Enabled: true,
+		Rules: []safety.Rule{
+			{Pattern: "ecs:*", Action: safety.ActionDeny, When: &safety.Condition{Endpoints: []string{"*-vpc.*"}}},
+		},
+	}))
+	calls := mockRunCommand(t, "ok", nil)
+
+	text, isError := s.invokeAPI(invokeArguments{Product: "ecs", API: "DescribeInstances"})
+	assert.True(t, isError)
+	assert.Contains(t, text, "blocked by safety policy")
+	assert.Empty(t, *calls)
+}
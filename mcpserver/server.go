@@ -0,0 +1,404 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpserver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/config"
+)
+
+// 本地 MCP Server：通过 stdio 或 HTTP（Streamable HTTP 的 POST 部分）提供 JSON-RPC 接口，
+// 工具的实现见 catalog.go 与 invoke.go。
+
+const (
+	TransportStdio = "stdio"
+	TransportHTTP  = "http"
+
+	latestProtocolVersion = "2025-06-18"
+	maxRequestBodySize    = 4 << 20
+)
+
+var supportedProtocolVersions = []string{latestProtocolVersion, "2025-03-26", "2024-11-05"}
+
+const (
+	jsonRPCParseError     = -32700
+	jsonRPCInvalidRequest = -32600
+	jsonRPCMethodNotFound = -32601
+	jsonRPCInvalidParams  = -32602
+)
+
+type jsonRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type jsonRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *jsonRPCError   `json:"error,omitempty"`
+}
+
+type toolDefinition struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+type textContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type toolResult struct {
+	Content []textContent `json:"content"`
+	IsError bool          `json:"isError"`
+}
+
+type Server struct {
+	catalog    *catalog
+	profile    config.Profile
+	configDir  string   // 安全策略所在的配置目录
+	globalArgs []string // 透传给每次调用的全局参数，如 --profile、--config-path
+	host       string   // HTTP 监听地址，来自该地址的 Origin 也被允许
+	endpoint   string   // 启动命令上的 --endpoint，参与安全策略的 endpoint 条件
+	// defaultRegion 为启动命令上的 --region，调用未指定地域时使用
+	defaultRegion string
+
+	invokeMu sync.Mutex // Commando 依赖进程级状态，调用串行执行
+}
+
+func stringProperty(description string) map[string]any {
+	return map[string]any{"type": "string", "description": description}
+}
+
+func (s *Server) tools() []toolDefinition {
+	return []toolDefinition{
+		{
+			Name:        "list_products",
+			Description: "List Alibaba Cloud products that can be called through this server, with their product code, API version and style (RPC or ROA). Use the query to filter by product code or name.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query": stringProperty("Keywords matched against product code and name, e.g. 'ecs' or 'database'"),
+				},
+			},
+		},
+		{
+			Name:        "search_apis",
+			Description: "Search APIs by keywords in the API name, title and summary. Results whose API name matches come first.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query":   stringProperty("Keywords, e.g. 'describe instances'"),
+					"product": stringProperty("Limit the search to one product code, e.g. 'ecs'"),
+					"limit": map[string]any{
+						"type":        "integer",
+						"description": fmt.Sprintf("Maximum number of results (default %d, maximum %d)", defaultSearchLimit, maxSearchLimit),
+					},
+				},
+			},
+		},
+		{
+			Name:        "describe_api",
+			Description: "Describe an API: HTTP method, path and its parameters with type, position, whether required and description.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"product": stringProperty("Product code, e.g. 'ecs'"),
+					"api":     stringProperty("API name, e.g. 'DescribeInstances'"),
+				},
+				"required": []string{"product", "api"},
+			},
+		},
+		{
+			Name: "invoke_api",
+			Description: "Call an API with the active aliyun CLI profile, exactly as 'aliyun <product> <api> --Param value' would. " +
+				"Operations matched by the local safety policy are denied or need the user's approval from a terminal. " +
+				"Use dry_run to validate the call and see the resolved endpoint without sending it.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"product": stringProperty("Product code, e.g. 'ecs'"),
+					"api":     stringProperty("API name, e.g. 'DescribeInstances'"),
+					"parameters": map[string]any{
+						"type":                 "object",
+						"description":          "API parameters by name, e.g. {\"PageSize\": 10}. Repeated list parameters use the CLI form such as \"Tag.1.Key\"; array and object values are passed as JSON",
+						"additionalProperties": true,
+					},
+					"region": stringProperty("Region ID, overrides the profile region"),
+					"body":   map[string]any{"description": "Request body for ROA APIs, as a JSON object or string"},
+					"dry_run": map[string]any{
+						"type":        "boolean",
+						"description": "Validate the call and return the product, API, region and endpoint that would be used, without calling the API",
+					},
+				},
+				"required": []string{"product", "api"},
+			},
+		},
+	}
+}
+
+func textResult(text string, isError bool) toolResult {
+	return toolResult{Content: []textContent{{Type: "text", Text: text}}, IsError: isError}
+}
+
+func jsonResult(v any) toolResult {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return textResult(err.Error(), true)
+	}
+	return textResult(string(data), false)
+}
+
+// callTool 执行工具调用；参数错误返回 JSON-RPC 错误，执行失败返回 isError 结果
+func (s *Server) callTool(params json.RawMessage) (any, *jsonRPCError) {
+	var call struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &call); err != nil {
+		return nil, &jsonRPCError{Code: jsonRPCInvalidParams, Message: fmt.Sprintf("Invalid params: %v", err)}
+	}
+	if len(call.Arguments) == 0 || string(call.Arguments) == "null" {
+		call.Arguments = json.RawMessage("{}")
+	}
+	invalid := func(err error) *jsonRPCError {
+		return &jsonRPCError{Code: jsonRPCInvalidParams, Message: fmt.Sprintf("Invalid arguments for tool %s: %v", call.Name, err)}
+	}
+
+	switch call.Name {
+	case "list_products":
+		var args struct {
+			Query string `json:"query"`
+		}
+		if err := json.Unmarshal(call.Arguments, &args); err != nil {
+			return nil, invalid(err)
+		}
+		products := s.catalog.listProducts(args.Query)
+		if products == nil {
+			products = []productSummary{}
+		}
+		return jsonResult(products), nil
+	case "search_apis":
+		var args struct {
+			Query   string `json:"query"`
+			Product string `json:"product"`
+			Limit   int    `json:"limit"`
+		}
+		if err := json.Unmarshal(call.Arguments, &args); err != nil {
+			return nil, invalid(err)
+		}
+		results, total, err := s.catalog.searchAPIs(args.Query, args.Product, args.Limit)
+		if err != nil {
+			return textResult(err.Error(), true), nil
+		}
+		if results == nil {
+			results = []apiSummary{}
+		}
+		return jsonResult(map[string]any{"total": total, "apis": results}), nil
+	case "describe_api":
+		var args struct {
+			Product string `json:"product"`
+			API     string `json:"api"`
+		}
+		if err := json.Unmarshal(call.Arguments, &args); err != nil {
+			return nil, invalid(err)
+		}
+		desc, err := s.catalog.describeAPI(args.Product, args.API)
+		if err != nil {
+			return textResult(err.Error(), true), nil
+		}
+		return jsonResult(desc), nil
+	case "invoke_api":
+		var args invokeArguments
+		if err := json.Unmarshal(call.Arguments, &args); err != nil {
+			return nil, invalid(err)
+		}
+		return textResult(s.invokeAPI(args)), nil
+	}
+	return nil, &jsonRPCError{Code: jsonRPCInvalidParams, Message: fmt.Sprintf("Unknown tool: %s", call.Name)}
+}
+
+func negotiateProtocolVersion(params json.RawMessage) string {
+	var init struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if json.Unmarshal(params, &init) == nil {
+		for _, v := range supportedProtocolVersions {
+			if v == init.ProtocolVersion {
+				return v
+			}
+		}
+	}
+	return latestProtocolVersion
+}
+
+// handleRequest 处理单条消息，通知（没有 ID）返回 nil
+func (s *Server) handleRequest(req jsonRPCRequest) *jsonRPCResponse {
+	if len(req.ID) == 0 {
+		return nil
+	}
+	resp := &jsonRPCResponse{JSONRPC: "2.0", ID: req.ID}
+	switch req.Method {
+	case "initialize":
+		resp.Result = map[string]any{
+			"protocolVersion": negotiateProtocolVersion(req.Params),
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "aliyun-cli", "version": cli.Version},
+		}
+	case "ping":
+		resp.Result = map[string]any{}
+	case "tools/list":
+		resp.Result = map[string]any{"tools": s.tools()}
+	case "tools/call":
+		resp.Result, resp.Error = s.callTool(req.Params)
+	default:
+		resp.Error = &jsonRPCError{Code: jsonRPCMethodNotFound, Message: fmt.Sprintf("Method not found: %s", req.Method)}
+	}
+	return resp
+}
+
+func errorResponse(code int, message string) *jsonRPCResponse {
+	return &jsonRPCResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &jsonRPCError{Code: code, Message: message}}
+}
+
+// HandleMessage 处理一条 JSON-RPC 消息或批量消息，返回需要回复的内容；全部为通知时返回 nil
+func (s *Server) HandleMessage(data []byte) []byte {
+	data = bytes.TrimSpace(data)
+	var reply any
+	if len(data) > 0 && data[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(data, &batch); err != nil {
+			reply = errorResponse(jsonRPCParseError, fmt.Sprintf("Parse error: %v", err))
+		} else if len(batch) == 0 {
+			reply = errorResponse(jsonRPCInvalidRequest, "Invalid Request: empty batch")
+		} else {
+			var responses []*jsonRPCResponse
+			for _, item := range batch {
+				if resp := s.handleRaw(item); resp != nil {
+					responses = append(responses, resp)
+				}
+			}
+			if len(responses) == 0 {
+				return nil
+			}
+			reply = responses
+		}
+	} else if resp := s.handleRaw(data); resp != nil {
+		reply = resp
+	} else {
+		return nil
+	}
+	out, _ := json.Marshal(reply)
+	return out
+}
+
+func (s *Server) handleRaw(data []byte) *jsonRPCResponse {
+	var req jsonRPCRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return errorResponse(jsonRPCParseError, fmt.Sprintf("Parse error: %v", err))
+	}
+	if req.Method == "" {
+		// 客户端发来的响应（本服务不发起请求）直接忽略
+		return nil
+	}
+	return s.handleRequest(req)
+}
+
+// ServeStdio 逐行读取 JSON-RPC 消息并按顺序处理，stdout 只输出 JSON-RPC 消息
+func (s *Server) ServeStdio(in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if reply := s.HandleMessage(line); reply != nil {
+				if _, err := out.Write(append(reply, '\n')); err != nil {
+					return err
+				}
+			}
+		}
+		if readErr != nil {
+			if readErr == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to read stdin: %w", readErr)
+		}
+	}
+}
+
+// allowedOrigin 防止网页通过浏览器（DNS rebinding 等）访问本地服务：只接受本机或监听地址的 Origin
+func (s *Server) allowedOrigin(origin string) bool {
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	return host == s.host || isLoopbackHost(host)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.allowedOrigin(r.Header.Get("Origin")) {
+		http.Error(w, "Forbidden origin", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBodySize+1))
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxRequestBodySize {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	reply := s.HandleMessage(body)
+	if reply == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !strings.Contains(r.Header.Get("Accept"), "application/json") && strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		// 只接受 SSE 的客户端按单个事件返回
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: message\ndata: %s\n\n", reply)
+		return
+	}
+	w.Write(reply)
+}
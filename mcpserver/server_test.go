@@ -0,0 +1,178 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aliyun/aliyun-cli/v3/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T) *Server {
+	return &Server{
+		catalog:   mockCatalog(t),
+		profile:   config.Profile{Name: "default", RegionId: "cn-hangzhou"},
+		configDir: t.TempDir(),
+		host:      "127.0.0.1",
+	}
+}
+
+func decodeResponse(t *testing.T, data []byte) map[string]any {
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(data, &resp), string(data))
+	return resp
+}
+
+// toolText 返回 tools/call 结果中的文本和 isError
+func toolText(t *testing.T, resp map[string]any) (string, bool) {
+	result, ok := resp["result"].(map[string]any)
+	require.True(t, ok, "%v", resp)
+	content := result["content"].([]any)
+	require.Len(t, content, 1)
+	return content[0].(map[string]any)["text"].(string), result["isError"].(bool)
+}
+
+func TestServer_HandleMessage(t *testing.T) {
+	s := newTestServer(t)
+
+	resp := decodeResponse(t, s.HandleMessage([]byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`)))
+	result := resp["result"].(map[string]any)
+	assert.Equal(t, "2025-03-26", result["protocolVersion"])
+	assert.Equal(t, "aliyun-cli", result["serverInfo"].(map[string]any)["name"])
+	assert.Contains(t, result["capabilities"], "tools")
+
+	resp = decodeResponse(t, s.HandleMessage([]byte(`{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"protocolVersion":"2099-01-01"}}`)))
+	assert.Equal(t, latestProtocolVersion, resp["result"].(map[string]any)["protocolVersion"])
+
+	assert.Nil(t, s.HandleMessage([]byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)))
+
+	resp = decodeResponse(t, s.HandleMessage([]byte(`{"jsonrpc":"2.0","id":"p","method":"ping"}`)))
+	assert.Equal(t, "p", resp["id"])
+	assert.Equal(t, map[string]any{}, resp["result"])
+
+	resp = decodeResponse(t, s.HandleMessage([]byte(`{"jsonrpc":"2.0","id":3,"method":"resources/list"}`)))
+	assert.Equal(t, float64(jsonRPCMethodNotFound), resp["error"].(map[string]any)["code"])
+
+	resp = decodeResponse(t, s.HandleMessage([]byte(`{not json`)))
+	assert.Nil(t, resp["id"])
+	assert.Equal(t, float64(jsonRPCParseError), resp["error"].(map[string]any)["code"])
+
+	var batch []map[string]any
+	require.NoError(t, json.Unmarshal(s.HandleMessage([]byte(`[
+		{"jsonrpc":"2.0","id":1,"method":"tools/list"},
+		{"jsonrpc":"2.0","method":"notifications/initialized"},
+		{"jsonrpc":"2.0","id":2,"method":"ping"}
+	]`)), &batch))
+	require.Len(t, batch, 2)
+	var names []string
+	for _, tool := range batch[0]["result"].(map[string]any)["tools"].([]any) {
+		names = append(names, tool.(map[string]any)["name"].(string))
+	}
+	assert.Equal(t, []string{"list_products", "search_apis", "describe_api", "invoke_api"}, names)
+}
+
+func TestServer_callTool(t *testing.T) {
+	s := newTestServer(t)
+
+	text, isError := toolText(t, decodeResponse(t, s.HandleMessage([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"list_products","arguments":{"query":"kubernetes"}}}`))))
+	assert.False(t, isError)
+	var products []productSummary
+	require.NoError(t, json.Unmarshal([]byte(text), &products))
+	require.Len(t, products, 1)
+	assert.Equal(t, "cs", products[0].Code)
+
+	text, isError = toolText(t, decodeResponse(t, s.HandleMessage([]byte(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"search_apis","arguments":{"query":"snapshot"}}}`))))
+	assert.False(t, isError)
+	var search struct {
+		Total int          `json:"total"`
+		APIs  []apiSummary `json:"apis"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(text), &search))
+	assert.Equal(t, 1, search.Total)
+	assert.Equal(t, "CreateSnapshot", search.APIs[0].API)
+
+	text, isError = toolText(t, decodeResponse(t, s.HandleMessage([]byte(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"describe_api","arguments":{"product":"ecs","api":"DescribeInstances"}}}`))))
+	assert.False(t, isError)
+	assert.Contains(t, text, `"name": "RegionId"`)
+
+	text, isError = toolText(t, decodeResponse(t, s.HandleMessage([]byte(`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"describe_api","arguments":{"product":"rds","api":"DescribeDBInstances"}}}`))))
+	assert.True(t, isError)
+	assert.Contains(t, text, "product 'rds' not found")
+
+	resp := decodeResponse(t, s.HandleMessage([]byte(`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"describe_api","arguments":{"product":1}}}`)))
+	assert.Equal(t, float64(jsonRPCInvalidParams), resp["error"].(map[string]any)["code"])
+
+	resp = decodeResponse(t, s.HandleMessage([]byte(`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"unknown"}}`)))
+	assert.Contains(t, resp["error"].(map[string]any)["message"], "Unknown tool")
+}
+
+func TestServer_ServeStdio(t *testing.T) {
+	s := newTestServer(t)
+	in := strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}` + "\n" +
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n\n" +
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	var out strings.Builder
+	require.NoError(t, s.ServeStdio(in, &out))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":{}}`, lines[0])
+	assert.Equal(t, float64(2), decodeResponse(t, []byte(lines[1]))["id"])
+}
+
+func TestServer_ServeHTTP(t *testing.T) {
+	s := newTestServer(t)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":{}}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	req.Header.Set("Accept", "text/event-stream")
+	s.ServeHTTP(w, req)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{}}\n\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/mcp", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	for origin, want := range map[string]int{
+		"http://localhost:3000":   http.StatusOK,
+		"http://127.0.0.1":        http.StatusOK,
+		"http://[::1]:8080":       http.StatusOK,
+		"https://evil.example":    http.StatusForbidden,
+		"http://127.0.0.1.nip.io": http.StatusForbidden,
+	} {
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		req.Header.Set("Origin", origin)
+		s.ServeHTTP(w, req)
+		assert.Equal(t, want, w.Code, origin)
+	}
+}
@@ -120,19 +120,29 @@ type RequestParameter struct {
 	Required    bool   `json:"required"`
 }
 
-func GetProductName(language, code string) (name string, err error) {
+func GetProducts(language string) (products []Product, err error) {
 	content, err := GetMetadata(language, "/products.json")
 	if err != nil {
 		return
 	}
 
-	products := new(ProductSet)
-	err = json.Unmarshal(content, &products)
+	set := new(ProductSet)
+	err = json.Unmarshal(content, &set)
+	if err != nil {
+		return
+	}
+
+	products = set.Products
+	return
+}
+
+func GetProductName(language, code string) (name string, err error) {
+	products, err := GetProducts(language)
 	if err != nil {
 		return
 	}
 
-	for _, p := range products.Products {
+	for _, p := range products {
 		if strings.EqualFold(p.Code, code) {
 			name = strings.TrimSpace(p.Name)
 			break
@@ -142,7 +152,8 @@ func GetProductName(language, code string) (name string, err error) {
 	return
 }
 
-func GetAPI(language, code, name string) (api *API, err error) {
+// GetAPIs returns the title and summary of every API of the product, keyed by API name
+func GetAPIs(language, code string) (apis map[string]API, err error) {
 	content, err := GetMetadata(language, "/"+strings.ToLower(code)+"/version.json")
 	if err != nil {
 		return
@@ -154,7 +165,17 @@ func GetAPI(language, code, name string) (api *API, err error) {
 		return
 	}
 
-	if found, ok := version.APIs[name]; ok {
+	apis = version.APIs
+	return
+}
+
+func GetAPI(language, code, name string) (api *API, err error) {
+	apis, err := GetAPIs(language, code)
+	if err != nil {
+		return
+	}
+
+	if found, ok := apis[name]; ok {
 		api = &found
 	}
 
@@ -27,6 +27,21 @@ func TestGetProductName(t *testing.T) {
 	assert.Equal(t, "云服务器 ECS", name)
 }
 
+func TestGetProducts(t *testing.T) {
+	products, err := GetProducts("en")
+	assert.Nil(t, err)
+	assert.Greater(t, len(products), 100)
+}
+
+func TestGetAPIs(t *testing.T) {
+	apis, err := GetAPIs("en", "ecs")
+	assert.Nil(t, err)
+	assert.Equal(t, "DescribeRegions", apis["DescribeRegions"].Title)
+
+	_, err = GetAPIs("en", "invalid-product")
+	assert.NotNil(t, err)
+}
+
 func TestGetAPI(t *testing.T) {
 	api, err := GetAPI("en", "ecs", "DescribeRegions")
 	assert.Nil(t, err)
@@ -0,0 +1,244 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	sdkerrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/cli/plugin"
+	"github.com/aliyun/aliyun-cli/v3/config"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/audit"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/safety"
+)
+
+// auditCall collects one audit record between the start of a command and its
+// result. A nil *auditCall means auditing is off or the command is out of
+// scope, and all of its methods are no-ops.
+type auditCall struct {
+	configDir string
+	cfg       *audit.Config
+	rec       *audit.Record
+	start     time.Time
+	stderr    io.Writer
+}
+
+func (c *Commando) startAudit(ctx *cli.Context, source, productCode, apiOrMethod, path string) *auditCall {
+	configDir := config.GetConfigDir(ctx)
+	cfg, err := audit.LoadEffective(configDir)
+	if err != nil || !cfg.ShouldRecord(audit.IsReadOnly(apiOrMethod)) {
+		return nil
+	}
+
+	rec := audit.NewRecord(source, productCode, apiOrMethod)
+	rec.Path = path
+	rec.Profile = c.profile.Name
+	rec.Identity = auditIdentity(&c.profile)
+	rec.Region = effectiveDryRunRegion(ctx, &c.profile)
+	rec.Endpoint = config.EndpointFlag(ctx.Flags()).GetStringOrDefault(c.profile.Endpoint)
+	rec.SetParameters(safety.ParamsFromFlags(ctx.UnknownFlags()), cfg.Redact)
+	return &auditCall{
+		configDir: configDir,
+		cfg:       cfg,
+		rec:       rec,
+		start:     time.Now(),
+		stderr:    ctx.Stderr(),
+	}
+}
+
+// auditSourceFor mirrors the dispatch rule in main: lowercase, non-HTTP-verb
+// sub-commands go to plugins, everything else is an OpenAPI call.
+func auditSourceFor(apiOrMethod, path string) string {
+	upper := strings.ToUpper(apiOrMethod)
+	isHttpMethod := upper == "GET" || upper == "POST" || upper == "PUT" || upper == "DELETE"
+	if path == "" && !isHttpMethod && strings.ToLower(apiOrMethod) == apiOrMethod {
+		return audit.SourcePlugin
+	}
+	return audit.SourceOpenAPI
+}
+
+func auditIdentity(p *config.Profile) *audit.Identity {
+	id := &audit.Identity{
+		Mode:      string(p.Mode),
+		RoleArn:   p.RamRoleArn,
+		AccountId: p.CloudSSOAccountId,
+	}
+	if p.AccessKeyId != "" {
+		id.AccessKeyId = config.MosaicString(p.AccessKeyId, 4)
+	}
+	return id
+}
+
+// setTarget records the region and endpoint the request was actually sent to,
+// which the invoker only knows after Init.
+func (a *auditCall) setTarget(region, endpoint string) {
+	if a == nil {
+		return
+	}
+	if region != "" {
+		a.rec.Region = region
+	}
+	if endpoint != "" {
+		a.rec.Endpoint = endpoint
+	}
+}
+
+// finish writes the record. requestID and statusCode describe a successful
+// response; for failures they are taken from err when available.
+func (a *auditCall) finish(requestID string, statusCode int, err error) {
+	if a == nil {
+		return
+	}
+	a.rec.Finish(a.start)
+	a.rec.RequestID = requestID
+	a.rec.StatusCode = statusCode
+	if err != nil {
+		code, errRequestID, errStatus := auditErrorDetails(err)
+		a.rec.ResultCode = code
+		a.rec.Error = err.Error()
+		if errRequestID != "" {
+			a.rec.RequestID = errRequestID
+		}
+		if errStatus != 0 {
+			a.rec.StatusCode = errStatus
+		}
+	} else {
+		a.rec.ResultCode = audit.ResultOK
+	}
+	a.write()
+}
+
+// reject records a command stopped by the safety policy before any call was made.
+func (a *auditCall) reject(err error) {
+	if a == nil {
+		return
+	}
+	a.rec.Finish(a.start)
+	a.rec.ResultCode = audit.ResultSafetyPolicyRejected
+	a.rec.Error = err.Error()
+	a.write()
+}
+
+// finishPlugin records a plugin process and its exit code.
+func (a *auditCall) finishPlugin(result plugin.ExecutionResult) {
+	if a == nil {
+		return
+	}
+	a.rec.DurationMS = result.Duration.Milliseconds()
+	exitCode := result.ExitCode
+	a.rec.ExitCode = &exitCode
+	if result.Err != nil {
+		a.rec.ResultCode = "ExitCode" + strconv.Itoa(exitCode)
+		a.rec.Error = result.Err.Error()
+	} else {
+		a.rec.ResultCode = audit.ResultOK
+	}
+	a.write()
+}
+
+func (a *auditCall) write() {
+	// Audit failures must not fail the command the user ran; warn instead.
+	if err := audit.Write(a.configDir, a.cfg, a.rec); err != nil && a.stderr != nil {
+		cli.Printf(a.stderr, "WARNING: failed to write audit log: %s\n", err)
+	}
+}
+
+// auditErrorDetails extracts the service error code, request id and HTTP
+// status from the SDK errors returned by both invoke paths.
+func auditErrorDetails(err error) (code string, requestID string, statusCode int) {
+	var serverErr *sdkerrors.ServerError
+	if errors.As(err, &serverErr) {
+		return serverErr.ErrorCode(), serverErr.RequestId(), serverErr.HttpStatus()
+	}
+	var clientErr *sdkerrors.ClientError
+	if errors.As(err, &clientErr) {
+		return clientErr.ErrorCode(), "", 0
+	}
+	var teaErr *tea.SDKError
+	if errors.As(err, &teaErr) {
+		code = tea.StringValue(teaErr.Code)
+		statusCode = tea.IntValue(teaErr.StatusCode)
+		if teaErr.Data != nil {
+			requestID = requestIDFromJSON(*teaErr.Data)
+		}
+		if code == "" {
+			code = "Error"
+		}
+		return code, requestID, statusCode
+	}
+	return "Error", "", 0
+}
+
+// requestIDFromJSON finds the request id in a response body or error data.
+func requestIDFromJSON(body string) string {
+	var m map[string]any
+	if err := json.Unmarshal([]byte(body), &m); err != nil {
+		return ""
+	}
+	return requestIDFromMap(m)
+}
+
+func requestIDFromMap(m map[string]any) string {
+	for _, k := range []string{"RequestId", "requestId", "request_id"} {
+		if v, ok := m[k]; ok && v != nil {
+			return fmt.Sprintf("%v", v)
+		}
+	}
+	return ""
+}
+
+func requestIDFromHeaders(headers map[string][]string) string {
+	for k, v := range headers {
+		lk := strings.ToLower(k)
+		if (lk == "x-acs-request-id" || lk == "x-log-requestid") && len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// responseMeta returns the request id and HTTP status of the last response.
+func (a *HttpContext) responseMeta() (requestID string, statusCode int) {
+	if a == nil || a.openapiResponse == nil {
+		return "", 0
+	}
+	if v, ok := a.openapiResponse["statusCode"]; ok {
+		statusCode, _ = strconv.Atoi(fmt.Sprintf("%v", v))
+	}
+	if headers, ok := a.openapiResponse["headers"].(map[string]any); ok {
+		for k, v := range headers {
+			lk := strings.ToLower(k)
+			if lk == "x-acs-request-id" || lk == "x-log-requestid" {
+				requestID = fmt.Sprintf("%v", v)
+				break
+			}
+		}
+	}
+	if requestID == "" {
+		if body, ok := a.openapiResponse["body"].(map[string]any); ok {
+			requestID = requestIDFromMap(body)
+		}
+	}
+	return requestID, statusCode
+}
@@ -0,0 +1,258 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	sdkerrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/cli/plugin"
+	"github.com/aliyun/aliyun-cli/v3/config"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/audit"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/safety"
+)
+
+func newAuditTestCtx(t *testing.T, params map[string]string) *cli.Context {
+	t.Helper()
+	ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+	cmd := &cli.Command{EnableUnknownFlag: true}
+	config.AddFlags(cmd.Flags())
+	AddFlags(cmd.Flags())
+	ctx.EnterCommand(cmd)
+	unknown := cli.NewFlagSet()
+	for k, v := range params {
+		f, err := unknown.AddByName(k)
+		assert.NoError(t, err)
+		f.SetAssigned(true)
+		f.SetValue(v)
+	}
+	ctx.SetUnknownFlags(unknown)
+	return ctx
+}
+
+func enableAuditForTest(t *testing.T, testHome string, scope string) string {
+	t.Helper()
+	for _, k := range []string{audit.EnvEnabled, audit.EnvPath, audit.EnvScope, audit.EnvRedact} {
+		prev, had := os.LookupEnv(k)
+		os.Unsetenv(k)
+		t.Cleanup(func() {
+			if had {
+				os.Setenv(k, prev)
+			}
+		})
+	}
+	dir := filepath.Join(testHome, ".aliyun")
+	enabled := true
+	assert.NoError(t, audit.Save(dir, &audit.Config{Enabled: &enabled, Scope: scope}))
+	return filepath.Join(dir, audit.DefaultLogFileName)
+}
+
+func readAuditRecords(t *testing.T, path string) []audit.Record {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var out []audit.Record
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var r audit.Record
+		assert.NoError(t, json.Unmarshal([]byte(line), &r))
+		out = append(out, r)
+	}
+	return out
+}
+
+func TestStartAudit_DisabledByDefault(t *testing.T) {
+	testHome := t.TempDir()
+	cleanup := setTestHomeDir(t, testHome)
+	defer cleanup()
+	t.Setenv(audit.EnvEnabled, "false")
+
+	c := &Commando{profile: config.Profile{Name: "default"}}
+	a := c.startAudit(newAuditTestCtx(t, nil), audit.SourceOpenAPI, "ecs", "DeleteInstance", "")
+	assert.Nil(t, a)
+	// nil auditCall is a no-op
+	a.finish("", 0, nil)
+	a.reject(errors.New("x"))
+	a.finishPlugin(plugin.ExecutionResult{})
+}
+
+func TestStartAudit_WritesRecord(t *testing.T) {
+	testHome := t.TempDir()
+	cleanup := setTestHomeDir(t, testHome)
+	defer cleanup()
+	logPath := enableAuditForTest(t, testHome, "")
+
+	c := &Commando{profile: config.Profile{
+		Name:        "prod",
+		Mode:        config.AK,
+		AccessKeyId: "LTAI1234567890abcd",
+		RegionId:    "cn-shanghai",
+	}}
+	ctx := newAuditTestCtx(t, map[string]string{"InstanceId": "i-1", "Password": "p@ss"})
+
+	// Read-only calls are out of the default scope.
+	assert.Nil(t, c.startAudit(ctx, audit.SourceOpenAPI, "ecs", "DescribeInstances", ""))
+
+	a := c.startAudit(ctx, audit.SourceOpenAPI, "ecs", "DeleteInstance", "")
+	if !assert.NotNil(t, a) {
+		return
+	}
+	a.setTarget("cn-beijing", "ecs.cn-beijing.aliyuncs.com")
+	a.finish("req-ok", 200, nil)
+
+	records := readAuditRecords(t, logPath)
+	if !assert.Len(t, records, 1) {
+		return
+	}
+	r := records[0]
+	assert.Equal(t, "prod", r.Profile)
+	assert.Equal(t, "ecs", r.Product)
+	assert.Equal(t, "DeleteInstance", r.API)
+	assert.Equal(t, "cn-beijing", r.Region)
+	assert.Equal(t, "ecs.cn-beijing.aliyuncs.com", r.Endpoint)
+	assert.Equal(t, "req-ok", r.RequestID)
+	assert.Equal(t, 200, r.StatusCode)
+	assert.Equal(t, audit.ResultOK, r.ResultCode)
+	assert.Equal(t, "i-1", r.Parameters["InstanceId"])
+	assert.Equal(t, "******", r.Parameters["Password"])
+	if assert.NotNil(t, r.Identity) {
+		assert.Equal(t, string(config.AK), r.Identity.Mode)
+		assert.Equal(t, "**************abcd", r.Identity.AccessKeyId)
+	}
+}
+
+func TestAuditCall_FinishWithServerError(t *testing.T) {
+	testHome := t.TempDir()
+	cleanup := setTestHomeDir(t, testHome)
+	defer cleanup()
+	logPath := enableAuditForTest(t, testHome, audit.ScopeAll)
+
+	c := &Commando{profile: config.Profile{Name: "default"}}
+	a := c.startAudit(newAuditTestCtx(t, nil), audit.SourceOpenAPI, "ecs", "DescribeInstances", "")
+	serverErr := sdkerrors.NewServerError(403, `{"Code":"Forbidden.RAM","Message":"denied","RequestId":"req-err"}`, "")
+	a.finish("", 0, serverErr)
+
+	records := readAuditRecords(t, logPath)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "Forbidden.RAM", records[0].ResultCode)
+		assert.Equal(t, "req-err", records[0].RequestID)
+		assert.Equal(t, 403, records[0].StatusCode)
+		assert.NotEmpty(t, records[0].Error)
+	}
+}
+
+func TestAuditCall_RejectAndPlugin(t *testing.T) {
+	testHome := t.TempDir()
+	cleanup := setTestHomeDir(t, testHome)
+	defer cleanup()
+	logPath := enableAuditForTest(t, testHome, "")
+
+	c := &Commando{profile: config.Profile{Name: "default"}}
+	c.startAudit(newAuditTestCtx(t, nil), audit.SourceOpenAPI, "ecs", "DeleteInstance", "").reject(errors.New("blocked"))
+	c.startAudit(newAuditTestCtx(t, nil), audit.SourcePlugin, "fc", "function:delete", "").finishPlugin(plugin.ExecutionResult{
+		ExitCode: 2,
+		Duration: 1500 * time.Millisecond,
+		Err:      errors.New("exit status 2"),
+	})
+
+	records := readAuditRecords(t, logPath)
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, audit.ResultSafetyPolicyRejected, records[0].ResultCode)
+		assert.Equal(t, audit.SourcePlugin, records[1].Source)
+		assert.Equal(t, "ExitCode2", records[1].ResultCode)
+		assert.Equal(t, int64(1500), records[1].DurationMS)
+		if assert.NotNil(t, records[1].ExitCode) {
+			assert.Equal(t, 2, *records[1].ExitCode)
+		}
+	}
+}
+
+func TestCheckSafetyPolicy_AuditsRejection(t *testing.T) {
+	testHome := t.TempDir()
+	cleanup := setTestHomeDir(t, testHome)
+	defer cleanup()
+	logPath := enableAuditForTest(t, testHome, "")
+	writeSafetyPolicy(t, testHome, []safety.Rule{{Pattern: "fc:*", Action: safety.ActionDeny}})
+
+	c := &Commando{profile: config.Profile{Name: "default"}}
+	err := c.checkSafetyPolicy(newAuditTestCtx(t, nil), "fc", "function:delete", "")
+	assert.Error(t, err)
+
+	records := readAuditRecords(t, logPath)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, audit.SourcePlugin, records[0].Source)
+		assert.Equal(t, audit.ResultSafetyPolicyRejected, records[0].ResultCode)
+	}
+}
+
+func TestAuditErrorDetails(t *testing.T) {
+	code, requestID, status := auditErrorDetails(sdkerrors.NewClientError("SDK.TimeoutError", "timeout", nil))
+	assert.Equal(t, "SDK.TimeoutError", code)
+	assert.Empty(t, requestID)
+	assert.Zero(t, status)
+
+	teaErr := tea.NewSDKError(map[string]interface{}{
+		"code":       "InvalidParameter",
+		"statusCode": 400,
+		"message":    "bad",
+		"data":       map[string]interface{}{"RequestId": "req-tea"},
+	})
+	code, requestID, status = auditErrorDetails(teaErr)
+	assert.Equal(t, "InvalidParameter", code)
+	assert.Equal(t, "req-tea", requestID)
+	assert.Equal(t, 400, status)
+
+	code, _, _ = auditErrorDetails(errors.New("boom"))
+	assert.Equal(t, "Error", code)
+}
+
+func TestHttpContextResponseMeta(t *testing.T) {
+	h := &HttpContext{openapiResponse: map[string]any{
+		"statusCode": float64(200),
+		"headers":    map[string]any{"X-Acs-Request-Id": "req-hdr"},
+		"body":       map[string]any{"RequestId": "req-body"},
+	}}
+	requestID, status := h.responseMeta()
+	assert.Equal(t, "req-hdr", requestID)
+	assert.Equal(t, 200, status)
+
+	h.openapiResponse["headers"] = map[string]any{}
+	requestID, _ = h.responseMeta()
+	assert.Equal(t, "req-body", requestID)
+
+	var nilCtx *HttpContext
+	requestID, status = nilCtx.responseMeta()
+	assert.Empty(t, requestID)
+	assert.Zero(t, status)
+}
+
+func TestAuditSourceFor(t *testing.T) {
+	assert.Equal(t, audit.SourcePlugin, auditSourceFor("function:create", ""))
+	assert.Equal(t, audit.SourceOpenAPI, auditSourceFor("DeleteInstance", ""))
+	assert.Equal(t, audit.SourceOpenAPI, auditSourceFor("delete", "/clusters"))
+	assert.Equal(t, audit.SourceOpenAPI, auditSourceFor("get", ""))
+}
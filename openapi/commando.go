@@ -24,6 +24,7 @@ import (
 	"github.com/aliyun/aliyun-cli/v3/i18n"
 	"github.com/aliyun/aliyun-cli/v3/meta"
 	"github.com/aliyun/aliyun-cli/v3/sysconfig/aimode"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/audit"
 	"github.com/aliyun/aliyun-cli/v3/sysconfig/headers"
 	"github.com/aliyun/aliyun-cli/v3/sysconfig/safety"
 	"github.com/aliyun/aliyun-cli/v3/sysconfig/throttlingretry"
@@ -70,6 +71,8 @@ func (c *Commando) loadPlugins() {
 		c.pluginIndexErr = err
 		return
 	}
+	// The index only feeds help and suggestions here, so it stays quiet about signature policy.
+	mgr.SilenceSignatureWarning()
 	// Remote index may fail offline; local manifest still loads for installed plugins.
 	c.pluginIndex, c.pluginIndexErr = mgr.GetIndex()
 	c.localManifest, _ = mgr.GetLocalManifest()
@@ -176,6 +179,11 @@ func (c *Commando) main(ctx *cli.Context, args []string) error {
 		return nil
 	}
 
+	// aliyun sls put-logs ...
+	if cmd := findSlsCommand(args); cmd != nil {
+		return c.runSlsCommand(ctx, cmd, args)
+	}
+
 	// Check if we should show original product help instead of plugin help, only and need to be applied in product level
 	envShowOriginalHelp := os.Getenv("ALIBABA_CLOUD_ORIGINAL_PRODUCT_HELP")
 	showOriginalProductHelp := envShowOriginalHelp == "true" || envShowOriginalHelp == "1"
@@ -343,6 +351,7 @@ func (c *Commando) main(ctx *cli.Context, args []string) error {
 				util.MergeAgentSegmentIntoPluginEnvs(envs)
 				safety.MergeSafetyPolicyPathIntoEnvs(configDir, envs)
 				throttlingretry.MergeIntoPluginEnvs(configDir, envs)
+				audit.MergeIntoPluginEnvs(configDir, envs)
 				headers.MergeIntoPluginEnvs(envs)
 				ctx.SetRuntimeEnvs(envs)
 			} else if isHelp || isVersion {
@@ -358,12 +367,21 @@ func (c *Commando) main(ctx *cli.Context, args []string) error {
 			//   aliyun fc invoke my-fn          -> fc:invoke:my-fn
 			// Users can still match coarsely with wildcards like `fc:function:*` or `fc:function*`.
 			if !isHelp && !isVersion {
+				if err := checkLogFormatFlags(ctx, nil, nil); err != nil {
+					return err
+				}
 				cmdName := strings.Join(args[1:], ":")
 				if err := c.checkSafetyPolicy(ctx, args[0], cmdName, ""); err != nil {
 					return err
 				}
 			}
 
+			if !isHelp && !isVersion {
+				if auditRun := c.startAudit(ctx, audit.SourcePlugin, args[0], strings.Join(args[1:], ":"), ""); auditRun != nil {
+					plugin.SetExecutionObserver(auditRun.finishPlugin)
+					defer plugin.SetExecutionObserver(nil)
+				}
+			}
 			ok, err := plugin.ExecutePlugin(args[0], pluginArgs, ctx)
 			if err != nil {
 				return err
@@ -492,6 +510,10 @@ func (c *Commando) processApiInvoke(ctx *cli.Context, product *meta.Product, api
 			"cost estimation supports RPC and ROA(restful) style products only")
 	}
 
+	if err := checkLogFormatFlags(ctx, product, api); err != nil {
+		return err
+	}
+
 	apiContext, err := c.createHttpContext(ctx, product, api, method, path)
 	if err != nil {
 		return err
@@ -514,7 +536,14 @@ func (c *Commando) processApiInvoke(ctx *cli.Context, product *meta.Product, api
 		return nil
 	}
 
+	auditRun := c.startAudit(ctx, audit.SourceOpenAPI, product.Code, api.Name, path)
 	err = hookHttpContextCall(apiContext.Call)()
+	if rm, ok := apiContext.(interface{ responseMeta() (string, int) }); ok && err == nil {
+		requestID, statusCode := rm.responseMeta()
+		auditRun.finish(requestID, statusCode, nil)
+	} else {
+		auditRun.finish("", 0, err)
+	}
 	if err != nil {
 		return err
 	}
@@ -530,6 +559,10 @@ func (c *Commando) processApiInvoke(ctx *cli.Context, product *meta.Product, api
 	if QuietFlag(ctx.Flags()).IsAssigned() {
 		return nil
 	}
+	if rf, ok := apiContext.(interface{ rawOutput() bool }); ok && rf.rawOutput() {
+		cli.Println(ctx.Stdout(), out)
+		return nil
+	}
 
 	if QueryFlag(ctx.Flags()).IsAssigned() {
 		out, err = ApplyQueryFilter(ctx, out)
@@ -550,6 +583,9 @@ func (c *Commando) processApiInvoke(ctx *cli.Context, product *meta.Product, api
 }
 
 func (c *Commando) processInvoke(ctx *cli.Context, productCode string, apiOrMethod string, path string) error {
+	if err := checkLogFormatFlags(ctx, nil, nil); err != nil {
+		return err
+	}
 	// create specific invoker
 	invoker, err := c.createInvoker(ctx, productCode, apiOrMethod, path)
 	if err != nil {
@@ -582,16 +618,31 @@ func (c *Commando) processInvoke(ctx *cli.Context, productCode string, apiOrMeth
 		return nil
 	}
 
+	auditRun := c.startAudit(ctx, audit.SourceOpenAPI, productCode, apiOrMethod, path)
+	if req := invoker.getRequest(); req != nil {
+		auditRun.setTarget(req.RegionId, req.Domain)
+	}
+
 	// if invoke with helper
 	out, err, ok := c.invokeWithHelper(invoker)
 
 	// cli.Printf("invoker %v %v \n", invoker, reflect.TypeOf(invoker))
 	if ok {
+		auditRun.finish(requestIDFromJSON(out), 0, err)
 		if err != nil { // call with helper failed
 			return err
 		}
 	} else {
 		resp, err := hookdo(invoker.Call)()
+		if resp != nil && err == nil {
+			requestID := requestIDFromHeaders(resp.GetHttpHeaders())
+			if requestID == "" {
+				requestID = requestIDFromJSON(resp.GetHttpContentString())
+			}
+			auditRun.finish(requestID, resp.GetHttpStatus(), nil)
+		} else {
+			auditRun.finish("", 0, err)
+		}
 		if err != nil {
 			// if unmarshal failed,
 			if !strings.Contains(strings.ToLower(err.Error()), "unmarshal") {
@@ -975,11 +1026,15 @@ func (c *Commando) createHttpContext(ctx *cli.Context, product *meta.Product, ap
 			product.GetLowerCode(),
 			product.GetLowerCode())
 	}
-	return &OpenapiContext{apiContext, method, path, api}, nil
+	return &OpenapiContext{HttpContext: apiContext, method: method, path: path, api: api}, nil
 }
 
 func (c *Commando) help(ctx *cli.Context, args []string) error {
 	// fmt.Println("commando help", args)
+	if cmd := findSlsCommand(args); cmd != nil {
+		cmd.printHelp(ctx)
+		return nil
+	}
 	c.loadPlugins()
 	cmd := ctx.Command()
 	if len(args) == 0 {
@@ -1006,6 +1061,18 @@ func (c *Commando) help(ctx *cli.Context, args []string) error {
 	}
 }
 
+var completeDelegate = plugin.CompletePluginCommand
+
+// isPluginSubCommand reports whether arg, the word after the product code, addresses a
+// plugin sub-command rather than an OpenAPI name (PascalCase) or a RESTful HTTP method.
+func isPluginSubCommand(arg string) bool {
+	upper := strings.ToUpper(arg)
+	if upper == "GET" || upper == "POST" || upper == "PUT" || upper == "DELETE" {
+		return false
+	}
+	return strings.ToLower(arg) == arg
+}
+
 func (c *Commando) complete(ctx *cli.Context, args []string) []string {
 	w := ctx.Stdout()
 
@@ -1024,6 +1091,21 @@ func (c *Commando) complete(ctx *cli.Context, args []string) []string {
 		return r
 	}
 
+	// Plugin sub-commands follow the same routing as execution: the word after the product
+	// is completed by the plugin unless it is an OpenAPI name or an HTTP method.
+	if len(args) == 1 || isPluginSubCommand(args[1]) {
+		candidates, handled, err := completeDelegate(args[0], args, ctx.Completion().Current)
+		if err == nil && handled {
+			if len(args) > 1 {
+				return candidates
+			}
+			// Built-in API names of the product are still listed below.
+			for _, s := range candidates {
+				cli.PrintfWithColor(w, "", "%s\n", s)
+			}
+		}
+	}
+
 	product, ok := c.library.GetProduct(args[0])
 	if !ok {
 		return r
@@ -1198,19 +1280,28 @@ func (c *Commando) checkSafetyPolicy(ctx *cli.Context, productCode string, apiOr
 	configDir := config.GetConfigDir(ctx)
 	policy, err := safety.LoadEffectivePolicy(configDir)
 	if err != nil {
-		// Failed to load - skip policy check (fail open)
-		return nil
+		// a broken system or locked policy must not let the call through
+		c.startAudit(ctx, auditSourceFor(apiOrMethod, path), productCode, apiOrMethod, path).reject(err)
+		return err
 	}
 	cmd := safety.CommandInfo{
 		Product:     productCode,
 		ApiOrMethod: apiOrMethod,
 		Path:        path,
+		Params:      safety.ParamsFromFlags(ctx.UnknownFlags()),
+		Region:      config.RegionFlag(ctx.Flags()).GetStringOrDefault(c.profile.RegionId),
+		Profile:     c.profile.Name,
+		Endpoint:    config.EndpointFlag(ctx.Flags()).GetStringOrDefault(""),
 	}
 	// --yes / -y or ALIBABA_CLOUD_SAFETY_SKIP_CONFIRM=1: skip confirm prompt for agent/non-interactive
 	skipConfirm := YesFlag(ctx.Flags()).IsAssigned() ||
 		os.Getenv("ALIBABA_CLOUD_SAFETY_SKIP_CONFIRM") == "1" ||
 		strings.EqualFold(os.Getenv("ALIBABA_CLOUD_SAFETY_SKIP_CONFIRM"), "true")
-	return safety.CheckAndConfirm(ctx, policy, cmd, skipConfirm)
+	if err := safety.CheckAndConfirm(ctx, policy, cmd, skipConfirm); err != nil {
+		c.startAudit(ctx, auditSourceFor(apiOrMethod, path), productCode, apiOrMethod, path).reject(err)
+		return err
+	}
+	return nil
 }
 
 func (c *Commando) setLangEnv(ctx *cli.Context) {
@@ -261,6 +261,9 @@ func (c *Commando) printProductUsage(ctx *cli.Context, productCode string) error
 	productName, _ := newmeta.GetProductName(i18n.GetLanguage(), product.Code)
 	cli.Printf(ctx.Stdout(), "\nProduct: %s (%s)\n", product.Code, productName)
 	cli.Printf(ctx.Stdout(), "Version: %s \n", product.Version)
+	if strings.EqualFold(product.Code, "sls") && len(slsCommands) > 0 {
+		printSlsCommands(ctx)
+	}
 
 	if len(product.ApiNames) > 0 {
 		cli.PrintfWithColor(ctx.Stdout(), cli.ColorOff, "\nAvailable Api List: \n")
@@ -3701,3 +3701,99 @@ func TestMain_SafetyPolicyEnforcement(t *testing.T) {
 		assert.Contains(t, err.Error(), "fc:create-function")
 	})
 }
+
+// TestCheckSafetyPolicy_Conditions verifies that checkSafetyPolicy feeds the
+// parsed parameters, region, profile and endpoint into safety.CommandInfo so
+// that rules with a `when` clause see what the user actually typed.
+func TestCheckSafetyPolicy_Conditions(t *testing.T) {
+	testHome := t.TempDir()
+	cleanup := setTestHomeDir(t, testHome)
+	defer cleanup()
+	writeSafetyPolicy(t, testHome, []safety.Rule{
+		{Pattern: "ecs:DeleteInstance", Action: safety.ActionDeny, When: &safety.Condition{
+			Params:   []safety.ParamCondition{{Name: "Force", Value: "true"}},
+			Regions:  []string{"cn-shanghai"},
+			Profiles: []string{"prod"},
+		}},
+	})
+
+	newCtx := func(region string, force string) *cli.Context {
+		ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+		cmd := &cli.Command{EnableUnknownFlag: true}
+		config.AddFlags(cmd.Flags())
+		AddFlags(cmd.Flags())
+		ctx.EnterCommand(cmd)
+		if region != "" {
+			config.RegionFlag(ctx.Flags()).SetAssigned(true)
+			config.RegionFlag(ctx.Flags()).SetValue(region)
+		}
+		unknown := cli.NewFlagSet()
+		if force != "" {
+			f, err := unknown.AddByName("Force")
+			assert.NoError(t, err)
+			f.SetAssigned(true)
+			f.SetValue(force)
+		}
+		ctx.SetUnknownFlags(unknown)
+		return ctx
+	}
+
+	c := &Commando{profile: config.Profile{Name: "prod", RegionId: "cn-shanghai"}}
+	err := c.checkSafetyPolicy(newCtx("", "true"), "ecs", "DeleteInstance", "")
+	if !assert.Error(t, err) {
+		return
+	}
+	assert.Contains(t, err.Error(), "blocked by safety policy")
+	assert.Contains(t, err.Error(), "Force=true")
+
+	// --region overrides the profile region, so the condition no longer holds.
+	assert.NoError(t, c.checkSafetyPolicy(newCtx("cn-hangzhou", "true"), "ecs", "DeleteInstance", ""))
+	assert.NoError(t, c.checkSafetyPolicy(newCtx("", "false"), "ecs", "DeleteInstance", ""))
+
+	dev := &Commando{profile: config.Profile{Name: "dev", RegionId: "cn-shanghai"}}
+	assert.NoError(t, dev.checkSafetyPolicy(newCtx("", "true"), "ecs", "DeleteInstance", ""))
+}
+
+func Test_complete_DelegatesToPlugin(t *testing.T) {
+	orig := completeDelegate
+	defer func() { completeDelegate = orig }()
+
+	var calls [][]string
+	completeDelegate = func(command string, args []string, current string) ([]string, bool, error) {
+		calls = append(calls, append([]string{current}, args...))
+		if command != "fc" {
+			return nil, false, nil
+		}
+		return []string{"list", "deploy"}, true, nil
+	}
+
+	newCtx := func(current string) (*cli.Context, *bytes.Buffer) {
+		w := new(bytes.Buffer)
+		ctx := cli.NewCommandContext(w, new(bytes.Buffer))
+		ctx.SetCompletion(&cli.Completion{Current: current})
+		return ctx, w
+	}
+	command := &Commando{library: &Library{builtinRepo: &meta.Repository{}}}
+
+	// 产品层：插件候选直接输出
+	ctx, w := newCtx("")
+	assert.Equal(t, []string{}, command.complete(ctx, []string{"fc"}))
+	assert.Equal(t, "list\ndeploy\n", w.String())
+
+	// 插件子命令：返回插件候选
+	ctx, _ = newCtx("--re")
+	assert.Equal(t, []string{"list", "deploy"}, command.complete(ctx, []string{"fc", "list"}))
+	assert.Equal(t, []string{"--re", "fc", "list"}, calls[len(calls)-1])
+
+	// OpenAPI 名称与 HTTP 方法不委托给插件
+	calls = nil
+	ctx, _ = newCtx("")
+	command.complete(ctx, []string{"fc", "ListServices"})
+	command.complete(ctx, []string{"fc", "get"})
+	assert.Empty(t, calls)
+
+	// 未安装插件的产品
+	ctx, w = newCtx("")
+	assert.Equal(t, []string{}, command.complete(ctx, []string{"ecs", "describe"}))
+	assert.Empty(t, w.String())
+}
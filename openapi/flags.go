@@ -42,6 +42,8 @@ func AddFlags(fs *cli.FlagSet) {
 	fs.Add(NewUserAgentFlag())
 	fs.Add(NewCliAIModeFlag())
 	fs.Add(NewCliNoAIModeFlag())
+	fs.Add(NewLogFormatFlag())
+	fs.Add(NewLogTimeFormatFlag())
 }
 
 const (
@@ -66,6 +68,8 @@ const (
 	UserAgentFlagName     = "user-agent"
 	CliAIModeFlagName     = "cli-ai-mode"
 	CliNoAIModeFlagName   = "no-cli-ai-mode"
+	LogFormatFlagName     = "cli-log-format"
+	LogTimeFormatFlagName = "cli-log-time-format"
 )
 
 func OutputFlag(fs *cli.FlagSet) *cli.Flag {
@@ -394,6 +398,39 @@ func NewCliNoAIModeFlag() *cli.Flag {
 	}
 }
 
+func NewLogFormatFlag() *cli.Flag {
+	return &cli.Flag{
+		Category:     "caller",
+		Name:         LogFormatFlagName,
+		AssignedMode: cli.AssignedOnce,
+		Short: i18n.T(
+			"use `--cli-log-format {ndjson|csv}` with `aliyun sls PullLogs` to print one flattened log per line, with __time__, __topic__, __source__ and __tag__:* as fields; a log content with one of these names is renamed to __content__:<name>",
+			"使用 `--cli-log-format {ndjson|csv}` 让 `aliyun sls PullLogs` 每行输出一条展开的日志，__time__、__topic__、__source__ 和 __tag__:* 作为字段；与这些字段同名的日志内容改名为 __content__:<名称>",
+		),
+		ExcludeWith: []string{QueryFlagName},
+	}
+}
+
+func LogFormatFlag(fs *cli.FlagSet) *cli.Flag {
+	return fs.Get(LogFormatFlagName)
+}
+
+func NewLogTimeFormatFlag() *cli.Flag {
+	return &cli.Flag{
+		Category:     "caller",
+		Name:         LogTimeFormatFlagName,
+		AssignedMode: cli.AssignedOnce,
+		Short: i18n.T(
+			"use `--cli-log-time-format {unix|unixms|rfc3339|utc}` with `--cli-log-format` to convert __time__ (default: unix)",
+			"与 `--cli-log-format` 一起使用 `--cli-log-time-format {unix|unixms|rfc3339|utc}` 转换 __time__ 的格式（默认: unix）",
+		),
+	}
+}
+
+func LogTimeFormatFlag(fs *cli.FlagSet) *cli.Flag {
+	return fs.Get(LogTimeFormatFlagName)
+}
+
 func CliAIModeFlag(fs *cli.FlagSet) *cli.Flag {
 	return fs.Get(CliAIModeFlagName)
 }
@@ -14,10 +14,12 @@
 package openapi
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -299,12 +301,59 @@ type OpenapiContext struct {
 	method string
 	path   string
 	api    *meta.Api
+
+	// logFormat and logTimeFormat flatten the logs of sls PullLogs, see checkLogFormatFlags
+	logFormat     string
+	logTimeFormat string
+	stdout        io.Writer
+	stderr        io.Writer
 }
 
 func (a *OpenapiContext) ProcessPullLogsHeaders(ctx *cli.Context) {
 	a.openapiRequest.Headers["Accept-Encoding"] = tea.String("lz4")
 	a.openapiRequest.Headers["accept"] = tea.String("application/x-protobuf")
 	a.openapiParams.BodyType = tea.String("byte")
+	// the values are checked by checkLogFormatFlags, case-insensitively like sls tail and export
+	format, _ := LogFormatFlag(ctx.Flags()).GetValue()
+	timeFormat, _ := LogTimeFormatFlag(ctx.Flags()).GetValue()
+	a.logFormat = strings.ToLower(format)
+	a.logTimeFormat = strings.ToLower(timeFormat)
+	a.stdout = ctx.Stdout()
+	a.stderr = ctx.Stderr()
+}
+
+// checkLogFormatFlags validates --cli-log-format and --cli-log-time-format, which only
+// apply to `aliyun sls PullLogs`; api is nil on the other paths, where the flags are rejected.
+func checkLogFormatFlags(ctx *cli.Context, product *meta.Product, api *meta.Api) error {
+	pullLogs := product != nil && api != nil && product.GetLowerCode() == "sls" && api.Name == "PullLogs"
+	format, ok := LogFormatFlag(ctx.Flags()).GetValue()
+	if !ok {
+		if !LogTimeFormatFlag(ctx.Flags()).IsAssigned() {
+			return nil
+		}
+		if !pullLogs {
+			return fmt.Errorf("--%s is only supported by `aliyun sls PullLogs`", LogTimeFormatFlagName)
+		}
+		return fmt.Errorf("--%s requires --%s", LogTimeFormatFlagName, LogFormatFlagName)
+	}
+	if !pullLogs {
+		return fmt.Errorf("--%s is only supported by `aliyun sls PullLogs`", LogFormatFlagName)
+	}
+	if lower := strings.ToLower(format); lower != slsUtils.FormatNDJSON && lower != slsUtils.FormatCSV {
+		return fmt.Errorf("invalid --%s '%s', must be %s or %s", LogFormatFlagName, format, slsUtils.FormatNDJSON, slsUtils.FormatCSV)
+	}
+	if timeFormat, ok := LogTimeFormatFlag(ctx.Flags()).GetValue(); ok {
+		if _, err := slsUtils.FormatLogTime(&slsUtils.Log{}, strings.ToLower(timeFormat)); err != nil {
+			return fmt.Errorf("invalid --%s '%s', must be one of %s", LogTimeFormatFlagName, timeFormat, strings.Join(slsUtils.TimeFormats, ", "))
+		}
+	}
+	return nil
+}
+
+// rawOutput reports whether the response is printed as is, bypassing --cli-query,
+// --output and JSON formatting.
+func (a *OpenapiContext) rawOutput() bool {
+	return a.logFormat != ""
 }
 
 func (a *OpenapiContext) ProcessHeaders(ctx *cli.Context) error {
@@ -566,6 +615,9 @@ func (a *OpenapiContext) CheckResponseForPullLogs(response map[string]any) (stri
 	if err != nil {
 		return "", err
 	}
+	if a.logFormat != "" {
+		return a.flattenPullLogs(bodyBytes, response)
+	}
 	if len(bodyBytes) == 0 {
 		return "", nil
 	}
@@ -575,12 +627,32 @@ func (a *OpenapiContext) CheckResponseForPullLogs(response map[string]any) (stri
 	}
 	// extract count and next cursor from headers
 	responseHeaders := response["headers"].(map[string]any)
-	fmt.Printf("count: %s\n", responseHeaders["x-log-count"])
-	fmt.Printf("next_cursor: %s\n", responseHeaders["x-log-cursor"])
+	if a.stdout != nil {
+		cli.Printf(a.stdout, "count: %s\n", responseHeaders["x-log-count"])
+		cli.Printf(a.stdout, "next_cursor: %s\n", responseHeaders["x-log-cursor"])
+	}
 
 	return string(result), nil
 }
 
+// flattenPullLogs renders the logs with --cli-log-format. Count and next cursor go to
+// stderr so that stdout stays valid NDJSON or CSV.
+func (a *OpenapiContext) flattenPullLogs(bodyBytes []byte, response map[string]any) (string, error) {
+	logGroups, err := slsUtils.ParseProtobufList(bodyBytes)
+	if err != nil {
+		return "", fmt.Errorf("parse proto object failed: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := slsUtils.WriteLogs(buf, logGroups, a.logFormat, a.logTimeFormat); err != nil {
+		return "", err
+	}
+	if a.stderr != nil {
+		cli.Printf(a.stderr, "count: %s\n", responseHeader(response, "x-log-count"))
+		cli.Printf(a.stderr, "next_cursor: %s\n", responseHeader(response, "x-log-cursor"))
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
 func (a *OpenapiContext) GetResponse() (string, error) {
 	if a.product.GetLowerCode() == "sls" && a.api.Name == "PullLogs" {
 		return a.CheckResponseForPullLogs(a.openapiResponse)
@@ -648,9 +648,12 @@ func TestOpenapiContext(t *testing.T) {
 		}
 		context.product = &meta.Product{Code: "sls"}
 		context.api = &meta.Api{Name: "PullLogs"}
+		stdout := new(bytes.Buffer)
+		context.stdout = stdout
 
 		result, _ := context.CheckResponseForPullLogs(context.openapiResponse)
 		assert.NotEmpty(t, result)
+		assert.Equal(t, "count: 1\nnext_cursor: test-cursor\n", stdout.String())
 	})
 
 	t.Run("CheckResponseForPullLogsFail", func(t *testing.T) {
@@ -735,6 +738,49 @@ func TestOpenapiContext(t *testing.T) {
 		assert.Equal(t, result, "")
 	})
 
+	t.Run("CheckResponseForPullLogsFlattened", func(t *testing.T) {
+		logGroupList := &sls.LogGroupList{
+			LogGroups: []*sls.LogGroup{{
+				Logs: []*sls.Log{{
+					Time:     proto.Uint32(1234567890),
+					Contents: []*sls.LogContent{{Key: proto.String("msg"), Value: proto.String("hello, world")}},
+				}},
+				Topic:   proto.String("test-topic"),
+				LogTags: []*sls.LogTag{{Key: proto.String("host"), Value: proto.String("h1")}},
+			}},
+		}
+		bodyBytes, err := proto.Marshal(logGroupList)
+		assert.NoError(t, err)
+
+		stderr := new(bytes.Buffer)
+		context := &OpenapiContext{HttpContext: &HttpContext{}, logFormat: "ndjson", logTimeFormat: "utc", stderr: stderr}
+		context.openapiResponse = map[string]any{
+			"body": base64.StdEncoding.EncodeToString(bodyBytes),
+			"headers": map[string]any{
+				"x-log-count":  "1",
+				"x-log-cursor": "test-cursor",
+			},
+		}
+		result, err := context.CheckResponseForPullLogs(context.openapiResponse)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"__time__":"2009-02-13T23:31:30Z","__topic__":"test-topic","__source__":"","__tag__:host":"h1","msg":"hello, world"}`, result)
+		assert.Equal(t, "count: 1\nnext_cursor: test-cursor\n", stderr.String())
+		assert.True(t, context.rawOutput())
+
+		context.logFormat, context.logTimeFormat = "csv", ""
+		result, err = context.CheckResponseForPullLogs(context.openapiResponse)
+		assert.NoError(t, err)
+		assert.Equal(t, "__time__,__topic__,__source__,__tag__:host,msg\n1234567890,test-topic,,h1,\"hello, world\"", result)
+
+		// 没有日志时仍输出下一个游标
+		stderr.Reset()
+		context.openapiResponse["body"] = ""
+		result, err = context.CheckResponseForPullLogs(context.openapiResponse)
+		assert.NoError(t, err)
+		assert.Equal(t, "", result)
+		assert.Contains(t, stderr.String(), "next_cursor: test-cursor")
+	})
+
 	t.Run("GetResponseForPullLogs", func(t *testing.T) {
 		httpContext := &HttpContext{}
 		context := &OpenapiContext{HttpContext: httpContext}
@@ -1820,3 +1866,42 @@ func TestProcessBodyInvalidParameter(t *testing.T) {
 	err := context.ProcessBody(ctx)
 	assert.Contains(t, err.Error(), "'--aaa' is not a valid parameter or flag")
 }
+
+func TestCheckLogFormatFlags(t *testing.T) {
+	newCtx := func(flags map[string]string) *cli.Context {
+		ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+		AddFlags(ctx.Flags())
+		for name, value := range flags {
+			ctx.Flags().Get(name).SetAssigned(true)
+			ctx.Flags().Get(name).SetValue(value)
+		}
+		return ctx
+	}
+	slsProduct := &meta.Product{Code: "sls"}
+	pullLogs := &meta.Api{Name: "PullLogs"}
+
+	assert.NoError(t, checkLogFormatFlags(newCtx(nil), nil, nil))
+	assert.NoError(t, checkLogFormatFlags(newCtx(map[string]string{"cli-log-format": "csv", "cli-log-time-format": "rfc3339"}), slsProduct, pullLogs))
+
+	err := checkLogFormatFlags(newCtx(map[string]string{"cli-log-format": "ndjson"}), slsProduct, &meta.Api{Name: "ListProject"})
+	assert.EqualError(t, err, "--cli-log-format is only supported by `aliyun sls PullLogs`")
+	err = checkLogFormatFlags(newCtx(map[string]string{"cli-log-format": "ndjson"}), nil, nil)
+	assert.EqualError(t, err, "--cli-log-format is only supported by `aliyun sls PullLogs`")
+	err = checkLogFormatFlags(newCtx(map[string]string{"cli-log-format": "xml"}), slsProduct, pullLogs)
+	assert.EqualError(t, err, "invalid --cli-log-format 'xml', must be ndjson or csv")
+	err = checkLogFormatFlags(newCtx(map[string]string{"cli-log-format": "ndjson", "cli-log-time-format": "iso"}), slsProduct, pullLogs)
+	assert.EqualError(t, err, "invalid --cli-log-time-format 'iso', must be one of unix, unixms, rfc3339, utc")
+	err = checkLogFormatFlags(newCtx(map[string]string{"cli-log-time-format": "utc"}), slsProduct, pullLogs)
+	assert.EqualError(t, err, "--cli-log-time-format requires --cli-log-format")
+	err = checkLogFormatFlags(newCtx(map[string]string{"cli-log-time-format": "utc"}), nil, nil)
+	assert.EqualError(t, err, "--cli-log-time-format is only supported by `aliyun sls PullLogs`")
+
+	// 与 sls tail、export 一样不区分大小写
+	assert.NoError(t, checkLogFormatFlags(newCtx(map[string]string{"cli-log-format": "NDJSON", "cli-log-time-format": "RFC3339"}), slsProduct, pullLogs))
+	context := &OpenapiContext{HttpContext: &HttpContext{}}
+	context.openapiRequest = &openapiutil.OpenApiRequest{Headers: map[string]*string{}}
+	context.openapiParams = &openapiutil.Params{}
+	context.ProcessPullLogsHeaders(newCtx(map[string]string{"cli-log-format": "CSV", "cli-log-time-format": "UnixMs"}))
+	assert.Equal(t, "csv", context.logFormat)
+	assert.Equal(t, "unixms", context.logTimeFormat)
+}
@@ -0,0 +1,202 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+
+	openapiutil "github.com/alibabacloud-go/darabonba-openapi/v2/utils"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/config"
+	"github.com/aliyun/aliyun-cli/v3/meta"
+	slsUtils "github.com/aliyun/aliyun-cli/v3/sls"
+)
+
+// slsShard is one entry of ListShards.
+type slsShard struct {
+	ID     int
+	Status string // readwrite or readonly (after a split or merge)
+}
+
+// slsPullResult is one PullLogs response.
+type slsPullResult struct {
+	Groups     []*slsUtils.LogGroup
+	NextCursor string
+}
+
+// slsPullClient is the part of the SLS data plane used to read a logstore shard by shard.
+type slsPullClient interface {
+	listShards(logstore string) ([]slsShard, error)
+	// getCursor returns the cursor of shard at from: begin, end or a unix time in seconds.
+	getCursor(logstore string, shard int, from string) (string, error)
+	// pullLogs returns up to count log groups after cursor, and before endCursor unless it is
+	// empty; the next cursor equals cursor when there is no new data.
+	pullLogs(logstore string, shard int, cursor, endCursor string, count int) (*slsPullResult, error)
+}
+
+var newSlsPullClient = func(ctx *cli.Context, profile *config.Profile, project string) (slsPullClient, error) {
+	return newSlsClient(ctx, profile, project)
+}
+
+// slsClient calls the SLS data plane APIs of one project. The openapi client is set up once;
+// every call gets its own request, so a slsClient can be shared by goroutines.
+type slsClient struct {
+	base    *HttpContext
+	project string
+}
+
+func newSlsClient(ctx *cli.Context, profile *config.Profile, project string) (*slsClient, error) {
+	base := NewHttpContext(profile)
+	if err := base.Init(ctx, &meta.Product{Code: "sls", Version: slsAPIVersion}); err != nil {
+		return nil, err
+	}
+	return &slsClient{base: base, project: project}, nil
+}
+
+func (c *slsClient) newRequest(action, method, pathname string) *HttpContext {
+	hc := *c.base
+	hc.openapiResponse = nil
+	hc.openapiRequest = &openapiutil.OpenApiRequest{
+		Query:            map[string]*string{},
+		Headers:          make(map[string]*string, len(c.base.openapiRequest.Headers)),
+		HostMap:          map[string]*string{"project": tea.String(c.project)},
+		EndpointOverride: c.base.openapiRequest.EndpointOverride,
+	}
+	for k, v := range c.base.openapiRequest.Headers {
+		hc.openapiRequest.Headers[k] = v
+	}
+	params := *c.base.openapiParams
+	params.Action = tea.String(action)
+	params.Version = tea.String(slsAPIVersion)
+	params.Method = tea.String(method)
+	params.Pathname = tea.String(pathname)
+	hc.openapiParams = &params
+	return &hc
+}
+
+func (c *slsClient) call(hc *HttpContext) (map[string]any, error) {
+	if err := hookHttpContextCall(hc.Call)(); err != nil {
+		return nil, err
+	}
+	return hc.openapiResponse, nil
+}
+
+func (c *slsClient) putLogs(logstore string, data []byte, rawSize int) error {
+	hc := c.newRequest("PutLogs", "POST", "/logstores/"+logstore+"/shards/lb")
+	hc.openapiParams.ReqBodyType = tea.String("binary")
+	hc.openapiParams.BodyType = tea.String("none")
+	hc.openapiRequest.Headers["content-type"] = tea.String("application/x-protobuf")
+	hc.openapiRequest.Headers["x-log-bodyrawsize"] = tea.String(strconv.Itoa(rawSize))
+	hc.openapiRequest.Headers["x-log-compresstype"] = tea.String("lz4")
+	hc.openapiRequest.SetBody(data)
+	_, err := c.call(hc)
+	return err
+}
+
+func (c *slsClient) listShards(logstore string) ([]slsShard, error) {
+	hc := c.newRequest("ListShards", "GET", "/logstores/"+logstore+"/shards")
+	hc.openapiParams.BodyType = tea.String("array")
+	resp, err := c.call(hc)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := resp["body"].([]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid ListShards response")
+	}
+	shards := make([]slsShard, 0, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid ListShards response")
+		}
+		id, err := strconv.Atoi(fmt.Sprint(m["shardID"]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid shard id '%v' in ListShards response", m["shardID"])
+		}
+		status, _ := m["status"].(string)
+		shards = append(shards, slsShard{ID: id, Status: status})
+	}
+	return shards, nil
+}
+
+func (c *slsClient) getCursor(logstore string, shard int, from string) (string, error) {
+	hc := c.newRequest("GetCursor", "GET", fmt.Sprintf("/logstores/%s/shards/%d", logstore, shard))
+	hc.openapiRequest.Query["type"] = tea.String("cursor")
+	hc.openapiRequest.Query["from"] = tea.String(from)
+	resp, err := c.call(hc)
+	if err != nil {
+		return "", err
+	}
+	body, _ := resp["body"].(map[string]any)
+	cursor, _ := body["cursor"].(string)
+	if cursor == "" {
+		return "", fmt.Errorf("no cursor returned for shard %d", shard)
+	}
+	return cursor, nil
+}
+
+func (c *slsClient) pullLogs(logstore string, shard int, cursor, endCursor string, count int) (*slsPullResult, error) {
+	hc := c.newRequest("PullLogs", "GET", fmt.Sprintf("/logstores/%s/shards/%d", logstore, shard))
+	hc.openapiRequest.Query["type"] = tea.String("log")
+	hc.openapiRequest.Query["cursor"] = tea.String(cursor)
+	hc.openapiRequest.Query["count"] = tea.String(strconv.Itoa(count))
+	if endCursor != "" {
+		hc.openapiRequest.Query["end_cursor"] = tea.String(endCursor)
+	}
+	hc.openapiRequest.Headers["Accept-Encoding"] = tea.String("lz4")
+	hc.openapiRequest.Headers["accept"] = tea.String("application/x-protobuf")
+	hc.openapiParams.BodyType = tea.String("byte")
+	resp, err := c.call(hc)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	switch body := resp["body"].(type) {
+	case []byte:
+		data = body
+	case string:
+		if data, err = base64.StdEncoding.DecodeString(body); err != nil {
+			return nil, fmt.Errorf("invalid PullLogs response: %v", err)
+		}
+	}
+	result := &slsPullResult{NextCursor: responseHeader(resp, "x-log-cursor")}
+	if result.NextCursor == "" {
+		return nil, fmt.Errorf("invalid PullLogs response: no next cursor")
+	}
+	if len(data) > 0 {
+		if result.Groups, err = slsUtils.ParseProtobufList(data); err != nil {
+			return nil, fmt.Errorf("parse PullLogs response failed: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// responseHeader reads a header of an Execute response, whose header map type depends on the body type.
+func responseHeader(resp map[string]any, name string) string {
+	switch headers := resp["headers"].(type) {
+	case map[string]any:
+		if v, ok := headers[name].(string); ok {
+			return v
+		}
+	case map[string]*string:
+		return tea.StringValue(headers[name])
+	case map[string]string:
+		return headers[name]
+	}
+	return ""
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/config"
+	"github.com/aliyun/aliyun-cli/v3/i18n"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/audit"
+)
+
+// Besides `aliyun sls <ApiName>`, a few workflows that need more than one API call are
+// implemented by the host as kebab-case commands, e.g. `aliyun sls put-logs`. They are
+// matched before the lowercase plugin routing in Commando.main, and take their options
+// from the unknown flags since they are not registered as cli.Command. Before running,
+// the APIs they call go through the safety policy and are audited like OpenAPI calls.
+
+// slsAPIVersion is the SLS OpenAPI version used by the sls commands.
+const slsAPIVersion = "2020-12-30"
+
+type slsCommandFlag struct {
+	Name     string
+	Required bool
+	Short    *i18n.Text
+}
+
+type slsCommand struct {
+	Name  string
+	Usage string
+	Short *i18n.Text
+	Flags []slsCommandFlag
+	// APIs are the SLS APIs the command calls; the safety policy and the audit log see
+	// each of them as if it was called with `aliyun sls <ApiName>`.
+	APIs []string
+	Run  func(c *Commando, ctx *cli.Context, opts slsCommandOptions) error
+}
+
+var slsCommands = map[string]*slsCommand{
+	slsPutLogsCommand.Name: slsPutLogsCommand,
+	slsTailCommand.Name:    slsTailCommand,
+	slsExportCommand.Name:  slsExportCommand,
+}
+
+// slsRetryBackoff is the wait before the given retry (1-based) of a failed request.
+var slsRetryBackoff = func(retry int) time.Duration {
+	return time.Duration(1<<uint(retry-1)) * 500 * time.Millisecond
+}
+
+// slsSignalContext is canceled on Ctrl+C, for the commands that run until stopped or
+// stop cleanly halfway.
+var slsSignalContext = func() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// findSlsCommand returns the sls command addressed by args (`sls <name> ...`), if any.
+func findSlsCommand(args []string) *slsCommand {
+	if len(args) < 2 || !strings.EqualFold(args[0], "sls") {
+		return nil
+	}
+	return slsCommands[args[1]]
+}
+
+// slsCommandOptions holds the flag values of an sls command.
+type slsCommandOptions map[string]string
+
+func (o slsCommandOptions) Get(name string) (string, bool) {
+	v, ok := o[name]
+	return v, ok
+}
+
+// Int returns the flag as an integer within [min, max], or def when it is not set.
+func (o slsCommandOptions) Int(name string, def, min, max int) (int, error) {
+	v, ok := o[name]
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("invalid --%s '%s', must be an integer between %d and %d", name, v, min, max)
+	}
+	return n, nil
+}
+
+func (cmd *slsCommand) parseOptions(ctx *cli.Context, args []string) (slsCommandOptions, error) {
+	if len(args) > 2 {
+		return nil, fmt.Errorf("unexpected argument '%s' for 'aliyun sls %s'", args[2], cmd.Name)
+	}
+	known := make(map[string]bool, len(cmd.Flags))
+	for _, f := range cmd.Flags {
+		known[f.Name] = true
+	}
+	opts := make(slsCommandOptions)
+	if ctx.UnknownFlags() != nil {
+		for _, f := range ctx.UnknownFlags().Flags() {
+			if !known[f.Name] {
+				return nil, cli.NewErrorWithTip(fmt.Errorf("unknown flag --%s for 'aliyun sls %s'", f.Name, cmd.Name),
+					"Use `aliyun sls %s --help` to show usage", cmd.Name)
+			}
+			opts[f.Name], _ = f.GetValue()
+		}
+	}
+	for _, f := range cmd.Flags {
+		if v, ok := opts[f.Name]; f.Required && (!ok || v == "") {
+			return nil, fmt.Errorf("required flag --%s is missing for 'aliyun sls %s'", f.Name, cmd.Name)
+		}
+	}
+	return opts, nil
+}
+
+func (cmd *slsCommand) printHelp(ctx *cli.Context) {
+	w := ctx.Stdout()
+	cli.Printf(w, "\n%s\n\nUsage:\n  aliyun sls %s %s\n\nFlags:\n", cmd.Short.Text(), cmd.Name, cmd.Usage)
+	tw := tabwriter.NewWriter(w, 8, 0, 1, ' ', 0)
+	for _, f := range cmd.Flags {
+		required := ""
+		if f.Required {
+			required = " " + i18n.T("(required)", "（必填）").Text()
+		}
+		cli.Printf(tw, "  --%s\t%s%s\n", f.Name, f.Short.Text(), required)
+	}
+	tw.Flush()
+}
+
+// printSlsCommands lists the sls commands in the sls product help.
+func printSlsCommands(ctx *cli.Context) {
+	names := make([]string, 0, len(slsCommands))
+	for name := range slsCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	cli.Printf(ctx.Stdout(), "\nCommands:\n")
+	tw := tabwriter.NewWriter(ctx.Stdout(), 8, 0, 1, ' ', 0)
+	for _, name := range names {
+		cli.Printf(tw, "  %s\t%s\n", name, slsCommands[name].Short.Text())
+	}
+	tw.Flush()
+}
+
+func (c *Commando) runSlsCommand(ctx *cli.Context, cmd *slsCommand, args []string) error {
+	if cli.HelpFlag(ctx.Flags()).IsAssigned() {
+		cmd.printHelp(ctx)
+		return nil
+	}
+	if err := checkLogFormatFlags(ctx, nil, nil); err != nil {
+		return err
+	}
+	opts, err := cmd.parseOptions(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	ctx.SetInConfigureMode(DetectInConfigureMode(ctx.Flags()))
+	c.profile, err = config.LoadProfileWithContext(ctx)
+	if err != nil {
+		return cli.NewErrorWithTip(err, "Configuration failed, use `aliyun configure` to configure it")
+	}
+	if err = c.profile.Validate(); err != nil {
+		return cli.NewErrorWithTip(err, "Configuration failed, use `aliyun configure` to configure it.")
+	}
+	i18n.SetLanguage(c.profile.Language)
+	return c.runSlsAPIs(ctx, cmd, opts)
+}
+
+// runSlsAPIs runs cmd once the safety policy allows every API it calls, and writes an
+// audit record for each of them with the result of the command.
+func (c *Commando) runSlsAPIs(ctx *cli.Context, cmd *slsCommand, opts slsCommandOptions) error {
+	for _, api := range cmd.APIs {
+		if err := c.checkSafetyPolicy(ctx, "sls", api, ""); err != nil {
+			return err
+		}
+	}
+	audits := make([]*auditCall, 0, len(cmd.APIs))
+	for _, api := range cmd.APIs {
+		audits = append(audits, c.startAudit(ctx, audit.SourceOpenAPI, "sls", api, ""))
+	}
+	err := cmd.Run(c, ctx, opts)
+	for _, a := range audits {
+		a.finish("", 0, err)
+	}
+	return err
+}
@@ -0,0 +1,421 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/i18n"
+	slsUtils "github.com/aliyun/aliyun-cli/v3/sls"
+)
+
+var slsExportCommand = &slsCommand{
+	Name:  "export",
+	Usage: "--project <project> --logstore <logstore> --from <time> [--to <time>] [--dir <path>] [--max-file-size <MB>]",
+	Short: i18n.T(
+		"Export the logs received in a time range to gzipped NDJSON files, one series per shard; an interrupted export resumes from its checkpoint",
+		"将某个时间范围内接收的日志导出为 gzip 压缩的 NDJSON 文件，每个 Shard 一组文件；中断后再次执行会从检查点继续"),
+	Flags: []slsCommandFlag{
+		{Name: "project", Required: true, Short: i18n.T("project name", "Project 名称")},
+		{Name: "logstore", Required: true, Short: i18n.T("logstore name", "Logstore 名称")},
+		{Name: "from", Required: true, Short: i18n.T(
+			"start of the range by receive time: begin, a unix time, an RFC 3339 time, or a duration such as 2h meaning that long ago",
+			"按接收时间的起始位置：begin、Unix 时间戳、RFC 3339 时间，或 2h 这样的时长表示从多久之前开始")},
+		{Name: "to", Short: i18n.T(
+			"end of the range, in the same forms as --from; the latest log by default",
+			"结束位置，格式同 --from；默认到最新的日志")},
+		{Name: "dir", Short: i18n.T("output directory, default the current directory", "输出目录，默认当前目录")},
+		{Name: "checkpoint", Short: i18n.T(
+			"checkpoint file, default <dir>/<logstore>.checkpoint.json",
+			"检查点文件，默认 <dir>/<logstore>.checkpoint.json")},
+		{Name: "max-file-size", Short: i18n.T("start a new file once one reaches this size in MB, 1-10240, default 100", "文件达到该大小（MB）后写入新文件，1-10240，默认 100")},
+		{Name: "time-format", Short: i18n.T("format of __time__: unix (default), unixms, rfc3339 or utc", "__time__ 的格式：unix（默认）、unixms、rfc3339 或 utc")},
+		{Name: "concurrency", Short: i18n.T("shards exported at the same time, 1-64, default 4", "同时导出的 Shard 数，1-64，默认 4")},
+		{Name: "retries", Short: i18n.T("retries of a failed request, 0-10, default 3", "请求失败后的重试次数，0-10，默认 3")},
+	},
+	APIs: []string{"ListShards", "GetCursor", "PullLogs"},
+	Run:  runSlsExport,
+}
+
+// slsExportPullCount is the max log groups of one PullLogs call; each call becomes one gzip member.
+const slsExportPullCount = 1000
+
+// slsExportCheckpoint records how far each shard got. Every output file is a series of
+// complete gzip members, so an export resumes by truncating the current file to Offset
+// and pulling again from Cursor. FromFlag and ToFlag keep --from and --to as given, since
+// a duration resolves to another time on every run; a resume has to give the same ones.
+type slsExportCheckpoint struct {
+	Project  string            `json:"project"`
+	Logstore string            `json:"logstore"`
+	From     string            `json:"from"`
+	To       string            `json:"to"`
+	FromFlag string            `json:"fromFlag"`
+	ToFlag   string            `json:"toFlag"`
+	Shards   []*slsExportShard `json:"shards"`
+}
+
+type slsExportShard struct {
+	ID          int      `json:"id"`
+	BeginCursor string   `json:"beginCursor"`
+	EndCursor   string   `json:"endCursor"`
+	Cursor      string   `json:"cursor"`
+	Files       []string `json:"files"` // the last one is being written unless Done
+	Offset      int64    `json:"offset"`
+	Logs        int64    `json:"logs"`
+	Done        bool     `json:"done"`
+}
+
+func loadSlsExportCheckpoint(path string) (*slsExportCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cp := &slsExportCheckpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %v", path, err)
+	}
+	return cp, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to dest and renames it into place.
+func writeFileAtomic(dest string, data []byte) error {
+	tmp := dest + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+type slsExporter struct {
+	client      slsPullClient
+	dir         string
+	maxFileSize int64
+	timeFormat  string
+	retries     int
+
+	mu             sync.Mutex
+	checkpoint     *slsExportCheckpoint
+	checkpointPath string
+}
+
+func (e *slsExporter) save() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	data, err := json.MarshalIndent(e.checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(e.checkpointPath, data)
+}
+
+// update applies fn to the checkpoint under the lock and saves it.
+func (e *slsExporter) update(fn func()) error {
+	e.mu.Lock()
+	fn()
+	e.mu.Unlock()
+	return e.save()
+}
+
+func (e *slsExporter) fileName(shard *slsExportShard, seq int) string {
+	return fmt.Sprintf("%s_shard%d_%04d.ndjson.gz", e.checkpoint.Logstore, shard.ID, seq)
+}
+
+// start lists the shards and resolves the range into cursors for a new export.
+func (e *slsExporter) start(from, to string) error {
+	list, err := e.client.listShards(e.checkpoint.Logstore)
+	if err != nil {
+		return err
+	}
+	for _, s := range list {
+		begin, err := e.client.getCursor(e.checkpoint.Logstore, s.ID, from)
+		if err != nil {
+			return fmt.Errorf("shard %d: %v", s.ID, err)
+		}
+		end, err := e.client.getCursor(e.checkpoint.Logstore, s.ID, to)
+		if err != nil {
+			return fmt.Errorf("shard %d: %v", s.ID, err)
+		}
+		e.checkpoint.Shards = append(e.checkpoint.Shards, &slsExportShard{
+			ID: s.ID, BeginCursor: begin, EndCursor: end, Cursor: begin, Done: begin == end,
+		})
+	}
+	sort.Slice(e.checkpoint.Shards, func(i, j int) bool { return e.checkpoint.Shards[i].ID < e.checkpoint.Shards[j].ID })
+	return e.save()
+}
+
+func (e *slsExporter) pull(shard *slsExportShard, cursor string) (*slsPullResult, error) {
+	var err error
+	for retry := 0; ; retry++ {
+		if retry > 0 {
+			time.Sleep(slsRetryBackoff(retry))
+		}
+		var result *slsPullResult
+		if result, err = e.client.pullLogs(e.checkpoint.Logstore, shard.ID, cursor, shard.EndCursor, slsExportPullCount); err == nil {
+			return result, nil
+		}
+		if retry >= e.retries {
+			return nil, err
+		}
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// export pulls shard from its checkpoint cursor to its end cursor.
+func (e *slsExporter) export(ctx context.Context, shard *slsExportShard) (err error) {
+	e.mu.Lock()
+	cursor, offset, logs := shard.Cursor, shard.Offset, shard.Logs
+	files := append([]string(nil), shard.Files...)
+	e.mu.Unlock()
+
+	// the file is opened on the first write, so that a shard without logs leaves no file
+	var f *os.File
+	defer func() {
+		if f != nil {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}()
+	gz := gzip.NewWriter(io.Discard)
+	for cursor != shard.EndCursor {
+		if ctx.Err() != nil {
+			return nil
+		}
+		result, err := e.pull(shard, cursor)
+		if err != nil {
+			return err
+		}
+		if result.NextCursor == cursor {
+			return fmt.Errorf("stopped at cursor %s before the end cursor %s", cursor, shard.EndCursor)
+		}
+		n := 0
+		for _, group := range result.Groups {
+			n += len(group.Logs)
+		}
+		if n > 0 {
+			if len(files) == 0 || offset >= e.maxFileSize {
+				if f != nil {
+					err := f.Close()
+					f = nil
+					if err != nil {
+						return err
+					}
+				}
+				files = append(files, e.fileName(shard, len(files)+1))
+				offset = 0
+			}
+			if f == nil {
+				if f, err = os.OpenFile(filepath.Join(e.dir, files[len(files)-1]), os.O_CREATE|os.O_WRONLY, 0644); err != nil {
+					return err
+				}
+				// drop whatever an interrupted run wrote after the last checkpoint
+				if err = f.Truncate(offset); err != nil {
+					return err
+				}
+				if _, err = f.Seek(offset, io.SeekStart); err != nil {
+					return err
+				}
+			}
+			cw := &countingWriter{w: f}
+			gz.Reset(cw)
+			if err := slsUtils.WriteLogs(gz, result.Groups, slsUtils.FormatNDJSON, e.timeFormat); err != nil {
+				return err
+			}
+			if err := gz.Close(); err != nil {
+				return err
+			}
+			offset += cw.n
+			logs += int64(n)
+			// the checkpoint must not get ahead of the data on disk
+			if err := f.Sync(); err != nil {
+				return err
+			}
+		}
+		cursor = result.NextCursor
+		if err := e.update(func() {
+			shard.Cursor, shard.Offset, shard.Logs = cursor, offset, logs
+			shard.Files = append(shard.Files[:0], files...)
+			shard.Done = cursor == shard.EndCursor
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runSlsExport(c *Commando, ctx *cli.Context, opts slsCommandOptions) error {
+	now := time.Now()
+	fromValue, _ := opts.Get("from")
+	if strings.EqualFold(fromValue, "end") {
+		return fmt.Errorf("invalid --from 'end', the range would be empty")
+	}
+	from, err := parseSlsFrom("from", fromValue, now)
+	if err != nil {
+		return err
+	}
+	toValue, _ := opts.Get("to")
+	to, err := parseSlsFrom("to", toValue, now)
+	if err != nil {
+		return err
+	}
+	maxFileSize, err := opts.Int("max-file-size", 100, 1, 10240)
+	if err != nil {
+		return err
+	}
+	concurrency, err := opts.Int("concurrency", 4, 1, 64)
+	if err != nil {
+		return err
+	}
+	retries, err := opts.Int("retries", 3, 0, 10)
+	if err != nil {
+		return err
+	}
+	timeFormat, _ := opts.Get("time-format")
+	if _, err := slsUtils.FormatLogTime(&slsUtils.Log{}, timeFormat); err != nil {
+		return fmt.Errorf("invalid --time-format '%s', must be one of %s", timeFormat, strings.Join(slsUtils.TimeFormats, ", "))
+	}
+
+	project, _ := opts.Get("project")
+	logstore, _ := opts.Get("logstore")
+	dir, ok := opts.Get("dir")
+	if !ok {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	checkpointPath, ok := opts.Get("checkpoint")
+	if !ok {
+		checkpointPath = filepath.Join(dir, logstore+".checkpoint.json")
+	}
+	checkpoint, err := loadSlsExportCheckpoint(checkpointPath)
+	if err != nil {
+		return err
+	}
+	if checkpoint != nil && (checkpoint.Project != project || checkpoint.Logstore != logstore) {
+		return cli.NewErrorWithTip(
+			fmt.Errorf("checkpoint %s belongs to %s/%s", checkpointPath, checkpoint.Project, checkpoint.Logstore),
+			"Use --checkpoint <path> or another --dir for this export")
+	}
+	if checkpoint != nil && (checkpoint.FromFlag != fromValue || checkpoint.ToFlag != toValue) {
+		return cli.NewErrorWithTip(
+			fmt.Errorf("checkpoint %s is for --from '%s' --to '%s', not --from '%s' --to '%s'",
+				checkpointPath, checkpoint.FromFlag, checkpoint.ToFlag, fromValue, toValue),
+			"Run with the same --from and --to to resume, or use --checkpoint <path> or another --dir for a new export")
+	}
+
+	client, err := newSlsPullClient(ctx, &c.profile, project)
+	if err != nil {
+		return err
+	}
+	e := &slsExporter{
+		client:         client,
+		dir:            dir,
+		maxFileSize:    int64(maxFileSize) << 20,
+		timeFormat:     timeFormat,
+		retries:        retries,
+		checkpoint:     checkpoint,
+		checkpointPath: checkpointPath,
+	}
+	if checkpoint != nil {
+		cli.Printf(ctx.Stdout(), "Resuming the export of %s/%s from %s (range %s to %s)\n",
+			project, logstore, checkpointPath, checkpoint.From, checkpoint.To)
+	} else {
+		e.checkpoint = &slsExportCheckpoint{
+			Project: project, Logstore: logstore, From: from, To: to, FromFlag: fromValue, ToFlag: toValue,
+		}
+		if err := e.start(from, to); err != nil {
+			return err
+		}
+	}
+
+	exportCtx, stop := slsSignalContext()
+	defer stop()
+	shards := make(chan *slsExportShard)
+	errs := make(map[int]error)
+	var errMu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for shard := range shards {
+				if err := e.export(exportCtx, shard); err != nil {
+					errMu.Lock()
+					errs[shard.ID] = err
+					errMu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, shard := range e.checkpoint.Shards {
+		if !shard.Done {
+			shards <- shard
+		}
+	}
+	close(shards)
+	wg.Wait()
+
+	var total int64
+	files, done := 0, 0
+	for _, shard := range e.checkpoint.Shards {
+		total += shard.Logs
+		files += len(shard.Files)
+		if shard.Done {
+			done++
+		}
+		if err := errs[shard.ID]; err != nil {
+			cli.Printf(ctx.Stderr(), "shard %d: %v\n", shard.ID, err)
+		}
+	}
+	cli.Printf(ctx.Stdout(), "Exported %d log(s) from %d of %d shard(s) of %s/%s into %d file(s) in %s\n",
+		total, done, len(e.checkpoint.Shards), project, logstore, files, dir)
+	if done < len(e.checkpoint.Shards) {
+		reason := fmt.Sprintf("%d shard(s) failed", len(errs))
+		if exportCtx.Err() != nil {
+			reason = "interrupted"
+		}
+		return cli.NewErrorWithTip(fmt.Errorf("export is incomplete: %s", reason),
+			"Run the same command again to resume from %s", checkpointPath)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	slsUtils "github.com/aliyun/aliyun-cli/v3/sls"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readExportedLines 读取由多个 gzip member 组成的导出文件
+func readExportedLines(t *testing.T, path string) []string {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	var lines []string
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	require.NoError(t, scanner.Err())
+	return lines
+}
+
+func newExportTestClient(logs int) *fakeSlsPullClient {
+	var groups []*slsUtils.LogGroup
+	for i := 0; i < logs; i++ {
+		groups = append(groups, newTestLogGroup("app", map[string]string{"n": fmt.Sprint(i)}))
+	}
+	return &fakeSlsPullClient{
+		cursors: map[int]string{},
+		shards: map[int]*fakeSlsShard{
+			0: {status: "readwrite", groups: groups},
+			1: {status: "readonly"},
+		},
+	}
+}
+
+func withNoRetryBackoff(t *testing.T) {
+	orig := slsRetryBackoff
+	slsRetryBackoff = func(int) time.Duration { return 0 }
+	t.Cleanup(func() { slsRetryBackoff = orig })
+}
+
+func TestRunSlsExport(t *testing.T) {
+	t.Run("resumes after a failure", func(t *testing.T) {
+		withNoRetryBackoff(t)
+		client := newExportTestClient(5)
+		withFakeSlsPullClient(t, client)
+		pulls := 0
+		client.onPull = func(shard int, cursor string) error {
+			pulls++
+			if cursor == "3" {
+				return errors.New("ServerBusy")
+			}
+			return nil
+		}
+		dir := t.TempDir()
+		opts := slsCommandOptions{"project": "p", "logstore": "l", "from": "begin", "dir": dir, "retries": "1"}
+
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		ctx := cli.NewCommandContext(stdout, stderr)
+		err := runSlsExport(&Commando{}, ctx, opts)
+		assert.EqualError(t, err, "export is incomplete: 1 shard(s) failed")
+		assert.Contains(t, stdout.String(), "Exported 3 log(s) from 1 of 2 shard(s) of p/l into 1 file(s)")
+		assert.Contains(t, stderr.String(), "shard 0: ServerBusy")
+		assert.Equal(t, map[int]string{0: "end", 1: "end"}, client.cursors)
+
+		cp, err := loadSlsExportCheckpoint(filepath.Join(dir, "l.checkpoint.json"))
+		require.NoError(t, err)
+		require.Len(t, cp.Shards, 2)
+		assert.Equal(t, "3", cp.Shards[0].Cursor)
+		assert.Equal(t, "5", cp.Shards[0].EndCursor)
+		assert.False(t, cp.Shards[0].Done)
+		// 空 shard 不生成文件
+		assert.True(t, cp.Shards[1].Done)
+		assert.Empty(t, cp.Shards[1].Files)
+
+		// 模拟中断时写了一半的数据，续传时应被截掉
+		file := filepath.Join(dir, "l_shard0_0001.ndjson.gz")
+		f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0644)
+		require.NoError(t, err)
+		f.Write([]byte("partial"))
+		f.Close()
+
+		client.onPull = nil
+		stdout.Reset()
+		require.NoError(t, runSlsExport(&Commando{}, ctx, opts))
+		assert.Contains(t, stdout.String(), "Resuming the export of p/l")
+		assert.Contains(t, stdout.String(), "Exported 5 log(s) from 2 of 2 shard(s)")
+
+		lines := readExportedLines(t, file)
+		require.Len(t, lines, 5)
+		for i, line := range lines {
+			assert.Equal(t, fmt.Sprintf(`{"__time__":"1712345678","__topic__":"app","__source__":"","n":"%d"}`, i), line)
+		}
+
+		// 已完成的导出再次执行不会重复拉取
+		pulls = 0
+		client.onPull = func(int, string) error { pulls++; return nil }
+		require.NoError(t, runSlsExport(&Commando{}, ctx, opts))
+		assert.Equal(t, 0, pulls)
+	})
+
+	t.Run("checkpoint of another logstore", func(t *testing.T) {
+		withFakeSlsPullClient(t, newExportTestClient(1))
+		dir := t.TempDir()
+		checkpoint := filepath.Join(dir, "cp.json")
+		require.NoError(t, os.WriteFile(checkpoint, []byte(`{"project":"p","logstore":"other"}`), 0644))
+		ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+		err := runSlsExport(&Commando{}, ctx, slsCommandOptions{"project": "p", "logstore": "l", "from": "begin", "dir": dir, "checkpoint": checkpoint})
+		assert.ErrorContains(t, err, "belongs to p/other")
+	})
+
+	t.Run("checkpoint of another range", func(t *testing.T) {
+		withFakeSlsPullClient(t, newExportTestClient(1))
+		dir := t.TempDir()
+		checkpoint := filepath.Join(dir, "l.checkpoint.json")
+		require.NoError(t, os.WriteFile(checkpoint, []byte(`{"project":"p","logstore":"l","fromFlag":"2h","toFlag":""}`), 0644))
+		ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+		err := runSlsExport(&Commando{}, ctx, slsCommandOptions{"project": "p", "logstore": "l", "from": "begin", "dir": dir})
+		assert.EqualError(t, err, "checkpoint "+checkpoint+" is for --from '2h' --to '', not --from 'begin' --to ''")
+		err = runSlsExport(&Commando{}, ctx, slsCommandOptions{"project": "p", "logstore": "l", "from": "2h", "to": "1h", "dir": dir})
+		assert.ErrorContains(t, err, "not --from '2h' --to '1h'")
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		withFakeSlsPullClient(t, &fakeSlsPullClient{})
+		ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+		err := runSlsExport(&Commando{}, ctx, slsCommandOptions{"project": "p", "logstore": "l", "from": "end"})
+		assert.EqualError(t, err, "invalid --from 'end', the range would be empty")
+		err = runSlsExport(&Commando{}, ctx, slsCommandOptions{"project": "p", "logstore": "l", "from": "begin", "to": "soon"})
+		assert.EqualError(t, err, "invalid --to 'soon', must be end, begin, a unix time, an RFC 3339 time or a duration")
+		err = runSlsExport(&Commando{}, ctx, slsCommandOptions{"project": "p", "logstore": "l", "from": "begin", "time-format": "iso"})
+		assert.EqualError(t, err, "invalid --time-format 'iso', must be one of unix, unixms, rfc3339, utc")
+	})
+}
+
+func TestSlsExporter_export(t *testing.T) {
+	newExporter := func(client slsPullClient, dir string) *slsExporter {
+		return &slsExporter{
+			client:         client,
+			dir:            dir,
+			maxFileSize:    1,
+			timeFormat:     slsUtils.TimeFormatUTC,
+			checkpoint:     &slsExportCheckpoint{Project: "p", Logstore: "l"},
+			checkpointPath: filepath.Join(dir, "cp.json"),
+		}
+	}
+
+	t.Run("rotates files by size", func(t *testing.T) {
+		dir := t.TempDir()
+		e := newExporter(newExportTestClient(3), dir)
+		require.NoError(t, e.start("begin", "end"))
+		require.NoError(t, e.export(context.Background(), e.checkpoint.Shards[0]))
+		shard := e.checkpoint.Shards[0]
+		assert.True(t, shard.Done)
+		assert.Equal(t, int64(3), shard.Logs)
+		assert.Equal(t, []string{"l_shard0_0001.ndjson.gz", "l_shard0_0002.ndjson.gz", "l_shard0_0003.ndjson.gz"}, shard.Files)
+		assert.Equal(t, []string{`{"__time__":"2024-04-05T19:34:38Z","__topic__":"app","__source__":"","n":"2"}`},
+			readExportedLines(t, filepath.Join(dir, shard.Files[2])))
+	})
+
+	t.Run("verifies the end cursor", func(t *testing.T) {
+		client := newExportTestClient(2)
+		e := newExporter(client, t.TempDir())
+		require.NoError(t, e.start("begin", "end"))
+		// 服务端在结束游标之前就不再返回数据
+		client.shards[0].groups = client.shards[0].groups[:1]
+		err := e.export(context.Background(), e.checkpoint.Shards[0])
+		assert.EqualError(t, err, "stopped at cursor 1 before the end cursor 2")
+		assert.False(t, e.checkpoint.Shards[0].Done)
+	})
+
+	t.Run("stops when canceled", func(t *testing.T) {
+		e := newExporter(newExportTestClient(2), t.TempDir())
+		require.NoError(t, e.start("begin", "end"))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.NoError(t, e.export(ctx, e.checkpoint.Shards[0]))
+		assert.Equal(t, "0", e.checkpoint.Shards[0].Cursor)
+	})
+}
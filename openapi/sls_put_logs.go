@@ -0,0 +1,260 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/config"
+	"github.com/aliyun/aliyun-cli/v3/i18n"
+	slsUtils "github.com/aliyun/aliyun-cli/v3/sls"
+)
+
+var slsPutLogsCommand = &slsCommand{
+	Name:  "put-logs",
+	Usage: "--project <project> --logstore <logstore> [--file <path>] [--format ndjson|csv] [--time-field <field>]",
+	Short: i18n.T(
+		"Write newline-delimited JSON or CSV logs from a file or stdin to a logstore, in batches",
+		"从文件或标准输入读取 NDJSON 或 CSV 日志，分批写入 Logstore"),
+	Flags: []slsCommandFlag{
+		{Name: "project", Required: true, Short: i18n.T("project name", "Project 名称")},
+		{Name: "logstore", Required: true, Short: i18n.T("logstore name", "Logstore 名称")},
+		{Name: "file", Short: i18n.T("input file, stdin when omitted", "输入文件，未指定时读取标准输入")},
+		{Name: "format", Short: i18n.T(
+			"ndjson (one JSON object per line) or csv (with a header row); detected from the file extension by default",
+			"ndjson（每行一个 JSON 对象）或 csv（首行为表头），默认根据文件扩展名判断")},
+		{Name: "time-field", Short: i18n.T(
+			"field holding the log time, the current time is used when omitted",
+			"日志时间所在的字段，未指定时使用当前时间")},
+		{Name: "time-format", Short: i18n.T(
+			"Go time layout of --time-field, e.g. 2006-01-02 15:04:05; unix timestamps and RFC 3339 are detected by default",
+			"--time-field 的 Go 时间格式，例如 2006-01-02 15:04:05；默认自动识别 Unix 时间戳和 RFC 3339")},
+		{Name: "topic", Short: i18n.T("log topic", "日志主题")},
+		{Name: "source", Short: i18n.T("log source", "日志来源")},
+		{Name: "batch-size", Short: i18n.T("max logs per request, 1-4096, default 4096", "每次请求的最大日志条数，1-4096，默认 4096")},
+		{Name: "concurrency", Short: i18n.T("concurrent requests, 1-64, default 4", "并发请求数，1-64，默认 4")},
+		{Name: "retries", Short: i18n.T("retries of a failed request, 0-10, default 3", "请求失败后的重试次数，0-10，默认 3")},
+	},
+	APIs: []string{"PutLogs"},
+	Run:  runSlsPutLogs,
+}
+
+// slsLogSender sends one LZ4-compressed log group with PutLogs.
+type slsLogSender func(data []byte, rawSize int) error
+
+// newSlsLogSender is called once per worker.
+var newSlsLogSender = func(ctx *cli.Context, profile *config.Profile, project, logstore string) (slsLogSender, error) {
+	client, err := newSlsClient(ctx, profile, project)
+	if err != nil {
+		return nil, err
+	}
+	return func(data []byte, rawSize int) error {
+		return client.putLogs(logstore, data, rawSize)
+	}, nil
+}
+
+// maxReportedLineErrors bounds the per-line errors printed to stderr.
+const maxReportedLineErrors = 10
+
+type slsPutLogsStats struct {
+	mu            sync.Mutex
+	stderr        io.Writer
+	read          int
+	accepted      int
+	invalid       int
+	rejected      int
+	batches       int
+	failedBatches int
+	reported      int
+}
+
+func (s *slsPutLogsStats) lineError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.invalid++
+	s.report("%v\n", err)
+}
+
+func (s *slsPutLogsStats) batchDone(batch *slsUtils.Batch, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches++
+	if err == nil {
+		s.accepted += len(batch.Lines)
+		return
+	}
+	s.failedBatches++
+	s.rejected += len(batch.Lines)
+	s.report("lines %d-%d: %v\n", batch.Lines[0], batch.Lines[len(batch.Lines)-1], err)
+}
+
+func (s *slsPutLogsStats) report(format string, args ...interface{}) {
+	s.reported++
+	if s.reported <= maxReportedLineErrors {
+		cli.Printf(s.stderr, format, args...)
+	} else if s.reported == maxReportedLineErrors+1 {
+		cli.Printf(s.stderr, "... more errors are not shown\n")
+	}
+}
+
+func slsPutLogsFormat(opts slsCommandOptions) (string, error) {
+	if format, ok := opts.Get("format"); ok {
+		format = strings.ToLower(format)
+		if format != slsUtils.FormatNDJSON && format != slsUtils.FormatCSV {
+			return "", fmt.Errorf("invalid --format '%s', must be %s or %s", format, slsUtils.FormatNDJSON, slsUtils.FormatCSV)
+		}
+		return format, nil
+	}
+	if file, ok := opts.Get("file"); ok && strings.EqualFold(filepath.Ext(file), ".csv") {
+		return slsUtils.FormatCSV, nil
+	}
+	return slsUtils.FormatNDJSON, nil
+}
+
+func runSlsPutLogs(c *Commando, ctx *cli.Context, opts slsCommandOptions) error {
+	format, err := slsPutLogsFormat(opts)
+	if err != nil {
+		return err
+	}
+	batchSize, err := opts.Int("batch-size", slsUtils.MaxLogGroupLogs, 1, slsUtils.MaxLogGroupLogs)
+	if err != nil {
+		return err
+	}
+	concurrency, err := opts.Int("concurrency", 4, 1, 64)
+	if err != nil {
+		return err
+	}
+	retries, err := opts.Int("retries", 3, 0, 10)
+	if err != nil {
+		return err
+	}
+
+	var input io.Reader
+	if file, ok := opts.Get("file"); ok {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input = f
+	} else if isInteractiveInput() {
+		return cli.NewErrorWithTip(fmt.Errorf("no logs provided"),
+			"Use --file <path> or pipe logs to stdin, e.g. `cat app.log | aliyun sls put-logs --project <project> --logstore <logstore>`")
+	} else {
+		input = stdin
+	}
+
+	timeField, _ := opts.Get("time-field")
+	timeLayout, _ := opts.Get("time-format")
+	reader, err := slsUtils.NewRecordReader(input, slsUtils.RecordReaderOptions{
+		Format:     format,
+		TimeField:  timeField,
+		TimeLayout: timeLayout,
+	})
+	if err != nil {
+		return err
+	}
+
+	project, _ := opts.Get("project")
+	logstore, _ := opts.Get("logstore")
+	senders := make([]slsLogSender, concurrency)
+	for i := range senders {
+		if senders[i], err = newSlsLogSender(ctx, &c.profile, project, logstore); err != nil {
+			return err
+		}
+	}
+
+	stats := &slsPutLogsStats{stderr: ctx.Stderr()}
+	batches := make(chan *slsUtils.Batch, concurrency)
+	var wg sync.WaitGroup
+	for _, send := range senders {
+		wg.Add(1)
+		go func(send slsLogSender) {
+			defer wg.Done()
+			for batch := range batches {
+				stats.batchDone(batch, sendSlsBatch(send, batch, retries))
+			}
+		}(send)
+	}
+
+	topic, _ := opts.Get("topic")
+	source, _ := opts.Get("source")
+	batcher := slsUtils.NewBatcher(batchSize, slsUtils.MaxLogGroupBytes, topic, source)
+	var readErr error
+	for {
+		rec, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		var recErr *slsUtils.RecordError
+		if errors.As(err, &recErr) {
+			stats.read++
+			stats.lineError(recErr)
+			continue
+		}
+		if err != nil {
+			readErr = err
+			break
+		}
+		stats.read++
+		full, err := batcher.Add(rec)
+		if err != nil {
+			stats.lineError(err)
+			continue
+		}
+		if full != nil {
+			batches <- full
+		}
+	}
+	if last := batcher.Flush(); last != nil {
+		batches <- last
+	}
+	close(batches)
+	wg.Wait()
+
+	failed := stats.invalid + stats.rejected
+	cli.Printf(ctx.Stdout(), "Read %d line(s): %d accepted, %d failed (%d invalid, %d rejected)\n",
+		stats.read, stats.accepted, failed, stats.invalid, stats.rejected)
+	cli.Printf(ctx.Stdout(), "Sent %d batch(es) to %s/%s, %d failed\n", stats.batches, project, logstore, stats.failedBatches)
+	if readErr != nil {
+		return fmt.Errorf("read input failed: %v", readErr)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d line(s) failed", failed, stats.read)
+	}
+	return nil
+}
+
+func sendSlsBatch(send slsLogSender, batch *slsUtils.Batch, retries int) error {
+	data, rawSize, err := slsUtils.EncodeLogGroup(batch.Group)
+	if err != nil {
+		return err
+	}
+	for retry := 0; ; retry++ {
+		if retry > 0 {
+			time.Sleep(slsRetryBackoff(retry))
+		}
+		if err = send(data, rawSize); err == nil || retry >= retries {
+			return err
+		}
+	}
+}
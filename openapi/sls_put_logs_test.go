@@ -0,0 +1,260 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/config"
+	slsUtils "github.com/aliyun/aliyun-cli/v3/sls"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/audit"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/safety"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pierrec/lz4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindSlsCommand(t *testing.T) {
+	assert.Equal(t, slsPutLogsCommand, findSlsCommand([]string{"sls", "put-logs"}))
+	assert.Equal(t, slsPutLogsCommand, findSlsCommand([]string{"SLS", "put-logs"}))
+	assert.Nil(t, findSlsCommand([]string{"sls", "PutLogs"}))
+	assert.Nil(t, findSlsCommand([]string{"ecs", "put-logs"}))
+	assert.Nil(t, findSlsCommand([]string{"sls"}))
+}
+
+func TestSlsCommand_parseOptions(t *testing.T) {
+	newCtx := func(flags map[string]string) *cli.Context {
+		ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+		ctx.SetUnknownFlags(cli.NewFlagSet())
+		for name, value := range flags {
+			f, _ := ctx.UnknownFlags().AddByName(name)
+			f.SetAssigned(true)
+			f.SetValue(value)
+		}
+		return ctx
+	}
+
+	opts, err := slsPutLogsCommand.parseOptions(newCtx(map[string]string{"project": "p", "logstore": "l", "concurrency": "8"}), []string{"sls", "put-logs"})
+	require.NoError(t, err)
+	assert.Equal(t, slsCommandOptions{"project": "p", "logstore": "l", "concurrency": "8"}, opts)
+	n, err := opts.Int("concurrency", 4, 1, 64)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, n)
+	n, _ = opts.Int("retries", 3, 0, 10)
+	assert.Equal(t, 3, n)
+	_, err = opts.Int("project", 0, 0, 1)
+	assert.EqualError(t, err, "invalid --project 'p', must be an integer between 0 and 1")
+
+	_, err = slsPutLogsCommand.parseOptions(newCtx(map[string]string{"project": "p"}), []string{"sls", "put-logs"})
+	assert.EqualError(t, err, "required flag --logstore is missing for 'aliyun sls put-logs'")
+
+	_, err = slsPutLogsCommand.parseOptions(newCtx(map[string]string{"project": "p", "logstore": "l", "LogStore": "x"}), []string{"sls", "put-logs"})
+	assert.ErrorContains(t, err, "unknown flag --LogStore for 'aliyun sls put-logs'")
+
+	_, err = slsPutLogsCommand.parseOptions(newCtx(map[string]string{"project": "p", "logstore": "l"}), []string{"sls", "put-logs", "extra"})
+	assert.EqualError(t, err, "unexpected argument 'extra' for 'aliyun sls put-logs'")
+}
+
+func TestSlsCommand_help(t *testing.T) {
+	stdout := new(bytes.Buffer)
+	ctx := cli.NewCommandContext(stdout, new(bytes.Buffer))
+	c := &Commando{}
+	require.NoError(t, c.help(ctx, []string{"sls", "put-logs"}))
+	assert.Contains(t, stdout.String(), "aliyun sls put-logs --project <project>")
+	assert.Contains(t, stdout.String(), "--time-field")
+}
+
+type fakeSlsLogstore struct {
+	mu       sync.Mutex
+	lines    []map[string]string
+	requests int
+	failures int // 前 failures 次请求失败
+}
+
+func (f *fakeSlsLogstore) sender(ctx *cli.Context, profile *config.Profile, project, logstore string) (slsLogSender, error) {
+	return func(data []byte, rawSize int) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.requests++
+		if f.failures > 0 {
+			f.failures--
+			return errors.New("ServerBusy")
+		}
+		raw := make([]byte, rawSize)
+		n, err := lz4.UncompressBlock(data, raw)
+		if err != nil {
+			return err
+		}
+		var group slsUtils.LogGroup
+		if err := proto.Unmarshal(raw[:n], &group); err != nil {
+			return err
+		}
+		for _, log := range group.Logs {
+			m := map[string]string{"__time__": fmt.Sprint(log.GetTime()), "__topic__": group.GetTopic()}
+			for _, c := range log.Contents {
+				m[c.GetKey()] = c.GetValue()
+			}
+			f.lines = append(f.lines, m)
+		}
+		return nil
+	}, nil
+}
+
+func withFakeSlsLogstore(t *testing.T, store *fakeSlsLogstore) {
+	origSender, origBackoff := newSlsLogSender, slsRetryBackoff
+	newSlsLogSender = store.sender
+	slsRetryBackoff = func(int) time.Duration { return 0 }
+	t.Cleanup(func() {
+		newSlsLogSender, slsRetryBackoff = origSender, origBackoff
+	})
+}
+
+func TestRunSlsPutLogs(t *testing.T) {
+	t.Run("ndjson from stdin in batches", func(t *testing.T) {
+		store := &fakeSlsLogstore{failures: 1}
+		withFakeSlsLogstore(t, store)
+		origStdin, origInteractive := stdin, isInteractiveInput
+		defer func() { stdin, isInteractiveInput = origStdin, origInteractive }()
+		isInteractiveInput = func() bool { return false }
+		var input strings.Builder
+		for i := 0; i < 25; i++ {
+			fmt.Fprintf(&input, `{"ts":%d,"msg":"m%d"}`+"\n", 1712345600+i, i)
+		}
+		input.WriteString("oops\n")
+		stdin = strings.NewReader(input.String())
+
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		ctx := cli.NewCommandContext(stdout, stderr)
+		err := runSlsPutLogs(&Commando{}, ctx, slsCommandOptions{
+			"project": "p", "logstore": "l", "time-field": "ts", "batch-size": "10", "concurrency": "2", "topic": "app",
+		})
+		assert.EqualError(t, err, "1 of 26 line(s) failed")
+		assert.Contains(t, stdout.String(), "Read 26 line(s): 25 accepted, 1 failed (1 invalid, 0 rejected)")
+		assert.Contains(t, stdout.String(), "Sent 3 batch(es) to p/l, 0 failed")
+		assert.Contains(t, stderr.String(), "line 26: invalid JSON")
+		// 一次失败后重试成功
+		assert.Equal(t, 4, store.requests)
+		require.Len(t, store.lines, 25)
+		for _, line := range store.lines {
+			assert.Equal(t, "app", line["__topic__"])
+			assert.Equal(t, line["ts"], line["__time__"])
+		}
+	})
+
+	t.Run("csv file with rejected batches", func(t *testing.T) {
+		store := &fakeSlsLogstore{failures: 100}
+		withFakeSlsLogstore(t, store)
+		file := filepath.Join(t.TempDir(), "logs.csv")
+		require.NoError(t, os.WriteFile(file, []byte("level,msg\ninfo,a\nwarn,b\n"), 0644))
+
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		ctx := cli.NewCommandContext(stdout, stderr)
+		err := runSlsPutLogs(&Commando{}, ctx, slsCommandOptions{"project": "p", "logstore": "l", "file": file, "retries": "1"})
+		assert.EqualError(t, err, "2 of 2 line(s) failed")
+		assert.Contains(t, stdout.String(), "Read 2 line(s): 0 accepted, 2 failed (0 invalid, 2 rejected)")
+		assert.Contains(t, stderr.String(), "lines 2-3: ServerBusy")
+		assert.Equal(t, 2, store.requests)
+
+		store.failures = 0
+		stdout.Reset()
+		require.NoError(t, runSlsPutLogs(&Commando{}, ctx, slsCommandOptions{"project": "p", "logstore": "l", "file": file}))
+		assert.Contains(t, stdout.String(), "Read 2 line(s): 2 accepted, 0 failed")
+		assert.Equal(t, "b", store.lines[1]["msg"])
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		withFakeSlsLogstore(t, &fakeSlsLogstore{})
+		ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+		err := runSlsPutLogs(&Commando{}, ctx, slsCommandOptions{"project": "p", "logstore": "l", "format": "xml"})
+		assert.EqualError(t, err, "invalid --format 'xml', must be ndjson or csv")
+		err = runSlsPutLogs(&Commando{}, ctx, slsCommandOptions{"project": "p", "logstore": "l", "batch-size": "5000"})
+		assert.EqualError(t, err, "invalid --batch-size '5000', must be an integer between 1 and 4096")
+
+		origInteractive := isInteractiveInput
+		defer func() { isInteractiveInput = origInteractive }()
+		isInteractiveInput = func() bool { return true }
+		err = runSlsPutLogs(&Commando{}, ctx, slsCommandOptions{"project": "p", "logstore": "l"})
+		assert.ErrorContains(t, err, "no logs provided")
+	})
+}
+
+func TestRunSlsCommand_RejectsLogFormatFlags(t *testing.T) {
+	ran := false
+	cmd := &slsCommand{
+		Name: "tail",
+		Run: func(c *Commando, ctx *cli.Context, opts slsCommandOptions) error {
+			ran = true
+			return nil
+		},
+	}
+	for _, name := range []string{LogFormatFlagName, LogTimeFormatFlagName} {
+		ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+		AddFlags(ctx.Flags())
+		ctx.Flags().Get(name).SetAssigned(true)
+		ctx.Flags().Get(name).SetValue("csv")
+		// sls tail 等命令有自己的 --format，不能静默忽略 --cli-log-format
+		err := (&Commando{}).runSlsCommand(ctx, cmd, []string{"sls", "tail"})
+		assert.EqualError(t, err, "--"+name+" is only supported by `aliyun sls PullLogs`")
+	}
+	assert.False(t, ran)
+}
+
+func TestRunSlsAPIs_SafetyPolicyAndAudit(t *testing.T) {
+	testHome := t.TempDir()
+	cleanup := setTestHomeDir(t, testHome)
+	defer cleanup()
+	logPath := enableAuditForTest(t, testHome, "")
+
+	ran := 0
+	cmd := &slsCommand{
+		Name: "tail",
+		APIs: []string{"ListShards", "GetCursor", "PullLogs"},
+		Run: func(c *Commando, ctx *cli.Context, opts slsCommandOptions) error {
+			ran++
+			return nil
+		},
+	}
+	c := &Commando{profile: config.Profile{Name: "default"}}
+
+	// 拒绝其中任一 API 时命令不会执行
+	writeSafetyPolicy(t, testHome, []safety.Rule{{Pattern: "sls:PullLogs", Action: safety.ActionDeny}})
+	err := c.runSlsAPIs(newAuditTestCtx(t, map[string]string{"project": "p", "logstore": "l"}), cmd, nil)
+	assert.ErrorContains(t, err, "blocked by safety policy")
+	assert.Equal(t, 0, ran)
+	records := readAuditRecords(t, logPath)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "PullLogs", records[0].API)
+		assert.Equal(t, audit.ResultSafetyPolicyRejected, records[0].ResultCode)
+	}
+
+	writeSafetyPolicy(t, testHome, []safety.Rule{})
+	require.NoError(t, c.runSlsAPIs(newAuditTestCtx(t, nil), cmd, nil))
+	assert.Equal(t, 1, ran)
+	records = readAuditRecords(t, logPath)
+	// 默认审计范围不记录 ListShards、GetCursor 等只读 API
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, "PullLogs", records[1].API)
+		assert.Equal(t, audit.ResultOK, records[1].ResultCode)
+	}
+}
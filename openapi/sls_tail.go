@@ -0,0 +1,314 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/i18n"
+	slsUtils "github.com/aliyun/aliyun-cli/v3/sls"
+)
+
+var slsTailCommand = &slsCommand{
+	Name:  "tail",
+	Usage: "--project <project> --logstore <logstore> [--from end|begin|<time>|<duration>] [--format json|text] [--filter <expr>]",
+	Short: i18n.T(
+		"Follow the new logs of a logstore across all shards, like `tail -f`; press Ctrl+C to stop",
+		"持续读取 Logstore 所有 Shard 的新日志，类似 `tail -f`；按 Ctrl+C 结束"),
+	Flags: []slsCommandFlag{
+		{Name: "project", Required: true, Short: i18n.T("project name", "Project 名称")},
+		{Name: "logstore", Required: true, Short: i18n.T("logstore name", "Logstore 名称")},
+		{Name: "from", Short: i18n.T(
+			"where to start: end (default), begin, a unix time, an RFC 3339 time, or a duration such as 15m meaning that long ago",
+			"起始位置：end（默认）、begin、Unix 时间戳、RFC 3339 时间，或 15m 这样的时长表示从多久之前开始")},
+		{Name: "format", Short: i18n.T(
+			"json (one object per log, default) or text (time followed by key=value pairs)",
+			"json（每条日志一个对象，默认）或 text（时间加 key=value）")},
+		{Name: "filter", Short: i18n.T(
+			"client-side filter, e.g. 'level=ERROR and status>=500 or msg~\"time ?out\"'; ops: = != ~ !~ > >= < <=, a bare word matches any value",
+			"客户端过滤表达式，例如 'level=ERROR and status>=500 or msg~\"time ?out\"'；运算符：= != ~ !~ > >= < <=，单独的词匹配任意字段值")},
+		{Name: "interval", Short: i18n.T("wait between polls once all shards are drained, default 1s", "所有 Shard 读完后的轮询间隔，默认 1s")},
+	},
+	APIs: []string{"ListShards", "GetCursor", "PullLogs"},
+	Run:  runSlsTail,
+}
+
+const (
+	// slsTailPullCount is the max log groups of one PullLogs call.
+	slsTailPullCount = 100
+	// slsTailMaxErrors consecutive failures of a shard stop the tail.
+	slsTailMaxErrors = 5
+	// slsTailRelistInterval is how often shards are listed to pick up splits and merges.
+	slsTailRelistInterval = 30 * time.Second
+)
+
+// parseSlsFrom turns the position flag name into the from parameter of GetCursor.
+func parseSlsFrom(name, value string, now time.Time) (string, error) {
+	switch strings.ToLower(value) {
+	case "", "end":
+		return "end", nil
+	case "begin":
+		return "begin", nil
+	}
+	if _, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return value, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return strconv.FormatInt(now.Add(-d).Unix(), 10), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return strconv.FormatInt(t.Unix(), 10), nil
+	}
+	return "", fmt.Errorf("invalid --%s '%s', must be end, begin, a unix time, an RFC 3339 time or a duration", name, value)
+}
+
+// formatSlsTailText renders a flattened log as `<time> key=value ...`, leaving out empty
+// topic and source.
+func formatSlsTailText(fields slsUtils.Fields) string {
+	var sb strings.Builder
+	for _, field := range fields {
+		switch field.Key {
+		case slsUtils.FieldTime:
+			sec, _ := strconv.ParseInt(field.Value, 10, 64)
+			sb.WriteString(time.Unix(sec, 0).Format(time.RFC3339))
+			continue
+		case slsUtils.FieldTopic, slsUtils.FieldSource:
+			if field.Value == "" {
+				continue
+			}
+		}
+		sb.WriteByte(' ')
+		sb.WriteString(field.Key)
+		sb.WriteByte('=')
+		if field.Value == "" || strings.ContainsAny(field.Value, " \t\r\n\"=") {
+			sb.WriteString(strconv.Quote(field.Value))
+		} else {
+			sb.WriteString(field.Value)
+		}
+	}
+	return sb.String()
+}
+
+type slsTailShard struct {
+	id       int
+	readonly bool
+	cursor   string
+	errors   int
+}
+
+type slsTailer struct {
+	client   slsPullClient
+	logstore string
+	filter   *slsUtils.Filter
+	text     bool
+	out      io.Writer
+	errOut   io.Writer
+	mu       sync.Mutex
+}
+
+func (t *slsTailer) print(groups []*slsUtils.LogGroup) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, group := range groups {
+		for _, log := range group.Logs {
+			fields := slsUtils.FlattenLog(group, log)
+			if !t.filter.Match(fields) {
+				continue
+			}
+			if t.text {
+				cli.Println(t.out, formatSlsTailText(fields))
+				continue
+			}
+			line, _ := json.Marshal(fields)
+			cli.Println(t.out, string(line))
+		}
+	}
+}
+
+// pull reads one batch of shard, so that a busy shard only gets its turn like the others.
+// It reports whether the shard has no new data, and whether it is finished, i.e. read only
+// and fully read.
+func (t *slsTailer) pull(shard *slsTailShard) (caughtUp, finished bool, err error) {
+	result, err := t.client.pullLogs(t.logstore, shard.id, shard.cursor, "", slsTailPullCount)
+	if err != nil {
+		return false, false, err
+	}
+	t.print(result.Groups)
+	if result.NextCursor == shard.cursor {
+		return true, shard.readonly, nil
+	}
+	shard.cursor = result.NextCursor
+	return false, false, nil
+}
+
+// sync adds the shards that appeared since the last listing, starting them at from, and
+// drops the ones that are gone.
+func (t *slsTailer) sync(shards map[int]*slsTailShard, finished map[int]bool, from string) error {
+	list, err := t.client.listShards(t.logstore)
+	if err != nil {
+		return err
+	}
+	listed := make(map[int]bool, len(list))
+	for _, s := range list {
+		listed[s.ID] = true
+		if finished[s.ID] {
+			continue
+		}
+		if shard, ok := shards[s.ID]; ok {
+			shard.readonly = s.Status == "readonly"
+			continue
+		}
+		cursor, err := t.client.getCursor(t.logstore, s.ID, from)
+		if err != nil {
+			return err
+		}
+		shards[s.ID] = &slsTailShard{id: s.ID, readonly: s.Status == "readonly", cursor: cursor}
+	}
+	for id := range shards {
+		if !listed[id] {
+			delete(shards, id)
+		}
+	}
+	return nil
+}
+
+func (t *slsTailer) run(ctx context.Context, from string, interval time.Duration) error {
+	shards := map[int]*slsTailShard{}
+	finished := map[int]bool{}
+	if err := t.sync(shards, finished, from); err != nil {
+		return err
+	}
+	listed := time.Now()
+	for {
+		ids := make([]int, 0, len(shards))
+		for id := range shards {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		caughtUp := make([]bool, len(ids))
+		done := make([]bool, len(ids))
+		errs := make([]error, len(ids))
+		var wg sync.WaitGroup
+		for i, id := range ids {
+			wg.Add(1)
+			go func(i int, shard *slsTailShard) {
+				defer wg.Done()
+				caughtUp[i], done[i], errs[i] = t.pull(shard)
+			}(i, shards[id])
+		}
+		wg.Wait()
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		relist := time.Since(listed) >= slsTailRelistInterval
+		idle := true
+		for i, id := range ids {
+			shard := shards[id]
+			if errs[i] != nil {
+				shard.errors++
+				cli.Printf(t.errOut, "shard %d: %v\n", id, errs[i])
+				if shard.errors >= slsTailMaxErrors {
+					return fmt.Errorf("shard %d failed %d times in a row: %v", id, shard.errors, errs[i])
+				}
+				continue
+			}
+			shard.errors = 0
+			if !caughtUp[i] {
+				idle = false
+			}
+			if done[i] {
+				// a split or merge finished this shard, its successors show up in the listing
+				delete(shards, id)
+				finished[id] = true
+				relist = true
+			}
+		}
+		if relist {
+			// shards created after the tail started are read from their beginning
+			if err := t.sync(shards, finished, "begin"); err != nil {
+				cli.Printf(t.errOut, "list shards failed: %v\n", err)
+			} else {
+				listed = time.Now()
+			}
+		}
+
+		if !idle {
+			// some shard still has data, pull the next round right away
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+func runSlsTail(c *Commando, ctx *cli.Context, opts slsCommandOptions) error {
+	fromValue, _ := opts.Get("from")
+	from, err := parseSlsFrom("from", fromValue, time.Now())
+	if err != nil {
+		return err
+	}
+	text := false
+	if format, ok := opts.Get("format"); ok {
+		switch strings.ToLower(format) {
+		case "json":
+		case "text":
+			text = true
+		default:
+			return fmt.Errorf("invalid --format '%s', must be json or text", format)
+		}
+	}
+	var filter *slsUtils.Filter
+	if expr, ok := opts.Get("filter"); ok {
+		if filter, err = slsUtils.ParseFilter(expr); err != nil {
+			return err
+		}
+	}
+	interval := time.Second
+	if value, ok := opts.Get("interval"); ok {
+		if interval, err = time.ParseDuration(value); err != nil || interval <= 0 {
+			return fmt.Errorf("invalid --interval '%s', must be a duration such as 500ms or 2s", value)
+		}
+	}
+
+	project, _ := opts.Get("project")
+	logstore, _ := opts.Get("logstore")
+	client, err := newSlsPullClient(ctx, &c.profile, project)
+	if err != nil {
+		return err
+	}
+	tailCtx, stop := slsSignalContext()
+	defer stop()
+	t := &slsTailer{
+		client:   client,
+		logstore: logstore,
+		filter:   filter,
+		text:     text,
+		out:      ctx.Stdout(),
+		errOut:   ctx.Stderr(),
+	}
+	return t.run(tailCtx, from, interval)
+}
@@ -0,0 +1,333 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	openapiClient "github.com/alibabacloud-go/darabonba-openapi/v2/client"
+	openapiutil "github.com/alibabacloud-go/darabonba-openapi/v2/utils"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/config"
+	slsUtils "github.com/aliyun/aliyun-cli/v3/sls"
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLogGroup(topic string, logs ...map[string]string) *slsUtils.LogGroup {
+	group := &slsUtils.LogGroup{Topic: proto.String(topic)}
+	for _, contents := range logs {
+		keys := make([]string, 0, len(contents))
+		for k := range contents {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		log := &slsUtils.Log{Time: proto.Uint32(1712345678)}
+		for _, k := range keys {
+			log.Contents = append(log.Contents, &slsUtils.LogContent{Key: proto.String(k), Value: proto.String(contents[k])})
+		}
+		group.Logs = append(group.Logs, log)
+	}
+	return group
+}
+
+func TestParseSlsFrom(t *testing.T) {
+	now := time.Unix(1712345678, 0)
+	cases := map[string]string{
+		"":                     "end",
+		"END":                  "end",
+		"begin":                "begin",
+		"1712345000":           "1712345000",
+		"15m":                  "1712344778",
+		"2024-04-05T19:34:38Z": "1712345678",
+	}
+	for value, expected := range cases {
+		actual, err := parseSlsFrom("from", value, now)
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, actual, value)
+	}
+	_, err := parseSlsFrom("from", "yesterday", now)
+	assert.EqualError(t, err, "invalid --from 'yesterday', must be end, begin, a unix time, an RFC 3339 time or a duration")
+}
+
+func TestFormatSlsTailText(t *testing.T) {
+	group := newTestLogGroup("", map[string]string{"level": "ERROR", "msg": "timed out", "empty": ""})
+	group.LogTags = []*slsUtils.LogTag{{Key: proto.String("host"), Value: proto.String("web-1")}}
+	line := formatSlsTailText(slsUtils.FlattenLog(group, group.Logs[0]))
+	assert.Equal(t, time.Unix(1712345678, 0).Format(time.RFC3339)+` __tag__:host=web-1 empty="" level=ERROR msg="timed out"`, line)
+}
+
+func TestSlsClient(t *testing.T) {
+	var requests []*HttpContext
+	responses := []map[string]interface{}{
+		{"body": []any{
+			map[string]any{"shardID": float64(0), "status": "readwrite"},
+			map[string]any{"shardID": float64(1), "status": "readonly"},
+		}},
+		{"body": map[string]any{"cursor": "MTIz"}},
+		{
+			"headers": map[string]any{"x-log-cursor": "NDU2", "x-log-count": "1"},
+			"body":    base64.StdEncoding.EncodeToString(mustMarshalLogGroupList(t, newTestLogGroup("app", map[string]string{"k": "v"}))),
+		},
+		{"headers": map[string]any{"x-log-cursor": "NDU2"}, "body": ""},
+		{"headers": map[string]any{}, "body": ""},
+	}
+	origExecute := httpContextExecuteFunc
+	defer func() { httpContextExecuteFunc = origExecute }()
+	httpContextExecuteFunc = func(a *HttpContext) (map[string]interface{}, error) {
+		requests = append(requests, a)
+		resp := responses[0]
+		responses = responses[1:]
+		return resp, nil
+	}
+
+	base := &HttpContext{
+		openapiRequest: &openapiutil.OpenApiRequest{Headers: map[string]*string{"x-custom": tea.String("1")}},
+		openapiParams:  &openapiClient.Params{BodyType: tea.String("json")},
+	}
+	client := &slsClient{base: base, project: "p"}
+
+	shards, err := client.listShards("l")
+	require.NoError(t, err)
+	assert.Equal(t, []slsShard{{ID: 0, Status: "readwrite"}, {ID: 1, Status: "readonly"}}, shards)
+	assert.Equal(t, "/logstores/l/shards", tea.StringValue(requests[0].openapiParams.Pathname))
+	assert.Equal(t, "p", tea.StringValue(requests[0].openapiRequest.HostMap["project"]))
+
+	cursor, err := client.getCursor("l", 1, "begin")
+	require.NoError(t, err)
+	assert.Equal(t, "MTIz", cursor)
+	assert.Equal(t, "cursor", tea.StringValue(requests[1].openapiRequest.Query["type"]))
+	assert.Equal(t, "begin", tea.StringValue(requests[1].openapiRequest.Query["from"]))
+
+	result, err := client.pullLogs("l", 1, cursor, "", 10)
+	require.NoError(t, err)
+	assert.Equal(t, "NDU2", result.NextCursor)
+	require.Len(t, result.Groups, 1)
+	assert.Equal(t, "app", result.Groups[0].GetTopic())
+	assert.Equal(t, "/logstores/l/shards/1", tea.StringValue(requests[2].openapiParams.Pathname))
+	assert.Equal(t, "10", tea.StringValue(requests[2].openapiRequest.Query["count"]))
+	assert.Equal(t, "byte", tea.StringValue(requests[2].openapiParams.BodyType))
+
+	result, err = client.pullLogs("l", 1, "NDU2", "", 10)
+	require.NoError(t, err)
+	assert.Empty(t, result.Groups)
+	_, err = client.pullLogs("l", 1, "NDU2", "", 10)
+	assert.EqualError(t, err, "invalid PullLogs response: no next cursor")
+
+	// 每个请求使用独立的 header，不影响共享的 base
+	assert.Equal(t, "1", tea.StringValue(requests[2].openapiRequest.Headers["x-custom"]))
+	assert.Len(t, base.openapiRequest.Headers, 1)
+	assert.Equal(t, "json", tea.StringValue(base.openapiParams.BodyType))
+}
+
+func mustMarshalLogGroupList(t *testing.T, groups ...*slsUtils.LogGroup) []byte {
+	data, err := proto.Marshal(&slsUtils.LogGroupList{LogGroups: groups})
+	require.NoError(t, err)
+	return data
+}
+
+// fakeSlsShard 的游标是日志组的下标
+type fakeSlsShard struct {
+	status     string
+	groups     []*slsUtils.LogGroup
+	listedFrom int // 从第几次 ListShards 开始出现
+}
+
+type fakeSlsPullClient struct {
+	mu       sync.Mutex
+	shards   map[int]*fakeSlsShard
+	lists    int
+	cursors  map[int]string
+	failures int
+	// onDrained 在某个 shard 读完时调用
+	onDrained func(shard int)
+	// onPull 返回错误时模拟请求失败
+	onPull func(shard int, cursor string) error
+}
+
+func (f *fakeSlsPullClient) listShards(logstore string) ([]slsShard, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lists++
+	var shards []slsShard
+	for id, s := range f.shards {
+		if f.lists >= s.listedFrom {
+			shards = append(shards, slsShard{ID: id, Status: s.status})
+		}
+	}
+	sort.Slice(shards, func(i, j int) bool { return shards[i].ID < shards[j].ID })
+	return shards, nil
+}
+
+func (f *fakeSlsPullClient) getCursor(logstore string, shard int, from string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cursors[shard] = from
+	if from == "end" {
+		return strconv.Itoa(len(f.shards[shard].groups)), nil
+	}
+	return "0", nil
+}
+
+func (f *fakeSlsPullClient) pullLogs(logstore string, shard int, cursor, endCursor string, count int) (*slsPullResult, error) {
+	f.mu.Lock()
+	if f.failures > 0 {
+		f.failures--
+		f.mu.Unlock()
+		return nil, errors.New("ServerBusy")
+	}
+	groups := f.shards[shard].groups
+	i, _ := strconv.Atoi(cursor)
+	f.mu.Unlock()
+	if f.onPull != nil {
+		if err := f.onPull(shard, cursor); err != nil {
+			return nil, err
+		}
+	}
+	if end, err := strconv.Atoi(endCursor); err == nil && end < len(groups) {
+		groups = groups[:end]
+	}
+	if i >= len(groups) {
+		if f.onDrained != nil {
+			f.onDrained(shard)
+		}
+		return &slsPullResult{NextCursor: cursor}, nil
+	}
+	return &slsPullResult{Groups: groups[i : i+1], NextCursor: strconv.Itoa(i + 1)}, nil
+}
+
+func withFakeSlsPullClient(t *testing.T, client *fakeSlsPullClient) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	origClient, origContext := newSlsPullClient, slsSignalContext
+	newSlsPullClient = func(*cli.Context, *config.Profile, string) (slsPullClient, error) { return client, nil }
+	slsSignalContext = func() (context.Context, context.CancelFunc) { return context.WithCancel(ctx) }
+	t.Cleanup(func() {
+		cancel()
+		newSlsPullClient, slsSignalContext = origClient, origContext
+	})
+	return cancel
+}
+
+func TestRunSlsTail(t *testing.T) {
+	t.Run("follows a split", func(t *testing.T) {
+		client := &fakeSlsPullClient{
+			cursors: map[int]string{},
+			shards: map[int]*fakeSlsShard{
+				0: {status: "readwrite", groups: []*slsUtils.LogGroup{
+					newTestLogGroup("app", map[string]string{"level": "INFO", "msg": "a"}),
+					newTestLogGroup("app", map[string]string{"level": "ERROR", "msg": "b"}),
+				}},
+				1: {status: "readonly", groups: []*slsUtils.LogGroup{
+					newTestLogGroup("app", map[string]string{"level": "ERROR", "msg": "c"}),
+				}},
+				2: {status: "readwrite", listedFrom: 2, groups: []*slsUtils.LogGroup{
+					newTestLogGroup("app", map[string]string{"level": "ERROR", "msg": "d"}),
+				}},
+			},
+		}
+		cancel := withFakeSlsPullClient(t, client)
+		client.onDrained = func(shard int) {
+			if shard == 2 {
+				cancel()
+			}
+		}
+
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		ctx := cli.NewCommandContext(stdout, stderr)
+		err := runSlsTail(&Commando{}, ctx, slsCommandOptions{
+			"project": "p", "logstore": "l", "from": "begin", "filter": "level=ERROR", "interval": "1ms",
+		})
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+		sort.Strings(lines)
+		assert.Equal(t, []string{
+			`{"__time__":"1712345678","__topic__":"app","__source__":"","level":"ERROR","msg":"b"}`,
+			`{"__time__":"1712345678","__topic__":"app","__source__":"","level":"ERROR","msg":"c"}`,
+			`{"__time__":"1712345678","__topic__":"app","__source__":"","level":"ERROR","msg":"d"}`,
+		}, lines)
+		// 分裂出的新 shard 从头读取
+		assert.Equal(t, map[int]string{0: "begin", 1: "begin", 2: "begin"}, client.cursors)
+		assert.Empty(t, stderr.String())
+	})
+
+	t.Run("busy shard does not hold back the others", func(t *testing.T) {
+		busy := make([]*slsUtils.LogGroup, 1000)
+		for i := range busy {
+			busy[i] = newTestLogGroup("app", map[string]string{"msg": "busy"})
+		}
+		client := &fakeSlsPullClient{
+			cursors: map[int]string{},
+			shards: map[int]*fakeSlsShard{
+				0: {status: "readwrite", groups: busy},
+				1: {status: "readonly", groups: []*slsUtils.LogGroup{
+					newTestLogGroup("app", map[string]string{"msg": "c"}),
+				}},
+				2: {status: "readwrite", listedFrom: 2, groups: []*slsUtils.LogGroup{
+					newTestLogGroup("app", map[string]string{"msg": "d"}),
+				}},
+			},
+		}
+		cancel := withFakeSlsPullClient(t, client)
+		client.onDrained = func(shard int) {
+			if shard == 2 {
+				cancel()
+			}
+		}
+
+		stdout := new(bytes.Buffer)
+		ctx := cli.NewCommandContext(stdout, new(bytes.Buffer))
+		err := runSlsTail(&Commando{}, ctx, slsCommandOptions{"project": "p", "logstore": "l", "from": "begin", "interval": "1h"})
+		require.NoError(t, err)
+		assert.Contains(t, stdout.String(), `"msg":"d"`)
+		// 每轮每个 shard 只读一批，shard 1 读完后下一轮即可发现新 shard
+		assert.Less(t, strings.Count(stdout.String(), `"msg":"busy"`), 10)
+	})
+
+	t.Run("stops after repeated failures", func(t *testing.T) {
+		client := &fakeSlsPullClient{
+			cursors:  map[int]string{},
+			failures: 100,
+			shards:   map[int]*fakeSlsShard{0: {status: "readwrite"}},
+		}
+		withFakeSlsPullClient(t, client)
+		stderr := new(bytes.Buffer)
+		ctx := cli.NewCommandContext(new(bytes.Buffer), stderr)
+		err := runSlsTail(&Commando{}, ctx, slsCommandOptions{"project": "p", "logstore": "l", "interval": "1ms"})
+		assert.EqualError(t, err, "shard 0 failed 5 times in a row: ServerBusy")
+		assert.Equal(t, "end", client.cursors[0])
+		assert.Equal(t, 5, strings.Count(stderr.String(), "shard 0: ServerBusy"))
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		withFakeSlsPullClient(t, &fakeSlsPullClient{})
+		ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+		err := runSlsTail(&Commando{}, ctx, slsCommandOptions{"project": "p", "logstore": "l", "format": "xml"})
+		assert.EqualError(t, err, "invalid --format 'xml', must be json or text")
+		err = runSlsTail(&Commando{}, ctx, slsCommandOptions{"project": "p", "logstore": "l", "filter": "and"})
+		assert.EqualError(t, err, "invalid filter 'and': unexpected 'and'")
+		err = runSlsTail(&Commando{}, ctx, slsCommandOptions{"project": "p", "logstore": "l", "interval": "0"})
+		assert.EqualError(t, err, "invalid --interval '0', must be a duration such as 500ms or 2s")
+	})
+}
@@ -0,0 +1,655 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+var specChineseBucketConfig = SpecText{
+	synopsisText: "导出、比较、应用bucket的全部配置",
+
+	paramText: "command_name bucket_url [local_file] [options]",
+
+	syntaxText: `
+    ossutil bucket-config export oss://bucket [local_file] [--config-format yaml|json] [-f]
+    ossutil bucket-config diff oss://bucket local_file
+    ossutil bucket-config apply oss://bucket local_file [-f]
+`,
+	detailHelpText: `
+    bucket-config命令把bucket的cors、lifecycle、referer、website、policy、logging、encryption、
+    versioning、tagging、worm、replication、inventory、qos、cname、style、access-monitor配置
+    放在一个yaml或json文档中，便于把bucket配置放到git中管理，并在不同环境之间同步
+
+    文档的每个key是一种配置，值是该配置的内容，和对应命令put/get的xml结构相同（policy为json）；
+    值为null表示没有该配置。inventory和style的值以id和名字为key，每个清单或样式一项。
+    worm、replication、cname只导出和比较，需要使用各自的命令修改
+
+用法:
+    该命令有三种用法:
+
+    1) ossutil bucket-config export oss://bucket [local_file] [--config-format yaml|json] [-f]
+        导出bucket的全部配置，local_file为空时输出到屏幕，文件已存在时需要确认是否覆盖，-f跳过确认
+
+    2) ossutil bucket-config diff oss://bucket local_file
+        比较bucket的配置和local_file，逐项输出新增(+)、修改(~)、删除(-)的内容
+        local_file中没有出现的配置不做比较
+
+    3) ossutil bucket-config apply oss://bucket local_file [-f]
+        先输出和diff相同的执行计划，确认后只修改有差异的配置；-f跳过确认
+        local_file中没有出现的配置保持不变，值为null的配置会被删除
+        配置逐项修改，某项失败时立即停止并报错，已修改的配置不会回滚；worm、replication、cname
+        的差异不会修改，存在这些差异时命令最后报错并列出它们
+`,
+	sampleText: `
+    1) 导出bucket配置到文件
+       ossutil bucket-config export oss://bucket bucket.yaml
+
+    2) 比较另一个bucket和该文件的差异
+       ossutil bucket-config diff oss://bucket-prod bucket.yaml
+
+    3) 把文件中的配置应用到另一个bucket
+       ossutil bucket-config apply oss://bucket-prod bucket.yaml
+`,
+}
+
+var specEnglishBucketConfig = SpecText{
+	synopsisText: "Export, diff and apply all configurations of the bucket",
+
+	paramText: "command_name bucket_url [local_file] [options]",
+
+	syntaxText: `
+    ossutil bucket-config export oss://bucket [local_file] [--config-format yaml|json] [-f]
+    ossutil bucket-config diff oss://bucket local_file
+    ossutil bucket-config apply oss://bucket local_file [-f]
+`,
+	detailHelpText: `
+    The bucket-config command keeps the cors, lifecycle, referer, website, policy, logging,
+    encryption, versioning, tagging, worm, replication, inventory, qos, cname, style and
+    access-monitor configurations of the bucket in one yaml or json document, so the bucket
+    settings can live in git and be promoted across environments
+
+    Every key of the document is a configuration, whose value has the same structure as the xml
+    put and get by its own command(json for policy); null means not configured. The values of
+    inventory and style are keyed by the id and the name, one item per inventory or style.
+    worm, replication and cname are only exported and compared, change them by their own commands
+
+Usage:
+    There are 3 usages for this command:
+
+    1) ossutil bucket-config export oss://bucket [local_file] [--config-format yaml|json] [-f]
+       This command exports all configurations of the bucket, to the screen if local_file is empty.
+       It asks before overwriting an existing file, -f skips the confirmation
+
+    2) ossutil bucket-config diff oss://bucket local_file
+       This command compares the bucket with local_file, and prints what would be added(+),
+       changed(~) and deleted(-). Configurations missing from local_file are not compared
+
+    3) ossutil bucket-config apply oss://bucket local_file [-f]
+       This command prints the same plan as diff, and changes only the configurations which
+       differ after confirmation; -f skips the confirmation. Configurations missing from
+       local_file are left as they are, and those set to null are deleted.
+       The configurations are changed one by one, it stops at the first failure and doesn't
+       roll back the ones already changed; differences of worm, replication and cname are not
+       changed, the command fails at the end listing them if there are any
+`,
+	sampleText: `
+    1) export the bucket configuration to a file
+       ossutil bucket-config export oss://bucket bucket.yaml
+
+    2) compare another bucket with the file
+       ossutil bucket-config diff oss://bucket-prod bucket.yaml
+
+    3) apply the configuration of the file to another bucket
+       ossutil bucket-config apply oss://bucket-prod bucket.yaml
+`,
+}
+
+// bucketConfigResource describes how a configuration of a bucket config document is read and
+// written by the sub resource of the bucket
+type bucketConfigResource struct {
+	name  string
+	param string
+	// the xml root of the put body, the policy is json
+	root      string
+	json      bool
+	deletable bool
+	// changed only by the command, such as worm whose id is given by oss
+	command string
+	// a collection is listed by listKey, and every item is put and deleted by itemParam=id
+	listKey    string
+	idKey      string
+	itemParam  string
+	ignoreKeys []string
+}
+
+// bucketConfigResources are in the order applied, versioning goes first since lifecycle,
+// inventory and others may depend on it
+var bucketConfigResources = []bucketConfigResource{
+	{name: "versioning", param: "versioning", root: "VersioningConfiguration"},
+	{name: "cors", param: "cors", root: "CORSConfiguration", deletable: true},
+	{name: "lifecycle", param: "lifecycle", root: "LifecycleConfiguration", deletable: true},
+	{name: "referer", param: "referer", root: "RefererConfiguration"},
+	{name: "website", param: "website", root: "WebsiteConfiguration", deletable: true},
+	{name: "policy", param: "policy", json: true, deletable: true},
+	{name: "logging", param: "logging", root: "BucketLoggingStatus", deletable: true},
+	{name: "encryption", param: "encryption", root: "ServerSideEncryptionRule", deletable: true},
+	{name: "tagging", param: "tagging", root: "Tagging", deletable: true},
+	{name: "worm", param: "worm", command: "worm"},
+	{name: "replication", param: "replication", command: "replication"},
+	{name: "inventory", param: "inventory", root: "InventoryConfiguration", deletable: true,
+		listKey: "InventoryConfiguration", idKey: "Id", itemParam: "inventoryId"},
+	{name: "qos", param: "qosInfo", root: "QoSConfiguration", deletable: true},
+	{name: "cname", param: "cname", command: "bucket-cname", ignoreKeys: []string{"Bucket", "Owner"}},
+	{name: "style", param: "style", root: "Style", deletable: true,
+		listKey: "Style", idKey: "Name", itemParam: "styleName", ignoreKeys: []string{"CreateTime", "LastModifyTime"}},
+	{name: "access-monitor", param: "accessmonitor", root: "AccessMonitorConfiguration"},
+}
+
+const (
+	bucketConfigAdd    = "+"
+	bucketConfigChange = "~"
+	bucketConfigDelete = "-"
+)
+
+// bucketConfigChangeType is a change of a configuration, or of an item of a collection
+type bucketConfigChangeType struct {
+	resource *bucketConfigResource
+	id       string
+	action   string
+	current  interface{}
+	desired  interface{}
+}
+
+func (change bucketConfigChangeType) key() string {
+	if change.id != "" {
+		return change.resource.name + "/" + change.id
+	}
+	return change.resource.name
+}
+
+type BucketConfigCommand struct {
+	command    Command
+	bucketName string
+	force      bool
+}
+
+var bucketConfigCommand = BucketConfigCommand{
+	command: Command{
+		name:        "bucket-config",
+		nameAlias:   []string{"bucket-config"},
+		minArgc:     2,
+		maxArgc:     3,
+		specChinese: specChineseBucketConfig,
+		specEnglish: specEnglishBucketConfig,
+		group:       GroupTypeNormalCommand,
+		validOptionNames: []string{
+			OptionConfigFile,
+			OptionEndpoint,
+			OptionAccessKeyID,
+			OptionAccessKeySecret,
+			OptionSTSToken,
+			OptionProxyHost,
+			OptionProxyUser,
+			OptionProxyPwd,
+			OptionLogLevel,
+			OptionConfigFormat,
+			OptionForce,
+			OptionPassword,
+			OptionMode,
+			OptionECSRoleName,
+			OptionTokenTimeout,
+			OptionRamRoleArn,
+			OptionRoleSessionName,
+			OptionExternalId,
+			OptionReadTimeout,
+			OptionConnectTimeout,
+			OptionSTSRegion,
+			OptionSkipVerifyCert,
+			OptionUserAgent,
+			OptionSignVersion,
+			OptionRegion,
+			OptionCloudBoxID,
+			OptionForcePathStyle,
+			OptionInsecure,
+		},
+	},
+}
+
+// function for FormatHelper interface
+func (bcc *BucketConfigCommand) formatHelpForWhole() string {
+	return bcc.command.formatHelpForWhole()
+}
+
+func (bcc *BucketConfigCommand) formatIndependHelp() string {
+	return bcc.command.formatIndependHelp()
+}
+
+// Init simulate inheritance, and polymorphism
+func (bcc *BucketConfigCommand) Init(args []string, options OptionMapType) error {
+	return bcc.command.Init(args, options, bcc)
+}
+
+// RunCommand simulate inheritance, and polymorphism
+func (bcc *BucketConfigCommand) RunCommand() error {
+	strCommand := bcc.command.args[0]
+	if strCommand != "export" && strCommand != "diff" && strCommand != "apply" {
+		return fmt.Errorf("invalid parameter %s,which must be export, diff, apply", strCommand)
+	}
+
+	bucketUrL, err := StorageURLFromString(bcc.command.args[1], "")
+	if err != nil {
+		return err
+	}
+
+	if !bucketUrL.IsCloudURL() {
+		return fmt.Errorf("parameter is not a cloud url,url is %s", bucketUrL.ToString())
+	}
+
+	cloudUrl := bucketUrL.(CloudURL)
+	if cloudUrl.bucket == "" {
+		return fmt.Errorf("bucket name is empty,url is %s", bucketUrL.ToString())
+	}
+
+	bcc.bucketName = cloudUrl.bucket
+	bcc.force, _ = GetBool(OptionForce, bcc.command.options)
+
+	if strCommand == "export" {
+		return bcc.exportBucketConfig()
+	}
+	if len(bcc.command.args) < 3 {
+		return fmt.Errorf("missing parameters,the local bucket config file is empty")
+	}
+	if strCommand == "diff" {
+		return bcc.diffBucketConfig()
+	}
+	return bcc.applyBucketConfig()
+}
+
+func (bcc *BucketConfigCommand) exportBucketConfig() error {
+	bucket, err := bcc.command.ossBucket(bcc.bucketName)
+	if err != nil {
+		return err
+	}
+	doc, err := getBucketConfig(bucket, nil)
+	if err != nil {
+		return err
+	}
+
+	format, _ := GetString(OptionConfigFormat, bcc.command.options)
+	if len(bcc.command.args) < 3 {
+		stdoutData = true
+		return encodeConfigDoc(os.Stdout, doc, format)
+	}
+
+	fileName := bcc.command.args[2]
+	if format == "" && strings.HasSuffix(strings.ToLower(fileName), ".json") {
+		format = ConfigFormatJSON
+	}
+	if _, err = os.Stat(fileName); err == nil && !bcc.force && !bcc.confirm(fmt.Sprintf("bucket config: overwrite \"%s\"(y or N)? ", fileName)) {
+		return nil
+	}
+
+	buf := new(bytes.Buffer)
+	if err = encodeConfigDoc(buf, doc, format); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, buf.Bytes(), 0660)
+}
+
+func (bcc *BucketConfigCommand) diffBucketConfig() error {
+	bucket, changes, err := bcc.planBucketConfig()
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Printf("No changes, the configuration of bucket %s matches %s\n", bucket.BucketName, bcc.command.args[2])
+		return nil
+	}
+	printBucketConfigChanges(os.Stdout, changes)
+	return nil
+}
+
+func (bcc *BucketConfigCommand) applyBucketConfig() error {
+	bucket, changes, err := bcc.planBucketConfig()
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Printf("No changes, the configuration of bucket %s matches %s\n", bucket.BucketName, bcc.command.args[2])
+		return nil
+	}
+	printBucketConfigChanges(os.Stdout, changes)
+	if !bcc.force && !bcc.confirm(fmt.Sprintf("bucket config: apply above changes to bucket %s(y or N)? ", bucket.BucketName)) {
+		return nil
+	}
+
+	return applyBucketConfigChanges(os.Stdout, changes, func(change bucketConfigChangeType) error {
+		return applyBucketConfigChange(bucket, change)
+	})
+}
+
+// applyBucketConfigChanges applies the changes one by one and stops at the first error.
+// Nothing is rolled back, the changes done before the error stay on the bucket. The changes
+// made only by their own commands are skipped, and reported by an error at the end
+func applyBucketConfigChanges(out io.Writer, changes []bucketConfigChangeType, apply func(change bucketConfigChangeType) error) error {
+	skipped := []string{}
+	for _, change := range changes {
+		if change.resource.command != "" {
+			skipped = append(skipped, fmt.Sprintf("%s(ossutil %s)", change.key(), change.resource.command))
+			continue
+		}
+		if err := apply(change); err != nil {
+			return fmt.Errorf("apply %s error: %s, the changes done before it are kept", change.key(), err.Error())
+		}
+		fmt.Fprintf(out, "%s %s done\n", change.action, change.key())
+	}
+	if len(skipped) > 0 {
+		return fmt.Errorf("not applied: %s, change them by their own commands", strings.Join(skipped, ", "))
+	}
+	return nil
+}
+
+// planBucketConfig reads the local file and the bucket, and returns the changes to apply
+func (bcc *BucketConfigCommand) planBucketConfig() (*oss.Bucket, []bucketConfigChangeType, error) {
+	data, err := ioutil.ReadFile(bcc.command.args[2])
+	if err != nil {
+		return nil, nil, err
+	}
+	desired, err := decodeConfigDoc(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse %s error: %s", bcc.command.args[2], err.Error())
+	}
+
+	bucket, err := bcc.command.ossBucket(bcc.bucketName)
+	if err != nil {
+		return nil, nil, err
+	}
+	current, err := getBucketConfig(bucket, desired.keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	changes, err := planBucketConfigChanges(current, desired)
+	return bucket, changes, err
+}
+
+func (bcc *BucketConfigCommand) confirm(prompt string) bool {
+	var val string
+	fmt.Printf(getClearStr(prompt))
+	if _, err := fmt.Scanln(&val); err != nil || (strings.ToLower(val) != "yes" && strings.ToLower(val) != "y") {
+		return false
+	}
+	return true
+}
+
+func findBucketConfigResource(name string) *bucketConfigResource {
+	for i := range bucketConfigResources {
+		if bucketConfigResources[i].name == name {
+			return &bucketConfigResources[i]
+		}
+	}
+	return nil
+}
+
+// getBucketConfig gets the configurations named, or all if names is nil, not configured is nil
+func getBucketConfig(bucket *oss.Bucket, names []string) (*configMap, error) {
+	doc := newConfigMap()
+	for i := range bucketConfigResources {
+		res := &bucketConfigResources[i]
+		if names != nil && !containsString(names, res.name) {
+			continue
+		}
+		value, err := getBucketConfigResource(bucket, res)
+		if err != nil {
+			return nil, fmt.Errorf("get %s of bucket %s error: %s", res.name, bucket.BucketName, err.Error())
+		}
+		doc.set(res.name, value)
+	}
+	return doc, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func getBucketConfigResource(bucket *oss.Bucket, res *bucketConfigResource) (interface{}, error) {
+	if res.listKey != "" {
+		return getBucketConfigCollection(bucket, res)
+	}
+	data, err := doBucketConfig(bucket, http.MethodGet, map[string]interface{}{res.param: nil}, nil)
+	if data == nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if res.json {
+		value, err = jsonToConfigValue(data)
+	} else {
+		_, value, err = xmlToConfigValue(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := value.(*configMap); ok {
+		for _, key := range res.ignoreKeys {
+			m.delete(key)
+		}
+	}
+	if isEmptyConfigValue(value) {
+		return nil, nil
+	}
+	return value, nil
+}
+
+// getBucketConfigCollection lists all items of a collection keyed by their id
+func getBucketConfigCollection(bucket *oss.Bucket, res *bucketConfigResource) (interface{}, error) {
+	items := newConfigMap()
+	token := ""
+	for {
+		params := map[string]interface{}{res.param: nil}
+		if token != "" {
+			params["continuation-token"] = token
+		}
+		data, err := doBucketConfig(bucket, http.MethodGet, params, nil)
+		if data == nil {
+			return nil, err
+		}
+		_, value, err := xmlToConfigValue(data)
+		if err != nil {
+			return nil, err
+		}
+		result, ok := value.(*configMap)
+		if !ok {
+			break
+		}
+
+		// one item is not a list, and an empty result has none
+		value, _ = result.get(res.listKey)
+		list, ok := value.([]interface{})
+		if !ok && value != nil {
+			list = []interface{}{value}
+		}
+		for _, v := range list {
+			item, ok := v.(*configMap)
+			if !ok {
+				continue
+			}
+			id, _ := item.get(res.idKey)
+			item.delete(res.idKey)
+			for _, key := range res.ignoreKeys {
+				item.delete(key)
+			}
+			items.set(fmt.Sprint(id), item)
+		}
+
+		truncated, _ := result.get("IsTruncated")
+		next, _ := result.get("NextContinuationToken")
+		if fmt.Sprint(truncated) != "true" || next == nil || fmt.Sprint(next) == "" {
+			break
+		}
+		token = fmt.Sprint(next)
+	}
+
+	if len(items.keys) == 0 {
+		return nil, nil
+	}
+	return items, nil
+}
+
+// doBucketConfig sends a request of the sub resource, and returns the body, or nil if the
+// configuration does not exist
+func doBucketConfig(bucket *oss.Bucket, method string, params map[string]interface{}, body []byte) ([]byte, error) {
+	var options []oss.Option
+	var reader io.Reader
+	if body != nil {
+		options = append(options, oss.ContentType(http.DetectContentType(body)))
+		reader = bytes.NewReader(body)
+	}
+	resp, err := bucket.Do(method, "", params, options, reader, nil)
+	if err != nil {
+		if serviceErr, ok := err.(oss.ServiceError); ok && serviceErr.StatusCode == 404 && serviceErr.Code != "NoSuchBucket" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+// applyBucketConfigChange puts or deletes a configuration, or an item of a collection
+func applyBucketConfigChange(bucket *oss.Bucket, change bucketConfigChangeType) error {
+	res := change.resource
+	params := map[string]interface{}{res.param: nil}
+	if change.id != "" {
+		params[res.itemParam] = change.id
+	}
+
+	if change.action == bucketConfigDelete {
+		_, err := doBucketConfig(bucket, http.MethodDelete, params, nil)
+		return err
+	}
+
+	var body []byte
+	var err error
+	if res.json {
+		body, err = json.Marshal(change.desired)
+	} else {
+		value := change.desired
+		if change.id != "" && res.itemParam == "inventoryId" {
+			// the put body of an inventory has its id too
+			item := newConfigMap()
+			item.set(res.idKey, change.id)
+			if m, ok := value.(*configMap); ok {
+				for _, key := range m.keys {
+					item.set(key, m.values[key])
+				}
+			}
+			value = item
+		}
+		body, err = configValueToXML(res.root, value)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = doBucketConfig(bucket, http.MethodPut, params, body)
+	return err
+}
+
+// planBucketConfigChanges compares the bucket with the document, the configurations missing
+// from the document are left as they are
+func planBucketConfigChanges(current, desired *configMap) ([]bucketConfigChangeType, error) {
+	for _, name := range desired.keys {
+		if findBucketConfigResource(name) == nil {
+			return nil, fmt.Errorf("unknown bucket configuration %s", name)
+		}
+	}
+
+	changes := []bucketConfigChangeType{}
+	for i := range bucketConfigResources {
+		res := &bucketConfigResources[i]
+		want, ok := desired.get(res.name)
+		if !ok {
+			continue
+		}
+		have, _ := current.get(res.name)
+		if isEmptyConfigValue(want) {
+			want = nil
+		}
+
+		if res.listKey == "" {
+			change, ok := planBucketConfigChange(res, "", have, want)
+			if !ok {
+				continue
+			}
+			if change.action == bucketConfigDelete && !res.deletable && res.command == "" {
+				return nil, fmt.Errorf("%s can't be deleted, set it to the configuration wanted instead", res.name)
+			}
+			changes = append(changes, change)
+			continue
+		}
+
+		haveItems, _ := have.(*configMap)
+		wantItems, ok := want.(*configMap)
+		if want != nil && !ok {
+			return nil, fmt.Errorf("%s must be an object keyed by %s", res.name, res.idKey)
+		}
+		for _, id := range sortedConfigKeys(haveItems, wantItems) {
+			var haveItem, wantItem interface{}
+			if haveItems != nil {
+				haveItem, _ = haveItems.get(id)
+			}
+			if wantItems != nil {
+				wantItem, _ = wantItems.get(id)
+			}
+			if change, ok := planBucketConfigChange(res, id, haveItem, wantItem); ok {
+				changes = append(changes, change)
+			}
+		}
+	}
+	return changes, nil
+}
+
+func planBucketConfigChange(res *bucketConfigResource, id string, have, want interface{}) (bucketConfigChangeType, bool) {
+	change := bucketConfigChangeType{resource: res, id: id, current: have, desired: want}
+	switch {
+	case configValueEqual(have, want):
+		return change, false
+	case have == nil:
+		change.action = bucketConfigAdd
+	case want == nil:
+		change.action = bucketConfigDelete
+	default:
+		change.action = bucketConfigChange
+	}
+	return change, true
+}
+
+// printBucketConfigChanges prints the changes as a line diff of yaml, and a summary
+func printBucketConfigChanges(w io.Writer, changes []bucketConfigChangeType) {
+	counts := map[string]int{}
+	for _, change := range changes {
+		header := change.action + " " + change.key()
+		if change.resource.command != "" {
+			header += fmt.Sprintf(" (change it by ossutil %s)", change.resource.command)
+		}
+		fmt.Fprintln(w, header)
+		for _, line := range diffLines(renderConfigValue(change.current), renderConfigValue(change.desired)) {
+			fmt.Fprintln(w, "    "+line)
+		}
+		counts[change.action]++
+	}
+	fmt.Fprintf(w, "\nPlan: %d to add, %d to change, %d to delete.\n",
+		counts[bucketConfigAdd], counts[bucketConfigChange], counts[bucketConfigDelete])
+}
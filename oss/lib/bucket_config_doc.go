@@ -0,0 +1,419 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configMap is an object of a bucket config document which keeps the order of its keys,
+// the values are strings, bools, numbers, nil, []interface{} or *configMap
+type configMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newConfigMap() *configMap {
+	return &configMap{values: map[string]interface{}{}}
+}
+
+func (m *configMap) set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *configMap) get(key string) (interface{}, bool) {
+	value, ok := m.values[key]
+	return value, ok
+}
+
+func (m *configMap) delete(key string) {
+	if _, ok := m.values[key]; !ok {
+		return
+	}
+	delete(m.values, key)
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+func (m *configMap) MarshalJSON() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteString("{")
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		k, _ := json.Marshal(key)
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteString(":")
+		buf.Write(v)
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+func (m *configMap) MarshalYAML() (interface{}, error) {
+	return configValueToNode(m), nil
+}
+
+// configValueToNode converts a value of a document to a yaml node in order
+func configValueToNode(value interface{}) *yaml.Node {
+	switch v := value.(type) {
+	case *configMap:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, key := range v.keys {
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+				configValueToNode(v.values[key]))
+		}
+		return node
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			node.Content = append(node.Content, configValueToNode(item))
+		}
+		return node
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(v)}
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: fmt.Sprint(v)}
+	}
+}
+
+// configNodeToValue converts a yaml node, or json which is yaml too, to a value of a document
+func configNodeToValue(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return configNodeToValue(node.Content[0])
+	case yaml.AliasNode:
+		return configNodeToValue(node.Alias)
+	case yaml.MappingNode:
+		m := newConfigMap()
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value, err := configNodeToValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			m.set(node.Content[i].Value, value)
+		}
+		return m, nil
+	case yaml.SequenceNode:
+		list := []interface{}{}
+		for _, item := range node.Content {
+			value, err := configNodeToValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	}
+
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// encodeConfigDoc writes the document in yaml or json
+func encodeConfigDoc(w io.Writer, doc *configMap, format string) error {
+	if format == ConfigFormatJSON {
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(configValueToNode(doc)); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// decodeConfigDoc reads a yaml or json document
+func decodeConfigDoc(data []byte) (*configMap, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	value, err := configNodeToValue(&node)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := value.(*configMap)
+	if !ok {
+		return nil, fmt.Errorf("bucket config document must be an object")
+	}
+	return doc, nil
+}
+
+// xmlToConfigValue converts xml to the root name and a value, an element with children is
+// a *configMap whose repeated children are lists, and an element without is its text
+func xmlToConfigValue(data []byte) (string, interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			value, err := xmlElementToConfigValue(decoder)
+			return start.Name.Local, value, err
+		}
+	}
+}
+
+func xmlElementToConfigValue(decoder *xml.Decoder) (interface{}, error) {
+	var m *configMap
+	text := ""
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			value, err := xmlElementToConfigValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if m == nil {
+				m = newConfigMap()
+			}
+			name := t.Name.Local
+			if old, ok := m.get(name); ok {
+				if list, ok := old.([]interface{}); ok {
+					m.set(name, append(list, value))
+				} else {
+					m.set(name, []interface{}{old, value})
+				}
+			} else {
+				m.set(name, value)
+			}
+		case xml.CharData:
+			text += string(t)
+		case xml.EndElement:
+			if m != nil {
+				return m, nil
+			}
+			return strings.TrimSpace(text), nil
+		}
+	}
+}
+
+// configValueToXML converts a value to xml with the root name
+func configValueToXML(root string, value interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteString(xml.Header)
+	if err := writeConfigXML(buf, root, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeConfigXML(buf *bytes.Buffer, name string, value interface{}) error {
+	if list, ok := value.([]interface{}); ok {
+		for _, item := range list {
+			if err := writeConfigXML(buf, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	fmt.Fprintf(buf, "<%s>", name)
+	switch v := value.(type) {
+	case *configMap:
+		for _, key := range v.keys {
+			if err := writeConfigXML(buf, key, v.values[key]); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := xml.EscapeText(buf, []byte(fmt.Sprint(v))); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(buf, "</%s>", name)
+	return nil
+}
+
+// jsonToConfigValue converts json to a value in order
+func jsonToConfigValue(data []byte) (interface{}, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	return configNodeToValue(&node)
+}
+
+// configValueEqual compares two values, the order of keys does not matter, a list of one
+// item equals the item as xml does not tell them apart, and scalars compare by their text
+func configValueEqual(a, b interface{}) bool {
+	if list, ok := a.([]interface{}); ok && len(list) == 1 {
+		a = list[0]
+	}
+	if list, ok := b.([]interface{}); ok && len(list) == 1 {
+		b = list[0]
+	}
+	switch va := a.(type) {
+	case *configMap:
+		vb, ok := b.(*configMap)
+		if !ok || len(va.keys) != len(vb.keys) {
+			return false
+		}
+		for _, key := range va.keys {
+			value, ok := vb.get(key)
+			if !ok || !configValueEqual(va.values[key], value) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		vb, ok := b.([]interface{})
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for i := range va {
+			if !configValueEqual(va[i], vb[i]) {
+				return false
+			}
+		}
+		return true
+	case nil:
+		return b == nil
+	}
+	if b == nil {
+		return false
+	}
+	if _, ok := b.(*configMap); ok {
+		return false
+	}
+	if _, ok := b.([]interface{}); ok {
+		return false
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+// isEmptyConfigValue reports whether a value has no text at all, such as an empty tag set
+func isEmptyConfigValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case *configMap:
+		for _, key := range v.keys {
+			if !isEmptyConfigValue(v.values[key]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		for _, item := range v {
+			if !isEmptyConfigValue(item) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// renderConfigValue renders a value in yaml lines for diff
+func renderConfigValue(value interface{}) []string {
+	if value == nil {
+		return nil
+	}
+	buf := new(bytes.Buffer)
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	encoder.Encode(configValueToNode(value))
+	encoder.Close()
+	return strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+}
+
+// diffLines returns the lines of a line diff from a to b, prefixed by "- ", "+ " or "  "
+func diffLines(a, b []string) []string {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := []string{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, "  "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "- "+a[i])
+			i++
+		default:
+			lines = append(lines, "+ "+b[j])
+			j++
+		}
+	}
+	return lines
+}
+
+// sortedConfigKeys returns the keys of the maps in order
+func sortedConfigKeys(maps ...*configMap) []string {
+	seen := map[string]bool{}
+	keys := []string{}
+	for _, m := range maps {
+		if m == nil {
+			continue
+		}
+		for _, key := range m.keys {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
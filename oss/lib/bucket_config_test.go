@@ -0,0 +1,210 @@
+package lib
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCORSXML = `<?xml version="1.0" encoding="UTF-8"?>
+<CORSConfiguration>
+  <CORSRule>
+    <AllowedOrigin>*</AllowedOrigin>
+    <AllowedMethod>PUT</AllowedMethod>
+    <AllowedMethod>GET</AllowedMethod>
+    <MaxAgeSeconds>100</MaxAgeSeconds>
+  </CORSRule>
+  <ResponseVary>false</ResponseVary>
+</CORSConfiguration>`
+
+func TestConfigValueXML(t *testing.T) {
+	root, value, err := xmlToConfigValue([]byte(testCORSXML))
+	require.NoError(t, err)
+	assert.Equal(t, "CORSConfiguration", root)
+	m := value.(*configMap)
+	assert.Equal(t, []string{"CORSRule", "ResponseVary"}, m.keys)
+	rule := m.values["CORSRule"].(*configMap)
+	// 重复的元素转为列表
+	assert.Equal(t, []interface{}{"PUT", "GET"}, rule.values["AllowedMethod"])
+	assert.Equal(t, "100", rule.values["MaxAgeSeconds"])
+
+	data, err := configValueToXML(root, value)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "<AllowedMethod>PUT</AllowedMethod><AllowedMethod>GET</AllowedMethod>")
+	_, again, err := xmlToConfigValue(data)
+	require.NoError(t, err)
+	assert.True(t, configValueEqual(value, again))
+
+	data, err = configValueToXML("Tagging", mustDecodeConfigDoc(t, "TagSet:\n  Tag:\n    Key: a&b\n    Value: 1\n"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "<Tagging><TagSet><Tag><Key>a&amp;b</Key><Value>1</Value></Tag></TagSet></Tagging>")
+}
+
+func TestConfigDocEncode(t *testing.T) {
+	_, cors, _ := xmlToConfigValue([]byte(testCORSXML))
+	policy, err := jsonToConfigValue([]byte(`{"Version":"1","Statement":[{"Effect":"Allow","Action":["oss:GetObject"]}]}`))
+	require.NoError(t, err)
+	doc := newConfigMap()
+	doc.set("cors", cors)
+	doc.set("policy", policy)
+	doc.set("website", nil)
+
+	out := new(bytes.Buffer)
+	require.NoError(t, encodeConfigDoc(out, doc, ConfigFormatYAML))
+	assert.True(t, strings.HasPrefix(out.String(), "cors:\n  CORSRule:\n    AllowedOrigin: '*'\n"))
+	assert.Contains(t, out.String(), "website: null\n")
+	yamlDoc, err := decodeConfigDoc(out.Bytes())
+	require.NoError(t, err)
+	assert.True(t, configValueEqual(doc, yamlDoc))
+
+	// json 保持 key 的顺序，读取时和 yaml 相同
+	out.Reset()
+	require.NoError(t, encodeConfigDoc(out, doc, ConfigFormatJSON))
+	assert.True(t, strings.HasPrefix(out.String(), "{\n  \"cors\": {\n    \"CORSRule\": {\n      \"AllowedOrigin\": \"*\""))
+	assert.Contains(t, out.String(), `"Version": "1"`)
+	jsonDoc, err := decodeConfigDoc(out.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, doc.keys, jsonDoc.keys)
+	assert.True(t, configValueEqual(doc, jsonDoc))
+
+	_, err = decodeConfigDoc([]byte("- a\n- b\n"))
+	assert.Error(t, err)
+}
+
+func TestConfigValueEqual(t *testing.T) {
+	a := mustDecodeConfigDoc(t, "Rule:\n  ID: r1\n  Days: 30\nOther: x\n")
+	b := mustDecodeConfigDoc(t, "Other: x\nRule:\n- Days: '30'\n  ID: r1\n")
+	// key 的顺序、一个元素的列表和数字的类型不影响比较
+	assert.True(t, configValueEqual(a, b))
+	assert.False(t, configValueEqual(a, mustDecodeConfigDoc(t, "Rule:\n  ID: r1\n  Days: 31\nOther: x\n")))
+	assert.False(t, configValueEqual(a, nil))
+	assert.False(t, configValueEqual("a", []interface{}{"a", "b"}))
+	assert.True(t, configValueEqual(nil, nil))
+
+	assert.True(t, isEmptyConfigValue(mustDecodeConfigDoc(t, "TagSet: ''\n")))
+	assert.False(t, isEmptyConfigValue(mustDecodeConfigDoc(t, "Status: Enabled\n")))
+}
+
+func TestPlanBucketConfigChanges(t *testing.T) {
+	current := mustDecodeConfigDoc(t, `
+versioning:
+  Status: Enabled
+cors:
+  CORSRule:
+    AllowedOrigin: '*'
+tagging:
+  TagSet:
+    Tag:
+      Key: env
+      Value: test
+website: null
+worm:
+  WormId: 1
+inventory:
+  inv1:
+    IsEnabled: 'true'
+  inv2:
+    IsEnabled: 'true'
+`)
+	desired := mustDecodeConfigDoc(t, `
+cors:
+  CORSRule:
+    AllowedOrigin: example.com
+tagging: null
+website:
+  IndexDocument:
+    Suffix: index.html
+worm:
+  WormId: 2
+inventory:
+  inv2:
+    IsEnabled: 'false'
+  inv3:
+    IsEnabled: 'true'
+`)
+	changes, err := planBucketConfigChanges(current, desired)
+	require.NoError(t, err)
+
+	// versioning 没有出现在文档中，不做修改
+	keys := []string{}
+	for _, change := range changes {
+		keys = append(keys, change.action+change.key())
+	}
+	assert.Equal(t, []string{"~cors", "+website", "-tagging", "~worm", "-inventory/inv1", "~inventory/inv2", "+inventory/inv3"}, keys)
+
+	out := new(bytes.Buffer)
+	printBucketConfigChanges(out, changes)
+	assert.Contains(t, out.String(), "~ cors\n      CORSRule:\n    -   AllowedOrigin: '*'\n    +   AllowedOrigin: example.com\n")
+	assert.Contains(t, out.String(), "~ worm (change it by ossutil worm)\n    - WormId: 1\n    + WormId: 2\n")
+	assert.Contains(t, out.String(), "Plan: 2 to add, 3 to change, 2 to delete.")
+
+	changes, err = planBucketConfigChanges(current, current)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	_, err = planBucketConfigChanges(current, mustDecodeConfigDoc(t, "unknown: 1\n"))
+	assert.EqualError(t, err, "unknown bucket configuration unknown")
+	_, err = planBucketConfigChanges(current, mustDecodeConfigDoc(t, "versioning: null\n"))
+	assert.Error(t, err)
+	_, err = planBucketConfigChanges(current, mustDecodeConfigDoc(t, "inventory:\n- a\n"))
+	assert.Error(t, err)
+}
+
+func TestApplyBucketConfigChanges(t *testing.T) {
+	current := mustDecodeConfigDoc(t, `
+cors: null
+worm:
+  WormId: 1
+`)
+	desired := mustDecodeConfigDoc(t, `
+cors:
+  CORSRule:
+    AllowedOrigin: '*'
+website:
+  IndexDocument:
+    Suffix: index.html
+worm:
+  WormId: 2
+`)
+	changes, err := planBucketConfigChanges(current, desired)
+	require.NoError(t, err)
+
+	// worm 不会修改，其余配置修改后报错列出未应用的差异
+	applied := []string{}
+	out := new(bytes.Buffer)
+	err = applyBucketConfigChanges(out, changes, func(change bucketConfigChangeType) error {
+		applied = append(applied, change.key())
+		return nil
+	})
+	assert.EqualError(t, err, "not applied: worm(ossutil worm), change them by their own commands")
+	assert.Equal(t, []string{"cors", "website"}, applied)
+	assert.Equal(t, "+ cors done\n+ website done\n", out.String())
+
+	// 失败时立即停止，已修改的配置保留
+	applied = applied[:0]
+	err = applyBucketConfigChanges(new(bytes.Buffer), changes, func(change bucketConfigChangeType) error {
+		applied = append(applied, change.key())
+		if change.key() == "website" {
+			return errors.New("AccessDenied")
+		}
+		return nil
+	})
+	assert.EqualError(t, err, "apply website error: AccessDenied, the changes done before it are kept")
+	assert.Equal(t, []string{"cors", "website"}, applied)
+}
+
+func TestDiffLines(t *testing.T) {
+	assert.Equal(t, []string{"  a", "- b", "+ c", "  d", "+ e"}, diffLines([]string{"a", "b", "d"}, []string{"a", "c", "d", "e"}))
+	assert.Equal(t, []string{"- a"}, diffLines([]string{"a"}, nil))
+	assert.Empty(t, diffLines(nil, nil))
+}
+
+func mustDecodeConfigDoc(t *testing.T, text string) *configMap {
+	doc, err := decodeConfigDoc([]byte(text))
+	require.NoError(t, err)
+	return doc
+}
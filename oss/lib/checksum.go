@@ -0,0 +1,137 @@
+package lib
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	leveldb "github.com/syndtr/goleveldb/leveldb"
+)
+
+// checksumCache remembers the crc64 of local files, an entry is valid while the
+// modification time and size of the file are unchanged. A nil cache hashes every time.
+type checksumCache struct {
+	db *leveldb.DB
+}
+
+func openChecksumCache(path string) (*checksumCache, error) {
+	if path == "" {
+		homeDir := currentHomeDir()
+		if homeDir == "" {
+			return nil, fmt.Errorf("can not find the home directory, please use --checksum-cache")
+		}
+		path = filepath.Join(homeDir, ChecksumCacheDir)
+	}
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &checksumCache{db: db}, nil
+}
+
+func (c *checksumCache) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.db.Close()
+}
+
+func checksumCachePrefix(info os.FileInfo) string {
+	return fmt.Sprintf("%d%s%d%s", info.ModTime().UnixNano(), SnapshotSep, info.Size(), SnapshotSep)
+}
+
+// fileCRC64 returns the crc64 of the file, info is the result of os.Stat(fileName)
+func (c *checksumCache) fileCRC64(fileName string, info os.FileInfo) (uint64, error) {
+	absPath, err := filepath.Abs(fileName)
+	if err != nil {
+		return 0, err
+	}
+	prefix := checksumCachePrefix(info)
+	if c != nil {
+		if value, err := c.db.Get([]byte(absPath), nil); err == nil && strings.HasPrefix(string(value), prefix) {
+			if crc, err := strconv.ParseUint(strings.TrimPrefix(string(value), prefix), 10, 64); err == nil {
+				return crc, nil
+			}
+		}
+	}
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	crc, err := crc64Of(f)
+	if err != nil {
+		return 0, err
+	}
+	if c != nil {
+		if err := c.db.Put([]byte(absPath), []byte(prefix+strconv.FormatUint(crc, 10)), nil); err != nil {
+			LogError("save checksum of %s error: %s\n", absPath, err.Error())
+		}
+	}
+	return crc, nil
+}
+
+// objectCRC64 returns the size and crc64 of an object from its headers, ok is false when
+// oss does not know its crc64, e.g. it was uploaded before oss supported crc64
+func objectCRC64(props http.Header) (size int64, crc uint64, ok bool) {
+	size, err := strconv.ParseInt(props.Get(oss.HTTPHeaderContentLength), 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	crc, err = strconv.ParseUint(props.Get(oss.HTTPHeaderOssCRC64), 10, 64)
+	if err != nil {
+		return size, 0, false
+	}
+	return size, crc, true
+}
+
+// compareContent compares the local file with the object with the headers props, it
+// returns ReasonSame, ReasonSizeDiff or ReasonChecksumDiff. Directories are the same as
+// empty objects.
+func (cc *CopyCommand) compareContent(fileName string, props http.Header) string {
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return ReasonNew
+	}
+	size, crc, ok := objectCRC64(props)
+	if info.IsDir() {
+		if props.Get(oss.HTTPHeaderContentLength) == "0" {
+			return ReasonSame
+		}
+		return ReasonSizeDiff
+	}
+	if size != info.Size() {
+		return ReasonSizeDiff
+	}
+	if !ok {
+		return ReasonChecksumDiff
+	}
+	fileCRC, err := cc.cpOption.checksumCache.fileCRC64(fileName, info)
+	if err != nil {
+		LogError("checksum of %s error: %s\n", fileName, err.Error())
+		return ReasonChecksumDiff
+	}
+	LogInfo("checksum of %s: %d, object: %d\n", fileName, fileCRC, crc)
+	if fileCRC != crc {
+		return ReasonChecksumDiff
+	}
+	return ReasonSame
+}
+
+// compareObjectContent compares two objects by size and crc64 like compareContent
+func compareObjectContent(srcProps, destProps http.Header) string {
+	srcSize, srcCRC, srcOK := objectCRC64(srcProps)
+	destSize, destCRC, destOK := objectCRC64(destProps)
+	if srcProps.Get(oss.HTTPHeaderContentLength) != destProps.Get(oss.HTTPHeaderContentLength) || srcSize != destSize {
+		return ReasonSizeDiff
+	}
+	if !srcOK || !destOK || srcCRC != destCRC {
+		return ReasonChecksumDiff
+	}
+	return ReasonSame
+}
@@ -0,0 +1,89 @@
+package lib
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCRC64Of(t *testing.T) {
+	crc, err := crc64Of(strings.NewReader("this is content"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2863152195715871371), crc)
+}
+
+func TestChecksumCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := openChecksumCache(filepath.Join(dir, "cache"))
+	require.NoError(t, err)
+	defer cache.Close()
+
+	fileName := filepath.Join(dir, "a.txt")
+	require.NoError(t, os.WriteFile(fileName, []byte("this is content"), 0644))
+	mtime := time.Unix(1712345678, 0)
+	require.NoError(t, os.Chtimes(fileName, mtime, mtime))
+	info, err := os.Stat(fileName)
+	require.NoError(t, err)
+	crc, err := cache.fileCRC64(fileName, info)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2863152195715871371), crc)
+
+	// 大小和修改时间不变时使用缓存，不重新计算
+	require.NoError(t, os.WriteFile(fileName, []byte("THIS IS CONTENT"), 0644))
+	require.NoError(t, os.Chtimes(fileName, mtime, mtime))
+	info, _ = os.Stat(fileName)
+	crc, err = cache.fileCRC64(fileName, info)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2863152195715871371), crc)
+
+	// 修改时间变化后重新计算
+	mtime = mtime.Add(time.Second)
+	require.NoError(t, os.Chtimes(fileName, mtime, mtime))
+	info, _ = os.Stat(fileName)
+	crc, err = cache.fileCRC64(fileName, info)
+	require.NoError(t, err)
+	expected, _ := crc64Of(strings.NewReader("THIS IS CONTENT"))
+	assert.Equal(t, expected, crc)
+
+	// nil cache 每次都计算
+	var nilCache *checksumCache
+	crc, err = nilCache.fileCRC64(fileName, info)
+	require.NoError(t, err)
+	assert.Equal(t, expected, crc)
+	assert.NoError(t, nilCache.Close())
+}
+
+func TestCompareContent(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "a.txt")
+	require.NoError(t, os.WriteFile(fileName, []byte("this is content"), 0644))
+	props := func(size, crc string) http.Header {
+		header := http.Header{}
+		header.Set(oss.HTTPHeaderContentLength, size)
+		if crc != "" {
+			header.Set(oss.HTTPHeaderOssCRC64, crc)
+		}
+		return header
+	}
+
+	cc := &CopyCommand{}
+	assert.Equal(t, ReasonSame, cc.compareContent(fileName, props("15", "2863152195715871371")))
+	assert.Equal(t, ReasonChecksumDiff, cc.compareContent(fileName, props("15", "1")))
+	assert.Equal(t, ReasonSizeDiff, cc.compareContent(fileName, props("16", "2863152195715871371")))
+	// 没有crc64的object总是重新传输
+	assert.Equal(t, ReasonChecksumDiff, cc.compareContent(fileName, props("15", "")))
+	assert.Equal(t, ReasonNew, cc.compareContent(filepath.Join(dir, "missing"), props("15", "2863152195715871371")))
+	assert.Equal(t, ReasonSame, cc.compareContent(dir, props("0", "")))
+
+	assert.Equal(t, ReasonSame, compareObjectContent(props("15", "2863152195715871371"), props("15", "2863152195715871371")))
+	assert.Equal(t, ReasonChecksumDiff, compareObjectContent(props("15", "2863152195715871371"), props("15", "1")))
+	assert.Equal(t, ReasonSizeDiff, compareObjectContent(props("15", "2863152195715871371"), props("16", "2863152195715871371")))
+	assert.Equal(t, ReasonChecksumDiff, compareObjectContent(props("15", ""), props("15", "")))
+}
@@ -72,6 +72,7 @@ func NewOssCommand() *cli.Command {
 		lcbCommand.command,
 		bucketAccessMonitorCommand.command,
 		bucketResourceGroupCommand.command,
+		bucketConfigCommand.command,
 	}
 
 	for _, cmd := range cmds {
@@ -913,5 +913,6 @@ func GetAllCommands() []interface{} {
 		&lcbCommand,
 		&bucketAccessMonitorCommand,
 		&bucketResourceGroupCommand,
+		&bucketConfigCommand,
 	}
 }
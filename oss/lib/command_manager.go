@@ -13,6 +13,9 @@ import (
 
 var commandLine string
 
+// stdoutData is set by the commands writing data to stdout, which the elapsed time would corrupt
+var stdoutData bool
+
 func LogEnd(startT time.Time) {
 	LogInfo("ossutil run end,cost:%d(ms).\n", time.Now().UnixNano()/1000/1000-startT.UnixNano()/1000/1000)
 	UnInitLogger()
@@ -59,7 +62,7 @@ func ParseAndRunCommand() error {
 		LogError("%s.\n", err.Error())
 		return err
 	}
-	if showElapse {
+	if showElapse && !stdoutData {
 		te := time.Now().UnixNano()
 		fmt.Printf("\n%.6f(s) elapsed\n", float64(te-ts)/1e9)
 		return nil
@@ -32,6 +32,13 @@ const (
 	OptionBigFileThreshold           = "bigfileThreshold"
 	OptionCheckpointDir              = "checkpointDir"
 	OptionSnapshotPath               = "snapshotPath"
+	OptionChecksum                   = "checksum"
+	OptionChecksumCache              = "checksumCache"
+	OptionDryRun                     = "dryrun"
+	OptionReportFormat               = "reportFormat"
+	OptionWatch                      = "watch"
+	OptionWatchInterval              = "watchInterval"
+	OptionConfigFormat               = "configFormat"
 	OptionRetryTimes                 = "retryTimes"
 	OptionRoutines                   = "routines"
 	OptionParallel                   = "parallel"
@@ -171,8 +178,14 @@ const (
 	MaxInt64                int64  = int64(MaxUint64 >> 1)
 	ReportPrefix                   = "ossutil_report_"
 	ReportSuffix                   = ".report"
+	ReportJSONSuffix               = ".json"
+	ReportFormatText               = "text"
+	ReportFormatJSON               = "json"
+	ConfigFormatYAML               = "yaml"
+	ConfigFormatJSON               = "json"
 	DefaultOutputDir               = "ossutil_output"
 	CheckpointDir                  = ".ossutil_checkpoint"
+	ChecksumCacheDir               = ".ossutil_checksum_cache"
 	CheckpointSep                  = "---"
 	SnapshotConnector              = "==>"
 	SnapshotSep                    = "#"
@@ -197,6 +210,12 @@ const (
 	MinRoutines             int64  = 1
 	MaxParallel             int64  = 10000
 	MinParallel             int64  = 1
+	StdioURL                       = "-"
+	DefaultStreamPartSize   int64  = 8388608
+	DefaultStreamParallel   int    = 4
+	WatchInterval           int    = 2
+	MaxWatchInterval        int64  = 3600
+	MinWatchInterval        int64  = 1
 	DefaultHashType         string = "crc64"
 	MD5HashType             string = "md5"
 	LogFilePrefix                  = "ossutil_log_"
@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
@@ -48,9 +49,14 @@ type copyOptionType struct {
 	routines          int64
 	reporter          *Reporter
 	snapshotldb       *leveldb.DB
+	checksumCache     *checksumCache
+	plan              *planPrinter
+	retries           *sync.Map
 	recursive         bool
 	force             bool
 	update            bool
+	checksum          bool
+	dryrun            bool
 	ctnu              bool
 	payerOptions      []oss.Option
 	partitionInfo     string
@@ -64,6 +70,7 @@ type copyOptionType struct {
 	tagging           string
 	opType            operationType
 	bSyncCommand      bool
+	afterCopy         func() error
 	startTime         int64
 	endTime           int64
 }
@@ -152,9 +159,11 @@ var specChineseCopy = SpecText{
 	paramText: "src_url dest_url [options]",
 
 	syntaxText: ` 
-    ossutil cp file_url cloud_url  [-r] [-f] [-u] [--enable-symlink-dir] [--disable-all-symlink] [--disable-ignore-error] [--only-current-dir] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir] [--payer requester]
-    ossutil cp cloud_url file_url  [-r] [-f] [-u] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] [--payer requester] [--version-id versionId]
-    ossutil cp cloud_url cloud_url [-r] [-f] [-u] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--payer requester] [--version-id versionId]
+    ossutil cp file_url cloud_url  [-r] [-f] [-u] [--checksum] [--enable-symlink-dir] [--disable-all-symlink] [--disable-ignore-error] [--only-current-dir] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir] [--payer requester]
+    ossutil cp cloud_url file_url  [-r] [-f] [-u] [--checksum] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] [--payer requester] [--version-id versionId]
+    ossutil cp cloud_url cloud_url [-r] [-f] [-u] [--checksum] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--payer requester] [--version-id versionId]
+    ossutil cp - cloud_url [--part-size=size] [--parallel=n] [--payer requester]
+    ossutil cp cloud_url - [--part-size=size] [--parallel=n] [--payer requester] [--version-id versionId]
 `,
 
 	detailHelpText: ` 
@@ -171,6 +180,16 @@ var specChineseCopy = SpecText{
 
     注意：在oss间拷贝文件，目前只支持拷贝object，不支持拷贝未complete的Multipart。
 
+    file_url为-时表示标准输入或标准输出：
+        tar czf - dir | ossutil cp - oss://bucket/backup.tgz
+        ossutil cp oss://bucket/backup.tgz - | tar xzf -
+    从标准输入上传时，由于长度未知，ossutil按--part-size（默认8MB）读取分片并进行分片上传，
+    内存中最多有--parallel（默认4）个分片，分片数最多为10000，--part-size不能小于100KB，不足
+    一个分片时直接上传。标准输入用于传输数据，无法询问是否替换，因此目标object已存在时必须指定
+    --force，否则报错退出；--update、--snapshot-path等选项也不生效。下载到标准输出时，ossutil
+    按--part-size并发下载多个范围并按顺序输出。两种情况下都会在最后校验整个
+    object的crc64（--disable-crc64时不校验），并且不输出进度和耗时，以免混入数据。
+
 
 --recursive选项
 
@@ -297,6 +316,18 @@ var specChineseCopy = SpecText{
     （3）由于读写snapshot信息需要额外开销，当要批量上传的文件数比较少或网络状况比较好或有其
     他用户操作相同object时，并不建议使用该选项。可以使用--update选项来增量上传。
 
+--checksum选项
+
+    该选项使增量上传/下载/拷贝根据内容而不是lastModifiedTime判断是否跳过：只有当目标文件（或
+    object）存在，且大小和crc64都与源文件（或object）相同时才跳过，否则执行上传、下载、拷贝。
+    适用于文件内容未变但lastModifiedTime变化（如CI重新检出代码）或者内容变化但lastModifiedTime
+    更旧的场景。oss端的crc64取自object的` + oss.HTTPHeaderOssCRC64 + `，没有该值的object（如在
+    oss支持crc64之前上传的object）总是会被重新传输。与--update同时指定时，以--checksum为准。
+
+    计算本地文件的crc64需要读取整个文件，ossutil会把结果缓存在--checksum-cache指定的目录中（默
+    认为用户主目录下的` + ChecksumCacheDir + `目录），只要文件路径、lastModifiedTime和大小不变，
+    下次就不会重新计算。
+
 注意：--update选项和--snapshot-path选项可以同时使用，ossutil会优先根据snapshot-path信息判断
     是否跳过上传，如果不满足跳过条件，再根据--update判断是否跳过上传。如果指定了这两种增量上
     传策略之中的任何一种，ossutil将根据策略判断是否进行上传/下载/拷贝，当遇到目标端的文件已
@@ -309,6 +340,40 @@ var specChineseCopy = SpecText{
 
 其他选项：
 
+--dryrun选项
+
+    只输出执行计划，不进行上传、下载、拷贝（sync --delete时也不删除）。计划输出到标准输出，每个
+    文件或object一行json：
+        {"action":"upload","reason":"new","source":"dir/a.txt","destination":"oss://bucket/a.txt","size":38}
+    action为upload、download、copy、skip、delete（sync --delete删除的object）或move（sync --delete
+    移到--backup-dir的本地文件）；reason说明原因：
+        new               目标端不存在
+        newer             指定了--update，源端比目标端新
+        size-diff         指定了--checksum，大小不同
+        checksum-diff     指定了--checksum，crc64不同或未知
+        overwrite         目标端存在，将被替换
+        force             指定了--force，不检查目标端
+        snapshot-miss     --snapshot-path中没有记录或文件已变化
+        extra             sync --delete时目标端多余的文件或object
+        time-range        不在--start-time和--end-time范围内
+        snapshot          --snapshot-path中记录的文件未变化
+        not-newer         指定了--update，目标端不比源端旧
+        same              指定了--checksum，内容相同
+        declined          用户选择不替换
+        is-dir            要下载的文件位置是一个本地目录
+        dir-object        指定了--disable-dir-object
+    dryrun不会询问是否替换；同时指定--report-format=json时，以一个json文档输出计划，包含actions和
+    每种action的数量summary。
+
+--report-format选项
+
+    report文件的格式，默认为text，即只记录出错信息的report文件。指定为json时，ossutil在--output-dir
+    下生成` + ReportPrefix + `<时间>` + ReportJSONSuffix + `，记录每个文件或object的结果（action、source、
+    destination、status为ok/skipped/failed、reason、bytes、durationMs、retries、errorCode、error），
+    最后是total、succeeded、skipped、failed、bytes、retries、durationMs的汇总summary，无论是否出错都
+    会保留，便于CI等工具解析。sync --delete删除的object或移走的文件也会记录在内。report文件的路径输出到
+    stderr。
+
 --force选项
 
     如果dest_url指定的文件或objects在oss上已经存在，并且未指定--update或--snapshot-path选项，
@@ -675,9 +740,11 @@ var specEnglishCopy = SpecText{
 	paramText: "src_url dest_url [options]",
 
 	syntaxText: ` 
-    ossutil cp file_url cloud_url  [-r] [-f] [-u] [--enable-symlink-dir] [--disable-all-symlink] [--disable-ignore-error] [--only-current-dir] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir] [--payer requester]
-    ossutil cp cloud_url file_url  [-r] [-f] [-u] [--only-current-dir] [--output-dir=odir] [--disable-ignore-error] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] [--payer requester]
-    ossutil cp cloud_url cloud_url [-r] [-f] [-u] [--only-current-dir] [--output-dir=odir] [--disable-ignore-error] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--payer requester]
+    ossutil cp file_url cloud_url  [-r] [-f] [-u] [--checksum] [--enable-symlink-dir] [--disable-all-symlink] [--disable-ignore-error] [--only-current-dir] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir] [--payer requester]
+    ossutil cp cloud_url file_url  [-r] [-f] [-u] [--checksum] [--only-current-dir] [--output-dir=odir] [--disable-ignore-error] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] [--payer requester]
+    ossutil cp cloud_url cloud_url [-r] [-f] [-u] [--checksum] [--only-current-dir] [--output-dir=odir] [--disable-ignore-error] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--payer requester]
+    ossutil cp - cloud_url [--part-size=size] [--parallel=n] [--payer requester]
+    ossutil cp cloud_url - [--part-size=size] [--parallel=n] [--payer requester] [--version-id versionId]
 `,
 
 	detailHelpText: ` 
@@ -701,6 +768,18 @@ var specEnglishCopy = SpecText{
     Note: when copy between oss, ossutil only support copy objects, the uncompleted Multipart 
     Uploads are not supported.
 
+    A file_url of - means stdin or stdout:
+        tar czf - dir | ossutil cp - oss://bucket/backup.tgz
+        ossutil cp oss://bucket/backup.tgz - | tar xzf -
+    When uploading from stdin, whose length is unknown, ossutil reads parts of --part-size(8MB 
+    by default) and uploads them by multipart upload, with at most --parallel(4 by default) parts 
+    in memory and at most 10000 parts, --part-size must be at least 100KB, a stream shorter than 
+    one part is put directly. As stdin carries the data, ossutil can't ask whether to replace an 
+    existing object: it fails unless --force is specified, and --update, --snapshot-path etc. don't 
+    apply. When downloading to stdout, ossutil fetches ranges of --part-size in parallel and 
+    writes them in order. Both check the crc64 of the whole object at the end(unless --disable-crc64), and print 
+    neither progress nor elapsed time, which would mix with the data.
+
 
 --recursive option:
 
@@ -855,6 +934,20 @@ Incremental Upload/Download/Copy:
         object in oss during the two uploads, it's not suggested to use the option. you can use --update 
         option for incremental upload. 
 
+--checksum option
+
+    Incremental upload, download and copy decide by content instead of lastModifiedTime: ossutil 
+    skips a file(or object) only when the destination exists and has the same size and crc64 as the 
+    source, otherwise it uploads, downloads or copies. This fits files whose content is unchanged 
+    while lastModifiedTime changed(e.g. a fresh CI checkout), and files changed with an older 
+    lastModifiedTime. The crc64 of an object is its ` + oss.HTTPHeaderOssCRC64 + ` header, objects 
+    without it(e.g. uploaded before oss supported crc64) are always transferred again. If --update 
+    is specified too, --checksum wins.
+
+    Hashing a local file reads the whole file, so ossutil caches the crc64 in the directory of 
+    --checksum-cache(default: ` + ChecksumCacheDir + ` in the home directory), and reuses it as long as 
+    the path, lastModifiedTime and size of the file are unchanged.
+
 Note: --update option and --snapshot-path can be used together, ossutil priority will be based on snapshot 
     information to determine whether to skip upload, if not satisfied, ossutil will then based on --update 
     to determine whether to skip upload. If any of those two policies is specified, ossutil will ingnore 
@@ -868,6 +961,40 @@ Note: --update option and --snapshot-path can be used together, ossutil priority
 
 Other Options:
 
+--dryrun option
+
+    Print the plan without uploading, downloading, copying or deleting(sync --delete) anything. The 
+    plan goes to stdout, one json line per file or object:
+        {"action":"upload","reason":"new","source":"dir/a.txt","destination":"oss://bucket/a.txt","size":38}
+    The action is upload, download, copy, skip, delete(objects deleted by sync --delete) or move(local 
+    files moved to --backup-dir by sync --delete), and the reason is:
+        new               the destination does not exist
+        newer             --update and the source is newer than the destination
+        size-diff         --checksum and the sizes differ
+        checksum-diff     --checksum and the crc64 differ or is unknown
+        overwrite         the destination exists and will be replaced
+        force             --force without checking the destination
+        snapshot-miss     --snapshot-path has no record or the file changed since
+        extra             sync --delete and the destination does not exist in the source
+        time-range        out of --start-time and --end-time
+        snapshot          --snapshot-path and the file is unchanged since
+        not-newer         --update and the destination is not older than the source
+        same              --checksum and the content is the same
+        declined          the user refused to replace the destination
+        is-dir            a local directory is in the place of the file to download
+        dir-object        --disable-dir-object
+    A dry run never asks whether to replace. With --report-format=json, the plan is printed as one json 
+    document with the actions and a summary of the count of each action.
+
+--report-format option
+
+    Format of the report file, text by default, which records errors only. With json, ossutil writes 
+    ` + ReportPrefix + `<time>` + ReportJSONSuffix + ` in --output-dir with the result of every file or object(action, source, 
+    destination, status of ok/skipped/failed, reason, bytes, durationMs, retries, errorCode, error) and 
+    a final summary of total, succeeded, skipped, failed, bytes, retries and durationMs. The json report 
+    is kept whether errors happen or not so that tools like CI can parse it. Objects deleted or files 
+    moved by sync --delete are recorded too. The path of the report file is printed to stderr.
+
 --force option
 
     If the file dest_url specified is existed, and --update and --snapshot-path option is not specified, 
@@ -1285,6 +1412,10 @@ var copyCommand = CopyCommand{
 			OptionRoutines,
 			OptionParallel,
 			OptionSnapshotPath,
+			OptionChecksum,
+			OptionChecksumCache,
+			OptionDryRun,
+			OptionReportFormat,
 			OptionDisableCRC64,
 			OptionRequestPayer,
 			OptionLogLevel,
@@ -1338,9 +1469,17 @@ func (cc *CopyCommand) Init(args []string, options OptionMapType) error {
 
 // RunCommand simulate inheritance, and polymorphism
 func (cc *CopyCommand) RunCommand() error {
+	// the hook set by sync is for this run only, copyCommand is reused by the next command
+	afterCopy := cc.cpOption.afterCopy
+	cc.cpOption.afterCopy = nil
+
 	cc.cpOption.recursive, _ = GetBool(OptionRecursion, cc.command.options)
 	cc.cpOption.force, _ = GetBool(OptionForce, cc.command.options)
 	cc.cpOption.update, _ = GetBool(OptionUpdate, cc.command.options)
+	cc.cpOption.checksum, _ = GetBool(OptionChecksum, cc.command.options)
+	cc.cpOption.dryrun, _ = GetBool(OptionDryRun, cc.command.options)
+	reportFormat, _ := GetString(OptionReportFormat, cc.command.options)
+	reportFormat = strings.ToLower(reportFormat)
 	cc.cpOption.threshold, _ = GetInt(OptionBigFileThreshold, cc.command.options)
 	cc.cpOption.cpDir, _ = GetString(OptionCheckpointDir, cc.command.options)
 	cc.cpOption.routines, _ = GetInt(OptionRoutines, cc.command.options)
@@ -1457,8 +1596,21 @@ func (cc *CopyCommand) RunCommand() error {
 		cc.cpOption.payerOptions = append(cc.cpOption.payerOptions, oss.RequestPayer(oss.PayerType(payer)))
 	}
 
-	// init reporter
-	if cc.cpOption.reporter, err = GetReporter(cc.cpOption.recursive, outputDir, commandLine); err != nil {
+	if isStdioURL(srcURLList[0]) || isStdioURL(destURL) {
+		return cc.copyStream(srcURLList[0], destURL, opType)
+	}
+
+	// init reporter, a dry run prints the plan in the report format instead
+	if cc.cpOption.dryrun {
+		stdoutData = true
+		cc.cpOption.plan = newPlanPrinter(os.Stdout, reportFormat)
+	}
+	if reportFormat == ReportFormatJSON && !cc.cpOption.dryrun {
+		if cc.cpOption.reporter, err = GetJSONReporter(outputDir, commandLine); err != nil {
+			return err
+		}
+		cc.cpOption.retries = &sync.Map{}
+	} else if cc.cpOption.reporter, err = GetReporter(cc.cpOption.recursive, outputDir, commandLine); err != nil {
 		return err
 	}
 
@@ -1478,6 +1630,18 @@ func (cc *CopyCommand) RunCommand() error {
 		defer cc.cpOption.snapshotldb.Close()
 	}
 
+	// load checksum cache, the default one may be locked by another ossutil
+	if cc.cpOption.checksum {
+		checksumCachePath, _ := GetString(OptionChecksumCache, cc.command.options)
+		if cc.cpOption.checksumCache, err = openChecksumCache(checksumCachePath); err != nil {
+			if checksumCachePath != "" {
+				return fmt.Errorf("load checksum cache error, reason: %s", err.Error())
+			}
+			LogWarn("load checksum cache error, checksum will not be cached, reason: %s\n", err.Error())
+		}
+		defer cc.cpOption.checksumCache.Close()
+	}
+
 	if cc.cpOption.partitionInfo != "" {
 		if opType == operationTypeGet {
 			sliceInfo := strings.Split(cc.cpOption.partitionInfo, ":")
@@ -1507,7 +1671,9 @@ func (cc *CopyCommand) RunCommand() error {
 	cc.cpOption.opType = opType
 
 	chProgressSignal = make(chan chProgressSignalType, 10)
-	go cc.progressBar()
+	if !cc.cpOption.dryrun {
+		go cc.progressBar()
+	}
 
 	startT := time.Now().UnixNano() / 1000 / 1000
 	switch opType {
@@ -1522,13 +1688,19 @@ func (cc *CopyCommand) RunCommand() error {
 		err = cc.copyFiles(srcURLList[0].(CloudURL), destURL.(CloudURL))
 	}
 	endT := time.Now().UnixNano() / 1000 / 1000
-	if endT-startT > 0 {
+	if !cc.cpOption.dryrun && endT-startT > 0 {
 		averSpeed := (cc.monitor.transferSize / (endT - startT)) * 1000
 		fmt.Printf("\naverage speed %d(byte/s)\n", averSpeed)
 		LogInfo("average speed %d(byte/s)\n", averSpeed)
 	}
 
-	cc.cpOption.reporter.Clear()
+	// sync --delete and --watch go on with the report and the plan after the copy
+	if err == nil && afterCopy != nil {
+		err = afterCopy()
+	}
+	if ferr := cc.finishReport(); err == nil {
+		err = ferr
+	}
 	ckFiles, _ := ioutil.ReadDir(cc.cpOption.cpDir)
 	if err == nil && len(ckFiles) == 0 {
 		LogInfo("begin Remove checkpointDir %s\n", cc.cpOption.cpDir)
@@ -1622,7 +1794,20 @@ func (cc *CopyCommand) checkCopyOptions(opType operationType) error {
 func (cc *CopyCommand) progressBar() {
 	// fetch all reveal
 	for signal := range chProgressSignal {
-		fmt.Printf(cc.monitor.progressBar(signal.finish, signal.exitStat))
+		cc.printProgress(signal.finish, signal.exitStat)
+	}
+}
+
+// finishReport prints the plan of a dry run and closes the report
+func (cc *CopyCommand) finishReport() error {
+	cc.cpOption.reporter.Clear()
+	return cc.cpOption.plan.finish()
+}
+
+// printProgress prints the progress unless it is a dry run, whose plan owns stdout
+func (cc *CopyCommand) printProgress(finish bool, exitStat int) {
+	if !cc.cpOption.dryrun {
+		fmt.Printf(cc.monitor.progressBar(finish, exitStat))
 	}
 }
 
@@ -1680,14 +1865,14 @@ func (cc *CopyCommand) uploadFiles(srcURLList []StorageURLer, destURL CloudURL)
 			} else {
 				if !cc.cpOption.ctnu {
 					cc.closeProgress()
-					fmt.Printf(cc.monitor.progressBar(true, errExit))
+					cc.printProgress(true, errExit)
 					return err
 				}
 			}
 		}
 	}
 	cc.closeProgress()
-	fmt.Printf(cc.monitor.progressBar(true, normalExit))
+	cc.printProgress(true, normalExit)
 	return listError
 }
 
@@ -2027,8 +2212,14 @@ func (cc *CopyCommand) filterPath(filePath string, cpDir string) bool {
 
 func (cc *CopyCommand) uploadFileWithReport(bucket *oss.Bucket, destURL CloudURL, file fileInfoType) error {
 	startT := time.Now()
-	skip, err, isDir, size, msg := cc.uploadFile(bucket, destURL, file)
+	skip, err, isDir, size, msg, reason := cc.uploadFile(bucket, destURL, file)
 	cost := time.Now().UnixNano()/1000/1000 - startT.UnixNano()/1000/1000
+	filePath := filepath.Join(file.dir, file.filePath)
+	objectURL := CloudURLToString(bucket.BucketName, cc.makeObjectName(destURL, file))
+	if cc.cpOption.dryrun && err == nil {
+		cc.addPlan(opUpload, reason, filePath, objectURL, size, skip)
+		return nil
+	}
 
 	if err != nil {
 		LogError("upload file error,file:%s,cost:%d(ms),error info:%s\n", file.filePath, cost, err.Error())
@@ -2052,10 +2243,25 @@ func (cc *CopyCommand) uploadFileWithReport(bucket *oss.Bucket, destURL CloudURL
 
 	cc.updateMonitor(skip, err, isDir, size)
 	cc.report(msg, err)
+	if cc.cpOption.reporter.IsJSON() {
+		var bytes int64
+		if fileInfo, errF := os.Stat(filePath); errF == nil && !fileInfo.IsDir() {
+			bytes = fileInfo.Size()
+		}
+		cc.reportResult(reportResult{
+			Action:      opUpload,
+			Source:      filePath,
+			Destination: objectURL,
+			Reason:      reason,
+			Bytes:       bytes,
+			DurationMs:  cost,
+			Retries:     cc.takeRetries(objectURL),
+		}, skip, err)
+	}
 	return err
 }
 
-func (cc *CopyCommand) uploadFile(bucket *oss.Bucket, destURL CloudURL, file fileInfoType) (skip bool, rerr error, isDir bool, size int64, msg string) {
+func (cc *CopyCommand) uploadFile(bucket *oss.Bucket, destURL CloudURL, file fileInfoType) (skip bool, rerr error, isDir bool, size int64, msg string, reason string) {
 	//first make object name
 	objectName := cc.makeObjectName(destURL, file)
 
@@ -2082,7 +2288,7 @@ func (cc *CopyCommand) uploadFile(bucket *oss.Bucket, destURL CloudURL, file fil
 	srct := f.ModTime().Unix()
 	absPath, _ := filepath.Abs(filePath)
 	spath := cc.formatSnapshotKey(absPath, destURL.bucket, objectName)
-	if skip, rerr = cc.skipUpload(spath, bucket, objectName, destURL, srct); rerr != nil || skip {
+	if skip, reason, rerr = cc.skipUpload(spath, filePath, bucket, objectName, destURL, srct); rerr != nil || skip {
 		return
 	}
 
@@ -2091,6 +2297,10 @@ func (cc *CopyCommand) uploadFile(bucket *oss.Bucket, destURL CloudURL, file fil
 		isDir = true
 		if cc.cpOption.disableDirObject {
 			skip = true
+			reason = ReasonDirObject
+			return
+		}
+		if cc.cpOption.dryrun {
 			return
 		}
 		rerr = cc.ossPutObjectRetry(bucket, objectName, "")
@@ -2100,6 +2310,10 @@ func (cc *CopyCommand) uploadFile(bucket *oss.Bucket, destURL CloudURL, file fil
 		return
 	}
 
+	if cc.cpOption.dryrun {
+		return
+	}
+
 	size = 0
 	//decide whether to use resume upload
 	if f.Size() < cc.cpOption.threshold {
@@ -2141,41 +2355,53 @@ func (cc *CopyCommand) makeObjectName(destURL CloudURL, file fileInfoType) strin
 	return destURL.object
 }
 
-func (cc *CopyCommand) skipUpload(spath string, bucket *oss.Bucket, objectName string, destURL CloudURL, srcModifiedTime int64) (bool, error) {
+// skipUpload decides whether to skip the upload, the reason is one of the Reason constants
+func (cc *CopyCommand) skipUpload(spath, filePath string, bucket *oss.Bucket, objectName string, destURL CloudURL, srcModifiedTime int64) (bool, string, error) {
 	if cc.cpOption.startTime > 0 && srcModifiedTime < cc.cpOption.startTime {
-		return true, nil
+		return true, ReasonTimeRange, nil
 	}
 
 	if cc.cpOption.endTime > 0 && srcModifiedTime > cc.cpOption.endTime {
-		return true, nil
+		return true, ReasonTimeRange, nil
 	}
 
-	if cc.cpOption.snapshotPath != "" || cc.cpOption.update {
+	if cc.cpOption.snapshotPath != "" || cc.cpOption.update || cc.cpOption.checksum {
 		if cc.cpOption.snapshotPath != "" {
 			tstr, err := cc.cpOption.snapshotldb.Get([]byte(spath), nil)
 			if err == nil {
 				t, _ := strconv.ParseInt(string(tstr), 10, 64)
 				if t == srcModifiedTime {
-					return true, nil
+					return true, ReasonSnapshot, nil
 				}
 			}
 		}
-		if cc.cpOption.update {
+		if cc.cpOption.checksum {
+			if props, err := cc.command.ossGetObjectStatRetry(bucket, objectName, cc.cpOption.payerOptions...); err == nil {
+				reason := cc.compareContent(filePath, props)
+				return reason == ReasonSame, reason, nil
+			}
+			return false, ReasonNew, nil
+		} else if cc.cpOption.update {
 			if props, err := cc.command.ossGetObjectStatRetry(bucket, objectName, cc.cpOption.payerOptions...); err == nil {
 				destt, err := time.Parse(http.TimeFormat, props.Get(oss.HTTPHeaderLastModified))
 				if err == nil && destt.Unix() >= srcModifiedTime {
-					return true, nil
+					return true, ReasonNotNewer, nil
 				}
+				return false, ReasonNewer, nil
 			}
+			return false, ReasonNew, nil
 		}
-	} else if !cc.cpOption.force {
+		return false, ReasonSnapshotMiss, nil
+	} else if !cc.cpOption.force || cc.cpOption.dryrun {
 		if _, err := cc.command.ossGetObjectMetaRetry(bucket, objectName, cc.cpOption.payerOptions...); err == nil {
-			if !cc.confirm(CloudURLToString(destURL.bucket, objectName)) {
-				return true, nil
+			if !cc.cpOption.force && !cc.cpOption.dryrun && !cc.confirm(CloudURLToString(destURL.bucket, objectName)) {
+				return true, ReasonDeclined, nil
 			}
+			return false, ReasonOverwrite, nil
 		}
+		return false, ReasonNew, nil
 	}
-	return false, nil
+	return false, ReasonForce, nil
 }
 
 func (cc *CopyCommand) formatSnapshotKey(absPath, bucket, object string) string {
@@ -2198,6 +2424,7 @@ func (cc *CopyCommand) ossPutObjectRetry(bucket *oss.Bucket, objectName string,
 	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
 	for i := 1; ; i++ {
 		if i > 1 {
+			cc.countRetry(bucket.BucketName, objectName)
 			time.Sleep(time.Duration(3) * time.Second)
 			if int64(i) >= retryTimes {
 				fmt.Printf("\nretry count:%d:put object:%s.\n", i-1, objectName)
@@ -2222,6 +2449,7 @@ func (cc *CopyCommand) ossUploadFileRetry(bucket *oss.Bucket, objectName string,
 	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
 	for i := 1; ; i++ {
 		if i > 1 {
+			cc.countRetry(bucket.BucketName, objectName)
 			time.Sleep(time.Duration(3) * time.Second)
 			if int64(i) >= retryTimes {
 				fmt.Printf("\nretry count:%d:upload file:%s\n", i-1, filePath)
@@ -2303,6 +2531,7 @@ func (cc *CopyCommand) ossResumeUploadRetry(bucket *oss.Bucket, objectName strin
 	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
 	for i := 1; ; i++ {
 		if i > 1 {
+			cc.countRetry(bucket.BucketName, objectName)
 			time.Sleep(time.Duration(3) * time.Second)
 			if int64(i) >= retryTimes {
 				fmt.Printf("\nretry count:%d,multipart upload file:%s.\n", i-1, filePath)
@@ -2331,6 +2560,53 @@ func (cc *CopyCommand) report(msg string, err error) {
 	}
 }
 
+// reportResult records the result of a file or object in the json report
+func (cc *CopyCommand) reportResult(result reportResult, skip bool, err error) {
+	result.Status = reportStatusOK
+	if err != nil {
+		result.Status = reportStatusFailed
+		result.ErrorCode = reportErrorCode(err)
+		result.Error = err.Error()
+	} else if skip {
+		result.Status = reportStatusSkipped
+	}
+	cc.cpOption.reporter.ReportResult(result)
+}
+
+// addPlan adds an action to the plan of a dry run
+func (cc *CopyCommand) addPlan(op, reason, source, destination string, size int64, skip bool) {
+	if skip {
+		op = actionSkip
+	}
+	cc.cpOption.plan.add(planAction{
+		Action:      op,
+		Reason:      reason,
+		Source:      source,
+		Destination: destination,
+		Size:        size,
+	})
+}
+
+// countRetry counts a retry of the object for the json report
+func (cc *CopyCommand) countRetry(bucketName, objectName string) {
+	if cc.cpOption.retries == nil {
+		return
+	}
+	count, _ := cc.cpOption.retries.LoadOrStore(CloudURLToString(bucketName, objectName), new(int64))
+	atomic.AddInt64(count.(*int64), 1)
+}
+
+// takeRetries returns and forgets the retries of objectURL
+func (cc *CopyCommand) takeRetries(objectURL string) int64 {
+	if cc.cpOption.retries == nil {
+		return 0
+	}
+	if count, ok := cc.cpOption.retries.LoadAndDelete(objectURL); ok {
+		return atomic.LoadInt64(count.(*int64))
+	}
+	return 0
+}
+
 func (cc *CopyCommand) updateMonitor(skip bool, err error, isDir bool, size int64) {
 	if err != nil {
 		cc.monitor.updateErr(0, 1)
@@ -2416,7 +2692,7 @@ func (cc *CopyCommand) downloadFiles(srcURL CloudURL, destURL FileURL) error {
 
 func (cc *CopyCommand) formatResultPrompt(err error) error {
 	cc.closeProgress()
-	fmt.Printf(cc.monitor.progressBar(true, normalExit))
+	cc.printProgress(true, normalExit)
 	if err != nil && cc.cpOption.ctnu {
 		return nil
 	}
@@ -2458,16 +2734,22 @@ func (cc *CopyCommand) adjustDestURLForDownload(destURL FileURL) (string, error)
 
 func (cc *CopyCommand) downloadSingleFileWithReport(bucket *oss.Bucket, objectInfo objectInfoType, filePath string) error {
 	startT := time.Now()
-	skip, err, size, msg := cc.downloadSingleFile(bucket, objectInfo, filePath)
+	skip, err, size, msg, reason := cc.downloadSingleFile(bucket, objectInfo, filePath)
 	cost := time.Now().UnixNano()/1000/1000 - startT.UnixNano()/1000/1000
+	objectURL := CloudURLToString(bucket.BucketName, objectInfo.prefix+objectInfo.relativeKey)
+	fileName := cc.makeFileName(objectInfo.relativeKey, filePath)
+	if cc.cpOption.dryrun && err == nil {
+		cc.addPlan(opDownload, reason, objectURL, fileName, size, skip)
+		return nil
+	}
+
 	var realSize int64 = objectInfo.size
 	if err != nil {
 		LogError("download error,file:%s,cost:%d(ms),error info:%s\n", objectInfo.relativeKey, cost, err.Error())
 	} else if skip {
 		LogInfo("download skip:%s\n", objectInfo.relativeKey)
 	} else {
-		if realSize < 0 && logLevel >= oss.Info {
-			fileName := cc.makeFileName(objectInfo.relativeKey, filePath)
+		if realSize < 0 && (logLevel >= oss.Info || cc.cpOption.reporter.IsJSON()) {
 			fileInfo, errF := os.Stat(fileName)
 			if errF == nil && !fileInfo.IsDir() {
 				realSize = fileInfo.Size()
@@ -2485,10 +2767,24 @@ func (cc *CopyCommand) downloadSingleFileWithReport(bucket *oss.Bucket, objectIn
 
 	cc.updateMonitor(skip, err, false, size)
 	cc.report(msg, err)
+	if cc.cpOption.reporter.IsJSON() {
+		if realSize < 0 {
+			realSize = size
+		}
+		cc.reportResult(reportResult{
+			Action:      opDownload,
+			Source:      objectURL,
+			Destination: fileName,
+			Reason:      reason,
+			Bytes:       realSize,
+			DurationMs:  cost,
+			Retries:     cc.takeRetries(objectURL),
+		}, skip, err)
+	}
 	return err
 }
 
-func (cc *CopyCommand) downloadSingleFile(bucket *oss.Bucket, objectInfo objectInfoType, filePath string) (bool, error, int64, string) {
+func (cc *CopyCommand) downloadSingleFile(bucket *oss.Bucket, objectInfo objectInfoType, filePath string) (bool, error, int64, string, string) {
 	//get object size and last modify time
 	object := objectInfo.prefix + objectInfo.relativeKey
 	size := objectInfo.size
@@ -2504,29 +2800,30 @@ func (cc *CopyCommand) downloadSingleFile(bucket *oss.Bucket, objectInfo objectI
 		}
 		props, err := cc.command.ossGetObjectStatRetry(bucket, object, statOptions...)
 		if err != nil {
-			return false, err, size, msg
+			return false, err, size, msg, ""
 		}
 		size, err = strconv.ParseInt(props.Get(oss.HTTPHeaderContentLength), 10, 64)
 		if err != nil {
-			return false, err, size, msg
+			return false, err, size, msg, ""
 		}
 		if srct, err = time.Parse(http.TimeFormat, props.Get(oss.HTTPHeaderLastModified)); err != nil {
-			return false, err, size, msg
+			return false, err, size, msg, ""
 		}
 	}
 
 	rsize := cc.getRangeSize(size)
-	if cc.skipDownload(fileName, srct, CloudURLToString(bucket.BucketName, object)) {
-		return true, nil, rsize, msg
+	skip, reason := cc.skipDownload(bucket, object, fileName, srct)
+	if skip || cc.cpOption.dryrun {
+		return skip, nil, rsize, msg, reason
 	}
 
 	if size == 0 && strings.HasSuffix(object, "/") {
-		return false, os.MkdirAll(fileName, 0755), rsize, msg
+		return false, os.MkdirAll(fileName, 0755), rsize, msg, reason
 	}
 
 	//create parent directory
 	if err := cc.createParentDirectory(fileName); err != nil {
-		return false, err, rsize, msg
+		return false, err, rsize, msg, reason
 	}
 
 	downloadOptions := cc.cpOption.options
@@ -2537,7 +2834,7 @@ func (cc *CopyCommand) downloadSingleFile(bucket *oss.Bucket, objectInfo objectI
 	if rsize < cc.cpOption.threshold {
 		var listener *OssProgressListener = &OssProgressListener{&cc.monitor, 0, 0, false}
 		downloadOptions = append(downloadOptions, oss.Progress(listener))
-		return false, cc.ossDownloadFileRetry(bucket, object, fileName, downloadOptions...), 0, msg
+		return false, cc.ossDownloadFileRetry(bucket, object, fileName, downloadOptions...), 0, msg, reason
 	}
 
 	var listener *OssResumeProgressListener = &OssResumeProgressListener{&cc.monitor, 0, 0, false, false}
@@ -2548,7 +2845,7 @@ func (cc *CopyCommand) downloadSingleFile(bucket *oss.Bucket, objectInfo objectI
 	LogInfo("multipart download,object %s,file size:%d,partSize %d,routin count:%d,checkpoint dir:%s\n",
 		object, size, partSize, rt, cc.cpOption.cpDir)
 	downloadOptions = append(downloadOptions, oss.Routines(rt), cp)
-	return false, cc.ossResumeDownloadRetry(bucket, object, fileName, size, partSize, downloadOptions...), 0, msg
+	return false, cc.ossResumeDownloadRetry(bucket, object, fileName, size, partSize, downloadOptions...), 0, msg, reason
 }
 
 func (cc *CopyCommand) makeFileName(relativeObject, filePath string) string {
@@ -2558,42 +2855,58 @@ func (cc *CopyCommand) makeFileName(relativeObject, filePath string) string {
 	return filePath
 }
 
-func (cc *CopyCommand) skipDownload(fileName string, srcModifiedTime time.Time, object string) bool {
+// skipDownload decides whether to skip the download, the reason is one of the Reason constants
+func (cc *CopyCommand) skipDownload(bucket *oss.Bucket, object, fileName string, srcModifiedTime time.Time) (bool, string) {
 	if cc.cpOption.startTime > 0 && srcModifiedTime.Unix() < cc.cpOption.startTime {
-		return true
+		return true, ReasonTimeRange
 	}
 
 	if cc.cpOption.endTime > 0 && srcModifiedTime.Unix() > cc.cpOption.endTime {
-		return true
+		return true, ReasonTimeRange
 	}
 
-	if cc.cpOption.snapshotPath != "" || cc.cpOption.update {
+	if cc.cpOption.snapshotPath != "" || cc.cpOption.update || cc.cpOption.checksum {
 		if cc.cpOption.snapshotPath != "" {
-			tstr, err := cc.cpOption.snapshotldb.Get([]byte(object), nil)
+			tstr, err := cc.cpOption.snapshotldb.Get([]byte(CloudURLToString(bucket.BucketName, object)), nil)
 			if err == nil {
 				t, _ := strconv.ParseInt(string(tstr), 10, 64)
 				if t == srcModifiedTime.Unix() {
-					return true
+					return true, ReasonSnapshot
 				}
 			}
 		}
 
-		if f, err := os.Stat(fileName); err == nil {
-			destt := f.ModTime()
-			if destt.Unix() >= srcModifiedTime.Unix() {
-				return true
-			}
+		f, err := os.Stat(fileName)
+		if err != nil {
+			return false, ReasonNew
 		}
-	} else {
-		if !cc.cpOption.force {
-			if fileInfo, err := os.Stat(fileName); err == nil {
-				if fileInfo.IsDir() || !cc.confirm(fileName) {
-					return true
-				}
+		if cc.cpOption.checksum {
+			statOptions := append([]oss.Option{}, cc.cpOption.payerOptions...)
+			if cc.cpOption.versionId != "" {
+				statOptions = append(statOptions, oss.VersionId(cc.cpOption.versionId))
 			}
+			if props, err := cc.command.ossGetObjectStatRetry(bucket, object, statOptions...); err == nil {
+				reason := cc.compareContent(fileName, props)
+				return reason == ReasonSame, reason
+			}
+			return false, ReasonChecksumDiff
+		}
+		if f.ModTime().Unix() >= srcModifiedTime.Unix() {
+			return true, ReasonNotNewer
 		}
+		return false, ReasonNewer
 	}
-	return false
+
+	if fileInfo, err := os.Stat(fileName); err == nil {
+		if fileInfo.IsDir() && !cc.cpOption.force {
+			return true, ReasonIsDir
+		}
+		if !cc.cpOption.force && !cc.cpOption.dryrun && !cc.confirm(fileName) {
+			return true, ReasonDeclined
+		}
+		return false, ReasonOverwrite
+	}
+	return false, ReasonNew
 }
 
 func (cc *CopyCommand) createParentDirectory(fileName string) error {
@@ -2609,6 +2922,7 @@ func (cc *CopyCommand) ossDownloadFileRetry(bucket *oss.Bucket, objectName, file
 	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
 	for i := 1; ; i++ {
 		if i > 1 {
+			cc.countRetry(bucket.BucketName, objectName)
 			time.Sleep(time.Duration(3) * time.Second)
 			if int64(i) >= retryTimes {
 				fmt.Printf("\nretry count:%d:get object to file:%s.\n", i-1, fileName)
@@ -2639,6 +2953,7 @@ func (cc *CopyCommand) ossResumeDownloadRetry(bucket *oss.Bucket, objectName str
 	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
 	for i := 1; ; i++ {
 		if i > 1 {
+			cc.countRetry(bucket.BucketName, objectName)
 			time.Sleep(time.Duration(3) * time.Second)
 			if int64(i) >= retryTimes {
 				fmt.Printf("\nretry count:%d:mulitpart download file:%s.\n", i-1, objectName)
@@ -2898,7 +3213,7 @@ func (cc *CopyCommand) waitRoutinueComplete(chError, chListError <-chan error, o
 				ferr = err
 				if !cc.cpOption.ctnu {
 					cc.closeProgress()
-					fmt.Printf(cc.monitor.progressBar(true, errExit))
+					cc.printProgress(true, errExit)
 					return err
 				}
 			}
@@ -2972,13 +3287,43 @@ func (cc *CopyCommand) checkCopyFileArgs(srcURL, destURL CloudURL) error {
 }
 
 func (cc *CopyCommand) copySingleFileWithReport(bucket *oss.Bucket, objectInfo objectInfoType, srcURL, destURL CloudURL) error {
-	skip, err, size, msg := cc.copySingleFile(bucket, objectInfo, srcURL, destURL)
+	startT := time.Now()
+	skip, err, size, msg, reason := cc.copySingleFile(bucket, objectInfo, srcURL, destURL)
+	cost := time.Now().UnixNano()/1000/1000 - startT.UnixNano()/1000/1000
+	srcObjectURL := CloudURLToString(srcURL.bucket, objectInfo.prefix+objectInfo.relativeKey)
+	destObject := cc.makeCopyObjectName(objectInfo.relativeKey, destURL.object)
+	if cc.cpOption.dryrun && err == nil {
+		cc.addPlan(opCopy, reason, srcObjectURL, CloudURLToString(destURL.bucket, destObject), size, skip)
+		return nil
+	}
+
 	cc.updateMonitor(skip, err, false, size)
 	cc.report(msg, err)
+	if cc.cpOption.reporter.IsJSON() {
+		bytes := objectInfo.size
+		if bytes < 0 {
+			// the size of a single object is known after the copy
+			bytes = size
+			if destBucket, errB := cc.command.ossBucket(destURL.bucket); errB == nil && err == nil && !skip {
+				if props, errS := cc.command.ossGetObjectStatRetry(destBucket, destObject, cc.cpOption.payerOptions...); errS == nil {
+					bytes, _ = strconv.ParseInt(props.Get(oss.HTTPHeaderContentLength), 10, 64)
+				}
+			}
+		}
+		cc.reportResult(reportResult{
+			Action:      opCopy,
+			Source:      srcObjectURL,
+			Destination: CloudURLToString(destURL.bucket, destObject),
+			Reason:      reason,
+			Bytes:       bytes,
+			DurationMs:  cost,
+			Retries:     cc.takeRetries(srcObjectURL),
+		}, skip, err)
+	}
 	return err
 }
 
-func (cc *CopyCommand) copySingleFile(bucket *oss.Bucket, objectInfo objectInfoType, srcURL, destURL CloudURL) (bool, error, int64, string) {
+func (cc *CopyCommand) copySingleFile(bucket *oss.Bucket, objectInfo objectInfoType, srcURL, destURL CloudURL) (bool, error, int64, string, string) {
 	//make object name
 	srcObject := objectInfo.prefix + objectInfo.relativeKey
 	destObject := cc.makeCopyObjectName(objectInfo.relativeKey, destURL.object)
@@ -2996,23 +3341,24 @@ func (cc *CopyCommand) copySingleFile(bucket *oss.Bucket, objectInfo objectInfoT
 
 		props, err := cc.command.ossGetObjectStatRetry(bucket, srcObject, statOptions...)
 		if err != nil {
-			return false, err, size, msg
+			return false, err, size, msg, ""
 		}
 		size, err = strconv.ParseInt(props.Get(oss.HTTPHeaderContentLength), 10, 64)
 		if err != nil {
-			return false, err, size, msg
+			return false, err, size, msg, ""
 		}
 		if srct, err = time.Parse(http.TimeFormat, props.Get(oss.HTTPHeaderLastModified)); err != nil {
-			return false, err, size, msg
+			return false, err, size, msg, ""
 		}
 	}
 
-	if skip, err := cc.skipCopy(destURL, destObject, srct); err != nil || skip {
-		return skip, err, size, msg
+	skip, reason, err := cc.skipCopy(bucket, srcObject, destURL, destObject, srct)
+	if err != nil || skip || cc.cpOption.dryrun {
+		return skip, err, size, msg, reason
 	}
 
 	if size < cc.cpOption.threshold {
-		return false, cc.ossCopyObjectRetry(bucket, srcObject, destURL.bucket, destObject), size, msg
+		return false, cc.ossCopyObjectRetry(bucket, srcObject, destURL.bucket, destObject), size, msg, reason
 	}
 
 	var listener *OssResumeProgressListener = &OssResumeProgressListener{&cc.monitor, 0, 0, false, false}
@@ -3020,7 +3366,7 @@ func (cc *CopyCommand) copySingleFile(bucket *oss.Bucket, objectInfo objectInfoT
 	cp := oss.CheckpointDir(true, cc.cpOption.cpDir)
 	options := cc.cpOption.options
 	options = append(options, oss.Routines(rt), cp, oss.Progress(listener), oss.MetadataDirective(oss.MetaReplace))
-	return false, cc.ossResumeCopyRetry(srcURL.bucket, srcObject, destURL.bucket, destObject, partSize, options...), 0, msg
+	return false, cc.ossResumeCopyRetry(srcURL.bucket, srcObject, destURL.bucket, destObject, partSize, options...), 0, msg, reason
 }
 
 func (cc *CopyCommand) makeCopyObjectName(srcRelativeObject, destObject string) string {
@@ -3030,37 +3376,55 @@ func (cc *CopyCommand) makeCopyObjectName(srcRelativeObject, destObject string)
 	return destObject
 }
 
-func (cc *CopyCommand) skipCopy(destURL CloudURL, destObject string, srct time.Time) (bool, error) {
+// skipCopy decides whether to skip the copy, the reason is one of the Reason constants
+func (cc *CopyCommand) skipCopy(bucket *oss.Bucket, srcObject string, destURL CloudURL, destObject string, srct time.Time) (bool, string, error) {
 	if cc.cpOption.startTime > 0 && srct.Unix() < cc.cpOption.startTime {
-		return true, nil
+		return true, ReasonTimeRange, nil
 	}
 
 	if cc.cpOption.endTime > 0 && srct.Unix() > cc.cpOption.endTime {
-		return true, nil
+		return true, ReasonTimeRange, nil
 	}
 
 	destBucket, err := cc.command.ossBucket(destURL.bucket)
 	if err != nil {
-		return false, err
+		return false, "", err
 	}
 
-	if cc.cpOption.update {
+	if cc.cpOption.checksum {
+		destProps, err := cc.command.ossGetObjectStatRetry(destBucket, destObject, cc.cpOption.payerOptions...)
+		if err != nil {
+			return false, ReasonNew, nil
+		}
+		statOptions := append([]oss.Option{}, cc.cpOption.payerOptions...)
+		if cc.cpOption.versionId != "" {
+			statOptions = append(statOptions, oss.VersionId(cc.cpOption.versionId))
+		}
+		srcProps, err := cc.command.ossGetObjectStatRetry(bucket, srcObject, statOptions...)
+		if err != nil {
+			return false, "", err
+		}
+		reason := compareObjectContent(srcProps, destProps)
+		return reason == ReasonSame, reason, nil
+	} else if cc.cpOption.update {
 		if props, err := cc.command.ossGetObjectStatRetry(destBucket, destObject, cc.cpOption.payerOptions...); err == nil {
 			destt, err := time.Parse(http.TimeFormat, props.Get(oss.HTTPHeaderLastModified))
 			if err == nil && destt.Unix() >= srct.Unix() {
-				return true, nil
+				return true, ReasonNotNewer, nil
 			}
+			return false, ReasonNewer, nil
 		}
-	} else {
-		if !cc.cpOption.force {
-			if _, err := cc.command.ossGetObjectMetaRetry(destBucket, destObject, cc.cpOption.payerOptions...); err == nil {
-				if !cc.confirm(CloudURLToString(destURL.bucket, destObject)) {
-					return true, nil
-				}
+		return false, ReasonNew, nil
+	} else if !cc.cpOption.force || cc.cpOption.dryrun {
+		if _, err := cc.command.ossGetObjectMetaRetry(destBucket, destObject, cc.cpOption.payerOptions...); err == nil {
+			if !cc.cpOption.force && !cc.cpOption.dryrun && !cc.confirm(CloudURLToString(destURL.bucket, destObject)) {
+				return true, ReasonDeclined, nil
 			}
+			return false, ReasonOverwrite, nil
 		}
+		return false, ReasonNew, nil
 	}
-	return false, nil
+	return false, ReasonForce, nil
 }
 
 func (cc *CopyCommand) ossCopyObjectRetry(bucket *oss.Bucket, objectName, destBucketName, destObjectName string) error {
@@ -3070,6 +3434,7 @@ func (cc *CopyCommand) ossCopyObjectRetry(bucket *oss.Bucket, objectName, destBu
 	options = append(options, oss.TaggingDirective(oss.TaggingReplace))
 	for i := 1; ; i++ {
 		if i > 1 {
+			cc.countRetry(bucket.BucketName, objectName)
 			time.Sleep(time.Duration(3) * time.Second)
 			if int64(i) >= retryTimes {
 				fmt.Printf("\nretry count:%d,copy object:%s.\n", i-1, objectName)
@@ -3097,6 +3462,7 @@ func (cc *CopyCommand) ossResumeCopyRetry(bucketName, objectName, destBucketName
 	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
 	for i := 1; ; i++ {
 		if i > 1 {
+			cc.countRetry(bucketName, objectName)
 			time.Sleep(time.Duration(3) * time.Second)
 			if int64(i) >= retryTimes {
 				fmt.Printf("\nretry count:%d, resume copy object:%s.\n", i-1, objectName)
@@ -0,0 +1,349 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc64"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// the stdin and stdout of stream copies, hook for test
+var (
+	streamStdin  io.Reader = os.Stdin
+	streamStdout io.Writer = os.Stdout
+)
+
+// isStdioURL reports whether url is "-", which is stdin as the source and stdout as the
+// destination
+func isStdioURL(url StorageURLer) bool {
+	return url.IsFileURL() && url.ToString() == StdioURL
+}
+
+// copyStream uploads stdin or downloads to stdout without reporter, progress or checkpoint
+func (cc *CopyCommand) copyStream(srcURL, destURL StorageURLer, opType operationType) error {
+	if cc.cpOption.recursive {
+		return fmt.Errorf("%s can't be used with option -r", StdioURL)
+	}
+	if cc.cpOption.dryrun {
+		return fmt.Errorf("%s can't be used with option --dryrun", StdioURL)
+	}
+	if cc.cpOption.vrange != "" {
+		return fmt.Errorf("%s can't be used with option --range", StdioURL)
+	}
+
+	switch opType {
+	case operationTypePut:
+		if isStdioURL(destURL) {
+			return fmt.Errorf("copy from stdin to stdout is not allowed in ossutil")
+		}
+		return cc.uploadStream(streamStdin, destURL.(CloudURL))
+	case operationTypeGet:
+		stdoutData = true
+		return cc.downloadStream(srcURL.(CloudURL), streamStdout)
+	}
+	return fmt.Errorf("invalid url: %s, only upload and download support %s", srcURL.ToString(), StdioURL)
+}
+
+// streamPartOption returns the part size and the parallel of a stream, which bound the
+// memory used to part size * parallel
+func (cc *CopyCommand) streamPartOption() (int64, int) {
+	partSize, _ := GetInt(OptionPartSize, cc.command.options)
+	if partSize < MinPartSize {
+		partSize = DefaultStreamPartSize
+	}
+	parallel := DefaultStreamParallel
+	if p, err := GetInt(OptionParallel, cc.command.options); err == nil {
+		parallel = int(p)
+	}
+	return partSize, parallel
+}
+
+// uploadStream uploads r to destURL, which fits in one part is put directly, otherwise by
+// multipart upload since the length is unknown. stdin carries the data, so an existing
+// object can't be confirmed like other uploads and is only overwritten with --force
+func (cc *CopyCommand) uploadStream(r io.Reader, destURL CloudURL) error {
+	if destURL.object == "" || strings.HasSuffix(destURL.object, "/") {
+		return fmt.Errorf("invalid url: %s, upload from stdin needs an object name", destURL.ToString())
+	}
+	partSize, parallel := cc.streamPartOption()
+	if partSize < oss.MinPartSize {
+		return fmt.Errorf("invalid --part-size %d, upload from stdin needs at least %d", partSize, oss.MinPartSize)
+	}
+	bucket, err := cc.command.ossBucket(destURL.bucket)
+	if err != nil {
+		return err
+	}
+	objectName := destURL.object
+	if !cc.cpOption.force {
+		if _, err := cc.command.ossGetObjectMetaRetry(bucket, objectName, cc.cpOption.payerOptions...); err == nil {
+			return fmt.Errorf("%s already exists, upload from stdin can't ask for confirmation, use --force to overwrite it", destURL.ToString())
+		}
+	}
+	LogInfo("upload stdin to %s,part size:%d,parallel:%d\n", destURL.ToString(), partSize, parallel)
+
+	// the first part tells whether the stream needs multipart upload
+	first := make([]byte, partSize)
+	n, err := io.ReadFull(r, first)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		data := first[:n]
+		err = cc.ossStreamRetry(bucket, objectName, "put object", func() error {
+			return bucket.PutObject(objectName, bytes.NewReader(data), cc.cpOption.options...)
+		})
+		if err != nil {
+			return ObjectError{err, bucket.BucketName, objectName}
+		}
+		crc, _ := crc64Of(bytes.NewReader(data))
+		return cc.verifyStreamCRC64(bucket, objectName, crc)
+	}
+	if err != nil {
+		return err
+	}
+
+	imur, err := bucket.InitiateMultipartUpload(objectName, cc.cpOption.options...)
+	if err != nil {
+		return ObjectError{err, bucket.BucketName, objectName}
+	}
+	var mu sync.Mutex
+	parts := []oss.UploadPart{}
+	crc, err := uploadInParts(io.MultiReader(bytes.NewReader(first), r), partSize, parallel, func(number int, data []byte) error {
+		var part oss.UploadPart
+		err := cc.ossStreamRetry(bucket, objectName, fmt.Sprintf("upload part %d", number), func() (err error) {
+			part, err = bucket.UploadPart(imur, bytes.NewReader(data), int64(len(data)), number, cc.cpOption.payerOptions...)
+			return
+		})
+		if err == nil {
+			mu.Lock()
+			parts = append(parts, part)
+			mu.Unlock()
+		}
+		return err
+	})
+	if err == nil {
+		sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+		_, err = bucket.CompleteMultipartUpload(imur, parts, cc.cpOption.payerOptions...)
+	}
+	if err != nil {
+		bucket.AbortMultipartUpload(imur, cc.cpOption.payerOptions...)
+		return ObjectError{err, bucket.BucketName, objectName}
+	}
+	LogInfo("upload stdin success,object:%s,part count:%d\n", objectName, len(parts))
+	return cc.verifyStreamCRC64(bucket, objectName, crc)
+}
+
+// uploadInParts reads r part by part and uploads them by parallel routines, at most
+// parallel parts are in memory. It returns the crc64 of r.
+func uploadInParts(r io.Reader, partSize int64, parallel int, upload func(number int, data []byte) error) (uint64, error) {
+	type streamPart struct {
+		number int
+		data   []byte
+	}
+	chParts := make(chan streamPart)
+	chBufs := make(chan []byte, parallel)
+	for i := 0; i < parallel; i++ {
+		chBufs <- nil
+	}
+
+	var mu sync.Mutex
+	var uploadErr error
+	failed := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		return uploadErr
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for part := range chParts {
+				if failed() == nil {
+					if err := upload(part.number, part.data); err != nil {
+						mu.Lock()
+						if uploadErr == nil {
+							uploadErr = err
+						}
+						mu.Unlock()
+					}
+				}
+				chBufs <- part.data[:cap(part.data)]
+			}
+		}()
+	}
+
+	crc := crc64.New(crc64.MakeTable(crc64.ECMA))
+	var readErr error
+	for number := 1; failed() == nil; number++ {
+		data := <-chBufs
+		if data == nil {
+			data = make([]byte, partSize)
+		}
+		n, err := io.ReadFull(r, data)
+		if n > 0 {
+			if number > MaxPartNum {
+				readErr = fmt.Errorf("stdin is larger than %d parts, please increase --part-size", MaxPartNum)
+				break
+			}
+			crc.Write(data[:n])
+			chParts <- streamPart{number, data[:n]}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			readErr = err
+			break
+		}
+	}
+	close(chParts)
+	wg.Wait()
+
+	if readErr != nil {
+		return 0, readErr
+	}
+	return crc.Sum64(), uploadErr
+}
+
+// downloadStream downloads srcURL to w by ranged parallel fetches written in order, and
+// checks the crc64 of the whole object at the end
+func (cc *CopyCommand) downloadStream(srcURL CloudURL, w io.Writer) error {
+	if srcURL.object == "" || strings.HasSuffix(srcURL.object, "/") {
+		return fmt.Errorf("invalid url: %s, download to stdout needs an object name", srcURL.ToString())
+	}
+	bucket, err := cc.command.ossBucket(srcURL.bucket)
+	if err != nil {
+		return err
+	}
+	objectName := srcURL.object
+	options := append([]oss.Option{}, cc.cpOption.payerOptions...)
+	if cc.cpOption.versionId != "" {
+		options = append(options, oss.VersionId(cc.cpOption.versionId))
+	}
+	props, err := cc.command.ossGetObjectStatRetry(bucket, objectName, options...)
+	if err != nil {
+		return err
+	}
+	size, expected, hasCRC := objectCRC64(props)
+	// a range never mixes two versions of the object
+	options = append(options, oss.IfMatch(props.Get(oss.HTTPHeaderEtag)))
+
+	partSize, parallel := cc.streamPartOption()
+	partNum := int((size + partSize - 1) / partSize)
+	LogInfo("download %s to stdout,size:%d,part size:%d,parallel:%d\n", srcURL.ToString(), size, partSize, parallel)
+
+	crc := crc64.New(crc64.MakeTable(crc64.ECMA))
+	err = fetchInOrder(partNum, parallel, func(i int) ([]byte, error) {
+		start := int64(i) * partSize
+		end := start + partSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		data := make([]byte, end-start+1)
+		err := cc.ossStreamRetry(bucket, objectName, fmt.Sprintf("get range %d-%d", start, end), func() error {
+			body, err := bucket.GetObject(objectName, append([]oss.Option{oss.Range(start, end)}, options...)...)
+			if err != nil {
+				return err
+			}
+			defer body.Close()
+			_, err = io.ReadFull(body, data)
+			return err
+		})
+		return data, err
+	}, func(data []byte) error {
+		crc.Write(data)
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return ObjectError{err, bucket.BucketName, objectName}
+	}
+
+	if disableCRC64, _ := GetBool(OptionDisableCRC64, cc.command.options); !disableCRC64 && hasCRC && crc.Sum64() != expected {
+		return fmt.Errorf("crc64 of %s is %d, but the data downloaded is %d", srcURL.ToString(), expected, crc.Sum64())
+	}
+	LogInfo("download %s to stdout success\n", srcURL.ToString())
+	return nil
+}
+
+// fetchInOrder fetches the parts by parallel routines and writes them in order, at most
+// parallel parts are fetched but not written
+func fetchInOrder(partNum, parallel int, fetch func(i int) ([]byte, error), write func(data []byte) error) error {
+	type fetchResult struct {
+		data []byte
+		err  error
+	}
+	tokens := make(chan struct{}, parallel)
+	chOrder := make(chan chan fetchResult, parallel)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		defer close(chOrder)
+		for i := 0; i < partNum; i++ {
+			select {
+			case tokens <- struct{}{}:
+			case <-done:
+				return
+			}
+			chResult := make(chan fetchResult, 1)
+			go func(i int) {
+				data, err := fetch(i)
+				chResult <- fetchResult{data, err}
+			}(i)
+			chOrder <- chResult
+		}
+	}()
+
+	for chResult := range chOrder {
+		result := <-chResult
+		if result.err != nil {
+			return result.err
+		}
+		if err := write(result.data); err != nil {
+			return err
+		}
+		<-tokens
+	}
+	return nil
+}
+
+// ossStreamRetry runs op of a stream copy with --retry-times
+func (cc *CopyCommand) ossStreamRetry(bucket *oss.Bucket, objectName, op string, fn func() error) error {
+	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
+	for i := 1; ; i++ {
+		if i > 1 {
+			cc.countRetry(bucket.BucketName, objectName)
+			time.Sleep(time.Duration(3) * time.Second)
+		}
+		err := fn()
+		if err == nil {
+			LogDebug("try count:%d,%s success,object:%s\n", i, op, objectName)
+			return nil
+		}
+		LogError("try count:%d,%s error,object:%s,error:%s\n", i, op, objectName, err.Error())
+		if int64(i) >= retryTimes {
+			return err
+		}
+	}
+}
+
+// verifyStreamCRC64 compares the crc64 of the object uploaded with the stream read
+func (cc *CopyCommand) verifyStreamCRC64(bucket *oss.Bucket, objectName string, crc uint64) error {
+	if disableCRC64, _ := GetBool(OptionDisableCRC64, cc.command.options); disableCRC64 {
+		return nil
+	}
+	props, err := cc.command.ossGetObjectStatRetry(bucket, objectName, cc.cpOption.payerOptions...)
+	if err != nil {
+		return err
+	}
+	if _, expected, ok := objectCRC64(props); ok && expected != crc {
+		return fmt.Errorf("crc64 of %s is %d, but the data uploaded is %d", CloudURLToString(bucket.BucketName, objectName), expected, crc)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package lib
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadInParts(t *testing.T) {
+	content := strings.Repeat("this is content", 10)
+	var mu sync.Mutex
+	parts := map[int]string{}
+	var inFlight, maxInFlight int32
+	crc, err := uploadInParts(strings.NewReader(content), 16, 3, func(number int, data []byte) error {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		mu.Lock()
+		if n > maxInFlight {
+			maxInFlight = n
+		}
+		parts[number] = string(data)
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	require.NoError(t, err)
+	expected, _ := crc64Of(strings.NewReader(content))
+	assert.Equal(t, expected, crc)
+
+	// 150 字节按 16 字节分成 10 片，最后一片 6 字节
+	require.Len(t, parts, 10)
+	joined := ""
+	for i := 1; i <= 10; i++ {
+		joined += parts[i]
+	}
+	assert.Equal(t, content, joined)
+	assert.Len(t, parts[10], 6)
+	assert.LessOrEqual(t, maxInFlight, int32(3))
+
+	// 上传失败后停止读取
+	uploaded := int32(0)
+	_, err = uploadInParts(strings.NewReader(content), 16, 1, func(number int, data []byte) error {
+		atomic.AddInt32(&uploaded, 1)
+		if number == 2 {
+			return errors.New("upload part error")
+		}
+		return nil
+	})
+	assert.EqualError(t, err, "upload part error")
+	assert.Less(t, atomic.LoadInt32(&uploaded), int32(10))
+}
+
+func TestFetchInOrder(t *testing.T) {
+	var inFlight, maxInFlight int32
+	out := new(bytes.Buffer)
+	err := fetchInOrder(10, 3, func(i int) ([]byte, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			old := atomic.LoadInt32(&maxInFlight)
+			if n <= old || atomic.CompareAndSwapInt32(&maxInFlight, old, n) {
+				break
+			}
+		}
+		// 后面的分片先返回
+		time.Sleep(time.Duration(10-i) * time.Millisecond)
+		return []byte(fmt.Sprintf("%d,", i)), nil
+	}, func(data []byte) error {
+		atomic.AddInt32(&inFlight, -1)
+		out.Write(data)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "0,1,2,3,4,5,6,7,8,9,", out.String())
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxInFlight), int32(3))
+
+	// 出错时停止输出
+	out.Reset()
+	err = fetchInOrder(10, 2, func(i int) ([]byte, error) {
+		if i == 3 {
+			return nil, errors.New("get range error")
+		}
+		return []byte(fmt.Sprintf("%d,", i)), nil
+	}, func(data []byte) error {
+		out.Write(data)
+		return nil
+	})
+	assert.EqualError(t, err, "get range error")
+	assert.Equal(t, "0,1,2,", out.String())
+
+	assert.NoError(t, fetchInOrder(0, 2, nil, nil))
+}
+
+func TestStreamPartOption(t *testing.T) {
+	cc := &CopyCommand{}
+	cc.command.options = OptionMapType{}
+	partSize, parallel := cc.streamPartOption()
+	assert.Equal(t, DefaultStreamPartSize, partSize)
+	assert.Equal(t, DefaultStreamParallel, parallel)
+
+	size := "1048576"
+	p := "8"
+	cc.command.options[OptionPartSize] = &size
+	cc.command.options[OptionParallel] = &p
+	partSize, parallel = cc.streamPartOption()
+	assert.Equal(t, int64(1048576), partSize)
+	assert.Equal(t, 8, parallel)
+}
+
+func TestCopyStreamArgs(t *testing.T) {
+	stdio, _ := StorageURLFromString(StdioURL, "")
+	cloud, _ := StorageURLFromString("oss://bucket/a.txt", "")
+	assert.True(t, isStdioURL(stdio))
+	assert.False(t, isStdioURL(cloud))
+
+	cc := &CopyCommand{}
+	cc.cpOption.recursive = true
+	assert.Error(t, cc.copyStream(stdio, cloud, operationTypePut))
+	cc.cpOption.recursive = false
+	cc.cpOption.vrange = "1-2"
+	assert.Error(t, cc.copyStream(cloud, stdio, operationTypeGet))
+	cc.cpOption.vrange = ""
+	assert.Error(t, cc.copyStream(stdio, stdio, operationTypePut))
+
+	// 需要明确的 object 名
+	dir, _ := StorageURLFromString("oss://bucket/dir/", "")
+	assert.Error(t, cc.uploadStream(strings.NewReader(""), dir.(CloudURL)))
+	assert.Error(t, cc.downloadStream(dir.(CloudURL), new(bytes.Buffer)))
+
+	// 分片上传要求分片不小于 100KB
+	size := "1024"
+	cc.command.options = OptionMapType{OptionPartSize: &size}
+	err := cc.uploadStream(strings.NewReader(""), cloud.(CloudURL))
+	assert.EqualError(t, err, "invalid --part-size 1024, upload from stdin needs at least 102400")
+}
@@ -1815,7 +1815,7 @@ func (s *OssutilCommandSuite) TestCPFunction(c *C) {
 	var fileInfo fileInfoType
 	fileInfo.filePath = "a"
 	fileInfo.dir = "notexistdir"
-	_, err, _, _, _ = copyCommand.uploadFile(bucket, destURL, fileInfo)
+	_, err, _, _, _, _ = copyCommand.uploadFile(bucket, destURL, fileInfo)
 	c.Assert(err, NotNil)
 }
 
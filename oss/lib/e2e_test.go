@@ -0,0 +1,364 @@
+package lib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aliyun/aliyun-cli/v3/oss/lib/osstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const e2eBucket = "e2e-bucket"
+
+// e2eEnv runs commands against a fake oss server, the tests do not need a real endpoint
+type e2eEnv struct {
+	t      *testing.T
+	server *osstest.Server
+	cm     CommandManager
+	dir    string
+}
+
+func newE2EEnv(t *testing.T) *e2eEnv {
+	dir, err := ioutil.TempDir("", "ossutil-e2e")
+	require.NoError(t, err)
+	server := osstest.NewServer()
+	server.CreateBucket(e2eBucket)
+	env := &e2eEnv{t: t, server: server, dir: dir}
+	env.cm.Init()
+	t.Cleanup(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+	return env
+}
+
+// options points the command at the fake server, extra options override the default ones.
+// Like ParseArgOptions, every option is in the map so that the command fills the defaults.
+func (env *e2eEnv) options(extra OptionMapType) OptionMapType {
+	options := OptionMapType{}
+	for name, option := range OptionMap {
+		switch option.optionType {
+		case OptionTypeFlagTrue:
+			options[name] = new(bool)
+		case OptionTypeStrings:
+			options[name] = &[]string{}
+		default:
+			options[name] = new(string)
+		}
+	}
+	str := func(s string) *string { return &s }
+	options[OptionEndpoint] = str(env.server.URL)
+	options[OptionAccessKeyID] = str(env.server.AccessKeyID)
+	options[OptionAccessKeySecret] = str(env.server.AccessKeySecret)
+	// 配置文件不存在时，使用命令行中的 endpoint 和 AK
+	options[OptionConfigFile] = str(filepath.Join(env.dir, "no-such-config"))
+	options[OptionCheckpointDir] = str(filepath.Join(env.dir, "checkpoint"))
+	options[OptionOutputDir] = str(filepath.Join(env.dir, "output"))
+	for k, v := range extra {
+		options[k] = v
+	}
+	return options
+}
+
+func (env *e2eEnv) run(command string, args []string, extra OptionMapType) error {
+	_, err := env.cm.RunCommand(command, args, env.options(extra))
+	return err
+}
+
+// writeFiles creates the files under dir, the key of files is the relative path
+func (env *e2eEnv) writeFiles(dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(env.t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(env.t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+// readFiles returns the relative paths and the contents of the files under dir
+func (env *e2eEnv) readFiles(dir string) map[string]string {
+	files := map[string]string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	require.NoError(env.t, err)
+	return files
+}
+
+// objects returns the objects under prefix by the relative keys, the directories uploaded are
+// empty objects ending with "/"
+func (env *e2eEnv) objects(prefix string) map[string]string {
+	objects := map[string]string{}
+	for _, key := range env.server.Objects(e2eBucket) {
+		if strings.HasPrefix(key, prefix) {
+			data, _ := env.server.Object(e2eBucket, key)
+			objects[strings.TrimPrefix(key, prefix)] = string(data)
+		}
+	}
+	return objects
+}
+
+func TestE2ECopyRecursive(t *testing.T) {
+	env := newE2EEnv(t)
+	recursive := true
+	files := map[string]string{
+		"a.txt":         "a",
+		"dir/b.txt":     "bb",
+		"dir/sub/c.txt": "ccc",
+		"中文 d.txt":      "dddd",
+	}
+	src := filepath.Join(env.dir, "src")
+	env.writeFiles(src, files)
+
+	// 上传目录，子目录会上传为以 / 结尾的空 object
+	err := env.run("cp", []string{src, CloudURLToString(e2eBucket, "up/")}, OptionMapType{OptionRecursion: &recursive})
+	require.NoError(t, err)
+	objects := env.objects("up/")
+	assert.Equal(t, "", objects["dir/"])
+	assert.Equal(t, "", objects["dir/sub/"])
+	delete(objects, "dir/")
+	delete(objects, "dir/sub/")
+	assert.Equal(t, files, objects)
+
+	// 下载目录
+	down := filepath.Join(env.dir, "down")
+	err = env.run("cp", []string{CloudURLToString(e2eBucket, "up/"), down}, OptionMapType{OptionRecursion: &recursive})
+	require.NoError(t, err)
+	assert.Equal(t, files, env.readFiles(down))
+
+	// bucket 内拷贝
+	err = env.run("cp", []string{CloudURLToString(e2eBucket, "up/dir/"), CloudURLToString(e2eBucket, "copy/")}, OptionMapType{OptionRecursion: &recursive})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"b.txt": "bb", "sub/": "", "sub/c.txt": "ccc"}, env.objects("copy/"))
+	assert.Equal(t, 3, env.server.Count("CopyObject"))
+}
+
+func TestE2ECopyDryRunOwnsStdout(t *testing.T) {
+	env := newE2EEnv(t)
+	stdoutData = false
+	defer func() { stdoutData = false }()
+	src := filepath.Join(env.dir, "src")
+	env.writeFiles(src, map[string]string{"a.txt": "a"})
+
+	recursive, dryrun := true, true
+	err := env.run("cp", []string{src, CloudURLToString(e2eBucket, "up/")}, OptionMapType{OptionRecursion: &recursive, OptionDryRun: &dryrun})
+	require.NoError(t, err)
+	// 计划输出到 stdout，不能再追加耗时
+	assert.True(t, stdoutData)
+	assert.Empty(t, env.objects("up/"))
+}
+
+func TestE2ECopyResume(t *testing.T) {
+	env := newE2EEnv(t)
+	content := bytes.Repeat([]byte("0123456789abcdef"), 25*1024)
+	src := filepath.Join(env.dir, "big.dat")
+	require.NoError(t, ioutil.WriteFile(src, content, 0644))
+
+	// 400K 按 100K 分成 4 片，第 3 片上传失败
+	threshold, partSize, parallel, retryTimes := "1024", "102400", "1", "1"
+	extra := OptionMapType{
+		OptionBigFileThreshold: &threshold,
+		OptionPartSize:         &partSize,
+		OptionParallel:         &parallel,
+		OptionRetryTimes:       &retryTimes,
+	}
+	env.server.SetFault(func(op, bucket, key string, r *http.Request) *osstest.Error {
+		if op == "UploadPart" && r.URL.Query().Get("partNumber") == "3" {
+			return &osstest.Error{Status: http.StatusInternalServerError, Code: "InternalError", Message: "injected"}
+		}
+		return nil
+	})
+	err := env.run("cp", []string{src, CloudURLToString(e2eBucket, "big.dat")}, extra)
+	assert.Error(t, err)
+	_, ok := env.server.Object(e2eBucket, "big.dat")
+	assert.False(t, ok)
+	assert.Equal(t, 1, env.server.Uploads(e2eBucket))
+
+	// 断点续传只上传剩下的 2 片
+	env.server.SetFault(nil)
+	failed := env.server.Count("UploadPart")
+	err = env.run("cp", []string{src, CloudURLToString(e2eBucket, "big.dat")}, extra)
+	require.NoError(t, err)
+	assert.Equal(t, 2, env.server.Count("UploadPart")-failed)
+	assert.Equal(t, 1, env.server.Count("InitiateMultipartUpload"))
+	assert.Equal(t, 0, env.server.Uploads(e2eBucket))
+	data, ok := env.server.Object(e2eBucket, "big.dat")
+	assert.True(t, ok)
+	assert.Equal(t, content, data)
+}
+
+func TestE2ESyncDelete(t *testing.T) {
+	env := newE2EEnv(t)
+	src := filepath.Join(env.dir, "src")
+	env.writeFiles(src, map[string]string{"keep.txt": "keep", "dir/new.txt": "new"})
+	for _, key := range []string{"sync/keep.txt", "sync/extra.txt", "sync/dir/extra.txt", "other/extra.txt"} {
+		require.NoError(t, env.server.PutObject(e2eBucket, key, []byte("old")))
+	}
+
+	del, force := true, true
+	err := env.run("sync", []string{src, CloudURLToString(e2eBucket, "sync/")}, OptionMapType{OptionDelete: &del, OptionForce: &force})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"keep.txt": "keep", "dir/": "", "dir/new.txt": "new"}, env.objects("sync/"))
+
+	// 前缀之外的 object 不会被删除
+	keys := env.server.Objects(e2eBucket)
+	sort.Strings(keys)
+	assert.Equal(t, []string{"other/extra.txt", "sync/dir/", "sync/dir/new.txt", "sync/keep.txt"}, keys)
+}
+
+func TestE2ESignV4(t *testing.T) {
+	env := newE2EEnv(t)
+	recursive := true
+	signVersion, region := "v4", osstest.DefaultRegion
+	extra := OptionMapType{OptionRecursion: &recursive, OptionSignVersion: &signVersion, OptionRegion: &region}
+	src := filepath.Join(env.dir, "src")
+	files := map[string]string{"a b.txt": "a", "dir/c~d.txt": "cd"}
+	env.writeFiles(src, files)
+
+	err := env.run("cp", []string{src, CloudURLToString(e2eBucket, "v4/")}, extra)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a b.txt": "a", "dir/": "", "dir/c~d.txt": "cd"}, env.objects("v4/"))
+
+	down := filepath.Join(env.dir, "down")
+	err = env.run("cp", []string{CloudURLToString(e2eBucket, "v4/"), down}, extra)
+	require.NoError(t, err)
+	assert.Equal(t, files, env.readFiles(down))
+}
+
+// captureStdout returns what f writes to os.Stdout
+func captureStdout(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	origin := os.Stdout
+	os.Stdout = w
+	out := make(chan string)
+	go func() {
+		data, _ := ioutil.ReadAll(r)
+		out <- string(data)
+	}()
+	defer func() {
+		os.Stdout = origin
+	}()
+	f()
+	w.Close()
+	return <-out
+}
+
+func TestE2EBucketConfigExportToStdout(t *testing.T) {
+	env := newE2EEnv(t)
+	stdoutData = false
+	defer func() { stdoutData = false }()
+	const target = "e2e-target"
+	env.server.CreateBucket(target)
+	// bucket-config 不支持 cp 的目录选项
+	force := true
+	extra := OptionMapType{OptionCheckpointDir: new(string), OptionOutputDir: new(string)}
+	forced := OptionMapType{OptionCheckpointDir: new(string), OptionOutputDir: new(string), OptionForce: &force}
+
+	src := filepath.Join(env.dir, "src.yaml")
+	env.writeFiles(env.dir, map[string]string{"src.yaml": "cors:\n  CORSRule:\n    AllowedOrigin: '*'\n    AllowedMethod: GET\ntagging:\n  TagSet:\n    Tag:\n      Key: env\n      Value: prod\n"})
+	require.NoError(t, env.run("bucket-config", []string{"apply", CloudURLToString(e2eBucket, ""), src}, forced))
+
+	var err error
+	exported := captureStdout(t, func() {
+		err = env.run("bucket-config", []string{"export", CloudURLToString(e2eBucket, "")}, extra)
+	})
+	require.NoError(t, err)
+	// 输出到 stdout 的配置不能再追加耗时，否则无法再 apply
+	assert.True(t, stdoutData)
+	assert.Contains(t, exported, "AllowedOrigin: '*'")
+
+	env.writeFiles(env.dir, map[string]string{"exported.yaml": exported})
+	require.NoError(t, env.run("bucket-config", []string{"apply", CloudURLToString(target, ""), filepath.Join(env.dir, "exported.yaml")}, forced))
+	again := captureStdout(t, func() {
+		err = env.run("bucket-config", []string{"export", CloudURLToString(target, "")}, extra)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, exported, again)
+}
+
+// e2eWatcher is a syncWatcher notified by the test
+type e2eWatcher struct {
+	changes chan struct{}
+}
+
+func (w *e2eWatcher) Changes() <-chan struct{} {
+	return w.changes
+}
+
+func (w *e2eWatcher) Close() error {
+	return nil
+}
+
+func TestE2ESyncWatchDelete(t *testing.T) {
+	env := newE2EEnv(t)
+	watcher := &e2eWatcher{changes: make(chan struct{})}
+	started := make(chan struct{})
+	origin := newSyncWatcher
+	newSyncWatcher = func(dir string, interval time.Duration, followSymlink bool) syncWatcher {
+		close(started)
+		return watcher
+	}
+	defer func() { newSyncWatcher = origin }()
+
+	src := filepath.Join(env.dir, "src")
+	env.writeFiles(src, map[string]string{"a.txt": "a", "b.txt": "b"})
+	for _, key := range []string{"watch/extra.txt", "watchdog/keep.txt"} {
+		require.NoError(t, env.server.PutObject(e2eBucket, key, []byte("old")))
+	}
+
+	// 目标前缀没有以 / 结尾，监听中的上传和删除与首次同步使用相同的前缀
+	del, force, watch, interval := true, true, true, "1"
+	extra := OptionMapType{OptionDelete: &del, OptionForce: &force, OptionWatch: &watch, OptionWatchInterval: &interval}
+	done := make(chan error, 1)
+	go func() {
+		done <- env.run("sync", []string{src, CloudURLToString(e2eBucket, "watch")}, extra)
+	}()
+	select {
+	case <-started:
+	case err := <-done:
+		require.FailNow(t, "sync exited before watching", "%v", err)
+	}
+	assert.Equal(t, map[string]string{"a.txt": "a", "b.txt": "b"}, env.objects("watch/"))
+
+	// 修改时间早于 --watch-interval 的文件才会上传
+	require.NoError(t, os.Remove(filepath.Join(src, "b.txt")))
+	env.writeFiles(src, map[string]string{"c.txt": "c"})
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, os.Chtimes(filepath.Join(src, "c.txt"), past, past))
+	watcher.changes <- struct{}{}
+	require.Eventually(t, func() bool {
+		_, ok := env.server.Object(e2eBucket, "watch/b.txt")
+		return !ok
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, map[string]string{"a.txt": "a", "c.txt": "c"}, env.objects("watch/"))
+	keys := env.server.Objects(e2eBucket)
+	sort.Strings(keys)
+	assert.Equal(t, []string{"watch/a.txt", "watch/c.txt", "watchdog/keep.txt"}, keys)
+
+	// 监听的目录被删除后以错误退出
+	require.NoError(t, os.RemoveAll(src))
+	watcher.changes <- struct{}{}
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "sync did not stop watching")
+	}
+}
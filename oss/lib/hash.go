@@ -186,13 +186,20 @@ func hashMD5(f io.Reader) error {
 }
 
 func hashCRC64(f io.Reader) error {
-	crc64Ins := crc64.New(crc64.MakeTable(crc64.ECMA))
-	w, _ := crc64Ins.(hash.Hash)
-	if _, err := io.Copy(w, f); err != nil {
+	result, err := crc64Of(f)
+	if err != nil {
 		return err
 	}
-
-	result := crc64Ins.Sum64()
 	fmt.Printf("%-28s: %d\n", HashCRC64, result)
 	return nil
 }
+
+// crc64Of returns the crc64 of f, the same as the x-oss-hash-crc64ecma of oss objects
+func crc64Of(f io.Reader) (uint64, error) {
+	crc64Ins := crc64.New(crc64.MakeTable(crc64.ECMA))
+	w, _ := crc64Ins.(hash.Hash)
+	if _, err := io.Copy(w, f); err != nil {
+		return 0, err
+	}
+	return crc64Ins.Sum64(), nil
+}
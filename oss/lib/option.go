@@ -69,8 +69,8 @@ var OptionMap = map[string]Option{
 		fmt.Sprintf("开启大文件断点续传的文件大小阈值，默认值:%dM，取值范围：%dB-%dB", DefaultBigFileThreshold/1048576, MinBigFileThreshold, MaxBigFileThreshold),
 		fmt.Sprintf("the threshold of file size, the file size larger than the threshold will use resume upload or download(default: %d), value range is: %d-%d", DefaultBigFileThreshold, MinBigFileThreshold, MaxBigFileThreshold)},
 	OptionPartSize: Option{"", "--part-size", strconv.FormatInt(DefaultPartSize, 10), OptionTypeInt64, strconv.FormatInt(MinPartSize, 10), strconv.FormatInt(MaxPartSize, 10),
-		fmt.Sprintf("分片大小，单位为Byte，默认情况下ossutil根据文件大小自行计算合适的分片大小值。如果有特殊需求或者需要性能调优，可以设置该值，取值范围：%d-%d(Byte)", MinPartSize, MaxPartSize),
-		fmt.Sprintf("Part size, the unit is: Byte, in default situation, ossutil will calculate the suitable part size according to file size. The option is useful when user has special needs or user need to performance tuning, the value range is: %d-%d(Byte)", MinPartSize, MaxPartSize)},
+		fmt.Sprintf("分片大小，单位为Byte，默认情况下ossutil根据文件大小自行计算合适的分片大小值。如果有特殊需求或者需要性能调优，可以设置该值，取值范围：%d-%d(Byte)。从标准输入上传或者下载到标准输出时默认值为%d", MinPartSize, MaxPartSize, DefaultStreamPartSize),
+		fmt.Sprintf("Part size, the unit is: Byte, in default situation, ossutil will calculate the suitable part size according to file size. The option is useful when user has special needs or user need to performance tuning, the value range is: %d-%d(Byte). When uploading from stdin or downloading to stdout, the default value is %d", MinPartSize, MaxPartSize, DefaultStreamPartSize)},
 	OptionDisableCRC64: Option{"", "--disable-crc64", "", OptionTypeFlagTrue, "", "", "该选项关闭crc64，默认情况下，ossutil进行数据传输都打开crc64校验。", "Disable crc64, in default situation, ossutil open crc64 check when transmit data."},
 	OptionCheckpointDir: Option{"", "--checkpoint-dir", CheckpointDir, OptionTypeString, "", "",
 		fmt.Sprintf("checkpoint目录的路径(默认值为:%s)，断点续传时，操作失败ossutil会自动创建该目录，并在该目录下记录checkpoint信息，操作成功会删除该目录。如果指定了该选项，请确保所指定的目录可以被删除。", CheckpointDir),
@@ -78,6 +78,27 @@ var OptionMap = map[string]Option{
 	OptionSnapshotPath: Option{"", "--snapshot-path", "", OptionTypeString, "", "",
 		"该选项用于在某些场景下加速增量上传批量文件或者增量下载批量object。在cp上传文件或者下载object时使用该选项，ossutil在指定的目录下生成快照文件，记录文件上传或者object下载的快照信息，在下一次指定该选项上传或下载时，ossutil会读取指定目录下的快照信息进行增量上传或者下载。用户指定的snapshot目录必须为本地文件系统上的可写目录，若该目录不存在，ossutil会创建该文件用于记录快照信息，如果该目录已存在，ossutil会读取里面的快照信息，根据快照信息进行增量上传（只上传上次未成功上传的文件和本地进行过修改的文件）或者增量下载（只下载上次未成功下载的object和修改过的object），并更新快照信息。注意：该选项在本地记录了成功上传的文件的本地lastModifiedTime或者记录了下载object的lastModifiedTime，从而在下次上传或者下载时通过比较lastModifiedTime来决定是否跳过相同文件的上传或者跳过相同的object下载。当使用该选项上传时，请确保两次上传期间没有其他用户更改了oss上的对应object。当不满足该场景时，如果想要增量上传批量文件，请使用--update选项。ossutil不会主动删除snapshot-path下的快照信息，当用户确定快照信息无用时，请用户及时自行删除snapshot-path。",
 		"This option is used to accelerate the incremental upload of batch files or download objects in certain scenarios. If you use the option when upload files or download objects, ossutil will generate files to record the snapshot information in the specified directory. When the next time you upload files or download objects with the option, ossutil will read the snapshot information under the specified directory for incremental upload or incremental download. The snapshot-path you specified must be a local file system directory can be written in, if the directory does not exist, ossutil creates the files for recording snapshot information, else ossutil will read snapshot information from the path for incremental upload(ossutil will only upload the files which haven't not been successfully uploaded to oss or been locally modified) or incremental download(ossutil will only download the objects which have not been successfully downloaded or have been modified), and update the snapshot information to the directory. Note: The option record the lastModifiedTime of local files which have been successfully uploaded in local file system or lastModifiedTime of objects which have been successfully downloaded, and compare the lastModifiedTime of local files or objects in the next cp to decided whether to skip the file or object. If you use the option to achieve incremental upload, please make sure no other user modified the corresponding object in oss during the two uploads. If you can not guarantee the scenarios, please use --update option to achieve incremental upload. In addition, ossutil does not automatically delete snapshot-path snapshot information, in order to avoid too much snapshot information, when the snapshot information is useless, please clean up your own snapshot-path on your own immediately."},
+	OptionChecksum: Option{"", "--checksum", "", OptionTypeFlagTrue, "", "",
+		"增量操作时根据内容而不是修改时间判断是否跳过：当目标端存在且大小和crc64都与源端相同时跳过，否则执行上传、下载或拷贝。本地文件的crc64会缓存在--checksum-cache指定的目录中。",
+		"Decide whether to skip by content instead of modification time: skip when the destination exists with the same size and crc64 as the source, otherwise upload, download or copy. The crc64 of local files is cached in the directory of --checksum-cache."},
+	OptionChecksumCache: Option{"", "--checksum-cache", "", OptionTypeString, "", "",
+		fmt.Sprintf("--checksum使用的本地文件crc64缓存目录，缓存以文件路径、修改时间和大小为准，未变化的文件不会重复计算。默认值为：用户主目录下的%s目录。", ChecksumCacheDir),
+		fmt.Sprintf("Directory of the crc64 cache of local files used by --checksum, an entry is reused while the path, modification time and size of the file are unchanged. The default value is: %s in the home directory.", ChecksumCacheDir)},
+	OptionDryRun: Option{"", "--dryrun", "", OptionTypeFlagTrue, "", "",
+		"只输出执行计划而不进行传输和删除：每个文件或object一行json，包含action(upload/download/copy/skip/delete/move)、reason、source、destination和size。",
+		"Print the plan without transferring or deleting anything: one json line per file or object with action(upload/download/copy/skip/delete/move), reason, source, destination and size."},
+	OptionReportFormat: Option{"", "--report-format", ReportFormatText, OptionTypeAlternative, fmt.Sprintf("%s/%s", ReportFormatText, ReportFormatJSON), "",
+		fmt.Sprintf("report文件的格式，默认值：%s，取值范围：%s/%s。%s格式记录每个文件或object的结果（字节数、耗时、重试次数、错误码）和最后的汇总，无论是否出错都会保留；和--dryrun一起使用时，以一个json文档输出执行计划。", ReportFormatText, ReportFormatText, ReportFormatJSON, ReportFormatJSON),
+		fmt.Sprintf("Format of the report file(default: %s), value range is: %s/%s. %s records the result of every file or object(bytes, duration, retries, error code) and a final summary, and is kept whether errors happen or not; with --dryrun, the plan is printed as one json document.", ReportFormatText, ReportFormatText, ReportFormatJSON, ReportFormatJSON)},
+	OptionConfigFormat: Option{"", "--config-format", "", OptionTypeAlternative, fmt.Sprintf("%s/%s", ConfigFormatYAML, ConfigFormatJSON), "",
+		fmt.Sprintf("bucket-config export输出文档的格式，默认值：%s，取值范围：%s/%s。导出到以.json结尾的文件时默认为%s。", ConfigFormatYAML, ConfigFormatYAML, ConfigFormatJSON, ConfigFormatJSON),
+		fmt.Sprintf("Format of the document bucket-config export writes(default: %s), value range is: %s/%s. It defaults to %s when exporting to a file ending with .json.", ConfigFormatYAML, ConfigFormatYAML, ConfigFormatJSON, ConfigFormatJSON)},
+	OptionWatch: Option{"", "--watch", "", OptionTypeFlagTrue, "", "",
+		"sync上传完成后继续监控本地目录（Linux上使用inotify，其他系统定时扫描），上传新增和修改的文件，和--delete一起使用时删除本地已删除文件对应的object，直到按下Ctrl+C。",
+		"After the sync upload, keep watching the local directory(inotify on Linux, periodic scans elsewhere), upload new and modified files, and delete the objects of removed files with --delete, until Ctrl+C is pressed."},
+	OptionWatchInterval: Option{"", "--watch-interval", strconv.Itoa(WatchInterval), OptionTypeInt64, strconv.FormatInt(MinWatchInterval, 10), strconv.FormatInt(MaxWatchInterval, 10),
+		fmt.Sprintf("--watch的间隔秒数，默认值：%d，取值范围：%d-%d。文件在该时间内没有变化才会上传，定时扫描时也是扫描的间隔。", WatchInterval, MinWatchInterval, MaxWatchInterval),
+		fmt.Sprintf("Interval seconds of --watch(default: %d), value range is: %d-%d. A file is uploaded once it has not changed for the interval, which is also the interval of periodic scans.", WatchInterval, MinWatchInterval, MaxWatchInterval)},
 	OptionRetryTimes: Option{"", "--retry-times", strconv.Itoa(RetryTimes), OptionTypeInt64, strconv.FormatInt(MinRetryTimes, 10), strconv.FormatInt(MaxRetryTimes, 10),
 		fmt.Sprintf("当错误发生时的重试次数，默认值：%d，取值范围：%d-%d", RetryTimes, MinRetryTimes, MaxRetryTimes),
 		fmt.Sprintf("retry times when fail(default: %d), value range is: %d-%d", RetryTimes, MinRetryTimes, MaxRetryTimes)},
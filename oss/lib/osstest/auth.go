@@ -0,0 +1,220 @@
+package osstest
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// signKeyList are the sub resources signed by V1, the same as the sdk
+var signKeyList = []string{"acl", "uploads", "location", "cors",
+	"logging", "website", "referer", "lifecycle",
+	"delete", "append", "tagging", "objectMeta",
+	"uploadId", "partNumber", "security-token",
+	"position", "img", "style", "styleName",
+	"replication", "replicationProgress",
+	"replicationLocation", "cname", "bucketInfo",
+	"comp", "qos", "live", "status", "vod",
+	"startTime", "endTime", "symlink",
+	"x-oss-process", "response-content-type", "x-oss-traffic-limit",
+	"response-content-language", "response-expires",
+	"response-cache-control", "response-content-disposition",
+	"response-content-encoding", "udf", "udfName", "udfImage",
+	"udfId", "udfImageDesc", "udfApplication",
+	"udfApplicationLog", "restore", "callback", "callback-var", "qosInfo",
+	"policy", "stat", "encryption", "versions", "versioning", "versionId", "requestPayment",
+	"x-oss-request-payer", "sequential",
+	"inventory", "inventoryId", "continuation-token", "asyncFetch",
+	"worm", "wormId", "wormExtend", "withHashContext",
+	"x-oss-enable-md5", "x-oss-enable-sha1", "x-oss-enable-sha256",
+	"x-oss-hash-ctx", "x-oss-md5-ctx", "transferAcceleration",
+	"regionList", "cloudboxes", "x-oss-ac-source-ip", "x-oss-ac-subnet-mask", "x-oss-ac-vpc-id", "x-oss-ac-forward-allow",
+	"metaQuery", "resourceGroup", "rtc", "x-oss-async-process", "responseHeader",
+}
+
+const (
+	authPrefixV1 = "OSS "
+	authPrefixV4 = "OSS4-HMAC-SHA256 "
+)
+
+// verifySignature checks the Authorization header of the request by V1 or V4
+func (s *Server) verifySignature(req *request) *Error {
+	auth := req.Header.Get("Authorization")
+	switch {
+	case auth == "":
+		return newError(http.StatusForbidden, "AccessDenied", "AccessDenied, anonymous access is not allowed by osstest.")
+	case strings.HasPrefix(auth, authPrefixV1):
+		return s.verifyV1(req, strings.TrimPrefix(auth, authPrefixV1))
+	case strings.HasPrefix(auth, authPrefixV4):
+		return s.verifyV4(req, strings.TrimPrefix(auth, authPrefixV4))
+	}
+	return newError(http.StatusBadRequest, "InvalidArgument", "Authorization %s is not supported by osstest, only V1 and V4 are.", auth)
+}
+
+func (s *Server) verifyV1(req *request, auth string) *Error {
+	pos := strings.LastIndex(auth, ":")
+	if pos < 0 {
+		return newError(http.StatusBadRequest, "InvalidArgument", "Authorization header is invalid.")
+	}
+	accessKeyID, signature := auth[:pos], auth[pos+1:]
+	if accessKeyID != s.AccessKeyID {
+		return newError(http.StatusForbidden, "InvalidAccessKeyId", "The OSS Access Key Id you provided does not exist in our records.")
+	}
+
+	params := url.Values{}
+	for k, v := range req.query {
+		if isSignKey(k) {
+			params[k] = v
+		}
+	}
+	resource := "/"
+	if req.bucket != "" {
+		resource += req.bucket + "/" + req.key
+	}
+	if len(params) > 0 {
+		resource += "?" + canonicalParams(params, func(s string) string { return s })
+	}
+
+	headers := map[string]string{}
+	for k, v := range req.Header {
+		if lower := strings.ToLower(k); strings.HasPrefix(lower, "x-oss-") {
+			headers[lower] = v[0]
+		}
+	}
+	stringToSign := req.Method + "\n" + req.Header.Get("Content-MD5") + "\n" + req.Header.Get("Content-Type") + "\n" +
+		req.Header.Get("Date") + "\n" + canonicalHeaders(headers) + resource
+
+	h := hmac.New(sha1.New, []byte(s.AccessKeySecret))
+	h.Write([]byte(stringToSign))
+	if !hmac.Equal([]byte(signature), []byte(base64.StdEncoding.EncodeToString(h.Sum(nil)))) {
+		return signatureError(stringToSign)
+	}
+	return nil
+}
+
+func (s *Server) verifyV4(req *request, auth string) *Error {
+	fields := map[string]string{}
+	for _, field := range strings.Split(auth, ",") {
+		if pos := strings.Index(field, "="); pos > 0 {
+			fields[strings.TrimSpace(field[:pos])] = strings.TrimSpace(field[pos+1:])
+		}
+	}
+	// Credential=ak/day/region/product/aliyun_v4_request
+	scope := strings.Split(fields["Credential"], "/")
+	if len(scope) != 5 || scope[4] != "aliyun_v4_request" || fields["Signature"] == "" {
+		return newError(http.StatusBadRequest, "InvalidArgument", "Authorization header is invalid.")
+	}
+	accessKeyID, day, region, product := scope[0], scope[1], scope[2], scope[3]
+	if accessKeyID != s.AccessKeyID {
+		return newError(http.StatusForbidden, "InvalidAccessKeyId", "The OSS Access Key Id you provided does not exist in our records.")
+	}
+	if region != s.Region || product != "oss" {
+		return newError(http.StatusBadRequest, "InvalidArgument", "Invalid signing region %s or product %s, expected %s and oss.", region, product, s.Region)
+	}
+
+	params := url.Values{}
+	for k, v := range req.query {
+		params[url.QueryEscape(k)] = v
+	}
+
+	resource := "/"
+	if req.bucket != "" {
+		key := strings.Replace(escapeV4(req.key), "%2F", "/", -1)
+		resource += req.bucket + "/" + key
+	}
+
+	additional := []string{}
+	if fields["AdditionalHeaders"] != "" {
+		additional = strings.Split(fields["AdditionalHeaders"], ";")
+	}
+	headers := map[string]string{}
+	for k, v := range req.Header {
+		lower := strings.ToLower(k)
+		if lower == "content-md5" || lower == "content-type" || strings.HasPrefix(lower, "x-oss-") || containsString(additional, lower) {
+			headers[lower] = strings.Trim(v[0], " ")
+		}
+	}
+	payload := req.Header.Get("x-oss-content-sha256")
+	if payload == "" {
+		payload = "UNSIGNED-PAYLOAD"
+	}
+	canonicalRequest := req.Method + "\n" + resource + "\n" + canonicalParams(params, escapeV4) + "\n" +
+		canonicalHeaders(headers) + "\n" + strings.Join(additional, ";") + "\n" + payload
+
+	date := req.Header.Get("x-oss-date")
+	if date == "" {
+		date = req.Header.Get("Date")
+	}
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "OSS4-HMAC-SHA256\n" + date + "\n" + day + "/" + region + "/" + product + "/aliyun_v4_request\n" + hex.EncodeToString(hashed[:])
+
+	key := hmacSHA256([]byte("aliyun_v4"+s.AccessKeySecret), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, product)
+	key = hmacSHA256(key, "aliyun_v4_request")
+	if !hmac.Equal([]byte(fields["Signature"]), []byte(hex.EncodeToString(hmacSHA256(key, stringToSign)))) {
+		return signatureError(canonicalRequest)
+	}
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func escapeV4(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
+// canonicalParams joins the params sorted by key, a param without value is its key only
+func canonicalParams(params url.Values, escape func(string) string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		if v := params[k][0]; v != "" {
+			keys[i] = k + "=" + escape(v)
+		}
+	}
+	return strings.Join(keys, "&")
+}
+
+func canonicalHeaders(headers map[string]string) string {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	text := ""
+	for _, k := range keys {
+		text += k + ":" + headers[k] + "\n"
+	}
+	return text
+}
+
+func signatureError(signed string) *Error {
+	return newError(http.StatusForbidden, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided, signed: %q", signed)
+}
+
+func isSignKey(key string) bool {
+	return containsString(signKeyList, key)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,689 @@
+package osstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type bucket struct {
+	name        string
+	created     time.Time
+	acl         string
+	objects     map[string]*object
+	uploads     map[string]*upload
+	configs     map[string][]byte
+	inventories map[string][]byte
+	styles      map[string]*style
+}
+
+type style struct {
+	content  string
+	created  time.Time
+	modified time.Time
+}
+
+func newBucket(name, acl string) *bucket {
+	return &bucket{
+		name:        name,
+		created:     time.Now(),
+		acl:         acl,
+		objects:     map[string]*object{},
+		uploads:     map[string]*upload{},
+		configs:     map[string][]byte{},
+		inventories: map[string][]byte{},
+		styles:      map[string]*style{},
+	}
+}
+
+func (b *bucket) sortedKeys() []string {
+	keys := make([]string, 0, len(b.objects))
+	for k := range b.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type owner struct {
+	ID          string `xml:"ID"`
+	DisplayName string `xml:"DisplayName"`
+}
+
+var defaultOwner = owner{ownerID, ownerID}
+
+type bucketProperties struct {
+	Name             string `xml:"Name"`
+	Location         string `xml:"Location"`
+	CreationDate     string `xml:"CreationDate"`
+	StorageClass     string `xml:"StorageClass"`
+	ExtranetEndpoint string `xml:"ExtranetEndpoint"`
+	IntranetEndpoint string `xml:"IntranetEndpoint"`
+	Region           string `xml:"Region"`
+}
+
+func (s *Server) bucketProperties(b *bucket) bucketProperties {
+	host := strings.TrimPrefix(s.URL, "http://")
+	return bucketProperties{
+		Name:             b.name,
+		Location:         "oss-" + s.Region,
+		CreationDate:     formatTime(b.created),
+		StorageClass:     "Standard",
+		ExtranetEndpoint: host,
+		IntranetEndpoint: host,
+		Region:           s.Region,
+	}
+}
+
+func (s *Server) listBuckets(req *request) *Error {
+	type result struct {
+		XMLName xml.Name           `xml:"ListAllMyBucketsResult"`
+		Prefix  string             `xml:"Prefix"`
+		Owner   owner              `xml:"Owner"`
+		Buckets []bucketProperties `xml:"Buckets>Bucket"`
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prefix := req.query.Get("prefix")
+	res := result{Prefix: prefix, Owner: defaultOwner, Buckets: []bucketProperties{}}
+	for _, b := range s.buckets {
+		if strings.HasPrefix(b.name, prefix) {
+			res.Buckets = append(res.Buckets, s.bucketProperties(b))
+		}
+	}
+	sort.Slice(res.Buckets, func(i, j int) bool { return res.Buckets[i].Name < res.Buckets[j].Name })
+	return writeXML(req.w, res)
+}
+
+func (s *Server) putBucket(req *request) *Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.buckets[req.bucket]; !ok {
+		acl := req.Header.Get("x-oss-acl")
+		if acl == "" {
+			acl = "private"
+		}
+		s.buckets[req.bucket] = newBucket(req.bucket, acl)
+	}
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *Server) deleteBucket(req *request) *Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	if len(b.objects) > 0 || len(b.uploads) > 0 {
+		return newError(http.StatusConflict, "BucketNotEmpty", "The bucket you tried to delete is not empty.")
+	}
+	delete(s.buckets, req.bucket)
+	req.w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *Server) getBucketInfo(req *request) *Error {
+	type result struct {
+		XMLName xml.Name `xml:"BucketInfo"`
+		Bucket  struct {
+			bucketProperties
+			Owner owner  `xml:"Owner"`
+			ACL   string `xml:"AccessControlList>Grant"`
+		} `xml:"Bucket"`
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	var res result
+	res.Bucket.bucketProperties = s.bucketProperties(b)
+	res.Bucket.Owner = defaultOwner
+	res.Bucket.ACL = b.acl
+	return writeXML(req.w, res)
+}
+
+func (s *Server) getBucketLocation(req *request) *Error {
+	type result struct {
+		XMLName  xml.Name `xml:"LocationConstraint"`
+		Location string   `xml:",chardata"`
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.bucketOf(req); err != nil {
+		return err
+	}
+	return writeXML(req.w, result{Location: "oss-" + s.Region})
+}
+
+type accessControlPolicy struct {
+	XMLName xml.Name `xml:"AccessControlPolicy"`
+	Owner   owner    `xml:"Owner"`
+	ACL     string   `xml:"AccessControlList>Grant"`
+}
+
+func (s *Server) putBucketACL(req *request) *Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	acl := req.Header.Get("x-oss-acl")
+	if acl != "private" && acl != "public-read" && acl != "public-read-write" {
+		return newError(http.StatusBadRequest, "InvalidArgument", "no such bucket access control exists")
+	}
+	b.acl = acl
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *Server) getBucketACL(req *request) *Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	return writeXML(req.w, accessControlPolicy{Owner: defaultOwner, ACL: b.acl})
+}
+
+// listEntries lists the keys and the common prefixes after marker, and returns the marker
+// of the next page if truncated
+func (b *bucket) listEntries(prefix, marker, delimiter string, maxKeys int) ([]string, []string, string) {
+	keys := []string{}
+	prefixes := []string{}
+	last := ""
+	for _, key := range b.sortedKeys() {
+		if key <= marker || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		// the marker of a page ending with a common prefix is the prefix
+		if delimiter != "" && strings.HasSuffix(marker, delimiter) && strings.HasPrefix(key, marker) {
+			continue
+		}
+		commonPrefix := ""
+		if delimiter != "" {
+			if pos := strings.Index(key[len(prefix):], delimiter); pos >= 0 {
+				commonPrefix = key[:len(prefix)+pos+len(delimiter)]
+			}
+		}
+		if commonPrefix != "" && len(prefixes) > 0 && prefixes[len(prefixes)-1] == commonPrefix {
+			continue
+		}
+		if len(keys)+len(prefixes) == maxKeys {
+			return keys, prefixes, last
+		}
+		if commonPrefix != "" {
+			prefixes = append(prefixes, commonPrefix)
+			last = commonPrefix
+		} else {
+			keys = append(keys, key)
+			last = key
+		}
+	}
+	return keys, prefixes, ""
+}
+
+type objectProperties struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Type         string `xml:"Type"`
+	Size         int    `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+	Owner        *owner `xml:"Owner,omitempty"`
+}
+
+func (req *request) objectProperties(b *bucket, keys []string, withOwner bool) []objectProperties {
+	list := make([]objectProperties, 0, len(keys))
+	for _, key := range keys {
+		obj := b.objects[key]
+		props := objectProperties{
+			Key:          req.encodeKey(key),
+			LastModified: formatTime(obj.modified),
+			ETag:         obj.quotedETag(),
+			Type:         obj.objectType,
+			Size:         len(obj.data),
+			StorageClass: obj.storageClass,
+		}
+		if withOwner {
+			props.Owner = &defaultOwner
+		}
+		list = append(list, props)
+	}
+	return list
+}
+
+func (req *request) commonPrefixes(prefixes []string) []string {
+	encoded := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		encoded = append(encoded, req.encodeKey(prefix))
+	}
+	return encoded
+}
+
+func (req *request) maxKeys() (int, *Error) {
+	maxKeys := 100
+	if v := req.query.Get("max-keys"); v != "" {
+		var err error
+		if maxKeys, err = strconv.Atoi(v); err != nil || maxKeys < 1 || maxKeys > 1000 {
+			return 0, newError(http.StatusBadRequest, "InvalidArgument", "max-keys %s is invalid", v)
+		}
+	}
+	return maxKeys, nil
+}
+
+func (s *Server) listObjects(req *request) *Error {
+	type result struct {
+		XMLName        xml.Name           `xml:"ListBucketResult"`
+		Name           string             `xml:"Name"`
+		Prefix         string             `xml:"Prefix"`
+		Marker         string             `xml:"Marker"`
+		MaxKeys        int                `xml:"MaxKeys"`
+		Delimiter      string             `xml:"Delimiter"`
+		EncodingType   string             `xml:"EncodingType,omitempty"`
+		IsTruncated    bool               `xml:"IsTruncated"`
+		NextMarker     string             `xml:"NextMarker,omitempty"`
+		Contents       []objectProperties `xml:"Contents"`
+		CommonPrefixes []string           `xml:"CommonPrefixes>Prefix"`
+	}
+	maxKeys, err := req.maxKeys()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	prefix, marker, delimiter := req.query.Get("prefix"), req.query.Get("marker"), req.query.Get("delimiter")
+	keys, prefixes, next := b.listEntries(prefix, marker, delimiter, maxKeys)
+	return writeXML(req.w, result{
+		Name:           b.name,
+		Prefix:         req.encodeKey(prefix),
+		Marker:         req.encodeKey(marker),
+		MaxKeys:        maxKeys,
+		Delimiter:      req.encodeKey(delimiter),
+		EncodingType:   req.query.Get("encoding-type"),
+		IsTruncated:    next != "",
+		NextMarker:     req.encodeKey(next),
+		Contents:       req.objectProperties(b, keys, true),
+		CommonPrefixes: req.commonPrefixes(prefixes),
+	})
+}
+
+func (s *Server) listObjectsV2(req *request) *Error {
+	type result struct {
+		XMLName               xml.Name           `xml:"ListBucketResult"`
+		Name                  string             `xml:"Name"`
+		Prefix                string             `xml:"Prefix"`
+		StartAfter            string             `xml:"StartAfter,omitempty"`
+		ContinuationToken     string             `xml:"ContinuationToken,omitempty"`
+		MaxKeys               int                `xml:"MaxKeys"`
+		Delimiter             string             `xml:"Delimiter"`
+		EncodingType          string             `xml:"EncodingType,omitempty"`
+		IsTruncated           bool               `xml:"IsTruncated"`
+		NextContinuationToken string             `xml:"NextContinuationToken,omitempty"`
+		KeyCount              int                `xml:"KeyCount"`
+		Contents              []objectProperties `xml:"Contents"`
+		CommonPrefixes        []string           `xml:"CommonPrefixes>Prefix"`
+	}
+	maxKeys, err := req.maxKeys()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	prefix, startAfter, delimiter := req.query.Get("prefix"), req.query.Get("start-after"), req.query.Get("delimiter")
+	// the continuation token is the marker of the next page
+	marker := startAfter
+	if token := req.query.Get("continuation-token"); token != "" {
+		marker = token
+	}
+	keys, prefixes, next := b.listEntries(prefix, marker, delimiter, maxKeys)
+	return writeXML(req.w, result{
+		Name:                  b.name,
+		Prefix:                req.encodeKey(prefix),
+		StartAfter:            req.encodeKey(startAfter),
+		ContinuationToken:     req.query.Get("continuation-token"),
+		MaxKeys:               maxKeys,
+		Delimiter:             req.encodeKey(delimiter),
+		EncodingType:          req.query.Get("encoding-type"),
+		IsTruncated:           next != "",
+		NextContinuationToken: next,
+		KeyCount:              len(keys) + len(prefixes),
+		Contents:              req.objectProperties(b, keys, req.query.Get("fetch-owner") == "true"),
+		CommonPrefixes:        req.commonPrefixes(prefixes),
+	})
+}
+
+func (s *Server) deleteMultipleObjects(req *request) *Error {
+	var body struct {
+		Quiet   bool `xml:"Quiet"`
+		Objects []struct {
+			Key string `xml:"Key"`
+		} `xml:"Object"`
+	}
+	data, _ := ioutil.ReadAll(req.Body)
+	if err := xml.Unmarshal(data, &body); err != nil {
+		return newError(http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed.")
+	}
+	if len(body.Objects) > 1000 {
+		return newError(http.StatusBadRequest, "MalformedXML", "Too many objects to delete.")
+	}
+
+	type deleted struct {
+		Key string `xml:"Key"`
+	}
+	type result struct {
+		XMLName      xml.Name  `xml:"DeleteResult"`
+		EncodingType string    `xml:"EncodingType,omitempty"`
+		Deleted      []deleted `xml:"Deleted"`
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	res := result{EncodingType: req.query.Get("encoding-type")}
+	for _, obj := range body.Objects {
+		delete(b.objects, obj.Key)
+		if !body.Quiet {
+			res.Deleted = append(res.Deleted, deleted{req.encodeKey(obj.Key)})
+		}
+	}
+	return writeXML(req.w, res)
+}
+
+// bucketConfig is a sub resource of the bucket kept as the body put
+type bucketConfig struct {
+	name         string
+	notFoundCode string
+	// the body of get if not put, not found if empty
+	defaultBody string
+	deletable   bool
+	json        bool
+	// only get is served, the configuration is changed by other apis
+	getOnly bool
+}
+
+var bucketConfigs = map[string]bucketConfig{
+	"cors":      {name: "BucketCors", notFoundCode: "NoSuchCORSConfiguration", deletable: true},
+	"lifecycle": {name: "BucketLifecycle", notFoundCode: "NoSuchLifecycle", deletable: true},
+	"referer": {name: "BucketReferer",
+		defaultBody: "<RefererConfiguration><AllowEmptyReferer>true</AllowEmptyReferer><RefererList/></RefererConfiguration>"},
+	"website":    {name: "BucketWebsite", notFoundCode: "NoSuchWebsiteConfiguration", deletable: true},
+	"policy":     {name: "BucketPolicy", notFoundCode: "NoSuchBucketPolicy", deletable: true, json: true},
+	"logging":    {name: "BucketLogging", defaultBody: "<BucketLoggingStatus/>", deletable: true},
+	"encryption": {name: "BucketEncryption", notFoundCode: "NoSuchServerSideEncryptionRule", deletable: true},
+	"versioning": {name: "BucketVersioning", defaultBody: "<VersioningConfiguration/>"},
+	"tagging":    {name: "BucketTags", defaultBody: "<Tagging><TagSet/></Tagging>", deletable: true},
+	"qosInfo":    {name: "BucketQoSInfo", notFoundCode: "NoSuchQoSConfiguration", deletable: true},
+	"accessmonitor": {name: "BucketAccessMonitor",
+		defaultBody: "<AccessMonitorConfiguration><Status>Disabled</Status></AccessMonitorConfiguration>"},
+	"worm":        {name: "BucketWorm", notFoundCode: "NoSuchWORMConfiguration", getOnly: true},
+	"replication": {name: "BucketReplication", notFoundCode: "NoSuchReplicationConfiguration", getOnly: true},
+	"cname":       {name: "Cname", defaultBody: "<ListCnameResult/>", getOnly: true},
+}
+
+func bucketConfigParam(req *request) (string, bool) {
+	for param := range bucketConfigs {
+		if req.has(param) {
+			return param, true
+		}
+	}
+	return "", false
+}
+
+func routeBucketConfig(method, param string) (string, handlerFunc) {
+	config := bucketConfigs[param]
+	get := func(s *Server, req *request) *Error { return s.getBucketConfig(req, param) }
+	if config.getOnly {
+		return routeMethod(method, config.name, nil, get, nil)
+	}
+	put := func(s *Server, req *request) *Error { return s.putBucketConfig(req, param) }
+	var del handlerFunc
+	if config.deletable {
+		del = func(s *Server, req *request) *Error { return s.deleteBucketConfig(req, param) }
+	}
+	return routeMethod(method, config.name, put, get, del)
+}
+
+func (s *Server) putBucketConfig(req *request, param string) *Error {
+	data, _ := ioutil.ReadAll(req.Body)
+	if bucketConfigs[param].json {
+		if !json.Valid(data) {
+			return newError(http.StatusBadRequest, "InvalidPolicyDocument", "The policy document is not valid json.")
+		}
+	} else if !wellFormedXML(data) {
+		return newError(http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed.")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	b.configs[param] = data
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func wellFormedXML(data []byte) bool {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	root := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return root
+		}
+		if err != nil {
+			return false
+		}
+		if _, ok := token.(xml.StartElement); ok {
+			root = true
+		}
+	}
+}
+
+func (s *Server) getBucketConfig(req *request, param string) *Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	config := bucketConfigs[param]
+	data, ok := b.configs[param]
+	if !ok {
+		if config.defaultBody == "" {
+			return newError(http.StatusNotFound, config.notFoundCode, "The %s configuration does not exist.", param)
+		}
+		data = []byte(xml.Header + config.defaultBody)
+	}
+	contentType := "application/xml"
+	if config.json {
+		contentType = "application/json"
+	}
+	writeBody(req.w, contentType, data)
+	return nil
+}
+
+func (s *Server) deleteBucketConfig(req *request, param string) *Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	delete(b.configs, param)
+	req.w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *Server) putBucketInventory(req *request) *Error {
+	var body struct {
+		XMLName xml.Name `xml:"InventoryConfiguration"`
+		ID      string   `xml:"Id"`
+	}
+	data, _ := ioutil.ReadAll(req.Body)
+	if err := xml.Unmarshal(data, &body); err != nil {
+		return newError(http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed.")
+	}
+	id := req.query.Get("inventoryId")
+	if id == "" || body.ID != id {
+		return newError(http.StatusBadRequest, "InvalidArgument", "The inventory id %s does not match %s of the configuration.", id, body.ID)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	b.inventories[id] = []byte(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(data)), strings.TrimSpace(xml.Header))))
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *Server) getBucketInventory(req *request) *Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	if id := req.query.Get("inventoryId"); id != "" {
+		data, ok := b.inventories[id]
+		if !ok {
+			return newError(http.StatusNotFound, "NoSuchInventory", "The inventory %s does not exist.", id)
+		}
+		writeBody(req.w, "application/xml", append([]byte(xml.Header), data...))
+		return nil
+	}
+
+	ids := make([]string, 0, len(b.inventories))
+	for id := range b.inventories {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	body := xml.Header + "<ListInventoryConfigurationsResult>"
+	for _, id := range ids {
+		body += string(b.inventories[id])
+	}
+	body += "<IsTruncated>false</IsTruncated></ListInventoryConfigurationsResult>"
+	writeBody(req.w, "application/xml", []byte(body))
+	return nil
+}
+
+func (s *Server) deleteBucketInventory(req *request) *Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	delete(b.inventories, req.query.Get("inventoryId"))
+	req.w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type styleResult struct {
+	XMLName        xml.Name `xml:"Style"`
+	Name           string   `xml:"Name"`
+	Content        string   `xml:"Content"`
+	CreateTime     string   `xml:"CreateTime"`
+	LastModifyTime string   `xml:"LastModifyTime"`
+}
+
+func (s *Server) putBucketStyle(req *request) *Error {
+	var body struct {
+		XMLName xml.Name `xml:"Style"`
+		Content string   `xml:"Content"`
+	}
+	data, _ := ioutil.ReadAll(req.Body)
+	if err := xml.Unmarshal(data, &body); err != nil {
+		return newError(http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed.")
+	}
+	name := req.query.Get("styleName")
+	if name == "" {
+		return newError(http.StatusBadRequest, "InvalidArgument", "The style name is empty.")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if old, ok := b.styles[name]; ok {
+		old.content, old.modified = body.Content, now
+	} else {
+		b.styles[name] = &style{content: body.Content, created: now, modified: now}
+	}
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *Server) getBucketStyle(req *request) *Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	result := func(name string) styleResult {
+		st := b.styles[name]
+		return styleResult{Name: name, Content: st.content, CreateTime: st.created.UTC().Format(http.TimeFormat),
+			LastModifyTime: st.modified.UTC().Format(http.TimeFormat)}
+	}
+	if name := req.query.Get("styleName"); name != "" {
+		if _, ok := b.styles[name]; !ok {
+			return newError(http.StatusNotFound, "NoSuchStyle", "The style %s does not exist.", name)
+		}
+		return writeXML(req.w, result(name))
+	}
+
+	type list struct {
+		XMLName xml.Name      `xml:"StyleList"`
+		Styles  []styleResult `xml:"Style"`
+	}
+	names := make([]string, 0, len(b.styles))
+	for name := range b.styles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	res := list{}
+	for _, name := range names {
+		res.Styles = append(res.Styles, result(name))
+	}
+	return writeXML(req.w, res)
+}
+
+func (s *Server) deleteBucketStyle(req *request) *Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	delete(b.styles, req.query.Get("styleName"))
+	req.w.WriteHeader(http.StatusNoContent)
+	return nil
+}
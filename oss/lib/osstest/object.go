@@ -0,0 +1,737 @@
+package osstest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash/crc64"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var crcTable = crc64.MakeTable(crc64.ECMA)
+
+// the headers of an object kept as they are put
+var objectHeaders = []string{"Content-Type", "Cache-Control", "Content-Disposition", "Content-Encoding", "Content-Language", "Expires"}
+
+type tag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+type object struct {
+	data         []byte
+	etag         string
+	crc          uint64
+	header       http.Header
+	modified     time.Time
+	acl          string
+	tags         []tag
+	storageClass string
+	objectType   string
+}
+
+func newObject(data []byte, contentType string, header http.Header) *object {
+	sum := md5.Sum(data)
+	obj := &object{
+		data:         data,
+		etag:         strings.ToUpper(hex.EncodeToString(sum[:])),
+		crc:          crc64.Checksum(data, crcTable),
+		header:       http.Header{},
+		modified:     time.Now(),
+		acl:          "default",
+		storageClass: "Standard",
+		objectType:   "Normal",
+	}
+	for k, v := range header {
+		if strings.HasPrefix(strings.ToLower(k), "x-oss-meta-") || containsString(objectHeaders, k) {
+			obj.header[k] = v
+		}
+	}
+	if contentType != "" {
+		obj.header.Set("Content-Type", contentType)
+	}
+	if obj.header.Get("Content-Type") == "" {
+		obj.header.Set("Content-Type", "application/octet-stream")
+	}
+	return obj
+}
+
+func (obj *object) quotedETag() string {
+	return `"` + obj.etag + `"`
+}
+
+// setOptions applies the acl, storage class and tagging headers of a put or copy
+func (obj *object) setOptions(header http.Header) *Error {
+	if acl := header.Get("x-oss-object-acl"); acl != "" {
+		obj.acl = acl
+	}
+	if storageClass := header.Get("x-oss-storage-class"); storageClass != "" {
+		obj.storageClass = storageClass
+	}
+	if tagging := header.Get("x-oss-tagging"); tagging != "" {
+		values, err := url.ParseQuery(tagging)
+		if err != nil {
+			return newError(http.StatusBadRequest, "InvalidArgument", "x-oss-tagging %s is invalid", tagging)
+		}
+		obj.tags = tagsOf(values)
+	}
+	return nil
+}
+
+func tagsOf(values url.Values) []tag {
+	tags := []tag{}
+	for k, v := range values {
+		tags = append(tags, tag{k, v[0]})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+	return tags
+}
+
+func (obj *object) writeHeader(w http.ResponseWriter) {
+	for k, v := range obj.header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("ETag", obj.quotedETag())
+	w.Header().Set("Last-Modified", obj.modified.UTC().Format(http.TimeFormat))
+	w.Header().Set("x-oss-hash-crc64ecma", strconv.FormatUint(obj.crc, 10))
+	w.Header().Set("x-oss-object-type", obj.objectType)
+	w.Header().Set("x-oss-storage-class", obj.storageClass)
+	w.Header().Set("Accept-Ranges", "bytes")
+	if len(obj.tags) > 0 {
+		w.Header().Set("x-oss-tagging-count", strconv.Itoa(len(obj.tags)))
+	}
+}
+
+// readBody reads the body of a put, and checks the Content-MD5 if any
+func (req *request) readBody() ([]byte, *Error) {
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, "IncompleteBody", "%s", err.Error())
+	}
+	if contentMD5 := req.Header.Get("Content-MD5"); contentMD5 != "" {
+		sum := md5.Sum(data)
+		if contentMD5 != base64.StdEncoding.EncodeToString(sum[:]) {
+			return nil, newError(http.StatusBadRequest, "InvalidDigest", "The Content-MD5 you specified is not valid.")
+		}
+	}
+	return data, nil
+}
+
+func (s *Server) putObject(req *request) *Error {
+	data, err := req.readBody()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	if _, ok := b.objects[req.key]; ok && req.Header.Get("x-oss-forbid-overwrite") == "true" {
+		return newError(http.StatusConflict, "FileAlreadyExists", "The object you specified already exists and can not be overwritten.")
+	}
+	obj := newObject(data, "", req.Header)
+	if err := obj.setOptions(req.Header); err != nil {
+		return err
+	}
+	b.objects[req.key] = obj
+	req.w.Header().Set("ETag", obj.quotedETag())
+	req.w.Header().Set("x-oss-hash-crc64ecma", strconv.FormatUint(obj.crc, 10))
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// copySource returns the source object of a copy, the caller holds s.mu
+func (s *Server) copySource(req *request) (*object, *Error) {
+	source := req.Header.Get("x-oss-copy-source")
+	if pos := strings.Index(source, "?"); pos >= 0 {
+		source = source[:pos]
+	}
+	source, uerr := url.QueryUnescape(strings.TrimPrefix(source, "/"))
+	pos := strings.Index(source, "/")
+	if uerr != nil || pos <= 0 {
+		return nil, newError(http.StatusBadRequest, "InvalidArgument", "x-oss-copy-source %s is invalid", req.Header.Get("x-oss-copy-source"))
+	}
+	b, ok := s.buckets[source[:pos]]
+	if !ok {
+		return nil, newError(http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.")
+	}
+	obj, ok := b.objects[source[pos+1:]]
+	if !ok {
+		return nil, newError(http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+	}
+	if etag := req.Header.Get("x-oss-copy-source-if-match"); etag != "" && !etagMatch(etag, obj) {
+		return nil, newError(http.StatusPreconditionFailed, "PreconditionFailed", "At least one of the pre-conditions you specified did not hold.")
+	}
+	return obj, nil
+}
+
+func (s *Server) copyObject(req *request) *Error {
+	type result struct {
+		XMLName      xml.Name `xml:"CopyObjectResult"`
+		LastModified string   `xml:"LastModified"`
+		ETag         string   `xml:"ETag"`
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	src, err := s.copySource(req)
+	if err != nil {
+		return err
+	}
+	if _, ok := b.objects[req.key]; ok && req.Header.Get("x-oss-forbid-overwrite") == "true" {
+		return newError(http.StatusConflict, "FileAlreadyExists", "The object you specified already exists and can not be overwritten.")
+	}
+
+	var obj *object
+	if strings.EqualFold(req.Header.Get("x-oss-metadata-directive"), "REPLACE") {
+		obj = newObject(src.data, "", req.Header)
+	} else {
+		obj = newObject(src.data, "", src.header)
+	}
+	obj.storageClass = src.storageClass
+	if !strings.EqualFold(req.Header.Get("x-oss-tagging-directive"), "REPLACE") {
+		obj.tags = src.tags
+	}
+	if err := obj.setOptions(req.Header); err != nil {
+		return err
+	}
+	b.objects[req.key] = obj
+	req.w.Header().Set("x-oss-hash-crc64ecma", strconv.FormatUint(obj.crc, 10))
+	return writeXML(req.w, result{LastModified: formatTime(obj.modified), ETag: obj.quotedETag()})
+}
+
+func etagMatch(etag string, obj *object) bool {
+	return strings.EqualFold(strings.Trim(etag, `"`), obj.etag)
+}
+
+// checkConditions checks the If-Match and If-None-Match of a get or head
+func checkConditions(req *request, obj *object) *Error {
+	if etag := req.Header.Get("If-Match"); etag != "" && !etagMatch(etag, obj) {
+		return newError(http.StatusPreconditionFailed, "PreconditionFailed", "At least one of the pre-conditions you specified did not hold.")
+	}
+	if etag := req.Header.Get("If-None-Match"); etag != "" && etagMatch(etag, obj) {
+		return newError(http.StatusNotModified, "NotModified", "Not Modified")
+	}
+	return nil
+}
+
+// parseRange parses bytes=start-end, bytes=start- and bytes=-suffix, ok is false if the whole
+// object is returned, which is what oss does for an invalid range
+func parseRange(header string, size int64) (start, end int64, ok bool) {
+	if !strings.HasPrefix(header, "bytes=") || strings.Contains(header, ",") {
+		return 0, 0, false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(header, "bytes="), "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	var err error
+	switch {
+	case parts[0] == "":
+		var suffix int64
+		if suffix, err = strconv.ParseInt(parts[1], 10, 64); err != nil || suffix <= 0 {
+			return 0, 0, false
+		}
+		if suffix > size {
+			suffix = size
+		}
+		start, end = size-suffix, size-1
+	case parts[1] == "":
+		if start, err = strconv.ParseInt(parts[0], 10, 64); err != nil {
+			return 0, 0, false
+		}
+		end = size - 1
+	default:
+		if start, err = strconv.ParseInt(parts[0], 10, 64); err != nil {
+			return 0, 0, false
+		}
+		if end, err = strconv.ParseInt(parts[1], 10, 64); err != nil || end < start {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	if start >= size || start > end {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+func (s *Server) getObject(req *request) *Error {
+	s.mu.Lock()
+	_, obj, err := s.objectOf(req)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := checkConditions(req, obj); err != nil {
+		return err
+	}
+
+	obj.writeHeader(req.w)
+	for _, param := range []string{"content-type", "cache-control", "content-disposition", "content-encoding", "content-language", "expires"} {
+		if v := req.query.Get("response-" + param); v != "" {
+			req.w.Header().Set(param, v)
+		}
+	}
+	size := int64(len(obj.data))
+	if start, end, ok := parseRange(req.Header.Get("Range"), size); ok {
+		req.w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+		req.w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+		req.w.WriteHeader(http.StatusPartialContent)
+		req.w.Write(obj.data[start : end+1])
+		return nil
+	}
+	req.w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	req.w.WriteHeader(http.StatusOK)
+	req.w.Write(obj.data)
+	return nil
+}
+
+func (s *Server) headObject(req *request) *Error {
+	s.mu.Lock()
+	_, obj, err := s.objectOf(req)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := checkConditions(req, obj); err != nil {
+		return err
+	}
+	obj.writeHeader(req.w)
+	req.w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *Server) getObjectMeta(req *request) *Error {
+	s.mu.Lock()
+	_, obj, err := s.objectOf(req)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	req.w.Header().Set("ETag", obj.quotedETag())
+	req.w.Header().Set("Last-Modified", obj.modified.UTC().Format(http.TimeFormat))
+	req.w.Header().Set("x-oss-hash-crc64ecma", strconv.FormatUint(obj.crc, 10))
+	req.w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *Server) deleteObject(req *request) *Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	delete(b.objects, req.key)
+	req.w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	Tags    []tag    `xml:"TagSet>Tag"`
+}
+
+func (s *Server) putObjectTagging(req *request) *Error {
+	var body tagging
+	data, _ := ioutil.ReadAll(req.Body)
+	if err := xml.Unmarshal(data, &body); err != nil {
+		return newError(http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed.")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, obj, err := s.objectOf(req)
+	if err != nil {
+		return err
+	}
+	obj.tags = body.Tags
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *Server) getObjectTagging(req *request) *Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, obj, err := s.objectOf(req)
+	if err != nil {
+		return err
+	}
+	return writeXML(req.w, tagging{Tags: obj.tags})
+}
+
+func (s *Server) deleteObjectTagging(req *request) *Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, obj, err := s.objectOf(req)
+	if err != nil {
+		return err
+	}
+	obj.tags = nil
+	req.w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *Server) putObjectACL(req *request) *Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, obj, err := s.objectOf(req)
+	if err != nil {
+		return err
+	}
+	acl := req.Header.Get("x-oss-object-acl")
+	if acl != "default" && acl != "private" && acl != "public-read" && acl != "public-read-write" {
+		return newError(http.StatusBadRequest, "InvalidArgument", "no such object access control exists")
+	}
+	obj.acl = acl
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *Server) getObjectACL(req *request) *Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, obj, err := s.objectOf(req)
+	if err != nil {
+		return err
+	}
+	return writeXML(req.w, accessControlPolicy{Owner: defaultOwner, ACL: obj.acl})
+}
+
+type upload struct {
+	id        string
+	key       string
+	initiated time.Time
+	header    http.Header
+	parts     map[int]*part
+}
+
+type part struct {
+	data     []byte
+	etag     string
+	modified time.Time
+}
+
+// uploadOf returns the bucket and the upload of the request, the caller holds s.mu
+func (s *Server) uploadOf(req *request) (*bucket, *upload, *Error) {
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	up, ok := b.uploads[req.query.Get("uploadId")]
+	if !ok || up.key != req.key {
+		return nil, nil, newError(http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist. The upload ID may be invalid, or the upload may have been aborted or completed.")
+	}
+	return b, up, nil
+}
+
+func (s *Server) initiateMultipartUpload(req *request) *Error {
+	type result struct {
+		XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+		Bucket   string   `xml:"Bucket"`
+		Key      string   `xml:"Key"`
+		UploadID string   `xml:"UploadId"`
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	s.uploadID++
+	up := &upload{
+		id:        fmt.Sprintf("%032X", s.uploadID),
+		key:       req.key,
+		initiated: time.Now(),
+		header:    req.Header.Clone(),
+		parts:     map[int]*part{},
+	}
+	b.uploads[up.id] = up
+	return writeXML(req.w, result{Bucket: b.name, Key: req.encodeKey(req.key), UploadID: up.id})
+}
+
+func (req *request) partNumber() (int, *Error) {
+	number, err := strconv.Atoi(req.query.Get("partNumber"))
+	if err != nil || number < 1 || number > 10000 {
+		return 0, newError(http.StatusBadRequest, "InvalidArgument", "Part number must be an integer between 1 and 10000, inclusive.")
+	}
+	return number, nil
+}
+
+func (up *upload) putPart(number int, data []byte) *part {
+	sum := md5.Sum(data)
+	p := &part{data: data, etag: strings.ToUpper(hex.EncodeToString(sum[:])), modified: time.Now()}
+	up.parts[number] = p
+	return p
+}
+
+func (s *Server) uploadPart(req *request) *Error {
+	number, err := req.partNumber()
+	if err != nil {
+		return err
+	}
+	data, err := req.readBody()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, up, err := s.uploadOf(req)
+	if err != nil {
+		return err
+	}
+	p := up.putPart(number, data)
+	req.w.Header().Set("ETag", `"`+p.etag+`"`)
+	req.w.Header().Set("x-oss-hash-crc64ecma", strconv.FormatUint(crc64.Checksum(data, crcTable), 10))
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *Server) uploadPartCopy(req *request) *Error {
+	type result struct {
+		XMLName      xml.Name `xml:"CopyPartResult"`
+		LastModified string   `xml:"LastModified"`
+		ETag         string   `xml:"ETag"`
+	}
+	number, err := req.partNumber()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, up, err := s.uploadOf(req)
+	if err != nil {
+		return err
+	}
+	src, err := s.copySource(req)
+	if err != nil {
+		return err
+	}
+	data := src.data
+	if v := req.Header.Get("x-oss-copy-source-range"); v != "" {
+		start, end, ok := parseRange(v, int64(len(data)))
+		if !ok {
+			return newError(http.StatusRequestedRangeNotSatisfiable, "InvalidRange", "The requested range %s is not satisfiable.", v)
+		}
+		data = data[start : end+1]
+	}
+	p := up.putPart(number, append([]byte{}, data...))
+	return writeXML(req.w, result{LastModified: formatTime(p.modified), ETag: `"` + p.etag + `"`})
+}
+
+func (s *Server) completeMultipartUpload(req *request) *Error {
+	var body struct {
+		Parts []struct {
+			PartNumber int    `xml:"PartNumber"`
+			ETag       string `xml:"ETag"`
+		} `xml:"Part"`
+	}
+	data, _ := ioutil.ReadAll(req.Body)
+	if err := xml.Unmarshal(data, &body); err != nil || len(body.Parts) == 0 {
+		return newError(http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed.")
+	}
+
+	type result struct {
+		XMLName      xml.Name `xml:"CompleteMultipartUploadResult"`
+		EncodingType string   `xml:"EncodingType,omitempty"`
+		Location     string   `xml:"Location"`
+		Bucket       string   `xml:"Bucket"`
+		Key          string   `xml:"Key"`
+		ETag         string   `xml:"ETag"`
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, up, err := s.uploadOf(req)
+	if err != nil {
+		return err
+	}
+	content := new(bytes.Buffer)
+	sums := new(bytes.Buffer)
+	for i, bp := range body.Parts {
+		p, ok := up.parts[bp.PartNumber]
+		if !ok || !strings.EqualFold(strings.Trim(bp.ETag, `"`), p.etag) {
+			return newError(http.StatusBadRequest, "InvalidPart", "One or more of the specified parts could not be found or the specified entity tag might not have matched the part's entity tag.")
+		}
+		if i > 0 && bp.PartNumber <= body.Parts[i-1].PartNumber {
+			return newError(http.StatusBadRequest, "InvalidPartOrder", "The list of parts was not in ascending order.")
+		}
+		if i < len(body.Parts)-1 && len(p.data) < minPartSize {
+			return newError(http.StatusBadRequest, "EntityTooSmall", "Your proposed upload is smaller than the minimum allowed size.")
+		}
+		content.Write(p.data)
+		sum, _ := hex.DecodeString(p.etag)
+		sums.Write(sum)
+	}
+
+	obj := newObject(content.Bytes(), "", up.header)
+	if err := obj.setOptions(up.header); err != nil {
+		return err
+	}
+	sum := md5.Sum(sums.Bytes())
+	obj.etag = fmt.Sprintf("%s-%d", strings.ToUpper(hex.EncodeToString(sum[:])), len(body.Parts))
+	obj.objectType = "Multipart"
+	b.objects[up.key] = obj
+	delete(b.uploads, up.id)
+
+	req.w.Header().Set("x-oss-hash-crc64ecma", strconv.FormatUint(obj.crc, 10))
+	return writeXML(req.w, result{
+		EncodingType: req.query.Get("encoding-type"),
+		Location:     s.URL + "/" + b.name + "/" + req.encodeKey(up.key),
+		Bucket:       b.name,
+		Key:          req.encodeKey(up.key),
+		ETag:         obj.quotedETag(),
+	})
+}
+
+func (s *Server) abortMultipartUpload(req *request) *Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, up, err := s.uploadOf(req)
+	if err != nil {
+		return err
+	}
+	delete(b.uploads, up.id)
+	req.w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *Server) listParts(req *request) *Error {
+	type partResult struct {
+		PartNumber   int    `xml:"PartNumber"`
+		LastModified string `xml:"LastModified"`
+		ETag         string `xml:"ETag"`
+		Size         int    `xml:"Size"`
+	}
+	type result struct {
+		XMLName              xml.Name     `xml:"ListPartsResult"`
+		EncodingType         string       `xml:"EncodingType,omitempty"`
+		Bucket               string       `xml:"Bucket"`
+		Key                  string       `xml:"Key"`
+		UploadID             string       `xml:"UploadId"`
+		PartNumberMarker     int          `xml:"PartNumberMarker"`
+		NextPartNumberMarker int          `xml:"NextPartNumberMarker"`
+		MaxParts             int          `xml:"MaxParts"`
+		IsTruncated          bool         `xml:"IsTruncated"`
+		Parts                []partResult `xml:"Part"`
+	}
+	marker, _ := strconv.Atoi(req.query.Get("part-number-marker"))
+	maxParts, _ := strconv.Atoi(req.query.Get("max-parts"))
+	if maxParts <= 0 || maxParts > 1000 {
+		maxParts = 1000
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, up, err := s.uploadOf(req)
+	if err != nil {
+		return err
+	}
+	numbers := []int{}
+	for number := range up.parts {
+		if number > marker {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Ints(numbers)
+	res := result{
+		EncodingType:     req.query.Get("encoding-type"),
+		Bucket:           b.name,
+		Key:              req.encodeKey(up.key),
+		UploadID:         up.id,
+		PartNumberMarker: marker,
+		MaxParts:         maxParts,
+	}
+	for _, number := range numbers {
+		if len(res.Parts) == maxParts {
+			res.IsTruncated = true
+			break
+		}
+		p := up.parts[number]
+		res.Parts = append(res.Parts, partResult{number, formatTime(p.modified), `"` + p.etag + `"`, len(p.data)})
+		res.NextPartNumberMarker = number
+	}
+	return writeXML(req.w, res)
+}
+
+func (s *Server) listMultipartUploads(req *request) *Error {
+	type uploadResult struct {
+		Key       string `xml:"Key"`
+		UploadID  string `xml:"UploadId"`
+		Initiated string `xml:"Initiated"`
+	}
+	type result struct {
+		XMLName            xml.Name       `xml:"ListMultipartUploadsResult"`
+		EncodingType       string         `xml:"EncodingType,omitempty"`
+		Bucket             string         `xml:"Bucket"`
+		Prefix             string         `xml:"Prefix"`
+		KeyMarker          string         `xml:"KeyMarker"`
+		UploadIDMarker     string         `xml:"UploadIdMarker"`
+		NextKeyMarker      string         `xml:"NextKeyMarker"`
+		NextUploadIDMarker string         `xml:"NextUploadIdMarker"`
+		MaxUploads         int            `xml:"MaxUploads"`
+		IsTruncated        bool           `xml:"IsTruncated"`
+		Uploads            []uploadResult `xml:"Upload"`
+	}
+	prefix, keyMarker, idMarker := req.query.Get("prefix"), req.query.Get("key-marker"), req.query.Get("upload-id-marker")
+	maxUploads, _ := strconv.Atoi(req.query.Get("max-uploads"))
+	if maxUploads <= 0 || maxUploads > 1000 {
+		maxUploads = 1000
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return err
+	}
+	uploads := []*upload{}
+	for _, up := range b.uploads {
+		if !strings.HasPrefix(up.key, prefix) {
+			continue
+		}
+		if up.key < keyMarker || (up.key == keyMarker && (idMarker == "" || up.id <= idMarker)) {
+			continue
+		}
+		uploads = append(uploads, up)
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		if uploads[i].key != uploads[j].key {
+			return uploads[i].key < uploads[j].key
+		}
+		return uploads[i].id < uploads[j].id
+	})
+
+	res := result{
+		EncodingType:   req.query.Get("encoding-type"),
+		Bucket:         b.name,
+		Prefix:         req.encodeKey(prefix),
+		KeyMarker:      req.encodeKey(keyMarker),
+		UploadIDMarker: idMarker,
+		MaxUploads:     maxUploads,
+	}
+	for _, up := range uploads {
+		if len(res.Uploads) == maxUploads {
+			res.IsTruncated = true
+			break
+		}
+		res.Uploads = append(res.Uploads, uploadResult{req.encodeKey(up.key), up.id, formatTime(up.initiated)})
+		res.NextKeyMarker, res.NextUploadIDMarker = req.encodeKey(up.key), up.id
+	}
+	return writeXML(req.w, res)
+}
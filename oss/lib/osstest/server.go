@@ -0,0 +1,423 @@
+// Package osstest provides an in-process OSS compatible server for offline tests of ossutil.
+//
+// The server keeps buckets and objects in memory, serves path-style requests (an endpoint of
+// an IP address, such as the URL of the server, is path-style to the sdk), and verifies the V1
+// and V4 signatures of every request against its access key.
+package osstest
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the credentials and region of a server created by NewServer
+const (
+	DefaultAccessKeyID     = "osstest-access-key-id"
+	DefaultAccessKeySecret = "osstest-access-key-secret"
+	DefaultRegion          = "cn-hangzhou"
+)
+
+const (
+	ownerID     = "osstest-owner"
+	timeFormat  = "2006-01-02T15:04:05.000Z"
+	minPartSize = 100 * 1024
+)
+
+// Error is an OSS error response
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Code, e.Message)
+}
+
+func newError(status int, code, format string, args ...interface{}) *Error {
+	return &Error{Status: status, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// FaultFunc is called before a request is served, the request fails with the error returned
+// if it is not nil. op is the name of the OSS api, such as PutObject or UploadPart.
+type FaultFunc func(op, bucket, key string, r *http.Request) *Error
+
+// Server is an OSS compatible server listening on a local port
+type Server struct {
+	// URL is the endpoint of the server, such as http://127.0.0.1:12345
+	URL             string
+	AccessKeyID     string
+	AccessKeySecret string
+	Region          string
+
+	srv       *httptest.Server
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	counts    map[string]int
+	fault     FaultFunc
+	requestID int64
+	uploadID  int64
+}
+
+// NewServer starts a server with the default credentials, which must be closed by Close
+func NewServer() *Server {
+	s := &Server{
+		AccessKeyID:     DefaultAccessKeyID,
+		AccessKeySecret: DefaultAccessKeySecret,
+		Region:          DefaultRegion,
+		buckets:         map[string]*bucket{},
+		counts:          map[string]int{},
+	}
+	s.srv = httptest.NewServer(s)
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// CreateBucket creates a bucket if it does not exist
+func (s *Server) CreateBucket(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.buckets[name]; !ok {
+		s.buckets[name] = newBucket(name, "private")
+	}
+}
+
+// PutObject puts an object to an existing bucket
+func (s *Server) PutObject(bucketName, key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.buckets[bucketName]
+	if !ok {
+		return newError(http.StatusNotFound, "NoSuchBucket", "the bucket %s does not exist", bucketName)
+	}
+	b.objects[key] = newObject(data, "application/octet-stream", http.Header{})
+	return nil
+}
+
+// Object returns the data of an object
+func (s *Server) Object(bucketName, key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.buckets[bucketName]; ok {
+		if obj, ok := b.objects[key]; ok {
+			return append([]byte{}, obj.data...), true
+		}
+	}
+	return nil, false
+}
+
+// Objects returns the sorted keys of the objects in a bucket
+func (s *Server) Objects(bucketName string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := []string{}
+	if b, ok := s.buckets[bucketName]; ok {
+		keys = b.sortedKeys()
+	}
+	return keys
+}
+
+// Uploads returns the number of multipart uploads not completed or aborted in a bucket
+func (s *Server) Uploads(bucketName string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.buckets[bucketName]; ok {
+		return len(b.uploads)
+	}
+	return 0
+}
+
+// Count returns how many requests of op have been served, including the failed ones
+func (s *Server) Count(op string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[op]
+}
+
+// SetFault sets the function injecting errors, nil removes it
+func (s *Server) SetFault(fault FaultFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fault = fault
+}
+
+// request is a request being served
+type request struct {
+	*http.Request
+	w      http.ResponseWriter
+	bucket string
+	key    string
+	query  url.Values
+}
+
+type handlerFunc func(s *Server, req *request) *Error
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requestID++
+	requestID := fmt.Sprintf("%024X", s.requestID)
+	s.mu.Unlock()
+	w.Header().Set("x-oss-request-id", requestID)
+	w.Header().Set("Server", "osstest")
+
+	req, err := parseRequest(w, r)
+	if err == nil {
+		err = s.serve(req)
+	}
+	if err != nil {
+		writeError(w, r, err, requestID)
+	}
+}
+
+func parseRequest(w http.ResponseWriter, r *http.Request) (*request, *Error) {
+	path := strings.TrimPrefix(r.URL.EscapedPath(), "/")
+	req := &request{Request: r, w: w, query: r.URL.Query()}
+	if path == "" {
+		return req, nil
+	}
+	bucketName := path
+	key := ""
+	if pos := strings.Index(path, "/"); pos >= 0 {
+		bucketName = path[:pos]
+		key = path[pos+1:]
+	}
+	var err error
+	if req.bucket, err = url.PathUnescape(bucketName); err != nil {
+		return nil, newError(http.StatusBadRequest, "InvalidURI", "invalid bucket %s", bucketName)
+	}
+	if req.key, err = url.PathUnescape(key); err != nil {
+		return nil, newError(http.StatusBadRequest, "InvalidURI", "invalid object name %s", key)
+	}
+	return req, nil
+}
+
+func (s *Server) serve(req *request) *Error {
+	op, handler := route(req)
+	if err := s.verifySignature(req); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.counts[op]++
+	fault := s.fault
+	s.mu.Unlock()
+	if handler == nil {
+		return newError(http.StatusNotImplemented, "NotImplemented", "%s %s is not supported by osstest", req.Method, req.URL.RequestURI())
+	}
+	if fault != nil {
+		if err := fault(op, req.bucket, req.key, req.Request); err != nil {
+			return err
+		}
+	}
+	return handler(s, req)
+}
+
+func (req *request) has(param string) bool {
+	_, ok := req.query[param]
+	return ok
+}
+
+// route returns the name of the OSS api of the request and its handler, nil if not supported
+func route(req *request) (string, handlerFunc) {
+	method := req.Method
+	if req.bucket == "" {
+		if method == http.MethodGet {
+			return "ListBuckets", (*Server).listBuckets
+		}
+		return method + "Service", nil
+	}
+
+	if req.key == "" {
+		if name, ok := bucketConfigParam(req); ok {
+			return routeBucketConfig(method, name)
+		}
+		switch {
+		case req.has("acl"):
+			return routeMethod(method, "BucketAcl", (*Server).putBucketACL, (*Server).getBucketACL, nil)
+		case req.has("inventory"):
+			return routeMethod(method, "BucketInventory", (*Server).putBucketInventory, (*Server).getBucketInventory, (*Server).deleteBucketInventory)
+		case req.has("style"):
+			return routeMethod(method, "Style", (*Server).putBucketStyle, (*Server).getBucketStyle, (*Server).deleteBucketStyle)
+		case req.has("bucketInfo") && method == http.MethodGet:
+			return "GetBucketInfo", (*Server).getBucketInfo
+		case req.has("location") && method == http.MethodGet:
+			return "GetBucketLocation", (*Server).getBucketLocation
+		case req.has("uploads") && method == http.MethodGet:
+			return "ListMultipartUploads", (*Server).listMultipartUploads
+		case req.has("delete") && method == http.MethodPost:
+			return "DeleteMultipleObjects", (*Server).deleteMultipleObjects
+		case len(req.query) > 0 && !req.has("list-type") && !req.has("prefix") && !req.has("marker") &&
+			!req.has("delimiter") && !req.has("max-keys") && !req.has("encoding-type"):
+			return method + "Bucket?" + sortedParams(req.query), nil
+		case method == http.MethodGet && req.query.Get("list-type") == "2":
+			return "ListObjectsV2", (*Server).listObjectsV2
+		case method == http.MethodGet:
+			return "ListObjects", (*Server).listObjects
+		case method == http.MethodPut:
+			return "PutBucket", (*Server).putBucket
+		case method == http.MethodDelete:
+			return "DeleteBucket", (*Server).deleteBucket
+		}
+		return method + "Bucket", nil
+	}
+
+	switch {
+	case req.has("uploadId"):
+		switch method {
+		case http.MethodPut:
+			if req.Header.Get("x-oss-copy-source") != "" {
+				return "UploadPartCopy", (*Server).uploadPartCopy
+			}
+			return "UploadPart", (*Server).uploadPart
+		case http.MethodPost:
+			return "CompleteMultipartUpload", (*Server).completeMultipartUpload
+		case http.MethodDelete:
+			return "AbortMultipartUpload", (*Server).abortMultipartUpload
+		case http.MethodGet:
+			return "ListParts", (*Server).listParts
+		}
+	case req.has("uploads") && method == http.MethodPost:
+		return "InitiateMultipartUpload", (*Server).initiateMultipartUpload
+	case req.has("tagging"):
+		return routeMethod(method, "ObjectTagging", (*Server).putObjectTagging, (*Server).getObjectTagging, (*Server).deleteObjectTagging)
+	case req.has("acl"):
+		return routeMethod(method, "ObjectAcl", (*Server).putObjectACL, (*Server).getObjectACL, nil)
+	case req.has("objectMeta") && method == http.MethodHead:
+		return "GetObjectMeta", (*Server).getObjectMeta
+	case len(unsupportedObjectParams(req.query)) > 0:
+		return method + "Object?" + strings.Join(unsupportedObjectParams(req.query), "&"), nil
+	case method == http.MethodPut:
+		if req.Header.Get("x-oss-copy-source") != "" {
+			return "CopyObject", (*Server).copyObject
+		}
+		return "PutObject", (*Server).putObject
+	case method == http.MethodGet:
+		return "GetObject", (*Server).getObject
+	case method == http.MethodHead:
+		return "HeadObject", (*Server).headObject
+	case method == http.MethodDelete:
+		return "DeleteObject", (*Server).deleteObject
+	}
+	return method + "Object", nil
+}
+
+func routeMethod(method, name string, put, get, del handlerFunc) (string, handlerFunc) {
+	switch method {
+	case http.MethodPut:
+		return "Put" + name, put
+	case http.MethodGet:
+		return "Get" + name, get
+	case http.MethodDelete:
+		return "Delete" + name, del
+	}
+	return method + name, nil
+}
+
+// unsupportedObjectParams returns the sub resources of an object request which are not served,
+// the response overrides and the version are ignored
+func unsupportedObjectParams(query url.Values) []string {
+	params := []string{}
+	for k := range query {
+		if strings.HasPrefix(k, "response-") || k == "versionId" || k == "encoding-type" {
+			continue
+		}
+		params = append(params, k)
+	}
+	sort.Strings(params)
+	return params
+}
+
+func sortedParams(query url.Values) string {
+	params := []string{}
+	for k := range query {
+		params = append(params, k)
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+type errorResult struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	RequestID string   `xml:"RequestId"`
+	HostID    string   `xml:"HostId"`
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err *Error, requestID string) {
+	body, _ := xml.Marshal(errorResult{Code: err.Code, Message: err.Message, RequestID: requestID, HostID: r.Host})
+	body = append([]byte(xml.Header), body...)
+	if r.Method == http.MethodHead {
+		// a head response has no body, the sdk reads the error from the header
+		w.Header().Set("x-oss-err", base64.StdEncoding.EncodeToString(body))
+		w.WriteHeader(err.Status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+	w.WriteHeader(err.Status)
+	w.Write(body)
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) *Error {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		return newError(http.StatusInternalServerError, "InternalError", "%s", err.Error())
+	}
+	writeBody(w, "application/xml", append([]byte(xml.Header), body...))
+	return nil
+}
+
+func writeBody(w http.ResponseWriter, contentType string, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+// encodeKey encodes a key of a response if the request asks for encoding-type=url
+func (req *request) encodeKey(key string) string {
+	if req.query.Get("encoding-type") == "url" {
+		return strings.Replace(url.QueryEscape(key), "+", "%20", -1)
+	}
+	return key
+}
+
+// bucketOf returns the bucket of the request, the caller holds s.mu
+func (s *Server) bucketOf(req *request) (*bucket, *Error) {
+	b, ok := s.buckets[req.bucket]
+	if !ok {
+		return nil, newError(http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.")
+	}
+	return b, nil
+}
+
+// objectOf returns the bucket and the object of the request, the caller holds s.mu
+func (s *Server) objectOf(req *request) (*bucket, *object, *Error) {
+	b, err := s.bucketOf(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	obj, ok := b.objects[req.key]
+	if !ok {
+		return nil, nil, newError(http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+	}
+	return b, obj, nil
+}
@@ -0,0 +1,330 @@
+package osstest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBucket(t *testing.T, s *Server, options ...oss.ClientOption) *oss.Bucket {
+	s.CreateBucket("test-bucket")
+	client, err := oss.New(s.URL, s.AccessKeyID, s.AccessKeySecret, options...)
+	assert.Nil(t, err)
+	bucket, err := client.Bucket("test-bucket")
+	assert.Nil(t, err)
+	return bucket
+}
+
+func serviceCode(err error) string {
+	if e, ok := err.(oss.ServiceError); ok {
+		return e.Code
+	}
+	return ""
+}
+
+func TestObject(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	bucket := newTestBucket(t, s)
+
+	key := "dir/中文 a+b.txt"
+	err := bucket.PutObject(key, strings.NewReader("hello osstest"), oss.ContentType("text/plain"), oss.Meta("owner", "me"))
+	assert.Nil(t, err)
+	data, ok := s.Object("test-bucket", key)
+	assert.True(t, ok)
+	assert.Equal(t, "hello osstest", string(data))
+
+	body, err := bucket.GetObject(key)
+	assert.Nil(t, err)
+	data, _ = ioutil.ReadAll(body)
+	body.Close()
+	assert.Equal(t, "hello osstest", string(data))
+
+	// range
+	body, err = bucket.GetObject(key, oss.Range(6, 12))
+	assert.Nil(t, err)
+	data, _ = ioutil.ReadAll(body)
+	body.Close()
+	assert.Equal(t, "osstest", string(data))
+
+	header, err := bucket.GetObjectDetailedMeta(key)
+	assert.Nil(t, err)
+	assert.Equal(t, "text/plain", header.Get("Content-Type"))
+	assert.Equal(t, "me", header.Get("X-Oss-Meta-Owner"))
+	assert.Equal(t, "13", header.Get("Content-Length"))
+
+	_, err = bucket.CopyObject(key, "copy.txt", oss.MetadataDirective(oss.MetaReplace), oss.ContentType("text/html"))
+	assert.Nil(t, err)
+	header, err = bucket.GetObjectDetailedMeta("copy.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, "text/html", header.Get("Content-Type"))
+	assert.Equal(t, "", header.Get("X-Oss-Meta-Owner"))
+
+	err = bucket.DeleteObject(key)
+	assert.Nil(t, err)
+	_, err = bucket.GetObjectDetailedMeta(key)
+	assert.Equal(t, "NoSuchKey", serviceCode(err))
+	_, err = bucket.GetObject(key)
+	assert.Equal(t, "NoSuchKey", serviceCode(err))
+	assert.Equal(t, []string{"copy.txt"}, s.Objects("test-bucket"))
+}
+
+func TestSignature(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	// v4 with query params and a key to be escaped
+	bucket := newTestBucket(t, s, oss.AuthVersion(oss.AuthV4), oss.Region(DefaultRegion))
+	err := bucket.PutObject("v4/a b~c", strings.NewReader("v4"))
+	assert.Nil(t, err)
+	_, err = bucket.ListObjectsV2(oss.Prefix("v4/"), oss.MaxKeys(10))
+	assert.Nil(t, err)
+	err = bucket.PutObjectTagging("v4/a b~c", oss.Tagging{Tags: []oss.Tag{{Key: "k", Value: "v"}}})
+	assert.Nil(t, err)
+
+	// wrong region
+	client, _ := oss.New(s.URL, s.AccessKeyID, s.AccessKeySecret, oss.AuthVersion(oss.AuthV4), oss.Region("cn-beijing"))
+	bucket, _ = client.Bucket("test-bucket")
+	err = bucket.PutObject("v4", strings.NewReader("v4"))
+	assert.Equal(t, "InvalidArgument", serviceCode(err))
+
+	// wrong secret for v1 and v4
+	for _, options := range [][]oss.ClientOption{nil, {oss.AuthVersion(oss.AuthV4), oss.Region(DefaultRegion)}} {
+		client, _ = oss.New(s.URL, s.AccessKeyID, "wrong-secret", options...)
+		bucket, _ = client.Bucket("test-bucket")
+		err = bucket.PutObject("v4", strings.NewReader("v4"))
+		assert.Equal(t, "SignatureDoesNotMatch", serviceCode(err))
+		_, err = bucket.GetObjectDetailedMeta("v4/a b~c")
+		assert.NotNil(t, err)
+	}
+
+	// wrong access key id
+	client, _ = oss.New(s.URL, "wrong-id", s.AccessKeySecret)
+	bucket, _ = client.Bucket("test-bucket")
+	_, err = bucket.ListObjects()
+	assert.Equal(t, "InvalidAccessKeyId", serviceCode(err))
+}
+
+func TestListObjects(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	bucket := newTestBucket(t, s)
+	for _, key := range []string{"a/1", "a/2", "a/b/3", "b/4", "c", "d"} {
+		assert.Nil(t, s.PutObject("test-bucket", key, []byte(key)))
+	}
+
+	// v1 with delimiter
+	res, err := bucket.ListObjects(oss.Delimiter("/"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a/", "b/"}, res.CommonPrefixes)
+	assert.Equal(t, 2, len(res.Objects))
+	assert.Equal(t, "c", res.Objects[0].Key)
+	assert.False(t, res.IsTruncated)
+
+	// v1 pagination
+	keys := []string{}
+	marker := ""
+	for {
+		res, err = bucket.ListObjects(oss.Prefix("a/"), oss.Marker(marker), oss.MaxKeys(2))
+		assert.Nil(t, err)
+		for _, obj := range res.Objects {
+			keys = append(keys, obj.Key)
+		}
+		if !res.IsTruncated {
+			break
+		}
+		marker = res.NextMarker
+	}
+	assert.Equal(t, []string{"a/1", "a/2", "a/b/3"}, keys)
+
+	// v2 pagination with delimiter
+	keys = []string{}
+	token := ""
+	for {
+		res2, err := bucket.ListObjectsV2(oss.Delimiter("/"), oss.ContinuationToken(token), oss.MaxKeys(1))
+		assert.Nil(t, err)
+		for _, obj := range res2.Objects {
+			keys = append(keys, obj.Key)
+		}
+		keys = append(keys, res2.CommonPrefixes...)
+		if !res2.IsTruncated {
+			break
+		}
+		token = res2.NextContinuationToken
+	}
+	assert.Equal(t, []string{"a/", "b/", "c", "d"}, keys)
+
+	// delete multiple objects
+	deleted, err := bucket.DeleteObjects([]string{"c", "d"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(deleted.DeletedObjects))
+	assert.Equal(t, []string{"a/1", "a/2", "a/b/3", "b/4"}, s.Objects("test-bucket"))
+}
+
+func TestMultipartUpload(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	bucket := newTestBucket(t, s)
+	data := bytes.Repeat([]byte("0123456789"), 25*1024)
+
+	imur, err := bucket.InitiateMultipartUpload("multi")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, s.Uploads("test-bucket"))
+	var parts []oss.UploadPart
+	for i, size := 0, 100*1024; i*size < len(data); i++ {
+		end := (i + 1) * size
+		if end > len(data) {
+			end = len(data)
+		}
+		part, err := bucket.UploadPart(imur, bytes.NewReader(data[i*size:end]), int64(end-i*size), i+1)
+		assert.Nil(t, err)
+		parts = append(parts, part)
+	}
+	assert.Equal(t, 3, len(parts))
+
+	lpr, err := bucket.ListUploadedParts(imur)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(lpr.UploadedParts))
+	lmur, err := bucket.ListMultipartUploads()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(lmur.Uploads))
+
+	// the parts but the last must not be too small
+	_, err = bucket.CompleteMultipartUpload(imur, []oss.UploadPart{parts[2], parts[2]})
+	assert.NotNil(t, err)
+	_, err = bucket.CompleteMultipartUpload(imur, parts)
+	assert.Nil(t, err)
+	got, _ := s.Object("test-bucket", "multi")
+	assert.Equal(t, data, got)
+	assert.Equal(t, 0, s.Uploads("test-bucket"))
+	header, err := bucket.GetObjectDetailedMeta("multi")
+	assert.Nil(t, err)
+	assert.Equal(t, "Multipart", header.Get("X-Oss-Object-Type"))
+	assert.True(t, strings.HasSuffix(header.Get("ETag"), `-3"`))
+
+	// upload part copy and abort
+	imur, err = bucket.InitiateMultipartUpload("copy")
+	assert.Nil(t, err)
+	part, err := bucket.UploadPartCopy(imur, "test-bucket", "multi", 0, 10, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, part.PartNumber)
+	err = bucket.AbortMultipartUpload(imur)
+	assert.Nil(t, err)
+	err = bucket.AbortMultipartUpload(imur)
+	assert.Equal(t, "NoSuchUpload", serviceCode(err))
+}
+
+func TestObjectTaggingAndACL(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	bucket := newTestBucket(t, s)
+
+	err := bucket.PutObject("obj", strings.NewReader("obj"), oss.SetTagging(oss.Tagging{Tags: []oss.Tag{{Key: "a", Value: "1"}}}))
+	assert.Nil(t, err)
+	res, err := bucket.GetObjectTagging("obj")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(res.Tags))
+	assert.Equal(t, "a", res.Tags[0].Key)
+	assert.Equal(t, "1", res.Tags[0].Value)
+
+	err = bucket.PutObjectTagging("obj", oss.Tagging{Tags: []oss.Tag{{Key: "b", Value: "2"}}})
+	assert.Nil(t, err)
+	res, err = bucket.GetObjectTagging("obj")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(res.Tags))
+	assert.Equal(t, "b", res.Tags[0].Key)
+	assert.Equal(t, "2", res.Tags[0].Value)
+	err = bucket.DeleteObjectTagging("obj")
+	assert.Nil(t, err)
+	res, err = bucket.GetObjectTagging("obj")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(res.Tags))
+
+	acl, err := bucket.GetObjectACL("obj")
+	assert.Nil(t, err)
+	assert.Equal(t, "default", acl.ACL)
+	err = bucket.SetObjectACL("obj", oss.ACLPublicRead)
+	assert.Nil(t, err)
+	acl, err = bucket.GetObjectACL("obj")
+	assert.Nil(t, err)
+	assert.Equal(t, "public-read", acl.ACL)
+}
+
+func TestBucket(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	client, err := oss.New(s.URL, s.AccessKeyID, s.AccessKeySecret)
+	assert.Nil(t, err)
+
+	err = client.CreateBucket("bucket-a", oss.ACL(oss.ACLPublicRead))
+	assert.Nil(t, err)
+	res, err := client.ListBuckets()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(res.Buckets))
+	assert.Equal(t, "bucket-a", res.Buckets[0].Name)
+	acl, err := client.GetBucketACL("bucket-a")
+	assert.Nil(t, err)
+	assert.Equal(t, "public-read", acl.ACL)
+	info, err := client.GetBucketInfo("bucket-a")
+	assert.Nil(t, err)
+	assert.Equal(t, "bucket-a", info.BucketInfo.Name)
+
+	// sub resources
+	_, err = client.GetBucketCORS("bucket-a")
+	assert.Equal(t, "NoSuchCORSConfiguration", serviceCode(err))
+	err = client.SetBucketCORS("bucket-a", []oss.CORSRule{{AllowedOrigin: []string{"*"}, AllowedMethod: []string{"GET"}}})
+	assert.Nil(t, err)
+	cors, err := client.GetBucketCORS("bucket-a")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"*"}, cors.CORSRules[0].AllowedOrigin)
+	err = client.DeleteBucketCORS("bucket-a")
+	assert.Nil(t, err)
+	_, err = client.GetBucketCORS("bucket-a")
+	assert.Equal(t, "NoSuchCORSConfiguration", serviceCode(err))
+
+	versioning, err := client.GetBucketVersioning("bucket-a")
+	assert.Nil(t, err)
+	assert.Equal(t, "", versioning.Status)
+	err = client.SetBucketPolicy("bucket-a", `{"Version":"1","Statement":[]}`)
+	assert.Nil(t, err)
+	policy, err := client.GetBucketPolicy("bucket-a")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"Version":"1","Statement":[]}`, policy)
+
+	// a bucket with objects can not be deleted
+	bucket, _ := client.Bucket("bucket-a")
+	assert.Nil(t, bucket.PutObject("obj", strings.NewReader("obj")))
+	err = client.DeleteBucket("bucket-a")
+	assert.Equal(t, "BucketNotEmpty", serviceCode(err))
+	assert.Nil(t, bucket.DeleteObject("obj"))
+	err = client.DeleteBucket("bucket-a")
+	assert.Nil(t, err)
+	_, err = client.GetBucketACL("bucket-a")
+	assert.Equal(t, "NoSuchBucket", serviceCode(err))
+}
+
+func TestFault(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	bucket := newTestBucket(t, s)
+
+	s.SetFault(func(op, bucket, key string, r *http.Request) *Error {
+		if op == "PutObject" && key == "fail" {
+			return &Error{Status: http.StatusForbidden, Code: "AccessDenied", Message: "injected"}
+		}
+		return nil
+	})
+	err := bucket.PutObject("fail", strings.NewReader("fail"))
+	assert.Equal(t, "AccessDenied", serviceCode(err))
+	assert.Nil(t, bucket.PutObject("ok", strings.NewReader("ok")))
+	assert.Equal(t, 2, s.Count("PutObject"))
+
+	s.SetFault(nil)
+	assert.Nil(t, bucket.PutObject("fail", strings.NewReader("fail")))
+	assert.Equal(t, []string{"fail", "ok"}, s.Objects("test-bucket"))
+}
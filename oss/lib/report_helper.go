@@ -1,10 +1,14 @@
 package lib
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"sync"
 	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
 )
 
 type Reporter struct {
@@ -16,6 +20,44 @@ type Reporter struct {
 	outputDir  string
 	createDir  bool
 	fileHandle *os.File
+
+	// json report, every file or object is recorded whatever the result
+	json      bool
+	mu        sync.Mutex
+	startTime time.Time
+	summary   reportSummary
+}
+
+// the status of a file or object in a json report
+const (
+	reportStatusOK      = "ok"
+	reportStatusSkipped = "skipped"
+	reportStatusFailed  = "failed"
+)
+
+// reportResult is the result of one file or object in a json report
+type reportResult struct {
+	Action      string `json:"action"`
+	Source      string `json:"source,omitempty"`
+	Destination string `json:"destination"`
+	Status      string `json:"status"`
+	Reason      string `json:"reason,omitempty"`
+	Bytes       int64  `json:"bytes"`
+	DurationMs  int64  `json:"durationMs"`
+	Retries     int64  `json:"retries"`
+	ErrorCode   string `json:"errorCode,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// reportSummary ends a json report, bytes are the bytes of the succeeded results
+type reportSummary struct {
+	Total      int64 `json:"total"`
+	Succeeded  int64 `json:"succeeded"`
+	Skipped    int64 `json:"skipped"`
+	Failed     int64 `json:"failed"`
+	Bytes      int64 `json:"bytes"`
+	Retries    int64 `json:"retries"`
+	DurationMs int64 `json:"durationMs"`
 }
 
 func (re *Reporter) Init(outputDir, comment string) error {
@@ -30,7 +72,11 @@ func (re *Reporter) Init(outputDir, comment string) error {
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return err
 	}
-	re.path = re.outputDir + string(os.PathSeparator) + ReportPrefix + time.Now().Format("20060102_150405") + ReportSuffix
+	suffix := ReportSuffix
+	if re.json {
+		suffix = ReportJSONSuffix
+	}
+	re.path = re.outputDir + string(os.PathSeparator) + ReportPrefix + time.Now().Format("20060102_150405") + suffix
 	re.comment = comment
 	re.written = false
 	re.prompted = false
@@ -39,6 +85,12 @@ func (re *Reporter) Init(outputDir, comment string) error {
 		return fmt.Errorf("Create reporter file error: %s", err.Error())
 	}
 	re.fileHandle = f
+	if re.json {
+		re.startTime = time.Now()
+		command, _ := json.Marshal(comment)
+		_, err = fmt.Fprintf(f, "{\"command\":%s,\"results\":[", command)
+		return err
+	}
 	re.rlogger = log.New(f, "", log.Ldate|log.Ltime)
 	re.Comment()
 	re.rlogger.SetFlags(log.Ldate | log.Ltime)
@@ -46,6 +98,11 @@ func (re *Reporter) Init(outputDir, comment string) error {
 }
 
 func (re *Reporter) Clear() {
+	if re != nil && re.json {
+		re.finishJSON()
+		return
+	}
+
 	if re != nil && re.fileHandle != nil {
 		re.fileHandle.Close()
 	}
@@ -58,6 +115,22 @@ func (re *Reporter) Clear() {
 	}
 }
 
+// finishJSON writes the summary, the json report is kept even if nothing failed.
+// The path goes to stderr so that stdout only carries the command output.
+func (re *Reporter) finishJSON() {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	if re.fileHandle == nil {
+		return
+	}
+	re.summary.DurationMs = time.Since(re.startTime).Milliseconds()
+	summary, _ := json.Marshal(re.summary)
+	fmt.Fprintf(re.fileHandle, "\n],\"summary\":%s}\n", summary)
+	re.fileHandle.Close()
+	re.fileHandle = nil
+	fmt.Fprintf(os.Stderr, "\nreport file: %s\n", re.path)
+}
+
 func (re *Reporter) HasPrompt() bool {
 	if re == nil {
 		return false
@@ -81,6 +154,41 @@ func (re *Reporter) ReportError(msg string) {
 	}
 }
 
+// IsJSON reports whether the reporter records every result in json
+func (re *Reporter) IsJSON() bool {
+	return re != nil && re.json
+}
+
+// ReportResult records a result in a json report
+func (re *Reporter) ReportResult(result reportResult) {
+	if !re.IsJSON() {
+		return
+	}
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	if re.fileHandle == nil {
+		return
+	}
+	sep := ","
+	if re.summary.Total == 0 {
+		sep = ""
+	}
+	re.summary.Total++
+	re.summary.Retries += result.Retries
+	switch result.Status {
+	case reportStatusOK:
+		re.summary.Succeeded++
+		re.summary.Bytes += result.Bytes
+	case reportStatusSkipped:
+		re.summary.Skipped++
+	default:
+		re.summary.Failed++
+		re.written = true
+	}
+	data, _ := json.Marshal(result)
+	fmt.Fprintf(re.fileHandle, "%s\n%s", sep, data)
+}
+
 func (re *Reporter) Prompt(err error) {
 	if re != nil && re.written && re.HasPrompt() {
 		re.prompted = true
@@ -98,3 +206,35 @@ func GetReporter(need bool, outputDir, comment string) (*Reporter, error) {
 	}
 	return nil, nil
 }
+
+// GetJSONReporter returns a reporter which records every file or object in json
+func GetJSONReporter(outputDir, comment string) (*Reporter, error) {
+	reporter := Reporter{json: true}
+	if err := reporter.Init(outputDir, comment); err != nil {
+		return nil, err
+	}
+	return &reporter, nil
+}
+
+// reportErrorCode returns the oss error code of err, or an empty string
+func reportErrorCode(err error) string {
+	for err != nil {
+		switch e := err.(type) {
+		case oss.ServiceError:
+			return e.Code
+		case *oss.ServiceError:
+			return e.Code
+		case FileError:
+			err = e.err
+		case ObjectError:
+			err = e.err
+		case BucketError:
+			err = e.err
+		case CopyError:
+			err = e.err
+		default:
+			return ""
+		}
+	}
+	return ""
+}
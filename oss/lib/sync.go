@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
 )
@@ -35,6 +36,9 @@ type syncOptionType struct {
 
 	filters      []filterOptionType
 	payerOptions []oss.Option
+	dryrun       bool
+	watch        bool
+	interval     time.Duration
 }
 
 var specChineseSync = SpecText{
@@ -44,9 +48,9 @@ var specChineseSync = SpecText{
 	paramText: "src dest [options]",
 
 	syntaxText: ` 
-    ossutil sync local_dir cloud_url [-f] [-u] [--delete] [--backup-dir] [--enable-symlink-dir] [--disable-all-symlink] [--disable-ignore-error] [--only-current-dir] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir] [--payer requester]
-    ossutil sync cloud_url local_dir [-f] [-u] [--delete] [--backup-dir] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] [--payer requester]
-    ossutil sync cloud_url cloud_url [-f] [-u] [--delete] [--backup-dir] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--payer requester]
+    ossutil sync local_dir cloud_url [-f] [-u] [--checksum] [--dryrun] [--delete] [--watch] [--watch-interval=sec] [--backup-dir] [--enable-symlink-dir] [--disable-all-symlink] [--disable-ignore-error] [--only-current-dir] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir] [--payer requester]
+    ossutil sync cloud_url local_dir [-f] [-u] [--checksum] [--dryrun] [--delete] [--backup-dir] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] [--payer requester]
+    ossutil sync cloud_url cloud_url [-f] [-u] [--checksum] [--dryrun] [--delete] [--backup-dir] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--payer requester]
 `,
 
 	detailHelpText: ` 
@@ -69,6 +73,16 @@ var specChineseSync = SpecText{
 --backup-dir
     该选项表示用于备份目的端文件的目录, 不能是目的端目录的子目录,如果输入了--delete, 该选项必须输入
 
+--dryrun选项
+    只输出执行计划而不同步, 可以在--delete之前查看哪些文件会被上传、跳过和删除, 详见cp命令的说明
+
+--watch选项
+    只支持从本地目录同步到oss. 同步完成后继续监控本地目录(Linux上使用inotify, 其他系统或者输入了
+    --enable-symlink-dir时每隔--watch-interval秒扫描一次), 上传新增和修改的文件, 如果输入了--delete,
+    还会删除本地已删除文件对应的object, 直到按下Ctrl+C. 文件在--watch-interval秒(默认2秒)内没有
+    变化才会上传, 以便合并连续的修改. --include/--exclude、--jobs和--parallel等选项同样生效, 修改过
+    的文件直接覆盖oss上的object; 删除时除非输入-f, 否则需要确认
+
   
     其他选项说明、用法和cp命令相同
 `,
@@ -201,9 +215,9 @@ var specEnglishSync = SpecText{
 	paramText: "src dest [options]",
 
 	syntaxText: ` 
-    ossutil sync local_dir cloud_url [-f] [-u] [--delete] [--backup-dir] [--enable-symlink-dir] [--disable-all-symlink] [--disable-ignore-error] [--only-current-dir] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir] [--payer requester]
-    ossutil sync cloud_url local_dir [-f] [-u] [--delete] [--backup-dir] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] [--payer requester]
-    ossutil sync cloud_url cloud_url [-f] [-u] [--delete] [--backup-dir] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--payer requester]
+    ossutil sync local_dir cloud_url [-f] [-u] [--checksum] [--dryrun] [--delete] [--watch] [--watch-interval=sec] [--backup-dir] [--enable-symlink-dir] [--disable-all-symlink] [--disable-ignore-error] [--only-current-dir] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir] [--payer requester]
+    ossutil sync cloud_url local_dir [-f] [-u] [--checksum] [--dryrun] [--delete] [--backup-dir] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] [--payer requester]
+    ossutil sync cloud_url cloud_url [-f] [-u] [--checksum] [--dryrun] [--delete] [--backup-dir] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--payer requester]
 `,
 
 	detailHelpText: ` 
@@ -232,6 +246,19 @@ var specEnglishSync = SpecText{
     It cannot be a subdirectory of the destination directory. 
     If you enter --delete, this option must be entered
 
+--dryrun
+    Print the plan without syncing, which shows what will be uploaded, skipped and deleted before 
+    using --delete, see the help of the cp command for details
+
+--watch
+    Only supports sync from a local directory to oss. After the sync, keep watching the local directory
+    (by inotify on Linux, or scan every --watch-interval seconds on other systems or with 
+    --enable-symlink-dir), upload new and modified files, and with --delete also delete the objects of 
+    removed files, until Ctrl+C is pressed. A file is uploaded once it has not changed for 
+    --watch-interval seconds(2 by default) so that bursts of changes are merged. --include/--exclude, 
+    --jobs, --parallel and other options still work, modified files overwrite the objects, and the 
+    deletions need confirmation unless -f is entered
+
     Other options descriptions and usage are the same as the cp command
 `,
 
@@ -400,6 +427,10 @@ var syncCommand = SyncCommand{
 			OptionRoutines,
 			OptionParallel,
 			OptionSnapshotPath,
+			OptionChecksum,
+			OptionChecksumCache,
+			OptionDryRun,
+			OptionReportFormat,
 			OptionDisableCRC64,
 			OptionRequestPayer,
 			OptionLogLevel,
@@ -434,6 +465,8 @@ var syncCommand = SyncCommand{
 			// The following options are only supported by sc command, not supported by cp command
 			OptionDelete,
 			OptionBackupDir,
+			OptionWatch,
+			OptionWatchInterval,
 			OptionInsecure,
 		},
 	},
@@ -460,6 +493,8 @@ func (sc *SyncCommand) Init(args []string, options OptionMapType) error {
 	bakupOptions[OptionRecursion] = &recursive
 	delete(bakupOptions, OptionDelete)
 	delete(bakupOptions, OptionBackupDir)
+	delete(bakupOptions, OptionWatch)
+	delete(bakupOptions, OptionWatchInterval)
 
 	copyCommand.cpOption.bSyncCommand = true
 	err := (&copyCommand).Init(args, bakupOptions)
@@ -481,6 +516,10 @@ func (sc *SyncCommand) RunCommand() error {
 	sc.syncOption.disableDirObject, _ = GetBool(OptionDisableDirObject, sc.command.options)
 	sc.syncOption.disableAllSymlink, _ = GetBool(OptionDisableAllSymlink, sc.command.options)
 	sc.syncOption.force, _ = GetBool(OptionForce, sc.command.options)
+	sc.syncOption.dryrun, _ = GetBool(OptionDryRun, sc.command.options)
+	sc.syncOption.watch, _ = GetBool(OptionWatch, sc.command.options)
+	interval, _ := GetInt(OptionWatchInterval, sc.command.options)
+	sc.syncOption.interval = time.Duration(interval) * time.Second
 
 	// check point dir
 	sc.syncOption.cpDir, _ = GetString(OptionCheckpointDir, sc.command.options)
@@ -529,7 +568,24 @@ func (sc *SyncCommand) RunCommand() error {
 		}
 	}
 
+	// the objects of the watched files are under the prefix ended with '/', like the ones synced
+	var watchURL CloudURL
+	if sc.syncOption.watch {
+		if !srcURL.IsFileURL() || !destURL.IsCloudURL() {
+			return fmt.Errorf("--watch only supports sync from local directory to oss")
+		}
+		if sc.syncOption.dryrun {
+			return fmt.Errorf("--watch and --dryrun can't be both exist")
+		}
+		watchURL = sc.adjustCloudUrl(destURL).(CloudURL)
+	}
+
 	if !sc.syncOption.bDelete {
+		if sc.syncOption.watch {
+			copyCommand.cpOption.afterCopy = func() error {
+				return sc.watch(srcURL, watchURL)
+			}
+		}
 		return copyCommand.RunCommand()
 	}
 
@@ -583,23 +639,49 @@ func (sc *SyncCommand) RunCommand() error {
 	}
 
 	if destURL.IsFileURL() {
-		fmt.Printf("\nfile(directory) will be removed count:%d\n", len(destKeys))
+		sc.printf("\nfile(directory) will be removed count:%d\n", len(destKeys))
 	} else {
-		fmt.Printf("\nobject will be deleted count:%d\n", len(destKeys))
+		sc.printf("\nobject will be deleted count:%d\n", len(destKeys))
 	}
 
-	err = copyCommand.RunCommand()
-	if err != nil {
-		return err
+	copyCommand.cpOption.afterCopy = func() error {
+		// move dest files or rm dest objects which not exist in src
+		if opType == operationTypeCopy || opType == operationTypePut {
+			if err := sc.DeleteExtraObjects(destKeys, destURL); err != nil {
+				return err
+			}
+		} else {
+			return sc.RemoveExtraFiles(destKeys, destURL)
+		}
+		if sc.syncOption.watch {
+			return sc.watch(srcURL, watchURL)
+		}
+		return nil
 	}
+	return copyCommand.RunCommand()
+}
 
-	// move dest files or rm dest objects which not exist in src
-	if opType == operationTypeCopy || opType == operationTypePut {
-		err = sc.DeleteExtraObjects(destKeys, destURL)
-	} else {
-		err = sc.RemoveExtraFiles(destKeys, destURL)
+// printf prints the progress unless it is a dry run, whose plan owns stdout
+func (sc *SyncCommand) printf(format string, a ...interface{}) {
+	if !sc.syncOption.dryrun {
+		fmt.Printf(format, a...)
+	}
+}
+
+// reportDeletion records the deletion of an extra object or file in the plan of a dry run
+// or in the json report
+func (sc *SyncCommand) reportDeletion(action, source, destination string, skip bool, err error) {
+	if sc.syncOption.dryrun {
+		copyCommand.addPlan(action, ReasonExtra, source, destination, 0, false)
+		return
+	}
+	if copyCommand.cpOption.reporter.IsJSON() {
+		result := reportResult{Action: action, Source: source, Destination: destination, Reason: ReasonExtra}
+		if skip {
+			result.Reason = ReasonDeclined
+		}
+		copyCommand.reportResult(result, skip, err)
 	}
-	return err
 }
 
 func (sc *SyncCommand) adjustCloudUrl(sUrl StorageURLer) StorageURLer {
@@ -621,36 +703,52 @@ func (sc *SyncCommand) DeleteExtraObjects(keys map[string]string, sUrl StorageUR
 		return err
 	}
 
+	if sc.syncOption.dryrun {
+		for k, v := range keys {
+			sc.reportDeletion(actionDelete, "", CloudURLToString(bucketName, v+k), false, nil)
+		}
+		return nil
+	}
+
 	deleteCount := 0
 	rmOptions := append(sc.syncOption.payerOptions, oss.DeleteObjectsQuiet(true))
 	objects := []string{}
 	for k, v := range keys {
 		if len(objects) >= MaxBatchCount {
-			if sc.confirm(objects) {
-				err := sc.BatchRmObjects(bucket, objects, rmOptions)
-				if err != nil {
-					return err
-				}
+			if err := sc.batchRmConfirmed(bucket, objects, rmOptions); err != nil {
+				return err
 			}
 			objects = []string{}
 			deleteCount += MaxBatchCount
-			fmt.Printf("\rdelete object count:%d", deleteCount)
+			sc.printf("\rdelete object count:%d", deleteCount)
 		}
 		// prefix + relativeKey
 		objects = append(objects, v+k)
 	}
 
-	if len(objects) > 0 && sc.confirm(objects) {
-		err := sc.BatchRmObjects(bucket, objects, rmOptions)
-		if err != nil {
+	if len(objects) > 0 {
+		if err := sc.batchRmConfirmed(bucket, objects, rmOptions); err != nil {
 			return err
 		}
 		deleteCount += len(objects)
-		fmt.Printf("\rdelete object count:%d", deleteCount)
+		sc.printf("\rdelete object count:%d", deleteCount)
 	}
 	return nil
 }
 
+// batchRmConfirmed deletes objects if the user confirms and records them in the report
+func (sc *SyncCommand) batchRmConfirmed(bucket *oss.Bucket, objects []string, options []oss.Option) error {
+	skip := !sc.confirm(objects)
+	var err error
+	if !skip {
+		err = sc.BatchRmObjects(bucket, objects, options)
+	}
+	for _, object := range objects {
+		sc.reportDeletion(actionDelete, "", CloudURLToString(bucket.BucketName, object), skip, err)
+	}
+	return err
+}
+
 func (sc *SyncCommand) RemoveExtraFiles(keys map[string]string, sUrl StorageURLer) error {
 	var sortList []string
 	for k, _ := range keys {
@@ -664,6 +762,13 @@ func (sc *SyncCommand) RemoveExtraFiles(keys map[string]string, sUrl StorageURLe
 		return err
 	}
 
+	if sc.syncOption.dryrun {
+		for _, k := range sortList {
+			sc.reportDeletion(actionMove, absDirName+k, sc.syncOption.backupDir+k, false, nil)
+		}
+		return nil
+	}
+
 	nowFatherDirName := ""
 	for _, k := range sortList {
 		if strings.HasSuffix(k, string(os.PathSeparator)) {
@@ -771,20 +876,20 @@ func (sc *SyncCommand) GetFileList(strPath string, chFiles chan<- fileInfoType,
 
 func (sc *SyncCommand) ReadLocalFileKeys(chFiles <-chan fileInfoType, chFinish chan<- error, keys map[string]string) {
 	totalCount := 0
-	fmt.Printf("\n")
+	sc.printf("\n")
 	for fileInfo := range chFiles {
 		if copyCommand.filterFile(fileInfo, sc.syncOption.cpDir) { // exclude checkpoint files
 			totalCount++
-			fmt.Printf("\rtotal file(directory) count:%d", totalCount)
+			sc.printf("\rtotal file(directory) count:%d", totalCount)
 			keys[fileInfo.filePath] = ""
 			if len(keys) > MaxSyncNumbers {
-				fmt.Printf("\n")
+				sc.printf("\n")
 				chFinish <- fmt.Errorf("over max sync numbers %d", MaxSyncNumbers)
 				break
 			}
 		}
 	}
-	fmt.Printf("\rtotal file(directory) count:%d", totalCount)
+	sc.printf("\rtotal file(directory) count:%d", totalCount)
 	chFinish <- nil
 }
 
@@ -897,18 +1002,18 @@ func (sc *SyncCommand) GetOssKeyList(bucket *oss.Bucket, sURL StorageURLer, chOb
 
 func (sc *SyncCommand) ReadOssKeys(keys map[string]string, sURL StorageURLer, chObjects <-chan objectInfoType, chFinish chan<- error) {
 	totalCount := 0
-	fmt.Printf("\n")
+	sc.printf("\n")
 	for objectInfo := range chObjects {
 		totalCount++
-		fmt.Printf("\r%s,total oss object count:%d", sURL.ToString(), totalCount)
+		sc.printf("\r%s,total oss object count:%d", sURL.ToString(), totalCount)
 		keys[objectInfo.relativeKey] = objectInfo.prefix
 		if len(keys) > MaxSyncNumbers {
-			fmt.Printf("\n")
+			sc.printf("\n")
 			chFinish <- fmt.Errorf("over max sync numbers %d", MaxSyncNumbers)
 			break
 		}
 	}
-	fmt.Printf("\r%s,total oss object count:%d", sURL.ToString(), totalCount)
+	sc.printf("\r%s,total oss object count:%d", sURL.ToString(), totalCount)
 	chFinish <- nil
 }
 
@@ -946,11 +1051,12 @@ func (sc *SyncCommand) readDirLimit(dirName string, limitCount int) ([]os.FileIn
 }
 func (sc *SyncCommand) movePath(srcName, destName string) error {
 	err := sc.moveFileToPath(srcName, destName)
+	sc.reportDeletion(actionMove, srcName, destName, false, err)
 	if err != nil {
 		LogError("rename %s %s error,%s\n", srcName, destName, err.Error())
 	} else {
 		sc.syncOption.removeCount += 1
-		fmt.Printf("\rremove file(directory) count:%d", sc.syncOption.removeCount)
+		sc.printf("\rremove file(directory) count:%d", sc.syncOption.removeCount)
 		LogInfo("rename success %s %s\n", srcName, destName)
 	}
 	return err
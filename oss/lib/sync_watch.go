@@ -0,0 +1,301 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// syncWatcher tells when the watched directory may have changed, bursts of changes
+// are merged into one notification
+type syncWatcher interface {
+	Changes() <-chan struct{}
+	Close() error
+}
+
+// newSyncWatcher uses inotify where it is supported and falls back to periodic scans
+var newSyncWatcher = func(dir string, interval time.Duration, followSymlink bool) syncWatcher {
+	// inotify does not follow symlink directories
+	if !followSymlink {
+		watcher, err := newNotifyWatcher(dir, interval)
+		if err == nil {
+			return watcher
+		}
+		LogWarn("watch %s by inotify error, fall back to scanning every %s, reason: %s\n", dir, interval, err.Error())
+	}
+	return newPollWatcher(interval)
+}
+
+// pollWatcher notifies every interval, the scan tells whether anything changed
+type pollWatcher struct {
+	ticker  *time.Ticker
+	changes chan struct{}
+	done    chan struct{}
+}
+
+func newPollWatcher(interval time.Duration) *pollWatcher {
+	pw := &pollWatcher{
+		ticker:  time.NewTicker(interval),
+		changes: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		for {
+			select {
+			case <-pw.ticker.C:
+				notifyChange(pw.changes)
+			case <-pw.done:
+				return
+			}
+		}
+	}()
+	return pw
+}
+
+func (pw *pollWatcher) Changes() <-chan struct{} {
+	return pw.changes
+}
+
+func (pw *pollWatcher) Close() error {
+	pw.ticker.Stop()
+	close(pw.done)
+	return nil
+}
+
+// notifyChange never blocks, a pending notification already covers the change
+func notifyChange(changes chan struct{}) {
+	select {
+	case changes <- struct{}{}:
+	default:
+	}
+}
+
+// watchFile is a file or directory of the watched directory when it was scanned
+type watchFile struct {
+	file    fileInfoType
+	isDir   bool
+	size    int64
+	modTime int64
+}
+
+// scanFiles lists the files of dir like the upload of sync, with the same filters
+func (cc *CopyCommand) scanFiles(dir string) (map[string]watchFile, error) {
+	chFiles := make(chan fileInfoType, ChannelBuf)
+	chError := make(chan error, 1)
+	go func() {
+		defer close(chFiles)
+		chError <- cc.getFileList(dir, chFiles)
+	}()
+
+	files := map[string]watchFile{}
+	for file := range chFiles {
+		if !cc.filterFile(file, cc.cpOption.cpDir) {
+			continue
+		}
+		f, err := os.Stat(file.dir + file.filePath)
+		if err != nil {
+			// removed after listed
+			continue
+		}
+		files[file.filePath] = watchFile{
+			file:    file,
+			isDir:   f.IsDir(),
+			size:    f.Size(),
+			modTime: f.ModTime().UnixNano(),
+		}
+	}
+	return files, <-chError
+}
+
+// diffWatchFiles returns the files to upload and the files removed since state, a file
+// modified after settled may still be written and is pending till the next scan
+func diffWatchFiles(state, files map[string]watchFile, settled time.Time) (changed, removed []watchFile, pending bool) {
+	for key, file := range files {
+		old, ok := state[key]
+		if ok && old.isDir == file.isDir && (file.isDir || (old.size == file.size && old.modTime == file.modTime)) {
+			continue
+		}
+		if !file.isDir && file.modTime > settled.UnixNano() {
+			pending = true
+			continue
+		}
+		changed = append(changed, file)
+	}
+	for key, file := range state {
+		if _, ok := files[key]; !ok {
+			removed = append(removed, file)
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i].file.filePath < changed[j].file.filePath })
+	sort.Slice(removed, func(i, j int) bool { return removed[i].file.filePath < removed[j].file.filePath })
+	return
+}
+
+// watch uploads the changes of the local directory after the sync until it is interrupted
+func (sc *SyncCommand) watch(srcURL StorageURLer, destURL CloudURL) error {
+	cc := &copyCommand
+	bucket, err := cc.command.ossBucket(destURL.bucket)
+	if err != nil {
+		return err
+	}
+
+	dir := srcURL.ToString()
+	if !strings.HasSuffix(dir, string(os.PathSeparator)) {
+		dir += string(os.PathSeparator)
+	}
+	state, err := cc.scanFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	watcher := newSyncWatcher(dir, sc.syncOption.interval, cc.cpOption.enableSymlinkDir)
+	defer watcher.Close()
+
+	chSignal := make(chan os.Signal, 1)
+	signal.Notify(chSignal, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(chSignal)
+
+	// the changes are made locally, overwrite the objects without asking
+	cc.cpOption.force = true
+
+	fmt.Printf("\nwatching %s, press Ctrl+C to stop\n", dir)
+	LogInfo("begin watch %s, interval %s\n", dir, sc.syncOption.interval)
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-chSignal:
+			fmt.Printf("\nstop watching %s\n", dir)
+			LogInfo("stop watch %s\n", dir)
+			return nil
+		case <-watcher.Changes():
+		case <-retry:
+		}
+
+		again, err := sc.watchRound(bucket, destURL, dir, state)
+		if err != nil {
+			return err
+		}
+		retry = nil
+		if again {
+			retry = time.After(sc.syncOption.interval)
+		}
+	}
+}
+
+// watchRound uploads the changed files and deletes the objects of the removed files with
+// --delete, it returns true if pending or failed files need another round
+func (sc *SyncCommand) watchRound(bucket *oss.Bucket, destURL CloudURL, dir string, state map[string]watchFile) (bool, error) {
+	cc := &copyCommand
+	files, err := cc.scanFiles(dir)
+	if err != nil {
+		return false, err
+	}
+	changed, removed, pending := diffWatchFiles(state, files, time.Now().Add(-sc.syncOption.interval))
+	if len(changed) == 0 && len(removed) == 0 {
+		return pending, nil
+	}
+
+	uploaded, failed, uploadErr := sc.watchUpload(bucket, destURL, changed, state)
+	deleted, err := sc.watchDelete(bucket, destURL, removed, state)
+	if err != nil {
+		failed += int64(len(removed))
+		LogError("watch delete objects error, info: %s\n", err.Error())
+	}
+
+	fmt.Printf("%s upload %d, delete %d, error %d\n", time.Now().Format("2006-01-02 15:04:05"), uploaded, deleted, failed)
+	LogInfo("watch round upload %d, delete %d, error %d\n", uploaded, deleted, failed)
+
+	// an error which can not be ignored stops watching
+	if !cc.cpOption.ctnu {
+		if uploadErr != nil {
+			return false, uploadErr
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return pending || failed > 0, nil
+}
+
+// watchUpload uploads the files by --jobs routines, state is updated by the uploaded ones
+func (sc *SyncCommand) watchUpload(bucket *oss.Bucket, destURL CloudURL, changed []watchFile, state map[string]watchFile) (int64, int64, error) {
+	cc := &copyCommand
+	var uploaded, failed int64
+	var firstErr error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	chFiles := make(chan watchFile, len(changed))
+	for _, file := range changed {
+		chFiles <- file
+	}
+	close(chFiles)
+
+	for i := int64(0); i < cc.cpOption.routines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range chFiles {
+				err := cc.uploadFileWithReport(bucket, destURL, file.file)
+				mu.Lock()
+				if err != nil {
+					failed++
+					if firstErr == nil {
+						firstErr = err
+					}
+				} else {
+					uploaded++
+					state[file.file.filePath] = file
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return uploaded, failed, firstErr
+}
+
+// watchDelete deletes the objects of the removed files with --delete after the confirmation
+// like sync, state forgets the removed files so that they are uploaded again if they come back
+func (sc *SyncCommand) watchDelete(bucket *oss.Bucket, destURL CloudURL, removed []watchFile, state map[string]watchFile) (int64, error) {
+	cc := &copyCommand
+	objects := []string{}
+	for _, file := range removed {
+		if !sc.syncOption.bDelete {
+			delete(state, file.file.filePath)
+			continue
+		}
+		if file.isDir && cc.cpOption.disableDirObject {
+			delete(state, file.file.filePath)
+			continue
+		}
+		objects = append(objects, cc.makeObjectName(destURL, file.file))
+	}
+
+	var deleted int64
+	rmOptions := append(sc.syncOption.payerOptions, oss.DeleteObjectsQuiet(true))
+	for len(objects) > 0 {
+		batch := objects
+		if len(batch) > MaxBatchCount {
+			batch = objects[:MaxBatchCount]
+		}
+		objects = objects[len(batch):]
+		if err := sc.batchRmConfirmed(bucket, batch, rmOptions); err != nil {
+			return deleted, err
+		}
+		deleted += int64(len(batch))
+	}
+
+	if sc.syncOption.bDelete {
+		for _, file := range removed {
+			delete(state, file.file.filePath)
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,142 @@
+//go:build linux
+// +build linux
+
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const notifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_ATTRIB | syscall.IN_DELETE_SELF
+
+// notifyWatcher watches every directory under dir by inotify, and notifies once no event
+// comes for interval
+type notifyWatcher struct {
+	file     *os.File
+	fd       int
+	interval time.Duration
+	changes  chan struct{}
+	mu       sync.Mutex
+	watches  map[int]string
+}
+
+func newNotifyWatcher(dir string, interval time.Duration) (syncWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	nw := &notifyWatcher{
+		// a non-blocking fd is read by the runtime poller, so Close stops the read
+		file:     os.NewFile(uintptr(fd), "inotify"),
+		fd:       fd,
+		interval: interval,
+		changes:  make(chan struct{}, 1),
+		watches:  map[int]string{},
+	}
+	if err := nw.addTree(dir); err != nil {
+		nw.file.Close()
+		return nil, err
+	}
+	go nw.readEvents()
+	return nw, nil
+}
+
+// addTree watches dir and its sub directories, a directory removed meanwhile is ignored
+func (nw *notifyWatcher) addTree(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		wd, err := syscall.InotifyAddWatch(nw.fd, path, notifyMask)
+		if err != nil {
+			if err == syscall.ENOENT {
+				return nil
+			}
+			return os.NewSyscallError("inotify_add_watch", err)
+		}
+		nw.mu.Lock()
+		nw.watches[wd] = path
+		nw.mu.Unlock()
+		return nil
+	})
+}
+
+func (nw *notifyWatcher) readEvents() {
+	chEvent := make(chan struct{}, 1)
+	go func() {
+		defer close(chEvent)
+		buf := make([]byte, 64*1024)
+		for {
+			n, err := nw.file.Read(buf)
+			if err != nil {
+				return
+			}
+			nw.handleEvents(buf[:n])
+			notifyChange(chEvent)
+		}
+	}()
+
+	timer := time.NewTimer(nw.interval)
+	timer.Stop()
+	for {
+		select {
+		case _, ok := <-chEvent:
+			if !ok {
+				timer.Stop()
+				return
+			}
+			timer.Reset(nw.interval)
+		case <-timer.C:
+			notifyChange(nw.changes)
+		}
+	}
+}
+
+// handleEvents watches the new directories and forgets the removed ones
+func (nw *notifyWatcher) handleEvents(buf []byte) {
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= len(buf); {
+		event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		nameStart := offset + syscall.SizeofInotifyEvent
+		offset = nameStart + int(event.Len)
+
+		nw.mu.Lock()
+		dir, ok := nw.watches[int(event.Wd)]
+		if event.Mask&syscall.IN_IGNORED != 0 {
+			delete(nw.watches, int(event.Wd))
+		}
+		nw.mu.Unlock()
+
+		if ok && event.Mask&syscall.IN_ISDIR != 0 && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 && offset <= len(buf) {
+			name := string(buf[nameStart:offset])
+			for i := 0; i < len(name); i++ {
+				if name[i] == 0 {
+					name = name[:i]
+					break
+				}
+			}
+			if err := nw.addTree(filepath.Join(dir, name)); err != nil {
+				LogWarn("watch %s error, reason: %s\n", filepath.Join(dir, name), err.Error())
+			}
+		}
+	}
+}
+
+func (nw *notifyWatcher) Changes() <-chan struct{} {
+	return nw.changes
+}
+
+func (nw *notifyWatcher) Close() error {
+	return nw.file.Close()
+}
@@ -0,0 +1,47 @@
+//go:build linux
+// +build linux
+
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyWatcher(t *testing.T) {
+	dir := t.TempDir()
+	watcher, err := newNotifyWatcher(dir, 100*time.Millisecond)
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	waitChange := func() bool {
+		select {
+		case <-watcher.Changes():
+			return true
+		case <-time.After(time.Second):
+			return false
+		}
+	}
+
+	// 新建的子目录也会被监控
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+	require.True(t, waitChange())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "a.txt"), []byte("a"), 0644))
+	require.True(t, waitChange())
+
+	// 连续的修改合并成一次通知
+	for i := 0; i < 5; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b"), 0644))
+		time.Sleep(20 * time.Millisecond)
+	}
+	require.True(t, waitChange())
+	assert.False(t, waitChange())
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "sub", "a.txt")))
+	assert.True(t, waitChange())
+}
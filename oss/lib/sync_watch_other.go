@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package lib
+
+import (
+	"fmt"
+	"time"
+)
+
+func newNotifyWatcher(dir string, interval time.Duration) (syncWatcher, error) {
+	return nil, fmt.Errorf("inotify is only supported on linux")
+}
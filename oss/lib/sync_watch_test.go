@@ -0,0 +1,78 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanFiles(t *testing.T) {
+	dir := t.TempDir() + string(os.PathSeparator)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("this is content"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.jpg"), []byte("jpg"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "c.txt"), []byte("c"), 0644))
+
+	cc := &CopyCommand{}
+	cc.cpOption.cpDir = CheckpointDir
+	files, err := cc.scanFiles(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 4)
+	assert.True(t, files["sub"+string(os.PathSeparator)].isDir)
+	assert.Equal(t, int64(15), files["a.txt"].size)
+	assert.Equal(t, fileInfoType{"a.txt", dir}, files["a.txt"].file)
+
+	// 和上传使用相同的 --include/--exclude
+	cc.cpOption.filters = []filterOptionType{{"--exclude", "*.jpg"}}
+	files, err = cc.scanFiles(dir)
+	require.NoError(t, err)
+	assert.NotContains(t, files, "b.jpg")
+	assert.Contains(t, files, filepath.Join("sub", "c.txt"))
+}
+
+func TestDiffWatchFiles(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-time.Minute).UnixNano()
+	file := func(name string, size, modTime int64) watchFile {
+		return watchFile{file: fileInfoType{name, "dir/"}, size: size, modTime: modTime}
+	}
+	state := map[string]watchFile{
+		"same.txt":    file("same.txt", 1, old),
+		"size.txt":    file("size.txt", 1, old),
+		"mtime.txt":   file("mtime.txt", 1, old),
+		"removed.txt": file("removed.txt", 1, old),
+		"sub/":        {file: fileInfoType{"sub/", "dir/"}, isDir: true, modTime: old},
+	}
+	files := map[string]watchFile{
+		"same.txt":  file("same.txt", 1, old),
+		"size.txt":  file("size.txt", 2, old),
+		"mtime.txt": file("mtime.txt", 1, old+1),
+		"new.txt":   file("new.txt", 1, old),
+		// 目录只看是否存在
+		"sub/": {file: fileInfoType{"sub/", "dir/"}, isDir: true, modTime: now.UnixNano()},
+	}
+	changed, removed, pending := diffWatchFiles(state, files, now.Add(-2*time.Second))
+	assert.Equal(t, []watchFile{files["mtime.txt"], files["new.txt"], files["size.txt"]}, changed)
+	assert.Equal(t, []watchFile{state["removed.txt"]}, removed)
+	assert.False(t, pending)
+
+	// 刚修改过的文件可能还在写，等下一轮再上传
+	files["writing.txt"] = file("writing.txt", 1, now.UnixNano())
+	changed, _, pending = diffWatchFiles(state, files, now.Add(-2*time.Second))
+	assert.Len(t, changed, 3)
+	assert.True(t, pending)
+}
+
+func TestPollWatcher(t *testing.T) {
+	pw := newPollWatcher(10 * time.Millisecond)
+	defer pw.Close()
+	select {
+	case <-pw.Changes():
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change notified")
+	}
+}
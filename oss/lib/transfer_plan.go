@@ -0,0 +1,110 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// the reasons of the actions in a dry run plan or a json report
+const (
+	// the destination does not exist
+	ReasonNew = "new"
+	// --update and the source is newer than the destination
+	ReasonNewer = "newer"
+	// --checksum and the sizes differ
+	ReasonSizeDiff = "size-diff"
+	// --checksum and the crc64 differ or is unknown
+	ReasonChecksumDiff = "checksum-diff"
+	// the destination exists and is replaced
+	ReasonOverwrite = "overwrite"
+	// --force without checking the destination
+	ReasonForce = "force"
+	// --snapshot-path has no record or the file changed since
+	ReasonSnapshotMiss = "snapshot-miss"
+	// sync --delete and the destination does not exist in the source
+	ReasonExtra = "extra"
+
+	// out of --start-time and --end-time
+	ReasonTimeRange = "time-range"
+	// --snapshot-path and the file is unchanged since
+	ReasonSnapshot = "snapshot"
+	// --update and the destination is not older than the source
+	ReasonNotNewer = "not-newer"
+	// --checksum and the content is the same
+	ReasonSame = "same"
+	// the user refused to overwrite the destination
+	ReasonDeclined = "declined"
+	// a local directory is in the place of the file to download
+	ReasonIsDir = "is-dir"
+	// --disable-dir-object
+	ReasonDirObject = "dir-object"
+)
+
+// the actions of a dry run plan besides upload, download and copy
+const (
+	actionSkip   = "skip"
+	actionDelete = "delete"
+	actionMove   = "move"
+)
+
+// planAction is one line of the plan printed by --dryrun
+type planAction struct {
+	Action      string `json:"action"`
+	Reason      string `json:"reason"`
+	Source      string `json:"source,omitempty"`
+	Destination string `json:"destination"`
+	Size        int64  `json:"size,omitempty"`
+}
+
+// planPrinter prints the plan of --dryrun, ndjson streams one action per line, json
+// prints all actions and a summary of the counts of each action at the end
+type planPrinter struct {
+	mu      sync.Mutex
+	out     io.Writer
+	json    bool
+	actions []planAction
+	summary map[string]int64
+}
+
+func newPlanPrinter(out io.Writer, reportFormat string) *planPrinter {
+	return &planPrinter{
+		out:     out,
+		json:    reportFormat == ReportFormatJSON,
+		actions: []planAction{},
+		summary: map[string]int64{},
+	}
+}
+
+func (pp *planPrinter) add(action planAction) {
+	if pp == nil {
+		return
+	}
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	pp.summary[action.Action]++
+	if pp.json {
+		pp.actions = append(pp.actions, action)
+		return
+	}
+	line, _ := json.Marshal(action)
+	fmt.Fprintln(pp.out, string(line))
+}
+
+func (pp *planPrinter) finish() error {
+	if pp == nil || !pp.json {
+		return nil
+	}
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"actions": pp.actions,
+		"summary": pp.summary,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(pp.out, string(data))
+	return err
+}
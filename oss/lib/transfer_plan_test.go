@@ -0,0 +1,103 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanPrinter(t *testing.T) {
+	out := new(bytes.Buffer)
+	pp := newPlanPrinter(out, ReportFormatText)
+	pp.add(planAction{Action: opUpload, Reason: ReasonNew, Source: "dir/a.txt", Destination: "oss://bucket/a.txt", Size: 38})
+	pp.add(planAction{Action: actionDelete, Reason: ReasonExtra, Destination: "oss://bucket/c.txt"})
+	require.NoError(t, pp.finish())
+	assert.Equal(t, `{"action":"upload","reason":"new","source":"dir/a.txt","destination":"oss://bucket/a.txt","size":38}
+{"action":"delete","reason":"extra","destination":"oss://bucket/c.txt"}
+`, out.String())
+
+	// json 格式在最后输出完整的计划和汇总
+	out.Reset()
+	pp = newPlanPrinter(out, ReportFormatJSON)
+	cc := &CopyCommand{}
+	cc.cpOption.plan = pp
+	cc.addPlan(opDownload, ReasonNotNewer, "oss://bucket/a.txt", "dir/a.txt", 38, true)
+	cc.addPlan(opDownload, ReasonNewer, "oss://bucket/b.txt", "dir/b.txt", 118, false)
+	assert.Empty(t, out.String())
+	require.NoError(t, pp.finish())
+	var plan struct {
+		Actions []planAction     `json:"actions"`
+		Summary map[string]int64 `json:"summary"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &plan))
+	assert.Equal(t, []planAction{
+		{Action: actionSkip, Reason: ReasonNotNewer, Source: "oss://bucket/a.txt", Destination: "dir/a.txt", Size: 38},
+		{Action: opDownload, Reason: ReasonNewer, Source: "oss://bucket/b.txt", Destination: "dir/b.txt", Size: 118},
+	}, plan.Actions)
+	assert.Equal(t, map[string]int64{actionSkip: 1, opDownload: 1}, plan.Summary)
+
+	// 非 dryrun 时没有 planPrinter
+	var nilPrinter *planPrinter
+	nilPrinter.add(planAction{})
+	assert.NoError(t, nilPrinter.finish())
+}
+
+func TestJSONReporter(t *testing.T) {
+	dir := t.TempDir()
+	reporter, err := GetJSONReporter(dir, "ossutil sync dir oss://bucket")
+	require.NoError(t, err)
+	assert.True(t, reporter.IsJSON())
+	assert.True(t, strings.HasSuffix(reporter.path, ReportJSONSuffix))
+
+	cc := &CopyCommand{}
+	cc.cpOption.reporter = reporter
+	cc.reportResult(reportResult{Action: opUpload, Source: "dir/a.txt", Destination: "oss://bucket/a.txt", Reason: ReasonNew, Bytes: 38, DurationMs: 5}, false, nil)
+	cc.reportResult(reportResult{Action: opUpload, Source: "dir/b.txt", Destination: "oss://bucket/b.txt", Reason: ReasonSame, Bytes: 118}, true, nil)
+	cc.reportResult(reportResult{Action: opUpload, Source: "dir/c.txt", Destination: "oss://bucket/c.txt", Reason: ReasonNew, Bytes: 7, Retries: 2},
+		false, FileError{oss.ServiceError{Code: "AccessDenied", Message: "denied"}, "dir/c.txt"})
+	reporter.Clear()
+
+	data, err := os.ReadFile(reporter.path)
+	require.NoError(t, err)
+	var report struct {
+		Command string         `json:"command"`
+		Results []reportResult `json:"results"`
+		Summary reportSummary  `json:"summary"`
+	}
+	require.NoError(t, json.Unmarshal(data, &report), string(data))
+	assert.Equal(t, "ossutil sync dir oss://bucket", report.Command)
+	require.Len(t, report.Results, 3)
+	assert.Equal(t, reportStatusOK, report.Results[0].Status)
+	assert.Equal(t, reportStatusSkipped, report.Results[1].Status)
+	assert.Equal(t, reportStatusFailed, report.Results[2].Status)
+	assert.Equal(t, "AccessDenied", report.Results[2].ErrorCode)
+	assert.Equal(t, reportSummary{Total: 3, Succeeded: 1, Skipped: 1, Failed: 1, Bytes: 38, Retries: 2, DurationMs: report.Summary.DurationMs}, report.Summary)
+}
+
+func TestReportErrorCode(t *testing.T) {
+	assert.Equal(t, "NoSuchKey", reportErrorCode(ObjectError{oss.ServiceError{Code: "NoSuchKey"}, "bucket", "a.txt"}))
+	assert.Equal(t, "NoSuchBucket", reportErrorCode(BucketError{&oss.ServiceError{Code: "NoSuchBucket"}, "bucket"}))
+	assert.Equal(t, "", reportErrorCode(FileError{fmt.Errorf("no such file"), "a.txt"}))
+	assert.Equal(t, "", reportErrorCode(nil))
+}
+
+func TestCountRetry(t *testing.T) {
+	cc := &CopyCommand{}
+	// 没有 json report 时不计数
+	cc.countRetry("bucket", "a.txt")
+	assert.Equal(t, int64(0), cc.takeRetries("oss://bucket/a.txt"))
+
+	cc.cpOption.retries = new(sync.Map)
+	cc.countRetry("bucket", "a.txt")
+	cc.countRetry("bucket", "a.txt")
+	assert.Equal(t, int64(2), cc.takeRetries("oss://bucket/a.txt"))
+	assert.Equal(t, int64(0), cc.takeRetries("oss://bucket/a.txt"))
+}
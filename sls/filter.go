@@ -0,0 +1,207 @@
+package sls
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Filter is a client-side log filter such as
+//
+//	level=ERROR and status>=500 or msg~"time ?out"
+//
+// A condition is `key<op>value` with op one of = != ~ !~ (regular expression) > >= < <=
+// (numeric), or a bare word that matches when any value contains it. Conditions are joined
+// by `and` (also implied between adjacent conditions) and `or`; `and` binds tighter.
+// Values may be double quoted.
+type Filter struct {
+	any [][]filterCondition // OR of ANDs
+}
+
+type filterCondition struct {
+	key    string
+	op     string
+	value  string
+	number float64
+	re     *regexp.Regexp
+}
+
+// filterOps is ordered so that at the same position `>=` wins over `>`.
+var filterOps = []string{"!=", "!~", ">=", "<=", "=", "~", ">", "<"}
+
+func ParseFilter(expr string) (*Filter, error) {
+	tokens, err := splitFilterTokens(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty filter")
+	}
+	f := &Filter{}
+	var current []filterCondition
+	expectCondition := true
+	for _, token := range tokens {
+		switch strings.ToLower(token) {
+		case "and", "or":
+			if expectCondition {
+				return nil, fmt.Errorf("invalid filter '%s': unexpected '%s'", expr, token)
+			}
+			if strings.EqualFold(token, "or") {
+				f.any = append(f.any, current)
+				current = nil
+			}
+			expectCondition = true
+			continue
+		}
+		cond, err := parseFilterCondition(token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter '%s': %v", expr, err)
+		}
+		current = append(current, cond)
+		expectCondition = false
+	}
+	if expectCondition {
+		return nil, fmt.Errorf("invalid filter '%s': missing condition at the end", expr)
+	}
+	f.any = append(f.any, current)
+	return f, nil
+}
+
+// splitFilterTokens splits on whitespace outside double quotes.
+func splitFilterTokens(expr string) ([]string, error) {
+	var tokens []string
+	var token strings.Builder
+	inQuote, escaped, started := false, false, false
+	for _, r := range expr {
+		switch {
+		case escaped:
+			token.WriteRune(r)
+			escaped = false
+		case r == '\\' && inQuote:
+			token.WriteRune(r)
+			escaped = true
+		case r == '"':
+			token.WriteRune(r)
+			inQuote = !inQuote
+			started = true
+		case (r == ' ' || r == '\t') && !inQuote:
+			if started {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				started = false
+			}
+		default:
+			token.WriteRune(r)
+			started = true
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("invalid filter '%s': unterminated quote", expr)
+	}
+	if started {
+		tokens = append(tokens, token.String())
+	}
+	return tokens, nil
+}
+
+func unquoteFilterValue(s string) (string, error) {
+	if strings.HasPrefix(s, `"`) {
+		return strconv.Unquote(s)
+	}
+	return s, nil
+}
+
+func parseFilterCondition(token string) (filterCondition, error) {
+	index, op := -1, ""
+	if !strings.HasPrefix(token, `"`) {
+		for _, candidate := range filterOps {
+			if i := strings.Index(token, candidate); i > 0 && (index < 0 || i < index) {
+				index, op = i, candidate
+			}
+		}
+	}
+	if index < 0 {
+		value, err := unquoteFilterValue(token)
+		if err != nil {
+			return filterCondition{}, err
+		}
+		return filterCondition{value: value}, nil
+	}
+	cond := filterCondition{key: token[:index], op: op}
+	value, err := unquoteFilterValue(token[index+len(op):])
+	if err != nil {
+		return cond, err
+	}
+	cond.value = value
+	switch op {
+	case "~", "!~":
+		if cond.re, err = regexp.Compile(value); err != nil {
+			return cond, err
+		}
+	case ">", ">=", "<", "<=":
+		if cond.number, err = strconv.ParseFloat(value, 64); err != nil {
+			return cond, fmt.Errorf("'%s' needs a number", token)
+		}
+	}
+	return cond, nil
+}
+
+func (c filterCondition) match(fields Fields) bool {
+	if c.op == "" {
+		for _, field := range fields {
+			if strings.Contains(field.Value, c.value) {
+				return true
+			}
+		}
+		return false
+	}
+	value, ok := fields.Get(c.key)
+	switch c.op {
+	case "=":
+		return ok && value == c.value
+	case "!=":
+		return !ok || value != c.value
+	case "~":
+		return ok && c.re.MatchString(value)
+	case "!~":
+		return !ok || !c.re.MatchString(value)
+	}
+	if !ok {
+		return false
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+	switch c.op {
+	case ">":
+		return n > c.number
+	case ">=":
+		return n >= c.number
+	case "<":
+		return n < c.number
+	default:
+		return n <= c.number
+	}
+}
+
+// Match reports whether the flattened log satisfies the filter; a nil filter matches every log.
+func (f *Filter) Match(fields Fields) bool {
+	if f == nil {
+		return true
+	}
+	for _, all := range f.any {
+		matched := true
+		for _, cond := range all {
+			if !cond.match(fields) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
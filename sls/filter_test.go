@@ -0,0 +1,72 @@
+package sls
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testFields() Fields {
+	group := &LogGroup{
+		Topic:   proto.String("app"),
+		LogTags: []*LogTag{{Key: proto.String("host"), Value: proto.String("web-1")}},
+	}
+	log := &Log{
+		Time: proto.Uint32(1712345678),
+		Contents: []*LogContent{
+			{Key: proto.String("level"), Value: proto.String("ERROR")},
+			{Key: proto.String("status"), Value: proto.String("502")},
+			{Key: proto.String("msg"), Value: proto.String("upstream timed out")},
+		},
+	}
+	return FlattenLog(group, log)
+}
+
+func TestFilter(t *testing.T) {
+	fields := testFields()
+	cases := map[string]bool{
+		"level=ERROR":                          true,
+		"level=INFO":                           false,
+		"level!=INFO":                          true,
+		"missing!=x":                           true,
+		"status>=500":                          true,
+		"status>=500 and level=INFO":           false,
+		"status>=500 level=ERROR":              true,
+		"level=INFO or status<503":             true,
+		"level=INFO or status<500 and msg~out": false,
+		`msg~"timed? out"`:                     true,
+		"msg!~^up":                             false,
+		"timed":                                true,
+		`"timed out"`:                          true,
+		"web-1":                                true,
+		"level>1":                              false,
+		`__tag__:host="web-1"`:                 true,
+		"__topic__=app AND level=ERROR":        true,
+	}
+	for expr, expected := range cases {
+		f, err := ParseFilter(expr)
+		require.NoError(t, err, expr)
+		assert.Equal(t, expected, f.Match(fields), expr)
+	}
+
+	var nilFilter *Filter
+	assert.True(t, nilFilter.Match(fields))
+}
+
+func TestParseFilter_Errors(t *testing.T) {
+	cases := map[string]string{
+		"   ":             "empty filter",
+		"and level=ERROR": "invalid filter 'and level=ERROR': unexpected 'and'",
+		"level=ERROR or":  "invalid filter 'level=ERROR or': missing condition at the end",
+		`msg="oops`:       `invalid filter 'msg="oops': unterminated quote`,
+		"status>=abc":     "invalid filter 'status>=abc': 'status>=abc' needs a number",
+	}
+	for expr, expected := range cases {
+		_, err := ParseFilter(expr)
+		assert.EqualError(t, err, expected, expr)
+	}
+	_, err := ParseFilter("msg~(")
+	assert.ErrorContains(t, err, "invalid filter 'msg~(':")
+}
@@ -0,0 +1,183 @@
+package sls
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FieldTime      = "__time__"
+	FieldTopic     = "__topic__"
+	FieldSource    = "__source__"
+	FieldTagPrefix = "__tag__:"
+	// FieldContentPrefix is put in front of a content key that has the name of one of the
+	// fields above, so that it neither repeats a JSON key nor overwrites a CSV column.
+	FieldContentPrefix = "__content__:"
+
+	// Formats of __time__ in flattened output.
+	TimeFormatUnix    = "unix"    // seconds, as stored
+	TimeFormatUnixMs  = "unixms"  // milliseconds, including TimeNs
+	TimeFormatRFC3339 = "rfc3339" // local time with offset, e.g. 2024-04-05T19:34:38.5+08:00
+	TimeFormatUTC     = "utc"     // RFC 3339 in UTC
+)
+
+var TimeFormats = []string{TimeFormatUnix, TimeFormatUnixMs, TimeFormatRFC3339, TimeFormatUTC}
+
+// Field is one key/value of a flattened log.
+type Field struct {
+	Key   string
+	Value string
+}
+
+// Fields is a flattened log; it marshals to a JSON object that keeps the field order.
+type Fields []Field
+
+func (f Fields) Get(key string) (string, bool) {
+	for _, field := range f {
+		if field.Key == key {
+			return field.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set replaces the value of key, or appends the field when there is none.
+func (f *Fields) Set(key, value string) {
+	for i := range *f {
+		if (*f)[i].Key == key {
+			(*f)[i].Value = value
+			return
+		}
+	}
+	*f = append(*f, Field{key, value})
+}
+
+func (f Fields) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range f {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// FlattenLog merges the group level metadata into log, in the order __time__, __topic__,
+// __source__, __tag__:<key> and then the contents, the way the SLS console shows a log.
+// A content key that is already used by the metadata gets FieldContentPrefix.
+func FlattenLog(group *LogGroup, log *Log) Fields {
+	fields := make(Fields, 0, 3+len(group.LogTags)+len(log.Contents))
+	fields = append(fields,
+		Field{FieldTime, strconv.FormatUint(uint64(log.GetTime()), 10)},
+		Field{FieldTopic, group.GetTopic()},
+		Field{FieldSource, group.GetSource()},
+	)
+	for _, tag := range group.LogTags {
+		fields = append(fields, Field{FieldTagPrefix + tag.GetKey(), tag.GetValue()})
+	}
+	metadata := len(fields)
+	for _, content := range log.Contents {
+		key := content.GetKey()
+		if _, ok := fields[:metadata].Get(key); ok {
+			key = FieldContentPrefix + key
+		}
+		fields = append(fields, Field{key, content.GetValue()})
+	}
+	return fields
+}
+
+// FormatLogTime renders the time of log in one of TimeFormats.
+func FormatLogTime(log *Log, format string) (string, error) {
+	t := time.Unix(int64(log.GetTime()), int64(log.GetTimeNs()))
+	switch format {
+	case "", TimeFormatUnix:
+		return strconv.FormatUint(uint64(log.GetTime()), 10), nil
+	case TimeFormatUnixMs:
+		return strconv.FormatInt(t.UnixMilli(), 10), nil
+	case TimeFormatRFC3339:
+		return t.Format(time.RFC3339Nano), nil
+	case TimeFormatUTC:
+		return t.UTC().Format(time.RFC3339Nano), nil
+	}
+	return "", fmt.Errorf("unsupported time format '%s', must be one of %s", format, strings.Join(TimeFormats, ", "))
+}
+
+// WriteLogs writes every log of groups flattened, as NDJSON (one object per line) or CSV
+// (a header with the union of the keys in the order they first appear, then one row per log).
+func WriteLogs(w io.Writer, groups []*LogGroup, format, timeFormat string) error {
+	var logs []Fields
+	for _, group := range groups {
+		for _, log := range group.Logs {
+			fields := FlattenLog(group, log)
+			t, err := FormatLogTime(log, timeFormat)
+			if err != nil {
+				return err
+			}
+			fields.Set(FieldTime, t)
+			logs = append(logs, fields)
+		}
+	}
+
+	switch format {
+	case FormatNDJSON:
+		for _, fields := range logs {
+			line, err := json.Marshal(fields)
+			if err != nil {
+				return err
+			}
+			line = append(line, '\n')
+			if _, err := w.Write(line); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV:
+		var header []string
+		columns := map[string]int{}
+		for _, fields := range logs {
+			for _, field := range fields {
+				if _, ok := columns[field.Key]; !ok {
+					columns[field.Key] = len(header)
+					header = append(header, field.Key)
+				}
+			}
+		}
+		cw := csv.NewWriter(w)
+		if len(header) > 0 {
+			if err := cw.Write(header); err != nil {
+				return err
+			}
+		}
+		for _, fields := range logs {
+			row := make([]string, len(header))
+			for _, field := range fields {
+				row[columns[field.Key]] = field.Value
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unsupported format '%s', must be %s or %s", format, FormatNDJSON, FormatCSV)
+}
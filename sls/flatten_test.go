@@ -0,0 +1,107 @@
+package sls
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlattenLog(t *testing.T) {
+	fields := testFields()
+	data, err := json.Marshal(fields)
+	require.NoError(t, err)
+	// 字段顺序与控制台一致
+	assert.Equal(t, `{"__time__":"1712345678","__topic__":"app","__source__":"","__tag__:host":"web-1",`+
+		`"level":"ERROR","status":"502","msg":"upstream timed out"}`, string(data))
+
+	value, ok := fields.Get("__tag__:host")
+	assert.True(t, ok)
+	assert.Equal(t, "web-1", value)
+	_, ok = fields.Get("missing")
+	assert.False(t, ok)
+}
+
+func TestFormatLogTime(t *testing.T) {
+	log := &Log{Time: proto.Uint32(1712345678), TimeNs: proto.Uint32(500000000)}
+	cases := map[string]string{
+		"":        "1712345678",
+		"unix":    "1712345678",
+		"unixms":  "1712345678500",
+		"rfc3339": time.Unix(1712345678, 500000000).Format(time.RFC3339Nano),
+		"utc":     "2024-04-05T19:34:38.5Z",
+	}
+	for format, expected := range cases {
+		actual, err := FormatLogTime(log, format)
+		require.NoError(t, err, format)
+		assert.Equal(t, expected, actual, format)
+	}
+	_, err := FormatLogTime(log, "iso")
+	assert.EqualError(t, err, "unsupported time format 'iso', must be one of unix, unixms, rfc3339, utc")
+}
+
+func TestWriteLogs(t *testing.T) {
+	groups := []*LogGroup{
+		{
+			Topic: proto.String("app"),
+			Logs: []*Log{
+				{Time: proto.Uint32(1712345678), Contents: []*LogContent{{Key: proto.String("a"), Value: proto.String("1")}}},
+				{Time: proto.Uint32(1712345679), Contents: []*LogContent{{Key: proto.String("b"), Value: proto.String("x,y")}}},
+			},
+		},
+		{
+			Source:  proto.String("10.0.0.1"),
+			LogTags: []*LogTag{{Key: proto.String("host"), Value: proto.String("web-1")}},
+			Logs:    []*Log{{Time: proto.Uint32(1712345680), Contents: []*LogContent{{Key: proto.String("a"), Value: proto.String("2")}}}},
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, WriteLogs(buf, groups, FormatNDJSON, TimeFormatUTC))
+	assert.Equal(t, `{"__time__":"2024-04-05T19:34:38Z","__topic__":"app","__source__":"","a":"1"}
+{"__time__":"2024-04-05T19:34:39Z","__topic__":"app","__source__":"","b":"x,y"}
+{"__time__":"2024-04-05T19:34:40Z","__topic__":"","__source__":"10.0.0.1","__tag__:host":"web-1","a":"2"}
+`, buf.String())
+
+	// CSV 表头为所有字段按首次出现顺序的并集
+	buf.Reset()
+	require.NoError(t, WriteLogs(buf, groups, FormatCSV, ""))
+	assert.Equal(t, `__time__,__topic__,__source__,a,b,__tag__:host
+1712345678,app,,1,,
+1712345679,app,,,"x,y",
+1712345680,,10.0.0.1,2,,web-1
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteLogs(buf, nil, FormatCSV, ""))
+	assert.Empty(t, buf.String())
+	assert.EqualError(t, WriteLogs(buf, groups, "xml", ""), "unsupported format 'xml', must be ndjson or csv")
+}
+
+func TestFlattenLog_ContentKeyCollision(t *testing.T) {
+	group := &LogGroup{
+		Topic:   proto.String("app"),
+		LogTags: []*LogTag{{Key: proto.String("host"), Value: proto.String("web-1")}},
+		Logs: []*Log{{Time: proto.Uint32(1712345678), Contents: []*LogContent{
+			{Key: proto.String("__time__"), Value: proto.String("yesterday")},
+			{Key: proto.String("__topic__"), Value: proto.String("t")},
+			{Key: proto.String("__tag__:host"), Value: proto.String("h")},
+			{Key: proto.String("level"), Value: proto.String("INFO")},
+		}}},
+	}
+	data, err := json.Marshal(FlattenLog(group, group.Logs[0]))
+	require.NoError(t, err)
+	assert.Equal(t, `{"__time__":"1712345678","__topic__":"app","__source__":"","__tag__:host":"web-1",`+
+		`"__content__:__time__":"yesterday","__content__:__topic__":"t","__content__:__tag__:host":"h","level":"INFO"}`, string(data))
+
+	// CSV 中内容字段不会覆盖元数据列
+	buf := new(bytes.Buffer)
+	require.NoError(t, WriteLogs(buf, []*LogGroup{group}, FormatCSV, TimeFormatUTC))
+	assert.Equal(t, `__time__,__topic__,__source__,__tag__:host,__content__:__time__,__content__:__topic__,__content__:__tag__:host,level
+2024-04-05T19:34:38Z,app,,web-1,yesterday,t,h,INFO
+`, buf.String())
+}
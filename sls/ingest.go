@@ -0,0 +1,319 @@
+package sls
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+const (
+	// MaxLogGroupLogs and MaxLogGroupBytes are the PutLogs limits of a single log group
+	// (raw protobuf size before compression).
+	MaxLogGroupLogs  = 4096
+	MaxLogGroupBytes = 5 * 1024 * 1024
+
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// Record is a log parsed from one input line, or one CSV row.
+type Record struct {
+	Line int
+	Log  *Log
+}
+
+// RecordError reports an input line that could not be turned into a log; reading continues after it.
+type RecordError struct {
+	Line int
+	Err  error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+type RecordReaderOptions struct {
+	Format string // FormatNDJSON or FormatCSV
+	// TimeField names the field holding the log time; it is kept in the contents as well.
+	// Logs get the current time when empty.
+	TimeField string
+	// TimeLayout is a Go time layout for TimeField; empty detects unix seconds, milliseconds,
+	// microseconds, nanoseconds, RFC 3339 and "2006-01-02 15:04:05" (local time).
+	TimeLayout string
+	Now        func() time.Time
+}
+
+// RecordReader turns newline-delimited JSON objects, or CSV rows with a header, into logs.
+type RecordReader struct {
+	opts   RecordReaderOptions
+	lines  *bufio.Reader
+	rows   *csv.Reader
+	header []string
+	line   int
+}
+
+func NewRecordReader(r io.Reader, opts RecordReaderOptions) (*RecordReader, error) {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	rr := &RecordReader{opts: opts}
+	switch opts.Format {
+	case FormatNDJSON:
+		rr.lines = bufio.NewReader(r)
+	case FormatCSV:
+		rr.rows = csv.NewReader(r)
+		header, err := rr.rows.Read()
+		if err == io.EOF {
+			return rr, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv header failed: %v", err)
+		}
+		rr.header = header
+	default:
+		return nil, fmt.Errorf("unsupported format '%s', must be %s or %s", opts.Format, FormatNDJSON, FormatCSV)
+	}
+	return rr, nil
+}
+
+// Next returns the next record. A *RecordError reports a bad line and reading may go on;
+// io.EOF ends the input.
+func (rr *RecordReader) Next() (*Record, error) {
+	if rr.rows != nil {
+		return rr.nextRow()
+	}
+	if rr.lines == nil {
+		return nil, io.EOF
+	}
+	for {
+		data, err := rr.lines.ReadBytes('\n')
+		if len(data) == 0 && err != nil {
+			return nil, err
+		}
+		rr.line++
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			continue
+		}
+		fields, perr := parseJSONObject(data)
+		if perr != nil {
+			return nil, &RecordError{Line: rr.line, Err: perr}
+		}
+		return rr.newRecord(rr.line, fields)
+	}
+}
+
+func (rr *RecordReader) nextRow() (*Record, error) {
+	if rr.header == nil {
+		return nil, io.EOF
+	}
+	row, err := rr.rows.Read()
+	if err == io.EOF {
+		return nil, err
+	}
+	var parseErr *csv.ParseError
+	if err != nil {
+		if errors.As(err, &parseErr) {
+			return nil, &RecordError{Line: parseErr.StartLine, Err: parseErr.Err}
+		}
+		return nil, err
+	}
+	line, _ := rr.rows.FieldPos(0)
+	fields := make(map[string]string, len(row))
+	for i, value := range row {
+		fields[rr.header[i]] = value
+	}
+	return rr.newRecord(line, fields)
+}
+
+func (rr *RecordReader) newRecord(line int, fields map[string]string) (*Record, error) {
+	t := rr.opts.Now()
+	if rr.opts.TimeField != "" {
+		value, ok := fields[rr.opts.TimeField]
+		if !ok {
+			return nil, &RecordError{Line: line, Err: fmt.Errorf("time field '%s' is missing", rr.opts.TimeField)}
+		}
+		parsed, err := ParseLogTime(value, rr.opts.TimeLayout)
+		if err != nil {
+			return nil, &RecordError{Line: line, Err: err}
+		}
+		t = parsed
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	log := &Log{Time: proto.Uint32(uint32(t.Unix()))}
+	if ns := t.Nanosecond(); ns != 0 {
+		log.TimeNs = proto.Uint32(uint32(ns))
+	}
+	for _, key := range keys {
+		log.Contents = append(log.Contents, &LogContent{Key: proto.String(key), Value: proto.String(fields[key])})
+	}
+	return &Record{Line: line, Log: log}, nil
+}
+
+// parseJSONObject flattens the top level of a JSON object into strings; nested values are kept as compact JSON.
+func parseJSONObject(data []byte) (map[string]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var object map[string]interface{}
+	if err := dec.Decode(&object); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	if object == nil {
+		return nil, fmt.Errorf("not a JSON object")
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON object")
+	}
+	fields := make(map[string]string, len(object))
+	for key, value := range object {
+		switch v := value.(type) {
+		case nil:
+			fields[key] = ""
+		case string:
+			fields[key] = v
+		case json.Number:
+			fields[key] = v.String()
+		case bool:
+			fields[key] = strconv.FormatBool(v)
+		default:
+			raw, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			fields[key] = string(raw)
+		}
+	}
+	return fields, nil
+}
+
+var logTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+}
+
+// ParseLogTime parses a log time with layout, or detects the format when layout is empty.
+func ParseLogTime(value, layout string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if layout != "" {
+		t, err := time.ParseInLocation(layout, value, time.Local)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time '%s': %v", value, err)
+		}
+		return t, nil
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil && n >= 0 {
+		switch digits := len(strings.TrimLeft(value, "0")); {
+		case digits <= 10:
+			return time.Unix(n, 0), nil
+		case digits <= 13:
+			return time.UnixMilli(n), nil
+		case digits <= 16:
+			return time.UnixMicro(n), nil
+		default:
+			return time.Unix(0, n), nil
+		}
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil && f >= 0 {
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*1e9)).Round(time.Microsecond), nil
+	}
+	for _, l := range logTimeLayouts {
+		if t, err := time.ParseInLocation(l, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s'", value)
+}
+
+// Batch is a log group ready for PutLogs together with the input lines it holds.
+type Batch struct {
+	Group   *LogGroup
+	Lines   []int
+	rawSize int
+}
+
+// Batcher groups records into log groups that respect the PutLogs count and size limits.
+type Batcher struct {
+	maxLogs  int
+	maxBytes int
+	topic    string
+	source   string
+	current  *Batch
+}
+
+func NewBatcher(maxLogs, maxBytes int, topic, source string) *Batcher {
+	if maxLogs <= 0 || maxLogs > MaxLogGroupLogs {
+		maxLogs = MaxLogGroupLogs
+	}
+	if maxBytes <= 0 || maxBytes > MaxLogGroupBytes {
+		maxBytes = MaxLogGroupBytes
+	}
+	return &Batcher{maxLogs: maxLogs, maxBytes: maxBytes, topic: topic, source: source}
+}
+
+// Add appends rec and returns the batch it completed, if any. A record that exceeds the
+// size limit on its own is returned as a *RecordError.
+func (b *Batcher) Add(rec *Record) (*Batch, error) {
+	// field tag and length prefix of the repeated Logs entry
+	size := proto.Size(rec.Log) + 1 + len(proto.EncodeVarint(uint64(proto.Size(rec.Log))))
+	if size > b.maxBytes {
+		return nil, &RecordError{Line: rec.Line, Err: fmt.Errorf("log size %d exceeds the limit of %d bytes", size, b.maxBytes)}
+	}
+	var full *Batch
+	if b.current != nil && (len(b.current.Lines) >= b.maxLogs || b.current.rawSize+size > b.maxBytes) {
+		full = b.Flush()
+	}
+	if b.current == nil {
+		b.current = &Batch{Group: &LogGroup{}}
+		if b.topic != "" {
+			b.current.Group.Topic = proto.String(b.topic)
+		}
+		if b.source != "" {
+			b.current.Group.Source = proto.String(b.source)
+		}
+	}
+	b.current.Group.Logs = append(b.current.Group.Logs, rec.Log)
+	b.current.Lines = append(b.current.Lines, rec.Line)
+	b.current.rawSize += size
+	return full, nil
+}
+
+// Flush returns the pending batch, or nil when there is none.
+func (b *Batcher) Flush() *Batch {
+	batch := b.current
+	b.current = nil
+	return batch
+}
+
+// EncodeLogGroup serializes and LZ4-compresses a log group for PutLogs.
+func EncodeLogGroup(logGroup *LogGroup) (compressedData []byte, rawSize int, err error) {
+	protobufData, err := proto.Marshal(logGroup)
+	if err != nil {
+		return nil, 0, fmt.Errorf("serialize pb failed: %v", err)
+	}
+	compressedData, err = CompressLZ4(protobufData)
+	if err != nil {
+		return nil, 0, fmt.Errorf("lz4 compress failed: %v", err)
+	}
+	return compressedData, len(protobufData), nil
+}
@@ -0,0 +1,182 @@
+package sls
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pierrec/lz4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func contentsOf(log *Log) map[string]string {
+	m := make(map[string]string)
+	for _, c := range log.Contents {
+		m[c.GetKey()] = c.GetValue()
+	}
+	return m
+}
+
+func readAll(t *testing.T, rr *RecordReader) ([]*Record, []*RecordError) {
+	var records []*Record
+	var errs []*RecordError
+	for {
+		rec, err := rr.Next()
+		if err == io.EOF {
+			return records, errs
+		}
+		var recErr *RecordError
+		if errors.As(err, &recErr) {
+			errs = append(errs, recErr)
+			continue
+		}
+		require.NoError(t, err)
+		records = append(records, rec)
+	}
+}
+
+func TestRecordReader_NDJSON(t *testing.T) {
+	input := `{"level":"info","msg":"hello","ts":1712345678,"n":1.5,"ok":true,"tags":{"a":1},"nil":null}
+
+not json
+[1,2]
+{"level":"warn","ts":"2024-04-05T19:34:38.5Z"}
+{"level":"error"}
+`
+	rr, err := NewRecordReader(strings.NewReader(input), RecordReaderOptions{Format: FormatNDJSON, TimeField: "ts"})
+	require.NoError(t, err)
+	records, errs := readAll(t, rr)
+
+	require.Len(t, records, 2)
+	assert.Equal(t, 1, records[0].Line)
+	assert.Equal(t, uint32(1712345678), records[0].Log.GetTime())
+	assert.Equal(t, map[string]string{
+		"level": "info", "msg": "hello", "ts": "1712345678", "n": "1.5", "ok": "true", "tags": `{"a":1}`, "nil": "",
+	}, contentsOf(records[0].Log))
+	// 内容按 key 排序，保证输出稳定
+	assert.Equal(t, "level", records[0].Log.Contents[0].GetKey())
+
+	assert.Equal(t, 5, records[1].Line)
+	assert.Equal(t, uint32(1712345678), records[1].Log.GetTime())
+	assert.Equal(t, uint32(500000000), records[1].Log.GetTimeNs())
+
+	require.Len(t, errs, 3)
+	assert.Equal(t, 3, errs[0].Line)
+	assert.Contains(t, errs[0].Error(), "line 3: invalid JSON")
+	assert.Equal(t, 4, errs[1].Line)
+	assert.EqualError(t, errs[2], "line 6: time field 'ts' is missing")
+}
+
+func TestRecordReader_CSV(t *testing.T) {
+	input := "time,level,msg\n2024-04-05 19:34:38,info,\"hello, world\"\n2024-04-05 19:34:39,warn\nbad time,info,x\n"
+	rr, err := NewRecordReader(strings.NewReader(input), RecordReaderOptions{
+		Format:     FormatCSV,
+		TimeField:  "time",
+		TimeLayout: "2006-01-02 15:04:05",
+	})
+	require.NoError(t, err)
+	records, errs := readAll(t, rr)
+
+	require.Len(t, records, 1)
+	assert.Equal(t, 2, records[0].Line)
+	expected, _ := time.ParseInLocation("2006-01-02 15:04:05", "2024-04-05 19:34:38", time.Local)
+	assert.Equal(t, uint32(expected.Unix()), records[0].Log.GetTime())
+	assert.Equal(t, map[string]string{"time": "2024-04-05 19:34:38", "level": "info", "msg": "hello, world"}, contentsOf(records[0].Log))
+
+	require.Len(t, errs, 2)
+	assert.Equal(t, 3, errs[0].Line)
+	assert.Equal(t, 4, errs[1].Line)
+	assert.Contains(t, errs[1].Error(), "invalid time 'bad time'")
+
+	rr, err = NewRecordReader(strings.NewReader(""), RecordReaderOptions{Format: FormatCSV})
+	require.NoError(t, err)
+	_, err = rr.Next()
+	assert.Equal(t, io.EOF, err)
+
+	_, err = NewRecordReader(strings.NewReader(""), RecordReaderOptions{Format: "xml"})
+	assert.EqualError(t, err, "unsupported format 'xml', must be ndjson or csv")
+}
+
+func TestRecordReader_CurrentTime(t *testing.T) {
+	now := time.Unix(1712345678, 0)
+	rr, err := NewRecordReader(strings.NewReader(`{"a":"b"}`), RecordReaderOptions{
+		Format: FormatNDJSON,
+		Now:    func() time.Time { return now },
+	})
+	require.NoError(t, err)
+	rec, err := rr.Next()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1712345678), rec.Log.GetTime())
+	assert.Nil(t, rec.Log.TimeNs)
+}
+
+func TestParseLogTime(t *testing.T) {
+	cases := map[string]time.Time{
+		"1712345678":           time.Unix(1712345678, 0),
+		"1712345678123":        time.UnixMilli(1712345678123),
+		"1712345678123456":     time.UnixMicro(1712345678123456),
+		"1712345678123456789":  time.Unix(0, 1712345678123456789),
+		"1712345678.25":        time.Unix(1712345678, 250000000),
+		"2024-04-05T19:34:38Z": time.Date(2024, 4, 5, 19, 34, 38, 0, time.UTC),
+	}
+	for value, expected := range cases {
+		actual, err := ParseLogTime(value, "")
+		require.NoError(t, err, value)
+		assert.True(t, expected.Equal(actual), "%s: %v != %v", value, expected, actual)
+	}
+
+	_, err := ParseLogTime("yesterday", "")
+	assert.EqualError(t, err, "invalid time 'yesterday'")
+	_, err = ParseLogTime("2024-04-05", "2006/01/02")
+	assert.Error(t, err)
+}
+
+func TestBatcher(t *testing.T) {
+	newRecord := func(line int, value string) *Record {
+		return &Record{Line: line, Log: &Log{
+			Time:     proto.Uint32(1),
+			Contents: []*LogContent{{Key: proto.String("k"), Value: proto.String(value)}},
+		}}
+	}
+
+	b := NewBatcher(2, 0, "topic", "")
+	full, err := b.Add(newRecord(1, "a"))
+	require.NoError(t, err)
+	assert.Nil(t, full)
+	full, _ = b.Add(newRecord(2, "b"))
+	assert.Nil(t, full)
+	full, _ = b.Add(newRecord(3, "c"))
+	require.NotNil(t, full)
+	assert.Equal(t, []int{1, 2}, full.Lines)
+	assert.Equal(t, "topic", full.Group.GetTopic())
+	assert.Nil(t, full.Group.Source)
+	last := b.Flush()
+	assert.Equal(t, []int{3}, last.Lines)
+	assert.Nil(t, b.Flush())
+
+	// 按大小切分
+	b = NewBatcher(0, 100, "", "")
+	_, err = b.Add(newRecord(1, strings.Repeat("x", 200)))
+	assert.ErrorContains(t, err, "exceeds the limit of 100 bytes")
+	full, _ = b.Add(newRecord(2, strings.Repeat("x", 30)))
+	assert.Nil(t, full)
+	full, _ = b.Add(newRecord(3, strings.Repeat("x", 30)))
+	assert.Nil(t, full)
+	full, _ = b.Add(newRecord(4, strings.Repeat("x", 30)))
+	require.NotNil(t, full)
+	assert.Equal(t, []int{2, 3}, full.Lines)
+
+	data, rawSize, err := EncodeLogGroup(full.Group)
+	require.NoError(t, err)
+	raw := make([]byte, rawSize)
+	n, err := lz4.UncompressBlock(data, raw)
+	require.NoError(t, err)
+	var decoded LogGroup
+	require.NoError(t, proto.Unmarshal(raw[:n], &decoded))
+	assert.Len(t, decoded.Logs, 2)
+	assert.LessOrEqual(t, rawSize, 100)
+}
@@ -58,16 +58,5 @@ func PreparePutLogsData(bodyBytes []byte) (compressedData []byte, rawSize int, e
 	if len(logGroup.Logs) == 0 {
 		return nil, 0, fmt.Errorf("log cannot be empty, please check")
 	}
-	protobufData, err := proto.Marshal(&logGroup)
-	if err != nil {
-		return nil, 0, fmt.Errorf("serialize pb failed: %v", err)
-	}
-
-	rawSize = len(protobufData)
-	compressedData, err = CompressLZ4(protobufData)
-	if err != nil {
-		return nil, 0, fmt.Errorf("lz4 compress failed: %v", err)
-	}
-
-	return compressedData, rawSize, nil
+	return EncodeLogGroup(&logGroup)
 }
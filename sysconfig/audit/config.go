@@ -0,0 +1,214 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit stores the settings of the local, append-only JSON-lines
+// audit log and writes one record per API call or plugin run.
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const ConfigFileName = "audit-log.json"
+
+// DefaultLogFileName is the log file created under the config dir when Path is empty.
+const DefaultLogFileName = "audit.log"
+
+const (
+	EnvEnabled = "ALIBABA_CLOUD_AUDIT_LOG_ENABLED"
+	EnvPath    = "ALIBABA_CLOUD_AUDIT_LOG_PATH"
+	EnvScope   = "ALIBABA_CLOUD_AUDIT_LOG_SCOPE"
+	// Comma-separated parameter name patterns whose values are masked, e.g. *Password*,UserData
+	EnvRedact = "ALIBABA_CLOUD_AUDIT_LOG_REDACT"
+)
+
+const (
+	// ScopeMutating records only calls that may change resources (default).
+	ScopeMutating = "mutating"
+	// ScopeAll records read-only calls as well.
+	ScopeAll = "all"
+)
+
+const (
+	defaultMaxSizeMB  = 10
+	defaultMaxBackups = 5
+)
+
+type Config struct {
+	Enabled *bool  `json:"enabled,omitempty"`
+	Path    string `json:"path,omitempty"`
+	// MaxSizeMB rotates the log to <path>.1 once it would grow beyond this size.
+	MaxSizeMB int `json:"max_size_mb,omitempty"`
+	// MaxBackups is the number of rotated files kept (<path>.1 ... <path>.N).
+	MaxBackups int    `json:"max_backups,omitempty"`
+	Scope      string `json:"scope,omitempty"`
+	// Redact lists extra parameter name patterns (with * wildcard) whose values
+	// are masked in addition to the built-in secret-like names.
+	Redact []string `json:"redact,omitempty"`
+}
+
+func Default() *Config {
+	return &Config{}
+}
+
+func GetConfigFilePath(configDir string) string {
+	return filepath.Join(configDir, ConfigFileName)
+}
+
+func Load(configDir string) (*Config, error) {
+	path := GetConfigFilePath(configDir)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Default(), nil
+		}
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Default(), nil
+	}
+	if c.MaxSizeMB < 0 {
+		c.MaxSizeMB = 0
+	}
+	if c.MaxBackups < 0 {
+		c.MaxBackups = 0
+	}
+	return &c, nil
+}
+
+func Save(configDir string, c *Config) error {
+	if c == nil {
+		c = Default()
+	}
+	path := GetConfigFilePath(configDir)
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func MergeFromEnv(base *Config) *Config {
+	if base == nil {
+		base = Default()
+	}
+
+	out := *base
+	if raw, ok := os.LookupEnv(EnvEnabled); ok {
+		if enabled, err := strconv.ParseBool(strings.TrimSpace(raw)); err == nil {
+			out.Enabled = &enabled
+		}
+	}
+	if raw, ok := os.LookupEnv(EnvPath); ok && strings.TrimSpace(raw) != "" {
+		out.Path = strings.TrimSpace(raw)
+	}
+	if raw, ok := os.LookupEnv(EnvScope); ok {
+		switch scope := strings.ToLower(strings.TrimSpace(raw)); scope {
+		case ScopeMutating, ScopeAll:
+			out.Scope = scope
+		}
+	}
+	if raw, ok := os.LookupEnv(EnvRedact); ok {
+		var extra []string
+		for _, p := range strings.Split(raw, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				extra = append(extra, p)
+			}
+		}
+		out.Redact = append(append([]string(nil), base.Redact...), extra...)
+	}
+	return &out
+}
+
+func LoadEffective(configDir string) (*Config, error) {
+	c, err := Load(configDir)
+	if err != nil {
+		return nil, err
+	}
+	return MergeFromEnv(c), nil
+}
+
+// IsEnabled reports whether audit logging is switched on; it is opt-in.
+func (c *Config) IsEnabled() bool {
+	return c != nil && c.Enabled != nil && *c.Enabled
+}
+
+// LogPath resolves the audit log file, defaulting to audit.log in configDir.
+func (c *Config) LogPath(configDir string) string {
+	if c != nil && c.Path != "" {
+		if strings.HasPrefix(c.Path, "~"+string(filepath.Separator)) || c.Path == "~" {
+			if home, err := os.UserHomeDir(); err == nil {
+				return filepath.Join(home, strings.TrimPrefix(c.Path, "~"))
+			}
+		}
+		return c.Path
+	}
+	return filepath.Join(configDir, DefaultLogFileName)
+}
+
+// ShouldRecord reports whether a call is in scope; readOnly comes from IsReadOnly.
+func (c *Config) ShouldRecord(readOnly bool) bool {
+	if !c.IsEnabled() {
+		return false
+	}
+	return c.Scope == ScopeAll || !readOnly
+}
+
+func (c *Config) maxSizeBytes() int64 {
+	mb := defaultMaxSizeMB
+	if c != nil && c.MaxSizeMB > 0 {
+		mb = c.MaxSizeMB
+	}
+	return int64(mb) * 1024 * 1024
+}
+
+func (c *Config) maxBackups() int {
+	if c != nil && c.MaxBackups > 0 {
+		return c.MaxBackups
+	}
+	return defaultMaxBackups
+}
+
+// MergeIntoPluginEnvs forwards the effective settings so plugins can append
+// to the same audit log.
+func MergeIntoPluginEnvs(configDir string, envs map[string]string) {
+	if envs == nil || configDir == "" {
+		return
+	}
+	c, err := LoadEffective(configDir)
+	if err != nil {
+		return
+	}
+	if c.Enabled != nil {
+		envs[EnvEnabled] = strconv.FormatBool(*c.Enabled)
+	}
+	if !c.IsEnabled() {
+		return
+	}
+	envs[EnvPath] = c.LogPath(configDir)
+	if c.Scope != "" {
+		envs[EnvScope] = c.Scope
+	}
+	if len(c.Redact) > 0 {
+		envs[EnvRedact] = strings.Join(c.Redact, ",")
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDefaultWhenMissing(t *testing.T) {
+	got, err := Load(t.TempDir())
+	require.NoError(t, err)
+	assert.Nil(t, got.Enabled)
+	assert.False(t, got.IsEnabled())
+	assert.False(t, got.ShouldRecord(false))
+}
+
+func TestLoadInvalidJSONFallsBackToDefault(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(GetConfigFilePath(dir), []byte("{not json"), 0600))
+	got, err := Load(dir)
+	require.NoError(t, err)
+	assert.False(t, got.IsEnabled())
+}
+
+func TestSaveLoadAndMergeIntoPluginEnvs(t *testing.T) {
+	dir := t.TempDir()
+	enabled := true
+	require.NoError(t, Save(dir, &Config{
+		Enabled: &enabled,
+		Path:    filepath.Join(dir, "custom.log"),
+		Scope:   ScopeAll,
+		Redact:  []string{"Tag.*", "Description"},
+	}))
+
+	envs := map[string]string{}
+	MergeIntoPluginEnvs(dir, envs)
+
+	assert.Equal(t, "true", envs[EnvEnabled])
+	assert.Equal(t, filepath.Join(dir, "custom.log"), envs[EnvPath])
+	assert.Equal(t, ScopeAll, envs[EnvScope])
+	assert.Equal(t, "Tag.*,Description", envs[EnvRedact])
+}
+
+func TestMergeIntoPluginEnvsDisabled(t *testing.T) {
+	dir := t.TempDir()
+	envs := map[string]string{}
+	MergeIntoPluginEnvs(dir, envs)
+	assert.Empty(t, envs)
+
+	MergeIntoPluginEnvs("", map[string]string{"a": "b"})
+	MergeIntoPluginEnvs(dir, nil)
+}
+
+func TestMergeFromEnvOverridesFile(t *testing.T) {
+	enabled := false
+	t.Setenv(EnvEnabled, "true")
+	t.Setenv(EnvPath, "/tmp/other-audit.log")
+	t.Setenv(EnvScope, "ALL")
+	t.Setenv(EnvRedact, " Foo , ,Bar")
+
+	got := MergeFromEnv(&Config{
+		Enabled: &enabled,
+		Path:    "/tmp/audit.log",
+		Scope:   ScopeMutating,
+		Redact:  []string{"Base"},
+	})
+
+	assert.True(t, got.IsEnabled())
+	assert.Equal(t, "/tmp/other-audit.log", got.Path)
+	assert.Equal(t, ScopeAll, got.Scope)
+	assert.Equal(t, []string{"Base", "Foo", "Bar"}, got.Redact)
+}
+
+func TestMergeFromEnvIgnoresInvalidScope(t *testing.T) {
+	t.Setenv(EnvScope, "everything")
+	got := MergeFromEnv(&Config{Scope: ScopeMutating})
+	assert.Equal(t, ScopeMutating, got.Scope)
+}
+
+func TestShouldRecordScope(t *testing.T) {
+	enabled := true
+	mutating := &Config{Enabled: &enabled}
+	assert.True(t, mutating.ShouldRecord(false))
+	assert.False(t, mutating.ShouldRecord(true))
+
+	all := &Config{Enabled: &enabled, Scope: ScopeAll}
+	assert.True(t, all.ShouldRecord(true))
+}
+
+func TestLogPath(t *testing.T) {
+	dir := t.TempDir()
+	assert.Equal(t, filepath.Join(dir, DefaultLogFileName), Default().LogPath(dir))
+	assert.Equal(t, "/var/log/aliyun.log", (&Config{Path: "/var/log/aliyun.log"}).LogPath(dir))
+
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, "audit", "a.log"), (&Config{Path: filepath.Join("~", "audit", "a.log")}).LogPath(dir))
+}
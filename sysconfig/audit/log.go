@@ -0,0 +1,250 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	SourceOpenAPI = "openapi"
+	SourcePlugin  = "plugin"
+)
+
+const (
+	// ResultOK is recorded for calls that returned without error.
+	ResultOK = "OK"
+	// ResultSafetyPolicyRejected is recorded when the safety policy denied
+	// the command or the user declined the confirmation.
+	ResultSafetyPolicyRejected = "SafetyPolicyRejected"
+)
+
+// RedactedValue replaces the values of secret-like parameters.
+const RedactedValue = "******"
+
+// maxParamValueLen caps each logged parameter value so bodies and user data
+// cannot blow up a single audit line.
+const maxParamValueLen = 512
+
+// defaultRedactPatterns are always masked, whatever the config says.
+var defaultRedactPatterns = []string{
+	"*Password*", "*Secret*", "*Token*", "*PrivateKey*", "*Credential*", "AccessKey*", "UserData", "body",
+}
+
+// Identity describes who made the call, as far as the local profile knows.
+type Identity struct {
+	Mode        string `json:"mode,omitempty"`
+	AccessKeyId string `json:"access_key_id,omitempty"`
+	RoleArn     string `json:"role_arn,omitempty"`
+	AccountId   string `json:"account_id,omitempty"`
+}
+
+type Record struct {
+	Time       string            `json:"time"`
+	OSUser     string            `json:"os_user,omitempty"`
+	Host       string            `json:"host,omitempty"`
+	Profile    string            `json:"profile,omitempty"`
+	Identity   *Identity         `json:"identity,omitempty"`
+	Source     string            `json:"source"`
+	Product    string            `json:"product"`
+	API        string            `json:"api"`
+	Path       string            `json:"path,omitempty"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+	Region     string            `json:"region,omitempty"`
+	Endpoint   string            `json:"endpoint,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
+	StatusCode int               `json:"status_code,omitempty"`
+	ResultCode string            `json:"result_code"`
+	ExitCode   *int              `json:"exit_code,omitempty"`
+	DurationMS int64             `json:"duration_ms"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// NewRecord stamps a record with the current time and local user/host.
+func NewRecord(source, product, api string) *Record {
+	r := &Record{
+		Time:    time.Now().UTC().Format(time.RFC3339Nano),
+		Source:  source,
+		Product: product,
+		API:     api,
+	}
+	if u, err := user.Current(); err == nil {
+		r.OSUser = u.Username
+	}
+	if h, err := os.Hostname(); err == nil {
+		r.Host = h
+	}
+	return r
+}
+
+// SetParameters stores params with secret-like names masked and long values truncated.
+func (r *Record) SetParameters(params map[string][]string, redact []string) {
+	r.Parameters = RedactParameters(params, redact)
+}
+
+// Finish records the call duration measured from start.
+func (r *Record) Finish(start time.Time) {
+	r.DurationMS = time.Since(start).Milliseconds()
+}
+
+// RedactParameters flattens params into name -> value, joining repeated
+// values with ',', masking names that match the default or extra patterns.
+func RedactParameters(params map[string][]string, extra []string) map[string]string {
+	if len(params) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(params))
+	for name, values := range params {
+		key := strings.TrimLeft(name, "-")
+		if IsSensitiveName(key, extra) {
+			out[key] = RedactedValue
+			continue
+		}
+		v := strings.Join(values, ",")
+		if len(v) > maxParamValueLen {
+			v = v[:maxParamValueLen] + fmt.Sprintf("...(%d bytes)", len(v))
+		}
+		out[key] = v
+	}
+	return out
+}
+
+// IsSensitiveName reports whether values of the named parameter must be
+// masked, either by the built-in secret-like patterns or the extra ones.
+func IsSensitiveName(name string, extra []string) bool {
+	return matchesAny(defaultRedactPatterns, name) || matchesAny(extra, name)
+}
+
+var readOnlyPrefixes = []string{
+	"describe", "list", "get", "query", "check", "search", "show", "inspect", "preview", "estimate", "head", "scan", "count", "batchget", "view",
+}
+
+// mutatingPrefixes end the search for the verb of a plugin command, so that an
+// argument after it such as "get-fn" in "delete:get-fn" is not taken for the verb.
+var mutatingPrefixes = []string{
+	"create", "delete", "remove", "update", "put", "set", "add", "modify", "invoke", "run", "start", "stop", "restart",
+	"deploy", "publish", "release", "attach", "detach", "enable", "disable", "apply", "upload", "import", "reset",
+	"cancel", "exec", "call", "send", "copy", "move", "rename", "install", "uninstall", "tag", "untag", "sync",
+	"bind", "unbind", "grant", "revoke", "rollback", "scale", "resize", "reboot", "purge", "clear", "post", "patch",
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsReadOnly infers whether a command only reads state, based on the API
+// name, HTTP verb or the verb of a plugin command. A plugin command is its
+// positional arguments joined by ':', so only the first segment, or the second
+// one after a command group such as "function:list", is taken as the verb.
+func IsReadOnly(apiOrMethod string) bool {
+	switch strings.ToUpper(apiOrMethod) {
+	case "GET", "HEAD", "OPTIONS":
+		return true
+	case "POST", "PUT", "DELETE", "PATCH":
+		return false
+	}
+	segs := strings.FieldsFunc(apiOrMethod, func(r rune) bool { return r == ':' })
+	for i, seg := range segs {
+		if i > 1 {
+			break
+		}
+		seg = strings.ToLower(seg)
+		if hasAnyPrefix(seg, readOnlyPrefixes) {
+			return true
+		}
+		if hasAnyPrefix(seg, mutatingPrefixes) {
+			return false
+		}
+	}
+	return false
+}
+
+// Write appends rec as one JSON line to the configured log, rotating the file
+// first when the line would push it past the size limit.
+func Write(configDir string, c *Config, rec *Record) error {
+	if rec == nil {
+		return nil
+	}
+	path := c.LogPath(configDir)
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	return AppendLine(path, line, c.maxSizeBytes(), c.maxBackups())
+}
+
+// AppendLine appends line to the file at path, first rotating the file to
+// path.1 ... path.N when the line would push it past maxSizeBytes.
+func AppendLine(path string, line []byte, maxSizeBytes int64, maxBackups int) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil && info.Size()+int64(len(line)) > maxSizeBytes {
+		if err := rotate(path, maxBackups); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(line)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// rotate shifts path.N-1 -> path.N ... path -> path.1, dropping the oldest.
+func rotate(path string, backups int) error {
+	_ = os.Remove(fmt.Sprintf("%s.%d", path, backups))
+	for i := backups - 1; i >= 1; i-- {
+		from := fmt.Sprintf("%s.%d", path, i)
+		if _, err := os.Stat(from); err == nil {
+			if err := os.Rename(from, fmt.Sprintf("%s.%d", path, i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	return os.Rename(path, path+".1")
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		re := "(?i)^" + strings.ReplaceAll(regexp.QuoteMeta(p), "\\*", ".*") + "$"
+		if ok, _ := regexp.MatchString(re, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readRecords(t *testing.T, path string) []Record {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var out []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		out = append(out, r)
+	}
+	require.NoError(t, scanner.Err())
+	return out
+}
+
+func TestWriteAppendsJSONLines(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &Config{}
+
+	rec := NewRecord(SourceOpenAPI, "ecs", "DeleteInstance")
+	rec.ResultCode = ResultOK
+	rec.RequestID = "req-1"
+	require.NoError(t, Write(dir, cfg, rec))
+
+	rec2 := NewRecord(SourcePlugin, "fc", "function:delete")
+	rec2.ResultCode = "ExitCode1"
+	require.NoError(t, Write(dir, cfg, rec2))
+
+	records := readRecords(t, filepath.Join(dir, DefaultLogFileName))
+	require.Len(t, records, 2)
+	assert.Equal(t, "ecs", records[0].Product)
+	assert.Equal(t, "req-1", records[0].RequestID)
+	assert.NotEmpty(t, records[0].Time)
+	assert.Equal(t, SourcePlugin, records[1].Source)
+
+	info, err := os.Stat(filepath.Join(dir, DefaultLogFileName))
+	require.NoError(t, err)
+	if os.PathSeparator == '/' {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+}
+
+func TestWriteRotates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nested", "audit.log")
+	cfg := &Config{Path: path, MaxSizeMB: 1, MaxBackups: 2}
+
+	// Pre-fill the log just under the limit so the next record rotates it.
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("x", 1024*1024-10)+"\n"), 0600))
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, Write(dir, cfg, NewRecord(SourceOpenAPI, "ecs", "StopInstance")))
+		if i < 2 {
+			// force rotation on every following write
+			require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("y", 1024*1024)), 0600))
+		}
+	}
+
+	_, err := os.Stat(path + ".1")
+	assert.NoError(t, err)
+	_, err = os.Stat(path + ".2")
+	assert.NoError(t, err)
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err), "only MaxBackups rotated files are kept")
+	assert.Len(t, readRecords(t, path), 1)
+}
+
+func TestRedactParameters(t *testing.T) {
+	got := RedactParameters(map[string][]string{
+		"InstanceId":      {"i-1"},
+		"Password":        {"p@ss"},
+		"LoginPassword":   {"x"},
+		"SecurityToken":   {"t"},
+		"Tag.1.Value":     {"secret-project"},
+		"InstanceIds":     {"i-1", "i-2"},
+		"--ClientSecret":  {"s"},
+		"Description":     {strings.Repeat("d", 600)},
+		"AccessKeySecret": {"sk"},
+	}, []string{"Tag.*"})
+
+	assert.Equal(t, "i-1", got["InstanceId"])
+	assert.Equal(t, RedactedValue, got["Password"])
+	assert.Equal(t, RedactedValue, got["LoginPassword"])
+	assert.Equal(t, RedactedValue, got["SecurityToken"])
+	assert.Equal(t, RedactedValue, got["Tag.1.Value"])
+	assert.Equal(t, RedactedValue, got["ClientSecret"])
+	assert.Equal(t, RedactedValue, got["AccessKeySecret"])
+	assert.Equal(t, "i-1,i-2", got["InstanceIds"])
+	assert.True(t, strings.HasSuffix(got["Description"], "...(600 bytes)"))
+
+	assert.Nil(t, RedactParameters(nil, nil))
+}
+
+func TestIsSensitiveName(t *testing.T) {
+	assert.True(t, IsSensitiveName("db_password", nil))
+	assert.True(t, IsSensitiveName("AccessKeyId", nil))
+	assert.False(t, IsSensitiveName("InstanceId", nil))
+	assert.True(t, IsSensitiveName("InstanceId", []string{"instance*"}))
+}
+
+func TestIsReadOnly(t *testing.T) {
+	for _, api := range []string{"DescribeInstances", "ListTagResources", "GetUser", "GET", "get", "list-functions", "function:list"} {
+		assert.True(t, IsReadOnly(api), api)
+	}
+	for _, api := range []string{"DeleteInstance", "RunInstances", "StopInstance", "DELETE", "POST", "create-function", "function:create"} {
+		assert.False(t, IsReadOnly(api), api)
+	}
+	// 插件命令的参数以 get/list 开头时不影响判断
+	for _, api := range []string{"delete:get-fn", "invoke:list-users", "function:delete:get-fn", "my-fn:alias:get"} {
+		assert.False(t, IsReadOnly(api), api)
+	}
+}
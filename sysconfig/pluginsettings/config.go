@@ -17,7 +17,11 @@
 package pluginsettings
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -29,11 +33,55 @@ const ConfigFileName = "plugin-settings.json"
 
 const EnvSourceBase = "ALIBABA_CLOUD_CLI_PLUGIN_SOURCE_BASE"
 
+const EnvSignaturePolicy = "ALIBABA_CLOUD_CLI_PLUGIN_SIGNATURE_POLICY"
+
+// Signature policies for plugin indexes and packages.
+const (
+	// SignaturePolicyOff skips signature verification. This is the default until a release
+	// key is built in.
+	SignaturePolicyOff = "off"
+	// SignaturePolicyVerify verifies signatures made by trusted keys and rejects invalid ones,
+	// but still accepts unsigned content.
+	SignaturePolicyVerify = "verify"
+	// SignaturePolicyRequire refuses any index or package not signed by a trusted key.
+	SignaturePolicyRequire = "require"
+)
+
 type PluginSettings struct {
 	// SourceBase is the URL prefix for the plugins tree, e.g. https://example.com/plugins
+	// or file:///opt/aliyun-plugins for a directory created by `aliyun plugin mirror`
 	// Index: {SourceBase}/plugin_pkg_index.json, {SourceBase}/plugin_search_index.json
 	// Packages: {SourceBase}/pkgs/{name}/{version}/{filename}
 	SourceBase string `json:"source_base,omitempty"`
+	// SignaturePolicy is one of off (default), verify or require
+	SignaturePolicy string `json:"signature_policy,omitempty"`
+	// TrustedKeys are base64 ed25519 public keys trusted in addition to the built-in release keys
+	TrustedKeys []string `json:"trusted_keys,omitempty"`
+}
+
+func IsValidSignaturePolicy(v string) bool {
+	switch v {
+	case SignaturePolicyOff, SignaturePolicyVerify, SignaturePolicyRequire:
+		return true
+	}
+	return false
+}
+
+// IsSupportedSourceBase reports whether v uses a scheme the plugin manager can fetch from.
+func IsSupportedSourceBase(v string) bool {
+	lower := strings.ToLower(strings.TrimSpace(v))
+	return strings.HasPrefix(lower, "http://") ||
+		strings.HasPrefix(lower, "https://") ||
+		strings.HasPrefix(lower, "file://")
+}
+
+// ParseTrustedKey decodes a base64 ed25519 public key as stored in TrustedKeys.
+func ParseTrustedKey(s string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid trusted key '%s': expected a base64 encoded %d-byte ed25519 public key", s, ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(raw), nil
 }
 
 func Default() *PluginSettings {
@@ -44,7 +92,21 @@ func GetConfigFilePath(configDir string) string {
 	return filepath.Join(configDir, ConfigFileName)
 }
 
+// errInvalidSettings marks a settings file that exists but cannot be parsed.
+var errInvalidSettings = errors.New("invalid plugin settings file")
+
+// Load reads the settings; a missing or unparsable file yields the defaults.
 func Load(configDir string) (*PluginSettings, error) {
+	c, err := LoadStrict(configDir)
+	if errors.Is(err, errInvalidSettings) {
+		return Default(), nil
+	}
+	return c, err
+}
+
+// LoadStrict is Load, but fails on an unparsable file. Use it where the defaults
+// would weaken a setting, such as the "require" signature policy.
+func LoadStrict(configDir string) (*PluginSettings, error) {
 	path := GetConfigFilePath(configDir)
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -55,7 +117,7 @@ func Load(configDir string) (*PluginSettings, error) {
 	}
 	var c PluginSettings
 	if err := json.Unmarshal(data, &c); err != nil {
-		return Default(), nil
+		return nil, fmt.Errorf("%w %s: %v", errInvalidSettings, path, err)
 	}
 	c.SourceBase = strings.TrimSpace(c.SourceBase)
 	return &c, nil
@@ -85,3 +147,38 @@ func EffectiveSourceBase(c *PluginSettings) string {
 	}
 	return strings.TrimRight(strings.TrimSpace(c.SourceBase), "/")
 }
+
+// EffectiveSignaturePolicy returns the stricter of the signature policies from the settings file
+// and the environment, so the environment can raise the policy but never lower it.
+// Unknown values fall back to require so that a typo never disables verification.
+func EffectiveSignaturePolicy(c *PluginSettings) string {
+	policy := SignaturePolicyOff
+	if c != nil {
+		policy = normalizeSignaturePolicy(c.SignaturePolicy)
+	}
+	if env := normalizeSignaturePolicy(util.GetFromEnv(EnvSignaturePolicy)); signaturePolicyRank(env) > signaturePolicyRank(policy) {
+		policy = env
+	}
+	return policy
+}
+
+func normalizeSignaturePolicy(v string) string {
+	v = strings.ToLower(strings.TrimSpace(v))
+	if v == "" {
+		return SignaturePolicyOff
+	}
+	if !IsValidSignaturePolicy(v) {
+		return SignaturePolicyRequire
+	}
+	return v
+}
+
+func signaturePolicyRank(v string) int {
+	switch v {
+	case SignaturePolicyVerify:
+		return 1
+	case SignaturePolicyRequire:
+		return 2
+	}
+	return 0
+}
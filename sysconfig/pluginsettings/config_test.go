@@ -54,4 +54,46 @@ func TestLoad_InvalidJSON(t *testing.T) {
 	c, err := Load(dir)
 	assert.NoError(t, err)
 	assert.Equal(t, "", c.SourceBase)
+
+	_, err = LoadStrict(dir)
+	assert.ErrorContains(t, err, "invalid plugin settings file")
+}
+
+func TestIsSupportedSourceBase(t *testing.T) {
+	assert.True(t, IsSupportedSourceBase("https://mirror.example.com/plugins"))
+	assert.True(t, IsSupportedSourceBase("HTTP://mirror.example.com/plugins"))
+	assert.True(t, IsSupportedSourceBase(" file:///opt/aliyun-plugins"))
+	assert.False(t, IsSupportedSourceBase("ftp://mirror.example.com/plugins"))
+	assert.False(t, IsSupportedSourceBase("/opt/aliyun-plugins"))
+}
+
+func TestEffectiveSignaturePolicy(t *testing.T) {
+	t.Setenv(EnvSignaturePolicy, "")
+	assert.Equal(t, SignaturePolicyOff, EffectiveSignaturePolicy(nil))
+	assert.Equal(t, SignaturePolicyOff, EffectiveSignaturePolicy(&PluginSettings{}))
+	assert.Equal(t, SignaturePolicyVerify, EffectiveSignaturePolicy(&PluginSettings{SignaturePolicy: "Verify"}))
+	// 无法识别的取值按 require 处理
+	assert.Equal(t, SignaturePolicyRequire, EffectiveSignaturePolicy(&PluginSettings{SignaturePolicy: "of"}))
+
+	t.Setenv(EnvSignaturePolicy, "Require")
+	assert.Equal(t, SignaturePolicyRequire, EffectiveSignaturePolicy(&PluginSettings{SignaturePolicy: "off"}))
+	t.Setenv(EnvSignaturePolicy, "verify")
+	assert.Equal(t, SignaturePolicyVerify, EffectiveSignaturePolicy(nil))
+
+	// 环境变量只能收紧策略，不能放宽配置文件中的设置
+	t.Setenv(EnvSignaturePolicy, "off")
+	assert.Equal(t, SignaturePolicyRequire, EffectiveSignaturePolicy(&PluginSettings{SignaturePolicy: "require"}))
+	t.Setenv(EnvSignaturePolicy, "verify")
+	assert.Equal(t, SignaturePolicyRequire, EffectiveSignaturePolicy(&PluginSettings{SignaturePolicy: "require"}))
+	t.Setenv(EnvSignaturePolicy, "of")
+	assert.Equal(t, SignaturePolicyRequire, EffectiveSignaturePolicy(&PluginSettings{SignaturePolicy: "verify"}))
+}
+
+func TestParseTrustedKey(t *testing.T) {
+	key, err := ParseTrustedKey(" AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA= ")
+	assert.NoError(t, err)
+	assert.Len(t, key, 32)
+
+	_, err = ParseTrustedKey("AAAA")
+	assert.ErrorContains(t, err, "invalid trusted key 'AAAA'")
 }
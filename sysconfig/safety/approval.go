@@ -0,0 +1,260 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package safety
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Approvals let a human pre-approve a class of `confirm` operations for a
+// limited time, e.g. so an agent may run `ecs:StopInstance` for the next 15
+// minutes without a blanket --yes. Grants are signed with a per-user key kept
+// next to the config, so a grant whose pattern or expiry was edited by hand
+// (or copied from another machine) is ignored. The key guards against
+// tampering, not against a process running as the same user, which can read
+// it; confirm rules of locked layers therefore ignore approvals.
+const (
+	ApprovalsFileName   = "safety-approvals.json"
+	ApprovalKeyFileName = "safety-approval.key"
+)
+
+// MaxApprovalTTL caps how long a single grant can last.
+const MaxApprovalTTL = 24 * time.Hour
+
+// now is replaced in tests.
+var now = time.Now
+
+// Grant approves commands matching Pattern until ExpiresAt.
+type Grant struct {
+	ID        string    `json:"id"`
+	Pattern   string    `json:"pattern"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	Signature string    `json:"signature"`
+}
+
+type approvalsFile struct {
+	Grants []Grant `json:"grants"`
+}
+
+func GetApprovalsFilePath(configDir string) string {
+	return filepath.Join(configDir, ApprovalsFileName)
+}
+
+func getApprovalKeyPath(configDir string) string {
+	return filepath.Join(configDir, ApprovalKeyFileName)
+}
+
+// Active reports whether the grant has not expired yet.
+func (g Grant) Active() bool {
+	return now().Before(g.ExpiresAt)
+}
+
+func (g Grant) payload() []byte {
+	return []byte(fmt.Sprintf("%s\n%s\n%d\n%d", g.ID, g.Pattern, g.CreatedAt.Unix(), g.ExpiresAt.Unix()))
+}
+
+func signGrant(key []byte, g Grant) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(g.payload())
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func verifyGrant(key []byte, g Grant) bool {
+	want, err := hex.DecodeString(g.Signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(g.payload())
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+// loadApprovalKey reads the signing key; with create it generates one on first use.
+func loadApprovalKey(configDir string, create bool) ([]byte, error) {
+	path := getApprovalKeyPath(configDir)
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) < 32 {
+			return nil, fmt.Errorf("invalid approval key %s", path)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) || !create {
+		return nil, err
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)), 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func readApprovalsFile(configDir string) ([]Grant, error) {
+	data, err := os.ReadFile(GetApprovalsFilePath(configDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var f approvalsFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	return f.Grants, nil
+}
+
+func writeApprovalsFile(configDir string, grants []Grant) error {
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return err
+	}
+	if grants == nil {
+		grants = []Grant{}
+	}
+	data, err := json.MarshalIndent(approvalsFile{Grants: grants}, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(GetApprovalsFilePath(configDir), data, 0600)
+}
+
+// LoadApprovals returns the active grants whose signature verifies. Missing
+// or unreadable files simply yield no approvals.
+func LoadApprovals(configDir string) []Grant {
+	key, err := loadApprovalKey(configDir, false)
+	if err != nil {
+		return nil
+	}
+	grants, err := readApprovalsFile(configDir)
+	if err != nil {
+		return nil
+	}
+	var valid []Grant
+	for _, g := range grants {
+		if g.Active() && verifyGrant(key, g) {
+			valid = append(valid, g)
+		}
+	}
+	return valid
+}
+
+// AddApproval signs and stores a grant for pattern lasting ttl, dropping
+// expired grants from the file on the way.
+func AddApproval(configDir, pattern string, ttl time.Duration, createdBy string) (*Grant, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil, fmt.Errorf("approval pattern is empty")
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("approval ttl must be positive")
+	}
+	if ttl > MaxApprovalTTL {
+		return nil, fmt.Errorf("approval ttl must not exceed %s", MaxApprovalTTL)
+	}
+	key, err := loadApprovalKey(configDir, true)
+	if err != nil {
+		return nil, err
+	}
+	grants, err := readApprovalsFile(configDir)
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	created := now().UTC().Truncate(time.Second)
+	g := Grant{
+		ID:        hex.EncodeToString(id),
+		Pattern:   pattern,
+		CreatedAt: created,
+		ExpiresAt: created.Add(ttl),
+		CreatedBy: createdBy,
+	}
+	g.Signature = signGrant(key, g)
+
+	kept := make([]Grant, 0, len(grants)+1)
+	for _, old := range grants {
+		if old.Active() {
+			kept = append(kept, old)
+		}
+	}
+	kept = append(kept, g)
+	if err := writeApprovalsFile(configDir, kept); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// RevokeApprovals removes grants matching pattern exactly, or all grants when
+// pattern is empty, and returns how many were removed.
+func RevokeApprovals(configDir, pattern string) (int, error) {
+	grants, err := readApprovalsFile(configDir)
+	if err != nil {
+		return 0, err
+	}
+	kept := make([]Grant, 0, len(grants))
+	for _, g := range grants {
+		if pattern != "" && g.Pattern != pattern {
+			kept = append(kept, g)
+		}
+	}
+	removed := len(grants) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, writeApprovalsFile(configDir, kept)
+}
+
+// ApprovalFor returns the active grant that satisfies a confirm rule for cmd,
+// or nil. Only policies from LoadEffectivePolicy carry approvals. Rules from a
+// locked layer are never satisfied by an approval: the key signing them is
+// readable by whatever runs as the user, agents included.
+func (p *Policy) ApprovalFor(cmd CommandInfo) *Grant {
+	if r := p.Check(cmd).Rule; r != nil && r.locked {
+		return nil
+	}
+	return approvalFor(p.approvals, cmd)
+}
+
+// approvalFor returns the first active grant covering cmd.
+func approvalFor(grants []Grant, cmd CommandInfo) *Grant {
+	pattern := buildCommandPattern(cmd)
+	for i := range grants {
+		if grants[i].Active() && matchPattern(grants[i].Pattern, pattern) {
+			return &grants[i]
+		}
+	}
+	return nil
+}
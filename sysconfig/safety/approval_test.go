@@ -0,0 +1,146 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package safety
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fixNow(t *testing.T, at time.Time) *time.Time {
+	t.Helper()
+	cur := at
+	orig := now
+	now = func() time.Time { return cur }
+	t.Cleanup(func() { now = orig })
+	return &cur
+}
+
+func TestAddApproval_LoadAndExpire(t *testing.T) {
+	dir := t.TempDir()
+	clock := fixNow(t, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	g, err := AddApproval(dir, "ecs:StopInstance", 15*time.Minute, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, clock.Add(15*time.Minute), g.ExpiresAt)
+
+	info, err := os.Stat(getApprovalKeyPath(dir))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	grants := LoadApprovals(dir)
+	require.Len(t, grants, 1)
+	assert.Equal(t, "ecs:StopInstance", grants[0].Pattern)
+
+	*clock = clock.Add(16 * time.Minute)
+	assert.Empty(t, LoadApprovals(dir))
+
+	// Adding a new grant prunes the expired one from the file.
+	_, err = AddApproval(dir, "fc:function:*", time.Hour, "")
+	require.NoError(t, err)
+	stored, err := readApprovalsFile(dir)
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, "fc:function:*", stored[0].Pattern)
+}
+
+func TestAddApproval_Validation(t *testing.T) {
+	dir := t.TempDir()
+	_, err := AddApproval(dir, " ", time.Minute, "")
+	assert.Error(t, err)
+	_, err = AddApproval(dir, "ecs:*", 0, "")
+	assert.Error(t, err)
+	_, err = AddApproval(dir, "ecs:*", 25*time.Hour, "")
+	assert.Error(t, err)
+}
+
+func TestLoadApprovals_IgnoresTamperedGrant(t *testing.T) {
+	dir := t.TempDir()
+	_, err := AddApproval(dir, "ecs:StopInstance", time.Minute, "")
+	require.NoError(t, err)
+
+	grants, err := readApprovalsFile(dir)
+	require.NoError(t, err)
+	grants[0].Pattern = "*"
+	grants[0].ExpiresAt = grants[0].ExpiresAt.Add(240 * time.Hour)
+	data, err := json.Marshal(approvalsFile{Grants: grants})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(GetApprovalsFilePath(dir), data, 0600))
+
+	assert.Empty(t, LoadApprovals(dir))
+}
+
+func TestRevokeApprovals(t *testing.T) {
+	dir := t.TempDir()
+	_, err := AddApproval(dir, "ecs:StopInstance", time.Minute, "")
+	require.NoError(t, err)
+	_, err = AddApproval(dir, "ecs:RebootInstance", time.Minute, "")
+	require.NoError(t, err)
+
+	n, err := RevokeApprovals(dir, "ecs:StopInstance")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.Len(t, LoadApprovals(dir), 1)
+
+	n, err = RevokeApprovals(dir, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Empty(t, LoadApprovals(dir))
+}
+
+func TestCheckAndConfirm_HonorsApprovalForConfirmRulesOnly(t *testing.T) {
+	dir := t.TempDir()
+	unsetEnvForTest(t, EnvSafetyPolicyEnabled)
+	unsetEnvForTest(t, EnvSafetyPolicyRules)
+	require.NoError(t, SavePolicy(dir, &Policy{Enabled: true, Rules: []Rule{
+		{Pattern: "ecs:StopInstance", Action: ActionConfirm},
+		{Pattern: "ecs:DeleteInstance", Action: ActionDeny},
+	}}))
+	_, err := AddApproval(dir, "ecs:*Instance", time.Minute, "")
+	require.NoError(t, err)
+
+	policy, err := LoadEffectivePolicy(dir)
+	require.NoError(t, err)
+	stderr := new(bytes.Buffer)
+	ctx := cli.NewCommandContext(new(bytes.Buffer), stderr)
+
+	assert.NoError(t, CheckAndConfirm(ctx, policy, CommandInfo{Product: "ecs", ApiOrMethod: "StopInstance"}, false))
+	assert.Contains(t, stderr.String(), "satisfied by approval")
+
+	err = CheckAndConfirm(ctx, policy, CommandInfo{Product: "ecs", ApiOrMethod: "DeleteInstance"}, false)
+	assert.ErrorContains(t, err, "blocked by safety policy")
+}
+
+func TestApprovalFor_IgnoredByLockedConfirmRules(t *testing.T) {
+	d := setupLayers(t)
+	fixNow(t, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	writePolicyFile(t, d.system, Policy{Enabled: true, Locked: true, Rules: []Rule{{Pattern: "ram:*", Action: ActionConfirm}}})
+	writePolicyFile(t, d.user, Policy{Enabled: true, Rules: []Rule{{Pattern: "ecs:*", Action: ActionConfirm}}})
+	_, err := AddApproval(d.user, "*", time.Minute, "")
+	require.NoError(t, err)
+
+	policy, err := LoadEffectivePolicy(d.user)
+	require.NoError(t, err)
+	assert.NotNil(t, policy.ApprovalFor(CommandInfo{Product: "ecs", ApiOrMethod: "StopInstance"}))
+	// 锁定层的确认规则只接受人工确认，审批无效
+	assert.Nil(t, policy.ApprovalFor(CommandInfo{Product: "ram", ApiOrMethod: "DeleteUser"}))
+}
@@ -20,6 +20,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/aliyun/aliyun-cli/v3/cli"
 	"github.com/aliyun/aliyun-cli/v3/i18n"
@@ -59,25 +60,33 @@ func CheckAndConfirm(ctx *cli.Context, policy *Policy, cmd CommandInfo, skipConf
 		return fmt.Errorf(i18n.T(
 			"operation blocked by safety policy: %s %s (rule: %s)",
 			"操作被安全策略拒绝: %s %s (规则: %s)",
-		).GetMessage(), cmd.Product, displayCommand(cmd), result.Rule.Pattern)
+		).GetMessage(), cmd.Product, displayCommand(cmd), displayRule(result))
 	case ActionConfirm:
 		if skipConfirm {
 			// --yes or env: treat as already confirmed
 			break
 		}
+		if g := policy.ApprovalFor(cmd); g != nil {
+			// A human approved this class of operation ahead of time
+			cli.Printf(ctx.Stderr(), i18n.T(
+				"Safety policy confirmation for %s %s satisfied by approval %s (%s, expires %s)\n",
+				"安全策略确认 %s %s 已由审批 %s 满足 (%s，过期时间 %s)\n",
+			).GetMessage(), cmd.Product, displayCommand(cmd), g.ID, g.Pattern, g.ExpiresAt.Local().Format(time.RFC3339))
+			break
+		}
 		if !IsInteractive() {
 			return fmt.Errorf(i18n.T(
-				"Safety policy requires confirmation for: %s %s\n"+
+				"Safety policy requires confirmation for: %s %s (rule: %s)\n"+
 					"This operation cannot run in non-interactive mode without explicit approval. "+
-					"If you are an agent, ask the user whether this operation is allowed; after they confirm (e.g. reply yes or 确认), re-run the same command with --yes.",
-				"安全策略要求确认以下操作：%s %s\n"+
-					"当前为非交互环境，无法自动确认。若调用方为智能体，请先向用户说明并征得同意；用户同意后（可在对话中回复 yes 或「确认」），再使用 --yes 重新执行同一命令。",
-			).GetMessage(), cmd.Product, displayCommand(cmd))
+					"If you are an agent, ask the user whether this operation is allowed; after they confirm (e.g. reply yes or 确认), re-run the same command with --yes, or have them pre-approve it with 'aliyun configure safety-policy approve --pattern <pattern> --ttl <duration>'.",
+				"安全策略要求确认以下操作：%s %s (规则: %s)\n"+
+					"当前为非交互环境，无法自动确认。若调用方为智能体，请先向用户说明并征得同意；用户同意后（可在对话中回复 yes 或「确认」），再使用 --yes 重新执行同一命令，或请用户通过 'aliyun configure safety-policy approve --pattern <模式> --ttl <时长>' 预先审批。",
+			).GetMessage(), cmd.Product, displayCommand(cmd), displayRule(result))
 		}
 		prompt := fmt.Sprintf(i18n.T(
-			"Safety policy requires confirmation for: %s %s\nType 'yes' to proceed, anything else to cancel: ",
-			"安全策略要求确认以下操作: %s %s\n输入 'yes' 继续，其他任意输入取消: ",
-		).GetMessage(), cmd.Product, displayCommand(cmd))
+			"Safety policy requires confirmation for: %s %s (rule: %s)\nType 'yes' to proceed, anything else to cancel: ",
+			"安全策略要求确认以下操作: %s %s (规则: %s)\n输入 'yes' 继续，其他任意输入取消: ",
+		).GetMessage(), cmd.Product, displayCommand(cmd), displayRule(result))
 		if !PromptConfirm(ctx.Stderr(), prompt) {
 			return fmt.Errorf(i18n.T(
 				"operation cancelled by user",
@@ -97,3 +106,15 @@ func displayCommand(cmd CommandInfo) string {
 	}
 	return cmd.ApiOrMethod
 }
+
+// displayRule renders the matched rule's pattern plus its When condition, if any,
+// so users can tell which region/profile/parameter triggered the rule.
+func displayRule(result CheckResult) string {
+	if result.Rule == nil {
+		return ""
+	}
+	if result.Condition != "" {
+		return result.Rule.Pattern + " when " + result.Condition
+	}
+	return result.Rule.Pattern
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package safety
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+)
+
+// Condition narrows a Rule to invocations that also match on parameters,
+// region, profile or endpoint. Every non-empty field must hold for the rule to
+// match; list fields hold when any of their wildcard patterns matches.
+//
+//	{"pattern": "ecs:DeleteInstance", "action": "confirm",
+//	 "when": {"params": [{"name": "Force", "value": "true"}]}}
+//	{"pattern": "*", "action": "deny",
+//	 "when": {"regions": ["cn-shanghai"], "profiles": ["prod"]}}
+//	{"pattern": "ecs:RunCommand", "action": "deny",
+//	 "when": {"params": [{"name": "InstanceId*", "countGreaterThan": 10}]}}
+type Condition struct {
+	Params    []ParamCondition `json:"params,omitempty"`
+	Regions   []string         `json:"regions,omitempty"`
+	Profiles  []string         `json:"profiles,omitempty"`
+	Endpoints []string         `json:"endpoints,omitempty"`
+}
+
+// ParamCondition matches a command line parameter by name. Name is the flag
+// name without leading dashes and supports the * wildcard, so "InstanceId.*"
+// covers RepeatList forms such as --InstanceId.1 a --InstanceId.2 b.
+// When neither Value, Present nor CountGreaterThan is set, the parameter only
+// has to be present.
+type ParamCondition struct {
+	Name string `json:"name"`
+	// Value matches when any value of the parameter matches this wildcard pattern.
+	Value string `json:"value,omitempty"`
+	// Present requires the parameter to be supplied (true) or absent (false).
+	Present *bool `json:"present,omitempty"`
+	// CountGreaterThan matches when the parameter carries more than N values.
+	// Repeated flags each count once and a JSON array value counts its elements.
+	CountGreaterThan *int `json:"countGreaterThan,omitempty"`
+}
+
+// ParamsFromFlags collects the values of the parsed flags keyed by flag name,
+// which is the shape CommandInfo.Params expects.
+func ParamsFromFlags(fs *cli.FlagSet) map[string][]string {
+	if fs == nil {
+		return nil
+	}
+	params := make(map[string][]string)
+	for _, f := range fs.Flags() {
+		if f == nil || !f.IsAssigned() {
+			continue
+		}
+		values := f.GetValues()
+		if len(values) == 0 {
+			v, _ := f.GetValue()
+			values = []string{v}
+		}
+		params[f.Name] = append(params[f.Name], values...)
+	}
+	return params
+}
+
+// Matches reports whether cmd satisfies the condition. A nil condition always matches.
+func (c *Condition) Matches(cmd CommandInfo) bool {
+	if c == nil {
+		return true
+	}
+	if len(c.Regions) > 0 && !matchAny(c.Regions, cmd.Region) {
+		return false
+	}
+	if len(c.Profiles) > 0 && !matchAny(c.Profiles, cmd.Profile) {
+		return false
+	}
+	if len(c.Endpoints) > 0 && !matchAny(c.Endpoints, cmd.Endpoint) {
+		return false
+	}
+	for _, pc := range c.Params {
+		if !pc.matches(cmd.Params) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsEmpty reports whether the condition has nothing to check.
+func (c *Condition) IsEmpty() bool {
+	return c == nil || (len(c.Params) == 0 && len(c.Regions) == 0 && len(c.Profiles) == 0 && len(c.Endpoints) == 0)
+}
+
+// String renders the condition for deny/confirm messages, e.g.
+// "region=cn-shanghai, profile=prod, Force=true".
+func (c *Condition) String() string {
+	if c.IsEmpty() {
+		return ""
+	}
+	var parts []string
+	if len(c.Regions) > 0 {
+		parts = append(parts, "region="+strings.Join(c.Regions, "|"))
+	}
+	if len(c.Profiles) > 0 {
+		parts = append(parts, "profile="+strings.Join(c.Profiles, "|"))
+	}
+	if len(c.Endpoints) > 0 {
+		parts = append(parts, "endpoint="+strings.Join(c.Endpoints, "|"))
+	}
+	for _, pc := range c.Params {
+		parts = append(parts, pc.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (pc ParamCondition) String() string {
+	var parts []string
+	if pc.Present != nil && !*pc.Present {
+		return pc.Name + " absent"
+	}
+	if pc.Value != "" {
+		parts = append(parts, pc.Name+"="+pc.Value)
+	}
+	if pc.CountGreaterThan != nil {
+		parts = append(parts, fmt.Sprintf("count(%s)>%d", pc.Name, *pc.CountGreaterThan))
+	}
+	if len(parts) == 0 {
+		return pc.Name + " present"
+	}
+	return strings.Join(parts, " ")
+}
+
+func (pc ParamCondition) matches(params map[string][]string) bool {
+	name := strings.TrimLeft(strings.TrimSpace(pc.Name), "-")
+	if name == "" {
+		return false
+	}
+	var values []string
+	found := false
+	for k, v := range params {
+		if matchPattern(name, strings.TrimLeft(k, "-")) {
+			found = true
+			values = append(values, v...)
+		}
+	}
+
+	if pc.Present != nil && *pc.Present != found {
+		return false
+	}
+	if !found {
+		// Only an explicit `present: false` can match a missing parameter.
+		return pc.Present != nil && !*pc.Present
+	}
+	if pc.Value != "" && !matchAny([]string{pc.Value}, values...) {
+		return false
+	}
+	if pc.CountGreaterThan != nil && countParamValues(values) <= *pc.CountGreaterThan {
+		return false
+	}
+	return true
+}
+
+// countParamValues counts each value once, except JSON arrays such as
+// --InstanceIds '["i-1","i-2"]' which count their elements.
+func countParamValues(values []string) int {
+	n := 0
+	for _, v := range values {
+		trimmed := strings.TrimSpace(v)
+		if strings.HasPrefix(trimmed, "[") {
+			var arr []interface{}
+			if err := json.Unmarshal([]byte(trimmed), &arr); err == nil {
+				n += len(arr)
+				continue
+			}
+		}
+		n++
+	}
+	return n
+}
+
+// matchAny reports whether any of the wildcard patterns matches any of the values.
+func matchAny(patterns []string, values ...string) bool {
+	for _, p := range patterns {
+		for _, v := range values {
+			if matchPattern(p, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
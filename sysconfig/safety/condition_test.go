@@ -0,0 +1,196 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package safety
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(i int) *int    { return &i }
+func boolPtr(b bool) *bool { return &b }
+
+func TestPolicy_Check_ConditionParamValue(t *testing.T) {
+	policy := &Policy{
+		Enabled: true,
+		Rules: []Rule{
+			{Pattern: "ecs:DeleteInstance", Action: ActionConfirm, When: &Condition{
+				Params: []ParamCondition{{Name: "Force", Value: "true"}},
+			}},
+		},
+	}
+
+	forced := CommandInfo{Product: "ecs", ApiOrMethod: "DeleteInstance", Params: map[string][]string{"Force": {"True"}}}
+	result := policy.Check(forced)
+	assert.True(t, result.Matched)
+	assert.Equal(t, ActionConfirm, result.Action)
+	assert.Equal(t, "Force=true", result.Condition)
+
+	notForced := CommandInfo{Product: "ecs", ApiOrMethod: "DeleteInstance", Params: map[string][]string{"Force": {"false"}}}
+	assert.False(t, policy.Check(notForced).Matched)
+
+	missing := CommandInfo{Product: "ecs", ApiOrMethod: "DeleteInstance"}
+	assert.False(t, policy.Check(missing).Matched)
+}
+
+func TestPolicy_Check_ConditionRegionAndProfile(t *testing.T) {
+	policy := &Policy{
+		Enabled: true,
+		Rules: []Rule{
+			{Pattern: "*", Action: ActionDeny, When: &Condition{
+				Regions:  []string{"cn-shanghai"},
+				Profiles: []string{"prod*"},
+			}},
+		},
+	}
+
+	result := policy.Check(CommandInfo{Product: "ecs", ApiOrMethod: "DescribeInstances", Region: "cn-shanghai", Profile: "prod-admin"})
+	assert.True(t, result.Matched)
+	assert.Equal(t, ActionDeny, result.Action)
+	assert.Equal(t, "region=cn-shanghai, profile=prod*", result.Condition)
+
+	assert.False(t, policy.Check(CommandInfo{Product: "ecs", ApiOrMethod: "DescribeInstances", Region: "cn-hangzhou", Profile: "prod"}).Matched)
+	assert.False(t, policy.Check(CommandInfo{Product: "ecs", ApiOrMethod: "DescribeInstances", Region: "cn-shanghai", Profile: "dev"}).Matched)
+}
+
+func TestPolicy_Check_ConditionEndpoint(t *testing.T) {
+	policy := &Policy{
+		Enabled: true,
+		Rules: []Rule{
+			{Pattern: "*", Action: ActionDeny, When: &Condition{Endpoints: []string{"*.vpc-proxy.aliyuncs.com"}}},
+		},
+	}
+	assert.True(t, policy.Check(CommandInfo{Product: "ecs", ApiOrMethod: "X", Endpoint: "ecs.vpc-proxy.aliyuncs.com"}).Matched)
+	assert.False(t, policy.Check(CommandInfo{Product: "ecs", ApiOrMethod: "X", Endpoint: "ecs.aliyuncs.com"}).Matched)
+	assert.False(t, policy.Check(CommandInfo{Product: "ecs", ApiOrMethod: "X"}).Matched)
+}
+
+func TestPolicy_Check_ConditionCountGreaterThan(t *testing.T) {
+	policy := &Policy{
+		Enabled: true,
+		Rules: []Rule{
+			{Pattern: "ecs:RunCommand", Action: ActionDeny, When: &Condition{
+				Params: []ParamCondition{{Name: "InstanceId*", CountGreaterThan: intPtr(2)}},
+			}},
+		},
+	}
+
+	repeatList := map[string][]string{
+		"InstanceId.1": {"i-1"},
+		"InstanceId.2": {"i-2"},
+		"InstanceId.3": {"i-3"},
+	}
+	result := policy.Check(CommandInfo{Product: "ecs", ApiOrMethod: "RunCommand", Params: repeatList})
+	assert.True(t, result.Matched)
+	assert.Equal(t, "count(InstanceId*)>2", result.Condition)
+
+	jsonArray := map[string][]string{"InstanceIds": {`["i-1","i-2","i-3"]`}}
+	assert.True(t, policy.Check(CommandInfo{Product: "ecs", ApiOrMethod: "RunCommand", Params: jsonArray}).Matched)
+
+	few := map[string][]string{"InstanceIds": {`["i-1","i-2"]`}}
+	assert.False(t, policy.Check(CommandInfo{Product: "ecs", ApiOrMethod: "RunCommand", Params: few}).Matched)
+}
+
+func TestPolicy_Check_ConditionPresent(t *testing.T) {
+	policy := &Policy{
+		Enabled: true,
+		Rules: []Rule{
+			{Pattern: "ecs:ModifyInstanceAttribute", Action: ActionConfirm, When: &Condition{
+				Params: []ParamCondition{{Name: "Password", Present: boolPtr(true)}},
+			}},
+			{Pattern: "ecs:CreateInstance", Action: ActionDeny, When: &Condition{
+				Params: []ParamCondition{{Name: "SecurityGroupId", Present: boolPtr(false)}},
+			}},
+		},
+	}
+	assert.True(t, policy.Check(CommandInfo{Product: "ecs", ApiOrMethod: "ModifyInstanceAttribute", Params: map[string][]string{"Password": {"x"}}}).Matched)
+	assert.False(t, policy.Check(CommandInfo{Product: "ecs", ApiOrMethod: "ModifyInstanceAttribute"}).Matched)
+
+	result := policy.Check(CommandInfo{Product: "ecs", ApiOrMethod: "CreateInstance"})
+	assert.True(t, result.Matched)
+	assert.Equal(t, "SecurityGroupId absent", result.Condition)
+	assert.False(t, policy.Check(CommandInfo{Product: "ecs", ApiOrMethod: "CreateInstance", Params: map[string][]string{"SecurityGroupId": {"sg-1"}}}).Matched)
+}
+
+func TestPolicy_Check_ConditionFallsThroughToNextRule(t *testing.T) {
+	policy := &Policy{
+		Enabled: true,
+		Rules: []Rule{
+			{Pattern: "ecs:Delete*", Action: ActionDeny, When: &Condition{Profiles: []string{"prod"}}},
+			{Pattern: "ecs:Delete*", Action: ActionConfirm},
+		},
+	}
+	assert.Equal(t, ActionDeny, policy.Check(CommandInfo{Product: "ecs", ApiOrMethod: "DeleteInstance", Profile: "prod"}).Action)
+	result := policy.Check(CommandInfo{Product: "ecs", ApiOrMethod: "DeleteInstance", Profile: "dev"})
+	assert.Equal(t, ActionConfirm, result.Action)
+	assert.Empty(t, result.Condition)
+}
+
+func TestParamsFromFlags(t *testing.T) {
+	assert.Nil(t, ParamsFromFlags(nil))
+
+	fs := cli.NewFlagSet()
+	force, err := fs.AddByName("Force")
+	require.NoError(t, err)
+	force.SetAssigned(true)
+	force.SetValue("true")
+	_, err = fs.AddByName("Unassigned")
+	require.NoError(t, err)
+
+	params := ParamsFromFlags(fs)
+	assert.Equal(t, map[string][]string{"Force": {"true"}}, params)
+}
+
+func TestCondition_JSONRoundTrip(t *testing.T) {
+	raw := `{"pattern":"ecs:RunCommand","action":"deny","when":{"params":[{"name":"InstanceId*","countGreaterThan":10}],"regions":["cn-*"]}}`
+	var r Rule
+	require.NoError(t, json.Unmarshal([]byte(raw), &r))
+	require.NotNil(t, r.When)
+	require.Len(t, r.When.Params, 1)
+	require.NotNil(t, r.When.Params[0].CountGreaterThan)
+	assert.Equal(t, 10, *r.When.Params[0].CountGreaterThan)
+	assert.Equal(t, []string{"cn-*"}, r.When.Regions)
+
+	out, err := json.Marshal(Rule{Pattern: "a:b", Action: ActionDeny})
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "when")
+}
+
+func TestMergeSafetyPolicyPathIntoEnvs_ConditionalRulesUseJSON(t *testing.T) {
+	unsetEnvForTest(t, EnvSafetyPolicyEnabled)
+	unsetEnvForTest(t, EnvSafetyPolicyRules)
+	dir := t.TempDir()
+	require.NoError(t, SavePolicy(dir, &Policy{
+		Enabled: true,
+		Rules: []Rule{
+			{Pattern: "ecs:Delete*", Action: ActionDeny, When: &Condition{Profiles: []string{"prod"}}},
+			{Pattern: "ecs:Stop*", Action: ActionConfirm},
+		},
+	}))
+	m := map[string]string{}
+	MergeSafetyPolicyPathIntoEnvs(dir, m)
+
+	// The plugin side reads the env back through MergePolicyFromEnv.
+	t.Setenv(EnvSafetyPolicyRules, m[EnvSafetyPolicyRules])
+	got := MergePolicyFromEnv(&Policy{Enabled: true})
+	require.Len(t, got.Rules, 2)
+	require.NotNil(t, got.Rules[0].When)
+	assert.Equal(t, []string{"prod"}, got.Rules[0].When.Profiles)
+	assert.Nil(t, got.Rules[1].When)
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package safety
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CommandInfoFromArgs maps the positional segments of a command line (without
+// the leading "aliyun" and without flags) to the product / API / path triple
+// that the openapi Commando passes to the policy:
+//
+//	ecs DeleteInstance              -> ecs:DeleteInstance
+//	cs DELETE /clusters/c-1         -> cs:DELETE/clusters/c-1
+//	fc function create              -> fc:function:create  (plugin, N segments)
+func CommandInfoFromArgs(args []string) (CommandInfo, error) {
+	switch {
+	case len(args) < 2:
+		return CommandInfo{}, fmt.Errorf("expected at least <product> <api>, got %q", strings.Join(args, " "))
+	case len(args) == 3 && isHTTPMethod(args[1]) && strings.HasPrefix(args[2], "/"):
+		return CommandInfo{Product: args[0], ApiOrMethod: args[1], Path: args[2]}, nil
+	default:
+		return CommandInfo{Product: args[0], ApiOrMethod: strings.Join(args[1:], ":")}, nil
+	}
+}
+
+func isHTTPMethod(s string) bool {
+	switch strings.ToUpper(s) {
+	case "GET", "POST", "PUT", "DELETE", "PATCH", "HEAD":
+		return true
+	}
+	return false
+}
+
+// NormalizeAction returns the action Check reports for a (possibly aliased)
+// action name, so "forbid" compares equal to "confirm".
+func NormalizeAction(a Action) Action {
+	a = Action(strings.ToLower(strings.TrimSpace(string(a))))
+	switch a {
+	case ActionForbid:
+		return ActionConfirm
+	case "":
+		return ActionAllow
+	}
+	return a
+}
+
+// SplitCommandLine splits a command line into arguments the way a POSIX shell
+// would for the simple cases found in scripts: whitespace separates words,
+// single quotes are literal, double quotes allow \" and \\ escapes, and a
+// backslash outside quotes escapes the next character.
+func SplitCommandLine(line string) ([]string, error) {
+	var (
+		args    []string
+		cur     strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				cur.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				args = append(args, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inWord {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// TestCase is one line of a policy test file:
+//
+//	# comment
+//	aliyun ecs DeleteInstance --InstanceId i-1 => deny
+//	ecs DescribeInstances --region cn-shanghai  => allow
+type TestCase struct {
+	Line     int
+	Command  string
+	Args     []string
+	Expected Action
+}
+
+// TestCaseSeparator separates the command line from the expected action.
+const TestCaseSeparator = "=>"
+
+// ParseTestCases reads policy test cases; blank lines and lines starting with
+// '#' are skipped. A leading "aliyun" on the command line is optional.
+func ParseTestCases(r io.Reader) ([]TestCase, error) {
+	var cases []TestCase
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		idx := strings.LastIndex(line, TestCaseSeparator)
+		if idx < 0 {
+			return nil, fmt.Errorf("line %d: missing '%s <action>'", lineNo, TestCaseSeparator)
+		}
+		command := strings.TrimSpace(line[:idx])
+		expected := Action(strings.ToLower(strings.TrimSpace(line[idx+len(TestCaseSeparator):])))
+		switch expected {
+		case ActionAllow, ActionDeny, ActionConfirm, ActionForbid:
+		default:
+			return nil, fmt.Errorf("line %d: expected action must be allow, deny, confirm or forbid, got %q", lineNo, expected)
+		}
+		args, err := SplitCommandLine(command)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		args = TrimProgramName(args)
+		if len(args) == 0 {
+			return nil, fmt.Errorf("line %d: empty command", lineNo)
+		}
+		cases = append(cases, TestCase{Line: lineNo, Command: command, Args: args, Expected: expected})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return cases, nil
+}
+
+// TrimProgramName drops a leading "aliyun" so command lines can be pasted as typed.
+func TrimProgramName(args []string) []string {
+	if len(args) > 0 && args[0] == "aliyun" {
+		return args[1:]
+	}
+	return args
+}
+
+// Pattern renders cmd the way rule patterns are matched against, e.g.
+// "ecs:DeleteInstance" or "cs:DELETE/clusters/c-1".
+func (cmd CommandInfo) Pattern() string {
+	return buildCommandPattern(cmd)
+}
+
+// Evaluate checks cmd against the merged rules as if the policy were enabled,
+// and returns the matched rule with its origin (nil when nothing matched).
+func (lp *LayeredPolicy) Evaluate(cmd CommandInfo) (CheckResult, *OriginRule) {
+	p := lp.Policy()
+	p.Enabled = true
+	result := p.Check(cmd)
+	for i := range p.Rules {
+		if result.Rule == &p.Rules[i] {
+			return result, &lp.Rules[i]
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package safety
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandInfoFromArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"ecs", "DeleteInstance"}, "ecs:DeleteInstance"},
+		{[]string{"cs", "delete", "/clusters/c-1"}, "cs:DELETE/clusters/c-1"},
+		{[]string{"fc", "function", "create"}, "fc:function:create"},
+		{[]string{"fc", "invoke", "my-fn", "extra"}, "fc:invoke:my-fn:extra"},
+	}
+	for _, tt := range tests {
+		cmd, err := CommandInfoFromArgs(tt.args)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, cmd.Pattern(), strings.Join(tt.args, " "))
+	}
+
+	_, err := CommandInfoFromArgs([]string{"ecs"})
+	assert.Error(t, err)
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	args, err := SplitCommandLine(`ecs RunCommand --CommandContent 'echo "hi"' --Name "a b" --Tag\ x  --Empty ""`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ecs", "RunCommand", "--CommandContent", `echo "hi"`, "--Name", "a b", "--Tag x", "--Empty", ""}, args)
+
+	_, err = SplitCommandLine(`ecs Describe --Name "open`)
+	assert.Error(t, err)
+}
+
+func TestParseTestCases(t *testing.T) {
+	input := `
+# destructive calls
+aliyun ecs DeleteInstance --InstanceId i-1 => deny
+ecs DescribeInstances => ALLOW
+
+fc function delete --name a=>b => forbid
+`
+	cases, err := ParseTestCases(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, cases, 3)
+	assert.Equal(t, 3, cases[0].Line)
+	assert.Equal(t, []string{"ecs", "DeleteInstance", "--InstanceId", "i-1"}, cases[0].Args)
+	assert.Equal(t, ActionDeny, cases[0].Expected)
+	assert.Equal(t, ActionAllow, cases[1].Expected)
+	assert.Equal(t, []string{"fc", "function", "delete", "--name", "a=>b"}, cases[2].Args)
+	assert.Equal(t, ActionConfirm, NormalizeAction(cases[2].Expected))
+
+	_, err = ParseTestCases(strings.NewReader("ecs DeleteInstance\n"))
+	assert.ErrorContains(t, err, "line 1")
+	_, err = ParseTestCases(strings.NewReader("ecs DeleteInstance => block\n"))
+	assert.ErrorContains(t, err, "block")
+}
+
+func TestLayeredPolicy_Evaluate(t *testing.T) {
+	lp := MergeLayers([]Layer{
+		{Name: LayerSystem, Path: "/etc/aliyun/safety-policy.json", Found: true, Policy: &Policy{
+			Locked: true, Rules: []Rule{{Pattern: "ram:*", Action: ActionDeny}},
+		}},
+		{Name: LayerUser, Path: "/home/u/.aliyun/safety-policy.json", Found: true, Policy: &Policy{
+			Rules: []Rule{{Pattern: "ecs:Delete*", Action: ActionForbid}},
+		}},
+	})
+	// Neither layer is enabled; Evaluate still applies the rules.
+	assert.False(t, lp.Enabled)
+
+	result, origin := lp.Evaluate(CommandInfo{Product: "ecs", ApiOrMethod: "DeleteInstance"})
+	assert.Equal(t, ActionConfirm, result.Action)
+	require.NotNil(t, origin)
+	assert.Equal(t, "user:/home/u/.aliyun/safety-policy.json", origin.Origin)
+
+	result, origin = lp.Evaluate(CommandInfo{Product: "ram", ApiOrMethod: "CreateUser"})
+	assert.Equal(t, ActionDeny, result.Action)
+	require.NotNil(t, origin)
+	assert.True(t, origin.Locked)
+
+	result, origin = lp.Evaluate(CommandInfo{Product: "ecs", ApiOrMethod: "DescribeInstances"})
+	assert.Equal(t, ActionAllow, result.Action)
+	assert.Nil(t, origin)
+}
@@ -0,0 +1,332 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package safety
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Policy layers, from the outermost (organization) to the innermost (current shell).
+const (
+	LayerSystem  = "system"
+	LayerProject = "project"
+	LayerUser    = "user"
+	LayerEnv     = "env"
+)
+
+// ProjectPolicyDir is the directory, relative to a repository root, that holds
+// a checked-in project policy (.aliyun/safety-policy.json).
+const ProjectPolicyDir = ".aliyun"
+
+// systemPolicyDir is where platform teams install the organization-wide policy.
+// It is deliberately not configurable through the environment, so users cannot
+// point it elsewhere to escape a locked policy.
+var systemPolicyDir = defaultSystemPolicyDir()
+
+// getwd is replaced in tests to control project policy discovery.
+var getwd = os.Getwd
+
+func defaultSystemPolicyDir() string {
+	if runtime.GOOS == "windows" {
+		if pd := os.Getenv("ProgramData"); pd != "" {
+			return filepath.Join(pd, "aliyun")
+		}
+		return `C:\ProgramData\aliyun`
+	}
+	return "/etc/aliyun"
+}
+
+// Layer is one source of safety rules.
+type Layer struct {
+	Name string `json:"name"`
+	Path string `json:"path,omitempty"`
+	// Found is false when the layer's file does not exist (or env is unset).
+	Found bool `json:"found"`
+	// Error is set when the file exists but cannot be read or parsed; the layer is then ignored,
+	// unless it is the system layer or a locked one, see LayeredPolicy.Err.
+	Error string `json:"error,omitempty"`
+	// Locked is set when the file declares itself locked, even if its rules cannot be parsed.
+	Locked bool    `json:"locked,omitempty"`
+	Policy *Policy `json:"-"`
+}
+
+// OriginRule is a rule of the merged policy together with where it came from.
+type OriginRule struct {
+	Rule
+	Origin string `json:"origin"`
+	Locked bool   `json:"locked,omitempty"`
+}
+
+// LayeredPolicy is the merged view of all layers, in evaluation order.
+type LayeredPolicy struct {
+	Enabled bool `json:"enabled"`
+	// EnabledBy names the layer that decided Enabled.
+	EnabledBy string       `json:"enabledBy,omitempty"`
+	Rules     []OriginRule `json:"rules"`
+	Layers    []Layer      `json:"layers"`
+}
+
+// Policy returns the merged rules as a plain Policy for Check.
+func (lp *LayeredPolicy) Policy() *Policy {
+	rules := make([]Rule, 0, len(lp.Rules))
+	for _, r := range lp.Rules {
+		rule := r.Rule
+		rule.locked = r.Locked
+		rules = append(rules, rule)
+	}
+	return &Policy{Enabled: lp.Enabled, Rules: rules}
+}
+
+// LoadLayers reads the system, project and user policy files and the env
+// overrides, outermost first.
+func LoadLayers(configDir string) []Layer {
+	layers := []Layer{readLayer(LayerSystem, GetPolicyFilePath(systemPolicyDir))}
+	userPath := GetPolicyFilePath(configDir)
+	if p := findProjectPolicy(userPath); p != "" {
+		layers = append(layers, readLayer(LayerProject, p))
+	} else {
+		layers = append(layers, Layer{Name: LayerProject})
+	}
+	layers = append(layers, readLayer(LayerUser, userPath), envLayer())
+	return layers
+}
+
+func readLayer(name, path string) Layer {
+	l := Layer{Name: name, Path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			l.Found = true
+			l.Error = err.Error()
+		}
+		return l
+	}
+	l.Found = true
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		l.Error = err.Error()
+		l.Locked = declaresLocked(data)
+		return l
+	}
+	if p.Rules == nil {
+		p.Rules = []Rule{}
+	}
+	l.Locked = p.Locked
+	l.Policy = &p
+	return l
+}
+
+// declaresLocked tells whether an unparsable policy file means to be locked. When not even
+// the locked field can be decoded, any mention of it counts, so a typo cannot unlock a file.
+func declaresLocked(data []byte) bool {
+	var head struct {
+		Locked bool `json:"locked"`
+	}
+	if err := json.Unmarshal(data, &head); err == nil {
+		return head.Locked
+	}
+	return bytes.Contains(data, []byte(`"locked"`))
+}
+
+// envLayer turns ALIBABA_CLOUD_SAFETY_POLICY_ENABLED / _RULES into a layer.
+// Its Policy.Rules is nil when the rules variable is unset, so that "unset"
+// and "set to empty" (which clears file rules) can be told apart.
+func envLayer() Layer {
+	l := Layer{Name: LayerEnv}
+	p := &Policy{}
+	enabledSet := false
+	if v, ok := os.LookupEnv(EnvSafetyPolicyEnabled); ok {
+		if parsed, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+			p.Enabled = parsed
+			enabledSet = true
+		}
+	}
+	if raw0, ok := os.LookupEnv(EnvSafetyPolicyRules); ok {
+		raw := strings.TrimSpace(raw0)
+		if raw == "" {
+			p.Rules = []Rule{}
+		} else if r, parsed := parseEnvRulesList(raw); parsed {
+			p.Rules = r
+		}
+	}
+	if enabledSet || p.Rules != nil {
+		l.Found = true
+		l.Policy = p
+		l.Path = envLayerPath(enabledSet, p.Rules != nil)
+	}
+	return l
+}
+
+func envLayerPath(enabledSet, rulesSet bool) string {
+	var names []string
+	if enabledSet {
+		names = append(names, EnvSafetyPolicyEnabled)
+	}
+	if rulesSet {
+		names = append(names, EnvSafetyPolicyRules)
+	}
+	return strings.Join(names, ",")
+}
+
+// findProjectPolicy walks up from the working directory looking for
+// .aliyun/safety-policy.json. The user's own config dir (~/.aliyun, or the
+// configDir in use) is never mistaken for a project policy.
+func findProjectPolicy(userPath string) string {
+	dir, err := getwd()
+	if err != nil {
+		return ""
+	}
+	skip := map[string]bool{cleanAbs(userPath): true}
+	if home, err := os.UserHomeDir(); err == nil {
+		skip[cleanAbs(GetPolicyFilePath(filepath.Join(home, ProjectPolicyDir)))] = true
+	}
+	for {
+		candidate := filepath.Join(dir, ProjectPolicyDir, SafetyPolicyFileName)
+		if !skip[cleanAbs(candidate)] {
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				return candidate
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func cleanAbs(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		return filepath.Clean(abs)
+	}
+	return filepath.Clean(p)
+}
+
+// MergeLayers combines layers into the effective policy:
+//
+//   - Rules of locked layers come first (outermost first), so no inner layer
+//     can shadow them with an allow rule, and a locked, enabled layer keeps
+//     the whole policy enabled.
+//   - Unlocked rules follow, innermost first (env, user, project, system), so
+//     closer layers refine broader ones. Env rules, when set, replace every
+//     unlocked file rule as they always have.
+//   - Otherwise Enabled comes from the innermost layer that sets it.
+func MergeLayers(layers []Layer) *LayeredPolicy {
+	lp := &LayeredPolicy{Layers: layers, Rules: []OriginRule{}}
+
+	var env *Layer
+	for i := range layers {
+		if layers[i].Name == LayerEnv && layers[i].Policy != nil {
+			env = &layers[i]
+		}
+	}
+
+	seen := map[string]bool{}
+	add := func(l *Layer, r Rule, locked bool) {
+		key := ruleKey(r)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		lp.Rules = append(lp.Rules, OriginRule{Rule: r, Origin: layerOrigin(l), Locked: locked})
+	}
+
+	lockedEnabled := false
+	for i := range layers {
+		l := &layers[i]
+		if l.Policy == nil || !l.Policy.Locked || l.Name == LayerEnv {
+			continue
+		}
+		if l.Policy.Enabled {
+			lockedEnabled = true
+			if lp.EnabledBy == "" {
+				lp.EnabledBy = l.Name
+			}
+		}
+		for _, r := range l.Policy.Rules {
+			add(l, r, true)
+		}
+	}
+
+	envReplacesRules := env != nil && env.Policy.Rules != nil
+	for i := len(layers) - 1; i >= 0; i-- {
+		l := &layers[i]
+		if l.Policy == nil || (l.Policy.Locked && l.Name != LayerEnv) {
+			continue
+		}
+		if envReplacesRules && l.Name != LayerEnv {
+			continue
+		}
+		for _, r := range l.Policy.Rules {
+			add(l, r, false)
+		}
+	}
+
+	if lockedEnabled {
+		lp.Enabled = true
+		return lp
+	}
+	for i := len(layers) - 1; i >= 0; i-- {
+		l := &layers[i]
+		if l.Policy == nil {
+			continue
+		}
+		if l.Name == LayerEnv && !strings.Contains(l.Path, EnvSafetyPolicyEnabled) {
+			continue
+		}
+		lp.Enabled = l.Policy.Enabled
+		lp.EnabledBy = l.Name
+		break
+	}
+	return lp
+}
+
+// Err reports a system or locked layer that exists but cannot be read or parsed. Its rules
+// would be missing from the merged policy, so callers must block instead of failing open.
+func (lp *LayeredPolicy) Err() error {
+	for _, l := range lp.Layers {
+		if l.Error != "" && (l.Name == LayerSystem || l.Locked) {
+			return fmt.Errorf("%s safety policy %s cannot be loaded: %s", l.Name, l.Path, l.Error)
+		}
+	}
+	return nil
+}
+
+func layerOrigin(l *Layer) string {
+	if l.Path == "" {
+		return l.Name
+	}
+	return l.Name + ":" + l.Path
+}
+
+func ruleKey(r Rule) string {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return r.Pattern + "=" + string(r.Action)
+	}
+	return strings.ToLower(string(data))
+}
+
+// LoadLayeredPolicy loads and merges every layer; configDir is the user layer.
+func LoadLayeredPolicy(configDir string) *LayeredPolicy {
+	return MergeLayers(LoadLayers(configDir))
+}
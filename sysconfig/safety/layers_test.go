@@ -0,0 +1,197 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package safety
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// layerDirs isolates a test from the real /etc/aliyun and working directory.
+type layerDirs struct {
+	system, project, user, cwd string
+}
+
+func setupLayers(t *testing.T) layerDirs {
+	t.Helper()
+	root := t.TempDir()
+	d := layerDirs{
+		system:  filepath.Join(root, "etc"),
+		project: filepath.Join(root, "repo", ProjectPolicyDir),
+		user:    filepath.Join(root, "home", ".aliyun"),
+		cwd:     filepath.Join(root, "repo", "sub", "dir"),
+	}
+	require.NoError(t, os.MkdirAll(d.cwd, 0755))
+
+	origSystem, origGetwd := systemPolicyDir, getwd
+	systemPolicyDir = d.system
+	getwd = func() (string, error) { return d.cwd, nil }
+	t.Cleanup(func() {
+		systemPolicyDir, getwd = origSystem, origGetwd
+	})
+	t.Setenv(EnvSafetyPolicyEnabled, "")
+	os.Unsetenv(EnvSafetyPolicyEnabled)
+	t.Setenv(EnvSafetyPolicyRules, "")
+	os.Unsetenv(EnvSafetyPolicyRules)
+	return d
+}
+
+func writePolicyFile(t *testing.T, dir string, p Policy) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(GetPolicyFilePath(dir), data, 0600))
+}
+
+func TestLoadLayeredPolicy_NoFiles(t *testing.T) {
+	d := setupLayers(t)
+	lp := LoadLayeredPolicy(d.user)
+	assert.False(t, lp.Enabled)
+	assert.Empty(t, lp.Rules)
+	require.Len(t, lp.Layers, 4)
+	for _, l := range lp.Layers {
+		assert.False(t, l.Found, l.Name)
+	}
+}
+
+func TestLoadLayeredPolicy_InnerLayersFirst(t *testing.T) {
+	d := setupLayers(t)
+	writePolicyFile(t, d.system, Policy{Enabled: true, Rules: []Rule{{Pattern: "*:Delete*", Action: ActionConfirm}}})
+	writePolicyFile(t, d.project, Policy{Enabled: true, Rules: []Rule{{Pattern: "ecs:DeleteInstance", Action: ActionDeny}}})
+	writePolicyFile(t, d.user, Policy{Enabled: false, Rules: []Rule{{Pattern: "ecs:DeleteSnapshot", Action: ActionForbid}}})
+
+	lp := LoadLayeredPolicy(d.user)
+	// The user layer is the innermost file, so its enabled flag wins.
+	assert.False(t, lp.Enabled)
+	assert.Equal(t, LayerUser, lp.EnabledBy)
+	require.Len(t, lp.Rules, 3)
+	assert.Equal(t, "ecs:DeleteSnapshot", lp.Rules[0].Pattern)
+	assert.Equal(t, LayerUser+":"+GetPolicyFilePath(d.user), lp.Rules[0].Origin)
+	assert.Equal(t, "ecs:DeleteInstance", lp.Rules[1].Pattern)
+	assert.Equal(t, LayerProject+":"+GetPolicyFilePath(d.project), lp.Rules[1].Origin)
+	assert.Equal(t, "*:Delete*", lp.Rules[2].Pattern)
+	assert.Equal(t, LayerSystem+":"+GetPolicyFilePath(d.system), lp.Rules[2].Origin)
+}
+
+func TestLoadLayeredPolicy_LockedSystemCannotBeWeakened(t *testing.T) {
+	d := setupLayers(t)
+	writePolicyFile(t, d.system, Policy{Enabled: true, Locked: true, Rules: []Rule{{Pattern: "*:Delete*", Action: ActionDeny}}})
+	writePolicyFile(t, d.user, Policy{Enabled: false, Rules: []Rule{{Pattern: "ecs:DeleteInstance", Action: ActionConfirm}}})
+	t.Setenv(EnvSafetyPolicyEnabled, "false")
+	t.Setenv(EnvSafetyPolicyRules, "")
+
+	lp := LoadLayeredPolicy(d.user)
+	assert.True(t, lp.Enabled)
+	assert.Equal(t, LayerSystem, lp.EnabledBy)
+	require.Len(t, lp.Rules, 1)
+	assert.True(t, lp.Rules[0].Locked)
+
+	got := lp.Policy().Check(CommandInfo{Product: "ecs", ApiOrMethod: "DeleteInstance"})
+	assert.Equal(t, ActionDeny, got.Action)
+}
+
+func TestLoadLayeredPolicy_LockedRulesEvaluatedBeforeUserRules(t *testing.T) {
+	d := setupLayers(t)
+	writePolicyFile(t, d.project, Policy{Enabled: true, Locked: true, Rules: []Rule{{Pattern: "ecs:DeleteInstance", Action: ActionDeny}}})
+	writePolicyFile(t, d.user, Policy{Enabled: true, Rules: []Rule{{Pattern: "ecs:*", Action: ActionConfirm}}})
+
+	lp := LoadLayeredPolicy(d.user)
+	require.Len(t, lp.Rules, 2)
+	assert.Equal(t, "ecs:DeleteInstance", lp.Rules[0].Pattern)
+	assert.Equal(t, "ecs:*", lp.Rules[1].Pattern)
+	got := lp.Policy().Check(CommandInfo{Product: "ecs", ApiOrMethod: "DeleteInstance"})
+	assert.Equal(t, ActionDeny, got.Action)
+}
+
+func TestLoadLayeredPolicy_EnvRulesReplaceUnlockedFileRules(t *testing.T) {
+	d := setupLayers(t)
+	writePolicyFile(t, d.system, Policy{Enabled: true, Locked: true, Rules: []Rule{{Pattern: "ram:*", Action: ActionDeny}}})
+	writePolicyFile(t, d.project, Policy{Enabled: true, Rules: []Rule{{Pattern: "rds:*", Action: ActionConfirm}}})
+	writePolicyFile(t, d.user, Policy{Enabled: true, Rules: []Rule{{Pattern: "ecs:*", Action: ActionConfirm}}})
+	t.Setenv(EnvSafetyPolicyRules, "oss:Delete*=deny,ram:*=deny")
+
+	lp := LoadLayeredPolicy(d.user)
+	require.Len(t, lp.Rules, 2)
+	assert.Equal(t, "ram:*", lp.Rules[0].Pattern)
+	assert.True(t, lp.Rules[0].Locked)
+	assert.Equal(t, "oss:Delete*", lp.Rules[1].Pattern)
+	assert.Equal(t, LayerEnv+":"+EnvSafetyPolicyRules, lp.Rules[1].Origin)
+}
+
+func TestLoadLayeredPolicy_UnparsableLayerIsReported(t *testing.T) {
+	d := setupLayers(t)
+	require.NoError(t, os.MkdirAll(d.project, 0755))
+	require.NoError(t, os.WriteFile(GetPolicyFilePath(d.project), []byte("{not json"), 0600))
+	writePolicyFile(t, d.user, Policy{Enabled: true, Rules: []Rule{}})
+
+	lp := LoadLayeredPolicy(d.user)
+	assert.True(t, lp.Enabled)
+	assert.True(t, lp.Layers[1].Found)
+	assert.NotEmpty(t, lp.Layers[1].Error)
+}
+
+func TestFindProjectPolicy_SkipsUserConfigDir(t *testing.T) {
+	d := setupLayers(t)
+	// A user config dir that happens to be .aliyun above the cwd must not be
+	// picked up again as a project policy.
+	userDir := filepath.Dir(d.project)
+	userDir = filepath.Join(userDir, ProjectPolicyDir)
+	writePolicyFile(t, userDir, Policy{Enabled: true})
+	assert.Equal(t, "", findProjectPolicy(GetPolicyFilePath(userDir)))
+	assert.Equal(t, GetPolicyFilePath(d.project), findProjectPolicy(GetPolicyFilePath(d.user)))
+}
+
+func TestLoadEffectivePolicy_BrokenSystemLayerFails(t *testing.T) {
+	d := setupLayers(t)
+	require.NoError(t, os.MkdirAll(d.system, 0755))
+	require.NoError(t, os.WriteFile(GetPolicyFilePath(d.system), []byte(`{"enabled": true, "rules": [`), 0600))
+
+	p, err := LoadEffectivePolicy(d.user)
+	assert.Nil(t, p)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), LayerSystem)
+}
+
+func TestLoadEffectivePolicy_BrokenLockedLayerFails(t *testing.T) {
+	d := setupLayers(t)
+	require.NoError(t, os.MkdirAll(d.project, 0755))
+	// 规则字段类型错误，但 locked 仍可解析
+	require.NoError(t, os.WriteFile(GetPolicyFilePath(d.project), []byte(`{"locked": true, "rules": {}}`), 0600))
+	_, err := LoadEffectivePolicy(d.user)
+	require.Error(t, err)
+
+	// JSON 本身损坏时，出现 locked 字段即视为锁定
+	require.NoError(t, os.WriteFile(GetPolicyFilePath(d.project), []byte(`{"locked": true, "rules": [`), 0600))
+	lp := LoadLayeredPolicy(d.user)
+	assert.True(t, lp.Layers[1].Locked)
+	assert.Error(t, lp.Err())
+}
+
+func TestLoadEffectivePolicy_BrokenUnlockedLayerIsSkipped(t *testing.T) {
+	d := setupLayers(t)
+	require.NoError(t, os.MkdirAll(d.project, 0755))
+	require.NoError(t, os.WriteFile(GetPolicyFilePath(d.project), []byte("{not json"), 0600))
+	writePolicyFile(t, d.user, Policy{Enabled: true, Rules: []Rule{{Pattern: "ecs:*", Action: ActionDeny}}})
+
+	p, err := LoadEffectivePolicy(d.user)
+	require.NoError(t, err)
+	assert.Equal(t, ActionDeny, p.Check(CommandInfo{Product: "ecs", ApiOrMethod: "DeleteInstance"}).Action)
+}
@@ -48,11 +48,24 @@ type Rule struct {
 	Pattern string `json:"pattern"`
 	// Action: allow, deny, confirm (or forbid)
 	Action Action `json:"action"`
+	// When optionally narrows the rule to matching parameters, region, profile
+	// or endpoint; the rule only applies when Pattern and When both match.
+	When *Condition `json:"when,omitempty"`
+
+	// locked marks a rule merged from a locked layer; approvals do not satisfy it.
+	locked bool
 }
 
 type Policy struct {
-	Enabled bool   `json:"enabled"`
-	Rules   []Rule `json:"rules"`
+	Enabled bool `json:"enabled"`
+	// Locked, in a system or project policy file, puts the file's rules ahead
+	// of every other layer and keeps the policy enabled, so users cannot weaken it.
+	Locked bool   `json:"locked,omitempty"`
+	Rules  []Rule `json:"rules"`
+
+	// approvals are the signed, unexpired grants that let matching confirm
+	// rules pass without a prompt; set by LoadEffectivePolicy.
+	approvals []Grant
 }
 
 func DefaultPolicy() *Policy {
@@ -66,6 +79,8 @@ type CheckResult struct {
 	Action  Action
 	Matched bool
 	Rule    *Rule
+	// Condition describes the rule's matched When clause, empty for unconditional rules.
+	Condition string
 }
 
 type CommandInfo struct {
@@ -86,6 +101,14 @@ type CommandInfo struct {
 	// Path is only set for REST style invocations that supply a path
 	// (e.g. `aliyun cs DELETE /clusters` -> Path = "/clusters").
 	Path string
+	// Params holds the parsed command line parameters keyed by flag name
+	// (e.g. {"InstanceId": ["i-xxx"], "Force": ["true"]}), see ParamsFromFlags.
+	Params map[string][]string
+	// Region, Profile and Endpoint describe where the command is sent;
+	// they are only consulted by rules with a When condition.
+	Region   string
+	Profile  string
+	Endpoint string
 }
 
 func (p *Policy) Check(cmd CommandInfo) CheckResult {
@@ -98,7 +121,7 @@ func (p *Policy) Check(cmd CommandInfo) CheckResult {
 	// Rules are evaluated in order; first match wins
 	for i := range p.Rules {
 		rule := &p.Rules[i]
-		if matchPattern(rule.Pattern, cmdPattern) {
+		if matchPattern(rule.Pattern, cmdPattern) && rule.When.Matches(cmd) {
 			action := rule.Action
 			if action == ActionForbid {
 				action = ActionConfirm
@@ -107,9 +130,10 @@ func (p *Policy) Check(cmd CommandInfo) CheckResult {
 				action = ActionAllow
 			}
 			return CheckResult{
-				Action:  action,
-				Matched: true,
-				Rule:    rule,
+				Action:    action,
+				Matched:   true,
+				Rule:      rule,
+				Condition: rule.When.String(),
 			}
 		}
 	}
@@ -153,6 +177,12 @@ func matchPattern(pattern, cmd string) bool {
 	return re.MatchString(cmd)
 }
 
+// MatchPattern reports whether s matches a rule-style pattern: '*' matches any
+// sequence and the comparison is case-insensitive.
+func MatchPattern(pattern, s string) bool {
+	return matchPattern(pattern, s)
+}
+
 func InferOperationFromApiName(apiName string) string {
 	apiLower := strings.ToLower(apiName)
 	if strings.HasPrefix(apiLower, "delete") {
@@ -209,6 +239,9 @@ func actionFromEnvToken(s string) (Action, bool) {
 }
 
 func parseEnvRulesList(raw string) ([]Rule, bool) {
+	if strings.HasPrefix(raw, "[") {
+		return parseEnvRulesJSON(raw)
+	}
 	var out []Rule
 	for _, part := range strings.Split(raw, ",") {
 		part = strings.TrimSpace(part)
@@ -236,6 +269,32 @@ func parseEnvRulesList(raw string) ([]Rule, bool) {
 	return out, true
 }
 
+// parseEnvRulesJSON accepts the JSON array form used when rules carry When
+// conditions, which the compact pattern=action list cannot express.
+func parseEnvRulesJSON(raw string) ([]Rule, bool) {
+	var rules []Rule
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		return nil, false
+	}
+	out := make([]Rule, 0, len(rules))
+	for _, r := range rules {
+		r.Pattern = strings.TrimSpace(r.Pattern)
+		if r.Pattern == "" {
+			continue
+		}
+		act, ok := actionFromEnvToken(string(r.Action))
+		if !ok {
+			continue
+		}
+		r.Action = act
+		out = append(out, r)
+	}
+	if len(out) == 0 {
+		return nil, false
+	}
+	return out, true
+}
+
 func copyPolicyRules(rules []Rule) []Rule {
 	if len(rules) == 0 {
 		return []Rule{}
@@ -247,6 +306,16 @@ func serializeRulesForEnv(rules []Rule) string {
 	if len(rules) == 0 {
 		return ""
 	}
+	for _, r := range rules {
+		if !r.When.IsEmpty() {
+			// Conditions only survive in the JSON form; dropping them would
+			// turn a narrow rule into a blanket one inside the plugin.
+			if data, err := json.Marshal(rules); err == nil {
+				return string(data)
+			}
+			break
+		}
+	}
 	parts := make([]string, 0, len(rules))
 	for _, r := range rules {
 		pat := strings.TrimSpace(r.Pattern)
@@ -288,12 +357,23 @@ func MergePolicyFromEnv(base *Policy) *Policy {
 	return &Policy{Enabled: enabled, Rules: rules}
 }
 
+// DenyAllPolicy blocks every operation. Callers that cannot run without a policy use it
+// in place of one that failed to load.
+func DenyAllPolicy() *Policy {
+	return &Policy{Enabled: true, Rules: []Rule{{Pattern: "*", Action: ActionDeny}}}
+}
+
+// LoadEffectivePolicy merges the system, project and user policy files with
+// the env overrides, see MergeLayers. Active approvals from configDir are attached for CheckAndConfirm.
+// It fails when the system layer or a locked layer cannot be loaded; callers must then block.
 func LoadEffectivePolicy(configDir string) (*Policy, error) {
-	p, err := LoadPolicy(configDir)
-	if err != nil {
+	lp := LoadLayeredPolicy(configDir)
+	if err := lp.Err(); err != nil {
 		return nil, err
 	}
-	return MergePolicyFromEnv(p), nil
+	p := lp.Policy()
+	p.approvals = LoadApprovals(configDir)
+	return p, nil
 }
 
 const EnvSafetyPolicyFile = "ALIBABA_CLOUD_CLI_SAFETY_POLICY_FILE"
@@ -310,7 +390,8 @@ func MergeSafetyPolicyPathIntoEnvs(configDir string, envs map[string]string) {
 
 	ef, err := LoadEffectivePolicy(configDir)
 	if err != nil {
-		return
+		// a broken system or locked policy blocks plugins as well
+		ef = DenyAllPolicy()
 	}
 	envs[EnvSafetyPolicyEnabled] = strconv.FormatBool(ef.Enabled)
 	envs[EnvSafetyPolicyRules] = serializeRulesForEnv(ef.Rules)